@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/config"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	gormRepo "github.com/jyogi-web/jyogi-discord-auth/internal/repository/gorm"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+func main() {
+	discordID := flag.String("user", "", "Discord User ID of the member running the export (must have an export role)")
+	format := flag.String("format", "csv", "Output format: csv, jsonl or xlsx")
+	fields := flag.String("fields", "", "Comma separated fields to export (default: username,display_name,guild_nickname,roles,joined_at,real_name,student_id,enrollment_year,faculty,grade)")
+	output := flag.String("o", "", "Output file (default: stdout)")
+	query := flag.String("q", "", "Filter by username, display name, nickname or real name")
+	roles := flag.String("role", "", "Comma separated role IDs or names to filter by")
+	enrollmentYear := flag.Int("enrollment-year", 0, "Filter by enrollment year")
+	faculty := flag.String("faculty", "", "Filter by faculty code")
+	flag.Parse()
+
+	if *discordID == "" {
+		flag.Usage()
+		log.Fatal("-user is required")
+	}
+
+	exportFormat, err := domain.ParseExportFormat(*format)
+	if err != nil {
+		log.Fatalf("Invalid format: %v", err)
+	}
+	exportFields, err := domain.ParseExportFields(strings.Split(*fields, ","))
+	if err != nil {
+		log.Fatalf("Invalid fields: %v", err)
+	}
+
+	// 絞り込み条件（監査ログにはAPIと同じクエリ文字列の形式で記録する）
+	filter := domain.MemberFilter{Query: *query, EnrollmentYear: *enrollmentYear, Faculty: *faculty}
+	conditions := url.Values{}
+	if *query != "" {
+		conditions.Set("q", *query)
+	}
+	if *roles != "" {
+		for _, role := range strings.Split(*roles, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				filter.RoleIDs = append(filter.RoleIDs, role)
+				conditions.Add("role", role)
+			}
+		}
+	}
+	if *enrollmentYear != 0 {
+		conditions.Set("enrollment_year", strconv.Itoa(*enrollmentYear))
+	}
+	if *faculty != "" {
+		conditions.Set("faculty", *faculty)
+	}
+
+	// 設定を読み込む
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// データベースを初期化
+	db, err := gormRepo.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	userRepo := gormRepo.NewUserRepository(db)
+	exportService := service.NewExportService(
+		userRepo,
+		gormRepo.NewProfileRepository(db),
+		gormRepo.NewRoleRepository(db),
+		gormRepo.NewAuditLogRepository(db),
+		cfg.ExportRoleIDs,
+	)
+
+	ctx := context.Background()
+
+	// Webと同じく、実行したメンバーのロールで許可と公開範囲を判定する
+	user, err := userRepo.GetByDiscordID(ctx, *discordID)
+	if err != nil {
+		log.Fatalf("Failed to get user: %v", err)
+	}
+	viewer := domain.NewProfileViewer(user, cfg.OfficerRoleIDs)
+	if !exportService.CanExport(user, viewer) {
+		log.Fatalf("User %s is not allowed to export members", user.Username)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+	}
+
+	export := &service.MemberExport{
+		Format: exportFormat,
+		Fields: exportFields,
+		Filter: filter,
+		Query:  conditions.Encode(),
+	}
+	actor := domain.AuditActor{UserID: user.ID, Via: domain.AuditViaCLI}
+	count, err := exportService.ExportMembers(ctx, actor, user, viewer, export, out)
+	if err != nil {
+		log.Fatalf("Failed to export members: %v", err)
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			log.Fatalf("Failed to close output file: %v", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d members as %s\n", count, exportFormat)
+}
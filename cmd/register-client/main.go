@@ -20,6 +20,7 @@ func main() {
 	name := flag.String("name", "", "Client Name")
 	redirectURIs := flag.String("redirects", "", "Comma separated redirect URIs")
 	update := flag.Bool("update", false, "Update existing client instead of creating new one")
+	public := flag.Bool("public", false, "Register a public client without a secret (device authorization grant only)")
 	flag.Parse()
 
 	if *clientID == "" {
@@ -27,7 +28,16 @@ func main() {
 		log.Fatal("Client ID is required")
 	}
 
-	if !*update && (*ownerID == "" || *clientSecret == "" || *name == "" || *redirectURIs == "") {
+	if *public {
+		if *update || *clientSecret != "" || *redirectURIs != "" {
+			flag.Usage()
+			log.Fatal("-public cannot be combined with -update, -secret or -redirects")
+		}
+		if *ownerID == "" || *name == "" {
+			flag.Usage()
+			log.Fatal("Owner ID and Name are required for new clients")
+		}
+	} else if !*update && (*ownerID == "" || *clientSecret == "" || *name == "" || *redirectURIs == "") {
 		flag.Usage()
 		log.Fatal("Owner ID, Client Secret, Name, and Redirect URIs are required for new clients")
 	}
@@ -45,7 +55,8 @@ func main() {
 	}
 
 	clientRepo := gormRepo.NewClientRepository(db)
-	clientService := service.NewClientService(clientRepo)
+	clientSecretRepo := gormRepo.NewClientSecretRepository(db)
+	clientService := service.NewClientService(clientRepo, clientSecretRepo)
 
 	var uris []string
 	if *redirectURIs != "" {
@@ -58,13 +69,20 @@ func main() {
 	ctx := context.Background()
 	var client *domain.ClientApp
 
-	if *update {
+	switch {
+	case *public:
+		client, err = clientService.RegisterPublicClient(ctx, *ownerID, *clientID, *name)
+		if err != nil {
+			log.Fatalf("Failed to register client: %v", err)
+		}
+		fmt.Printf("Successfully registered public client: %s (ID: %s)\n", client.Name, client.ID)
+	case *update:
 		client, err = clientService.UpdateClient(ctx, *clientID, *clientSecret, *name, uris)
 		if err != nil {
 			log.Fatalf("Failed to update client: %v", err)
 		}
 		fmt.Printf("Successfully updated client: %s (ID: %s)\n", client.Name, client.ID)
-	} else {
+	default:
 		client, err = clientService.RegisterClient(ctx, *ownerID, *clientID, *clientSecret, *name, uris)
 		if err != nil {
 			log.Fatalf("Failed to register client: %v", err)
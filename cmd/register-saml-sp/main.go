@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/config"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	gormRepo "github.com/jyogi-web/jyogi-discord-auth/internal/repository/gorm"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/saml"
+)
+
+func main() {
+	ownerID := flag.String("owner", "", "Owner ID (User ID)")
+	entityID := flag.String("entity-id", "", "Service provider entity ID")
+	name := flag.String("name", "", "Service provider name")
+	acsURLs := flag.String("acs", "", "Comma separated assertion consumer service URLs")
+	metadataPath := flag.String("metadata", "", "Service provider metadata XML file (sets entity ID and ACS URLs)")
+	attributes := flag.String("attributes", "", `Attribute mapping as JSON (e.g. {"uid":"username","groups":"roles"})`)
+	profileAccess := flag.String("profile-access", "", "Maximum profile visibility sent to the service provider (members or officers)")
+	update := flag.Bool("update", false, "Update existing service provider instead of creating new one")
+	remove := flag.Bool("delete", false, "Delete the service provider")
+	list := flag.Bool("list", false, "List registered service providers")
+	flag.Parse()
+
+	// 設定を読み込む
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// データベースを初期化
+	db, err := gormRepo.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	spRepo := gormRepo.NewSAMLServiceProviderRepository(db)
+	samlService := service.NewSAMLService(spRepo, nil, nil, cfg.PublicBaseURL())
+	ctx := context.Background()
+
+	if *list {
+		sps, err := samlService.GetAllServiceProviders(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list service providers: %v", err)
+		}
+		for _, sp := range sps {
+			fmt.Printf("%s\t%s\t%s\n", sp.EntityID, sp.Name, strings.Join(sp.ACSURLs, ","))
+		}
+		return
+	}
+
+	// メタデータからエンティティIDとACS URLを読み込む
+	var uris []string
+	if *metadataPath != "" {
+		data, err := os.ReadFile(*metadataPath)
+		if err != nil {
+			log.Fatalf("Failed to read metadata: %v", err)
+		}
+		metadata, err := saml.ParseServiceProviderMetadata(data)
+		if err != nil {
+			log.Fatalf("Failed to parse metadata: %v", err)
+		}
+		if *entityID == "" {
+			*entityID = metadata.EntityID
+		}
+		uris = metadata.ACSURLs
+	}
+	if *acsURLs != "" {
+		uris = strings.Split(*acsURLs, ",")
+		for i, uri := range uris {
+			uris[i] = strings.TrimSpace(uri)
+		}
+	}
+
+	if *entityID == "" {
+		flag.Usage()
+		log.Fatal("Entity ID or metadata is required")
+	}
+
+	if *remove {
+		if err := samlService.DeleteServiceProvider(ctx, *entityID); err != nil {
+			log.Fatalf("Failed to delete service provider: %v", err)
+		}
+		fmt.Printf("Successfully deleted service provider: %s\n", *entityID)
+		return
+	}
+
+	var mapping map[string]domain.SAMLAttributeSource
+	if *attributes != "" {
+		if err := json.Unmarshal([]byte(*attributes), &mapping); err != nil {
+			log.Fatalf("Invalid attributes: %v", err)
+		}
+	}
+
+	var sp *domain.SAMLServiceProvider
+	if *update {
+		sp, err = samlService.GetServiceProvider(ctx, *entityID)
+		if err != nil {
+			log.Fatalf("Failed to get service provider: %v", err)
+		}
+		// 指定された項目のみ更新する
+		if *name != "" {
+			sp.Name = *name
+		}
+		if len(uris) > 0 {
+			sp.ACSURLs = uris
+		}
+		if mapping != nil {
+			sp.Attributes = mapping
+		}
+		if *profileAccess != "" {
+			sp.ProfileAccess = domain.FieldVisibility(*profileAccess)
+		}
+		if err := samlService.UpdateServiceProvider(ctx, sp); err != nil {
+			log.Fatalf("Failed to update service provider: %v", err)
+		}
+		fmt.Printf("Successfully updated service provider: %s\n", sp.Name)
+	} else {
+		if *ownerID == "" || *name == "" || len(uris) == 0 {
+			flag.Usage()
+			log.Fatal("Owner ID, Name, and ACS URLs (or metadata) are required for new service providers")
+		}
+		sp, err = samlService.RegisterServiceProvider(ctx, &domain.SAMLServiceProvider{
+			OwnerID:       *ownerID,
+			EntityID:      *entityID,
+			Name:          *name,
+			ACSURLs:       uris,
+			Attributes:    mapping,
+			ProfileAccess: domain.FieldVisibility(*profileAccess),
+		})
+		if err != nil {
+			log.Fatalf("Failed to register service provider: %v", err)
+		}
+		fmt.Printf("Successfully registered service provider: %s\n", sp.Name)
+	}
+
+	fmt.Printf("Entity ID: %s\n", sp.EntityID)
+	fmt.Printf("ACS URLs: %s\n", strings.Join(sp.ACSURLs, ", "))
+	fmt.Printf("IdP metadata: %s\n", samlService.EntityID())
+}
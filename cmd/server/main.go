@@ -2,20 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/jyogi-web/jyogi-discord-auth/internal/config"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/handler"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/middleware"
 	gormRepo "github.com/jyogi-web/jyogi-discord-auth/internal/repository/gorm"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
 	"github.com/jyogi-web/jyogi-discord-auth/pkg/discord"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwt"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/ldap"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/saml"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/studentid"
 )
 
 func main() {
@@ -42,9 +51,22 @@ func main() {
 	userRepo := gormRepo.NewUserRepository(db)
 	sessionRepo := gormRepo.NewSessionRepository(db)
 	clientRepo := gormRepo.NewClientRepository(db)
+	clientSecretRepo := gormRepo.NewClientSecretRepository(db)
+	clientAssertionRepo := gormRepo.NewClientAssertionRepository(db)
 	authCodeRepo := gormRepo.NewAuthCodeRepository(db)
+	deviceCodeRepo := gormRepo.NewDeviceCodeRepository(db)
+	initialAccessTokenRepo := gormRepo.NewInitialAccessTokenRepository(db)
 	tokenRepo := gormRepo.NewTokenRepository(db)
 	profileRepo := gormRepo.NewProfileRepository(db)
+	roleRepo := gormRepo.NewRoleRepository(db)
+	webhookEndpointRepo := gormRepo.NewWebhookEndpointRepository(db)
+	webhookDeliveryRepo := gormRepo.NewWebhookDeliveryRepository(db)
+	searchRepo := gormRepo.NewProfileSearchRepository(db)
+	auditRepo := gormRepo.NewAuditLogRepository(db)
+	cursorRepo := gormRepo.NewSyncCursorRepository(db)
+	introRepo := gormRepo.NewIntroMessageRepository(db)
+	samlSPRepo := gormRepo.NewSAMLServiceProviderRepository(db)
+	appPasswordRepo := gormRepo.NewAppPasswordRepository(db)
 
 	// Discord OAuth2クライアントを初期化
 	discordClient := discord.NewClient(
@@ -53,33 +75,184 @@ func main() {
 		cfg.DiscordRedirectURI,
 	)
 
+	// Webhookとイベントストリーム（変更履歴の記録に合わせてクライアントアプリ・ダッシュボードに通知する）
+	changeHistoryRepo := gormRepo.NewChangeHistoryRepository(db)
+	webhookService := service.NewWebhookService(webhookEndpointRepo, webhookDeliveryRepo, cfg.WebhookAllowPrivateNetworks)
+	eventBus := service.NewEventBus(changeHistoryRepo)
+	historyRepo := service.NewEventHistoryRepository(changeHistoryRepo, service.NewMultiPublisher(webhookService, eventBus))
+
 	// サービスを初期化
 	authService := service.NewAuthService(
 		discordClient,
 		userRepo,
 		sessionRepo,
 		profileRepo,
+		roleRepo,
+		historyRepo,
 		cfg.DiscordGuildID,
+		cfg.OfficerRoleIDs,
 	)
+	// JWT形式のアクセストークン（署名鍵が設定されている場合のみ有効）
+	var jwtAccessTokens *service.JWTAccessTokenIssuer
+	if cfg.OAuthSigningKey != "" {
+		signer, err := jwt.LoadSigner(cfg.OAuthSigningKey)
+		if err != nil {
+			log.Fatalf("Failed to load OAuth signing key: %v", err)
+		}
+		jwtAccessTokens = service.NewJWTAccessTokenIssuer(signer, cfg.PublicBaseURL(), roleRepo)
+	}
 	oauth2Service := service.NewOAuth2Service(
 		clientRepo,
+		clientSecretRepo,
 		authCodeRepo,
+		deviceCodeRepo,
 		tokenRepo,
 		userRepo,
+		webhookService,
+		service.NewClientAssertionVerifier(clientAssertionRepo, cfg.PublicBaseURL()),
+		jwtAccessTokens,
 	)
-	clientService := service.NewClientService(clientRepo)
+	clientService := service.NewClientService(clientRepo, clientSecretRepo)
+	clientRegistrationService := service.NewClientRegistrationService(clientRepo, clientSecretRepo, initialAccessTokenRepo)
+	historyService := service.NewHistoryService(historyRepo, profileRepo)
+	searchService := service.NewSearchService(searchRepo, userRepo, profileRepo, roleRepo)
+	exportService := service.NewExportService(userRepo, profileRepo, roleRepo, auditRepo, cfg.ExportRoleIDs)
 	sessionCleanupService := service.NewSessionCleanupService(
 		sessionRepo,
 		1*time.Hour, // 1時間ごとにクリーンアップ
 	)
-	// プロフィールサービス（もしあれば）
+
+	// プロフィールサービス（Webからのプロフィール編集に使用。同期はsync-profilesで実行）
+	mergeStrategy, err := domain.ParseProfileMergeStrategy(cfg.ProfileMergeStrategy)
+	if err != nil {
+		log.Fatalf("Invalid PROFILE_MERGE_STRATEGY: %v", err)
+	}
+	var profileTemplate *discord.ProfileTemplate
+	if cfg.ProfileTemplatePath != "" {
+		profileTemplate, err = discord.LoadProfileTemplate(cfg.ProfileTemplatePath)
+		if err != nil {
+			log.Fatalf("Failed to load profile template: %v", err)
+		}
+	}
+	var studentIDs *studentid.Parser
+	if cfg.StudentIDPatterns != "" {
+		studentIDs, err = studentid.ParsePatterns([]byte(cfg.StudentIDPatterns))
+		if err != nil {
+			log.Fatalf("Invalid STUDENT_ID_PATTERNS: %v", err)
+		}
+	}
+	profileService := service.NewProfileService(
+		profileRepo,
+		userRepo,
+		roleRepo,
+		cursorRepo,
+		introRepo,
+		historyRepo,
+		searchRepo,
+		cfg.DiscordBotToken,
+		cfg.DiscordGuildID,
+		cfg.DiscordProfileChannel,
+		0,
+		mergeStrategy,
+		profileTemplate,
+		studentIDs,
+	)
+
+	// アプリパスワード（LDAP・HTTP Basic認証などOAuth2に対応していない連携での認証に使用する）
+	appPasswordService := service.NewAppPasswordService(appPasswordRepo, userRepo)
+
+	// フォワード認証（リバースプロキシの背後の内部ツールをDiscordログインとロールで保護する）
+	var accessPolicies domain.AccessPolicies
+	if cfg.ForwardAuthPolicies != "" {
+		accessPolicies, err = domain.ParseAccessPolicies([]byte(cfg.ForwardAuthPolicies))
+		if err != nil {
+			log.Fatalf("Invalid FORWARD_AUTH_POLICIES: %v", err)
+		}
+	}
+	forwardAuthService := service.NewForwardAuthService(authService, appPasswordService, userRepo, roleRepo, cfg.JWTSecret, accessPolicies)
+
+	// 認証プロキシモード（設定されたルートの上流のサービスにログイン済みのリクエストのみ転送する）
+	var proxyRoutes domain.ProxyRoutes
+	if cfg.ProxyRoutes != "" {
+		proxyRoutes, err = domain.ParseProxyRoutes([]byte(cfg.ProxyRoutes))
+		if err != nil {
+			log.Fatalf("Invalid PROXY_ROUTES: %v", err)
+		}
+		// ログインのパスを転送するとログインできなくなるため拒否する
+		if publicURL, err := url.Parse(cfg.PublicBaseURL()); err == nil && proxyRoutes.Match(publicURL.Host, "/auth/callback") != nil {
+			log.Fatalf("Invalid PROXY_ROUTES: a route must not include %s/auth/", publicURL.Host)
+		}
+	}
+	// ログイン後のリダイレクト先として、フォワード認証と認証プロキシモードで保護するホストを許可する
+	redirectPolicies := append(slices.Clone(accessPolicies), proxyRoutes.Policies()...)
+
+	// SAML IdP（署名鍵が設定されている場合のみ有効）
+	var samlService *service.SAMLService
+	if cfg.SAMLSigningKey != "" || cfg.SAMLSigningCert != "" {
+		if cfg.SAMLSigningKey == "" || cfg.SAMLSigningCert == "" {
+			log.Fatal("SAML_SIGNING_KEY and SAML_SIGNING_CERT must be set together")
+		}
+		keyPair, err := saml.LoadKeyPair(cfg.SAMLSigningKey, cfg.SAMLSigningCert)
+		if err != nil {
+			log.Fatalf("Failed to load SAML signing key: %v", err)
+		}
+		samlService = service.NewSAMLService(samlSPRepo, authService, keyPair, cfg.PublicBaseURL())
+	}
+
+	// LDAPサーバー（待ち受けアドレスが設定されている場合のみ有効）
+	var ldapServer *ldap.Server
+	var ldapListener net.Listener
+	if cfg.LDAPListenAddr != "" {
+		baseDN, err := ldap.ParseDN(cfg.LDAPBaseDN)
+		if err != nil || len(baseDN) == 0 {
+			log.Fatalf("Invalid LDAP_BASE_DN %q: %v", cfg.LDAPBaseDN, err)
+		}
+		if (cfg.LDAPTLSCert == "") != (cfg.LDAPTLSKey == "") {
+			log.Fatal("LDAP_TLS_CERT and LDAP_TLS_KEY must be set together")
+		}
+		if cfg.LDAPTLSCert != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.LDAPTLSCert, cfg.LDAPTLSKey)
+			if err != nil {
+				log.Fatalf("Failed to load LDAP TLS certificate: %v", err)
+			}
+			ldapListener, err = tls.Listen("tcp", cfg.LDAPListenAddr, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+			if err != nil {
+				log.Fatalf("Failed to listen for LDAP: %v", err)
+			}
+		} else {
+			ldapListener, err = net.Listen("tcp", cfg.LDAPListenAddr)
+			if err != nil {
+				log.Fatalf("Failed to listen for LDAP: %v", err)
+			}
+			log.Println("Warning: LDAP_TLS_CERT is not set; app passwords are sent in plain text over LDAP")
+		}
+		ldapService := service.NewLDAPService(userRepo, roleRepo, appPasswordService, baseDN)
+		ldapServer = ldap.NewServer(baseDN, ldapService)
+	}
+
+	// 全文検索の索引が空の場合（導入直後）は保存済みのプロフィールから作成する
+	if indexed, err := profileService.EnsureSearchIndex(context.Background()); err != nil {
+		log.Printf("Warning: failed to build search index: %v", err)
+	} else if indexed > 0 {
+		log.Printf("Built search index for %d profiles", indexed)
+	}
 
 	// ハンドラーを初期化
-	authHandler := handler.NewAuthHandler(authService, cfg.CORSAllowedOrigins)
+	authHandler := handler.NewAuthHandler(authService, cfg.CORSAllowedOrigins, redirectPolicies, cfg.SessionCookieDomain)
 	tokenHandler := handler.NewTokenHandler(authService, cfg.JWTSecret)
 	apiHandler := handler.NewAPIHandler(authService)
-	oauth2Handler := handler.NewOAuth2Handler(oauth2Service, authService)
-	clientHandler := handler.NewClientHandler(clientService, authService)
+	oauth2Handler := handler.NewOAuth2Handler(oauth2Service, authService, appPasswordService)
+	deviceHandler := handler.NewDeviceHandler(oauth2Service, authService, cfg.PublicBaseURL()+"/device")
+	clientHandler := handler.NewClientHandler(clientService, authService, webhookService)
+	clientRegistrationHandler := handler.NewClientRegistrationHandler(clientRegistrationService, authService, webhookService, cfg.PublicBaseURL()+"/oauth/register")
+	profileHandler := handler.NewProfileHandler(profileService, authService)
+	appPasswordHandler := handler.NewAppPasswordHandler(appPasswordService, authService)
+	historyHandler := handler.NewHistoryHandler(historyService, authService)
+	searchHandler := handler.NewSearchHandler(searchService, authService)
+	exportHandler := handler.NewExportHandler(exportService, authService, cfg.TrustedProxies)
+	eventsHandler := handler.NewEventsHandler(eventBus, authService)
+	forwardAuthHandler := handler.NewForwardAuthHandler(forwardAuthService, cfg.PublicBaseURL()+"/auth/login", cfg.TrustedProxies)
+	proxyHandler := handler.NewProxyHandler(proxyRoutes, forwardAuthService, cfg.PublicBaseURL()+"/auth/login", cfg.TrustedProxies)
 
 	// セッション認証ミドルウェア
 	sessionAuthMiddleware := middleware.SessionAuth(authService)
@@ -100,8 +273,22 @@ func main() {
 	mux.HandleFunc("/auth/login", authHandler.HandleLogin)
 	mux.HandleFunc("/auth/callback", authHandler.HandleCallback)
 	mux.HandleFunc("/auth/logout", authHandler.HandleLogout)
+	mux.HandleFunc("/auth/forward", forwardAuthHandler.HandleForwardAuth)
 	mux.HandleFunc("/api/me", authHandler.HandleMe)
 	mux.HandleFunc("/api/members", authHandler.HandleMembers)
+	mux.HandleFunc("/api/members/search", searchHandler.HandleSearchMembers)
+	mux.HandleFunc("/api/members/export", exportHandler.HandleExportMembers)
+	mux.HandleFunc("/api/events", eventsHandler.HandleEvents)
+	mux.HandleFunc("/api/me/profile/visibility", authHandler.HandleProfileVisibility)
+
+	// プロフィール編集
+	mux.HandleFunc("/account/profile", profileHandler.HandleAccountProfile)
+	mux.HandleFunc("/account/app-passwords", appPasswordHandler.HandleAppPasswords)
+	mux.HandleFunc("/api/members/{id}/profile", profileHandler.HandleMemberProfile)
+
+	// 変更履歴（幹部のみ）
+	mux.HandleFunc("/api/members/{id}/history", historyHandler.HandleMemberHistory)
+	mux.HandleFunc("/admin/history", historyHandler.HandleAdminHistory)
 
 	// クライアント管理エンドポイント
 	mux.Handle("/clients", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleListClients))) // クライアント一覧
@@ -128,26 +315,61 @@ func main() {
 		}
 	})))
 
+	// Client Secretの管理（所有者と幹部のみ）
+	mux.Handle("POST /clients/{id}/secrets", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleRotateClientSecret)))
+	mux.Handle("POST /clients/{id}/secrets/{secretID}/delete", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleRevokeClientSecret)))
+
+	// Webhookの管理（所有者と幹部のみ）
+	mux.Handle("POST /clients/{id}/webhooks", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleCreateWebhook)))
+	mux.Handle("POST /clients/{id}/webhooks/{webhookID}", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleUpdateWebhook)))
+	mux.Handle("POST /clients/{id}/webhooks/{webhookID}/rotate", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleRotateWebhookSecret)))
+	mux.Handle("POST /clients/{id}/webhooks/{webhookID}/delete", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleDeleteWebhook)))
+	mux.Handle("POST /clients/{id}/webhooks/{webhookID}/deliveries/{deliveryID}/replay", sessionAuthMiddleware(http.HandlerFunc(clientHandler.HandleReplayWebhookDelivery)))
+
 	// トークンエンドポイント
 	mux.HandleFunc("/token", tokenHandler.HandleIssueToken)
 	mux.HandleFunc("/token/refresh", tokenHandler.HandleRefreshToken)
+	mux.HandleFunc("POST /api/clients/initial-access-token", clientRegistrationHandler.HandleIssueInitialAccessToken)
 
 	// OAuth2エンドポイント（クライアントアプリ統合用）
 	mux.HandleFunc("/oauth/authorize", oauth2Handler.HandleAuthorize)
 	mux.HandleFunc("/oauth/token", oauth2Handler.HandleToken)
+	mux.HandleFunc("/oauth/revoke", oauth2Handler.HandleRevoke)
+	mux.HandleFunc("/oauth/introspect", oauth2Handler.HandleIntrospect)
+	mux.HandleFunc("/oauth/device_authorization", deviceHandler.HandleDeviceAuthorization)
+	mux.HandleFunc("/device", deviceHandler.HandleDevice)
+	mux.HandleFunc("/oauth/register", clientRegistrationHandler.HandleRegister)
+	mux.HandleFunc("/oauth/register/{client_id}", clientRegistrationHandler.HandleClientConfiguration)
 	mux.HandleFunc("/oauth/verify", oauth2Handler.HandleVerifyToken)
+	mux.HandleFunc("/oauth/jwks", oauth2Handler.HandleJWKS)
+	mux.HandleFunc("/oauth/revoked", oauth2Handler.HandleRevokedTokens)
 	mux.HandleFunc("/oauth/userinfo", oauth2Handler.HandleUserInfo)
 	mux.HandleFunc("/oauth/user/{id}", oauth2Handler.HandleUserByID)
 	mux.HandleFunc("/oauth/members", oauth2Handler.HandleMembers)
 
-	// JWT認証が必要なAPIエンドポイント
-	jwtAuthMiddleware := middleware.JWTAuth(cfg.JWTSecret)
-	mux.Handle("/api/verify", jwtAuthMiddleware(http.HandlerFunc(apiHandler.HandleVerify)))
-	mux.Handle("/api/user", jwtAuthMiddleware(http.HandlerFunc(apiHandler.HandleUser)))
-	mux.Handle("/api/user/{id}", jwtAuthMiddleware(http.HandlerFunc(apiHandler.HandleUserByID)))
+	// SAML IdPエンドポイント
+	if samlService != nil {
+		samlHandler := handler.NewSAMLHandler(samlService, authService)
+		mux.HandleFunc("/saml/metadata", samlHandler.HandleMetadata)
+		mux.HandleFunc("/saml/sso", samlHandler.HandleSSO)
+		log.Printf("SAML IdP enabled: %s", samlService.EntityID())
+	} else {
+		log.Println("SAML IdP disabled (SAML_SIGNING_KEY is not set)")
+	}
+
+	// JWT（またはアプリパスワードのBasic認証）が必要なAPIエンドポイント
+	profileAuthMiddleware := middleware.ResourceAuth(cfg.JWTSecret, appPasswordService, domain.AppPasswordScopeProfile)
+	membersAuthMiddleware := middleware.ResourceAuth(cfg.JWTSecret, appPasswordService, domain.AppPasswordScopeMembers)
+	mux.Handle("/api/verify", profileAuthMiddleware(http.HandlerFunc(apiHandler.HandleVerify)))
+	mux.Handle("/api/user", profileAuthMiddleware(http.HandlerFunc(apiHandler.HandleUser)))
+	mux.Handle("/api/user/{id}", membersAuthMiddleware(http.HandlerFunc(apiHandler.HandleUserByID)))
 
 	// ミドルウェアを適用
 	handler := middleware.CORS(cfg.CORSAllowedOrigins)(mux)
+	if len(proxyRoutes) > 0 {
+		// 転送するリクエストにはこのサーバーのCORSヘッダーを付けない
+		handler = proxyHandler.Wrap(handler)
+	}
 	handler = middleware.Logging(handler)
 	handler = middleware.HTTPSOnly(cfg.HTTPSOnly)(handler)
 
@@ -159,6 +381,8 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// シャットダウン時にイベントストリームの接続を終了する（終了しないとShutdownが完了しない）
+	server.RegisterOnShutdown(eventBus.Close)
 
 	// セッションクリーンアップ用のコンテキスト
 	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
@@ -167,6 +391,12 @@ func main() {
 	// バックグラウンドでセッションクリーンアップを開始
 	go sessionCleanupService.Start(cleanupCtx)
 
+	// バックグラウンドでWebhookの配信を開始（sync-profilesで記録された配信もここで送信する）
+	go webhookService.Start(cleanupCtx)
+
+	// バックグラウンドでsync-profilesなど別のプロセスで記録された変更をイベントストリームに配信
+	go eventBus.Start(cleanupCtx)
+
 	// ゴルーチンでサーバーを起動
 	go func() {
 		log.Printf("Server listening on port %s", cfg.ServerPort)
@@ -175,6 +405,16 @@ func main() {
 		}
 	}()
 
+	// ゴルーチンでLDAPサーバーを起動
+	if ldapServer != nil {
+		go func() {
+			log.Printf("LDAP server listening on %s (base DN: %s)", ldapListener.Addr(), cfg.LDAPBaseDN)
+			if err := ldapServer.Serve(ldapListener); err != nil {
+				log.Printf("LDAP server stopped: %v", err)
+			}
+		}()
+	}
+
 	// グレースフルシャットダウン
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -182,6 +422,10 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	if ldapServer != nil {
+		ldapServer.Close()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -11,14 +11,22 @@ import (
 	"time"
 
 	"github.com/jyogi-web/jyogi-discord-auth/internal/config"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
 	gormRepo "github.com/jyogi-web/jyogi-discord-auth/internal/repository/gorm"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/discord"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/studentid"
 )
 
 func main() {
 	// コマンドラインフラグを定義
 	once := flag.Bool("once", false, "Run profile sync once and exit")
 	intervalMinutes := flag.Int("interval", 60, "Sync interval in minutes (default: 60)")
+	roster := flag.Bool("roster", true, "Also sync guild roles and members (requires Server Members Intent)")
+	full := flag.Bool("full", false, "Force a full rescan of the intro channel (with -once)")
+	fullSyncHours := flag.Int("full-sync-interval", 24, "Interval in hours between full rescans that catch edits and deletions")
+	gateway := flag.Bool("gateway", false, "Listen to Discord Gateway events for real-time updates (polling continues as a fallback)")
+	reindex := flag.Bool("reindex", false, "Rebuild the full-text search index from stored profiles on startup")
 	flag.Parse()
 
 	// 設定を読み込む
@@ -44,21 +52,99 @@ func main() {
 	// リポジトリを作成 (GORM)
 	profileRepo := gormRepo.NewProfileRepository(db)
 	userRepo := gormRepo.NewUserRepository(db)
+	roleRepo := gormRepo.NewRoleRepository(db)
+	cursorRepo := gormRepo.NewSyncCursorRepository(db)
+	introRepo := gormRepo.NewIntroMessageRepository(db)
+	// Webhookの配信は記録のみ行い、送信はサーバーのワーカーが行う
+	webhookService := service.NewWebhookService(
+		gormRepo.NewWebhookEndpointRepository(db),
+		gormRepo.NewWebhookDeliveryRepository(db),
+		cfg.WebhookAllowPrivateNetworks,
+	)
+	historyRepo := service.NewEventHistoryRepository(gormRepo.NewChangeHistoryRepository(db), webhookService)
+	searchRepo := gormRepo.NewProfileSearchRepository(db)
+
+	mergeStrategy, err := domain.ParseProfileMergeStrategy(cfg.ProfileMergeStrategy)
+	if err != nil {
+		log.Fatalf("Invalid PROFILE_MERGE_STRATEGY: %v", err)
+	}
+
+	// 自己紹介テンプレートを読み込む（未設定の場合は標準テンプレート）
+	var template *discord.ProfileTemplate
+	if cfg.ProfileTemplatePath != "" {
+		template, err = discord.LoadProfileTemplate(cfg.ProfileTemplatePath)
+		if err != nil {
+			log.Fatalf("Failed to load profile template: %v", err)
+		}
+	}
+
+	// 学籍番号の形式定義を読み込む（未設定の場合は標準の形式）
+	var studentIDs *studentid.Parser
+	if cfg.StudentIDPatterns != "" {
+		studentIDs, err = studentid.ParsePatterns([]byte(cfg.StudentIDPatterns))
+		if err != nil {
+			log.Fatalf("Invalid STUDENT_ID_PATTERNS: %v", err)
+		}
+	}
+
+	// ロスター同期を行わない場合はギルドIDを渡さない
+	guildID := cfg.DiscordGuildID
+	if !*roster {
+		guildID = ""
+	}
 
 	// プロフィールサービスを作成
 	profileService := service.NewProfileService(
 		profileRepo,
 		userRepo,
+		roleRepo,
+		cursorRepo,
+		introRepo,
+		historyRepo,
+		searchRepo,
 		cfg.DiscordBotToken,
+		guildID,
 		cfg.DiscordProfileChannel,
+		time.Duration(*fullSyncHours)*time.Hour,
+		mergeStrategy,
+		template,
+		studentIDs,
 	)
 
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 保存済みプロフィールの学籍番号を現在の形式定義で解析し直す
+	if updated, err := profileService.RefreshStudentIDs(ctx); err != nil {
+		log.Printf("Failed to refresh student IDs: %v", err)
+	} else if updated > 0 {
+		log.Printf("Refreshed student IDs of %d profiles", updated)
+	}
+
+	// 全文検索の索引を作成する（-reindexの場合は作り直し、それ以外は索引が空の場合のみ）
+	buildIndex := profileService.EnsureSearchIndex
+	if *reindex {
+		buildIndex = profileService.RebuildSearchIndex
+	}
+	if indexed, err := buildIndex(ctx); err != nil {
+		log.Printf("Failed to build search index: %v", err)
+	} else if indexed > 0 {
+		log.Printf("Indexed %d profiles for full-text search", indexed)
+	}
 
 	if *once {
 		// 1回だけ実行
 		log.Println("Running profile sync once...")
-		if err := profileService.SyncProfiles(ctx); err != nil {
+		if *full {
+			if guildID != "" {
+				if err := profileService.SyncRoster(ctx); err != nil {
+					log.Printf("Roster sync failed: %v", err)
+				}
+			}
+			if err := profileService.SyncProfilesFull(ctx); err != nil {
+				log.Fatalf("Profile sync failed: %v", err)
+			}
+		} else if err := profileService.SyncAll(ctx); err != nil {
 			log.Fatalf("Profile sync failed: %v", err)
 		}
 		log.Println("Profile sync completed successfully")
@@ -76,6 +162,17 @@ func main() {
 	// スケジューラーをゴルーチンで起動
 	go scheduler.Start(ctx)
 
+	// Gatewayモードではイベントをリアルタイムに反映する（ポーリングは取りこぼしの補完として継続）
+	if *gateway {
+		gw := discord.NewGateway(cfg.DiscordBotToken, profileService.GatewayIntents(), profileService.GatewayHandlers())
+		go func() {
+			log.Println("Starting Discord Gateway listener...")
+			if err := gw.Run(ctx); err != nil {
+				log.Printf("Gateway listener stopped: %v (continuing with polling only)", err)
+			}
+		}()
+	}
+
 	// シグナルを待つ
 	sig := <-sigChan
 	fmt.Printf("\nReceived signal: %v\n", sig)
@@ -83,6 +180,7 @@ func main() {
 	// グレースフルシャットダウン
 	log.Println("Shutting down...")
 	scheduler.Stop()
+	cancel()
 	time.Sleep(1 * time.Second)
 	log.Println("Shutdown complete")
 }
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -16,10 +18,15 @@ type Config struct {
 	DiscordClientSecret string
 	DiscordRedirectURI  string
 	DiscordGuildID      string
+	OfficerRoleIDs      []string // 幹部として扱うDiscordロールID（プロフィールの幹部限定項目の閲覧に使用）
+	ExportRoleIDs       []string // メンバー一覧のエクスポートを許可するDiscordロールID（未設定の場合は幹部）
 
 	// Discord Bot
 	DiscordBotToken       string
 	DiscordProfileChannel string
+	ProfileMergeStrategy  string // newest または merge
+	ProfileTemplatePath   string // 自己紹介テンプレート（フィールド定義ファイル）のパス
+	StudentIDPatterns     string // 学籍番号の形式定義（JSON配列）
 
 	// JWT
 	JWTSecret string
@@ -37,12 +44,36 @@ type Config struct {
 	DisableAutoMigrate bool
 
 	// Server
-	ServerPort string
-	HTTPSOnly  bool
+	ServerPort     string
+	HTTPSOnly      bool
+	TrustedProxies []netip.Prefix // X-Forwarded-For・X-Forwarded-Protoなどのヘッダーを信頼する前段のプロキシのアドレス（未設定の場合は信頼しない）
 
 	// CORS
 	CORSAllowedOrigins []string
 
+	// Session
+	SessionCookieDomain string // セッションCookieのDomain属性（フォワード認証で保護するサブドメインとセッションを共有する場合に指定）
+
+	// Forward auth
+	ForwardAuthPolicies string // フォワード認証で保護するホスト・パスとアクセスを許可するロール（JSON配列）
+	ProxyRoutes         string // 認証プロキシモードで転送するルート（JSON配列。未設定の場合はプロキシモードを使用しない）
+
+	// OAuth2
+	OAuthSigningKey string // JWT形式のアクセストークンに署名するRSA秘密鍵（PEMまたはファイルのパス。未設定の場合はJWT形式を使用しない）
+
+	// SAML
+	SAMLSigningKey  string // SAMLのアサーションに署名する秘密鍵（PEMまたはファイルのパス。未設定の場合はSAMLを使用しない）
+	SAMLSigningCert string // 署名鍵の証明書（PEMまたはファイルのパス）
+
+	// LDAP
+	LDAPListenAddr string // LDAPサーバーの待ち受けアドレス（例: :3389。未設定の場合はLDAPサーバーを起動しない）
+	LDAPBaseDN     string // LDAPディレクトリの最上位の識別名（既定は dc=jyogi）
+	LDAPTLSCert    string // LDAPSで使用する証明書ファイルのパス
+	LDAPTLSKey     string // LDAPSで使用する秘密鍵ファイルのパス
+
+	// Webhook
+	WebhookAllowPrivateNetworks bool // プライベートIPアドレス・ループバックアドレスへのWebhookの送信を許可するか（開発用）
+
 	// Environment
 	Env string
 }
@@ -75,8 +106,13 @@ func Load() (*Config, error) {
 		DiscordClientSecret:   discordCfg.ClientSecret,
 		DiscordRedirectURI:    discordCfg.RedirectURI,
 		DiscordGuildID:        discordCfg.GuildID,
+		OfficerRoleIDs:        parseIDList(os.Getenv("OFFICER_ROLE_IDS")),
+		ExportRoleIDs:         parseIDList(os.Getenv("EXPORT_ROLE_IDS")),
 		DiscordBotToken:       discordCfg.BotToken,
 		DiscordProfileChannel: os.Getenv("DISCORD_PROFILE_CHANNEL"),
+		ProfileMergeStrategy:  os.Getenv("PROFILE_MERGE_STRATEGY"),
+		ProfileTemplatePath:   os.Getenv("PROFILE_TEMPLATE_PATH"),
+		StudentIDPatterns:     os.Getenv("STUDENT_ID_PATTERNS"),
 		JWTSecret:             discordCfg.JWTSecret,
 		DatabasePath:          os.Getenv("DATABASE_PATH"),
 		TiDBHost:              tidbCfg.Host,
@@ -86,6 +122,16 @@ func Load() (*Config, error) {
 		TiDBDatabase:          tidbCfg.Database,
 		ServerPort:            os.Getenv("SERVER_PORT"),
 		CORSAllowedOrigins:    parseCORSOrigins(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		SessionCookieDomain:   strings.TrimSpace(os.Getenv("SESSION_COOKIE_DOMAIN")),
+		ForwardAuthPolicies:   os.Getenv("FORWARD_AUTH_POLICIES"),
+		ProxyRoutes:           os.Getenv("PROXY_ROUTES"),
+		OAuthSigningKey:       os.Getenv("OAUTH_SIGNING_KEY"),
+		SAMLSigningKey:        os.Getenv("SAML_SIGNING_KEY"),
+		SAMLSigningCert:       os.Getenv("SAML_SIGNING_CERT"),
+		LDAPListenAddr:        strings.TrimSpace(os.Getenv("LDAP_LISTEN_ADDR")),
+		LDAPBaseDN:            strings.TrimSpace(os.Getenv("LDAP_BASE_DN")),
+		LDAPTLSCert:           os.Getenv("LDAP_TLS_CERT"),
+		LDAPTLSKey:            os.Getenv("LDAP_TLS_KEY"),
 		Env:                   os.Getenv("ENV"),
 	}
 
@@ -116,6 +162,22 @@ func Load() (*Config, error) {
 		cfg.DisableAutoMigrate = disableAutoMigrate
 	}
 
+	// WEBHOOK_ALLOW_PRIVATE_NETWORKSをbooleanとしてパース
+	allowPrivate, err := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"))
+	if err != nil {
+		// 設定されていないか不正な場合はfalseをデフォルトとする（SSRF対策として拒否）
+		cfg.WebhookAllowPrivateNetworks = false
+	} else {
+		cfg.WebhookAllowPrivateNetworks = allowPrivate
+	}
+
+	// TRUSTED_PROXIESをIPアドレス・CIDRの一覧としてパース
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.TrustedProxies = trustedProxies
+
 	// デフォルト値を設定
 	if cfg.DatabasePath == "" {
 		cfg.DatabasePath = "./jyogi_auth.db"
@@ -129,6 +191,12 @@ func Load() (*Config, error) {
 	if cfg.Env == "" {
 		cfg.Env = "development"
 	}
+	if cfg.ProfileMergeStrategy == "" {
+		cfg.ProfileMergeStrategy = "newest"
+	}
+	if cfg.LDAPBaseDN == "" {
+		cfg.LDAPBaseDN = "dc=jyogi"
+	}
 
 	// CORS設定のデフォルト値
 	if len(cfg.CORSAllowedOrigins) == 0 {
@@ -171,6 +239,44 @@ func parseCORSOrigins(origins string) []string {
 	return result
 }
 
+// parseIDList はカンマ区切りのIDをパースします
+func parseIDList(ids string) []string {
+	var result []string
+	for _, part := range strings.Split(ids, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
+// parseTrustedProxies はカンマ区切りのIPアドレス・CIDRをパースします
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var result []netip.Prefix
+	for _, part := range parseIDList(value) {
+		if prefix, err := netip.ParsePrefix(part); err == nil {
+			result = append(result, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES contains an invalid address: %s", part)
+		}
+		result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return result, nil
+}
+
+// PublicBaseURL はこのサーバーの公開URL（スキームとホスト）を返します
+// DISCORD_REDIRECT_URIのオリジンを使用し、取得できない場合は空文字列を返します
+func (c *Config) PublicBaseURL() string {
+	u, err := url.Parse(c.DiscordRedirectURI)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
 // Validate は必須設定がすべて存在することを確認します
 func (c *Config) Validate() error {
 	if c.DiscordClientID == "" {
@@ -192,6 +298,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT_SECRET must be at least 32 characters long")
 	}
 
+	if c.ProfileMergeStrategy != "" && c.ProfileMergeStrategy != "newest" && c.ProfileMergeStrategy != "merge" {
+		return fmt.Errorf("PROFILE_MERGE_STRATEGY must be either newest or merge")
+	}
+
 	// TiDBの設定バリデーション
 	// 環境変数が設定されていない場合はエラーにする（移行のため必須）
 	if c.TiDBHost == "" {
@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// AccessPolicy は認証プロキシ（フォワード認証）で保護するホスト・パスへのアクセス条件です
+type AccessPolicy struct {
+	Host       string   `json:"host"`        // ホスト名（"*.example.com" で全サブドメイン）
+	PathPrefix string   `json:"path_prefix"` // 対象とするパスの接頭辞（空の場合はすべてのパス）
+	Roles      []string `json:"roles"`       // アクセスを許可するロールのIDまたは名前（空の場合は在籍中のメンバー全員）
+}
+
+// AccessPolicies はホスト・パスごとのアクセス条件の一覧です
+type AccessPolicies []*AccessPolicy
+
+// ParseAccessPolicies はJSON配列からアクセス条件の一覧を読み込みます
+func ParseAccessPolicies(data []byte) (AccessPolicies, error) {
+	var policies AccessPolicies
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAccessPolicy, err)
+	}
+	for i, policy := range policies {
+		if policy == nil {
+			return nil, fmt.Errorf("%w: policy %d is empty", ErrInvalidAccessPolicy, i)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i, err)
+		}
+	}
+	return policies, nil
+}
+
+// Validate はアクセス条件が有効かどうかを確認し、ホスト名を小文字にそろえます
+func (p *AccessPolicy) Validate() error {
+	host := strings.ToLower(strings.TrimSpace(p.Host))
+	pattern := strings.TrimPrefix(host, "*.")
+	if pattern == "" || strings.ContainsAny(pattern, "*:/ ") {
+		return fmt.Errorf("%w: invalid host %q", ErrInvalidAccessPolicy, p.Host)
+	}
+	if p.PathPrefix != "" && !strings.HasPrefix(p.PathPrefix, "/") {
+		return fmt.Errorf("%w: path_prefix must start with /: %q", ErrInvalidAccessPolicy, p.PathPrefix)
+	}
+	p.Host = host
+	return nil
+}
+
+// MatchesHost はホスト名（ポートを含んでもよい）がアクセス条件の対象かどうかを返します
+func (p *AccessPolicy) MatchesHost(host string) bool {
+	host = NormalizeHost(host)
+	if suffix, ok := strings.CutPrefix(p.Host, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == p.Host
+}
+
+// MatchesPath はパスがアクセス条件の対象かどうかを返します（"/wiki" は "/wiki" と "/wiki/..." に一致し、"/wikis" には一致しません）
+func (p *AccessPolicy) MatchesPath(path string) bool {
+	if p.PathPrefix == "" || p.PathPrefix == "/" {
+		return true
+	}
+	prefix := strings.TrimSuffix(p.PathPrefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// CleanRequestPath はアクセス条件の照合に使用するパスを正規化します
+// escapedPathはパーセントエンコードされたままのパスです。ドットセグメント（"/public/../admin"）を解決し、
+// エンコードされたスラッシュ・バックスラッシュ（%2F・%5C）や二重にエンコードしたドット・スラッシュを含むパスは、
+// 上流のサービスで別のパスとして解釈されうるためErrInvalidRequestPathを返します
+func CleanRequestPath(escapedPath string) (string, error) {
+	if containsEncodedSeparator(escapedPath) {
+		return "", fmt.Errorf("%w: encoded separator in %q", ErrInvalidRequestPath, escapedPath)
+	}
+	decoded, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRequestPath, err)
+	}
+	if strings.Contains(decoded, "\\") || containsEncodedSeparator(decoded) || strings.Contains(strings.ToLower(decoded), "%2e") {
+		return "", fmt.Errorf("%w: ambiguous path %q", ErrInvalidRequestPath, escapedPath)
+	}
+
+	cleaned := path.Clean("/" + decoded)
+	if strings.HasSuffix(decoded, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	if slices.Contains(strings.Split(cleaned, "/"), "..") {
+		return "", fmt.Errorf("%w: dot segment in %q", ErrInvalidRequestPath, escapedPath)
+	}
+	return cleaned, nil
+}
+
+// containsEncodedSeparator はパスにエンコードされたスラッシュ・バックスラッシュを含むかどうかを返します
+func containsEncodedSeparator(p string) bool {
+	lower := strings.ToLower(p)
+	return strings.Contains(lower, "%2f") || strings.Contains(lower, "%5c")
+}
+
+// Allows はユーザーがアクセスを許可されているかどうかを返します（退出したメンバーは常に拒否します）
+// rolesはユーザーのロール（名前での指定と照合するために使用します）
+func (p *AccessPolicy) Allows(user *User, roles []*Role) bool {
+	if user == nil || !user.IsGuildMember() {
+		return false
+	}
+	if len(p.Roles) == 0 {
+		return true
+	}
+	for _, roleID := range user.GuildRoles {
+		if slices.Contains(p.Roles, roleID) {
+			return true
+		}
+	}
+	for _, role := range roles {
+		if slices.ContainsFunc(p.Roles, func(name string) bool { return strings.EqualFold(name, role.Name) }) && slices.Contains(user.GuildRoles, role.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match はホストとパスに適用するアクセス条件を返します（該当する条件がない場合はnil）
+// 完全一致のホストはワイルドカードより優先し、同じホストではパスの接頭辞が最も長いものを適用します
+func (p AccessPolicies) Match(host, path string) *AccessPolicy {
+	var matched *AccessPolicy
+	for _, policy := range p {
+		if !policy.MatchesHost(host) || !policy.MatchesPath(path) {
+			continue
+		}
+		if matched == nil || policy.moreSpecificThan(matched) {
+			matched = policy
+		}
+	}
+	return matched
+}
+
+// HasHost はホスト名がいずれかのアクセス条件の対象かどうかを返します（ログイン後のリダイレクト先の検証に使用します）
+func (p AccessPolicies) HasHost(host string) bool {
+	return slices.ContainsFunc(p, func(policy *AccessPolicy) bool { return policy.MatchesHost(host) })
+}
+
+// moreSpecificThan はアクセス条件がotherより限定的かどうかを返します
+func (p *AccessPolicy) moreSpecificThan(other *AccessPolicy) bool {
+	wildcard, otherWildcard := strings.HasPrefix(p.Host, "*."), strings.HasPrefix(other.Host, "*.")
+	if wildcard != otherWildcard {
+		return !wildcard
+	}
+	if len(p.Host) != len(other.Host) {
+		return len(p.Host) > len(other.Host)
+	}
+	return len(strings.TrimSuffix(p.PathPrefix, "/")) > len(strings.TrimSuffix(other.PathPrefix, "/"))
+}
+
+// NormalizeHost はホスト名からポートと末尾のドットを取り除き、小文字にそろえます
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxAppPasswordsPerUser はユーザーごとに発行できるアプリパスワードの上限です
+const MaxAppPasswordsPerUser = 20
+
+// アプリパスワードのスコープ（パスワードで認証できる連携の種類）
+const (
+	// AppPasswordScopeProfile は自分のユーザー情報の取得（/api/user・/api/verify・/oauth/userinfo）です
+	AppPasswordScopeProfile = "profile"
+	// AppPasswordScopeMembers は他のメンバーの情報の取得（/api/user/{id}・/oauth/user/{id}・/oauth/members）です
+	AppPasswordScopeMembers = "members"
+	// AppPasswordScopeLDAP はLDAPサーバーへのBindです
+	AppPasswordScopeLDAP = "ldap"
+	// AppPasswordScopeProxy はフォワード認証・認証プロキシで保護するサイトへのHTTP Basic認証です
+	AppPasswordScopeProxy = "proxy"
+)
+
+// AppPasswordScopes は発行できるスコープの一覧です（画面の表示順）
+var AppPasswordScopes = []string{AppPasswordScopeLDAP, AppPasswordScopeProxy, AppPasswordScopeProfile, AppPasswordScopeMembers}
+
+// AppPassword はDiscordログインを使えないツール（LDAP・HTTP Basic認証など）でログインするための、ユーザーごとのパスワードです
+// パスワードは「<ID>.<秘密の部分>」の形式で発行時に一度だけ表示し、秘密の部分をbcryptでハッシュ化して保存します
+type AppPassword struct {
+	ID             string
+	UserID         string
+	Name           string   // 用途を区別するための名前（例: Gitea）
+	Scopes         []string // 認証に使用できる連携（AppPasswordScopes）
+	HashedPassword string
+	CreatedAt      time.Time
+	ExpiresAt      *time.Time // nilの場合は無期限
+	LastUsedAt     *time.Time
+}
+
+// Validate はアプリパスワードのデータが有効かどうかを確認します
+func (p *AppPassword) Validate() error {
+	if p.UserID == "" {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidAppPassword)
+	}
+	name := strings.TrimSpace(p.Name)
+	if name == "" || utf8.RuneCountInString(name) > 64 {
+		return fmt.Errorf("%w: name must be 1-64 characters", ErrInvalidAppPassword)
+	}
+	if len(p.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidAppPassword)
+	}
+	for _, scope := range p.Scopes {
+		if !slices.Contains(AppPasswordScopes, scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidAppPassword, scope)
+		}
+	}
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(p.CreatedAt) {
+		return fmt.Errorf("%w: expires_at must be after created_at", ErrInvalidAppPassword)
+	}
+	if p.HashedPassword == "" {
+		return fmt.Errorf("%w: hashed_password is required", ErrInvalidAppPassword)
+	}
+	return nil
+}
+
+// HasScope はアプリパスワードがスコープの連携に使用できるかどうかを確認します
+func (p *AppPassword) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// IsExpired はアプリパスワードの有効期限が切れているかどうかを確認します
+func (p *AppPassword) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// FormatAppPassword はアプリパスワードのIDと秘密の部分から、利用者に渡すパスワードを作成します
+func FormatAppPassword(id, secret string) string {
+	return id + "." + secret
+}
+
+// ParseAppPassword はパスワードをアプリパスワードのIDと秘密の部分に分けます（形式が異なる場合はfalse）
+// 認証時にIDで1件のアプリパスワードを取得し、そのハッシュだけを検証するために使用します
+func ParseAppPassword(password string) (id, secret string, ok bool) {
+	id, secret, ok = strings.Cut(password, ".")
+	return id, secret, ok && id != "" && secret != ""
+}
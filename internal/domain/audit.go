@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// 監査ログに記録する操作
+const (
+	// AuditActionMemberExport はメンバー一覧のエクスポートです
+	AuditActionMemberExport = "member.export"
+)
+
+// 監査ログに記録する操作の経路
+const (
+	// AuditViaWeb はWeb（セッション）からの操作です
+	AuditViaWeb = "web"
+	// AuditViaCLI はコマンドラインツールからの操作です
+	AuditViaCLI = "cli"
+)
+
+// AuditActor は監査ログに記録する操作の主体です
+type AuditActor struct {
+	UserID     string // 操作したユーザーのID
+	Via        string // 操作の経路（web / cli）
+	RemoteAddr string // 操作元のIPアドレス（Webの場合）
+}
+
+// AuditEntry は監査ログの1件です
+// 個人情報の持ち出しなど、後から確認が必要な操作を記録します
+type AuditEntry struct {
+	ID          string
+	Action      string
+	ActorUserID string
+	Via         string
+	RemoteAddr  string
+	Details     map[string]string // 操作の内容（形式・項目・条件など）
+	CreatedAt   time.Time
+}
@@ -3,9 +3,45 @@ package domain
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 )
 
+// クライアントが使用できるグラント（grant_type）
+const (
+	// GrantTypeAuthorizationCode は認可コードグラントです
+	GrantTypeAuthorizationCode = "authorization_code"
+	// GrantTypeDeviceCode はデバイス認可グラント（RFC 8628）です
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// ClientGrantTypes は登録できるグラントの一覧です
+var ClientGrantTypes = []string{GrantTypeAuthorizationCode, GrantTypeDeviceCode}
+
+// クライアントのスコープ（発行したアクセストークンで取得できる情報）
+// 値は同じ情報を取得できるアプリパスワードのスコープと同じです
+const (
+	// ClientScopeProfile はトークンのユーザー自身の情報の取得（/oauth/userinfo）です
+	ClientScopeProfile = AppPasswordScopeProfile
+	// ClientScopeMembers は他のメンバーの情報の取得（/oauth/user/{id}・/oauth/members）です
+	ClientScopeMembers = AppPasswordScopeMembers
+)
+
+// ClientScopes は登録できるスコープの一覧です
+var ClientScopes = []string{ClientScopeProfile, ClientScopeMembers}
+
+// クライアントに発行するアクセストークンの形式
+const (
+	// AccessTokenFormatOpaque はランダムな文字列のアクセストークンです（検証にはこのサーバーへの問い合わせが必要です）
+	AccessTokenFormatOpaque = "opaque"
+	// AccessTokenFormatJWT はサーバーの鍵で署名したJWT（RFC 9068）のアクセストークンです
+	// リソースサーバーは公開鍵で署名を検証し、取り消しは取り消し済みトークンの一覧で確認します
+	AccessTokenFormatJWT = "jwt"
+)
+
+// AccessTokenFormats は選択できるアクセストークンの形式の一覧です
+var AccessTokenFormats = []string{AccessTokenFormatOpaque, AccessTokenFormatJWT}
+
 // ClientApp はこの認証サーバーを使用するアプリケーション（SSO用）を表します
 type ClientApp struct {
 	ID           string
@@ -14,8 +50,27 @@ type ClientApp struct {
 	ClientSecret string // bcryptでハッシュ化
 	Name         string
 	RedirectURIs []string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// ProfileAccess はこのクライアントに返すプロフィール項目の公開範囲の上限です（membersまたはofficers）
+	// officersの場合でも、幹部ロールを持つユーザーのトークンでなければ幹部限定の項目は返しません
+	ProfileAccess FieldVisibility
+	// GrantTypes・Scopes はクライアントが使用できるグラントとスコープです
+	// 空の場合はすべて使用できます（動的登録の導入前に登録したクライアント）
+	GrantTypes []string
+	Scopes     []string
+	LogoURI    string
+	// TokenEndpointAuthMethod は登録時に指定したトークンエンドポイントでのクライアント認証方式です（空の場合はclient_secret_post、noneは公開クライアント）
+	// client_secret_post・client_secret_basicはどちらを指定しても両方使用できます
+	TokenEndpointAuthMethod string
+	// JWKS・JWKSURI はprivate_key_jwtで署名を検証する公開鍵（JWKSのJSONまたはその取得先のURL）です
+	JWKS    string
+	JWKSURI string
+	// AccessTokenFormat はこのクライアントに発行するアクセストークンの形式です（空の場合はopaque）
+	AccessTokenFormat string
+	// RegistrationAccessToken は動的登録したクライアントの管理（RFC 7592）に使用するトークンです（bcryptでハッシュ化）
+	// 画面から登録したクライアントは空です
+	RegistrationAccessToken string
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
 }
 
 // Validate はクライアントアプリのデータが有効かどうかを確認します
@@ -23,18 +78,96 @@ func (c *ClientApp) Validate() error {
 	if c.ClientID == "" {
 		return fmt.Errorf("client_id is required")
 	}
-	if c.ClientSecret == "" {
+	switch {
+	case c.UsesPrivateKeyJWT():
+		if c.JWKS == "" && c.JWKSURI == "" {
+			return fmt.Errorf("jwks or jwks_uri is required for private_key_jwt")
+		}
+	case c.IsPublic():
+		if !usesOnlyDeviceCodeGrant(c.GrantTypes) {
+			return fmt.Errorf("public clients must use only the device_code grant")
+		}
+	case c.ClientSecret == "":
 		return fmt.Errorf("client_secret is required")
 	}
 	if c.Name == "" {
 		return fmt.Errorf("name is required")
 	}
-	if len(c.RedirectURIs) == 0 {
+	if len(c.RedirectURIs) == 0 && c.AllowsGrantType(GrantTypeAuthorizationCode) {
 		return fmt.Errorf("at least one redirect_uri is required")
 	}
+	for _, grantType := range c.GrantTypes {
+		if !slices.Contains(ClientGrantTypes, grantType) {
+			return fmt.Errorf("unsupported grant_type %q", grantType)
+		}
+	}
+	for _, scope := range c.Scopes {
+		if !slices.Contains(ClientScopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if c.ProfileAccess != "" && c.ProfileAccess != VisibilityMembers && c.ProfileAccess != VisibilityOfficers {
+		return fmt.Errorf("profile_access must be members or officers")
+	}
+	if c.AccessTokenFormat != "" && !slices.Contains(AccessTokenFormats, c.AccessTokenFormat) {
+		return fmt.Errorf("unsupported access_token_format %q", c.AccessTokenFormat)
+	}
 	return nil
 }
 
+// UsesPrivateKeyJWT はクライアントがClient Secretの代わりにprivate_key_jwtで認証するかどうかを返します
+func (c *ClientApp) UsesPrivateKeyJWT() bool {
+	return c.TokenEndpointAuthMethod == TokenEndpointAuthMethodPrivateKeyJWT
+}
+
+// AccessTokenFormatOrDefault はアクセストークンの形式を返します（未設定の場合はopaque）
+func (c *ClientApp) AccessTokenFormatOrDefault() string {
+	if c.AccessTokenFormat == "" {
+		return AccessTokenFormatOpaque
+	}
+	return c.AccessTokenFormat
+}
+
+// ProfileAccessOrDefault はプロフィール項目の公開範囲の上限を返します（未設定の場合はmembers）
+func (c *ClientApp) ProfileAccessOrDefault() FieldVisibility {
+	if c.ProfileAccess == "" {
+		return VisibilityMembers
+	}
+	return c.ProfileAccess
+}
+
+// IsPublic はクライアントがClient Secretを持たない公開クライアント（token_endpoint_auth_methodがnone）かどうかを返します
+// 公開クライアントはclient_idのみで識別し、デバイス認可グラントにのみ使用できます
+func (c *ClientApp) IsPublic() bool {
+	return c.TokenEndpointAuthMethod == TokenEndpointAuthMethodNone
+}
+
+// AllowsGrantType はクライアントがグラントを使用できるかどうかを確認します
+func (c *ClientApp) AllowsGrantType(grantType string) bool {
+	return len(c.GrantTypes) == 0 || slices.Contains(c.GrantTypes, grantType)
+}
+
+// EffectiveScopes はクライアントに発行したアクセストークンの実際のスコープを返します
+// スコープを登録していないクライアントは、登録できるすべてのスコープを持つものとして扱います
+func (c *ClientApp) EffectiveScopes() []string {
+	if len(c.Scopes) == 0 {
+		return ClientScopes
+	}
+	return c.Scopes
+}
+
+// AllowsScope はクライアントに発行したアクセストークンでスコープの情報を取得できるかどうかを確認します
+func (c *ClientApp) AllowsScope(scope string) bool {
+	return slices.Contains(c.EffectiveScopes(), scope)
+}
+
+// usesOnlyDeviceCodeGrant はグラントの一覧がデバイス認可グラントだけかどうかを返します
+func usesOnlyDeviceCodeGrant(grantTypes []string) bool {
+	return len(grantTypes) > 0 && !slices.ContainsFunc(grantTypes, func(grantType string) bool {
+		return grantType != GrantTypeDeviceCode
+	})
+}
+
 // RedirectURIsToJSON はリダイレクトURIのスライスを保存用のJSON文字列に変換します
 func (c *ClientApp) RedirectURIsToJSON() (string, error) {
 	data, err := json.Marshal(c.RedirectURIs)
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// ClientAssertionTypeJWTBearer はclient_assertion_typeに指定する署名付きJWTの種類です（RFC 7523 2.2）
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// MaxClientAssertionLifetime はclient_assertionの有効期限（exp）として受け付ける、現在時刻からの最大の長さです
+// jtiの再利用の確認はこの間だけ記録を保持します
+const MaxClientAssertionLifetime = 10 * time.Minute
+
+// MaxClientAssertionJTILength はclient_assertionのjtiの最大の長さです
+const MaxClientAssertionJTILength = 255
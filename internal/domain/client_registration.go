@@ -0,0 +1,256 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// InitialAccessTokenExpiration は初期アクセストークンの有効期限（24時間）です
+const InitialAccessTokenExpiration = 24 * time.Hour
+
+// MaxClientRedirectURIs は動的登録で指定できるリダイレクトURIの上限です
+const MaxClientRedirectURIs = 10
+
+const (
+	// TokenEndpointAuthMethodClientSecretPost はリクエストボディのclient_id・client_secretによるクライアント認証です
+	TokenEndpointAuthMethodClientSecretPost = "client_secret_post"
+	// TokenEndpointAuthMethodClientSecretBasic はHTTP Basic認証によるクライアント認証です（RFC 6749 2.3.1）
+	TokenEndpointAuthMethodClientSecretBasic = "client_secret_basic"
+	// TokenEndpointAuthMethodPrivateKeyJWT はクライアントの秘密鍵で署名したJWT（client_assertion）によるクライアント認証です（RFC 7523）
+	// このクライアントにはClient Secretを発行せず、登録したJWKSの公開鍵で署名を検証します
+	TokenEndpointAuthMethodPrivateKeyJWT = "private_key_jwt"
+	// TokenEndpointAuthMethodNone はクライアント認証を行わない公開クライアントです（client_idのみを送信します）
+	// 配布するCLIツールなど秘密を保持できないクライアント向けで、デバイス認可グラントのみを使用するクライアントに限ります
+	TokenEndpointAuthMethodNone = "none"
+)
+
+// TokenEndpointAuthMethods は対応しているクライアント認証方式の一覧です
+var TokenEndpointAuthMethods = []string{
+	TokenEndpointAuthMethodClientSecretPost,
+	TokenEndpointAuthMethodClientSecretBasic,
+	TokenEndpointAuthMethodPrivateKeyJWT,
+	TokenEndpointAuthMethodNone,
+}
+
+// ClientMetadata はクライアントの動的登録（RFC 7591 2）で指定するメタデータを表します
+type ClientMetadata struct {
+	RedirectURIs            []string `json:"redirect_uris"`
+	ClientName              string   `json:"client_name"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	Scope                   string   `json:"scope"` // スペース区切り
+	LogoURI                 string   `json:"logo_uri,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	// JWKSURI・JWKS はprivate_key_jwtで使用する公開鍵（どちらか一方）です
+	JWKSURI string          `json:"jwks_uri,omitempty"`
+	JWKS    json.RawMessage `json:"jwks,omitempty"`
+	// AccessTokenFormat は発行するアクセストークンの形式です（独自の項目。opaqueまたはjwt）
+	AccessTokenFormat string `json:"access_token_format"`
+}
+
+// ApplyDefaults は省略された項目にRFC 7591の既定値を設定します
+// スコープを省略した場合は自分の情報の取得（profile）のみです
+func (m *ClientMetadata) ApplyDefaults() {
+	if len(m.GrantTypes) == 0 {
+		m.GrantTypes = []string{GrantTypeAuthorizationCode}
+	}
+	if len(m.ResponseTypes) == 0 && slices.Contains(m.GrantTypes, GrantTypeAuthorizationCode) {
+		m.ResponseTypes = []string{"code"}
+	}
+	if strings.TrimSpace(m.Scope) == "" {
+		m.Scope = ClientScopeProfile
+	}
+	if m.TokenEndpointAuthMethod == "" {
+		m.TokenEndpointAuthMethod = TokenEndpointAuthMethodClientSecretPost
+	}
+	if m.AccessTokenFormat == "" {
+		m.AccessTokenFormat = AccessTokenFormatOpaque
+	}
+}
+
+// Validate はメタデータが有効かどうかを確認します
+// リダイレクトURIが無効な場合はErrInvalidRedirectURI、それ以外はErrInvalidClientMetadataを返します
+func (m *ClientMetadata) Validate() error {
+	name := strings.TrimSpace(m.ClientName)
+	if name == "" || utf8.RuneCountInString(name) > 255 {
+		return fmt.Errorf("%w: client_name must be 1-255 characters", ErrInvalidClientMetadata)
+	}
+
+	for _, grantType := range m.GrantTypes {
+		if !slices.Contains(ClientGrantTypes, grantType) {
+			return fmt.Errorf("%w: unsupported grant_type %q", ErrInvalidClientMetadata, grantType)
+		}
+	}
+	usesAuthorizationCode := slices.Contains(m.GrantTypes, GrantTypeAuthorizationCode)
+	for _, responseType := range m.ResponseTypes {
+		if responseType != "code" {
+			return fmt.Errorf("%w: unsupported response_type %q", ErrInvalidClientMetadata, responseType)
+		}
+	}
+	if usesAuthorizationCode != slices.Contains(m.ResponseTypes, "code") {
+		return fmt.Errorf("%w: response_types must include code only with the authorization_code grant", ErrInvalidClientMetadata)
+	}
+
+	if usesAuthorizationCode && len(m.RedirectURIs) == 0 {
+		return fmt.Errorf("%w: at least one redirect_uri is required for the authorization_code grant", ErrInvalidRedirectURI)
+	}
+	if len(m.RedirectURIs) > MaxClientRedirectURIs {
+		return fmt.Errorf("%w: at most %d redirect_uris are allowed", ErrInvalidRedirectURI, MaxClientRedirectURIs)
+	}
+	for _, uri := range m.RedirectURIs {
+		if err := ValidateRedirectURI(uri); err != nil {
+			return err
+		}
+	}
+
+	for _, scope := range strings.Fields(m.Scope) {
+		if !slices.Contains(ClientScopes, scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidClientMetadata, scope)
+		}
+	}
+
+	if m.LogoURI != "" {
+		u, err := url.Parse(m.LogoURI)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%w: logo_uri must be an https URL", ErrInvalidClientMetadata)
+		}
+	}
+
+	if !slices.Contains(TokenEndpointAuthMethods, m.TokenEndpointAuthMethod) {
+		return fmt.Errorf("%w: unsupported token_endpoint_auth_method %q", ErrInvalidClientMetadata, m.TokenEndpointAuthMethod)
+	}
+	if m.TokenEndpointAuthMethod == TokenEndpointAuthMethodNone && !usesOnlyDeviceCodeGrant(m.GrantTypes) {
+		return fmt.Errorf("%w: token_endpoint_auth_method none is only allowed with the device_code grant alone", ErrInvalidClientMetadata)
+	}
+
+	if !slices.Contains(AccessTokenFormats, m.AccessTokenFormat) {
+		return fmt.Errorf("%w: unsupported access_token_format %q", ErrInvalidClientMetadata, m.AccessTokenFormat)
+	}
+
+	// 公開鍵の内容（JWKSとして読み込めるか）はサービスで検証する
+	if m.TokenEndpointAuthMethod == TokenEndpointAuthMethodPrivateKeyJWT {
+		if (m.JWKSURI == "") == (len(m.JWKS) == 0) {
+			return fmt.Errorf("%w: private_key_jwt requires either jwks or jwks_uri", ErrInvalidClientMetadata)
+		}
+	} else if m.JWKSURI != "" || len(m.JWKS) > 0 {
+		return fmt.Errorf("%w: jwks and jwks_uri are only supported with private_key_jwt", ErrInvalidClientMetadata)
+	}
+	if m.JWKSURI != "" {
+		u, err := url.Parse(m.JWKSURI)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%w: jwks_uri must be an https URL", ErrInvalidClientMetadata)
+		}
+	}
+	return nil
+}
+
+// UsesClientSecret はクライアント認証方式がClient Secretを使用するかどうかを返します
+// private_key_jwtとnone（公開クライアント）のクライアントにはClient Secretを発行しません
+func (m *ClientMetadata) UsesClientSecret() bool {
+	return m.TokenEndpointAuthMethod == TokenEndpointAuthMethodClientSecretPost || m.TokenEndpointAuthMethod == TokenEndpointAuthMethodClientSecretBasic
+}
+
+// ValidateRedirectURI はリダイレクトURIがHTTPS（開発用にhttp://localhost・ループバックアドレスも許可）の絶対URLかどうかを確認します
+func ValidateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%w: %q is not an absolute URL", ErrInvalidRedirectURI, uri)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("%w: %q must not include a fragment", ErrInvalidRedirectURI, uri)
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	if u.Scheme == "http" {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q must use https (http is allowed only for localhost)", ErrInvalidRedirectURI, uri)
+}
+
+// ApplyMetadata はメタデータの内容をクライアントに設定します（既定値を設定・検証済みのメタデータを渡してください）
+func (c *ClientApp) ApplyMetadata(m *ClientMetadata) {
+	c.Name = strings.TrimSpace(m.ClientName)
+	c.RedirectURIs = m.RedirectURIs
+	if c.RedirectURIs == nil {
+		c.RedirectURIs = []string{}
+	}
+	c.GrantTypes = slices.Compact(slices.Sorted(slices.Values(m.GrantTypes)))
+	c.Scopes = slices.Compact(slices.Sorted(slices.Values(strings.Fields(m.Scope))))
+	c.LogoURI = m.LogoURI
+	c.TokenEndpointAuthMethod = m.TokenEndpointAuthMethod
+	c.JWKSURI = m.JWKSURI
+	c.JWKS = string(m.JWKS)
+	c.AccessTokenFormat = m.AccessTokenFormat
+}
+
+// Metadata はクライアントの登録内容をメタデータとして返します
+// グラント・スコープが空のクライアントは、すべてを使用できるものとして返します
+func (c *ClientApp) Metadata() *ClientMetadata {
+	grantTypes := c.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = ClientGrantTypes
+	}
+	responseTypes := []string{}
+	if c.AllowsGrantType(GrantTypeAuthorizationCode) {
+		responseTypes = []string{"code"}
+	}
+	authMethod := c.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = TokenEndpointAuthMethodClientSecretPost
+	}
+	metadata := &ClientMetadata{
+		RedirectURIs:            c.RedirectURIs,
+		ClientName:              c.Name,
+		GrantTypes:              grantTypes,
+		ResponseTypes:           responseTypes,
+		Scope:                   strings.Join(c.EffectiveScopes(), " "),
+		LogoURI:                 c.LogoURI,
+		TokenEndpointAuthMethod: authMethod,
+		JWKSURI:                 c.JWKSURI,
+		AccessTokenFormat:       c.AccessTokenFormatOrDefault(),
+	}
+	if c.JWKS != "" {
+		metadata.JWKS = json.RawMessage(c.JWKS)
+	}
+	return metadata
+}
+
+// InitialAccessToken はログイン中のメンバーに発行する、クライアントを動的登録するためのトークン（RFC 7591 3）を表します
+// トークンは発行時に一度だけ表示し、SHA-256のハッシュで保存します。1回の登録にのみ使用できます
+type InitialAccessToken struct {
+	ID        string
+	TokenHash string // SHA-256（16進数）
+	UserID    string // 発行したメンバー（登録したクライアントの所有者になります）
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time // nilの場合は未使用
+}
+
+// Validate は初期アクセストークンのデータが有効かどうかを確認します
+func (t *InitialAccessToken) Validate() error {
+	if t.TokenHash == "" {
+		return fmt.Errorf("token_hash is required")
+	}
+	if t.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if t.ExpiresAt.IsZero() {
+		return fmt.Errorf("expires_at is required")
+	}
+	return nil
+}
+
+// IsUsable は初期アクセストークンが未使用かつ期限内かどうかを確認します
+func (t *InitialAccessToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
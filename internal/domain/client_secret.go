@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxActiveClientSecrets はクライアントごとに同時に有効にできるClient Secretの上限です
+const MaxActiveClientSecrets = 5
+
+// ClientSecret はクライアントのClient Secretを表します
+// ローテーション中は古いシークレットに有効期限を設定し、新しいシークレットと並行して使用できるようにします
+type ClientSecret struct {
+	ID           string
+	ClientID     string // client_apps.client_id
+	HashedSecret string // bcryptでハッシュ化
+	CreatedAt    time.Time
+	ExpiresAt    *time.Time // nilの場合は無期限
+	LastUsedAt   *time.Time
+}
+
+// Validate はClient Secretのデータが有効かどうかを確認します
+func (s *ClientSecret) Validate() error {
+	if s.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if s.HashedSecret == "" {
+		return fmt.Errorf("hashed_secret is required")
+	}
+	return nil
+}
+
+// IsExpired はClient Secretの有効期限が切れているかどうかを確認します
+func (s *ClientSecret) IsExpired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeviceCodeStatus はデバイス認可（RFC 8628）の状態を表します
+type DeviceCodeStatus string
+
+const (
+	// DeviceCodeStatusPending はユーザーの承認を待っている状態です
+	DeviceCodeStatusPending DeviceCodeStatus = "pending"
+	// DeviceCodeStatusApproved はユーザーが承認し、トークンの発行を待っている状態です
+	DeviceCodeStatusApproved DeviceCodeStatus = "approved"
+	// DeviceCodeStatusDenied はユーザーが拒否した状態です
+	DeviceCodeStatusDenied DeviceCodeStatus = "denied"
+	// DeviceCodeStatusUsed はトークンを発行済みの状態です
+	DeviceCodeStatusUsed DeviceCodeStatus = "used"
+)
+
+// UserCodeCharset はユーザーコードに使用する文字です（読み間違えやすい母音・数字を含まない20文字、RFC 8628 6.1）
+const UserCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+
+// UserCodeLength はユーザーコードの文字数です（区切りのハイフンを除く）
+const UserCodeLength = 8
+
+// DeviceCode はOAuth2デバイス認可のデバイスコードとユーザーコードを表します
+type DeviceCode struct {
+	ID           string
+	DeviceCode   string
+	UserCode     string // 正規化済み（区切りなしの大文字）
+	ClientID     string
+	UserID       string // 承認・拒否したユーザーのID（承認待ちの間は空）
+	Status       DeviceCodeStatus
+	Interval     time.Duration // ポーリングの最小間隔（slow_downのたびに5秒延長します）
+	LastPolledAt *time.Time
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// Validate はデバイスコードのデータが有効かどうかを確認します
+func (d *DeviceCode) Validate() error {
+	if d.DeviceCode == "" {
+		return fmt.Errorf("device_code is required")
+	}
+	if len(d.UserCode) != UserCodeLength {
+		return fmt.Errorf("user_code must be %d characters", UserCodeLength)
+	}
+	if d.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	switch d.Status {
+	case DeviceCodeStatusPending:
+	case DeviceCodeStatusApproved, DeviceCodeStatusDenied, DeviceCodeStatusUsed:
+		if d.UserID == "" {
+			return fmt.Errorf("user_id is required once the device code is %s", d.Status)
+		}
+	default:
+		return fmt.Errorf("unknown status: %s", d.Status)
+	}
+	if d.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if d.ExpiresAt.IsZero() {
+		return fmt.Errorf("expires_at is required")
+	}
+	return nil
+}
+
+// IsExpired はデバイスコードが期限切れかどうかを確認します
+func (d *DeviceCode) IsExpired() bool {
+	return time.Now().After(d.ExpiresAt)
+}
+
+// FormattedUserCode は画面に表示する形式（XXXX-XXXX）のユーザーコードを返します
+func (d *DeviceCode) FormattedUserCode() string {
+	if len(d.UserCode) != UserCodeLength {
+		return d.UserCode
+	}
+	return d.UserCode[:UserCodeLength/2] + "-" + d.UserCode[UserCodeLength/2:]
+}
+
+// NormalizeUserCode は入力されたユーザーコードを保存形式に変換します
+// 大文字に揃え、ハイフン・空白などユーザーコードに使用しない文字を取り除きます
+func NormalizeUserCode(input string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		if strings.ContainsRune(UserCodeCharset, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
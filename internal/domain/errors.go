@@ -25,6 +25,99 @@ var (
 	// ErrAuthCodeAlreadyUsed は認可コードが既に使用済みの場合のエラー
 	ErrAuthCodeAlreadyUsed = errors.New("authorization code already used")
 
+	// ErrDeviceCodeNotFound はデバイスコード・ユーザーコードが見つからない場合のエラー
+	ErrDeviceCodeNotFound = errors.New("device code not found")
+
+	// ErrDeviceCodeExpired はデバイスコードが期限切れの場合のエラー（RFC 8628のexpired_token）
+	ErrDeviceCodeExpired = errors.New("device code expired")
+
+	// ErrAuthorizationPending はユーザーがデバイス認可をまだ承認していない場合のエラー（RFC 8628のauthorization_pending）
+	ErrAuthorizationPending = errors.New("authorization pending")
+
+	// ErrSlowDown はデバイスがポーリングの間隔を守っていない場合のエラー（RFC 8628のslow_down）
+	ErrSlowDown = errors.New("slow down")
+
+	// ErrInvalidClient はクライアント認証（Client IDとClient Secretの検証）に失敗した場合のエラー
+	ErrInvalidClient = errors.New("invalid client credentials")
+
+	// ErrUnauthorizedClient はクライアントに登録されていないグラント（公開クライアントの認可コードグラントなど）を使用した場合のエラー
+	ErrUnauthorizedClient = errors.New("unauthorized client")
+
 	// ErrProfileNotFound はプロフィールが見つからない場合のエラー
 	ErrProfileNotFound = errors.New("profile not found")
+
+	// ErrIntroMessageNotFound は自己紹介メッセージが見つからない場合のエラー
+	ErrIntroMessageNotFound = errors.New("intro message not found")
+
+	// ErrInvalidVisibility はプロフィールの公開範囲の設定が無効な場合のエラー
+	ErrInvalidVisibility = errors.New("invalid profile visibility")
+
+	// ErrInvalidProfileField はWebから編集できないプロフィールの項目が指定された場合のエラー
+	ErrInvalidProfileField = errors.New("invalid profile field")
+
+	// ErrInvalidMemberPage はメンバー一覧の並び順・カーソルの指定が無効な場合のエラー
+	ErrInvalidMemberPage = errors.New("invalid member page")
+
+	// ErrInvalidExport はエクスポートの形式・項目の指定が無効な場合のエラー
+	ErrInvalidExport = errors.New("invalid export request")
+
+	// ErrExportForbidden はエクスポートを許可されたロールを持たない場合のエラー
+	ErrExportForbidden = errors.New("member export is not allowed for this user")
+
+	// ErrInvalidWebhook はWebhookの送信先・購読する出来事の指定が無効な場合のエラー
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrWebhookNotFound はWebhookの送信先または配信が見つからない場合のエラー
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrInvalidAccessPolicy は認証プロキシのアクセス条件の設定が無効な場合のエラー
+	ErrInvalidAccessPolicy = errors.New("invalid access policy")
+
+	// ErrInvalidProxyRoute は認証プロキシモードのルートの設定が無効な場合のエラー
+	ErrInvalidProxyRoute = errors.New("invalid proxy route")
+
+	// ErrUnauthenticated はセッションまたはトークンによる認証に失敗した場合のエラー
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrAccessDenied はアクセス条件によりアクセスが拒否された場合のエラー
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrInvalidRequestPath は認証プロキシに転送されたリクエストのパスを安全に正規化できない場合のエラー
+	ErrInvalidRequestPath = errors.New("invalid request path")
+
+	// ErrInvalidSAMLServiceProvider はSAMLのサービスの登録内容が無効な場合のエラー
+	ErrInvalidSAMLServiceProvider = errors.New("invalid saml service provider")
+
+	// ErrSAMLServiceProviderNotFound はSAMLのサービスが登録されていない場合のエラー
+	ErrSAMLServiceProviderNotFound = errors.New("saml service provider not found")
+
+	// ErrInvalidSAMLRequest はSAMLの認証要求（AuthnRequest）が無効な場合のエラー
+	ErrInvalidSAMLRequest = errors.New("invalid saml request")
+
+	// ErrInvalidAppPassword はアプリパスワードの名前などの指定が無効な場合のエラー
+	ErrInvalidAppPassword = errors.New("invalid app password")
+
+	// ErrAppPasswordNotFound はアプリパスワードが見つからない場合のエラー
+	ErrAppPasswordNotFound = errors.New("app password not found")
+
+	// ErrSyncCursorNotFound は同期カーソルが見つからない場合のエラー
+	ErrSyncCursorNotFound = errors.New("sync cursor not found")
+
+	// ErrInvalidClientMetadata はクライアントの動的登録（RFC 7591）のメタデータが無効な場合のエラー
+	ErrInvalidClientMetadata = errors.New("invalid client metadata")
+
+	// ErrInvalidRedirectURI はクライアントの動的登録のリダイレクトURIが無効な場合のエラー
+	ErrInvalidRedirectURI = errors.New("invalid redirect uri")
+
+	// ErrInvalidRegistrationToken は初期アクセストークンまたは登録アクセストークンが無効な場合のエラー
+	ErrInvalidRegistrationToken = errors.New("invalid registration token")
+
+	// ErrClientSecretNotFound はClient Secretが見つからない場合のエラー
+	ErrClientSecretNotFound = errors.New("client secret not found")
+
+	// ErrInvalidClientSecret はClient Secretの発行・取り消しができない場合（上限・最後のシークレットの取り消しなど）のエラー
+	ErrInvalidClientSecret = errors.New("invalid client secret operation")
+
+	// ErrClientAssertionReplayed はクライアントの署名付きJWT（client_assertion）のjtiが使用済みの場合のエラー
+	ErrClientAssertionReplayed = errors.New("client assertion replayed")
 )
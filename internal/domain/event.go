@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// EventType はクライアントアプリに通知する出来事の種類を表します
+type EventType string
+
+const (
+	// EventUserCreated はユーザーが初めてログイン（またはロスター同期で登録）されたことを表します
+	EventUserCreated EventType = "user.created"
+	// EventUserUpdated はユーザー情報（ユーザー名・ロール・在籍状況など）が変更されたことを表します
+	EventUserUpdated EventType = "user.updated"
+	// EventProfileUpdated はプロフィール（公開範囲・削除状態を含む）が変更されたことを表します
+	EventProfileUpdated EventType = "profile.updated"
+	// EventMemberLeft はメンバーがサーバーから退出したことを表します
+	EventMemberLeft EventType = "member.left"
+	// EventTokenRevoked はアクセストークンまたはリフレッシュトークンが取り消されたことを表します
+	EventTokenRevoked EventType = "token.revoked"
+)
+
+// EventTypes は通知できる出来事の種類の一覧です
+var EventTypes = []EventType{
+	EventUserCreated,
+	EventUserUpdated,
+	EventProfileUpdated,
+	EventMemberLeft,
+	EventTokenRevoked,
+}
+
+// ParseEventType は出来事の種類を検証して返します
+func ParseEventType(value string) (EventType, error) {
+	eventType := EventType(value)
+	if !slices.Contains(EventTypes, eventType) {
+		return "", fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, value)
+	}
+	return eventType, nil
+}
+
+// Event はクライアントアプリに通知する出来事です
+// 値そのものは含めず、変更された項目名とIDのみを通知します（受信側はAPIで最新の値を取得します）
+type Event struct {
+	ID         string
+	Type       EventType
+	UserID     string                 // 対象のユーザーのID
+	ClientID   string                 // 通知先を限定するクライアントのClient ID（空の場合は購読している全クライアント）
+	Data       map[string]interface{} // 通知する内容（変更された項目名など）
+	OccurredAt time.Time
+}
+
+// EventsFromChange は変更履歴から通知する出来事を返します（IDと日時は呼び出し側で設定します）
+// ユーザー情報の初回の記録は作成、退出日時の設定は退出として扱い、それ以外の項目の変更は更新として扱います
+func EventsFromChange(record *ChangeRecord) []*Event {
+	if len(record.Changes) == 0 {
+		return nil
+	}
+
+	if record.Subject == ChangeSubjectProfile {
+		return []*Event{changeEvent(EventProfileUpdated, record, record.Changes)}
+	}
+	if record.Version == 1 {
+		return []*Event{changeEvent(EventUserCreated, record, record.Changes)}
+	}
+
+	var events []*Event
+	var updated []FieldChange
+	for _, change := range record.Changes {
+		if change.Field == "left_at" && change.Old == "" && change.New != "" {
+			events = append(events, &Event{
+				Type:   EventMemberLeft,
+				UserID: record.UserID,
+				Data:   map[string]interface{}{"left_at": change.New},
+			})
+			continue
+		}
+		updated = append(updated, change)
+	}
+	if len(updated) > 0 {
+		events = append(events, changeEvent(EventUserUpdated, record, updated))
+	}
+	return events
+}
+
+// changeEvent は変更された項目名を内容とする出来事を返します
+func changeEvent(eventType EventType, record *ChangeRecord, changes []FieldChange) *Event {
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	return &Event{
+		Type:   eventType,
+		UserID: record.UserID,
+		Data: map[string]interface{}{
+			"changed_fields": fields,
+			"actor":          string(record.ActorType),
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ExportFormat はメンバー一覧のエクスポートの形式です
+type ExportFormat string
+
+const (
+	// ExportFormatCSV はCSV（UTF-8、BOM付き）です
+	ExportFormatCSV ExportFormat = "csv"
+	// ExportFormatJSONL はJSON Lines（1行1メンバーのJSONオブジェクト）です
+	ExportFormatJSONL ExportFormat = "jsonl"
+	// ExportFormatXLSX はExcelのワークブックです
+	ExportFormatXLSX ExportFormat = "xlsx"
+)
+
+// ParseExportFormat は文字列からエクスポートの形式を返します（空文字列はCSV）
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch f := ExportFormat(strings.ToLower(s)); f {
+	case "":
+		return ExportFormatCSV, nil
+	case ExportFormatCSV, ExportFormatJSONL, ExportFormatXLSX:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: unknown format %q (must be csv, jsonl or xlsx)", ErrInvalidExport, s)
+	}
+}
+
+// ContentType はエクスポートの形式のContent-Typeを返します
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatJSONL:
+		return "application/x-ndjson; charset=utf-8"
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// エクスポートできるユーザー情報・学籍番号から求めた項目のキー
+// （プロフィールの項目はProfileFieldsとExtraのキーで指定します）
+const (
+	ExportFieldID             = "id"
+	ExportFieldDiscordID      = "discord_id"
+	ExportFieldUsername       = "username"
+	ExportFieldDisplayName    = "display_name"
+	ExportFieldGuildNickname  = "guild_nickname"
+	ExportFieldRoles          = "roles"
+	ExportFieldJoinedAt       = "joined_at"
+	ExportFieldLastLoginAt    = "last_login_at"
+	ExportFieldEnrollmentYear = "enrollment_year"
+	ExportFieldFaculty        = "faculty"
+	ExportFieldGrade          = "grade"
+)
+
+// ExportUserFields はプロフィール以外のエクスポートできる項目のキーです
+var ExportUserFields = []string{
+	ExportFieldID,
+	ExportFieldDiscordID,
+	ExportFieldUsername,
+	ExportFieldDisplayName,
+	ExportFieldGuildNickname,
+	ExportFieldRoles,
+	ExportFieldJoinedAt,
+	ExportFieldLastLoginAt,
+}
+
+// DefaultExportFields は項目を指定しない場合にエクスポートする項目です
+var DefaultExportFields = []string{
+	ExportFieldUsername,
+	ExportFieldDisplayName,
+	ExportFieldGuildNickname,
+	ExportFieldRoles,
+	ExportFieldJoinedAt,
+	ProfileFieldRealName,
+	ProfileFieldStudentID,
+	ExportFieldEnrollmentYear,
+	ExportFieldFaculty,
+	ExportFieldGrade,
+}
+
+// maxExportFields はエクスポートで指定できる項目の最大数です
+const maxExportFields = 50
+
+// ParseExportFields はエクスポートする項目のキーの一覧を検証し、重複を除いて返します（空の場合は既定の項目）
+// ユーザー情報・固定フィールド・学籍番号から求めた項目以外のキーはプロフィールのカスタム項目として扱います
+func ParseExportFields(keys []string) ([]string, error) {
+	var fields []string
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || slices.Contains(fields, key) {
+			continue
+		}
+		if len(key) > 64 || strings.ContainsAny(key, " \t\r\n,") {
+			return nil, fmt.Errorf("%w: invalid field %q", ErrInvalidExport, key)
+		}
+		fields = append(fields, key)
+	}
+	if len(fields) == 0 {
+		return slices.Clone(DefaultExportFields), nil
+	}
+	if len(fields) > maxExportFields {
+		return nil, fmt.Errorf("%w: too many fields (max %d)", ErrInvalidExport, maxExportFields)
+	}
+	return fields, nil
+}
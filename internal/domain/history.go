@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ChangeSubject は変更履歴の対象を表します
+type ChangeSubject string
+
+const (
+	// ChangeSubjectProfile はプロフィールの変更です
+	ChangeSubjectProfile ChangeSubject = "profile"
+	// ChangeSubjectUser はユーザー情報（Discordのユーザー名・ロール・在籍状況など）の変更です
+	ChangeSubjectUser ChangeSubject = "user"
+)
+
+// ChangeActorType は変更を行った主体の種類を表します
+type ChangeActorType string
+
+const (
+	// ChangeActorSync はDiscordとの同期（自己紹介・ロスター・ログイン時の更新）による変更です
+	ChangeActorSync ChangeActorType = "sync"
+	// ChangeActorManual は本人によるWebからの変更です
+	ChangeActorManual ChangeActorType = "manual"
+	// ChangeActorAdmin は幹部による変更です
+	ChangeActorAdmin ChangeActorType = "admin"
+)
+
+// ChangeActor は変更を行った主体です
+type ChangeActor struct {
+	Type   ChangeActorType
+	UserID string // 変更したユーザーのID（syncの場合は空）
+}
+
+// SyncActor は同期による変更の主体を返します
+func SyncActor() ChangeActor {
+	return ChangeActor{Type: ChangeActorSync}
+}
+
+// UserActor はactorUserIDのユーザーがuserIDのデータを変更する場合の主体を返します
+// 本人以外による変更は幹部による変更として扱います
+func UserActor(actorUserID, userID string) ChangeActor {
+	if actorUserID == userID {
+		return ChangeActor{Type: ChangeActorManual, UserID: actorUserID}
+	}
+	return ChangeActor{Type: ChangeActorAdmin, UserID: actorUserID}
+}
+
+// FieldChange は1項目の変更前後の値です
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+	// Redacted は閲覧者が閲覧できない項目のため値を取り除いたかどうかです（保存時は常にfalse）
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// ChangeRecord はプロフィールまたはユーザー情報の1回の変更を表します
+type ChangeRecord struct {
+	ID          string
+	UserID      string // 変更されたユーザーのID
+	Subject     ChangeSubject
+	Version     int // ユーザーと対象ごとの連番（リポジトリが採番）
+	ActorType   ChangeActorType
+	ActorUserID string
+	Changes     []FieldChange
+	CreatedAt   time.Time
+}
+
+// RedactFor は閲覧者が閲覧できない項目の値を取り除いた変更履歴のコピーを返します
+// プロフィールの変更は現在の公開範囲に従い、公開範囲の設定の変更は本人にのみ返します
+// profileがnil（削除済みなど）の場合は、本人以外には削除状態以外の値を返しません
+func (r *ChangeRecord) RedactFor(viewer *ProfileViewer, profile *Profile) *ChangeRecord {
+	redacted := *r
+	redacted.Changes = slices.Clone(r.Changes)
+	if r.Subject != ChangeSubjectProfile {
+		return &redacted
+	}
+
+	self := viewer.access(r.UserID) >= accessSelf
+	for i, change := range redacted.Changes {
+		if change.Field == "deleted" || self {
+			continue
+		}
+		visible := false
+		if profile != nil && !strings.HasPrefix(change.Field, "visibility.") {
+			visible = viewer.CanSee(r.UserID, profile.VisibilityOf(change.Field))
+		}
+		if !visible {
+			redacted.Changes[i] = FieldChange{Field: change.Field, Redacted: true}
+		}
+	}
+	return &redacted
+}
+
+// Clone はプロフィールのコピーを返します（マップも複製します）
+func (p *Profile) Clone() *Profile {
+	clone := *p
+	clone.Extra = maps.Clone(p.Extra)
+	clone.Visibility = maps.Clone(p.Visibility)
+	clone.Sources = maps.Clone(p.Sources)
+	return &clone
+}
+
+// Clone はユーザーのコピーを返します（ロールIDの配列も複製します）
+func (u *User) Clone() *User {
+	clone := *u
+	clone.GuildRoles = slices.Clone(u.GuildRoles)
+	return &clone
+}
+
+// DiffProfiles はプロフィールの変更前後の差分を返します
+// oldがnilの場合は新規作成として扱います。項目の値と公開範囲（visibility.<キー>）、削除状態を比較します
+func DiffProfiles(old, new *Profile) []FieldChange {
+	if old == nil {
+		old = &Profile{}
+	}
+
+	var changes []FieldChange
+	if old.IsDeleted() != new.IsDeleted() {
+		changes = append(changes, FieldChange{Field: "deleted", Old: fmt.Sprint(old.IsDeleted()), New: fmt.Sprint(new.IsDeleted())})
+	}
+	// 墓標化ではプロフィールの値は保存されないため、削除状態の変化のみを記録する
+	if new.IsDeleted() {
+		return changes
+	}
+
+	for _, key := range unionKeys(old.FieldKeys(), new.FieldKeys()) {
+		changes = appendChange(changes, key, old.FieldValue(key), new.FieldValue(key))
+	}
+	for _, key := range unionKeys(mapKeys(old.Visibility), mapKeys(new.Visibility)) {
+		changes = appendChange(changes, "visibility."+key, string(old.VisibilityOf(key)), string(new.VisibilityOf(key)))
+	}
+	return changes
+}
+
+// DiffUsers はユーザー情報の変更前後の差分を返します
+// oldがnilの場合は新規作成として扱います。最終ログイン日時などの記録用の項目は比較しません
+func DiffUsers(old, new *User) []FieldChange {
+	if old == nil {
+		old = &User{}
+	}
+
+	var changes []FieldChange
+	changes = appendChange(changes, "username", old.Username, new.Username)
+	changes = appendChange(changes, "display_name", old.DisplayName, new.DisplayName)
+	changes = appendChange(changes, "avatar_url", old.AvatarURL, new.AvatarURL)
+	changes = appendChange(changes, "guild_nickname", stringValue(old.GuildNickname), stringValue(new.GuildNickname))
+	changes = appendChange(changes, "guild_roles", strings.Join(old.GuildRoles, ","), strings.Join(new.GuildRoles, ","))
+	changes = appendChange(changes, "joined_at", timeValue(old.JoinedAt), timeValue(new.JoinedAt))
+	changes = appendChange(changes, "left_at", timeValue(old.LeftAt), timeValue(new.LeftAt))
+	return changes
+}
+
+// appendChange は値が異なる場合のみ変更を追加します
+func appendChange(changes []FieldChange, field, old, new string) []FieldChange {
+	if old == new {
+		return changes
+	}
+	return append(changes, FieldChange{Field: field, Old: old, New: new})
+}
+
+// unionKeys は2つのキーの一覧を重複なく結合します（aの順序を保ち、bにのみあるキーはソートして後ろに並べます）
+func unionKeys(a, b []string) []string {
+	keys := slices.Clone(a)
+	var extra []string
+	for _, key := range b {
+		if !slices.Contains(keys, key) && !slices.Contains(extra, key) {
+			extra = append(extra, key)
+		}
+	}
+	slices.Sort(extra)
+	return append(keys, extra...)
+}
+
+// mapKeys はマップのキーをソートして返します
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// stringValue はnilを空文字列として文字列ポインタの値を返します
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// timeValue は日時をRFC3339形式の文字列で返します（nilは空文字列）
+func timeValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"fmt"
+	"maps"
+	"time"
+)
+
+// IntroMessage は自己紹介チャンネルに投稿された1件のメッセージとそのパース結果を表します
+// 1人のメンバーが複数回投稿した場合、それぞれが別のIntroMessageとして保存され、
+// ProfileMergeStrategyに従って1つのProfileにまとめられます
+type IntroMessage struct {
+	ID        string // DiscordメッセージID
+	ChannelID string
+	UserID    string
+	RealName  string
+	StudentID string
+	Hobbies   string
+	WhatToDo  string
+	Comment   string
+	Extra     map[string]string
+	PostedAt  time.Time
+	EditedAt  *time.Time // Discordのedited_timestamp
+	DeletedAt *time.Time // メッセージが削除された場合に設定される墓標
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate は自己紹介メッセージのデータが有効かどうかを確認します
+func (m *IntroMessage) Validate() error {
+	if m.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if m.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	return nil
+}
+
+// IsDeleted はメッセージが削除済みかどうかを確認します
+func (m *IntroMessage) IsDeleted() bool {
+	return m.DeletedAt != nil
+}
+
+// SameContent はパース結果と編集日時が同じかどうかを確認します
+func (m *IntroMessage) SameContent(other *IntroMessage) bool {
+	if !sameTime(m.EditedAt, other.EditedAt) {
+		return false
+	}
+	return m.RealName == other.RealName &&
+		m.StudentID == other.StudentID &&
+		m.Hobbies == other.Hobbies &&
+		m.WhatToDo == other.WhatToDo &&
+		m.Comment == other.Comment &&
+		maps.Equal(m.Extra, other.Extra)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// ProfileMergeStrategy は複数の自己紹介メッセージから正規のプロフィールを決める方法です
+type ProfileMergeStrategy string
+
+const (
+	// ProfileMergeNewest は最新のメッセージの内容をそのまま採用します
+	ProfileMergeNewest ProfileMergeStrategy = "newest"
+	// ProfileMergeFields はフィールドごとに、値が入っている最新のメッセージの内容を採用します
+	ProfileMergeFields ProfileMergeStrategy = "merge"
+)
+
+// ParseProfileMergeStrategy は文字列をProfileMergeStrategyに変換します（空の場合はnewest）
+func ParseProfileMergeStrategy(s string) (ProfileMergeStrategy, error) {
+	switch ProfileMergeStrategy(s) {
+	case "", ProfileMergeNewest:
+		return ProfileMergeNewest, nil
+	case ProfileMergeFields:
+		return ProfileMergeFields, nil
+	default:
+		return "", fmt.Errorf("unknown profile merge strategy: %s", s)
+	}
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// MemberSort はメンバー一覧の並び順の基準です
+type MemberSort string
+
+const (
+	// MemberSortLastLogin は最終ログイン日時の順です（既定は新しい順）
+	MemberSortLastLogin MemberSort = "last_login"
+	// MemberSortJoinedAt はサーバー参加日時の順です（既定は古い順）
+	MemberSortJoinedAt MemberSort = "joined_at"
+	// MemberSortUsername はユーザー名の順です（既定は昇順）
+	MemberSortUsername MemberSort = "username"
+)
+
+// ParseMemberSort は文字列から並び順の基準を返します（空文字列は最終ログイン日時）
+func ParseMemberSort(s string) (MemberSort, error) {
+	switch MemberSort(s) {
+	case "":
+		return MemberSortLastLogin, nil
+	case MemberSortLastLogin, MemberSortJoinedAt, MemberSortUsername:
+		return MemberSort(s), nil
+	default:
+		return "", fmt.Errorf("%w: unknown sort %q", ErrInvalidMemberPage, s)
+	}
+}
+
+// DefaultDesc は並び順の基準ごとの既定の向きが降順かどうかを返します
+func (s MemberSort) DefaultDesc() bool {
+	return s == MemberSortLastLogin
+}
+
+// MemberPage はメンバー一覧の並び順と取得範囲です
+// Cursorが指定されている場合はOffsetを無視し、カーソルの次の要素から取得します
+type MemberPage struct {
+	Sort   MemberSort
+	Desc   bool
+	Limit  int // 0の場合は上限なし
+	Offset int
+	Cursor *MemberCursor
+}
+
+// DefaultMemberPage は最終ログイン日時の新しい順の取得範囲を返します
+func DefaultMemberPage(limit, offset int) MemberPage {
+	return MemberPage{Sort: MemberSortLastLogin, Desc: true, Limit: limit, Offset: offset}
+}
+
+// Validate はカーソルが並び順と一致するかどうかを確認します
+func (p MemberPage) Validate() error {
+	if p.Cursor != nil && (p.Cursor.Sort != p.Sort || p.Cursor.Desc != p.Desc) {
+		return fmt.Errorf("%w: cursor does not match sort order", ErrInvalidMemberPage)
+	}
+	return nil
+}
+
+// MemberCursor はメンバー一覧の最後に返した要素の位置です
+// 並び順の基準の値とユーザーIDの組で位置を表すため、一覧の途中でメンバーが増減しても結果がずれません
+type MemberCursor struct {
+	Sort MemberSort `json:"s"`
+	Desc bool       `json:"d,omitempty"`
+	// Value は並び順の基準の値です（日時はRFC3339形式、値がない場合はnil）
+	Value *string `json:"v,omitempty"`
+	ID    string  `json:"id"`
+}
+
+// Encode はカーソルをクエリパラメータに使用できる不透明な文字列に変換します
+func (c *MemberCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeMemberCursor はEncodeで変換した文字列からカーソルを復元します
+func DecodeMemberCursor(s string) (*MemberCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidMemberPage)
+	}
+	var cursor MemberCursor
+	if err := json.Unmarshal(b, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidMemberPage)
+	}
+	if _, err := ParseMemberSort(string(cursor.Sort)); err != nil || cursor.Sort == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidMemberPage)
+	}
+	return &cursor, nil
+}
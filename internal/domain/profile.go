@@ -11,10 +11,17 @@ type Profile struct {
 	UserID           string
 	DiscordMessageID string
 	RealName         string
-	StudentID        string
+	StudentID        string // 正規化済みの学籍番号
+	EnrollmentYear   int    // 学籍番号から求めた入学年度（不明な場合は0）
+	Faculty          string // 学籍番号から求めた学部コード（不明な場合は空文字列）
 	Hobbies          string
 	WhatToDo         string
 	Comment          string
+	Extra            map[string]string          // テンプレートで定義したカスタム項目など、固定フィールド以外の項目
+	Visibility       map[string]FieldVisibility // 項目ごとの公開範囲（キーはProfileFieldsまたはExtraのキー。未設定の項目はmembers）
+	Sources          map[string]FieldOrigin     // 手動編集された項目（未設定の項目はdiscord_intro）
+	EditedAt         *time.Time                 // 元メッセージの最終編集日時（edited_timestamp）
+	DeletedAt        *time.Time                 // 元メッセージがすべて削除された場合に設定される墓標
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 }
@@ -25,7 +32,12 @@ func (p *Profile) Validate() error {
 		return fmt.Errorf("user_id is required")
 	}
 	if p.DiscordMessageID == "" {
-		return fmt.Errorf("discord_message_id is required")
+		return fmt.Errorf("discord_message_id is required (use ManualProfileMessageID for profiles without intro)")
 	}
 	return nil
 }
+
+// IsDeleted はプロフィールが墓標化（削除済み）かどうかを確認します
+func (p *Profile) IsDeleted() bool {
+	return p.DeletedAt != nil
+}
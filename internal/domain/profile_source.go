@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+)
+
+// FieldSource はプロフィールの項目の値の出所を表します
+type FieldSource string
+
+const (
+	// FieldSourceDiscordIntro は自己紹介チャンネルの投稿から取り込んだ値です（既定）
+	FieldSourceDiscordIntro FieldSource = "discord_intro"
+	// FieldSourceManual はメンバーがWebから直接編集した値です
+	FieldSourceManual FieldSource = "manual"
+)
+
+// manualMessageIDPrefix は自己紹介の投稿がなく、手動編集の項目だけを持つプロフィールのDiscordMessageIDの接頭辞です
+const manualMessageIDPrefix = "manual:"
+
+// FieldOrigin は手動編集された項目の情報です
+type FieldOrigin struct {
+	Source FieldSource `json:"source"`
+	// IntroValue は手動編集した時点の自己紹介の値です
+	// 自己紹介の値がこれと異なるものに変わった場合は、自己紹介の値で上書きします
+	IntroValue string `json:"intro_value,omitempty"`
+}
+
+// ProfileEdit はWebからのプロフィール編集の内容です
+type ProfileEdit struct {
+	// Fields は手動で設定する項目の値です（空文字列は項目を空にします）
+	Fields map[string]string `json:"fields"`
+	// Reset は手動編集をやめて自己紹介の値に戻す項目のキーです
+	Reset []string `json:"reset"`
+}
+
+// ManualProfileMessageID は自己紹介の投稿がないプロフィールに設定するDiscordMessageIDを返します
+func ManualProfileMessageID(userID string) string {
+	return manualMessageIDPrefix + userID
+}
+
+// HasIntro はプロフィールが自己紹介の投稿に基づいているかどうかを確認します
+func (p *Profile) HasIntro() bool {
+	return p.DiscordMessageID != "" && !strings.HasPrefix(p.DiscordMessageID, manualMessageIDPrefix)
+}
+
+// IsEditableField はkeyがWebから編集できる項目かどうかを確認します
+// 固定フィールドと既存のExtraの項目のほか、新しい項目として英小文字・数字・アンダースコアのみのキーを受け付けます
+func (p *Profile) IsEditableField(key string) bool {
+	if slices.Contains(ProfileFields, key) {
+		return true
+	}
+	if _, ok := p.Extra[key]; ok {
+		return true
+	}
+	if key == "" || len(key) > 64 {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// SourceOf は項目の値の出所を返します
+func (p *Profile) SourceOf(key string) FieldSource {
+	if origin, ok := p.Sources[key]; ok && origin.Source == FieldSourceManual {
+		return FieldSourceManual
+	}
+	return FieldSourceDiscordIntro
+}
+
+// FieldKeys は固定フィールドとExtraのキーの一覧を返します（Extraのキーはソート順）
+func (p *Profile) FieldKeys() []string {
+	return append(slices.Clone(ProfileFields), mapKeys(p.Extra)...)
+}
+
+// FieldValue はキーに対応する項目の値を返します（固定フィールド以外はExtraから取得）
+func (p *Profile) FieldValue(key string) string {
+	switch key {
+	case ProfileFieldRealName:
+		return p.RealName
+	case ProfileFieldStudentID:
+		return p.StudentID
+	case ProfileFieldHobbies:
+		return p.Hobbies
+	case ProfileFieldWhatToDo:
+		return p.WhatToDo
+	case ProfileFieldComment:
+		return p.Comment
+	default:
+		return p.Extra[key]
+	}
+}
+
+// SetFieldValue はキーに対応する項目の値を設定します（Extraの項目は空文字列で削除）
+func (p *Profile) SetFieldValue(key, value string) {
+	switch key {
+	case ProfileFieldRealName:
+		p.RealName = value
+	case ProfileFieldStudentID:
+		p.StudentID = value
+	case ProfileFieldHobbies:
+		p.Hobbies = value
+	case ProfileFieldWhatToDo:
+		p.WhatToDo = value
+	case ProfileFieldComment:
+		p.Comment = value
+	default:
+		if value == "" {
+			delete(p.Extra, key)
+			return
+		}
+		if p.Extra == nil {
+			p.Extra = make(map[string]string)
+		}
+		p.Extra[key] = value
+	}
+}
+
+// HasContent はいずれかの項目に値があるかどうかを確認します
+func (p *Profile) HasContent() bool {
+	for _, key := range p.FieldKeys() {
+		if p.FieldValue(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyIntro は自己紹介から組み立てた値をプロフィールに反映します
+// 手動編集された項目は、自己紹介の値が編集時点から変わっていなければ手動の値を残します
+// introが自己紹介の投稿を持たない場合（削除された場合）は、自己紹介由来の項目だけを空にします
+func (p *Profile) ApplyIntro(intro *Profile) {
+	keys := p.FieldKeys()
+	for key := range intro.Extra {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		introValue := intro.FieldValue(key)
+		if origin, ok := p.Sources[key]; ok && origin.Source == FieldSourceManual {
+			// 自己紹介が削除された場合も手動の値は残す
+			if origin.IntroValue == introValue || !intro.HasIntro() {
+				continue
+			}
+			delete(p.Sources, key)
+		}
+		p.SetFieldValue(key, introValue)
+	}
+
+	if intro.HasIntro() {
+		p.DiscordMessageID = intro.DiscordMessageID
+	} else {
+		p.DiscordMessageID = ManualProfileMessageID(p.UserID)
+	}
+	p.EditedAt = intro.EditedAt
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// ProxyRoute は認証プロキシモードで上流のサービスに転送するルートです
+// ホストとパスの接頭辞で対象のリクエストを決め、アクセス条件を満たすユーザーのリクエストのみ転送します
+type ProxyRoute struct {
+	AccessPolicy
+	Upstream     string `json:"upstream"`      // 転送先のURL（http/https）
+	StripPrefix  bool   `json:"strip_prefix"`  // 転送時にパスの接頭辞を取り除くか
+	PreserveHost bool   `json:"preserve_host"` // 転送時に元のHostヘッダーを維持するか（falseの場合は転送先のホスト）
+}
+
+// ProxyRoutes は認証プロキシモードのルートの一覧です
+type ProxyRoutes []*ProxyRoute
+
+// ParseProxyRoutes はJSON配列から認証プロキシモードのルートの一覧を読み込みます
+func ParseProxyRoutes(data []byte) (ProxyRoutes, error) {
+	var routes ProxyRoutes
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxyRoute, err)
+	}
+	for i, route := range routes {
+		if route == nil {
+			return nil, fmt.Errorf("%w: route %d is empty", ErrInvalidProxyRoute, i)
+		}
+		if err := route.Validate(); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+	}
+	return routes, nil
+}
+
+// Validate はルートが有効かどうかを確認します
+func (r *ProxyRoute) Validate() error {
+	if err := r.AccessPolicy.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProxyRoute, err)
+	}
+	if _, err := r.UpstreamURL(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpstreamURL は転送先のURLを返します
+func (r *ProxyRoute) UpstreamURL() (*url.URL, error) {
+	u, err := url.Parse(r.Upstream)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("%w: invalid upstream %q", ErrInvalidProxyRoute, r.Upstream)
+	}
+	return u, nil
+}
+
+// Match はホストとパスに一致するルートを返します（一致するルートがない場合はnil）
+// 完全一致のホストはワイルドカードより優先し、同じホストではパスの接頭辞が最も長いものを使用します
+func (r ProxyRoutes) Match(host, path string) *ProxyRoute {
+	var matched *ProxyRoute
+	for _, route := range r {
+		if !route.MatchesHost(host) || !route.MatchesPath(path) {
+			continue
+		}
+		if matched == nil || route.moreSpecificThan(&matched.AccessPolicy) {
+			matched = route
+		}
+	}
+	return matched
+}
+
+// Policies はルートのアクセス条件の一覧を返します（ログイン後のリダイレクト先の検証に使用します）
+func (r ProxyRoutes) Policies() AccessPolicies {
+	policies := make(AccessPolicies, len(r))
+	for i, route := range r {
+		policies[i] = &route.AccessPolicy
+	}
+	return policies
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Role はじょぎサーバーのDiscordロールを表します
+// IDはDiscordのロールID（スノーフレーク）で、User.GuildRolesの値と対応します
+type Role struct {
+	ID        string
+	Name      string
+	Color     int // RGBを整数で表した色（0は色なし）
+	Position  int // 大きいほど上位のロール
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate はロールデータが有効かどうかを確認します
+func (r *Role) Validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	return nil
+}
+
+// ColorHex は色を#RRGGBB形式で返します（色なしの場合は空文字列）
+func (r *Role) ColorHex() string {
+	if r.Color == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%06x", r.Color)
+}
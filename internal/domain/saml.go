@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SAMLAttributeSource はSAMLの属性に設定するユーザーの項目です
+type SAMLAttributeSource string
+
+const (
+	SAMLAttributeUserID      SAMLAttributeSource = "user_id"      // このサーバーのユーザーID
+	SAMLAttributeDiscordID   SAMLAttributeSource = "discord_id"   // DiscordのユーザーID
+	SAMLAttributeUsername    SAMLAttributeSource = "username"     // Discordのユーザー名
+	SAMLAttributeDisplayName SAMLAttributeSource = "display_name" // サーバーニックネーム（未設定の場合は表示名・ユーザー名）
+	SAMLAttributeRoles       SAMLAttributeSource = "roles"        // ロール名（複数の値）
+	SAMLAttributeStudentID   SAMLAttributeSource = "student_id"   // 学籍番号（プロフィールの公開範囲に従う）
+)
+
+// SAMLAttributeSources は属性に設定できるユーザーの項目の一覧です
+var SAMLAttributeSources = []SAMLAttributeSource{
+	SAMLAttributeUserID,
+	SAMLAttributeDiscordID,
+	SAMLAttributeUsername,
+	SAMLAttributeDisplayName,
+	SAMLAttributeRoles,
+	SAMLAttributeStudentID,
+}
+
+// DefaultSAMLAttributes は属性の対応を設定していないサービスに送る属性です
+var DefaultSAMLAttributes = map[string]SAMLAttributeSource{
+	"username":    SAMLAttributeUsername,
+	"displayName": SAMLAttributeDisplayName,
+	"roles":       SAMLAttributeRoles,
+}
+
+// SAMLServiceProvider はSAMLでログインするサービス（SP）を表します
+type SAMLServiceProvider struct {
+	ID       string
+	OwnerID  string // 登録したユーザーのID
+	EntityID string // SPのエンティティID（AuthnRequestのIssuer）
+	Name     string
+	// ACSURLs はアサーションを送るURL（HTTP-POST）の一覧です。AuthnRequestで指定されない場合は先頭のURLを使用します
+	ACSURLs []string
+	// Attributes はSAMLの属性名とユーザーの項目の対応です（未設定の場合はDefaultSAMLAttributes）
+	Attributes map[string]SAMLAttributeSource
+	// ProfileAccess はこのサービスに送るプロフィール項目の公開範囲の上限です（membersまたはofficers）
+	ProfileAccess FieldVisibility
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Validate はSAMLのサービスのデータが有効かどうかを確認します
+func (sp *SAMLServiceProvider) Validate() error {
+	if sp.EntityID == "" {
+		return fmt.Errorf("%w: entity_id is required", ErrInvalidSAMLServiceProvider)
+	}
+	if sp.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSAMLServiceProvider)
+	}
+	if len(sp.ACSURLs) == 0 {
+		return fmt.Errorf("%w: at least one acs_url is required", ErrInvalidSAMLServiceProvider)
+	}
+	for _, raw := range sp.ACSURLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil || u.Fragment != "" {
+			return fmt.Errorf("%w: invalid acs_url %q", ErrInvalidSAMLServiceProvider, raw)
+		}
+	}
+	for name, source := range sp.Attributes {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%w: attribute name is required", ErrInvalidSAMLServiceProvider)
+		}
+		if !slices.Contains(SAMLAttributeSources, source) {
+			return fmt.Errorf("%w: unknown attribute source %q for %s", ErrInvalidSAMLServiceProvider, source, name)
+		}
+	}
+	if sp.ProfileAccess != "" && sp.ProfileAccess != VisibilityMembers && sp.ProfileAccess != VisibilityOfficers {
+		return fmt.Errorf("%w: profile_access must be members or officers", ErrInvalidSAMLServiceProvider)
+	}
+	return nil
+}
+
+// AttributesOrDefault はSAMLの属性名とユーザーの項目の対応を返します（未設定の場合はDefaultSAMLAttributes）
+func (sp *SAMLServiceProvider) AttributesOrDefault() map[string]SAMLAttributeSource {
+	if len(sp.Attributes) == 0 {
+		return DefaultSAMLAttributes
+	}
+	return sp.Attributes
+}
+
+// ProfileAccessOrDefault はプロフィール項目の公開範囲の上限を返します（未設定の場合はmembers）
+func (sp *SAMLServiceProvider) ProfileAccessOrDefault() FieldVisibility {
+	if sp.ProfileAccess == "" {
+		return VisibilityMembers
+	}
+	return sp.ProfileAccess
+}
+
+// ACSURLFor はAuthnRequestで指定されたアサーションの送信先を返します
+// 指定がない場合は先頭のURLを、登録されていないURLが指定された場合は空文字列を返します
+func (sp *SAMLServiceProvider) ACSURLFor(requested string) string {
+	if requested == "" {
+		return sp.ACSURLs[0]
+	}
+	if slices.Contains(sp.ACSURLs, requested) {
+		return requested
+	}
+	return ""
+}
@@ -0,0 +1,12 @@
+package domain
+
+// ProfileSearchFields は全文検索の対象となるプロフィールの項目です
+var ProfileSearchFields = []string{ProfileFieldHobbies, ProfileFieldWhatToDo, ProfileFieldComment}
+
+// SearchPosting は全文検索の索引の1件で、ユーザーのプロフィールの項目に語が出現した回数を表します
+type SearchPosting struct {
+	UserID string
+	Field  string // プロフィールの項目のキー（ProfileSearchFieldsのいずれか）
+	Term   string // 正規化した文字n-gram
+	Count  int
+}
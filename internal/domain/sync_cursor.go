@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// SyncCursor はチャンネルごとのプロフィール同期の進捗（チェックポイント）を表します
+type SyncCursor struct {
+	ChannelID      string
+	LastMessageID  string     // 処理済みの最新メッセージID
+	LastFullSyncAt *time.Time // 最後に全件照合（フルスキャン）を行った日時
+	UpdatedAt      time.Time
+}
+
+// Validate は同期カーソルのデータが有効かどうかを確認します
+func (c *SyncCursor) Validate() error {
+	if c.ChannelID == "" {
+		return fmt.Errorf("channel_id is required")
+	}
+	return nil
+}
+
+// NeedsFullSync は前回の全件照合からinterval以上経過しているかを確認します
+func (c *SyncCursor) NeedsFullSync(interval time.Duration) bool {
+	if c.LastMessageID == "" || c.LastFullSyncAt == nil {
+		return true
+	}
+	return time.Since(*c.LastFullSyncAt) >= interval
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -13,7 +14,17 @@ const (
 	TokenTypeRefresh TokenType = "refresh"
 )
 
+// JWTAccessTokenPrefix はJWT形式のアクセストークンを保存するときのキーの接頭辞です
+// JWTそのものではなく接頭辞を付けたjtiを保存し、署名を検証したJWTからのみ検索します
+const JWTAccessTokenPrefix = "jwt:"
+
+// JWTAccessTokenKey はJWT形式のアクセストークンのjtiから保存用のキーを返します
+func JWTAccessTokenKey(jti string) string {
+	return JWTAccessTokenPrefix + jti
+}
+
 // Token はアクセストークンまたはリフレッシュトークンを表します
+// JWT形式のアクセストークンの場合、Tokenは JWTAccessTokenKey(jti)、IDはjtiです
 type Token struct {
 	ID        string
 	Token     string
@@ -50,6 +61,14 @@ func (t *Token) IsExpired() bool {
 	return time.Now().After(t.ExpiresAt)
 }
 
+// JTI はJWT形式のアクセストークンのjtiを返します（それ以外のトークンは空）
+func (t *Token) JTI() string {
+	if jti, ok := strings.CutPrefix(t.Token, JWTAccessTokenPrefix); ok {
+		return jti
+	}
+	return ""
+}
+
 // IsValid はトークンが有効（期限切れでなく取り消されていない）かどうかを確認します
 func (t *Token) IsValid() bool {
 	return !t.IsExpired() && !t.Revoked
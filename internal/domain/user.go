@@ -15,6 +15,7 @@ type User struct {
 	GuildNickname *string    // サーバー固有のニックネーム
 	GuildRoles    []string   // ロールIDの配列
 	JoinedAt      *time.Time // サーバー参加日時
+	LeftAt        *time.Time // サーバーから脱退した日時（在籍中はnil）
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	LastLoginAt   *time.Time
@@ -30,3 +31,50 @@ func (u *User) Validate() error {
 	}
 	return nil
 }
+
+// IsGuildMember はユーザーが現在サーバーに在籍しているかどうかを確認します
+func (u *User) IsGuildMember() bool {
+	return u.LeftAt == nil
+}
+
+// PreferredName はサーバーニックネーム・表示名・ユーザー名のうち最初に設定されているものを返します
+func (u *User) PreferredName() string {
+	if u.GuildNickname != nil && *u.GuildNickname != "" {
+		return *u.GuildNickname
+	}
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
+
+// MemberFilter はメンバー一覧の絞り込み条件です（ゼロ値の項目は条件に含めません）
+// 学籍番号から求めた項目・趣味で絞り込む場合、プロフィールのないメンバーは対象外になります
+type MemberFilter struct {
+	EnrollmentYear int    // 入学年度
+	Faculty        string // 学部コード
+
+	// Query はユーザー名・表示名・サーバーニックネーム・氏名の部分一致検索の文字列です
+	Query string
+	// Usernames はいずれかのユーザー名に完全一致するメンバーに絞り込むユーザー名の一覧です
+	Usernames []string
+	// RoleIDs はいずれかのロールを持つメンバーに絞り込むロールIDの一覧です
+	RoleIDs []string
+	// JoinedAfter・JoinedBefore はサーバー参加日時の範囲です（JoinedAfterを含み、JoinedBeforeを含まない）
+	JoinedAfter  *time.Time
+	JoinedBefore *time.Time
+	// HobbiesKeywords は趣味にすべて含まれるキーワードです
+	HobbiesKeywords []string
+	// HasProfile はプロフィールの有無です（nilの場合は条件に含めません）
+	HasProfile *bool
+
+	// Viewer は絞り込みを行う閲覧者です
+	// 設定されている場合、閲覧できない項目（学籍番号・氏名・趣味）の値は条件に一致しません
+	Viewer *ProfileViewer
+}
+
+// IsEmpty は絞り込み条件が指定されていないかどうかを確認します
+func (f MemberFilter) IsEmpty() bool {
+	return f.EnrollmentYear == 0 && f.Faculty == "" && f.Query == "" && len(f.Usernames) == 0 && len(f.RoleIDs) == 0 &&
+		f.JoinedAfter == nil && f.JoinedBefore == nil && len(f.HobbiesKeywords) == 0 && f.HasProfile == nil
+}
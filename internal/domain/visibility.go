@@ -0,0 +1,223 @@
+package domain
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// FieldVisibility はプロフィールの項目ごとの公開範囲を表します
+type FieldVisibility string
+
+const (
+	// VisibilityMembers はサーバーのメンバー全員に公開します（既定）
+	VisibilityMembers FieldVisibility = "members"
+	// VisibilityOfficers は幹部ロールを持つメンバーにのみ公開します
+	VisibilityOfficers FieldVisibility = "officers"
+	// VisibilityHidden は本人以外には公開しません
+	VisibilityHidden FieldVisibility = "hidden"
+)
+
+// 公開範囲を設定できるプロフィールの固定フィールドのキー
+// （Extraの項目はそのキーで設定します）
+const (
+	ProfileFieldRealName  = "real_name"
+	ProfileFieldStudentID = "student_id"
+	ProfileFieldHobbies   = "hobbies"
+	ProfileFieldWhatToDo  = "what_to_do"
+	ProfileFieldComment   = "comment"
+)
+
+// ProfileFields は公開範囲を設定できる固定フィールドのキーの一覧です
+var ProfileFields = []string{
+	ProfileFieldRealName,
+	ProfileFieldStudentID,
+	ProfileFieldHobbies,
+	ProfileFieldWhatToDo,
+	ProfileFieldComment,
+}
+
+// 閲覧者の権限の強さ（公開範囲の強さと比較します）
+const (
+	accessNone = iota
+	accessMembers
+	accessOfficers
+	accessSelf
+)
+
+// ParseFieldVisibility は文字列を公開範囲に変換します（空文字列はmembers）
+func ParseFieldVisibility(s string) (FieldVisibility, error) {
+	switch v := FieldVisibility(s); v {
+	case "":
+		return VisibilityMembers, nil
+	case VisibilityMembers, VisibilityOfficers, VisibilityHidden:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid visibility: %s (must be members, officers or hidden)", s)
+	}
+}
+
+// requiredAccess は公開範囲の項目を閲覧するのに必要な権限を返します
+func (v FieldVisibility) requiredAccess() int {
+	switch v {
+	case VisibilityOfficers:
+		return accessOfficers
+	case VisibilityHidden:
+		return accessSelf
+	default:
+		return accessMembers
+	}
+}
+
+// ProfileViewer はプロフィールを閲覧する主体（ログイン中のユーザーと、経由するクライアント）を表します
+type ProfileViewer struct {
+	UserID  string // 閲覧者のユーザーID
+	Officer bool   // 閲覧者が幹部ロールを持つかどうか
+	Member  bool   // 閲覧者がサーバーに在籍しているかどうか
+
+	// ClientID はOAuth2クライアント経由で閲覧する場合のクライアントIDです（本サーバーの画面・APIでは空）
+	ClientID string
+	// ClientAccess はクライアントに許可された公開範囲の上限です
+	// クライアント経由ではhiddenの項目は本人であっても返しません
+	ClientAccess FieldVisibility
+}
+
+// NewProfileViewer はユーザーのロールから閲覧者を作成します
+// officerRoleIDsのいずれかのロールを持つ在籍中のユーザーを幹部として扱います
+func NewProfileViewer(user *User, officerRoleIDs []string) *ProfileViewer {
+	viewer := &ProfileViewer{
+		UserID: user.ID,
+		Member: user.IsGuildMember(),
+	}
+	if viewer.Member {
+		for _, roleID := range user.GuildRoles {
+			if slices.Contains(officerRoleIDs, roleID) {
+				viewer.Officer = true
+				break
+			}
+		}
+	}
+	return viewer
+}
+
+// ViaClient はクライアント経由の閲覧者を返します
+func (v *ProfileViewer) ViaClient(client *ClientApp) *ProfileViewer {
+	viaClient := *v
+	viaClient.ClientID = client.ClientID
+	viaClient.ClientAccess = client.ProfileAccessOrDefault()
+	return &viaClient
+}
+
+// ViaServiceProvider はSAMLのサービス経由の閲覧者を返します（クライアントIDにはエンティティIDを使用します）
+func (v *ProfileViewer) ViaServiceProvider(sp *SAMLServiceProvider) *ProfileViewer {
+	viaSP := *v
+	viaSP.ClientID = sp.EntityID
+	viaSP.ClientAccess = sp.ProfileAccessOrDefault()
+	return &viaSP
+}
+
+// access はownerUserIDのプロフィールに対する閲覧者の権限を返します
+func (v *ProfileViewer) access(ownerUserID string) int {
+	if v == nil {
+		return accessNone
+	}
+
+	level := accessNone
+	switch {
+	case v.UserID != "" && v.UserID == ownerUserID:
+		level = accessSelf
+	case v.Officer:
+		level = accessOfficers
+	case v.Member:
+		level = accessMembers
+	}
+
+	if v.ClientID != "" {
+		level = min(level, v.ClientAccess.requiredAccess(), accessOfficers)
+	}
+	return level
+}
+
+// CanSee は閲覧者がownerUserIDのプロフィールの公開範囲visibilityの項目を閲覧できるかどうかを確認します
+func (v *ProfileViewer) CanSee(ownerUserID string, visibility FieldVisibility) bool {
+	return v.access(ownerUserID) >= visibility.requiredAccess()
+}
+
+// VisibleLevels はownerUserIDのプロフィールのうち閲覧者が閲覧できる公開範囲の一覧を返します
+func (v *ProfileViewer) VisibleLevels(ownerUserID string) []FieldVisibility {
+	var levels []FieldVisibility
+	for _, visibility := range []FieldVisibility{VisibilityMembers, VisibilityOfficers, VisibilityHidden} {
+		if v.CanSee(ownerUserID, visibility) {
+			levels = append(levels, visibility)
+		}
+	}
+	return levels
+}
+
+// VisibilityOf はプロフィールの項目の公開範囲を返します（未設定の場合はmembers）
+func (p *Profile) VisibilityOf(key string) FieldVisibility {
+	if v, ok := p.Visibility[key]; ok && v != "" {
+		return v
+	}
+	return VisibilityMembers
+}
+
+// ValidateVisibility は公開範囲の設定が有効かどうかを確認します
+// キーは固定フィールドまたはExtraの項目である必要があります
+func (p *Profile) ValidateVisibility(visibility map[string]FieldVisibility) error {
+	for key, v := range visibility {
+		if !slices.Contains(ProfileFields, key) {
+			if _, ok := p.Extra[key]; !ok {
+				return fmt.Errorf("unknown profile field: %s", key)
+			}
+		}
+		if _, err := ParseFieldVisibility(string(v)); err != nil {
+			return fmt.Errorf("field %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// RedactFor は閲覧者が閲覧できない項目を取り除いたプロフィールのコピーを返します
+// 学籍番号を閲覧できない場合は、学籍番号から求めた入学年度・学部コードも取り除きます
+func (p *Profile) RedactFor(viewer *ProfileViewer) *Profile {
+	redacted := *p
+	redacted.Extra = maps.Clone(p.Extra)
+	redacted.Visibility = maps.Clone(p.Visibility)
+	redacted.Sources = maps.Clone(p.Sources)
+
+	visible := func(key string) bool {
+		return viewer.CanSee(p.UserID, p.VisibilityOf(key))
+	}
+
+	if !visible(ProfileFieldRealName) {
+		redacted.RealName = ""
+	}
+	if !visible(ProfileFieldStudentID) {
+		redacted.StudentID = ""
+		redacted.EnrollmentYear = 0
+		redacted.Faculty = ""
+	}
+	if !visible(ProfileFieldHobbies) {
+		redacted.Hobbies = ""
+	}
+	if !visible(ProfileFieldWhatToDo) {
+		redacted.WhatToDo = ""
+	}
+	if !visible(ProfileFieldComment) {
+		redacted.Comment = ""
+	}
+	for key := range redacted.Extra {
+		if !visible(key) {
+			delete(redacted.Extra, key)
+		}
+	}
+
+	// 公開範囲の設定と項目の出所は本人にのみ返す
+	if viewer.access(p.UserID) < accessSelf {
+		redacted.Visibility = nil
+		redacted.Sources = nil
+	}
+
+	return &redacted
+}
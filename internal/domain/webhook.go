@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxWebhookEndpointsPerClient はクライアントごとに登録できるWebhookの送信先の上限です
+const MaxWebhookEndpointsPerClient = 10
+
+// WebhookEndpoint はクライアントアプリが登録したWebhookの送信先です
+type WebhookEndpoint struct {
+	ID        string
+	ClientID  string // 登録したクライアントのClient ID
+	URL       string
+	Secret    string // 署名に使用する共有シークレット（署名の計算に必要なため平文で保存します）
+	Events    []EventType
+	Active    bool // 無効にした送信先には配信しません
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribes は送信先が出来事の種類を購読しているかどうかを返します
+func (e *WebhookEndpoint) Subscribes(eventType EventType) bool {
+	return e.Active && slices.Contains(e.Events, eventType)
+}
+
+// Validate は送信先のURLと購読する出来事の種類を検証します
+// URLはHTTPSのみ許可します（開発環境向けに http://localhost も許可します）
+func (e *WebhookEndpoint) Validate() error {
+	if err := ValidateWebhookURL(e.URL); err != nil {
+		return err
+	}
+	if len(e.Events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	for _, eventType := range e.Events {
+		if _, err := ParseEventType(string(eventType)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateWebhookURL はWebhookの送信先のURLを検証します
+func ValidateWebhookURL(rawURL string) error {
+	if len(rawURL) > 2048 {
+		return fmt.Errorf("%w: url is too long", ErrInvalidWebhook)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: invalid url %q", ErrInvalidWebhook, rawURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: url must not contain credentials", ErrInvalidWebhook)
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && (u.Hostname() == "localhost" || strings.HasSuffix(u.Hostname(), ".localhost")):
+	default:
+		return fmt.Errorf("%w: url must use https (http is allowed only for localhost)", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+// WebhookDeliveryStatus はWebhookの配信の状態を表します
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending は配信待ち（再試行待ちを含む）です
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded は送信先が2xxを返した配信です
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryFailed は再試行の上限に達した（または送信先が削除・無効化された）配信です
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery はWebhookの1回の配信（再試行を含む）の記録です
+// 配信は永続化され、サーバーの再起動後も再試行されます
+type WebhookDelivery struct {
+	ID             string
+	EndpointID     string
+	EventID        string
+	EventType      EventType
+	Payload        string // 送信する本文（JSON）
+	Status         WebhookDeliveryStatus
+	Attempts       int       // 送信を試みた回数
+	NextAttemptAt  time.Time // 次に送信を試みる日時
+	LastStatusCode int       // 最後の送信で送信先が返したHTTPステータス（接続できなかった場合は0）
+	LastError      string
+	ReplayOf       string // 再送の場合は元の配信のID
+	CreatedAt      time.Time
+	CompletedAt    *time.Time // 成功または失敗が確定した日時
+}
@@ -60,14 +60,21 @@ func (h *APIHandler) HandleUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// DTOに変換して返す
-	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile)
+	// DTOに変換して返す（本人のプロフィールなので全項目を返す）
+	viewer := h.authService.ProfileViewer(memberWithProfile.User)
+	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile, memberWithProfile.Roles, viewer)
 	WriteJSON(w, http.StatusOK, dto)
 }
 
 // HandleUserByID は指定されたIDのユーザー情報を返します
 // GET /api/user/{id}
 func (h *APIHandler) HandleUserByID(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r.Context())
+	if !ok {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Failed to get user claims")
+		return
+	}
+
 	// URLパラメータからIDを取得
 	userID := r.PathValue("id")
 	if userID == "" {
@@ -83,7 +90,15 @@ func (h *APIHandler) HandleUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 閲覧者のロールに応じてプロフィールの公開範囲を判定する
+	viewer, err := h.authService.GetProfileViewer(r.Context(), claims.UserID)
+	if err != nil {
+		log.Printf("Failed to get viewer: %v", err)
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "User not found")
+		return
+	}
+
 	// DTOに変換して返す
-	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile)
+	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile, memberWithProfile.Roles, viewer)
 	WriteJSON(w, http.StatusOK, dto)
 }
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// maxAppPasswordFormBytes はアプリパスワードのフォームの最大サイズです
+const maxAppPasswordFormBytes = 8 << 10
+
+// AppPasswordHandler はアプリパスワードの管理画面のハンドラーを表します
+type AppPasswordHandler struct {
+	appPasswordService *service.AppPasswordService
+	authService        *service.AuthService
+	templates          *template.Template
+}
+
+// NewAppPasswordHandler は新しいアプリパスワードハンドラーを作成します
+func NewAppPasswordHandler(appPasswordService *service.AppPasswordService, authService *service.AuthService) *AppPasswordHandler {
+	// テンプレートをパース
+	templates, err := template.ParseGlob("web/templates/*.html")
+	if err != nil {
+		log.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	return &AppPasswordHandler{
+		appPasswordService: appPasswordService,
+		authService:        authService,
+		templates:          templates,
+	}
+}
+
+// appPasswordScopeLabels はスコープの画面の表示名です
+var appPasswordScopeLabels = map[string]string{
+	domain.AppPasswordScopeLDAP:    "LDAP（Gitea・Wiki.jsなどのログイン）",
+	domain.AppPasswordScopeProxy:   "HTTP Basic認証（フォワード認証・認証プロキシで保護されたサイト）",
+	domain.AppPasswordScopeProfile: "自分のユーザー情報の取得（/api/user・/oauth/userinfo）",
+	domain.AppPasswordScopeMembers: "メンバー情報の取得（/api/user/{id}・/oauth/members）",
+}
+
+// appPasswordExpiryDays は発行画面で選択できる有効期間（日数。0は無期限）です
+var appPasswordExpiryDays = []int{30, 90, 365, 0}
+
+// appPasswordView は一覧に表示するアプリパスワードです
+type appPasswordView struct {
+	ID         string
+	Name       string
+	Scopes     string
+	CreatedAt  string
+	ExpiresAt  string
+	Expired    bool
+	LastUsedAt string
+}
+
+// appPasswordScopeView は発行画面のスコープの選択肢です
+type appPasswordScopeView struct {
+	Value string
+	Label string
+}
+
+// HandleAppPasswords はログイン中のユーザーのアプリパスワードを表示・発行・取り消します
+// GET  /account/app-passwords  一覧
+// POST /account/app-passwords  action=create（name・scope・expires_in_days）で発行、action=revoke（id）で取り消し
+func (h *AppPasswordHandler) HandleAppPasswords(w http.ResponseWriter, r *http.Request) {
+	sessionCookie, err := r.Cookie("session_token")
+	var user *domain.User
+	if err == nil {
+		user, err = h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	}
+	if err != nil {
+		http.Redirect(w, r, "/auth/login?redirect_uri=/account/app-passwords", http.StatusFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.renderAppPasswords(w, r, user, "", "", "")
+	case http.MethodPost:
+		h.handleAppPasswordFormSubmit(w, r, user)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET and POST are allowed")
+	}
+}
+
+// handleAppPasswordFormSubmit は管理画面からのフォーム送信を処理します
+func (h *AppPasswordHandler) handleAppPasswordFormSubmit(w http.ResponseWriter, r *http.Request, user *domain.User) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAppPasswordFormBytes)
+	if err := r.ParseForm(); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Failed to parse form")
+		return
+	}
+
+	// CSRFトークンの検証
+	csrfToken := r.FormValue("csrf_token")
+	csrfCookie, err := r.Cookie("csrf_token")
+	if err != nil || csrfCookie.Value == "" || csrfToken == "" || csrfToken != csrfCookie.Value {
+		log.Printf("CSRF token validation failed")
+		http.Error(w, "Forbidden: Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	switch r.FormValue("action") {
+	case "create":
+		days, err := strconv.Atoi(r.FormValue("expires_in_days"))
+		if err != nil || !slices.Contains(appPasswordExpiryDays, days) {
+			h.renderAppPasswords(w, r, user, "有効期間を選択してください", "", "")
+			return
+		}
+		password, plain, err := h.appPasswordService.Create(r.Context(), user.ID, r.FormValue("name"), r.Form["scope"], time.Duration(days)*24*time.Hour)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidAppPassword) {
+				h.renderAppPasswords(w, r, user, "名前（64文字以内）と用途を1つ以上入力してください（アプリパスワードは1人20個まで発行できます）", "", "")
+				return
+			}
+			log.Printf("Failed to create app password: %v", err)
+			h.renderAppPasswords(w, r, user, "アプリパスワードの発行に失敗しました", "", "")
+			return
+		}
+		h.renderAppPasswords(w, r, user, "", "アプリパスワード「"+password.Name+"」を発行しました", plain)
+	case "revoke":
+		if err := h.appPasswordService.Revoke(r.Context(), user.ID, r.FormValue("id")); err != nil {
+			if errors.Is(err, domain.ErrAppPasswordNotFound) {
+				h.renderAppPasswords(w, r, user, "アプリパスワードが見つかりません", "", "")
+				return
+			}
+			log.Printf("Failed to revoke app password: %v", err)
+			h.renderAppPasswords(w, r, user, "アプリパスワードの取り消しに失敗しました", "", "")
+			return
+		}
+		h.renderAppPasswords(w, r, user, "", "アプリパスワードを取り消しました", "")
+	default:
+		WriteError(w, http.StatusBadRequest, "invalid_request", "action must be create or revoke")
+	}
+}
+
+// renderAppPasswords は管理画面を表示します（plainは発行直後の一度だけ表示する平文のパスワード）
+func (h *AppPasswordHandler) renderAppPasswords(w http.ResponseWriter, r *http.Request, user *domain.User, errorMsg, message, plain string) {
+	passwords, err := h.appPasswordService.List(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to list app passwords: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to list app passwords")
+		return
+	}
+
+	now := time.Now()
+	views := make([]appPasswordView, len(passwords))
+	for i, p := range passwords {
+		views[i] = appPasswordView{
+			ID:         p.ID,
+			Name:       p.Name,
+			Scopes:     strings.Join(p.Scopes, ", "),
+			CreatedAt:  p.CreatedAt.Format("2006/01/02 15:04"),
+			ExpiresAt:  "無期限",
+			Expired:    p.IsExpired(now),
+			LastUsedAt: "未使用",
+		}
+		if p.ExpiresAt != nil {
+			views[i].ExpiresAt = p.ExpiresAt.Format("2006/01/02 15:04")
+		}
+		if p.LastUsedAt != nil {
+			views[i].LastUsedAt = p.LastUsedAt.Format("2006/01/02 15:04")
+		}
+	}
+
+	scopes := make([]appPasswordScopeView, len(domain.AppPasswordScopes))
+	for i, scope := range domain.AppPasswordScopes {
+		scopes[i] = appPasswordScopeView{Value: scope, Label: appPasswordScopeLabels[scope]}
+	}
+
+	data := map[string]interface{}{
+		"Username":    user.Username,
+		"Passwords":   views,
+		"Scopes":      scopes,
+		"ExpiryDays":  appPasswordExpiryDays,
+		"NewPassword": plain,
+		"Error":       errorMsg,
+		"Message":     message,
+	}
+
+	// CSRFトークンを生成
+	csrfToken, err := h.authService.GenerateState()
+	if err == nil {
+		SetSecureCookie(w, r, CookieOptions{
+			Name:     "csrf_token",
+			Value:    csrfToken,
+			Path:     "/",
+			MaxAge:   1800, // 30分
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		data["CSRFToken"] = csrfToken
+	} else {
+		log.Printf("Failed to generate CSRF token: %v", err)
+	}
+
+	// 発行直後の平文のパスワードをキャッシュさせない
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.templates.ExecuteTemplate(w, "account_app_passwords.html", data); err != nil {
+		log.Printf("Failed to render app passwords template: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to render page")
+		return
+	}
+}
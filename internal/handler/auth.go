@@ -17,16 +17,38 @@ import (
 type AuthHandler struct {
 	authService    *service.AuthService
 	allowedOrigins []string
+	accessPolicies domain.AccessPolicies // フォワード認証で保護するホスト（ログイン後のリダイレクト先として許可する）
+	cookieDomain   string                // セッションCookieのDomain属性（空の場合はこのサーバーのホストのみ）
 }
 
 // NewAuthHandler は新しい認証ハンドラーを作成します
-func NewAuthHandler(authService *service.AuthService, allowedOrigins []string) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, allowedOrigins []string, accessPolicies domain.AccessPolicies, cookieDomain string) *AuthHandler {
 	return &AuthHandler{
 		authService:    authService,
 		allowedOrigins: allowedOrigins,
+		accessPolicies: accessPolicies,
+		cookieDomain:   cookieDomain,
 	}
 }
 
+// isAllowedRedirect はリダイレクト先が内部パス・許可オリジン・フォワード認証で保護するホストのいずれかかどうかを返します
+func (h *AuthHandler) isAllowedRedirect(redirectURI string) bool {
+	if redirectURI == "" {
+		return false
+	}
+	// 内部パス（/で始まる）は許可
+	if strings.HasPrefix(redirectURI, "/") {
+		return true
+	}
+	for _, origin := range h.allowedOrigins {
+		// 完全一致または正当なパス指定を確認（"example.com.attacker.com"のような攻撃を防ぐ）
+		if redirectURI == origin || strings.HasPrefix(redirectURI, origin+"/") {
+			return true
+		}
+	}
+	return isSafeForwardAuthRedirect(redirectURI, h.accessPolicies)
+}
+
 // HandleLogin はログインリクエストを処理します
 // Discord OAuth2認証ページにリダイレクトします
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
@@ -34,21 +56,7 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	redirectURI := r.URL.Query().Get("redirect_uri")
 
 	// redirect_uriの検証
-	isValidOrigin := false
-	if redirectURI != "" {
-		// 内部パス（/で始まる）は許可
-		if strings.HasPrefix(redirectURI, "/") {
-			isValidOrigin = true
-		} else {
-			for _, origin := range h.allowedOrigins {
-				// 完全一致または正当なパス指定を確認（"example.com.attacker.com"のような攻撃を防ぐ）
-				if redirectURI == origin || strings.HasPrefix(redirectURI, origin+"/") {
-					isValidOrigin = true
-					break
-				}
-			}
-		}
-	}
+	isValidOrigin := h.isAllowedRedirect(redirectURI)
 
 	// Cookieから既存のredirect_uriを確認（OAuth2フロー中の場合は既に設定されている可能性がある）
 	// また、/oauth/authorize経由の場合は元のURLを保持する必要がある
@@ -67,18 +75,7 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// 内部パスか許可オリジンか再検証
-			if redirectURI != "" {
-				if strings.HasPrefix(redirectURI, "/") {
-					isValidOrigin = true
-				} else {
-					for _, origin := range h.allowedOrigins {
-						if redirectURI == origin || strings.HasPrefix(redirectURI, origin+"/") {
-							isValidOrigin = true
-							break
-						}
-					}
-				}
-			}
+			isValidOrigin = h.isAllowedRedirect(redirectURI)
 		}
 	}
 
@@ -182,6 +179,7 @@ func (h *AuthHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 		Name:     "session_token",
 		Value:    sessionToken,
 		Path:     "/",
+		Domain:   h.cookieDomain,
 		MaxAge:   7 * 24 * 60 * 60, // 7日間
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
@@ -205,21 +203,7 @@ func (h *AuthHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// redirect_uriの再検証（念のため）
-	isValidOrigin := false
-	if redirectURL != "" {
-		// 内部パス（/で始まる）は許可
-		if strings.HasPrefix(redirectURL, "/") {
-			isValidOrigin = true
-		} else {
-			for _, origin := range h.allowedOrigins {
-				// 完全一致または正当なパス指定を確認（"example.com.attacker.com"のような攻撃を防ぐ）
-				if redirectURL == origin || strings.HasPrefix(redirectURL, origin+"/") {
-					isValidOrigin = true
-					break
-				}
-			}
-		}
-	}
+	isValidOrigin := h.isAllowedRedirect(redirectURL)
 
 	if !isValidOrigin {
 		if len(h.allowedOrigins) > 0 {
@@ -259,6 +243,9 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 
 	// セッションCookieを削除
 	DeleteCookie(w, r, "session_token", "/")
+	if h.cookieDomain != "" {
+		DeleteDomainCookie(w, r, "session_token", "/", h.cookieDomain)
+	}
 
 	// リダイレクトURLを取得（redirect_url または redirect_uri をサポート）
 	redirectURL := r.URL.Query().Get("redirect_url")
@@ -268,24 +255,8 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 
 	// リダイレクトURLが指定されている場合は検証してリダイレクト
 	if redirectURL != "" {
-		isValidOrigin := false
-
-		// 内部パス（/で始まる）は許可
-		if strings.HasPrefix(redirectURL, "/") {
-			isValidOrigin = true
-		} else {
-			// 許可オリジンリストと照合
-			for _, origin := range h.allowedOrigins {
-				// 完全一致または正当なパス指定を確認（"example.com.attacker.com"のような攻撃を防ぐ）
-				if redirectURL == origin || strings.HasPrefix(redirectURL, origin+"/") {
-					isValidOrigin = true
-					break
-				}
-			}
-		}
-
 		// 検証が通ればリダイレクト
-		if isValidOrigin {
+		if h.isAllowedRedirect(redirectURL) {
 			http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
 			return
 		}
@@ -333,7 +304,9 @@ func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleMembers はじょぎメンバー一覧を返します
-// GET /api/members?limit=50&offset=0
+// GET /api/members?limit=50&offset=0&q=taro&role=Member&enrollment_year=2024&sort=joined_at&cursor=...
+// 絞り込み条件はparseMemberFilter、並び順とカーソルはparseMemberPageを参照してください
+// cursorを指定した場合はoffsetを無視し、続きがある場合はnext_cursorを返します
 func (h *AuthHandler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 	// セッショントークンを取得
 	sessionCookie, err := r.Cookie("session_token")
@@ -343,11 +316,12 @@ func (h *AuthHandler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// セッションを検証
-	_, err = h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
 	if err != nil {
 		WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired session")
 		return
 	}
+	viewer := h.authService.ProfileViewer(user)
 
 	// ページネーションパラメータの取得
 	limitStr := r.URL.Query().Get("limit")
@@ -371,8 +345,26 @@ func (h *AuthHandler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	filter, err := parseMemberFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+	filter.Viewer = viewer
+
+	page, err := parseMemberPage(r.URL.Query(), limit, offset)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_page", err.Error())
+		return
+	}
+	includeTotal, err := parseIncludeTotal(r.URL.Query())
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_page", err.Error())
+		return
+	}
+
 	// メンバー一覧をプロフィール情報付きで取得
-	membersWithProfiles, err := h.authService.GetMembersWithProfiles(r.Context(), limit, offset)
+	membersWithProfiles, next, err := h.authService.GetMembersWithProfiles(r.Context(), filter, page)
 	if err != nil {
 		log.Printf("Failed to get members: %v", err)
 		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get members")
@@ -382,14 +374,28 @@ func (h *AuthHandler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 	// DTOに変換
 	membersList := make([]*UserWithProfile, len(membersWithProfiles))
 	for i, memberWithProfile := range membersWithProfiles {
-		membersList[i] = NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile)
+		membersList[i] = NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile, memberWithProfile.Roles, viewer)
 	}
 
 	// メンバー一覧を返す
-	WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"members": membersList,
-		"limit":   limit,
-		"offset":  offset,
-		"count":   len(membersList),
-	})
+	response := map[string]interface{}{
+		"members":  membersList,
+		"limit":    limit,
+		"offset":   offset,
+		"count":    len(membersList),
+		"has_more": next != nil,
+	}
+	if next != nil {
+		response["next_cursor"] = next.Encode()
+	}
+	if includeTotal {
+		total, err := h.authService.CountMembers(r.Context(), filter)
+		if err != nil {
+			log.Printf("Failed to count members: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to count members")
+			return
+		}
+		response["total"] = total
+	}
+	WriteJSON(w, http.StatusOK, response)
 }
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
@@ -17,13 +18,14 @@ import (
 
 // ClientHandler はクライアント管理ハンドラーを表します
 type ClientHandler struct {
-	clientService *service.ClientService
-	authService   *service.AuthService
-	templates     *template.Template
+	clientService  *service.ClientService
+	authService    *service.AuthService
+	webhookService *service.WebhookService
+	templates      *template.Template
 }
 
 // NewClientHandler は新しいクライアント管理ハンドラーを作成します
-func NewClientHandler(clientService *service.ClientService, authService *service.AuthService) *ClientHandler {
+func NewClientHandler(clientService *service.ClientService, authService *service.AuthService, webhookService *service.WebhookService) *ClientHandler {
 	// テンプレートをパース
 	templates, err := template.ParseGlob("web/templates/*.html")
 	if err != nil {
@@ -31,9 +33,10 @@ func NewClientHandler(clientService *service.ClientService, authService *service
 	}
 
 	return &ClientHandler{
-		clientService: clientService,
-		authService:   authService,
-		templates:     templates,
+		clientService:  clientService,
+		authService:    authService,
+		webhookService: webhookService,
+		templates:      templates,
 	}
 }
 
@@ -50,6 +53,7 @@ func (h *ClientHandler) HandleIndex(w http.ResponseWriter, r *http.Request) {
 			user = map[string]interface{}{
 				"Username":  u.Username,
 				"AvatarURL": u.AvatarURL,
+				"IsOfficer": h.authService.ProfileViewer(u).Officer,
 			}
 		}
 	}
@@ -322,7 +326,7 @@ func (h *ClientHandler) HandleEditClientForm(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	_, err = h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
 	if err != nil {
 		http.Redirect(w, r, "/auth/login?redirect_uri="+r.URL.Path, http.StatusFound)
 		return
@@ -344,22 +348,8 @@ func (h *ClientHandler) HandleEditClientForm(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// RedirectURIsを改行区切りのテキストに変換
-	redirectURIsText := strings.Join(client.RedirectURIs, "\n")
-
-	// テンプレートデータ
-	data := map[string]interface{}{
-		"Client":           client,
-		"RedirectURIsText": redirectURIsText,
-		"Error":            nil,
-	}
-
-	// テンプレートをレンダリング
-	if err := h.templates.ExecuteTemplate(w, "edit_client.html", data); err != nil {
-		log.Printf("Failed to render edit template: %v", err)
-		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to render page")
-		return
-	}
+	// テンプレートをレンダリング（所有者と幹部にはWebhookの管理欄も表示する）
+	h.renderEditPage(w, r, user, client, http.StatusOK, nil)
 }
 
 // HandleUpdateClient はPOST /clients/:idを処理します
@@ -372,11 +362,12 @@ func (h *ClientHandler) HandleUpdateClient(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	_, err = h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
 	if err != nil {
 		http.Redirect(w, r, "/auth/login", http.StatusFound)
 		return
 	}
+	canSetProfileAccess := h.authService.ProfileViewer(user).Officer
 
 	// URLからクライアントIDを取得
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
@@ -445,6 +436,26 @@ func (h *ClientHandler) HandleUpdateClient(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	// プロフィールの公開範囲の上限（幹部のみ変更可能）
+	profileAccess := client.ProfileAccessOrDefault()
+	if canSetProfileAccess && r.FormValue("profile_access") != "" {
+		profileAccess = domain.FieldVisibility(r.FormValue("profile_access"))
+		if profileAccess != domain.VisibilityMembers && profileAccess != domain.VisibilityOfficers {
+			h.renderEditFormWithError(w, client, "プロフィールの公開範囲が不正です", redirectURIsRaw)
+			return
+		}
+	}
+
+	// アクセストークンの形式
+	accessTokenFormat := client.AccessTokenFormatOrDefault()
+	if r.FormValue("access_token_format") != "" {
+		accessTokenFormat = r.FormValue("access_token_format")
+		if !slices.Contains(domain.AccessTokenFormats, accessTokenFormat) {
+			h.renderEditFormWithError(w, client, "アクセストークンの形式が不正です", redirectURIsRaw)
+			return
+		}
+	}
+
 	// ClientServiceで更新 (Secretは変更しない)
 	_, err = h.clientService.UpdateClient(r.Context(), client.ClientID, "", name, redirectURIs)
 	if err != nil {
@@ -452,6 +463,20 @@ func (h *ClientHandler) HandleUpdateClient(w http.ResponseWriter, r *http.Reques
 		h.renderEditFormWithError(w, client, "クライアントの更新に失敗しました", redirectURIsRaw)
 		return
 	}
+	if profileAccess != client.ProfileAccessOrDefault() {
+		if _, err := h.clientService.SetProfileAccess(r.Context(), client.ClientID, profileAccess); err != nil {
+			log.Printf("Failed to update client profile access: %v", err)
+			h.renderEditFormWithError(w, client, "プロフィールの公開範囲の更新に失敗しました", redirectURIsRaw)
+			return
+		}
+	}
+	if accessTokenFormat != client.AccessTokenFormatOrDefault() {
+		if _, err := h.clientService.SetAccessTokenFormat(r.Context(), client.ClientID, accessTokenFormat); err != nil {
+			log.Printf("Failed to update client access token format: %v", err)
+			h.renderEditFormWithError(w, client, "アクセストークンの形式の更新に失敗しました", redirectURIsRaw)
+			return
+		}
+	}
 
 	// 一覧画面にリダイレクト
 	http.Redirect(w, r, "/clients", http.StatusFound)
@@ -520,6 +545,11 @@ func (h *ClientHandler) HandleDeleteClient(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// クライアントのWebhookの送信先も削除する
+	if err := h.webhookService.DeleteClientEndpoints(r.Context(), client.ClientID); err != nil {
+		log.Printf("Warning: Failed to delete webhook endpoints of client %s: %v", client.ClientID, err)
+	}
+
 	WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "Client deleted successfully",
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// maxClientMetadataBytes はクライアントのメタデータ（JSON）の最大サイズです
+const maxClientMetadataBytes = 64 << 10
+
+// ClientRegistrationHandler はクライアントの動的登録（RFC 7591）と登録内容の管理（RFC 7592）のハンドラーを表します
+type ClientRegistrationHandler struct {
+	registrationService *service.ClientRegistrationService
+	authService         *service.AuthService
+	webhookService      *service.WebhookService
+	registrationURI     string
+}
+
+// NewClientRegistrationHandler は新しいクライアント登録ハンドラーを作成します
+// registrationURIは登録エンドポイント（/oauth/register）の公開URLです
+func NewClientRegistrationHandler(registrationService *service.ClientRegistrationService, authService *service.AuthService, webhookService *service.WebhookService, registrationURI string) *ClientRegistrationHandler {
+	return &ClientRegistrationHandler{
+		registrationService: registrationService,
+		authService:         authService,
+		webhookService:      webhookService,
+		registrationURI:     registrationURI,
+	}
+}
+
+// clientInformationResponse はクライアント情報のレスポンス（RFC 7591 3.2.1・RFC 7592 3）を表します
+// Client Secretと登録アクセストークンは登録時のみ返します
+type clientInformationResponse struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+	*domain.ClientMetadata
+}
+
+// clientUpdateRequest はクライアントの更新リクエスト（RFC 7592 2.2）を表します
+type clientUpdateRequest struct {
+	domain.ClientMetadata
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// HandleIssueInitialAccessToken はPOST /api/clients/initial-access-tokenを処理します
+// ログイン中のメンバーに、スクリプトからクライアントを1つ登録するための初期アクセストークンを発行します
+func (h *ClientRegistrationHandler) HandleIssueInitialAccessToken(w http.ResponseWriter, r *http.Request) {
+	// セッショントークンを取得
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+		return
+	}
+
+	// セッショントークンからユーザー情報を取得
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "invalid_session", "Session is invalid or expired")
+		return
+	}
+
+	token, issued, err := h.registrationService.IssueInitialAccessToken(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to issue initial access token: %v", err)
+		WriteError(w, http.StatusInternalServerError, "token_generation_failed", "Failed to issue initial access token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"initial_access_token": token,
+		"token_type":           "Bearer",
+		"expires_in":           int(domain.InitialAccessTokenExpiration.Seconds()),
+		"registration_uri":     h.registrationURI,
+		"expires_at":           issued.ExpiresAt,
+	})
+}
+
+// HandleRegister はPOST /oauth/registerを処理します
+// 初期アクセストークン（Bearer）で認証し、メタデータの内容でクライアントを登録します（RFC 7591 3）
+func (h *ClientRegistrationHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only POST is allowed")
+		return
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		writeRegistrationTokenError(w)
+		return
+	}
+
+	var metadata domain.ClientMetadata
+	if !decodeClientMetadata(w, r, &metadata) {
+		return
+	}
+
+	registration, err := h.registrationService.Register(r.Context(), token, &metadata)
+	if err != nil {
+		h.writeRegistrationError(w, err, "Failed to register client")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	resp := h.clientInformation(registration.Client)
+	resp.ClientSecret = registration.ClientSecret
+	resp.RegistrationAccessToken = registration.RegistrationAccessToken
+	WriteJSON(w, http.StatusCreated, resp)
+}
+
+// HandleClientConfiguration は /oauth/register/{client_id} を処理します（RFC 7592）
+// GET    登録内容の取得
+// PUT    メタデータの置き換え（Client IDとClient Secretは変更しません）
+// DELETE クライアントの削除
+// いずれも登録時に発行した登録アクセストークン（Bearer）で認証します
+func (h *ClientRegistrationHandler) HandleClientConfiguration(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+	token := bearerToken(r)
+	if token == "" {
+		writeRegistrationTokenError(w)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	switch r.Method {
+	case http.MethodGet:
+		client, err := h.registrationService.GetClient(r.Context(), clientID, token)
+		if err != nil {
+			h.writeRegistrationError(w, err, "Failed to get client")
+			return
+		}
+		WriteJSON(w, http.StatusOK, h.clientInformation(client))
+
+	case http.MethodPut:
+		var req clientUpdateRequest
+		if !decodeClientMetadata(w, r, &req) {
+			return
+		}
+		if req.ClientID != clientID {
+			WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error":             "invalid_request",
+				"error_description": "client_id in the request body must match the client being updated",
+			})
+			return
+		}
+		client, err := h.registrationService.UpdateClient(r.Context(), clientID, token, req.ClientSecret, &req.ClientMetadata)
+		if err != nil {
+			h.writeRegistrationError(w, err, "Failed to update client")
+			return
+		}
+		WriteJSON(w, http.StatusOK, h.clientInformation(client))
+
+	case http.MethodDelete:
+		if err := h.registrationService.DeleteClient(r.Context(), clientID, token); err != nil {
+			h.writeRegistrationError(w, err, "Failed to delete client")
+			return
+		}
+		// クライアントのWebhookの送信先も削除する
+		if err := h.webhookService.DeleteClientEndpoints(r.Context(), clientID); err != nil {
+			log.Printf("Warning: Failed to delete webhook endpoints of client %s: %v", clientID, err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET, PUT and DELETE are allowed")
+	}
+}
+
+// clientInformation はクライアントの登録内容をレスポンスに変換します
+func (h *ClientRegistrationHandler) clientInformation(client *domain.ClientApp) *clientInformationResponse {
+	return &clientInformationResponse{
+		ClientID:              client.ClientID,
+		ClientIDIssuedAt:      client.CreatedAt.Unix(),
+		ClientSecretExpiresAt: 0, // 無期限
+		RegistrationClientURI: h.registrationURI + "/" + client.ClientID,
+		ClientMetadata:        client.Metadata(),
+	}
+}
+
+// writeRegistrationError は登録・管理エンドポイントのエラーレスポンス（RFC 7591 3.2.2）を書き込みます
+func (h *ClientRegistrationHandler) writeRegistrationError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRegistrationToken):
+		writeRegistrationTokenError(w)
+	case errors.Is(err, domain.ErrInvalidRedirectURI):
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_redirect_uri",
+			"error_description": err.Error(),
+		})
+	case errors.Is(err, domain.ErrInvalidClientMetadata):
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_client_metadata",
+			"error_description": err.Error(),
+		})
+	default:
+		log.Printf("%s: %v", message, err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", message)
+	}
+}
+
+// writeRegistrationTokenError は初期アクセストークン・登録アクセストークンが無効な場合の401を書き込みます
+func writeRegistrationTokenError(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
+		"error":             "invalid_token",
+		"error_description": "The access token is missing, invalid, expired or already used",
+	})
+}
+
+// decodeClientMetadata はリクエストボディのJSONをデコードします（未知の項目は無視します）
+// デコードできない場合は400を書き込みfalseを返します
+func decodeClientMetadata(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxClientMetadataBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_client_metadata",
+			"error_description": "request body must be a JSON object",
+		})
+		return false
+	}
+	return true
+}
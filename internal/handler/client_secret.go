@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// clientSecretGracePeriod はClient Secretを再発行するときに選択できる、古いシークレットの猶予期間です
+type clientSecretGracePeriod struct {
+	Hours int
+	Label string
+}
+
+// clientSecretGracePeriods は編集画面に表示する猶予期間の選択肢です
+var clientSecretGracePeriods = []clientSecretGracePeriod{
+	{Hours: 0, Label: "直ちに無効にする"},
+	{Hours: 24, Label: "1日後に無効にする"},
+	{Hours: 24 * 7, Label: "7日後に無効にする"},
+	{Hours: 24 * 30, Label: "30日後に無効にする"},
+}
+
+// clientSecretView は編集画面に表示するClient Secretです
+type clientSecretView struct {
+	*domain.ClientSecret
+	Expired bool
+}
+
+// clientSecretViews はClient Secretを編集画面に表示する形に変換します
+func clientSecretViews(secrets []*domain.ClientSecret, now time.Time) []clientSecretView {
+	views := make([]clientSecretView, len(secrets))
+	for i, secret := range secrets {
+		views[i] = clientSecretView{ClientSecret: secret, Expired: secret.IsExpired(now)}
+	}
+	return views
+}
+
+// redirectToClientSecrets は編集画面のClient Secretの欄にリダイレクトします
+func redirectToClientSecrets(w http.ResponseWriter, r *http.Request, client *domain.ClientApp) {
+	http.Redirect(w, r, "/clients/"+client.ID+"/edit#client-secrets", http.StatusSeeOther)
+}
+
+// handleClientSecretError はClient Secretの操作のエラーを表示します
+func (h *ClientHandler) handleClientSecretError(w http.ResponseWriter, r *http.Request, req *clientManageRequest, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidClientSecret):
+		h.renderEditPage(w, r, req.user, req.client, http.StatusBadRequest, map[string]interface{}{
+			"ClientSecretError": fmt.Sprintf("Client Secretを操作できません: %v", err),
+		})
+	case errors.Is(err, domain.ErrClientSecretNotFound):
+		WriteError(w, http.StatusNotFound, "not_found", "Client secret not found")
+	default:
+		log.Printf("Failed to manage client secret: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to manage client secret")
+	}
+}
+
+// HandleRotateClientSecret はPOST /clients/{id}/secretsを処理します
+// 新しいClient Secretを発行して一度だけ表示し、既存のシークレットは選択した猶予期間の後に無効にします
+func (h *ClientHandler) HandleRotateClientSecret(w http.ResponseWriter, r *http.Request) {
+	req := h.authorizeClientManageRequest(w, r)
+	if req == nil {
+		return
+	}
+
+	hours, err := strconv.Atoi(r.PostFormValue("grace_hours"))
+	if err != nil || !slices.ContainsFunc(clientSecretGracePeriods, func(p clientSecretGracePeriod) bool { return p.Hours == hours }) {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Invalid grace period")
+		return
+	}
+
+	plainSecret, _, err := h.clientService.RotateClientSecret(r.Context(), req.client.ClientID, time.Duration(hours)*time.Hour)
+	if err != nil {
+		h.handleClientSecretError(w, r, req, err)
+		return
+	}
+	h.renderEditPage(w, r, req.user, req.client, http.StatusOK, map[string]interface{}{
+		"NewClientSecret": plainSecret,
+	})
+}
+
+// HandleRevokeClientSecret はPOST /clients/{id}/secrets/{secretID}/deleteを処理します
+// Client Secretを直ちに無効にします
+func (h *ClientHandler) HandleRevokeClientSecret(w http.ResponseWriter, r *http.Request) {
+	req := h.authorizeClientManageRequest(w, r)
+	if req == nil {
+		return
+	}
+
+	if err := h.clientService.RevokeClientSecret(r.Context(), req.client.ClientID, r.PathValue("secretID")); err != nil {
+		h.handleClientSecretError(w, r, req, err)
+		return
+	}
+	redirectToClientSecrets(w, r, req.client)
+}
@@ -7,6 +7,7 @@ type CookieOptions struct {
 	Name     string
 	Value    string
 	Path     string
+	Domain   string // 空の場合はリクエストのホストのみ（サブドメインで共有する場合に指定）
 	MaxAge   int
 	HttpOnly bool
 	SameSite http.SameSite
@@ -22,6 +23,7 @@ func SetSecureCookie(w http.ResponseWriter, r *http.Request, opts CookieOptions)
 		Name:     opts.Name,
 		Value:    opts.Value,
 		Path:     opts.Path,
+		Domain:   opts.Domain,
 		MaxAge:   opts.MaxAge,
 		HttpOnly: opts.HttpOnly,
 		Secure:   isHTTPS, // HTTPS接続の場合はSecureフラグを設定
@@ -42,3 +44,16 @@ func DeleteCookie(w http.ResponseWriter, r *http.Request, name, path string) {
 		SameSite: http.SameSiteLaxMode,
 	})
 }
+
+// DeleteDomainCookie はDomain属性を指定して設定したCookieを削除します
+func DeleteDomainCookie(w http.ResponseWriter, r *http.Request, name, path, domain string) {
+	SetSecureCookie(w, r, CookieOptions{
+		Name:     name,
+		Value:    "",
+		Path:     path,
+		Domain:   domain,
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// maxDeviceFormBytes はユーザーコードの入力フォームの最大サイズです
+const maxDeviceFormBytes = 8 << 10
+
+// DeviceHandler はOAuth2デバイス認可グラント（RFC 8628）のハンドラーを表します
+type DeviceHandler struct {
+	oauth2Service   *service.OAuth2Service
+	authService     *service.AuthService
+	verificationURI string
+	templates       *template.Template
+}
+
+// NewDeviceHandler は新しいデバイス認可ハンドラーを作成します
+// verificationURIはユーザーがユーザーコードを入力するページ（/device）の公開URLです
+func NewDeviceHandler(oauth2Service *service.OAuth2Service, authService *service.AuthService, verificationURI string) *DeviceHandler {
+	// テンプレートをパース
+	templates, err := template.ParseGlob("web/templates/*.html")
+	if err != nil {
+		log.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	return &DeviceHandler{
+		oauth2Service:   oauth2Service,
+		authService:     authService,
+		verificationURI: verificationURI,
+		templates:       templates,
+	}
+}
+
+// HandleDeviceAuthorization はPOST /oauth/device_authorizationを処理します
+// デバイスにデバイスコードとユーザーコードを発行します（RFC 8628 3.1・3.2）
+func (h *DeviceHandler) HandleDeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// フォームパラメータを解析
+	if err := r.ParseForm(); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": "failed to parse form",
+		})
+		return
+	}
+
+	creds, err := parseClientCredentials(r)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": err.Error(),
+		})
+		return
+	}
+	if !creds.present() {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": "missing required parameters",
+		})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	resp, err := h.oauth2Service.RequestDeviceAuthorization(r.Context(), &service.DeviceAuthorizationRequest{
+		ClientID:        creds.ClientID,
+		ClientSecret:    creds.ClientSecret,
+		ClientAssertion: creds.ClientAssertion,
+	}, h.verificationURI)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClient) {
+			if creds.Basic {
+				w.Header().Set("WWW-Authenticate", clientBasicChallenge)
+			}
+			WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error":             "invalid_client",
+				"error_description": "client authentication failed",
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrUnauthorizedClient) {
+			WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error":             "unauthorized_client",
+				"error_description": "the client is not registered for the device_code grant",
+			})
+			return
+		}
+		log.Printf("Failed to start device authorization: %v", err)
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error":             "temporarily_unavailable",
+			"error_description": "failed to start device authorization",
+		})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// HandleDevice はユーザーコードの入力ページを処理します
+// GET  /device（?user_code=）  ユーザーコードの入力・要求したクライアントの確認
+// POST /device                action=approve で承認、action=deny で拒否
+// 未ログインの場合はログイン後にこのページに戻ります
+func (h *DeviceHandler) HandleDevice(w http.ResponseWriter, r *http.Request) {
+	sessionCookie, err := r.Cookie("session_token")
+	var user *domain.User
+	if err == nil {
+		user, err = h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	}
+	if err != nil {
+		returnTo := "/device"
+		if userCode := r.URL.Query().Get("user_code"); userCode != "" {
+			returnTo += "?user_code=" + url.QueryEscape(userCode)
+		}
+		http.Redirect(w, r, "/auth/login?redirect_uri="+url.QueryEscape(returnTo), http.StatusFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.renderDevice(w, r, user, r.URL.Query().Get("user_code"), "", "")
+	case http.MethodPost:
+		h.handleDeviceFormSubmit(w, r, user)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET and POST are allowed")
+	}
+}
+
+// handleDeviceFormSubmit はユーザーコードの承認・拒否のフォーム送信を処理します
+func (h *DeviceHandler) handleDeviceFormSubmit(w http.ResponseWriter, r *http.Request, user *domain.User) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDeviceFormBytes)
+	if err := r.ParseForm(); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Failed to parse form")
+		return
+	}
+
+	// CSRFトークンの検証
+	csrfToken := r.FormValue("csrf_token")
+	csrfCookie, err := r.Cookie("csrf_token")
+	if err != nil || csrfCookie.Value == "" || csrfToken == "" || csrfToken != csrfCookie.Value {
+		log.Printf("CSRF token validation failed")
+		http.Error(w, "Forbidden: Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	userCode := r.PostFormValue("user_code")
+	var message string
+	switch r.PostFormValue("action") {
+	case "approve":
+		err = h.oauth2Service.ApproveDeviceAuthorization(r.Context(), userCode, user.ID)
+		message = "デバイスを承認しました。デバイスの画面に戻ってください"
+	case "deny":
+		err = h.oauth2Service.DenyDeviceAuthorization(r.Context(), userCode, user.ID)
+		message = "デバイスのログインを拒否しました"
+	default:
+		WriteError(w, http.StatusBadRequest, "invalid_request", "action must be approve or deny")
+		return
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			h.renderDevice(w, r, user, "", "コードが見つからないか、有効期限が切れています。デバイスに表示されたコードを確認してください", "")
+			return
+		}
+		log.Printf("Failed to decide device authorization: %v", err)
+		h.renderDevice(w, r, user, "", "処理に失敗しました。もう一度お試しください", "")
+		return
+	}
+	h.renderDevice(w, r, user, "", "", message)
+}
+
+// renderDevice はユーザーコードの入力ページを表示します
+// userCodeが承認待ちのものであれば、要求したクライアントの確認と承認・拒否のボタンを表示します
+func (h *DeviceHandler) renderDevice(w http.ResponseWriter, r *http.Request, user *domain.User, userCode, errorMsg, message string) {
+	data := map[string]interface{}{
+		"Username": user.Username,
+		"Error":    errorMsg,
+		"Message":  message,
+	}
+
+	if userCode != "" && message == "" {
+		deviceCode, client, err := h.oauth2Service.GetPendingDeviceAuthorization(r.Context(), userCode)
+		switch {
+		case err == nil:
+			data["UserCode"] = deviceCode.FormattedUserCode()
+			data["ClientName"] = client.Name
+		case errors.Is(err, domain.ErrDeviceCodeNotFound):
+			data["Error"] = "コードが見つからないか、有効期限が切れています。デバイスに表示されたコードを確認してください"
+		default:
+			log.Printf("Failed to get device authorization: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get device authorization")
+			return
+		}
+	}
+
+	// CSRFトークンを生成
+	csrfToken, err := h.authService.GenerateState()
+	if err == nil {
+		SetSecureCookie(w, r, CookieOptions{
+			Name:     "csrf_token",
+			Value:    csrfToken,
+			Path:     "/",
+			MaxAge:   1800, // 30分
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		data["CSRFToken"] = csrfToken
+	} else {
+		log.Printf("Failed to generate CSRF token: %v", err)
+	}
+
+	// ユーザーコードを入力するページを他のサイトに埋め込ませない
+	w.Header().Set("X-Frame-Options", "DENY")
+	if err := h.templates.ExecuteTemplate(w, "device.html", data); err != nil {
+		log.Printf("Failed to render device template: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to render page")
+		return
+	}
+}
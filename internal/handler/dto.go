@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"time"
+
 	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/studentid"
 )
 
 // UserWithProfile はユーザー情報とプロフィール情報を結合したDTO
@@ -15,9 +18,10 @@ type UserWithProfile struct {
 	LastLoginAt *string `json:"last_login_at,omitempty"`
 
 	// Guild Member情報（オプション）
-	GuildNickname *string  `json:"guild_nickname,omitempty"`
-	GuildRoles    []string `json:"guild_roles,omitempty"`
-	JoinedAt      *string  `json:"joined_at,omitempty"`
+	GuildNickname *string     `json:"guild_nickname,omitempty"`
+	GuildRoles    []string    `json:"guild_roles,omitempty"`
+	Roles         []*RoleData `json:"roles,omitempty"` // guild_rolesを名前解決したもの（上位順）
+	JoinedAt      *string     `json:"joined_at,omitempty"`
 
 	// プロフィール情報（オプション）
 	Profile *ProfileData `json:"profile,omitempty"`
@@ -30,10 +34,33 @@ type ProfileData struct {
 	Hobbies   *string `json:"hobbies,omitempty"`
 	WhatToDo  *string `json:"what_to_do,omitempty"`
 	Comment   *string `json:"comment,omitempty"`
+
+	// 学籍番号から求めた値（学籍番号が設定された形式に一致しない場合は省略）
+	EnrollmentYear *int    `json:"enrollment_year,omitempty"` // 入学年度
+	Faculty        *string `json:"faculty,omitempty"`         // 学部コード
+	Grade          *int    `json:"grade,omitempty"`           // 現在の学年（4月始まりの年度で計算）
+
+	// Extra はテンプレートで定義したカスタム項目など、固定フィールド以外の項目
+	Extra map[string]string `json:"extra,omitempty"`
+
+	// Visibility は項目ごとの公開範囲（本人が閲覧する場合のみ、設定された項目を返す）
+	Visibility map[string]domain.FieldVisibility `json:"visibility,omitempty"`
+	// Sources はWebから手動編集された項目（本人が閲覧する場合のみ、値は "manual"）
+	Sources map[string]domain.FieldSource `json:"sources,omitempty"`
+}
+
+// RoleData はロール情報のDTO
+type RoleData struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color,omitempty"` // #RRGGBB形式
+	Position int    `json:"position"`
 }
 
 // NewUserWithProfile はドメインモデルからDTOを作成します
-func NewUserWithProfile(user *domain.User, profile *domain.Profile) *UserWithProfile {
+// rolesにはuser.GuildRolesを名前解決したロールを渡します
+// プロフィールはviewerが閲覧できない項目を取り除いてから変換します（viewerがnilの場合は全項目を取り除きます）
+func NewUserWithProfile(user *domain.User, profile *domain.Profile, roles []*domain.Role, viewer *domain.ProfileViewer) *UserWithProfile {
 	dto := &UserWithProfile{
 		ID:          user.ID,
 		DiscordID:   user.DiscordID,
@@ -55,6 +82,14 @@ func NewUserWithProfile(user *domain.User, profile *domain.Profile) *UserWithPro
 	if len(user.GuildRoles) > 0 {
 		dto.GuildRoles = user.GuildRoles
 	}
+	for _, role := range roles {
+		dto.Roles = append(dto.Roles, &RoleData{
+			ID:       role.ID,
+			Name:     role.Name,
+			Color:    role.ColorHex(),
+			Position: role.Position,
+		})
+	}
 	if user.JoinedAt != nil {
 		joinedAt := user.JoinedAt.Format("2006-01-02T15:04:05Z07:00")
 		dto.JoinedAt = &joinedAt
@@ -62,13 +97,22 @@ func NewUserWithProfile(user *domain.User, profile *domain.Profile) *UserWithPro
 
 	// プロフィール情報が存在する場合は追加
 	if profile != nil {
+		profile = profile.RedactFor(viewer)
 		dto.Profile = &ProfileData{
-			RealName:  stringToPtr(profile.RealName),
-			StudentID: stringToPtr(profile.StudentID),
-			Hobbies:   stringToPtr(profile.Hobbies),
-			WhatToDo:  stringToPtr(profile.WhatToDo),
-			Comment:   stringToPtr(profile.Comment),
+			RealName:   stringToPtr(profile.RealName),
+			StudentID:  stringToPtr(profile.StudentID),
+			Hobbies:    stringToPtr(profile.Hobbies),
+			WhatToDo:   stringToPtr(profile.WhatToDo),
+			Comment:    stringToPtr(profile.Comment),
+			Extra:      profile.Extra,
+			Visibility: profile.Visibility,
+			Sources:    manualSources(profile),
+		}
+		if profile.EnrollmentYear != 0 {
+			dto.Profile.EnrollmentYear = intToPtr(profile.EnrollmentYear)
+			dto.Profile.Grade = intToPtr(studentid.Grade(profile.EnrollmentYear, time.Now()))
 		}
+		dto.Profile.Faculty = stringToPtr(profile.Faculty)
 	}
 
 	return dto
@@ -81,3 +125,59 @@ func stringToPtr(s string) *string {
 	}
 	return &s
 }
+
+// intToPtr は0でなければポインタを返す
+func intToPtr(n int) *int {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+// manualSources は手動編集された項目とその出所を返します（該当する項目がなければnil）
+func manualSources(profile *domain.Profile) map[string]domain.FieldSource {
+	var sources map[string]domain.FieldSource
+	for key := range profile.Sources {
+		if profile.SourceOf(key) != domain.FieldSourceManual {
+			continue
+		}
+		if sources == nil {
+			sources = make(map[string]domain.FieldSource)
+		}
+		sources[key] = domain.FieldSourceManual
+	}
+	return sources
+}
+
+// ChangeRecordData は変更履歴のDTO
+type ChangeRecordData struct {
+	ID          string                 `json:"id"`
+	UserID      string                 `json:"user_id"`
+	Subject     domain.ChangeSubject   `json:"subject"` // profile または user
+	Version     int                    `json:"version"`
+	Actor       domain.ChangeActorType `json:"actor"` // sync, manual, admin
+	ActorUserID *string                `json:"actor_user_id,omitempty"`
+	Changes     []domain.FieldChange   `json:"changes"`
+	CreatedAt   string                 `json:"created_at"`
+}
+
+// NewChangeRecordData はドメインモデルから変更履歴のDTOを作成します
+func NewChangeRecordData(record *domain.ChangeRecord) *ChangeRecordData {
+	return &ChangeRecordData{
+		ID:          record.ID,
+		UserID:      record.UserID,
+		Subject:     record.Subject,
+		Version:     record.Version,
+		Actor:       record.ActorType,
+		ActorUserID: stringToPtr(record.ActorUserID),
+		Changes:     record.Changes,
+		CreatedAt:   record.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// SearchHitData は全文検索の結果の1件のDTO
+type SearchHitData struct {
+	Member        *UserWithProfile `json:"member"`
+	Score         float64          `json:"score"`          // 大きいほど検索語によく一致します
+	MatchedFields []string         `json:"matched_fields"` // 検索語に一致したプロフィールの項目のキー
+}
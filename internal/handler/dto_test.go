@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// TestNewUserWithProfile_Visibility は閲覧者のロールとクライアントに応じてプロフィールの項目が取り除かれることを確認します
+func TestNewUserWithProfile_Visibility(t *testing.T) {
+	officerRoleIDs := []string{"role-officer"}
+	owner := &domain.User{ID: "owner", DiscordID: "discord-owner", Username: "owner"}
+	member := &domain.User{ID: "member", DiscordID: "discord-member", Username: "member", GuildRoles: []string{"role-member"}}
+	officer := &domain.User{ID: "officer", DiscordID: "discord-officer", Username: "officer", GuildRoles: []string{"role-officer"}}
+	leftAt := time.Now()
+	leftOfficer := &domain.User{ID: "left", DiscordID: "discord-left", Username: "left", GuildRoles: []string{"role-officer"}, LeftAt: &leftAt}
+
+	profile := &domain.Profile{
+		UserID:         owner.ID,
+		RealName:       "じょぎ太郎",
+		StudentID:      "23X0123",
+		EnrollmentYear: 2023,
+		Faculty:        "X",
+		Hobbies:        "読書",
+		Extra:          map[string]string{"favorite_language": "Go"},
+		Visibility: map[string]domain.FieldVisibility{
+			domain.ProfileFieldRealName:  domain.VisibilityOfficers,
+			domain.ProfileFieldStudentID: domain.VisibilityHidden,
+			"favorite_language":          domain.VisibilityOfficers,
+		},
+	}
+
+	membersClient := &domain.ClientApp{ClientID: "members-client"}
+	officersClient := &domain.ClientApp{ClientID: "officers-client", ProfileAccess: domain.VisibilityOfficers}
+
+	tests := []struct {
+		name           string
+		viewer         *domain.ProfileViewer
+		wantRealName   bool
+		wantStudentID  bool
+		wantExtra      bool
+		wantVisibility bool
+	}{
+		{name: "本人", viewer: domain.NewProfileViewer(owner, officerRoleIDs), wantRealName: true, wantStudentID: true, wantExtra: true, wantVisibility: true},
+		{name: "メンバー", viewer: domain.NewProfileViewer(member, officerRoleIDs)},
+		{name: "幹部", viewer: domain.NewProfileViewer(officer, officerRoleIDs), wantRealName: true, wantExtra: true},
+		{name: "脱退した幹部", viewer: domain.NewProfileViewer(leftOfficer, officerRoleIDs)},
+		{name: "幹部・membersクライアント経由", viewer: domain.NewProfileViewer(officer, officerRoleIDs).ViaClient(membersClient)},
+		{name: "幹部・officersクライアント経由", viewer: domain.NewProfileViewer(officer, officerRoleIDs).ViaClient(officersClient), wantRealName: true, wantExtra: true},
+		{name: "本人・officersクライアント経由", viewer: domain.NewProfileViewer(owner, officerRoleIDs).ViaClient(officersClient), wantRealName: true, wantExtra: true},
+		{name: "閲覧者なし", viewer: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dto := NewUserWithProfile(owner, profile, nil, tt.viewer)
+			if dto.Profile == nil {
+				t.Fatal("Expected profile, got nil")
+			}
+
+			if got := dto.Profile.RealName != nil; got != tt.wantRealName {
+				t.Errorf("real_name visible = %v, want %v", got, tt.wantRealName)
+			}
+			if got := dto.Profile.StudentID != nil; got != tt.wantStudentID {
+				t.Errorf("student_id visible = %v, want %v", got, tt.wantStudentID)
+			}
+			// 学籍番号から求めた値も学籍番号と同じ公開範囲に従う
+			if got := dto.Profile.EnrollmentYear != nil || dto.Profile.Faculty != nil || dto.Profile.Grade != nil; got != tt.wantStudentID {
+				t.Errorf("derived academic fields visible = %v, want %v", got, tt.wantStudentID)
+			}
+			if got := dto.Profile.Extra["favorite_language"] != ""; got != tt.wantExtra {
+				t.Errorf("extra.favorite_language visible = %v, want %v", got, tt.wantExtra)
+			}
+			if got := dto.Profile.Visibility != nil; got != tt.wantVisibility {
+				t.Errorf("visibility settings visible = %v, want %v", got, tt.wantVisibility)
+			}
+		})
+	}
+
+	// 元のプロフィールは変更されない
+	if profile.RealName == "" || profile.StudentID == "" || profile.Extra["favorite_language"] == "" {
+		t.Errorf("Expected original profile to be unchanged, got %+v", profile)
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+const (
+	// eventStreamHeartbeatInterval は接続を維持するためのコメントを送信し、セッションを確認し直す間隔です
+	eventStreamHeartbeatInterval = 30 * time.Second
+	// eventStreamRetry は切断されたときにブラウザが再接続するまでの時間（ミリ秒）です
+	eventStreamRetry = 3000
+)
+
+// EventsHandler はメンバーの変更のイベントストリーム（Server-Sent Events）のハンドラーを表します
+type EventsHandler struct {
+	eventBus    *service.EventBus
+	authService *service.AuthService
+}
+
+// NewEventsHandler は新しいイベントストリームハンドラーを作成します
+func NewEventsHandler(eventBus *service.EventBus, authService *service.AuthService) *EventsHandler {
+	return &EventsHandler{
+		eventBus:    eventBus,
+		authService: authService,
+	}
+}
+
+// HandleEvents はメンバーの登録・変更・退出をServer-Sent Eventsで配信します（ログイン中のユーザーのみ）
+// Last-Event-IDヘッダーを指定すると、保持している出来事のうちそれより後のものから配信します
+// GET /api/events
+func (h *EventsHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+		return
+	}
+	if _, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value); err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired session")
+		return
+	}
+
+	subscription, missed, found, err := h.eventBus.Subscribe(r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		if errors.Is(err, service.ErrTooManySubscribers) {
+			w.Header().Set("Retry-After", "30")
+			WriteError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "Too many event stream connections")
+			return
+		}
+		log.Printf("Failed to subscribe events: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to subscribe events")
+		return
+	}
+	defer subscription.Close()
+
+	// ストリームはサーバーの書き込みタイムアウトより長く続くため、期限を解除する
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to clear write deadline for event stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // nginxでバッファリングしない
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetry)
+	if !found {
+		// 最後に受け取った出来事より後を再送できないため、状態を取得し直すよう通知する
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventStreamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// 受信が遅れた場合やシャットダウン時は切断する（ブラウザはLast-Event-IDを付けて再接続する）
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			// ログアウトやセッションの期限切れの後は配信を続けない
+			if _, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value); err != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent は出来事をServer-Sent Eventsの形式で書き込みます
+func writeEvent(w http.ResponseWriter, event *domain.Event) error {
+	data, err := service.MarshalEvent(event)
+	if err != nil {
+		log.Printf("Failed to encode event %s: %v", event.ID, err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
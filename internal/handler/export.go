@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// ExportHandler はメンバー一覧のエクスポートのハンドラーを表します
+type ExportHandler struct {
+	exportService  *service.ExportService
+	authService    *service.AuthService
+	trustedProxies []netip.Prefix // X-Forwarded-Forを信頼する前段のプロキシのアドレス
+}
+
+// NewExportHandler は新しいエクスポートハンドラーを作成します
+// trustedProxiesが空の場合、このサーバーが直接クライアントの接続を受けるものとしてX-Forwarded-Forを無視します
+func NewExportHandler(exportService *service.ExportService, authService *service.AuthService, trustedProxies []netip.Prefix) *ExportHandler {
+	return &ExportHandler{
+		exportService:  exportService,
+		authService:    authService,
+		trustedProxies: trustedProxies,
+	}
+}
+
+// HandleExportMembers は絞り込み条件に一致する在籍中のメンバーをCSV・JSON Lines・XLSXでダウンロードさせます
+// エクスポートを許可されたロールを持つメンバーのみ利用でき、操作は監査ログに記録されます
+// GET /api/members/export?format=csv&fields=username,real_name,student_id&role=部員
+func (h *ExportHandler) HandleExportMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+		return
+	}
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired session")
+		return
+	}
+	viewer := h.authService.ProfileViewer(user)
+	if !h.exportService.CanExport(user, viewer) {
+		WriteError(w, http.StatusForbidden, "forbidden", "You are not allowed to export members")
+		return
+	}
+
+	query := r.URL.Query()
+	format, err := domain.ParseExportFormat(query.Get("format"))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_export", err.Error())
+		return
+	}
+	fields, err := domain.ParseExportFields(strings.Split(query.Get("fields"), ","))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_export", err.Error())
+		return
+	}
+	filter, err := parseMemberFilter(query, time.Now())
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+
+	// 監査ログには形式・項目以外の絞り込み条件を記録する
+	conditions := r.URL.Query()
+	conditions.Del("format")
+	conditions.Del("fields")
+
+	export := &service.MemberExport{
+		Format: format,
+		Fields: fields,
+		Filter: filter,
+		Query:  conditions.Encode(),
+	}
+	actor := domain.AuditActor{UserID: user.ID, Via: domain.AuditViaWeb, RemoteAddr: remoteAddr(r, h.trustedProxies)}
+	filename := fmt.Sprintf("members-%s.%s", time.Now().Format("20060102"), format)
+	out := &attachmentWriter{w: w, contentType: format.ContentType(), filename: filename}
+
+	// メンバーが多い場合の書き出しはサーバーの書き込みタイムアウトより長く続くことがあるため、期限を解除する
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to clear write deadline for export: %v", err)
+	}
+
+	count, err := h.exportService.ExportMembers(r.Context(), actor, user, viewer, export, out)
+	if err != nil {
+		// 書き出しを始めた後はステータスを変更できないため、途中で打ち切る
+		if out.started {
+			log.Printf("Failed to export members after %d rows: %v", count, err)
+			return
+		}
+		if errors.Is(err, domain.ErrExportForbidden) {
+			WriteError(w, http.StatusForbidden, "forbidden", "You are not allowed to export members")
+			return
+		}
+		log.Printf("Failed to export members: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to export members")
+	}
+}
+
+// attachmentWriter は最初の書き込みの時点でダウンロード用のヘッダーを設定します
+// 書き出しの前にエラーになった場合は、通常のエラーレスポンスを返せるようにします
+type attachmentWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (a *attachmentWriter) Write(p []byte) (int, error) {
+	if !a.started {
+		a.started = true
+		a.w.Header().Set("Content-Type", a.contentType)
+		a.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, a.filename))
+		a.w.Header().Set("Cache-Control", "no-store")
+		a.w.WriteHeader(http.StatusOK)
+	}
+	return a.w.Write(p)
+}
+
+// remoteAddr はリクエストの送信元のIPアドレスを返します
+// 信頼する前段のプロキシ（Cloud Runなど）からのリクエストでX-Forwarded-Forがある場合は、プロキシが追加した末尾の値を使用します
+// それ以外の場合、X-Forwarded-Forはクライアントが自由に設定できるため無視します
+func remoteAddr(r *http.Request, trustedProxies []netip.Prefix) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && isFromTrustedProxy(r, trustedProxies) {
+		parts := strings.Split(forwarded, ",")
+		return strings.TrimSpace(parts[len(parts)-1])
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
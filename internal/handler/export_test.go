@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+// TestRemoteAddr は信頼する前段のプロキシからのリクエストに限りX-Forwarded-Forを使用することを確認します
+func TestRemoteAddr(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwarded      string
+		trustedProxies []netip.Prefix
+		want           string
+	}{
+		{name: "信頼するプロキシ", remoteAddr: "10.1.2.3:5000", forwarded: "198.51.100.7, 203.0.113.5", trustedProxies: trusted, want: "203.0.113.5"},
+		{name: "信頼しない接続元", remoteAddr: "203.0.113.5:5000", forwarded: "198.51.100.7", trustedProxies: trusted, want: "203.0.113.5"},
+		{name: "プロキシ未設定", remoteAddr: "10.1.2.3:5000", forwarded: "198.51.100.7", want: "10.1.2.3"},
+		{name: "ヘッダーなし", remoteAddr: "10.1.2.3:5000", trustedProxies: trusted, want: "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/members/export", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := remoteAddr(r, tt.trustedProxies); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
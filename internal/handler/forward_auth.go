@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// ForwardAuthHandler はリバースプロキシからの認証の問い合わせ（フォワード認証）のハンドラーを表します
+type ForwardAuthHandler struct {
+	forwardAuthService *service.ForwardAuthService
+	loginURL           string         // 未ログインのブラウザをリダイレクトするログインURL（公開URL）
+	trustedProxies     []netip.Prefix // 元のリクエストのURLのヘッダーを信頼するリバースプロキシのアドレス
+}
+
+// NewForwardAuthHandler は新しいフォワード認証ハンドラーを作成します
+// trustedProxiesに含まれないアドレスからの問い合わせは、X-Original-URLなどのヘッダーを無視して問い合わせ自体のURLで判定します
+func NewForwardAuthHandler(forwardAuthService *service.ForwardAuthService, loginURL string, trustedProxies []netip.Prefix) *ForwardAuthHandler {
+	return &ForwardAuthHandler{
+		forwardAuthService: forwardAuthService,
+		loginURL:           loginURL,
+		trustedProxies:     trustedProxies,
+	}
+}
+
+// HandleForwardAuth はリバースプロキシからの認証の問い合わせに応答します
+// アクセスを許可する場合は200とX-Auth-User・X-Auth-User-Id・X-Auth-Rolesヘッダーを返します
+// 未ログインの場合、ブラウザにはログインへのリダイレクト（redirect=falseの場合は401）を、それ以外には401を返します
+// GET /auth/forward
+func (h *ForwardAuthHandler) HandleForwardAuth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	original := originalRequestURL(r, h.trustedProxies)
+	// ドットセグメントで弱いアクセス条件のパスを経由しないよう、上流が解決した後のパスで照合する
+	cleanedPath, err := domain.CleanRequestPath(original.EscapedPath())
+	if err != nil {
+		log.Printf("Forward auth rejected path: %v", err)
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Invalid request path")
+		return
+	}
+	req := &service.ForwardAuthRequest{
+		BearerToken: bearerToken(r),
+		Host:        original.Host,
+		Path:        cleanedPath,
+	}
+	req.BasicUsername, req.BasicPassword, _ = r.BasicAuth()
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		req.SessionToken = cookie.Value
+	}
+
+	identity, err := h.forwardAuthService.Authorize(r.Context(), req)
+	switch {
+	case err == nil:
+		w.Header().Set("X-Auth-User", identity.User.Username)
+		w.Header().Set("X-Auth-User-Id", identity.User.ID)
+		w.Header().Set("X-Auth-Roles", encodeRoleNames(identity.RoleNames))
+		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, domain.ErrUnauthenticated):
+		if !req.HasCredentials() && r.URL.Query().Get("redirect") != "false" && acceptsHTML(r) {
+			http.Redirect(w, r, h.loginRedirectURL(original), http.StatusFound)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="jyogi", Basic realm="jyogi", charset="UTF-8"`)
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+	case errors.Is(err, domain.ErrAccessDenied):
+		log.Printf("Forward auth denied: %v", err)
+		WriteError(w, http.StatusForbidden, "forbidden", "You are not allowed to access this site")
+	default:
+		log.Printf("Failed to authorize forward auth request: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to authorize request")
+	}
+}
+
+// loginRedirectURL はログイン後に元のURLに戻るログインURLを返します
+// 元のURLは保護対象のホストのhttp(s)のURLの場合のみ引き継ぎます（オープンリダイレクト対策）
+func (h *ForwardAuthHandler) loginRedirectURL(original *url.URL) string {
+	if !isSafeForwardAuthRedirect(original.String(), h.forwardAuthService.Policies()) {
+		return h.loginURL
+	}
+	return h.loginURL + "?redirect_uri=" + url.QueryEscape(original.String())
+}
+
+// originalRequestURL はリバースプロキシが転送した元のリクエストのURLを返します
+// X-Original-URL（nginx）またはX-Forwarded-Proto・X-Forwarded-Host・X-Forwarded-Uri（Traefik・Caddy）を参照します
+// ヘッダーは信頼するリバースプロキシからの問い合わせに限り参照し、それ以外は問い合わせのホストとパスを返します
+func originalRequestURL(r *http.Request, trustedProxies []netip.Prefix) *url.URL {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if !isFromTrustedProxy(r, trustedProxies) {
+		return &url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+	}
+
+	if raw := r.Header.Get("X-Original-URL"); raw != "" {
+		if u, err := url.Parse(raw); err == nil && u.Host != "" {
+			return u
+		}
+	}
+
+	u := &url.URL{Scheme: scheme, Host: r.Host, Path: "/"}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		u.Scheme = proto
+	}
+	if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+		u.Host = host
+	}
+	if uri := r.Header.Get("X-Forwarded-Uri"); uri != "" {
+		if parsed, err := url.ParseRequestURI(uri); err == nil {
+			u.Path, u.RawPath, u.RawQuery = parsed.Path, parsed.RawPath, parsed.RawQuery
+		}
+	}
+	return u
+}
+
+// isSafeForwardAuthRedirect はURLがログイン後のリダイレクト先として安全か（保護対象のホストのhttp(s)のURLか）を返します
+func isSafeForwardAuthRedirect(raw string, policies domain.AccessPolicies) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || u.Host == "" {
+		return false
+	}
+	return policies.HasHost(u.Host)
+}
+
+// bearerToken はAuthorizationヘッダーのBearerトークンを返します
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// acceptsHTML はリクエストがブラウザのページ遷移（HTMLを受け付ける）かどうかを返します
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// encodeRoleNames はロール名をパーセントエンコードしてカンマ区切りにします（ヘッダーに日本語・カンマを含めないため）
+func encodeRoleNames(names []string) string {
+	encoded := make([]string, len(names))
+	for i, name := range names {
+		encoded[i] = url.PathEscape(name)
+	}
+	return strings.Join(encoded, ",")
+}
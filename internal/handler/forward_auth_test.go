@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// testTrustedProxies はhttptest.NewRequestの接続元（192.0.2.1）を含む信頼するプロキシのアドレスです
+var testTrustedProxies = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+
+// TestOriginalRequestURL はリバースプロキシごとのヘッダーから元のURLを復元できることを確認します
+func TestOriginalRequestURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		untrusted bool
+		want      string
+	}{
+		{
+			name:    "nginx X-Original-URL",
+			headers: map[string]string{"X-Original-URL": "https://wiki.example.com/page?q=1"},
+			want:    "https://wiki.example.com/page?q=1",
+		},
+		{
+			name: "Traefik/Caddy X-Forwarded-*",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "wiki.example.com",
+				"X-Forwarded-Uri":   "/page%20name?q=1",
+			},
+			want: "https://wiki.example.com/page%20name?q=1",
+		},
+		{
+			name:    "invalid proto is ignored",
+			headers: map[string]string{"X-Forwarded-Proto": "javascript", "X-Forwarded-Host": "wiki.example.com"},
+			want:    "http://wiki.example.com/",
+		},
+		{
+			name: "no headers",
+			want: "http://auth.internal/",
+		},
+		{
+			name: "headers from an untrusted address are ignored",
+			headers: map[string]string{
+				"X-Original-URL":    "https://wiki.example.com/admin",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "wiki.example.com",
+				"X-Forwarded-Uri":   "/admin",
+			},
+			untrusted: true,
+			want:      "http://auth.internal/auth/forward",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://auth.internal/auth/forward", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			trustedProxies := testTrustedProxies
+			if tt.untrusted {
+				trustedProxies = nil
+			}
+			if got := originalRequestURL(r, trustedProxies).String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestIsSafeForwardAuthRedirect はログイン後のリダイレクト先を保護対象のホストに限定することを確認します
+func TestIsSafeForwardAuthRedirect(t *testing.T) {
+	policies := domain.AccessPolicies{{Host: "wiki.example.com"}, {Host: "*.tools.example.com"}}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://wiki.example.com/page", true},
+		{"http://wiki.example.com:8080/", true},
+		{"https://jupyter.tools.example.com/lab", true},
+		{"https://wiki.example.com.attacker.com/", false},
+		{"https://wiki.example.com@attacker.com/", false},
+		{"https://user@wiki.example.com/", false},
+		{"javascript://wiki.example.com/%0aalert(1)", false},
+		{"//wiki.example.com/", false},
+		{"https://tools.example.com/", false},
+	}
+	for _, tt := range tests {
+		if got := isSafeForwardAuthRedirect(tt.url, policies); got != tt.want {
+			t.Errorf("isSafeForwardAuthRedirect(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+// TestForwardAuthPath_DotSegments はドットセグメント・エンコードしたスラッシュで弱いアクセス条件のパスを経由できないことを確認します
+func TestForwardAuthPath_DotSegments(t *testing.T) {
+	policies := domain.AccessPolicies{
+		{Host: "wiki.example.com", PathPrefix: "/public"},
+		{Host: "wiki.example.com", PathPrefix: "/admin", Roles: []string{"幹部"}},
+	}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string // 照合に使用するパス（空の場合は拒否）
+	}{
+		{name: "plain", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/public/page"}, want: "/public/page"},
+		{name: "trailing slash", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/public/docs/"}, want: "/public/docs/"},
+		{name: "dot segments", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/public/../admin"}, want: "/admin"},
+		{name: "dot segments in X-Original-URL", headers: map[string]string{"X-Original-URL": "https://wiki.example.com/public/./../admin/users"}, want: "/admin/users"},
+		{name: "above root", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/../../admin"}, want: "/admin"},
+		{name: "encoded dots", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/public/%2e%2e/admin"}, want: "/admin"},
+		{name: "encoded slash", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/public%2F..%2Fadmin"}},
+		{name: "encoded backslash", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/public/..%5Cadmin"}},
+		{name: "double encoded", headers: map[string]string{"X-Forwarded-Host": "wiki.example.com", "X-Forwarded-Uri": "/public/%252e%252e/admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://auth.internal/auth/forward", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			original := originalRequestURL(r, testTrustedProxies)
+			got, err := domain.CleanRequestPath(original.EscapedPath())
+			if tt.want == "" {
+				if !errors.Is(err, domain.ErrInvalidRequestPath) {
+					t.Fatalf("Expected ErrInvalidRequestPath, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to clean path: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if strings.HasPrefix(got, "/admin") && len(policies.Match(original.Host, got).Roles) == 0 {
+				t.Errorf("Expected %s to match the admin policy", got)
+			}
+		})
+	}
+}
+
+// TestEncodeRoleNames はロール名をヘッダーに含められる形式にすることを確認します
+func TestEncodeRoleNames(t *testing.T) {
+	got := encodeRoleNames([]string{"幹部", "A,B", "Game Dev"})
+	want := "%E5%B9%B9%E9%83%A8,A%2CB,Game%20Dev"
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
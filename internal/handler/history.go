@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// HistoryHandler は幹部向けの変更履歴ハンドラーを表します
+type HistoryHandler struct {
+	historyService *service.HistoryService
+	authService    *service.AuthService
+	templates      *template.Template
+}
+
+// NewHistoryHandler は新しい変更履歴ハンドラーを作成します
+func NewHistoryHandler(historyService *service.HistoryService, authService *service.AuthService) *HistoryHandler {
+	// テンプレートをパース
+	templates, err := template.ParseGlob("web/templates/*.html")
+	if err != nil {
+		log.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	return &HistoryHandler{
+		historyService: historyService,
+		authService:    authService,
+		templates:      templates,
+	}
+}
+
+// HandleMemberHistory は指定されたメンバーのプロフィール・ユーザー情報の変更履歴を返します（幹部のみ）
+// GET /api/members/{id}/history?limit=50&offset=0
+func (h *HistoryHandler) HandleMemberHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+		return
+	}
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired session")
+		return
+	}
+	viewer := h.authService.ProfileViewer(user)
+	if !viewer.Officer {
+		WriteError(w, http.StatusForbidden, "forbidden", "Only officers can view change history")
+		return
+	}
+
+	limit, offset := parseHistoryPagination(r)
+	records, err := h.historyService.ListChanges(r.Context(), viewer, r.PathValue("id"), limit, offset)
+	if err != nil {
+		log.Printf("Failed to get change history: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get change history")
+		return
+	}
+
+	history := make([]*ChangeRecordData, len(records))
+	for i, record := range records {
+		history[i] = NewChangeRecordData(record)
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"history": history,
+		"limit":   limit,
+		"offset":  offset,
+		"count":   len(history),
+	})
+}
+
+// HandleAdminHistory は変更履歴の差分を一覧表示する管理画面です（幹部のみ）
+// GET /admin/history?user_id=...&offset=0
+func (h *HistoryHandler) HandleAdminHistory(w http.ResponseWriter, r *http.Request) {
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/auth/login?redirect_uri=/admin/history", http.StatusFound)
+		return
+	}
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		http.Redirect(w, r, "/auth/login?redirect_uri=/admin/history", http.StatusFound)
+		return
+	}
+	viewer := h.authService.ProfileViewer(user)
+	if !viewer.Officer {
+		http.Error(w, "Forbidden: Only officers can view change history", http.StatusForbidden)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	limit, offset := parseHistoryPagination(r)
+	records, err := h.historyService.ListChanges(r.Context(), viewer, userID, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get change history: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get change history")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Records":    records,
+		"UserID":     userID,
+		"Offset":     offset,
+		"PrevOffset": max(offset-limit, 0),
+		"NextOffset": offset + limit,
+		"HasNext":    len(records) == limit,
+		"ActorLabels": map[domain.ChangeActorType]string{
+			domain.ChangeActorSync:   "同期",
+			domain.ChangeActorManual: "本人",
+			domain.ChangeActorAdmin:  "幹部",
+		},
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "admin_history.html", data); err != nil {
+		log.Printf("Failed to render admin history template: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to render page")
+		return
+	}
+}
+
+// parseHistoryPagination はクエリパラメータから変更履歴のページネーションを取得します（limitは既定50、最大100）
+func parseHistoryPagination(r *http.Request) (limit, offset int) {
+	limit = 50
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && parsed > 0 && parsed <= 100 {
+		limit = parsed
+	}
+	if parsed, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+	return limit, offset
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/studentid"
+)
+
+// parseMemberFilter はクエリパラメータからメンバーの絞り込み条件を作成します
+//   - enrollment_year, faculty, grade: 学籍番号から求めた値（gradeはnow時点の学年として入学年度に変換）
+//   - q: ユーザー名・表示名・サーバーニックネーム・氏名の部分一致
+//   - role: ロールIDまたはロール名（複数指定・カンマ区切り可、いずれかに一致）
+//   - joined_after, joined_before: サーバー参加日時の範囲（YYYY-MM-DDまたはRFC3339）
+//   - hobbies: 趣味のキーワード（空白区切り、すべてに一致）
+//   - has_profile: プロフィールの有無（true/false）
+func parseMemberFilter(query url.Values, now time.Time) (domain.MemberFilter, error) {
+	var filter domain.MemberFilter
+
+	if v := query.Get("enrollment_year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil || year <= 0 {
+			return filter, fmt.Errorf("enrollment_year must be a positive integer")
+		}
+		filter.EnrollmentYear = year
+	}
+
+	if v := query.Get("grade"); v != "" {
+		grade, err := strconv.Atoi(v)
+		if err != nil || grade <= 0 {
+			return filter, fmt.Errorf("grade must be a positive integer")
+		}
+		year := studentid.EnrollmentYearForGrade(grade, now)
+		if filter.EnrollmentYear != 0 && filter.EnrollmentYear != year {
+			return filter, fmt.Errorf("grade and enrollment_year do not match")
+		}
+		filter.EnrollmentYear = year
+	}
+
+	if v := query.Get("faculty"); v != "" {
+		filter.Faculty = studentid.Normalize(v)
+	}
+
+	filter.Query = strings.TrimSpace(query.Get("q"))
+
+	for _, v := range query["role"] {
+		for _, role := range strings.Split(v, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				filter.RoleIDs = append(filter.RoleIDs, role)
+			}
+		}
+	}
+
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"joined_after", &filter.JoinedAfter},
+		{"joined_before", &filter.JoinedBefore},
+	} {
+		if v := query.Get(param.name); v != "" {
+			t, err := parseDateParam(v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be a date (YYYY-MM-DD) or RFC3339 timestamp", param.name)
+			}
+			*param.dest = &t
+		}
+	}
+
+	filter.HobbiesKeywords = strings.Fields(query.Get("hobbies"))
+
+	if v := query.Get("has_profile"); v != "" {
+		hasProfile, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("has_profile must be true or false")
+		}
+		filter.HasProfile = &hasProfile
+	}
+
+	return filter, nil
+}
+
+// parseMemberPage はクエリパラメータ（sort, order, cursor）からメンバー一覧の並び順と取得範囲を作成します
+// sortはlast_login（既定）・joined_at・username、orderはascまたはdescです（既定はsortごとに異なります）
+func parseMemberPage(query url.Values, limit, offset int) (domain.MemberPage, error) {
+	sort, err := domain.ParseMemberSort(query.Get("sort"))
+	if err != nil {
+		return domain.MemberPage{}, err
+	}
+
+	page := domain.MemberPage{Sort: sort, Desc: sort.DefaultDesc(), Limit: limit, Offset: offset}
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		page.Desc = false
+	case "desc":
+		page.Desc = true
+	default:
+		return page, fmt.Errorf("%w: order must be asc or desc", domain.ErrInvalidMemberPage)
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := domain.DecodeMemberCursor(v)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = cursor
+	}
+
+	return page, page.Validate()
+}
+
+// parseIncludeTotal はinclude_totalパラメータ（true/false）を解析します
+// 件数の集計は絞り込み条件に一致する全件を数えるため、指定された場合のみ行います
+func parseIncludeTotal(query url.Values) (bool, error) {
+	v := query.Get("include_total")
+	if v == "" {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%w: include_total must be true or false", domain.ErrInvalidMemberPage)
+	}
+	return include, nil
+}
+
+// parseDateParam はYYYY-MM-DD形式（UTCの0時）またはRFC3339形式の日時を解析します
+func parseDateParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
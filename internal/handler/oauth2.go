@@ -1,26 +1,31 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
 )
 
 // OAuth2Handler はOAuth2エンドポイントのハンドラーです
 type OAuth2Handler struct {
-	oauth2Service *service.OAuth2Service
-	authService   *service.AuthService
+	oauth2Service      *service.OAuth2Service
+	authService        *service.AuthService
+	appPasswordService *service.AppPasswordService
 }
 
 // NewOAuth2Handler は新しいOAuth2ハンドラーを作成します
-func NewOAuth2Handler(oauth2Service *service.OAuth2Service, authService *service.AuthService) *OAuth2Handler {
+func NewOAuth2Handler(oauth2Service *service.OAuth2Service, authService *service.AuthService, appPasswordService *service.AppPasswordService) *OAuth2Handler {
 	return &OAuth2Handler{
-		oauth2Service: oauth2Service,
-		authService:   authService,
+		oauth2Service:      oauth2Service,
+		authService:        authService,
+		appPasswordService: appPasswordService,
 	}
 }
 
@@ -94,8 +99,12 @@ func (h *OAuth2Handler) HandleAuthorize(w http.ResponseWriter, r *http.Request)
 
 	authResp, err := h.oauth2Service.Authorize(r.Context(), authReq)
 	if err != nil {
+		code := "invalid_request"
+		if errors.Is(err, domain.ErrUnauthorizedClient) {
+			code = "unauthorized_client"
+		}
 		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"error":             "invalid_request",
+			"error":             code,
 			"error_description": err.Error(),
 		})
 		return
@@ -122,7 +131,7 @@ func (h *OAuth2Handler) HandleAuthorize(w http.ResponseWriter, r *http.Request)
 }
 
 // HandleToken はPOST /oauth/tokenを処理します
-// 認可コードをアクセストークンに交換します
+// 認可コード（またはデバイス認可グラントのデバイスコード）をアクセストークンに交換します
 func (h *OAuth2Handler) HandleToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -150,12 +159,23 @@ func (h *OAuth2Handler) HandleToken(w http.ResponseWriter, r *http.Request) {
 
 	grantType := r.FormValue("grant_type")
 	code := r.FormValue("code")
-	clientID := r.FormValue("client_id")
-	clientSecret := r.FormValue("client_secret")
+	deviceCode := r.FormValue("device_code")
 	redirectURI := r.FormValue("redirect_uri")
+	creds, err := parseClientCredentials(r)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": err.Error(),
+		})
+		return
+	}
 
-	// 必須パラメータのチェック
-	if grantType == "" || code == "" || clientID == "" || clientSecret == "" {
+	// 必須パラメータのチェック（デバイス認可グラントはcodeの代わりにdevice_code）
+	grantCode := code
+	if grantType == service.GrantTypeDeviceCode {
+		grantCode = deviceCode
+	}
+	if grantType == "" || grantCode == "" || !creds.present() {
 		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
 			"error":             "invalid_request",
 			"error_description": "missing required parameters",
@@ -165,24 +185,182 @@ func (h *OAuth2Handler) HandleToken(w http.ResponseWriter, r *http.Request) {
 
 	// トークンリクエストを処理
 	tokenReq := &service.TokenRequest{
-		GrantType:    grantType,
-		Code:         code,
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		RedirectURI:  redirectURI,
+		GrantType:       grantType,
+		Code:            code,
+		DeviceCode:      deviceCode,
+		ClientID:        creds.ClientID,
+		ClientSecret:    creds.ClientSecret,
+		ClientAssertion: creds.ClientAssertion,
+		RedirectURI:     redirectURI,
 	}
 
+	w.Header().Set("Cache-Control", "no-store")
 	tokenResp, err := h.oauth2Service.ExchangeToken(r.Context(), tokenReq)
+	if err != nil {
+		if creds.Basic && errors.Is(err, domain.ErrInvalidClient) {
+			w.Header().Set("WWW-Authenticate", clientBasicChallenge)
+		}
+		writeTokenError(w, err)
+		return
+	}
+
+	// レスポンスを返す
+	WriteJSON(w, http.StatusOK, tokenResp)
+}
+
+// clientBasicChallenge はHTTP Basic認証でクライアント認証に失敗した場合のWWW-Authenticateヘッダーの値です
+const clientBasicChallenge = `Basic realm="jyogi", charset="UTF-8"`
+
+// clientCredentials はリクエストで送信されたクライアントの認証情報です
+type clientCredentials struct {
+	ClientID        string
+	ClientSecret    string
+	ClientAssertion string // private_key_jwt（RFC 7523）の署名したJWT
+	Basic           bool   // HTTP Basic認証を使用した場合はtrue
+}
+
+// present はクライアントを識別するパラメータが揃っているかを返します
+// client_assertionを使用する場合、client_idは省略できます
+// 公開クライアントはclient_idのみを送信するため、Client Secretの有無はサービスでクライアントごとに確認します
+func (c *clientCredentials) present() bool {
+	return c.ClientAssertion != "" || c.ClientID != ""
+}
+
+// parseClientCredentials はリクエストからクライアントの認証情報を取得します
+// HTTP Basic認証（client_secret_basic、RFC 6749 2.3.1）、フォームパラメータ（client_secret_post）、
+// client_assertion（private_key_jwt、RFC 7523 2.2）に対応します。複数の方式で認証情報を送信した場合はエラーを返します
+func parseClientCredentials(r *http.Request) (*clientCredentials, error) {
+	creds := &clientCredentials{
+		ClientID:     r.PostFormValue("client_id"),
+		ClientSecret: r.PostFormValue("client_secret"),
+	}
+	username, password, basic := r.BasicAuth()
+
+	assertionType := r.PostFormValue("client_assertion_type")
+	assertion := r.PostFormValue("client_assertion")
+	if assertionType != "" || assertion != "" {
+		if assertionType != domain.ClientAssertionTypeJWTBearer {
+			return nil, fmt.Errorf("unsupported client_assertion_type")
+		}
+		if assertion == "" {
+			return nil, fmt.Errorf("missing client_assertion")
+		}
+		if basic || creds.ClientSecret != "" {
+			return nil, fmt.Errorf("multiple client authentication methods are not allowed")
+		}
+		creds.ClientAssertion = assertion
+		return creds, nil
+	}
+
+	if !basic {
+		return creds, nil
+	}
+	if creds.ClientSecret != "" {
+		return nil, fmt.Errorf("multiple client authentication methods are not allowed")
+	}
+
+	// Basic認証のClient IDとClient Secretはapplication/x-www-form-urlencodedでエンコードされている
+	clientID, err := url.QueryUnescape(username)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client_id in the Authorization header")
+	}
+	clientSecret, err := url.QueryUnescape(password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client_secret in the Authorization header")
+	}
+	if creds.ClientID != "" && creds.ClientID != clientID {
+		return nil, fmt.Errorf("client_id does not match the Authorization header")
+	}
+	return &clientCredentials{ClientID: clientID, ClientSecret: clientSecret, Basic: true}, nil
+}
+
+// writeTokenError はトークンエンドポイントのエラーレスポンスを書き込みます
+// デバイス認可グラントのポーリング中の状態はRFC 8628 3.5のエラーコードで返します
+func writeTokenError(w http.ResponseWriter, err error) {
+	status, code := http.StatusBadRequest, "invalid_grant"
+	switch {
+	case errors.Is(err, domain.ErrAuthorizationPending):
+		code = "authorization_pending"
+	case errors.Is(err, domain.ErrSlowDown):
+		code = "slow_down"
+	case errors.Is(err, domain.ErrAccessDenied):
+		code = "access_denied"
+	case errors.Is(err, domain.ErrDeviceCodeExpired):
+		code = "expired_token"
+	case errors.Is(err, domain.ErrUnauthorizedClient):
+		code = "unauthorized_client"
+	case errors.Is(err, domain.ErrInvalidClient):
+		status, code = http.StatusUnauthorized, "invalid_client"
+	}
+	WriteJSON(w, status, map[string]interface{}{
+		"error":             code,
+		"error_description": err.Error(),
+	})
+}
+
+// HandleRevoke はPOST /oauth/revokeを処理します
+// クライアントに発行したアクセストークン・リフレッシュトークンを取り消します（RFC 7009）
+func (h *OAuth2Handler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// フォームパラメータを解析
+	if err := r.ParseForm(); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": "failed to parse form",
+		})
+		return
+	}
+
+	token := r.PostFormValue("token")
+	creds, err := parseClientCredentials(r)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"error":             "invalid_grant",
+			"error":             "invalid_request",
 			"error_description": err.Error(),
 		})
 		return
 	}
+	if token == "" || !creds.present() {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": "missing required parameters",
+		})
+		return
+	}
 
-	// レスポンスを返す
-	WriteJSON(w, http.StatusOK, tokenResp)
+	err = h.oauth2Service.RevokeToken(r.Context(), &service.RevokeRequest{
+		Token:           token,
+		TokenTypeHint:   r.PostFormValue("token_type_hint"),
+		ClientID:        creds.ClientID,
+		ClientSecret:    creds.ClientSecret,
+		ClientAssertion: creds.ClientAssertion,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClient) {
+			if creds.Basic {
+				w.Header().Set("WWW-Authenticate", clientBasicChallenge)
+			}
+			WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error":             "invalid_client",
+				"error_description": "client authentication failed",
+			})
+			return
+		}
+		log.Printf("Failed to revoke token: %v", err)
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error":             "temporarily_unavailable",
+			"error_description": "failed to revoke token",
+		})
+		return
+	}
+
+	// 存在しないトークンの場合も200を返す（トークンの有無を推測させない）
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
 }
 
 // HandleVerifyToken はGET /oauth/verify を処理します
@@ -200,46 +378,133 @@ func (h *OAuth2Handler) HandleVerifyToken(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// HandleUserInfo はGET /oauth/userinfoを処理します
-// アクセストークンに紐づくユーザー情報を返します
-func (h *OAuth2Handler) HandleUserInfo(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// HandleIntrospect はPOST /oauth/introspectを処理します
+// クライアントに発行したアクセストークンが有効か確認します（RFC 7662）
+func (h *OAuth2Handler) HandleIntrospect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Authorization ヘッダーからトークンを取得
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
-			"error":   "invalid_token",
-			"message": "Authorization header is required",
+	// フォームパラメータを解析
+	if err := r.ParseForm(); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": "failed to parse form",
 		})
 		return
 	}
 
-	// Bearer トークンの形式を確認
-	var accessToken string
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		accessToken = authHeader[7:]
-	} else {
-		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
-			"error":   "invalid_token",
-			"message": "Authorization header must be in 'Bearer <token>' format",
+	token := r.PostFormValue("token")
+	creds, err := parseClientCredentials(r)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": err.Error(),
+		})
+		return
+	}
+	if token == "" || !creds.present() {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":             "invalid_request",
+			"error_description": "missing required parameters",
 		})
 		return
 	}
 
-	// アクセストークンからユーザー情報を取得
-	user, err := h.oauth2Service.GetUserByAccessToken(r.Context(), accessToken)
+	resp, err := h.oauth2Service.IntrospectToken(r.Context(), &service.IntrospectRequest{
+		Token:           token,
+		TokenTypeHint:   r.PostFormValue("token_type_hint"),
+		ClientID:        creds.ClientID,
+		ClientSecret:    creds.ClientSecret,
+		ClientAssertion: creds.ClientAssertion,
+	})
 	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
-			"error":   "invalid_token",
-			"message": "Token is invalid or expired",
+		if errors.Is(err, domain.ErrInvalidClient) {
+			if creds.Basic {
+				w.Header().Set("WWW-Authenticate", clientBasicChallenge)
+			}
+			WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error":             "invalid_client",
+				"error_description": "client authentication failed",
+			})
+			return
+		}
+		log.Printf("Failed to introspect token: %v", err)
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error":             "temporarily_unavailable",
+			"error_description": "failed to introspect token",
 		})
 		return
 	}
 
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// revokedTokensMaxAge はリソースサーバーが取り消し済みトークンの一覧をキャッシュしてよい秒数です
+const revokedTokensMaxAge = 60
+
+// HandleJWKS はGET /oauth/jwksを処理します
+// JWT形式のアクセストークンの署名を検証するための公開鍵（JWKS）を返します
+func (h *OAuth2Handler) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	keys := h.oauth2Service.AccessTokenKeys()
+	if keys == nil {
+		WriteError(w, http.StatusNotFound, "not_found", "JWT access tokens are not enabled")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	WriteJSON(w, http.StatusOK, keys)
+}
+
+// HandleRevokedTokens はGET /oauth/revokedを処理します
+// 取り消したJWT形式のアクセストークンのうち期限切れでないもののjti（取り消し済みリスト）を返します
+// リソースサーバーはこの一覧をCache-Controlの期間キャッシュして、アクセストークンのjtiが含まれていないか確認します
+func (h *OAuth2Handler) HandleRevokedTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokens, err := h.oauth2Service.ListRevokedAccessTokens(r.Context())
+	if err != nil {
+		log.Printf("Failed to list revoked access tokens: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to list revoked access tokens")
+		return
+	}
+
+	revoked := make([]map[string]interface{}, len(tokens))
+	for i, token := range tokens {
+		revoked[i] = map[string]interface{}{
+			"jti": token.JTI(),
+			"exp": token.ExpiresAt.Unix(),
+		}
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", revokedTokensMaxAge))
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"revoked": revoked,
+	})
+}
+
+// HandleUserInfo はGET /oauth/userinfoを処理します
+// アクセストークン（またはアプリパスワード）に紐づくユーザー情報を返します
+func (h *OAuth2Handler) HandleUserInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// アクセストークンまたはアプリパスワードで認証
+	user, client, ok := h.authenticateResource(w, r, domain.AppPasswordScopeProfile)
+	if !ok {
+		return
+	}
+
 	// ユーザー情報とプロフィールを取得
 	memberWithProfile, err := h.authService.GetUserWithProfile(r.Context(), user.ID)
 	if err != nil {
@@ -251,48 +516,23 @@ func (h *OAuth2Handler) HandleUserInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// DTOに変換して返す（/api/userと同じ形式）
-	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile)
+	// DTOに変換して返す（/api/userと同じ形式。公開範囲はクライアントの上限に従う）
+	viewer := h.resourceViewer(user, client)
+	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile, memberWithProfile.Roles, viewer)
 	WriteJSON(w, http.StatusOK, dto)
 }
 
 // HandleUserByID はGET /oauth/user/{id}を処理します
-// アクセストークンで認証し、指定されたIDのユーザー情報を返します
+// アクセストークンまたはアプリパスワードで認証し、指定されたIDのユーザー情報を返します
 func (h *OAuth2Handler) HandleUserByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Authorization ヘッダーからトークンを取得
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
-			"error":   "invalid_token",
-			"message": "Authorization header is required",
-		})
-		return
-	}
-
-	// Bearer トークンの形式を確認
-	var accessToken string
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		accessToken = authHeader[7:]
-	} else {
-		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
-			"error":   "invalid_token",
-			"message": "Authorization header must be in 'Bearer <token>' format",
-		})
-		return
-	}
-
-	// アクセストークンを検証（トークンの有効性を確認）
-	_, err := h.oauth2Service.GetUserByAccessToken(r.Context(), accessToken)
-	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
-			"error":   "invalid_token",
-			"message": "Token is invalid or expired",
-		})
+	// アクセストークンまたはアプリパスワードで認証
+	user, client, ok := h.authenticateResource(w, r, domain.AppPasswordScopeMembers)
+	if !ok {
 		return
 	}
 
@@ -317,17 +557,35 @@ func (h *OAuth2Handler) HandleUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// DTOに変換して返す
-	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile)
+	// DTOに変換して返す（公開範囲は閲覧者のロールとクライアントの上限に従う）
+	viewer := h.resourceViewer(user, client)
+	dto := NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile, memberWithProfile.Roles, viewer)
 	WriteJSON(w, http.StatusOK, dto)
 }
 
-// HandleMembers はGET /oauth/membersを処理します
-// アクセストークンで認証し、じょぎメンバー一覧をプロフィール情報付きで返します
-func (h *OAuth2Handler) HandleMembers(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// authenticateResource はBearerのアクセストークンまたはBasic認証のアプリパスワード（scopeを持つもの）でユーザーを認証します
+// アプリパスワードで認証した場合、クライアントはnilです。認証できない場合は401、
+// アクセストークンの発行先のクライアントがscopeを登録していない場合は403を書き込みokがfalseです
+func (h *OAuth2Handler) authenticateResource(w http.ResponseWriter, r *http.Request, scope string) (user *domain.User, client *domain.ClientApp, ok bool) {
+	if username, password, isBasic := r.BasicAuth(); isBasic {
+		user, err := h.appPasswordService.Authenticate(r.Context(), username, password, scope)
+		if err != nil {
+			if !errors.Is(err, domain.ErrUnauthenticated) {
+				log.Printf("Failed to authenticate app password: %v", err)
+				WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+					"error":   "internal_error",
+					"message": "Failed to authenticate",
+				})
+				return nil, nil, false
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="jyogi", charset="UTF-8"`)
+			WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error":   "invalid_token",
+				"message": "Username or app password is invalid, expired or lacks the required scope",
+			})
+			return nil, nil, false
+		}
+		return user, nil, true
 	}
 
 	// Authorization ヘッダーからトークンを取得
@@ -337,7 +595,7 @@ func (h *OAuth2Handler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 			"error":   "invalid_token",
 			"message": "Authorization header is required",
 		})
-		return
+		return nil, nil, false
 	}
 
 	// Bearer トークンの形式を確認
@@ -349,38 +607,56 @@ func (h *OAuth2Handler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 			"error":   "invalid_token",
 			"message": "Authorization header must be in 'Bearer <token>' format",
 		})
-		return
+		return nil, nil, false
 	}
 
-	// アクセストークンを検証（トークンの有効性を確認）
-	user, err := h.oauth2Service.GetUserByAccessToken(r.Context(), accessToken)
+	// アクセストークンからユーザー情報と発行先のクライアントを取得
+	user, client, err := h.oauth2Service.GetUserAndClientByAccessToken(r.Context(), accessToken)
 	if err != nil {
 		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{
 			"error":   "invalid_token",
 			"message": "Token is invalid or expired",
 		})
+		return nil, nil, false
+	}
+
+	// クライアントに登録されたスコープの情報のみ返す
+	if !client.AllowsScope(scope) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+		WriteJSON(w, http.StatusForbidden, map[string]interface{}{
+			"error":   "insufficient_scope",
+			"message": "The client is not registered for the " + scope + " scope",
+		})
+		return nil, nil, false
+	}
+	return user, client, true
+}
+
+// resourceViewer はリソースの閲覧者を返します（クライアント経由の場合はクライアントの公開範囲の上限に従います）
+func (h *OAuth2Handler) resourceViewer(user *domain.User, client *domain.ClientApp) *domain.ProfileViewer {
+	viewer := h.authService.ProfileViewer(user)
+	if client != nil {
+		viewer = viewer.ViaClient(client)
+	}
+	return viewer
+}
+
+// HandleMembers はGET /oauth/membersを処理します
+// アクセストークンまたはアプリパスワードで認証し、じょぎメンバー一覧をプロフィール情報付きで返します
+func (h *OAuth2Handler) HandleMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// アクセストークンまたはアプリパスワードで認証
+	user, client, ok := h.authenticateResource(w, r, domain.AppPasswordScopeMembers)
+	if !ok {
 		return
 	}
 
-	// TODO: 認可チェック - 将来のスコープシステム実装時に追加
-	// このエンドポイントは現在、全ての有効なOAuth2トークンでアクセス可能です。
-	// 将来的には以下のチェックを実装する予定：
-	// 1. トークンに "members.read" スコープが含まれているか
-	// 2. クライアントまたはユーザーが適切なロール（例: "admin"）を持っているか
-	//
-	// スコープチェックの実装例：
-	// token, _ := h.oauth2Service.GetTokenByAccessToken(r.Context(), accessToken)
-	// if !hasScope(token.Scopes, "members.read") {
-	//     WriteJSON(w, http.StatusForbidden, map[string]interface{}{
-	//         "error":   "insufficient_scope",
-	//         "message": "Token does not have required scope: members.read",
-	//     })
-	//     return
-	// }
-	//
-	// 注意: メンバー一覧には機密情報（プロフィール）が含まれるため、
-	// 本番環境では適切なスコープベースの認可を実装することを強く推奨します。
-	_ = user // トークン検証済み、将来のロールチェックで使用予定
+	// プロフィールの公開範囲は閲覧者のロールとクライアントの上限に従って絞り込む
+	viewer := h.resourceViewer(user, client)
 
 	// ページネーションパラメータの取得と検証
 	limitStr := r.URL.Query().Get("limit")
@@ -426,8 +702,35 @@ func (h *OAuth2Handler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 		offset = parsedOffset
 	}
 
+	filter, err := parseMemberFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "invalid_parameter",
+			"message": err.Error(),
+		})
+		return
+	}
+	filter.Viewer = viewer
+
+	page, err := parseMemberPage(r.URL.Query(), limit, offset)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "invalid_parameter",
+			"message": err.Error(),
+		})
+		return
+	}
+	includeTotal, err := parseIncludeTotal(r.URL.Query())
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "invalid_parameter",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// メンバー一覧をプロフィール情報付きで取得
-	membersWithProfiles, err := h.authService.GetMembersWithProfiles(r.Context(), limit, offset)
+	membersWithProfiles, next, err := h.authService.GetMembersWithProfiles(r.Context(), filter, page)
 	if err != nil {
 		log.Printf("Failed to get members: %v", err)
 		WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
@@ -440,14 +743,31 @@ func (h *OAuth2Handler) HandleMembers(w http.ResponseWriter, r *http.Request) {
 	// DTOに変換
 	membersList := make([]*UserWithProfile, len(membersWithProfiles))
 	for i, memberWithProfile := range membersWithProfiles {
-		membersList[i] = NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile)
+		membersList[i] = NewUserWithProfile(memberWithProfile.User, memberWithProfile.Profile, memberWithProfile.Roles, viewer)
 	}
 
 	// メンバー一覧を返す
-	WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"members": membersList,
-		"limit":   limit,
-		"offset":  offset,
-		"count":   len(membersList),
-	})
+	response := map[string]interface{}{
+		"members":  membersList,
+		"limit":    limit,
+		"offset":   offset,
+		"count":    len(membersList),
+		"has_more": next != nil,
+	}
+	if next != nil {
+		response["next_cursor"] = next.Encode()
+	}
+	if includeTotal {
+		total, err := h.authService.CountMembers(r.Context(), filter)
+		if err != nil {
+			log.Printf("Failed to count members: %v", err)
+			WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error":   "internal_error",
+				"message": "Failed to count members",
+			})
+			return
+		}
+		response["total"] = total
+	}
+	WriteJSON(w, http.StatusOK, response)
 }
@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// maxProfileEditBodyBytes はプロフィール編集リクエストの最大サイズです
+const maxProfileEditBodyBytes = 64 << 10
+
+// ProfileHandler はWebからのプロフィール編集ハンドラーを表します
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	authService    *service.AuthService
+	templates      *template.Template
+}
+
+// NewProfileHandler は新しいプロフィール編集ハンドラーを作成します
+func NewProfileHandler(profileService *service.ProfileService, authService *service.AuthService) *ProfileHandler {
+	// テンプレートをパース
+	templates, err := template.ParseGlob("web/templates/*.html")
+	if err != nil {
+		log.Fatalf("Failed to parse templates: %v", err)
+	}
+
+	return &ProfileHandler{
+		profileService: profileService,
+		authService:    authService,
+		templates:      templates,
+	}
+}
+
+// profileFieldView は編集画面に表示する1項目です
+type profileFieldView struct {
+	Key    string
+	Label  string
+	Value  string
+	Manual bool
+}
+
+// HandleAccountProfile はログイン中のユーザー自身のプロフィールを表示・編集します
+// GET  /account/profile  編集画面（Accept: application/jsonの場合はJSON）
+// POST /account/profile  編集画面からのフォーム送信
+// PUT  /account/profile  {"fields": {"hobbies": "読書"}, "reset": ["comment"]}
+func (h *ProfileHandler) HandleAccountProfile(w http.ResponseWriter, r *http.Request) {
+	wantsJSON := r.Method == http.MethodPut || acceptsJSON(r)
+
+	sessionCookie, err := r.Cookie("session_token")
+	var user *domain.User
+	if err == nil {
+		user, err = h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	}
+	if err != nil {
+		if wantsJSON {
+			WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+			return
+		}
+		http.Redirect(w, r, "/auth/login?redirect_uri=/account/profile", http.StatusFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		profile, err := h.currentProfile(r, user)
+		if err != nil {
+			log.Printf("Failed to get user profile: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get profile")
+			return
+		}
+		if wantsJSON {
+			h.writeProfileJSON(w, user, profile)
+			return
+		}
+		h.renderProfileForm(w, r, user, profile, "", "")
+	case http.MethodPut:
+		// JSON以外のContent-Typeはフォーム送信によるCSRFを防ぐため拒否する
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			WriteError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+			return
+		}
+
+		var edit domain.ProfileEdit
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProfileEditBodyBytes)).Decode(&edit); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid_request", "Request body must be a JSON object")
+			return
+		}
+
+		profile, err := h.profileService.UpdateProfileManually(r.Context(), user.ID, edit, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, domain.ErrInvalidProfileField):
+				WriteError(w, http.StatusBadRequest, "invalid_field", err.Error())
+			case errors.Is(err, domain.ErrProfileNotFound):
+				WriteError(w, http.StatusNotFound, "profile_not_found", "Profile has no fields")
+			default:
+				log.Printf("Failed to update profile: %v", err)
+				WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to update profile")
+			}
+			return
+		}
+		h.writeProfileJSON(w, user, profile)
+	case http.MethodPost:
+		h.handleProfileFormSubmit(w, r, user)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET, POST and PUT are allowed")
+	}
+}
+
+// HandleMemberProfile は幹部が他のメンバーのプロフィールを編集します（変更履歴には幹部による変更として記録）
+// PUT /api/members/{id}/profile  {"fields": {"real_name": "じょぎ太郎"}, "reset": ["comment"]}
+func (h *ProfileHandler) HandleMemberProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", "PUT")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only PUT is allowed")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+		return
+	}
+	officer, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired session")
+		return
+	}
+	viewer := h.authService.ProfileViewer(officer)
+	if !viewer.Officer {
+		WriteError(w, http.StatusForbidden, "forbidden", "Only officers can edit other members' profiles")
+		return
+	}
+
+	// JSON以外のContent-Typeはフォーム送信によるCSRFを防ぐため拒否する
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		WriteError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+		return
+	}
+
+	var edit domain.ProfileEdit
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProfileEditBodyBytes)).Decode(&edit); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Request body must be a JSON object")
+		return
+	}
+
+	member, err := h.authService.GetUserWithProfile(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			WriteError(w, http.StatusNotFound, "user_not_found", "User not found")
+			return
+		}
+		log.Printf("Failed to get user: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get user")
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfileManually(r.Context(), member.User.ID, edit, officer.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidProfileField):
+			WriteError(w, http.StatusBadRequest, "invalid_field", err.Error())
+		case errors.Is(err, domain.ErrProfileNotFound):
+			WriteError(w, http.StatusNotFound, "profile_not_found", "Profile has no fields")
+		default:
+			log.Printf("Failed to update profile: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to update profile")
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, NewUserWithProfile(member.User, profile, member.Roles, viewer))
+}
+
+// handleProfileFormSubmit は編集画面からのフォーム送信を処理します
+// 表示時から値が変わった項目だけを手動編集として記録します
+func (h *ProfileHandler) handleProfileFormSubmit(w http.ResponseWriter, r *http.Request, user *domain.User) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxProfileEditBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Failed to parse form")
+		return
+	}
+
+	// CSRFトークンの検証
+	csrfToken := r.FormValue("csrf_token")
+	csrfCookie, err := r.Cookie("csrf_token")
+	if err != nil || csrfCookie.Value == "" || csrfToken == "" || csrfToken != csrfCookie.Value {
+		log.Printf("CSRF token validation failed")
+		http.Error(w, "Forbidden: Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	profile, err := h.currentProfile(r, user)
+	if err != nil {
+		log.Printf("Failed to get user profile: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get profile")
+		return
+	}
+	if profile == nil {
+		profile = &domain.Profile{UserID: user.ID}
+	}
+
+	edit := domain.ProfileEdit{
+		Fields: make(map[string]string),
+		Reset:  r.Form["reset"],
+	}
+	for name, values := range r.Form {
+		key, ok := strings.CutPrefix(name, "field_")
+		if !ok || slices.Contains(edit.Reset, key) {
+			continue
+		}
+		value := strings.TrimSpace(values[0])
+		if value != profile.FieldValue(key) {
+			edit.Fields[key] = value
+		}
+	}
+	if newKey := strings.TrimSpace(r.FormValue("new_key")); newKey != "" {
+		edit.Fields[newKey] = r.FormValue("new_value")
+	}
+
+	updated, err := h.profileService.UpdateProfileManually(r.Context(), user.ID, edit, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidProfileField):
+			h.renderProfileForm(w, r, user, profile, "項目名は英小文字・数字・アンダースコア（64文字以内）で入力してください", "")
+		case errors.Is(err, domain.ErrProfileNotFound):
+			h.renderProfileForm(w, r, user, nil, "", "プロフィールの項目がすべて空になったため、プロフィールを非表示にしました")
+		default:
+			log.Printf("Failed to update profile: %v", err)
+			h.renderProfileForm(w, r, user, profile, "プロフィールの更新に失敗しました", "")
+		}
+		return
+	}
+
+	h.renderProfileForm(w, r, user, updated, "", "プロフィールを更新しました")
+}
+
+// currentProfile はユーザーの現在のプロフィールを返します（ない場合はnil）
+func (h *ProfileHandler) currentProfile(r *http.Request, user *domain.User) (*domain.Profile, error) {
+	memberWithProfile, err := h.authService.GetUserWithProfile(r.Context(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return memberWithProfile.Profile, nil
+}
+
+// writeProfileJSON は本人向けのプロフィールをJSONで返します
+func (h *ProfileHandler) writeProfileJSON(w http.ResponseWriter, user *domain.User, profile *domain.Profile) {
+	dto := NewUserWithProfile(user, profile, nil, h.authService.ProfileViewer(user))
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"profile": dto.Profile,
+	})
+}
+
+// renderProfileForm は編集画面を表示します
+func (h *ProfileHandler) renderProfileForm(w http.ResponseWriter, r *http.Request, user *domain.User, profile *domain.Profile, errorMsg, message string) {
+	if profile == nil {
+		profile = &domain.Profile{UserID: user.ID}
+	}
+
+	// 自己紹介テンプレートの定義順に並べ、テンプレートにない項目はキー順で後ろに並べる
+	var fields []profileFieldView
+	seen := make(map[string]bool)
+	addField := func(key, label string) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		if label == "" {
+			label = key
+		}
+		fields = append(fields, profileFieldView{
+			Key:    key,
+			Label:  label,
+			Value:  profile.FieldValue(key),
+			Manual: profile.SourceOf(key) == domain.FieldSourceManual,
+		})
+	}
+	for _, def := range h.profileService.FieldLabels() {
+		addField(def.Key, def.Label)
+	}
+	keys := profile.FieldKeys()
+	slices.Sort(keys)
+	for _, key := range keys {
+		addField(key, "")
+	}
+
+	data := map[string]interface{}{
+		"Username": user.Username,
+		"HasIntro": profile.HasIntro(),
+		"Fields":   fields,
+		"Error":    errorMsg,
+		"Message":  message,
+	}
+
+	// CSRFトークンを生成
+	csrfToken, err := h.authService.GenerateState()
+	if err == nil {
+		SetSecureCookie(w, r, CookieOptions{
+			Name:     "csrf_token",
+			Value:    csrfToken,
+			Path:     "/",
+			MaxAge:   1800, // 30分
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		data["CSRFToken"] = csrfToken
+	} else {
+		log.Printf("Failed to generate CSRF token: %v", err)
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "account_profile.html", data); err != nil {
+		log.Printf("Failed to render account profile template: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to render page")
+		return
+	}
+}
+
+// acceptsJSON はリクエストがJSONの応答を求めているかどうかを確認します
+func acceptsJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept)); mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// identityHeaders は上流に渡すユーザーの識別情報のヘッダーです（クライアントから送られた値は常に取り除きます）
+var identityHeaders = []string{"X-Auth-User", "X-Auth-User-Id", "X-Auth-Roles"}
+
+// proxyIdentityKey は転送するリクエストのコンテキストに認証したユーザーを保持するキーです
+type proxyIdentityKey struct{}
+
+// ProxyHandler は認証プロキシモードのハンドラーを表します
+// 設定されたルートに一致するリクエストを、ログインとアクセス条件を確認してから上流のサービスに転送します
+type ProxyHandler struct {
+	routes             domain.ProxyRoutes
+	proxies            map[*domain.ProxyRoute]*httputil.ReverseProxy
+	forwardAuthService *service.ForwardAuthService
+	loginURL           string         // 未ログインのブラウザをリダイレクトするログインURL（公開URL）
+	trustedProxies     []netip.Prefix // X-Forwarded-Protoを信頼する前段のプロキシのアドレス
+}
+
+// NewProxyHandler は新しい認証プロキシハンドラーを作成します
+// trustedProxiesが空の場合、このサーバーが直接クライアントの接続を受けるものとしてX-Forwarded-Protoを無視します
+func NewProxyHandler(routes domain.ProxyRoutes, forwardAuthService *service.ForwardAuthService, loginURL string, trustedProxies []netip.Prefix) *ProxyHandler {
+	proxies := make(map[*domain.ProxyRoute]*httputil.ReverseProxy, len(routes))
+	for _, route := range routes {
+		upstream, err := route.UpstreamURL()
+		if err != nil {
+			log.Fatalf("Invalid proxy route: %v", err)
+		}
+		proxies[route] = newRouteProxy(route, upstream)
+	}
+
+	return &ProxyHandler{
+		routes:             routes,
+		proxies:            proxies,
+		forwardAuthService: forwardAuthService,
+		loginURL:           loginURL,
+		trustedProxies:     trustedProxies,
+	}
+}
+
+// newRouteProxy はルートの上流に転送するリバースプロキシを作成します（WebSocketのUpgradeにも対応します）
+func newRouteProxy(route *domain.ProxyRoute, upstream *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if route.StripPrefix {
+				stripPathPrefix(pr.Out.URL, route.PathPrefix)
+			}
+			pr.SetURL(upstream)
+			pr.SetXForwarded()
+			if route.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+
+			// クライアントが送った識別情報は信頼せず、認証したユーザーの値で置き換える
+			for _, name := range identityHeaders {
+				pr.Out.Header.Del(name)
+			}
+			identity, _ := pr.In.Context().Value(proxyIdentityKey{}).(*service.ForwardAuthIdentity)
+			if identity != nil {
+				pr.Out.Header.Set("X-Auth-User", identity.User.Username)
+				pr.Out.Header.Set("X-Auth-User-Id", identity.User.ID)
+				pr.Out.Header.Set("X-Auth-Roles", encodeRoleNames(identity.RoleNames))
+				if identity.ByCredentials {
+					pr.Out.Header.Del("Authorization")
+				}
+			}
+			// セッションを上流のサービスに渡さない
+			removeCookie(pr.Out.Header, "session_token")
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Proxy error for %s%s: %v", r.Host, r.URL.Path, err)
+			WriteError(w, http.StatusBadGateway, "bad_gateway", "Upstream service is unavailable")
+		},
+	}
+}
+
+// Wrap はルートに一致するリクエストを上流に転送し、それ以外をnextで処理するハンドラーを返します
+func (h *ProxyHandler) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, routed, err := h.routeRequest(r)
+		switch {
+		case err != nil:
+			log.Printf("Proxy rejected path: %v", err)
+			WriteError(w, http.StatusBadRequest, "invalid_request", "Invalid request path")
+		case route == nil:
+			next.ServeHTTP(w, r)
+		default:
+			h.serveRoute(w, routed, route)
+		}
+	})
+}
+
+// routeRequest はリクエストのパスを正規化して一致するルートを返します（一致するルートがない場合はnil）
+// ServeMuxより前に実行されるため、ドットセグメントを解決したパスで照合し、上流にも解決したパスを転送します
+// 保護対象のホストのパスを正規化できない場合はdomain.ErrInvalidRequestPathを返します
+func (h *ProxyHandler) routeRequest(r *http.Request) (*domain.ProxyRoute, *http.Request, error) {
+	cleanedPath, err := domain.CleanRequestPath(r.URL.EscapedPath())
+	if err != nil {
+		if h.routes.Policies().HasHost(r.Host) {
+			return nil, nil, err
+		}
+		return nil, r, nil
+	}
+
+	route := h.routes.Match(r.Host, cleanedPath)
+	if route == nil || cleanedPath == r.URL.Path {
+		return route, r, nil
+	}
+	routed := r.Clone(r.Context())
+	routed.URL.Path, routed.URL.RawPath = cleanedPath, ""
+	return route, routed, nil
+}
+
+// serveRoute はログインとルートのアクセス条件を確認し、リクエストを上流に転送します
+func (h *ProxyHandler) serveRoute(w http.ResponseWriter, r *http.Request, route *domain.ProxyRoute) {
+	req := &service.ForwardAuthRequest{
+		BearerToken: bearerToken(r),
+		Host:        r.Host,
+		Path:        r.URL.Path,
+	}
+	req.BasicUsername, req.BasicPassword, _ = r.BasicAuth()
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		req.SessionToken = cookie.Value
+	}
+
+	identity, err := h.forwardAuthService.AuthorizePolicy(r.Context(), req, &route.AccessPolicy)
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrUnauthenticated):
+		if !req.HasCredentials() && acceptsHTML(r) {
+			http.Redirect(w, r, h.loginRedirectURL(r), http.StatusFound)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="jyogi", Basic realm="jyogi", charset="UTF-8"`)
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	case errors.Is(err, domain.ErrAccessDenied):
+		log.Printf("Proxy access denied: %v", err)
+		WriteError(w, http.StatusForbidden, "forbidden", "You are not allowed to access this site")
+		return
+	default:
+		log.Printf("Failed to authorize proxy request: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to authorize request")
+		return
+	}
+
+	// 上流の応答（ダウンロード・WebSocketなど）はサーバーのタイムアウトより長く続くことがあるため、期限を解除する
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to clear write deadline for proxy: %v", err)
+	}
+	if r.Header.Get("Upgrade") != "" {
+		if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("Failed to clear read deadline for proxy: %v", err)
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), proxyIdentityKey{}, identity)
+	h.proxies[route].ServeHTTP(w, r.WithContext(ctx))
+}
+
+// loginRedirectURL はログイン後に元のURLに戻るログインURLを返します
+func (h *ProxyHandler) loginRedirectURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || (isFromTrustedProxy(r, h.trustedProxies) && r.Header.Get("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+	original := scheme + "://" + r.Host + r.URL.RequestURI()
+	if !isSafeForwardAuthRedirect(original, h.routes.Policies()) {
+		return h.loginURL
+	}
+	return h.loginURL + "?redirect_uri=" + url.QueryEscape(original)
+}
+
+// stripPathPrefix はURLのパスから接頭辞を取り除きます（取り除いた結果が空の場合は "/" にします）
+func stripPathPrefix(u *url.URL, prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return
+	}
+	u.Path = strings.TrimPrefix(u.Path, prefix)
+	if u.Path == "" || u.Path[0] != '/' {
+		u.Path = "/" + u.Path
+	}
+	if u.RawPath != "" {
+		u.RawPath = strings.TrimPrefix(u.RawPath, prefix)
+		if u.RawPath == "" || u.RawPath[0] != '/' {
+			u.RawPath = "/" + u.RawPath
+		}
+	}
+}
+
+// removeCookie はCookieヘッダーから指定した名前のCookieを取り除きます（それ以外のCookieはそのまま残します）
+func removeCookie(header http.Header, name string) {
+	var kept []string
+	for _, line := range header.Values("Cookie") {
+		for _, part := range strings.Split(line, ";") {
+			part = strings.TrimSpace(part)
+			cookieName, _, _ := strings.Cut(part, "=")
+			if part != "" && cookieName != name {
+				kept = append(kept, part)
+			}
+		}
+	}
+	header.Del("Cookie")
+	if len(kept) > 0 {
+		header.Set("Cookie", strings.Join(kept, "; "))
+	}
+}
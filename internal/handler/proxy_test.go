@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// TestParseProxyRoutes はルートの読み込みと、ホスト・パスに一致するルートの選択を確認します
+func TestParseProxyRoutes(t *testing.T) {
+	routes, err := domain.ParseProxyRoutes([]byte(`[
+		{"host": "tools.example.com", "upstream": "http://127.0.0.1:3000"},
+		{"host": "tools.example.com", "path_prefix": "/grafana", "upstream": "http://127.0.0.1:3001", "strip_prefix": true, "roles": ["幹部"]},
+		{"host": "*.example.com", "upstream": "https://fallback.internal"}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+
+	tests := []struct {
+		host, path string
+		want       string
+	}{
+		{"tools.example.com", "/", "http://127.0.0.1:3000"},
+		{"tools.example.com", "/grafana/d/1", "http://127.0.0.1:3001"},
+		{"tools.example.com", "/grafanax", "http://127.0.0.1:3000"},
+		{"wiki.example.com:443", "/", "https://fallback.internal"},
+		{"example.org", "/", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if route := routes.Match(tt.host, tt.path); route != nil {
+			got = route.Upstream
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q, %q) = %q, want %q", tt.host, tt.path, got, tt.want)
+		}
+	}
+
+	for _, data := range []string{
+		`[{"host": "tools.example.com"}]`,
+		`[{"host": "tools.example.com", "upstream": "ftp://127.0.0.1"}]`,
+		`[{"host": "", "upstream": "http://127.0.0.1"}]`,
+	} {
+		if _, err := domain.ParseProxyRoutes([]byte(data)); !errors.Is(err, domain.ErrInvalidProxyRoute) {
+			t.Errorf("Expected ErrInvalidProxyRoute for %s, got %v", data, err)
+		}
+	}
+}
+
+// TestRouteProxy_Headers は識別情報のヘッダーの置き換え・セッションCookieの除去・接頭辞の除去を確認します
+func TestRouteProxy_Headers(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":          r.URL.Path,
+			"query":         r.URL.RawQuery,
+			"user":          r.Header.Get("X-Auth-User"),
+			"roles":         r.Header.Get("X-Auth-Roles"),
+			"cookie":        r.Header.Get("Cookie"),
+			"authorization": r.Header.Get("Authorization"),
+			"forwardedHost": r.Header.Get("X-Forwarded-Host"),
+		})
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	route := &domain.ProxyRoute{AccessPolicy: domain.AccessPolicy{Host: "tools.example.com", PathPrefix: "/grafana"}, Upstream: upstream.URL, StripPrefix: true}
+	proxy := newRouteProxy(route, upstreamURL)
+
+	identity := &service.ForwardAuthIdentity{User: &domain.User{ID: "user-1", Username: "taro"}, RoleNames: []string{"幹部"}, ByCredentials: true}
+	req := httptest.NewRequest("GET", "http://tools.example.com/grafana/d/1?orgId=1", nil)
+	req.Header.Set("X-Auth-User", "spoofed")
+	req.Header.Set("Authorization", "Bearer jwt")
+	req.Header.Set("Cookie", "theme=dark; session_token=secret; grafana_session=abc")
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), proxyIdentityKey{}, identity)))
+
+	var got map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode upstream response (status %d): %v", rec.Code, err)
+	}
+	want := map[string]string{
+		"path":          "/d/1",
+		"query":         "orgId=1",
+		"user":          "taro",
+		"roles":         "%E5%B9%B9%E9%83%A8",
+		"cookie":        "theme=dark; grafana_session=abc",
+		"authorization": "",
+		"forwardedHost": "tools.example.com",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got[key])
+		}
+	}
+}
+
+// TestRouteProxy_WebSocket はUpgradeしたコネクションを上流と中継できることを確認します
+func TestRouteProxy_WebSocket(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("X-Auth-User") != "taro" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		brw.Flush()
+		line, _ := brw.ReadString('\n')
+		brw.WriteString("echo: " + line)
+		brw.Flush()
+	}))
+	defer upstream.Close()
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	route := &domain.ProxyRoute{AccessPolicy: domain.AccessPolicy{Host: "tools.example.com"}, Upstream: upstream.URL}
+	proxy := newRouteProxy(route, upstreamURL)
+	identity := &service.ForwardAuthIdentity{User: &domain.User{ID: "user-1", Username: "taro"}}
+	front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), proxyIdentityKey{}, identity)))
+	}))
+	defer front.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(front.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /socket HTTP/1.1\r\nHost: tools.example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	io.WriteString(conn, "ping\n")
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "echo: ping\n" {
+		t.Errorf("Expected echo through upgraded connection, got %q (%v)", line, err)
+	}
+}
+
+// TestRemoveCookie はセッションCookie以外のCookieをそのまま残すことを確認します
+func TestRemoveCookie(t *testing.T) {
+	header := http.Header{}
+	header.Add("Cookie", "session_token=secret")
+	header.Add("Cookie", `a="quoted value"; session_token=other`)
+	removeCookie(header, "session_token")
+	if got := header.Values("Cookie"); len(got) != 1 || got[0] != `a="quoted value"` {
+		t.Errorf("Unexpected cookies: %v", got)
+	}
+
+	header = http.Header{"Cookie": {"session_token=secret"}}
+	removeCookie(header, "session_token")
+	if _, ok := header["Cookie"]; ok {
+		t.Errorf("Expected Cookie header to be removed, got %v", header)
+	}
+}
+
+// TestProxyHandler_RouteRequest はドットセグメントを解決したパスでルートを選び、解決したパスを上流に転送することを確認します
+func TestProxyHandler_RouteRequest(t *testing.T) {
+	routes, err := domain.ParseProxyRoutes([]byte(`[
+		{"host": "tools.example.com", "path_prefix": "/public", "upstream": "http://127.0.0.1:3000", "strip_prefix": true},
+		{"host": "tools.example.com", "path_prefix": "/admin", "upstream": "http://127.0.0.1:3001", "roles": ["幹部"]}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	h := NewProxyHandler(routes, nil, "https://auth.example.com/auth/login", nil)
+
+	tests := []struct {
+		target       string
+		wantUpstream string // 空の場合はルートなし
+		wantPath     string
+		wantErr      bool
+	}{
+		{target: "http://tools.example.com/public/page", wantUpstream: "http://127.0.0.1:3000", wantPath: "/public/page"},
+		{target: "http://tools.example.com/public/../admin", wantUpstream: "http://127.0.0.1:3001", wantPath: "/admin"},
+		{target: "http://tools.example.com/public/%2e%2e/admin/users", wantUpstream: "http://127.0.0.1:3001", wantPath: "/admin/users"},
+		{target: "http://tools.example.com/public%2F..%2Fadmin", wantErr: true},
+		{target: "http://auth.example.com/public%2F..%2Fadmin", wantPath: "/public/../admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.URL, _ = url.Parse(tt.target)
+			r.Host = r.URL.Host
+			route, routed, err := h.routeRequest(r)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidRequestPath) {
+					t.Fatalf("Expected ErrInvalidRequestPath, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to route request: %v", err)
+			}
+			gotUpstream := ""
+			if route != nil {
+				gotUpstream = route.Upstream
+			}
+			if gotUpstream != tt.wantUpstream || routed.URL.Path != tt.wantPath {
+				t.Errorf("Expected %q %s, got %q %s", tt.wantUpstream, tt.wantPath, gotUpstream, routed.URL.Path)
+			}
+		})
+	}
+}
+
+// TestProxyHandler_LoginRedirectURL は信頼するプロキシから送られた場合のみX-Forwarded-Protoを使用することを確認します
+func TestProxyHandler_LoginRedirectURL(t *testing.T) {
+	routes := domain.ProxyRoutes{{AccessPolicy: domain.AccessPolicy{Host: "tools.example.com"}, Upstream: "http://127.0.0.1:3000"}}
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	h := NewProxyHandler(routes, nil, "https://auth.example.com/auth/login", trusted)
+
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{remoteAddr: "10.1.2.3:5000", want: "https://tools.example.com/page"},
+		{remoteAddr: "203.0.113.5:5000", want: "http://tools.example.com/page"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://tools.example.com/page", nil)
+		r.RemoteAddr = tt.remoteAddr
+		r.Header.Set("X-Forwarded-Proto", "https")
+		want := "https://auth.example.com/auth/login?redirect_uri=" + url.QueryEscape(tt.want)
+		if got := h.loginRedirectURL(r); got != want {
+			t.Errorf("%s: expected %s, got %s", tt.remoteAddr, want, got)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/saml"
+)
+
+// samlPostTemplate はSAMLのレスポンスをSPにHTTP-POSTで送るページです（JavaScriptが無効な場合はボタンで送信します）
+var samlPostTemplate = template.Must(template.New("saml_post").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="utf-8"><title>ログイン中...</title></head>
+<body onload="document.forms[0].submit()">
+<form method="post" action="{{.ACSURL}}">
+<input type="hidden" name="SAMLResponse" value="{{.SAMLResponse}}">
+{{if .RelayState}}<input type="hidden" name="RelayState" value="{{.RelayState}}">{{end}}
+<noscript><button type="submit">続行</button></noscript>
+</form>
+</body>
+</html>
+`))
+
+// SAMLHandler はSAML 2.0のIdentity Provider（IdP）のハンドラーを表します
+type SAMLHandler struct {
+	samlService *service.SAMLService
+	authService *service.AuthService
+}
+
+// NewSAMLHandler は新しいSAMLハンドラーを作成します
+func NewSAMLHandler(samlService *service.SAMLService, authService *service.AuthService) *SAMLHandler {
+	return &SAMLHandler{
+		samlService: samlService,
+		authService: authService,
+	}
+}
+
+// HandleMetadata はGET /saml/metadataを処理します
+// IdPのメタデータ（エンティティID・署名用の証明書・SSOのURL）を返します
+func (h *SAMLHandler) HandleMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(h.samlService.Metadata())
+}
+
+// HandleSSO はGET・POST /saml/ssoを処理します
+// SPからの認証要求（HTTP-Redirect・HTTP-POST）を受け取り、ログイン中のユーザーのアサーションをACS URLにHTTP-POSTで送ります
+// 未ログインの場合はログイン後にこのURLに戻ります
+func (h *SAMLHandler) HandleSSO(w http.ResponseWriter, r *http.Request) {
+	var binding, samlRequest, relayState string
+	switch r.Method {
+	case http.MethodGet:
+		binding = saml.BindingHTTPRedirect
+		samlRequest = r.URL.Query().Get("SAMLRequest")
+		relayState = r.URL.Query().Get("RelayState")
+	case http.MethodPost:
+		binding = saml.BindingHTTPPost
+		samlRequest = r.PostFormValue("SAMLRequest")
+		relayState = r.PostFormValue("RelayState")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if samlRequest == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "SAMLRequest is required")
+		return
+	}
+
+	req, err := h.samlService.ParseRequest(r.Context(), binding, samlRequest)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidSAMLRequest) {
+			log.Printf("Invalid SAML request: %v", err)
+			WriteError(w, http.StatusBadRequest, "invalid_request", "Invalid SAML request")
+			return
+		}
+		log.Printf("Failed to parse SAML request: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to process SAML request")
+		return
+	}
+
+	// 認証し直すことを求められても既存のセッションのアサーションを返さないよう、要求を拒否する
+	if req.ForceAuthn {
+		resp, err := h.samlService.IssueForceAuthnDeniedResponse(req)
+		if err != nil {
+			log.Printf("Failed to create SAML response: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to create SAML response")
+			return
+		}
+		log.Printf("SAML ForceAuthn request denied: sp=%s", req.ServiceProvider.EntityID)
+		h.postResponse(w, resp, relayState)
+		return
+	}
+
+	// 別サイトからのPOSTにはSameSite=LaxのセッションCookieが付かないため、同じ要求をHTTP-Redirectの形式で受け直す
+	redirectURL, err := ssoRedirectURL(req.Raw, relayState)
+	if err != nil {
+		log.Printf("Failed to encode SAML request: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to process SAML request")
+		return
+	}
+	if r.Method == http.MethodPost {
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	var user *domain.User
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		user, _ = h.authService.GetUserBySessionToken(r.Context(), cookie.Value)
+	}
+	if user == nil {
+		if req.IsPassive {
+			resp, err := h.samlService.IssueNoPassiveResponse(req)
+			if err != nil {
+				log.Printf("Failed to create SAML response: %v", err)
+				WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to create SAML response")
+				return
+			}
+			h.postResponse(w, resp, relayState)
+			return
+		}
+		http.Redirect(w, r, "/auth/login?redirect_uri="+url.QueryEscape(redirectURL), http.StatusFound)
+		return
+	}
+
+	resp, err := h.samlService.IssueResponse(r.Context(), req, user)
+	if err != nil {
+		if errors.Is(err, domain.ErrAccessDenied) {
+			log.Printf("SAML login denied: %v", err)
+			WriteError(w, http.StatusForbidden, "forbidden", "You are not allowed to log in to this service")
+			return
+		}
+		log.Printf("Failed to create SAML response: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to create SAML response")
+		return
+	}
+	log.Printf("SAML login: user=%s sp=%s", user.ID, req.ServiceProvider.EntityID)
+	h.postResponse(w, resp, relayState)
+}
+
+// postResponse はレスポンスをACS URLにHTTP-POSTで送るページを返します
+func (h *SAMLHandler) postResponse(w http.ResponseWriter, resp *service.SAMLLoginResponse, relayState string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := map[string]string{
+		"ACSURL":       resp.ACSURL,
+		"SAMLResponse": resp.SAMLResponse,
+		"RelayState":   relayState,
+	}
+	if err := samlPostTemplate.Execute(w, data); err != nil {
+		log.Printf("Failed to render SAML post form: %v", err)
+	}
+}
+
+// ssoRedirectURL は認証要求をHTTP-Redirectバインディングで受け付けるこのサーバーのパスを返します
+func ssoRedirectURL(raw []byte, relayState string) (string, error) {
+	encoded, err := saml.EncodeRedirectRequest(raw)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{"SAMLRequest": {encoded}}
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	return "/saml/sso?" + query.Encode(), nil
+}
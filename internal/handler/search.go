@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
+)
+
+// maxSearchQueryLength は全文検索の検索語の最大文字数です
+const maxSearchQueryLength = 100
+
+// SearchHandler はプロフィールの全文検索のハンドラーを表します
+type SearchHandler struct {
+	searchService *service.SearchService
+	authService   *service.AuthService
+}
+
+// NewSearchHandler は新しい全文検索ハンドラーを作成します
+func NewSearchHandler(searchService *service.SearchService, authService *service.AuthService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+		authService:   authService,
+	}
+}
+
+// HandleSearchMembers は趣味・やりたいこと・ひとことを全文検索し、一致したメンバーをスコアの高い順に返します
+// GET /api/members/search?q=ゲーム制作&limit=20
+func (h *SearchHandler) HandleSearchMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+		return
+	}
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired session")
+		return
+	}
+	viewer := h.authService.ProfileViewer(user)
+
+	query := r.URL.Query().Get("q")
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLength {
+		WriteError(w, http.StatusBadRequest, "invalid_query", "q is required and must be at most 100 characters")
+		return
+	}
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	results, err := h.searchService.SearchProfiles(r.Context(), viewer, query, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrEmptySearchQuery) {
+			WriteError(w, http.StatusBadRequest, "invalid_query", "q must contain letters or numbers")
+			return
+		}
+		log.Printf("Failed to search profiles: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to search profiles")
+		return
+	}
+
+	hits := make([]*SearchHitData, len(results))
+	for i, result := range results {
+		hits[i] = &SearchHitData{
+			Member:        NewUserWithProfile(result.User, result.Profile, result.Roles, viewer),
+			Score:         math.Round(result.Score*1000) / 1000,
+			MatchedFields: result.Fields,
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"query":   query,
+		"results": hits,
+		"count":   len(hits),
+	})
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+)
+
+// isFromTrustedProxy はリクエストの接続元（r.RemoteAddr）が信頼する前段のプロキシのアドレスに含まれるかどうかを返します
+// 信頼するプロキシからのリクエストに限り、X-Forwarded-Forなどのヘッダーを使用します
+func isFromTrustedProxy(r *http.Request, trustedProxies []netip.Prefix) bool {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	return slices.ContainsFunc(trustedProxies, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// maxVisibilityBodyBytes は公開範囲の更新リクエストの最大サイズです
+const maxVisibilityBodyBytes = 16 << 10
+
+// HandleProfileVisibility はログイン中のユーザー自身のプロフィールの公開範囲を取得・更新します
+// GET /api/me/profile/visibility
+// PUT /api/me/profile/visibility  {"real_name": "officers", "student_id": "hidden"}
+func (h *AuthHandler) HandleProfileVisibility(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "GET, PUT")
+		WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET and PUT are allowed")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "No active session")
+		return
+	}
+
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired session")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		memberWithProfile, err := h.authService.GetUserWithProfile(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Failed to get user profile: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get profile")
+			return
+		}
+		if memberWithProfile.Profile == nil {
+			WriteError(w, http.StatusNotFound, "profile_not_found", "Profile not found")
+			return
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"visibility": visibilityResponse(memberWithProfile.Profile),
+		})
+		return
+	}
+
+	// JSON以外のContent-Typeはフォーム送信によるCSRFを防ぐため拒否する
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		WriteError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+		return
+	}
+
+	var visibility map[string]domain.FieldVisibility
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxVisibilityBodyBytes)).Decode(&visibility); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Request body must be a JSON object")
+		return
+	}
+
+	profile, err := h.authService.UpdateProfileVisibility(r.Context(), user.ID, visibility)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrProfileNotFound):
+			WriteError(w, http.StatusNotFound, "profile_not_found", "Profile not found")
+		case errors.Is(err, domain.ErrInvalidVisibility):
+			WriteError(w, http.StatusBadRequest, "invalid_visibility", err.Error())
+		default:
+			log.Printf("Failed to update profile visibility: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to update visibility")
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"visibility": visibilityResponse(profile),
+	})
+}
+
+// visibilityResponse は固定フィールドとExtraの全項目について公開範囲を返します（未設定の項目はmembers）
+func visibilityResponse(profile *domain.Profile) map[string]domain.FieldVisibility {
+	result := make(map[string]domain.FieldVisibility, len(domain.ProfileFields)+len(profile.Extra))
+	for _, key := range domain.ProfileFields {
+		result[key] = profile.VisibilityOf(key)
+	}
+	for key := range profile.Extra {
+		result[key] = profile.VisibilityOf(key)
+	}
+	return result
+}
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// webhookDeliveriesShown は編集画面に表示する送信先ごとの最近の配信の件数です
+const webhookDeliveriesShown = 10
+
+// webhookEventLabels は編集画面に表示する出来事の種類の説明です
+var webhookEventLabels = map[domain.EventType]string{
+	domain.EventUserCreated:    "ユーザーの登録",
+	domain.EventUserUpdated:    "ユーザー情報の変更（ユーザー名・ロールなど）",
+	domain.EventProfileUpdated: "プロフィールの変更",
+	domain.EventMemberLeft:     "メンバーの退出",
+	domain.EventTokenRevoked:   "このクライアントのトークンの取り消し",
+}
+
+// webhookEventOption は出来事の種類の選択肢です
+type webhookEventOption struct {
+	Type    domain.EventType
+	Label   string
+	Checked bool
+}
+
+// webhookView は編集画面に表示するWebhookの送信先と最近の配信です
+type webhookView struct {
+	Endpoint   *domain.WebhookEndpoint
+	Events     []webhookEventOption
+	Deliveries []*domain.WebhookDelivery
+}
+
+// webhookEventOptions は出来事の種類の選択肢を返します（selectedに含まれるものを選択済みにします）
+func webhookEventOptions(selected []domain.EventType) []webhookEventOption {
+	options := make([]webhookEventOption, len(domain.EventTypes))
+	for i, eventType := range domain.EventTypes {
+		options[i] = webhookEventOption{
+			Type:    eventType,
+			Label:   webhookEventLabels[eventType],
+			Checked: slices.Contains(selected, eventType),
+		}
+	}
+	return options
+}
+
+// canManageClient はユーザーがクライアントのWebhookとClient Secretを管理できるかどうかを返します（所有者と幹部のみ）
+func (h *ClientHandler) canManageClient(user *domain.User, client *domain.ClientApp) bool {
+	return client.OwnerID == user.ID || h.authService.ProfileViewer(user).Officer
+}
+
+// renderEditPage はクライアント編集画面を表示します
+// 所有者と幹部にはWebhookの送信先と最近の配信、Client Secretの一覧、操作用のCSRFトークンも表示します
+func (h *ClientHandler) renderEditPage(w http.ResponseWriter, r *http.Request, user *domain.User, client *domain.ClientApp, status int, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"Client":              client,
+		"RedirectURIsText":    strings.Join(client.RedirectURIs, "\n"),
+		"CanSetProfileAccess": h.authService.ProfileViewer(user).Officer,
+		"Error":               nil,
+	}
+
+	if h.canManageClient(user, client) {
+		endpoints, err := h.webhookService.ListEndpoints(r.Context(), client.ClientID)
+		if err != nil {
+			log.Printf("Failed to list webhook endpoints: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get webhooks")
+			return
+		}
+		webhooks := make([]webhookView, len(endpoints))
+		for i, endpoint := range endpoints {
+			deliveries, err := h.webhookService.ListDeliveries(r.Context(), client.ClientID, endpoint.ID, webhookDeliveriesShown)
+			if err != nil {
+				log.Printf("Failed to list webhook deliveries: %v", err)
+				WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get webhook deliveries")
+				return
+			}
+			webhooks[i] = webhookView{Endpoint: endpoint, Events: webhookEventOptions(endpoint.Events), Deliveries: deliveries}
+		}
+		data["CanManageWebhooks"] = true
+		data["Webhooks"] = webhooks
+		data["WebhookEvents"] = webhookEventOptions(nil)
+		data["CanAddWebhook"] = len(endpoints) < domain.MaxWebhookEndpointsPerClient
+
+		secrets, err := h.clientService.ListClientSecrets(r.Context(), client.ClientID)
+		if err != nil {
+			log.Printf("Failed to list client secrets: %v", err)
+			WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to get client secrets")
+			return
+		}
+		data["ClientSecrets"] = clientSecretViews(secrets, time.Now())
+		data["SecretGracePeriods"] = clientSecretGracePeriods
+
+		// CSRFトークンを生成
+		csrfToken, err := h.authService.GenerateState()
+		if err == nil {
+			SetSecureCookie(w, r, CookieOptions{
+				Name:     "csrf_token",
+				Value:    csrfToken,
+				Path:     "/",
+				MaxAge:   1800, // 30分
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+			data["CSRFToken"] = csrfToken
+		} else {
+			log.Printf("Failed to generate CSRF token: %v", err)
+		}
+	}
+
+	for key, value := range extra {
+		data[key] = value
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "edit_client.html", data); err != nil {
+		log.Printf("Failed to render edit template: %v", err)
+	}
+}
+
+// clientManageRequest はWebhook・Client Secretを操作するリクエストのユーザーとクライアントです
+type clientManageRequest struct {
+	user   *domain.User
+	client *domain.ClientApp
+}
+
+// authorizeClientManageRequest はWebhook・Client Secretを操作するリクエストのセッション・権限・CSRFトークンを検証します
+// 検証に失敗した場合はレスポンスを書き込み、nilを返します
+func (h *ClientHandler) authorizeClientManageRequest(w http.ResponseWriter, r *http.Request) *clientManageRequest {
+	sessionCookie, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/auth/login", http.StatusFound)
+		return nil
+	}
+	user, err := h.authService.GetUserBySessionToken(r.Context(), sessionCookie.Value)
+	if err != nil {
+		http.Redirect(w, r, "/auth/login", http.StatusFound)
+		return nil
+	}
+
+	client, err := h.clientService.GetClientByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		WriteError(w, http.StatusNotFound, "not_found", "Client not found")
+		return nil
+	}
+	if !h.canManageClient(user, client) {
+		WriteError(w, http.StatusForbidden, "forbidden", "You are not authorized to manage this client")
+		return nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Failed to parse form")
+		return nil
+	}
+
+	// CSRFトークンの検証
+	csrfToken := r.FormValue("csrf_token")
+	csrfCookie, err := r.Cookie("csrf_token")
+	if err != nil || csrfCookie.Value == "" || csrfToken == "" || csrfToken != csrfCookie.Value {
+		log.Printf("CSRF token validation failed")
+		http.Error(w, "Forbidden: Invalid CSRF token", http.StatusForbidden)
+		return nil
+	}
+
+	return &clientManageRequest{user: user, client: client}
+}
+
+// parseWebhookForm はフォームから送信先のURLと購読する出来事の種類を取得します
+func parseWebhookForm(r *http.Request) (string, []domain.EventType, error) {
+	url := strings.TrimSpace(r.PostFormValue("url"))
+	var events []domain.EventType
+	for _, value := range r.PostForm["events"] {
+		eventType, err := domain.ParseEventType(value)
+		if err != nil {
+			return "", nil, err
+		}
+		events = append(events, eventType)
+	}
+	return url, events, nil
+}
+
+// handleWebhookError はWebhookの操作のエラーを表示します
+func (h *ClientHandler) handleWebhookError(w http.ResponseWriter, r *http.Request, req *clientManageRequest, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidWebhook):
+		h.renderEditPage(w, r, req.user, req.client, http.StatusBadRequest, map[string]interface{}{
+			"WebhookError": fmt.Sprintf("Webhookの設定が不正です: %v", err),
+		})
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		WriteError(w, http.StatusNotFound, "not_found", "Webhook not found")
+	default:
+		log.Printf("Failed to manage webhook: %v", err)
+		WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to manage webhook")
+	}
+}
+
+// redirectToWebhooks は編集画面のWebhookの欄にリダイレクトします
+func redirectToWebhooks(w http.ResponseWriter, r *http.Request, client *domain.ClientApp) {
+	http.Redirect(w, r, "/clients/"+client.ID+"/edit#webhooks", http.StatusSeeOther)
+}
+
+// HandleCreateWebhook はPOST /clients/{id}/webhooksを処理します
+// Webhookの送信先を追加し、署名用のシークレットを一度だけ表示します
+func (h *ClientHandler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	req := h.authorizeClientManageRequest(w, r)
+	if req == nil {
+		return
+	}
+
+	url, events, err := parseWebhookForm(r)
+	if err == nil {
+		var endpoint *domain.WebhookEndpoint
+		endpoint, err = h.webhookService.CreateEndpoint(r.Context(), req.client.ClientID, url, events)
+		if err == nil {
+			h.renderEditPage(w, r, req.user, req.client, http.StatusOK, map[string]interface{}{
+				"NewWebhookSecret":   endpoint.Secret,
+				"NewWebhookEndpoint": endpoint,
+			})
+			return
+		}
+	}
+	h.handleWebhookError(w, r, req, err)
+}
+
+// HandleUpdateWebhook はPOST /clients/{id}/webhooks/{webhookID}を処理します
+// Webhookの送信先のURL・購読する出来事・有効状態を更新します
+func (h *ClientHandler) HandleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	req := h.authorizeClientManageRequest(w, r)
+	if req == nil {
+		return
+	}
+
+	url, events, err := parseWebhookForm(r)
+	if err == nil {
+		active := r.PostFormValue("active") != ""
+		_, err = h.webhookService.UpdateEndpoint(r.Context(), req.client.ClientID, r.PathValue("webhookID"), url, events, active)
+	}
+	if err != nil {
+		h.handleWebhookError(w, r, req, err)
+		return
+	}
+	redirectToWebhooks(w, r, req.client)
+}
+
+// HandleRotateWebhookSecret はPOST /clients/{id}/webhooks/{webhookID}/rotateを処理します
+// 署名用のシークレットを再生成し、一度だけ表示します
+func (h *ClientHandler) HandleRotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	req := h.authorizeClientManageRequest(w, r)
+	if req == nil {
+		return
+	}
+
+	endpoint, err := h.webhookService.RotateSecret(r.Context(), req.client.ClientID, r.PathValue("webhookID"))
+	if err != nil {
+		h.handleWebhookError(w, r, req, err)
+		return
+	}
+	h.renderEditPage(w, r, req.user, req.client, http.StatusOK, map[string]interface{}{
+		"NewWebhookSecret":   endpoint.Secret,
+		"NewWebhookEndpoint": endpoint,
+	})
+}
+
+// HandleDeleteWebhook はPOST /clients/{id}/webhooks/{webhookID}/deleteを処理します
+// Webhookの送信先とその配信の記録を削除します
+func (h *ClientHandler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	req := h.authorizeClientManageRequest(w, r)
+	if req == nil {
+		return
+	}
+
+	if err := h.webhookService.DeleteEndpoint(r.Context(), req.client.ClientID, r.PathValue("webhookID")); err != nil {
+		h.handleWebhookError(w, r, req, err)
+		return
+	}
+	redirectToWebhooks(w, r, req.client)
+}
+
+// HandleReplayWebhookDelivery はPOST /clients/{id}/webhooks/{webhookID}/deliveries/{deliveryID}/replayを処理します
+// 配信と同じ本文を送信し直します
+func (h *ClientHandler) HandleReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	req := h.authorizeClientManageRequest(w, r)
+	if req == nil {
+		return
+	}
+
+	if _, err := h.webhookService.Replay(r.Context(), req.client.ClientID, r.PathValue("deliveryID")); err != nil {
+		h.handleWebhookError(w, r, req, err)
+		return
+	}
+	redirectToWebhooks(w, r, req.client)
+}
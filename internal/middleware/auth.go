@@ -3,8 +3,12 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/service"
 	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwt"
 )
 
@@ -51,6 +55,38 @@ func JWTAuth(jwtSecret string) func(http.Handler) http.Handler {
 	}
 }
 
+// ResourceAuth はJWT（Bearer）またはアプリパスワード（Basic）で認証するミドルウェアを返します
+// Basic認証ではユーザー名とscopeを持つアプリパスワードを検証し、JWTと同じ形式のクレームをコンテキストに追加します
+func ResourceAuth(jwtSecret string, appPasswordService *service.AppPasswordService, scope string) func(http.Handler) http.Handler {
+	bearerAuth := JWTAuth(jwtSecret)
+	return func(next http.Handler) http.Handler {
+		bearerNext := bearerAuth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				bearerNext.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := appPasswordService.Authenticate(r.Context(), username, password, scope)
+			if err != nil {
+				if !errors.Is(err, domain.ErrUnauthenticated) {
+					log.Printf("Failed to authenticate app password: %v", err)
+					writeJSONError(w, http.StatusInternalServerError, "internal_error", "Failed to authenticate")
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Basic realm="jyogi", charset="UTF-8"`)
+				writeJSONError(w, http.StatusUnauthorized, "invalid_credentials", "Username or app password is invalid, expired or lacks the required scope")
+				return
+			}
+
+			claims := &jwt.Claims{UserID: user.ID, DiscordID: user.DiscordID, Username: user.Username}
+			ctx := context.WithValue(r.Context(), UserClaimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 // writeJSONError はJSON形式のエラーレスポンスを書き込みます
 func writeJSONError(w http.ResponseWriter, status int, errorCode, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,80 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type appPasswordRepository struct {
+	db *gorm.DB
+}
+
+// NewAppPasswordRepository は新しいGORMアプリパスワードリポジトリを作成します
+func NewAppPasswordRepository(db *gorm.DB) repository.AppPasswordRepository {
+	return &appPasswordRepository{db: db}
+}
+
+// Create は新しいアプリパスワードをデータベースに挿入します
+func (r *appPasswordRepository) Create(ctx context.Context, password *domain.AppPassword) error {
+	if err := password.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.db.WithContext(ctx).Create(FromDomainAppPassword(password)).Error; err != nil {
+		return fmt.Errorf("failed to create app password: %w", err)
+	}
+	return nil
+}
+
+// GetByID はIDでアプリパスワードを取得します
+func (r *appPasswordRepository) GetByID(ctx context.Context, id string) (*domain.AppPassword, error) {
+	var model AppPassword
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: id=%s", domain.ErrAppPasswordNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get app password: %w", err)
+	}
+	return model.ToDomain(), nil
+}
+
+// ListByUserID はユーザーのアプリパスワードを新しい順に取得します
+func (r *appPasswordRepository) ListByUserID(ctx context.Context, userID string) ([]*domain.AppPassword, error) {
+	var models []AppPassword
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Order("id").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list app passwords: %w", err)
+	}
+
+	passwords := make([]*domain.AppPassword, len(models))
+	for i, model := range models {
+		passwords[i] = model.ToDomain()
+	}
+	return passwords, nil
+}
+
+// UpdateLastUsed は最終使用日時を記録します
+func (r *appPasswordRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&AppPassword{}).Where("id = ?", id).Update("last_used_at", usedAt).Error; err != nil {
+		return fmt.Errorf("failed to update app password: %w", err)
+	}
+	return nil
+}
+
+// Delete はユーザーのアプリパスワードを削除します
+func (r *appPasswordRepository) Delete(ctx context.Context, userID, id string) error {
+	result := r.db.WithContext(ctx).Delete(&AppPassword{}, "id = ? AND user_id = ?", id, userID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete app password: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id=%s", domain.ErrAppPasswordNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupAppPasswordTestDB はアプリパスワードのテスト用のインメモリGORMデータベースをセットアップします
+func setupAppPasswordTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&AppPassword{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestAppPasswordRepository はアプリパスワードの登録・一覧・最終使用日時の記録・削除をテストします
+func TestAppPasswordRepository(t *testing.T) {
+	db := setupAppPasswordTestDB(t)
+	repo := NewAppPasswordRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	expiresAt := now.Add(30 * 24 * time.Hour)
+	passwords := []*domain.AppPassword{
+		{ID: "ap-1", UserID: "user-1", Name: "Gitea", Scopes: []string{domain.AppPasswordScopeLDAP}, HashedPassword: "hash-1", CreatedAt: now.Add(-time.Hour)},
+		{ID: "ap-2", UserID: "user-1", Name: "Wiki.js", Scopes: []string{domain.AppPasswordScopeProfile, domain.AppPasswordScopeMembers}, HashedPassword: "hash-2", CreatedAt: now, ExpiresAt: &expiresAt},
+		{ID: "ap-3", UserID: "user-2", Name: "Gitea", Scopes: []string{domain.AppPasswordScopeLDAP}, HashedPassword: "hash-3", CreatedAt: now},
+	}
+	for _, p := range passwords {
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatalf("Failed to create app password: %v", err)
+		}
+	}
+	if err := repo.Create(ctx, &domain.AppPassword{ID: "ap-4", UserID: "user-1", Scopes: []string{domain.AppPasswordScopeLDAP}, HashedPassword: "hash"}); !errors.Is(err, domain.ErrInvalidAppPassword) {
+		t.Errorf("Expected ErrInvalidAppPassword for empty name, got %v", err)
+	}
+	if err := repo.Create(ctx, &domain.AppPassword{ID: "ap-5", UserID: "user-1", Name: "Git", Scopes: []string{"admin"}, HashedPassword: "hash"}); !errors.Is(err, domain.ErrInvalidAppPassword) {
+		t.Errorf("Expected ErrInvalidAppPassword for unknown scope, got %v", err)
+	}
+
+	list, err := repo.ListByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to list app passwords: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "ap-2" || list[1].ID != "ap-1" || list[0].LastUsedAt != nil {
+		t.Fatalf("Unexpected app passwords: %+v", list)
+	}
+	if !slices.Equal(list[0].Scopes, []string{domain.AppPasswordScopeProfile, domain.AppPasswordScopeMembers}) {
+		t.Errorf("Unexpected scopes: %v", list[0].Scopes)
+	}
+	if list[0].ExpiresAt == nil || !list[0].ExpiresAt.Equal(expiresAt) || list[1].ExpiresAt != nil {
+		t.Errorf("Unexpected expiry: %v, %v", list[0].ExpiresAt, list[1].ExpiresAt)
+	}
+
+	// スコープの導入前に発行したパスワード（スコープが空）はLDAP用として扱う
+	if err := db.Model(&AppPassword{}).Where("id = ?", "ap-3").Update("scopes", "").Error; err != nil {
+		t.Fatalf("Failed to clear scopes: %v", err)
+	}
+	if legacy, _ := repo.ListByUserID(ctx, "user-2"); !slices.Equal(legacy[0].Scopes, []string{domain.AppPasswordScopeLDAP}) {
+		t.Errorf("Expected legacy password to have ldap scope, got %v", legacy[0].Scopes)
+	}
+
+	if err := repo.UpdateLastUsed(ctx, "ap-1", now); err != nil {
+		t.Fatalf("Failed to update last used: %v", err)
+	}
+	list, _ = repo.ListByUserID(ctx, "user-1")
+	if list[1].LastUsedAt == nil || !list[1].LastUsedAt.Equal(now) {
+		t.Errorf("Expected last used at %v, got %v", now, list[1].LastUsedAt)
+	}
+
+	// 他のユーザーのアプリパスワードは削除できない
+	if err := repo.Delete(ctx, "user-2", "ap-1"); !errors.Is(err, domain.ErrAppPasswordNotFound) {
+		t.Errorf("Expected ErrAppPasswordNotFound, got %v", err)
+	}
+	if err := repo.Delete(ctx, "user-1", "ap-1"); err != nil {
+		t.Fatalf("Failed to delete app password: %v", err)
+	}
+	if list, _ := repo.ListByUserID(ctx, "user-1"); len(list) != 1 {
+		t.Errorf("Expected 1 app password after delete, got %d", len(list))
+	}
+}
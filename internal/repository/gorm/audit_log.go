@@ -0,0 +1,53 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository は新しいGORM監査ログリポジトリを作成します
+func NewAuditLogRepository(db *gorm.DB) repository.AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create は監査ログを追加します
+func (r *auditLogRepository) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	if err := r.db.WithContext(ctx).Create(FromDomainAuditEntry(entry)).Error; err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// List は監査ログを新しい順に取得します（actionが空の場合は全ての操作）
+func (r *auditLogRepository) List(ctx context.Context, action string, limit, offset int) ([]*domain.AuditEntry, error) {
+	query := r.db.WithContext(ctx).Order("created_at DESC").Order("id DESC")
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var logs []AuditLog
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	entries := make([]*domain.AuditEntry, len(logs))
+	for i, l := range logs {
+		entries[i] = l.ToDomain()
+	}
+	return entries, nil
+}
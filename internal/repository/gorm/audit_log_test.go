@@ -0,0 +1,62 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// TestAuditLogRepository_CreateAndList は監査ログの追加と操作ごとの新しい順の取得をテストします
+func TestAuditLogRepository_CreateAndList(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&AuditLog{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+	repo := NewAuditLogRepository(db)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	entries := []*domain.AuditEntry{
+		{ID: "1", Action: domain.AuditActionMemberExport, ActorUserID: "user-1", Via: domain.AuditViaWeb, RemoteAddr: "192.0.2.1", Details: map[string]string{"format": "csv"}, CreatedAt: base},
+		{ID: "2", Action: "other.action", ActorUserID: "user-1", Via: domain.AuditViaWeb, CreatedAt: base.Add(time.Minute)},
+		{ID: "3", Action: domain.AuditActionMemberExport, ActorUserID: "user-2", Via: domain.AuditViaCLI, Details: map[string]string{"format": "xlsx"}, CreatedAt: base.Add(2 * time.Minute)},
+	}
+	for _, entry := range entries {
+		if err := repo.Create(ctx, entry); err != nil {
+			t.Fatalf("Failed to create audit log: %v", err)
+		}
+	}
+
+	exports, err := repo.List(ctx, domain.AuditActionMemberExport, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to list audit logs: %v", err)
+	}
+	if len(exports) != 2 || exports[0].ID != "3" || exports[1].ID != "1" {
+		t.Fatalf("Expected exports [3 1], got %d entries", len(exports))
+	}
+	if exports[0].Via != domain.AuditViaCLI || exports[0].Details["format"] != "xlsx" {
+		t.Errorf("Expected cli xlsx export, got %+v", exports[0])
+	}
+	if exports[1].RemoteAddr != "192.0.2.1" || exports[1].ActorUserID != "user-1" {
+		t.Errorf("Expected remote address and actor to be stored, got %+v", exports[1])
+	}
+
+	all, err := repo.List(ctx, "", 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to list audit logs: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != "3" || all[1].ID != "2" {
+		t.Errorf("Expected [3 2], got %d entries", len(all))
+	}
+}
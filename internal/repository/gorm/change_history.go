@@ -0,0 +1,91 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type changeHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewChangeHistoryRepository は新しいGORM変更履歴リポジトリを作成します
+func NewChangeHistoryRepository(db *gorm.DB) repository.ChangeHistoryRepository {
+	return &changeHistoryRepository{db: db}
+}
+
+// Create は変更履歴を追加します
+// Versionはユーザーと対象ごとの最大値+1を採番し、recordに設定します
+func (r *changeHistoryRepository) Create(ctx context.Context, record *domain.ChangeRecord) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var version int
+		if err := tx.Model(&ChangeRecord{}).
+			Where("user_id = ? AND subject = ?", record.UserID, string(record.Subject)).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&version).Error; err != nil {
+			return fmt.Errorf("failed to get latest change version: %w", err)
+		}
+
+		record.Version = version + 1
+		if err := tx.Create(FromDomainChangeRecord(record)).Error; err != nil {
+			return fmt.Errorf("failed to create change record: %w", err)
+		}
+		return nil
+	})
+}
+
+// List は変更履歴を新しい順に取得します（userIDが空の場合は全ユーザー）
+func (r *changeHistoryRepository) List(ctx context.Context, userID string, limit, offset int) ([]*domain.ChangeRecord, error) {
+	query := r.db.WithContext(ctx).Order("created_at DESC").Order("version DESC")
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var records []ChangeRecord
+	if err := query.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to list change history: %w", err)
+	}
+
+	domainRecords := make([]*domain.ChangeRecord, len(records))
+	for i, record := range records {
+		domainRecords[i] = record.ToDomain()
+	}
+	return domainRecords, nil
+}
+
+// ListSince は(since, afterID)より後に記録された変更履歴を古い順に取得します（記録日時・IDの順）
+// afterIDが空の場合はsince以降に記録されたものをすべて対象にします
+func (r *changeHistoryRepository) ListSince(ctx context.Context, since time.Time, afterID string, limit int) ([]*domain.ChangeRecord, error) {
+	query := r.db.WithContext(ctx).Order("created_at ASC").Order("id ASC")
+	if afterID == "" {
+		query = query.Where("created_at >= ?", since)
+	} else {
+		query = query.Where("created_at > ? OR (created_at = ? AND id > ?)", since, since, afterID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var records []ChangeRecord
+	if err := query.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to list change history since %s: %w", since.Format(time.RFC3339), err)
+	}
+
+	domainRecords := make([]*domain.ChangeRecord, len(records))
+	for i, record := range records {
+		domainRecords[i] = record.ToDomain()
+	}
+	return domainRecords, nil
+}
@@ -0,0 +1,134 @@
+package gorm
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupChangeHistoryTestDB は変更履歴テスト用のインメモリGORMデータベースをセットアップします
+func setupChangeHistoryTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&ChangeRecord{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestChangeHistoryRepository_CreateAndList はバージョンの採番と新しい順の取得をテストします
+func TestChangeHistoryRepository_CreateAndList(t *testing.T) {
+	db := setupChangeHistoryTestDB(t)
+	repo := NewChangeHistoryRepository(db)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	records := []*domain.ChangeRecord{
+		{ID: "1", UserID: "user-1", Subject: domain.ChangeSubjectProfile, ActorType: domain.ChangeActorSync, CreatedAt: base},
+		{ID: "2", UserID: "user-1", Subject: domain.ChangeSubjectProfile, ActorType: domain.ChangeActorManual, ActorUserID: "user-1", CreatedAt: base.Add(time.Minute)},
+		{ID: "3", UserID: "user-1", Subject: domain.ChangeSubjectUser, ActorType: domain.ChangeActorSync, CreatedAt: base.Add(2 * time.Minute)},
+		{ID: "4", UserID: "user-2", Subject: domain.ChangeSubjectProfile, ActorType: domain.ChangeActorAdmin, ActorUserID: "user-1", CreatedAt: base.Add(3 * time.Minute)},
+	}
+	for _, record := range records {
+		record.Changes = []domain.FieldChange{{Field: "real_name", Old: "", New: "じょぎ太郎"}}
+		if err := repo.Create(ctx, record); err != nil {
+			t.Fatalf("Failed to create change record: %v", err)
+		}
+	}
+
+	// バージョンはユーザーと対象ごとに採番される
+	wantVersions := []int{1, 2, 1, 1}
+	for i, record := range records {
+		if record.Version != wantVersions[i] {
+			t.Errorf("record %s: expected version %d, got %d", record.ID, wantVersions[i], record.Version)
+		}
+	}
+
+	history, err := repo.List(ctx, "user-1", 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to list change history: %v", err)
+	}
+	if len(history) != 3 || history[0].ID != "3" || history[2].ID != "1" {
+		t.Fatalf("Expected 3 records for user-1 newest first, got %v", history)
+	}
+	if history[1].ActorType != domain.ChangeActorManual || history[1].ActorUserID != "user-1" {
+		t.Errorf("Expected actor to round-trip, got %s (%s)", history[1].ActorType, history[1].ActorUserID)
+	}
+	if len(history[0].Changes) != 1 || history[0].Changes[0].New != "じょぎ太郎" {
+		t.Errorf("Expected changes to round-trip, got %v", history[0].Changes)
+	}
+
+	all, err := repo.List(ctx, "", 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to list change history: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != "4" {
+		t.Errorf("Expected 2 newest records across users, got %v", all)
+	}
+}
+
+// TestChangeHistoryRepository_ListSince は記録日時・IDの順で続きから取得できることをテストします
+func TestChangeHistoryRepository_ListSince(t *testing.T) {
+	db := setupChangeHistoryTestDB(t)
+	repo := NewChangeHistoryRepository(db)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	records := []*domain.ChangeRecord{
+		{ID: "a", UserID: "user-1", Subject: domain.ChangeSubjectUser, CreatedAt: base},
+		{ID: "c", UserID: "user-2", Subject: domain.ChangeSubjectUser, CreatedAt: base.Add(time.Minute)},
+		{ID: "b", UserID: "user-3", Subject: domain.ChangeSubjectUser, CreatedAt: base.Add(time.Minute)},
+		{ID: "d", UserID: "user-4", Subject: domain.ChangeSubjectUser, CreatedAt: base.Add(2 * time.Minute)},
+	}
+	for _, record := range records {
+		record.ActorType = domain.ChangeActorSync
+		if err := repo.Create(ctx, record); err != nil {
+			t.Fatalf("Failed to create change record: %v", err)
+		}
+	}
+
+	ids := func(records []*domain.ChangeRecord) []string {
+		result := make([]string, len(records))
+		for i, record := range records {
+			result[i] = record.ID
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		since   time.Time
+		afterID string
+		limit   int
+		want    []string
+	}{
+		{name: "since includes the same time", since: base.Add(time.Minute), want: []string{"b", "c", "d"}},
+		{name: "limit", since: base, limit: 2, want: []string{"a", "b"}},
+		{name: "after id at the same time", since: base.Add(time.Minute), afterID: "b", want: []string{"c", "d"}},
+		{name: "nothing newer", since: base.Add(2 * time.Minute), afterID: "d", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.ListSince(ctx, tt.since, tt.afterID, tt.limit)
+			if err != nil {
+				t.Fatalf("Failed to list change history: %v", err)
+			}
+			if !slices.Equal(ids(got), tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, ids(got))
+			}
+		})
+	}
+}
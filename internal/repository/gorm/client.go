@@ -99,9 +99,18 @@ func (r *clientRepository) Update(ctx context.Context, client *domain.ClientApp)
 
 	// 全フィールド更新。IDで特定
 	result := r.db.WithContext(ctx).Model(&ClientApp{}).Where("id = ?", c.ID).Updates(map[string]interface{}{
-		"name":          c.Name,
-		"redirect_uris": c.RedirectURIs,
-		"updated_at":    c.UpdatedAt,
+		"client_secret":              c.ClientSecret,
+		"name":                       c.Name,
+		"redirect_uris":              c.RedirectURIs,
+		"profile_access":             c.ProfileAccess,
+		"grant_types":                c.GrantTypes,
+		"scopes":                     c.Scopes,
+		"logo_uri":                   c.LogoURI,
+		"token_endpoint_auth_method": c.TokenEndpointAuthMethod,
+		"jwks":                       c.JWKS,
+		"jwks_uri":                   c.JWKSURI,
+		"access_token_format":        c.AccessTokenFormat,
+		"updated_at":                 c.UpdatedAt,
 	})
 
 	if result.Error != nil {
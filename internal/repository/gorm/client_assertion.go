@@ -0,0 +1,46 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type clientAssertionRepository struct {
+	db *gorm.DB
+}
+
+// NewClientAssertionRepository は新しいGORM client_assertionのjtiの記録リポジトリを作成します
+func NewClientAssertionRepository(db *gorm.DB) repository.ClientAssertionRepository {
+	return &clientAssertionRepository{db: db}
+}
+
+// Record はjtiを有効期限まで記録します
+// 有効期限内に同じクライアントの同じjtiが記録済みの場合はErrClientAssertionReplayedを返します
+func (r *clientAssertionRepository) Record(ctx context.Context, clientID, jti string, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 期限切れの記録を削除する（期限切れのjtiは再び使用できる）
+		if err := tx.Where("client_id = ? AND expires_at <= ?", clientID, time.Now()).Delete(&ClientAssertionJTI{}).Error; err != nil {
+			return fmt.Errorf("failed to delete expired client assertions: %w", err)
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ClientAssertionJTI{
+			ClientID:  clientID,
+			JTI:       jti,
+			ExpiresAt: expiresAt,
+		})
+		if result.Error != nil {
+			return fmt.Errorf("failed to record client assertion: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: jti=%s", domain.ErrClientAssertionReplayed, jti)
+		}
+		return nil
+	})
+}
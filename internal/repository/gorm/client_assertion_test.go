@@ -0,0 +1,54 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// TestClientAssertionRepository_Record はjtiの記録と再利用の検出をテストします
+func TestClientAssertionRepository_Record(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&ClientAssertionJTI{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+	repo := NewClientAssertionRepository(db)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute)
+
+	if err := repo.Record(ctx, "client-1", "jti-1", expiresAt); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := repo.Record(ctx, "client-1", "jti-1", expiresAt); !errors.Is(err, domain.ErrClientAssertionReplayed) {
+		t.Errorf("Expected ErrClientAssertionReplayed for a reused jti, got %v", err)
+	}
+	// jtiはクライアントごとに記録する
+	if err := repo.Record(ctx, "client-2", "jti-1", expiresAt); err != nil {
+		t.Errorf("Expected the same jti of another client to be recorded, got %v", err)
+	}
+
+	// 期限切れの記録は削除され、同じjtiを記録できる
+	if err := repo.Record(ctx, "client-1", "jti-2", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := repo.Record(ctx, "client-1", "jti-2", expiresAt); err != nil {
+		t.Errorf("Expected an expired jti to be recorded again, got %v", err)
+	}
+	var count int64
+	db.Model(&ClientAssertionJTI{}).Where("client_id = ?", "client-1").Count(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 records of client-1, got %d", count)
+	}
+}
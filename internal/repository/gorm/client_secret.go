@@ -0,0 +1,83 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type clientSecretRepository struct {
+	db *gorm.DB
+}
+
+// NewClientSecretRepository は新しいGORM Client Secretリポジトリを作成します
+func NewClientSecretRepository(db *gorm.DB) repository.ClientSecretRepository {
+	return &clientSecretRepository{db: db}
+}
+
+// Create は新しいClient Secretをデータベースに挿入します
+func (r *clientSecretRepository) Create(ctx context.Context, secret *domain.ClientSecret) error {
+	if err := secret.Validate(); err != nil {
+		return fmt.Errorf("invalid client secret: %w", err)
+	}
+
+	if err := r.db.WithContext(ctx).Create(FromDomainClientSecret(secret)).Error; err != nil {
+		return fmt.Errorf("failed to create client secret: %w", err)
+	}
+	return nil
+}
+
+// ListByClientID はクライアントのClient Secretを新しい順に取得します
+func (r *clientSecretRepository) ListByClientID(ctx context.Context, clientID string) ([]*domain.ClientSecret, error) {
+	var models []ClientSecret
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).Order("created_at DESC").Order("id").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list client secrets: %w", err)
+	}
+
+	secrets := make([]*domain.ClientSecret, len(models))
+	for i, model := range models {
+		secrets[i] = model.ToDomain()
+	}
+	return secrets, nil
+}
+
+// UpdateExpiresAt は有効期限を変更します
+func (r *clientSecretRepository) UpdateExpiresAt(ctx context.Context, id string, expiresAt time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&ClientSecret{}).Where("id = ?", id).Update("expires_at", expiresAt).Error; err != nil {
+		return fmt.Errorf("failed to update client secret: %w", err)
+	}
+	return nil
+}
+
+// UpdateLastUsed は最終使用日時を記録します
+func (r *clientSecretRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&ClientSecret{}).Where("id = ?", id).Update("last_used_at", usedAt).Error; err != nil {
+		return fmt.Errorf("failed to update client secret: %w", err)
+	}
+	return nil
+}
+
+// Delete はクライアントのClient Secretを削除します
+func (r *clientSecretRepository) Delete(ctx context.Context, clientID, id string) error {
+	result := r.db.WithContext(ctx).Delete(&ClientSecret{}, "id = ? AND client_id = ?", id, clientID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete client secret: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id=%s", domain.ErrClientSecretNotFound, id)
+	}
+	return nil
+}
+
+// DeleteByClientID はクライアントのClient Secretをすべて削除します
+func (r *clientSecretRepository) DeleteByClientID(ctx context.Context, clientID string) error {
+	if err := r.db.WithContext(ctx).Delete(&ClientSecret{}, "client_id = ?", clientID).Error; err != nil {
+		return fmt.Errorf("failed to delete client secrets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupClientSecretTestDB はClient Secretのテスト用のインメモリGORMデータベースをセットアップします
+func setupClientSecretTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&ClientSecret{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestClientSecretRepository はClient Secretの登録・一覧・有効期限と最終使用日時の記録・削除をテストします
+func TestClientSecretRepository(t *testing.T) {
+	db := setupClientSecretTestDB(t)
+	repo := NewClientSecretRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	old := &domain.ClientSecret{ID: "cs-1", ClientID: "client-1", HashedSecret: "hash-1", CreatedAt: now.Add(-time.Hour)}
+	current := &domain.ClientSecret{ID: "cs-2", ClientID: "client-1", HashedSecret: "hash-2", CreatedAt: now}
+	other := &domain.ClientSecret{ID: "cs-3", ClientID: "client-2", HashedSecret: "hash-3", CreatedAt: now}
+	for _, secret := range []*domain.ClientSecret{old, current, other} {
+		if err := repo.Create(ctx, secret); err != nil {
+			t.Fatalf("Failed to create client secret: %v", err)
+		}
+	}
+	if err := repo.Create(ctx, &domain.ClientSecret{ID: "cs-4", ClientID: "client-1"}); err == nil {
+		t.Error("Expected error for missing hashed secret")
+	}
+
+	t.Run("一覧", func(t *testing.T) {
+		secrets, err := repo.ListByClientID(ctx, "client-1")
+		if err != nil {
+			t.Fatalf("ListByClientID failed: %v", err)
+		}
+		if len(secrets) != 2 || secrets[0].ID != "cs-2" || secrets[1].ID != "cs-1" {
+			t.Fatalf("Expected newest first, got %+v", secrets)
+		}
+		if secrets[0].ExpiresAt != nil || secrets[0].LastUsedAt != nil {
+			t.Errorf("Expected no expiry and no usage, got %+v", secrets[0])
+		}
+	})
+
+	t.Run("有効期限と最終使用日時", func(t *testing.T) {
+		if err := repo.UpdateExpiresAt(ctx, "cs-1", now.Add(time.Hour)); err != nil {
+			t.Fatalf("UpdateExpiresAt failed: %v", err)
+		}
+		if err := repo.UpdateLastUsed(ctx, "cs-1", now); err != nil {
+			t.Fatalf("UpdateLastUsed failed: %v", err)
+		}
+		secrets, _ := repo.ListByClientID(ctx, "client-1")
+		if secrets[1].ExpiresAt == nil || secrets[1].LastUsedAt == nil || secrets[1].IsExpired(now) || !secrets[1].IsExpired(now.Add(2*time.Hour)) {
+			t.Errorf("Unexpected client secret: %+v", secrets[1])
+		}
+	})
+
+	t.Run("削除", func(t *testing.T) {
+		if err := repo.Delete(ctx, "client-2", "cs-1"); !errors.Is(err, domain.ErrClientSecretNotFound) {
+			t.Errorf("Expected ErrClientSecretNotFound for another client's secret, got %v", err)
+		}
+		if err := repo.Delete(ctx, "client-1", "cs-1"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := repo.DeleteByClientID(ctx, "client-1"); err != nil {
+			t.Fatalf("DeleteByClientID failed: %v", err)
+		}
+		if secrets, _ := repo.ListByClientID(ctx, "client-1"); len(secrets) != 0 {
+			t.Errorf("Expected all secrets of client-1 to be deleted, got %d", len(secrets))
+		}
+		if secrets, _ := repo.ListByClientID(ctx, "client-2"); len(secrets) != 1 {
+			t.Errorf("Expected secrets of client-2 to remain, got %d", len(secrets))
+		}
+	})
+}
@@ -144,6 +144,60 @@ func TestClientRepository_Update(t *testing.T) {
 	}
 }
 
+// TestClientRepository_RegistrationFields は所有者・動的登録のメタデータの保存と更新をテストします
+func TestClientRepository_RegistrationFields(t *testing.T) {
+	db := setupClientTestDB(t)
+	repo := NewClientRepository(db)
+	ctx := context.Background()
+
+	client := &domain.ClientApp{
+		ID:                      "test-client-dcr",
+		OwnerID:                 "owner-1",
+		ClientID:                "client-id-dcr",
+		ClientSecret:            "hashed-secret",
+		Name:                    "CLI App",
+		RedirectURIs:            []string{},
+		GrantTypes:              []string{domain.GrantTypeDeviceCode},
+		Scopes:                  []string{domain.ClientScopeProfile},
+		RegistrationAccessToken: "hashed-registration-token",
+		CreatedAt:               time.Now(),
+		UpdatedAt:               time.Now(),
+	}
+	if err := repo.Create(ctx, client); err != nil {
+		t.Fatalf("Failed to create client without redirect URIs: %v", err)
+	}
+
+	retrieved, err := repo.GetByClientID(ctx, client.ClientID)
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	if retrieved.OwnerID != "owner-1" || retrieved.RegistrationAccessToken != "hashed-registration-token" {
+		t.Errorf("Expected owner and registration access token to be persisted, got %+v", retrieved)
+	}
+	if retrieved.AllowsGrantType(domain.GrantTypeAuthorizationCode) || !retrieved.AllowsGrantType(domain.GrantTypeDeviceCode) || retrieved.AllowsScope(domain.ClientScopeMembers) {
+		t.Errorf("Unexpected grant types or scopes: %v %v", retrieved.GrantTypes, retrieved.Scopes)
+	}
+
+	client.GrantTypes = []string{domain.GrantTypeAuthorizationCode, domain.GrantTypeDeviceCode}
+	client.RedirectURIs = []string{"http://localhost:8080/callback"}
+	client.Scopes = []string{domain.ClientScopeMembers, domain.ClientScopeProfile}
+	client.LogoURI = "https://example.com/logo.png"
+	client.TokenEndpointAuthMethod = domain.TokenEndpointAuthMethodClientSecretBasic
+	if err := repo.Update(ctx, client); err != nil {
+		t.Fatalf("Failed to update client: %v", err)
+	}
+	retrieved, _ = repo.GetByClientID(ctx, client.ClientID)
+	if len(retrieved.GrantTypes) != 2 || !retrieved.AllowsScope(domain.ClientScopeMembers) || retrieved.LogoURI != client.LogoURI || retrieved.TokenEndpointAuthMethod != client.TokenEndpointAuthMethod {
+		t.Errorf("Expected metadata to be updated, got %+v", retrieved)
+	}
+
+	// 認可コードグラントを使用するクライアントにはリダイレクトURIが必要
+	client.RedirectURIs = []string{}
+	if err := repo.Update(ctx, client); err == nil {
+		t.Error("Expected error for authorization_code client without redirect URIs")
+	}
+}
+
 // TestClientRepository_GetAll は全クライアント取得をテストします
 func TestClientRepository_GetAll(t *testing.T) {
 	db := setupClientTestDB(t)
@@ -1,6 +1,7 @@
 package gorm
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -142,9 +143,24 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 			&User{},
 			&Session{},
 			&ClientApp{},
+			&ClientSecret{},
+			&ClientAssertionJTI{},
+			&InitialAccessToken{},
 			&AuthCode{},
+			&DeviceCode{},
 			&Token{},
 			&Profile{},
+			&Role{},
+			&SyncCursor{},
+			&IntroMessage{},
+			&ChangeRecord{},
+			&UserRole{},
+			&ProfileSearchTerm{},
+			&AuditLog{},
+			&WebhookEndpoint{},
+			&WebhookDelivery{},
+			&SAMLServiceProvider{},
+			&AppPassword{},
 		); err != nil {
 			// マイグレーション失敗時、DB接続をクローズしてリソースリークを防ぐ
 			if sqlDB, dbErr := db.DB(); dbErr == nil {
@@ -158,6 +174,11 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 
 		log.Printf("AutoMigrate completed successfully for TiDB %s@%s:%s/%s",
 			cfg.TiDBUser, cfg.TiDBHost, cfg.TiDBPort, cfg.TiDBDatabase)
+
+		// 検索用の対応表・カラムは導出データのため、失敗しても起動は継続する
+		if err := backfillMemberSearch(context.Background(), db); err != nil {
+			log.Printf("Warning: failed to backfill member search data: %v", err)
+		}
 	}
 
 	log.Printf("TiDB initialized: %s@%s:%s/%s", cfg.TiDBUser, cfg.TiDBHost, cfg.TiDBPort, cfg.TiDBDatabase)
@@ -0,0 +1,101 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type deviceCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceCodeRepository は新しいGORMデバイスコードリポジトリを作成します
+func NewDeviceCodeRepository(db *gorm.DB) repository.DeviceCodeRepository {
+	return &deviceCodeRepository{db: db}
+}
+
+// Create は新しいデバイスコードをデータベースに挿入します
+func (r *deviceCodeRepository) Create(ctx context.Context, deviceCode *domain.DeviceCode) error {
+	if err := deviceCode.Validate(); err != nil {
+		return fmt.Errorf("invalid device code: %w", err)
+	}
+
+	if err := r.db.WithContext(ctx).Create(FromDomainDeviceCode(deviceCode)).Error; err != nil {
+		return fmt.Errorf("failed to create device code: %w", err)
+	}
+	return nil
+}
+
+// GetByDeviceCode はデバイスコードで取得します
+func (r *deviceCodeRepository) GetByDeviceCode(ctx context.Context, deviceCode string) (*domain.DeviceCode, error) {
+	var d DeviceCode
+	if err := r.db.WithContext(ctx).Where("device_code = ?", deviceCode).First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrDeviceCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to get device code: %w", err)
+	}
+	return d.ToDomain(), nil
+}
+
+// GetByUserCode はユーザーコードで期限内のデバイスコードを取得します
+// 期限切れのユーザーコードは削除されるまで残るため、期限内で最も新しいものを返します
+func (r *deviceCodeRepository) GetByUserCode(ctx context.Context, userCode string) (*domain.DeviceCode, error) {
+	var d DeviceCode
+	err := r.db.WithContext(ctx).
+		Where("user_code = ? AND expires_at > ?", userCode, time.Now()).
+		Order("created_at DESC").
+		First(&d).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrDeviceCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to get device code: %w", err)
+	}
+	return d.ToDomain(), nil
+}
+
+// UpdatePolling は最後にポーリングされた日時とポーリングの間隔を記録します
+func (r *deviceCodeRepository) UpdatePolling(ctx context.Context, id string, polledAt time.Time, interval time.Duration) error {
+	err := r.db.WithContext(ctx).Model(&DeviceCode{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_polled_at":   polledAt,
+		"interval_seconds": int(interval / time.Second),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update device code: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus は状態がfromの場合のみtoに変更します
+// 同じデバイスコードの承認とトークンの発行が同時に行われても、一方のみ成功します
+func (r *deviceCodeRepository) UpdateStatus(ctx context.Context, id string, from, to domain.DeviceCodeStatus, userID string) error {
+	updates := map[string]interface{}{"status": string(to)}
+	if userID != "" {
+		updates["user_id"] = userID
+	}
+
+	result := r.db.WithContext(ctx).Model(&DeviceCode{}).Where("id = ? AND status = ?", id, string(from)).Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update device code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id=%s status=%s", domain.ErrDeviceCodeNotFound, id, from)
+	}
+	return nil
+}
+
+// DeleteExpired は期限切れのデバイスコードを削除します
+func (r *deviceCodeRepository) DeleteExpired(ctx context.Context) error {
+	if err := r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&DeviceCode{}).Error; err != nil {
+		return fmt.Errorf("failed to delete expired device codes: %w", err)
+	}
+	return nil
+}
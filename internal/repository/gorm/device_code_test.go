@@ -0,0 +1,131 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupDeviceCodeTestDB はデバイスコードのテスト用のインメモリGORMデータベースをセットアップします
+func setupDeviceCodeTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&DeviceCode{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestDeviceCodeRepository はデバイスコードの登録・取得・状態の変更・期限切れの削除をテストします
+func TestDeviceCodeRepository(t *testing.T) {
+	db := setupDeviceCodeTestDB(t)
+	repo := NewDeviceCodeRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	live := &domain.DeviceCode{
+		ID:         "dc-1",
+		DeviceCode: "device-code-1",
+		UserCode:   "BCDFGHJK",
+		ClientID:   "client-1",
+		Status:     domain.DeviceCodeStatusPending,
+		Interval:   5 * time.Second,
+		ExpiresAt:  now.Add(10 * time.Minute),
+		CreatedAt:  now,
+	}
+	expired := &domain.DeviceCode{
+		ID:         "dc-2",
+		DeviceCode: "device-code-2",
+		UserCode:   "LMNPQRST",
+		ClientID:   "client-1",
+		Status:     domain.DeviceCodeStatusPending,
+		Interval:   5 * time.Second,
+		ExpiresAt:  now.Add(-time.Minute),
+		CreatedAt:  now.Add(-11 * time.Minute),
+	}
+	for _, d := range []*domain.DeviceCode{live, expired} {
+		if err := repo.Create(ctx, d); err != nil {
+			t.Fatalf("Failed to create device code: %v", err)
+		}
+	}
+	if err := repo.Create(ctx, &domain.DeviceCode{ID: "dc-3", DeviceCode: "device-code-3", UserCode: "ABC", ClientID: "client-1", Status: domain.DeviceCodeStatusPending, Interval: 5 * time.Second, ExpiresAt: now}); err == nil {
+		t.Error("Expected error for invalid user code")
+	}
+
+	t.Run("デバイスコードで取得", func(t *testing.T) {
+		got, err := repo.GetByDeviceCode(ctx, "device-code-1")
+		if err != nil {
+			t.Fatalf("GetByDeviceCode failed: %v", err)
+		}
+		if got.UserCode != live.UserCode || got.Interval != 5*time.Second || got.Status != domain.DeviceCodeStatusPending || got.LastPolledAt != nil {
+			t.Errorf("Unexpected device code: %+v", got)
+		}
+		if _, err := repo.GetByDeviceCode(ctx, "unknown"); !errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			t.Errorf("Expected ErrDeviceCodeNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ユーザーコードで取得", func(t *testing.T) {
+		got, err := repo.GetByUserCode(ctx, "BCDFGHJK")
+		if err != nil {
+			t.Fatalf("GetByUserCode failed: %v", err)
+		}
+		if got.ID != live.ID {
+			t.Errorf("Expected %s, got %s", live.ID, got.ID)
+		}
+		if _, err := repo.GetByUserCode(ctx, "LMNPQRST"); !errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			t.Errorf("Expected ErrDeviceCodeNotFound for expired user code, got %v", err)
+		}
+	})
+
+	t.Run("ポーリングの記録", func(t *testing.T) {
+		if err := repo.UpdatePolling(ctx, live.ID, now, 10*time.Second); err != nil {
+			t.Fatalf("UpdatePolling failed: %v", err)
+		}
+		got, _ := repo.GetByDeviceCode(ctx, "device-code-1")
+		if got.LastPolledAt == nil || got.Interval != 10*time.Second {
+			t.Errorf("Expected polling to be recorded, got %+v", got)
+		}
+	})
+
+	t.Run("状態の変更", func(t *testing.T) {
+		if err := repo.UpdateStatus(ctx, live.ID, domain.DeviceCodeStatusPending, domain.DeviceCodeStatusApproved, "user-1"); err != nil {
+			t.Fatalf("UpdateStatus failed: %v", err)
+		}
+		if err := repo.UpdateStatus(ctx, live.ID, domain.DeviceCodeStatusPending, domain.DeviceCodeStatusDenied, "user-2"); !errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			t.Errorf("Expected ErrDeviceCodeNotFound for a decided device code, got %v", err)
+		}
+		if err := repo.UpdateStatus(ctx, live.ID, domain.DeviceCodeStatusApproved, domain.DeviceCodeStatusUsed, ""); err != nil {
+			t.Fatalf("UpdateStatus failed: %v", err)
+		}
+		got, _ := repo.GetByDeviceCode(ctx, "device-code-1")
+		if got.Status != domain.DeviceCodeStatusUsed || got.UserID != "user-1" {
+			t.Errorf("Unexpected device code: %+v", got)
+		}
+	})
+
+	t.Run("期限切れの削除", func(t *testing.T) {
+		if err := repo.DeleteExpired(ctx); err != nil {
+			t.Fatalf("DeleteExpired failed: %v", err)
+		}
+		if _, err := repo.GetByDeviceCode(ctx, "device-code-2"); !errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			t.Errorf("Expected expired device code to be deleted, got %v", err)
+		}
+		if _, err := repo.GetByDeviceCode(ctx, "device-code-1"); err != nil {
+			t.Errorf("Expected live device code to remain, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type initialAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewInitialAccessTokenRepository は新しいGORM初期アクセストークンリポジトリを作成します
+func NewInitialAccessTokenRepository(db *gorm.DB) repository.InitialAccessTokenRepository {
+	return &initialAccessTokenRepository{db: db}
+}
+
+// Create は新しい初期アクセストークンをデータベースに挿入します
+func (r *initialAccessTokenRepository) Create(ctx context.Context, token *domain.InitialAccessToken) error {
+	if err := token.Validate(); err != nil {
+		return fmt.Errorf("invalid initial access token: %w", err)
+	}
+
+	if err := r.db.WithContext(ctx).Create(FromDomainInitialAccessToken(token)).Error; err != nil {
+		return fmt.Errorf("failed to create initial access token: %w", err)
+	}
+	return nil
+}
+
+// GetByTokenHash はトークンのハッシュで取得します
+func (r *initialAccessTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*domain.InitialAccessToken, error) {
+	var t InitialAccessToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrInvalidRegistrationToken
+		}
+		return nil, fmt.Errorf("failed to get initial access token: %w", err)
+	}
+	return t.ToDomain(), nil
+}
+
+// MarkUsed は未使用の場合のみ使用済みにします
+// 同じトークンで同時に登録されても、一方のみ成功します
+func (r *initialAccessTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&InitialAccessToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark initial access token as used: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: initial access token already used", domain.ErrInvalidRegistrationToken)
+	}
+	return nil
+}
+
+// DeleteExpired は期限切れの初期アクセストークンを削除します
+func (r *initialAccessTokenRepository) DeleteExpired(ctx context.Context) error {
+	if err := r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&InitialAccessToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete expired initial access tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupInitialAccessTokenTestDB は初期アクセストークンのテスト用のインメモリGORMデータベースをセットアップします
+func setupInitialAccessTokenTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&InitialAccessToken{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestInitialAccessTokenRepository は初期アクセストークンの登録・取得・使用済みへの変更・期限切れの削除をテストします
+func TestInitialAccessTokenRepository(t *testing.T) {
+	db := setupInitialAccessTokenTestDB(t)
+	repo := NewInitialAccessTokenRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	live := &domain.InitialAccessToken{ID: "iat-1", TokenHash: "hash-1", UserID: "user-1", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	expired := &domain.InitialAccessToken{ID: "iat-2", TokenHash: "hash-2", UserID: "user-1", ExpiresAt: now.Add(-time.Minute), CreatedAt: now.Add(-25 * time.Hour)}
+	for _, token := range []*domain.InitialAccessToken{live, expired} {
+		if err := repo.Create(ctx, token); err != nil {
+			t.Fatalf("Failed to create initial access token: %v", err)
+		}
+	}
+	if err := repo.Create(ctx, &domain.InitialAccessToken{ID: "iat-3", TokenHash: "hash-3", ExpiresAt: now}); err == nil {
+		t.Error("Expected error for missing user_id")
+	}
+
+	t.Run("ハッシュで取得", func(t *testing.T) {
+		got, err := repo.GetByTokenHash(ctx, "hash-1")
+		if err != nil {
+			t.Fatalf("GetByTokenHash failed: %v", err)
+		}
+		if got.ID != live.ID || got.UserID != "user-1" || got.UsedAt != nil || !got.IsUsable(now) {
+			t.Errorf("Unexpected initial access token: %+v", got)
+		}
+		if _, err := repo.GetByTokenHash(ctx, "unknown"); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected ErrInvalidRegistrationToken, got %v", err)
+		}
+	})
+
+	t.Run("使用済みへの変更", func(t *testing.T) {
+		if err := repo.MarkUsed(ctx, live.ID, now); err != nil {
+			t.Fatalf("MarkUsed failed: %v", err)
+		}
+		if err := repo.MarkUsed(ctx, live.ID, now); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected ErrInvalidRegistrationToken for a used token, got %v", err)
+		}
+		got, _ := repo.GetByTokenHash(ctx, "hash-1")
+		if got.UsedAt == nil || got.IsUsable(now) {
+			t.Errorf("Expected token to be used, got %+v", got)
+		}
+	})
+
+	t.Run("期限切れの削除", func(t *testing.T) {
+		if err := repo.DeleteExpired(ctx); err != nil {
+			t.Fatalf("DeleteExpired failed: %v", err)
+		}
+		if _, err := repo.GetByTokenHash(ctx, "hash-2"); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected expired token to be deleted, got %v", err)
+		}
+		if _, err := repo.GetByTokenHash(ctx, "hash-1"); err != nil {
+			t.Errorf("Expected live token to remain, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type introMessageRepository struct {
+	db *gorm.DB
+}
+
+// NewIntroMessageRepository は新しいGORM自己紹介メッセージリポジトリを作成します
+func NewIntroMessageRepository(db *gorm.DB) repository.IntroMessageRepository {
+	return &introMessageRepository{db: db}
+}
+
+// Upsert は自己紹介メッセージを挿入または更新します
+func (r *introMessageRepository) Upsert(ctx context.Context, message *domain.IntroMessage) error {
+	if err := message.Validate(); err != nil {
+		return fmt.Errorf("invalid intro message: %w", err)
+	}
+
+	m := FromDomainIntroMessage(message)
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"channel_id", "user_id", "real_name", "student_id", "hobbies", "what_to_do", "comment", "extra",
+			"posted_at", "edited_at", "deleted_at", "updated_at",
+		}),
+	}).Create(m).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert intro message: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID はメッセージIDで自己紹介メッセージを取得します（削除済みを含む）
+func (r *introMessageRepository) GetByID(ctx context.Context, id string) (*domain.IntroMessage, error) {
+	var m IntroMessage
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: id=%s", domain.ErrIntroMessageNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get intro message: %w", err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+// GetActiveByUserID はユーザーの削除されていない自己紹介メッセージを投稿日時の昇順で取得します
+func (r *introMessageRepository) GetActiveByUserID(ctx context.Context, userID string) ([]*domain.IntroMessage, error) {
+	var messages []IntroMessage
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND deleted_at IS NULL", userID).
+		Order("posted_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get intro messages by user_id: %w", err)
+	}
+
+	return toDomainIntroMessages(messages), nil
+}
+
+// GetActiveByChannelID はチャンネルの削除されていない自己紹介メッセージを取得します
+func (r *introMessageRepository) GetActiveByChannelID(ctx context.Context, channelID string) ([]*domain.IntroMessage, error) {
+	var messages []IntroMessage
+	if err := r.db.WithContext(ctx).
+		Where("channel_id = ? AND deleted_at IS NULL", channelID).
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get intro messages by channel_id: %w", err)
+	}
+
+	return toDomainIntroMessages(messages), nil
+}
+
+// MarkDeleted は自己紹介メッセージを削除済み（墓標）にします
+func (r *introMessageRepository) MarkDeleted(ctx context.Context, id string) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&IntroMessage{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark intro message as deleted: %w", result.Error)
+	}
+
+	return nil
+}
+
+func toDomainIntroMessages(messages []IntroMessage) []*domain.IntroMessage {
+	domainMessages := make([]*domain.IntroMessage, len(messages))
+	for i, m := range messages {
+		domainMessages[i] = m.ToDomain()
+	}
+	return domainMessages
+}
@@ -0,0 +1,123 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupIntroMessageTestDB は自己紹介メッセージテスト用のインメモリGORMデータベースをセットアップします
+func setupIntroMessageTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&IntroMessage{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestIntroMessageRepository_UpsertAndGet はメッセージの作成・更新・取得をテストします
+func TestIntroMessageRepository_UpsertAndGet(t *testing.T) {
+	db := setupIntroMessageTestDB(t)
+	repo := NewIntroMessageRepository(db)
+	ctx := context.Background()
+
+	postedAt := time.Now().Add(-time.Hour)
+	msg := &domain.IntroMessage{
+		ID:        "100",
+		ChannelID: "channel-1",
+		UserID:    "user-1",
+		RealName:  "じょぎ太郎",
+		Extra:     map[string]string{"hometown": "福岡"},
+		PostedAt:  postedAt,
+	}
+	if err := repo.Upsert(ctx, msg); err != nil {
+		t.Fatalf("Failed to create intro message: %v", err)
+	}
+
+	editedAt := time.Now()
+	msg.RealName = "じょぎ次郎"
+	msg.EditedAt = &editedAt
+	if err := repo.Upsert(ctx, msg); err != nil {
+		t.Fatalf("Failed to update intro message: %v", err)
+	}
+
+	retrieved, err := repo.GetByID(ctx, "100")
+	if err != nil {
+		t.Fatalf("Failed to get intro message: %v", err)
+	}
+	if retrieved.RealName != "じょぎ次郎" {
+		t.Errorf("Expected real_name to be updated, got %s", retrieved.RealName)
+	}
+	if retrieved.EditedAt == nil {
+		t.Error("Expected edited_at to be set")
+	}
+	if retrieved.Extra["hometown"] != "福岡" {
+		t.Errorf("Expected extra to round-trip, got %v", retrieved.Extra)
+	}
+
+	if _, err := repo.GetByID(ctx, "999"); !errors.Is(err, domain.ErrIntroMessageNotFound) {
+		t.Errorf("Expected ErrIntroMessageNotFound, got %v", err)
+	}
+}
+
+// TestIntroMessageRepository_MarkDeleted は削除済みメッセージが取得対象から外れることをテストします
+func TestIntroMessageRepository_MarkDeleted(t *testing.T) {
+	db := setupIntroMessageTestDB(t)
+	repo := NewIntroMessageRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	for i, id := range []string{"100", "200"} {
+		msg := &domain.IntroMessage{
+			ID:        id,
+			ChannelID: "channel-1",
+			UserID:    "user-1",
+			PostedAt:  now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := repo.Upsert(ctx, msg); err != nil {
+			t.Fatalf("Failed to create intro message: %v", err)
+		}
+	}
+
+	if err := repo.MarkDeleted(ctx, "100"); err != nil {
+		t.Fatalf("Failed to mark intro message as deleted: %v", err)
+	}
+
+	active, err := repo.GetActiveByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to get active intro messages: %v", err)
+	}
+	if len(active) != 1 || active[0].ID != "200" {
+		t.Errorf("Expected only message 200 to be active, got %v", active)
+	}
+
+	byChannel, err := repo.GetActiveByChannelID(ctx, "channel-1")
+	if err != nil {
+		t.Fatalf("Failed to get active intro messages by channel: %v", err)
+	}
+	if len(byChannel) != 1 {
+		t.Errorf("Expected 1 active message in channel, got %d", len(byChannel))
+	}
+
+	deleted, err := repo.GetByID(ctx, "100")
+	if err != nil {
+		t.Fatalf("Failed to get deleted intro message: %v", err)
+	}
+	if !deleted.IsDeleted() {
+		t.Error("Expected message 100 to be tombstoned")
+	}
+}
@@ -0,0 +1,225 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// memberSortColumns は並び順の基準に対応するカラムです
+// いずれもユーザーIDとの複合インデックス（idx_users_last_login, idx_users_joined, idx_users_username）があります
+var memberSortColumns = map[domain.MemberSort]string{
+	domain.MemberSortLastLogin: "users.last_login_at",
+	domain.MemberSortJoinedAt:  "users.joined_at",
+	domain.MemberSortUsername:  "users.username",
+}
+
+// memberQuery は在籍中のユーザーのうち絞り込み条件に一致するものを取得するクエリを作成します
+// プロフィールの条件は相関サブクエリ（profiles.user_idのインデックスを使用）で判定するため、結果のユーザーは重複しません
+func (r *userRepository) memberQuery(ctx context.Context, filter domain.MemberFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&User{}).Where("users.left_at IS NULL")
+
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		realName := r.activeProfiles().Where("profiles.real_name LIKE ? ESCAPE '!'", pattern)
+		realName = whereVisible(realName, "real_name_visibility", filter.Viewer)
+		query = query.Where(
+			"(users.username LIKE ? ESCAPE '!' OR users.display_name LIKE ? ESCAPE '!' OR users.guild_nickname LIKE ? ESCAPE '!' OR EXISTS (?))",
+			pattern, pattern, pattern, realName,
+		)
+	}
+	if len(filter.Usernames) > 0 {
+		query = query.Where("users.username IN ?", filter.Usernames)
+	}
+	if len(filter.RoleIDs) > 0 {
+		query = query.Where("users.id IN (?)", r.db.Model(&UserRole{}).Select("user_id").Where("role_id IN ?", filter.RoleIDs))
+	}
+	if filter.JoinedAfter != nil {
+		query = query.Where("users.joined_at >= ?", *filter.JoinedAfter)
+	}
+	if filter.JoinedBefore != nil {
+		query = query.Where("users.joined_at < ?", *filter.JoinedBefore)
+	}
+
+	// 学籍番号から求めた項目と趣味の条件は同じプロフィールに対して判定する
+	if filter.EnrollmentYear != 0 || filter.Faculty != "" || len(filter.HobbiesKeywords) > 0 {
+		profiles := r.activeProfiles()
+		if filter.EnrollmentYear != 0 || filter.Faculty != "" {
+			if filter.EnrollmentYear != 0 {
+				profiles = profiles.Where("profiles.enrollment_year = ?", filter.EnrollmentYear)
+			}
+			if filter.Faculty != "" {
+				profiles = profiles.Where("profiles.faculty = ?", filter.Faculty)
+			}
+			profiles = whereVisible(profiles, "student_id_visibility", filter.Viewer)
+		}
+		if len(filter.HobbiesKeywords) > 0 {
+			for _, keyword := range filter.HobbiesKeywords {
+				profiles = profiles.Where("profiles.hobbies LIKE ? ESCAPE '!'", "%"+escapeLike(keyword)+"%")
+			}
+			profiles = whereVisible(profiles, "hobbies_visibility", filter.Viewer)
+		}
+		query = query.Where("EXISTS (?)", profiles)
+	}
+
+	if filter.HasProfile != nil {
+		if *filter.HasProfile {
+			query = query.Where("EXISTS (?)", r.activeProfiles())
+		} else {
+			query = query.Where("NOT EXISTS (?)", r.activeProfiles())
+		}
+	}
+
+	return query
+}
+
+// activeProfiles はユーザーの墓標化されていないプロフィールを参照するサブクエリを作成します
+func (r *userRepository) activeProfiles() *gorm.DB {
+	return r.db.Table("profiles").Select("1").Where("profiles.user_id = users.id AND profiles.deleted_at IS NULL")
+}
+
+// whereVisible は閲覧者が公開範囲のカラムの項目を閲覧できるプロフィールに絞り込みます（閲覧者がnilの場合は絞り込みません）
+func whereVisible(query *gorm.DB, column string, viewer *domain.ProfileViewer) *gorm.DB {
+	if viewer == nil {
+		return query
+	}
+	return query.Where(
+		fmt.Sprintf("((profiles.user_id <> ? AND profiles.%[1]s IN ?) OR (profiles.user_id = ? AND profiles.%[1]s IN ?))", column),
+		viewer.UserID, visibilityValues(viewer.VisibleLevels("")),
+		viewer.UserID, visibilityValues(viewer.VisibleLevels(viewer.UserID)),
+	)
+}
+
+// escapeLike はLIKE句のパターンで特殊な意味を持つ文字をエスケープします（エスケープ文字は「!」）
+// バックスラッシュはMySQLの文字列リテラルでもエスケープ文字として扱われるため使用しません
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// keysetCondition はカーソルより後ろの要素に絞り込む条件を作成します
+// NULLはMySQL・SQLiteとも最小の値として並ぶため、昇順では先頭、降順では末尾になります
+func keysetCondition(column string, page domain.MemberPage) (string, []interface{}, error) {
+	cursor := page.Cursor
+	op := ">"
+	if page.Desc {
+		op = "<"
+	}
+
+	if cursor.Value == nil {
+		if page.Desc {
+			return fmt.Sprintf("(%s IS NULL AND users.id < ?)", column), []interface{}{cursor.ID}, nil
+		}
+		return fmt.Sprintf("((%s IS NULL AND users.id > ?) OR %s IS NOT NULL)", column, column), []interface{}{cursor.ID}, nil
+	}
+
+	var value interface{} = *cursor.Value
+	if page.Sort != domain.MemberSortUsername {
+		t, err := time.Parse(time.RFC3339Nano, *cursor.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidMemberPage)
+		}
+		value = t
+	}
+
+	condition := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND users.id %[2]s ?))", column, op)
+	if page.Desc {
+		condition = fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND users.id %[2]s ?) OR %[1]s IS NULL)", column, op)
+	}
+	return condition, []interface{}{value, value, cursor.ID}, nil
+}
+
+// memberCursor は取得した範囲の最後のユーザーの位置を表すカーソルを作成します
+func memberCursor(page domain.MemberPage, last *User) *domain.MemberCursor {
+	cursor := &domain.MemberCursor{Sort: page.Sort, Desc: page.Desc, ID: last.ID}
+	switch page.Sort {
+	case domain.MemberSortUsername:
+		cursor.Value = &last.Username
+	case domain.MemberSortJoinedAt:
+		cursor.Value = nullTimeValue(last.JoinedAt)
+	default:
+		cursor.Value = nullTimeValue(last.LastLoginAt)
+	}
+	return cursor
+}
+
+// nullTimeValue は日時をRFC3339形式の文字列で返します（NULLの場合はnil）
+func nullTimeValue(t sql.NullTime) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.Time.Format(time.RFC3339Nano)
+	return &s
+}
+
+// replaceUserRoles はユーザーのロールの対応表をroleIDsで置き換えます
+func replaceUserRoles(tx *gorm.DB, userID string, roleIDs []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&UserRole{}).Error; err != nil {
+		return fmt.Errorf("failed to delete user roles: %w", err)
+	}
+
+	var rows []UserRole
+	for _, roleID := range roleIDs {
+		if roleID == "" || slices.ContainsFunc(rows, func(r UserRole) bool { return r.RoleID == roleID }) {
+			continue
+		}
+		rows = append(rows, UserRole{UserID: userID, RoleID: roleID})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to create user roles: %w", err)
+	}
+	return nil
+}
+
+// backfillMemberSearch はメンバー検索に使用する対応表・カラムを既存のデータから作成します
+// ロールの対応表が空の場合（導入直後）のみロールを展開し、公開範囲のカラムは公開範囲の設定があるプロフィールについて導出し直します
+func backfillMemberSearch(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+
+	var roleCount int64
+	if err := db.Model(&UserRole{}).Count(&roleCount).Error; err != nil {
+		return fmt.Errorf("failed to count user roles: %w", err)
+	}
+	if roleCount == 0 {
+		var users []User
+		if err := db.Where("guild_roles IS NOT NULL AND guild_roles <> ''").Find(&users).Error; err != nil {
+			return fmt.Errorf("failed to get users: %w", err)
+		}
+		for _, u := range users {
+			if err := replaceUserRoles(db, u.ID, u.ToDomain().GuildRoles); err != nil {
+				return err
+			}
+		}
+	}
+
+	var profiles []Profile
+	if err := db.Where("visibility IS NOT NULL AND visibility <> ''").Find(&profiles).Error; err != nil {
+		return fmt.Errorf("failed to get profiles: %w", err)
+	}
+	for _, p := range profiles {
+		derived := FromDomainProfile(p.ToDomain())
+		if derived.StudentIDVisibility == p.StudentIDVisibility &&
+			derived.RealNameVisibility == p.RealNameVisibility &&
+			derived.HobbiesVisibility == p.HobbiesVisibility {
+			continue
+		}
+		if err := db.Model(&Profile{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+			"student_id_visibility": derived.StudentIDVisibility,
+			"real_name_visibility":  derived.RealNameVisibility,
+			"hobbies_visibility":    derived.HobbiesVisibility,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update profile visibility columns: %w", err)
+		}
+	}
+
+	return nil
+}
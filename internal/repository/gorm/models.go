@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
@@ -11,17 +12,18 @@ import (
 
 // User GORM model
 type User struct {
-	ID            string         `gorm:"primaryKey;type:varchar(36)"`
+	ID            string         `gorm:"primaryKey;type:varchar(36);index:idx_users_last_login,priority:2;index:idx_users_joined,priority:2;index:idx_users_username,priority:2"`
 	DiscordID     string         `gorm:"uniqueIndex;type:varchar(255);not null"`
-	Username      string         `gorm:"type:varchar(255);not null"`
+	Username      string         `gorm:"index:idx_users_username,priority:1;type:varchar(255);not null"`
 	DisplayName   string         `gorm:"type:varchar(255)"`
 	AvatarURL     string         `gorm:"type:varchar(512)"`
 	GuildNickname sql.NullString `gorm:"type:varchar(255)"`
 	GuildRoles    string         `gorm:"type:text"` // JSON配列として保存
-	JoinedAt      sql.NullTime   `gorm:"index;type:datetime"`
+	JoinedAt      sql.NullTime   `gorm:"index;index:idx_users_joined,priority:1;type:datetime"`
+	LeftAt        sql.NullTime   `gorm:"index;type:datetime"`
 	CreatedAt     time.Time      `gorm:"autoCreateTime"`
 	UpdatedAt     time.Time      `gorm:"autoUpdateTime"`
-	LastLoginAt   sql.NullTime   `gorm:"type:datetime"`
+	LastLoginAt   sql.NullTime   `gorm:"index:idx_users_last_login,priority:1;type:datetime"`
 }
 
 func (User) TableName() string {
@@ -49,6 +51,11 @@ func (u *User) ToDomain() *domain.User {
 		joinedAt = &u.JoinedAt.Time
 	}
 
+	var leftAt *time.Time
+	if u.LeftAt.Valid {
+		leftAt = &u.LeftAt.Time
+	}
+
 	return &domain.User{
 		ID:            u.ID,
 		DiscordID:     u.DiscordID,
@@ -58,6 +65,7 @@ func (u *User) ToDomain() *domain.User {
 		GuildNickname: guildNickname,
 		GuildRoles:    guildRoles,
 		JoinedAt:      joinedAt,
+		LeftAt:        leftAt,
 		CreatedAt:     u.CreatedAt,
 		UpdatedAt:     u.UpdatedAt,
 		LastLoginAt:   lastLoginAt,
@@ -86,6 +94,11 @@ func FromDomainUser(u *domain.User) *User {
 		joinedAt = sql.NullTime{Time: *u.JoinedAt, Valid: true}
 	}
 
+	var leftAt sql.NullTime
+	if u.LeftAt != nil {
+		leftAt = sql.NullTime{Time: *u.LeftAt, Valid: true}
+	}
+
 	return &User{
 		ID:            u.ID,
 		DiscordID:     u.DiscordID,
@@ -95,12 +108,24 @@ func FromDomainUser(u *domain.User) *User {
 		GuildNickname: guildNickname,
 		GuildRoles:    guildRolesJSON,
 		JoinedAt:      joinedAt,
+		LeftAt:        leftAt,
 		CreatedAt:     u.CreatedAt,
 		UpdatedAt:     u.UpdatedAt,
 		LastLoginAt:   lastLoginAt,
 	}
 }
 
+// UserRole はユーザーが持つロールの対応表です
+// User.GuildRolesはJSON文字列として保存しているため、ロールでの絞り込みにはこの表のインデックスを使用します
+type UserRole struct {
+	UserID string `gorm:"primaryKey;type:varchar(36)"`
+	RoleID string `gorm:"primaryKey;index:idx_user_roles_role;type:varchar(255)"`
+}
+
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
 // Session GORM model
 type Session struct {
 	ID        string    `gorm:"primaryKey;type:varchar(36)"`
@@ -136,13 +161,23 @@ func FromDomainSession(s *domain.Session) *Session {
 
 // ClientApp GORM model
 type ClientApp struct {
-	ID           string    `gorm:"primaryKey;type:varchar(36)"`
-	ClientID     string    `gorm:"uniqueIndex;type:varchar(255);not null"`
-	ClientSecret string    `gorm:"type:varchar(255);not null"`
-	Name         string    `gorm:"type:varchar(255);not null"`
-	RedirectURIs string    `gorm:"type:text;not null"` // JSON string
-	CreatedAt    time.Time `gorm:"autoCreateTime"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+	ID                      string    `gorm:"primaryKey;type:varchar(36)"`
+	ClientID                string    `gorm:"uniqueIndex;type:varchar(255);not null"`
+	ClientSecret            string    `gorm:"type:varchar(255);not null"` // 公開クライアントは空
+	Name                    string    `gorm:"type:varchar(255);not null"`
+	RedirectURIs            string    `gorm:"type:text;not null"` // JSON string
+	ProfileAccess           string    `gorm:"type:varchar(16)"`   // 空の場合はmembers
+	OwnerID                 string    `gorm:"index;type:varchar(36)"`
+	GrantTypes              string    `gorm:"type:varchar(255)"` // スペース区切り。空の場合はすべて
+	Scopes                  string    `gorm:"type:varchar(255)"` // スペース区切り。空の場合はすべて
+	LogoURI                 string    `gorm:"type:text"`
+	TokenEndpointAuthMethod string    `gorm:"type:varchar(32)"`  // 空の場合はclient_secret_post、noneは公開クライアント
+	JWKS                    string    `gorm:"type:text"`         // private_key_jwtの公開鍵（JWKSのJSON）
+	JWKSURI                 string    `gorm:"type:text"`         // private_key_jwtの公開鍵の取得先
+	AccessTokenFormat       string    `gorm:"type:varchar(16)"`  // 空の場合はopaque
+	RegistrationAccessToken string    `gorm:"type:varchar(255)"` // bcrypt。動的登録したクライアントのみ
+	CreatedAt               time.Time `gorm:"autoCreateTime"`
+	UpdatedAt               time.Time `gorm:"autoUpdateTime"`
 }
 
 func (ClientApp) TableName() string {
@@ -155,13 +190,23 @@ func (c *ClientApp) ToDomain() (*domain.ClientApp, error) {
 		return nil, fmt.Errorf("failed to unmarshal redirect_uris: %w", err)
 	}
 	return &domain.ClientApp{
-		ID:           c.ID,
-		ClientID:     c.ClientID,
-		ClientSecret: c.ClientSecret,
-		Name:         c.Name,
-		RedirectURIs: redirectURIs,
-		CreatedAt:    c.CreatedAt,
-		UpdatedAt:    c.UpdatedAt,
+		ID:                      c.ID,
+		OwnerID:                 c.OwnerID,
+		ClientID:                c.ClientID,
+		ClientSecret:            c.ClientSecret,
+		Name:                    c.Name,
+		RedirectURIs:            redirectURIs,
+		ProfileAccess:           domain.FieldVisibility(c.ProfileAccess),
+		GrantTypes:              strings.Fields(c.GrantTypes),
+		Scopes:                  strings.Fields(c.Scopes),
+		LogoURI:                 c.LogoURI,
+		TokenEndpointAuthMethod: c.TokenEndpointAuthMethod,
+		JWKS:                    c.JWKS,
+		JWKSURI:                 c.JWKSURI,
+		AccessTokenFormat:       c.AccessTokenFormat,
+		RegistrationAccessToken: c.RegistrationAccessToken,
+		CreatedAt:               c.CreatedAt,
+		UpdatedAt:               c.UpdatedAt,
 	}, nil
 }
 
@@ -171,16 +216,132 @@ func FromDomainClientApp(c *domain.ClientApp) (*ClientApp, error) {
 		return nil, fmt.Errorf("failed to marshal redirect_uris: %w", err)
 	}
 	return &ClientApp{
-		ID:           c.ID,
-		ClientID:     c.ClientID,
-		ClientSecret: c.ClientSecret,
-		Name:         c.Name,
-		RedirectURIs: string(redirectURIsJSON),
-		CreatedAt:    c.CreatedAt,
-		UpdatedAt:    c.UpdatedAt,
+		ID:                      c.ID,
+		ClientID:                c.ClientID,
+		ClientSecret:            c.ClientSecret,
+		Name:                    c.Name,
+		RedirectURIs:            string(redirectURIsJSON),
+		ProfileAccess:           string(c.ProfileAccess),
+		OwnerID:                 c.OwnerID,
+		GrantTypes:              strings.Join(c.GrantTypes, " "),
+		Scopes:                  strings.Join(c.Scopes, " "),
+		LogoURI:                 c.LogoURI,
+		TokenEndpointAuthMethod: c.TokenEndpointAuthMethod,
+		JWKS:                    c.JWKS,
+		JWKSURI:                 c.JWKSURI,
+		AccessTokenFormat:       c.AccessTokenFormat,
+		RegistrationAccessToken: c.RegistrationAccessToken,
+		CreatedAt:               c.CreatedAt,
+		UpdatedAt:               c.UpdatedAt,
 	}, nil
 }
 
+// ClientSecret GORM model
+type ClientSecret struct {
+	ID           string       `gorm:"primaryKey;type:varchar(36)"`
+	ClientID     string       `gorm:"index;type:varchar(255);not null"`
+	HashedSecret string       `gorm:"type:varchar(255);not null"`
+	CreatedAt    time.Time    `gorm:"not null"`
+	ExpiresAt    sql.NullTime `gorm:"type:datetime"`
+	LastUsedAt   sql.NullTime `gorm:"type:datetime"`
+}
+
+func (ClientSecret) TableName() string {
+	return "client_secrets"
+}
+
+func (s *ClientSecret) ToDomain() *domain.ClientSecret {
+	var expiresAt, lastUsedAt *time.Time
+	if s.ExpiresAt.Valid {
+		expiresAt = &s.ExpiresAt.Time
+	}
+	if s.LastUsedAt.Valid {
+		lastUsedAt = &s.LastUsedAt.Time
+	}
+	return &domain.ClientSecret{
+		ID:           s.ID,
+		ClientID:     s.ClientID,
+		HashedSecret: s.HashedSecret,
+		CreatedAt:    s.CreatedAt,
+		ExpiresAt:    expiresAt,
+		LastUsedAt:   lastUsedAt,
+	}
+}
+
+func FromDomainClientSecret(s *domain.ClientSecret) *ClientSecret {
+	var expiresAt, lastUsedAt sql.NullTime
+	if s.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *s.ExpiresAt, Valid: true}
+	}
+	if s.LastUsedAt != nil {
+		lastUsedAt = sql.NullTime{Time: *s.LastUsedAt, Valid: true}
+	}
+	return &ClientSecret{
+		ID:           s.ID,
+		ClientID:     s.ClientID,
+		HashedSecret: s.HashedSecret,
+		CreatedAt:    s.CreatedAt,
+		ExpiresAt:    expiresAt,
+		LastUsedAt:   lastUsedAt,
+	}
+}
+
+// ClientAssertionJTI GORM model
+// private_key_jwtのclient_assertionのjtiを有効期限まで記録し、再利用を防ぎます
+type ClientAssertionJTI struct {
+	ClientID  string    `gorm:"primaryKey;type:varchar(255)"`
+	JTI       string    `gorm:"primaryKey;type:varchar(255)"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+func (ClientAssertionJTI) TableName() string {
+	return "client_assertion_jtis"
+}
+
+// InitialAccessToken GORM model
+type InitialAccessToken struct {
+	ID        string       `gorm:"primaryKey;type:varchar(36)"`
+	TokenHash string       `gorm:"uniqueIndex;type:varchar(64);not null"`
+	UserID    string       `gorm:"index;type:varchar(36);not null"`
+	ExpiresAt time.Time    `gorm:"index;not null"`
+	CreatedAt time.Time    `gorm:"autoCreateTime"`
+	UsedAt    sql.NullTime `gorm:"type:datetime"`
+}
+
+func (InitialAccessToken) TableName() string {
+	return "initial_access_tokens"
+}
+
+func (t *InitialAccessToken) ToDomain() *domain.InitialAccessToken {
+	var usedAt *time.Time
+	if t.UsedAt.Valid {
+		usedAt = &t.UsedAt.Time
+	}
+	return &domain.InitialAccessToken{
+		ID:        t.ID,
+		TokenHash: t.TokenHash,
+		UserID:    t.UserID,
+		ExpiresAt: t.ExpiresAt,
+		CreatedAt: t.CreatedAt,
+		UsedAt:    usedAt,
+	}
+}
+
+func FromDomainInitialAccessToken(t *domain.InitialAccessToken) *InitialAccessToken {
+	var usedAt sql.NullTime
+	if t.UsedAt != nil {
+		usedAt = sql.NullTime{Time: *t.UsedAt, Valid: true}
+	}
+	return &InitialAccessToken{
+		ID:        t.ID,
+		TokenHash: t.TokenHash,
+		UserID:    t.UserID,
+		ExpiresAt: t.ExpiresAt,
+		CreatedAt: t.CreatedAt,
+		UsedAt:    usedAt,
+	}
+}
+
 // AuthCode GORM model
 type AuthCode struct {
 	ID          string    `gorm:"primaryKey;type:varchar(36)"`
@@ -223,6 +384,64 @@ func FromDomainAuthCode(a *domain.AuthCode) *AuthCode {
 	}
 }
 
+// DeviceCode GORM model
+type DeviceCode struct {
+	ID              string       `gorm:"primaryKey;type:varchar(36)"`
+	DeviceCode      string       `gorm:"uniqueIndex;type:varchar(255);not null"`
+	UserCode        string       `gorm:"index;type:varchar(16);not null"`
+	ClientID        string       `gorm:"index;type:varchar(36);not null"`
+	UserID          string       `gorm:"type:varchar(36);not null;default:''"`
+	Status          string       `gorm:"type:varchar(16);not null"`
+	IntervalSeconds int          `gorm:"not null"`
+	LastPolledAt    sql.NullTime `gorm:"type:datetime"`
+	ExpiresAt       time.Time    `gorm:"index;not null"`
+	CreatedAt       time.Time    `gorm:"autoCreateTime"`
+}
+
+func (DeviceCode) TableName() string {
+	return "device_codes"
+}
+
+func (d *DeviceCode) ToDomain() *domain.DeviceCode {
+	var lastPolledAt *time.Time
+	if d.LastPolledAt.Valid {
+		lastPolledAt = &d.LastPolledAt.Time
+	}
+
+	return &domain.DeviceCode{
+		ID:           d.ID,
+		DeviceCode:   d.DeviceCode,
+		UserCode:     d.UserCode,
+		ClientID:     d.ClientID,
+		UserID:       d.UserID,
+		Status:       domain.DeviceCodeStatus(d.Status),
+		Interval:     time.Duration(d.IntervalSeconds) * time.Second,
+		LastPolledAt: lastPolledAt,
+		ExpiresAt:    d.ExpiresAt,
+		CreatedAt:    d.CreatedAt,
+	}
+}
+
+func FromDomainDeviceCode(d *domain.DeviceCode) *DeviceCode {
+	var lastPolledAt sql.NullTime
+	if d.LastPolledAt != nil {
+		lastPolledAt = sql.NullTime{Time: *d.LastPolledAt, Valid: true}
+	}
+
+	return &DeviceCode{
+		ID:              d.ID,
+		DeviceCode:      d.DeviceCode,
+		UserCode:        d.UserCode,
+		ClientID:        d.ClientID,
+		UserID:          d.UserID,
+		Status:          string(d.Status),
+		IntervalSeconds: int(d.Interval / time.Second),
+		LastPolledAt:    lastPolledAt,
+		ExpiresAt:       d.ExpiresAt,
+		CreatedAt:       d.CreatedAt,
+	}
+}
+
 // Token GORM model
 type Token struct {
 	ID        string    `gorm:"primaryKey;type:varchar(36)"`
@@ -267,16 +486,28 @@ func FromDomainToken(t *domain.Token) *Token {
 
 // Profile GORM model
 type Profile struct {
-	ID               string    `gorm:"primaryKey;type:varchar(36)"`
-	UserID           string    `gorm:"index;type:varchar(36);not null"`
-	DiscordMessageID string    `gorm:"uniqueIndex;type:varchar(255);not null"`
-	RealName         string    `gorm:"type:varchar(255)"`
-	StudentID        string    `gorm:"type:varchar(255)"`
-	Hobbies          string    `gorm:"type:text"`
-	WhatToDo         string    `gorm:"type:text"`
-	Comment          string    `gorm:"type:text"`
-	CreatedAt        time.Time `gorm:"autoCreateTime"`
-	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+	ID               string `gorm:"primaryKey;type:varchar(36)"`
+	UserID           string `gorm:"index;type:varchar(36);not null"`
+	DiscordMessageID string `gorm:"uniqueIndex;type:varchar(255);not null"`
+	RealName         string `gorm:"type:varchar(255)"`
+	StudentID        string `gorm:"type:varchar(255)"`
+	EnrollmentYear   int    `gorm:"index:idx_profiles_academic"`
+	Faculty          string `gorm:"index:idx_profiles_academic;type:varchar(32)"`
+	Hobbies          string `gorm:"type:text"`
+	WhatToDo         string `gorm:"type:text"`
+	Comment          string `gorm:"type:text"`
+	Extra            string `gorm:"type:text"` // JSONオブジェクトとして保存
+	Visibility       string `gorm:"type:text"` // 項目ごとの公開範囲（JSONオブジェクト）
+	Sources          string `gorm:"type:text"` // 手動編集された項目（JSONオブジェクト）
+	// StudentIDVisibility は学籍番号の公開範囲です（Visibilityから導出し、入学年度・学部コードでの絞り込みに使用）
+	StudentIDVisibility string `gorm:"type:varchar(16)"`
+	// RealNameVisibility・HobbiesVisibility は氏名・趣味の公開範囲です（Visibilityから導出し、メンバー検索に使用）
+	RealNameVisibility string       `gorm:"type:varchar(16)"`
+	HobbiesVisibility  string       `gorm:"type:varchar(16)"`
+	EditedAt           sql.NullTime `gorm:"type:datetime"`
+	DeletedAt          sql.NullTime `gorm:"index;type:datetime"`
+	CreatedAt          time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt          time.Time    `gorm:"autoUpdateTime"`
 }
 
 func (Profile) TableName() string {
@@ -284,31 +515,647 @@ func (Profile) TableName() string {
 }
 
 func (p *Profile) ToDomain() *domain.Profile {
+	var editedAt *time.Time
+	if p.EditedAt.Valid {
+		editedAt = &p.EditedAt.Time
+	}
+
+	var deletedAt *time.Time
+	if p.DeletedAt.Valid {
+		deletedAt = &p.DeletedAt.Time
+	}
+
 	return &domain.Profile{
 		ID:               p.ID,
 		UserID:           p.UserID,
 		DiscordMessageID: p.DiscordMessageID,
 		RealName:         p.RealName,
 		StudentID:        p.StudentID,
+		EnrollmentYear:   p.EnrollmentYear,
+		Faculty:          p.Faculty,
 		Hobbies:          p.Hobbies,
 		WhatToDo:         p.WhatToDo,
 		Comment:          p.Comment,
+		Extra:            decodeExtra(p.Extra),
+		Visibility:       decodeVisibility(p.Visibility),
+		Sources:          decodeSources(p.Sources),
+		EditedAt:         editedAt,
+		DeletedAt:        deletedAt,
 		CreatedAt:        p.CreatedAt,
 		UpdatedAt:        p.UpdatedAt,
 	}
 }
 
 func FromDomainProfile(p *domain.Profile) *Profile {
+	var editedAt sql.NullTime
+	if p.EditedAt != nil {
+		editedAt = sql.NullTime{Time: *p.EditedAt, Valid: true}
+	}
+
+	var deletedAt sql.NullTime
+	if p.DeletedAt != nil {
+		deletedAt = sql.NullTime{Time: *p.DeletedAt, Valid: true}
+	}
+
 	return &Profile{
-		ID:               p.ID,
-		UserID:           p.UserID,
-		DiscordMessageID: p.DiscordMessageID,
-		RealName:         p.RealName,
-		StudentID:        p.StudentID,
-		Hobbies:          p.Hobbies,
-		WhatToDo:         p.WhatToDo,
-		Comment:          p.Comment,
-		CreatedAt:        p.CreatedAt,
-		UpdatedAt:        p.UpdatedAt,
+		ID:                  p.ID,
+		UserID:              p.UserID,
+		DiscordMessageID:    p.DiscordMessageID,
+		RealName:            p.RealName,
+		StudentID:           p.StudentID,
+		EnrollmentYear:      p.EnrollmentYear,
+		Faculty:             p.Faculty,
+		Hobbies:             p.Hobbies,
+		WhatToDo:            p.WhatToDo,
+		Comment:             p.Comment,
+		Extra:               encodeExtra(p.Extra),
+		Visibility:          encodeVisibility(p.Visibility),
+		Sources:             encodeSources(p.Sources),
+		StudentIDVisibility: string(p.VisibilityOf(domain.ProfileFieldStudentID)),
+		RealNameVisibility:  string(p.VisibilityOf(domain.ProfileFieldRealName)),
+		HobbiesVisibility:   string(p.VisibilityOf(domain.ProfileFieldHobbies)),
+		EditedAt:            editedAt,
+		DeletedAt:           deletedAt,
+		CreatedAt:           p.CreatedAt,
+		UpdatedAt:           p.UpdatedAt,
+	}
+}
+
+// encodeExtra は追加項目をJSON文字列に変換します（空の場合は空文字列）
+func encodeExtra(extra map[string]string) string {
+	if len(extra) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(extra)
+	return string(b)
+}
+
+// decodeExtra はJSON文字列から追加項目を復元します
+func decodeExtra(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	var extra map[string]string
+	_ = json.Unmarshal([]byte(s), &extra)
+	return extra
+}
+
+// encodeVisibility は項目ごとの公開範囲をJSON文字列に変換します（空の場合は空文字列）
+func encodeVisibility(visibility map[string]domain.FieldVisibility) string {
+	if len(visibility) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(visibility)
+	return string(b)
+}
+
+// decodeVisibility はJSON文字列から項目ごとの公開範囲を復元します
+func decodeVisibility(s string) map[string]domain.FieldVisibility {
+	if s == "" {
+		return nil
+	}
+	var visibility map[string]domain.FieldVisibility
+	_ = json.Unmarshal([]byte(s), &visibility)
+	return visibility
+}
+
+// encodeSources は手動編集された項目の情報をJSON文字列に変換します（空の場合は空文字列）
+func encodeSources(sources map[string]domain.FieldOrigin) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(sources)
+	return string(b)
+}
+
+// decodeSources はJSON文字列から手動編集された項目の情報を復元します
+func decodeSources(s string) map[string]domain.FieldOrigin {
+	if s == "" {
+		return nil
+	}
+	var sources map[string]domain.FieldOrigin
+	_ = json.Unmarshal([]byte(s), &sources)
+	return sources
+}
+
+// IntroMessage GORM model
+type IntroMessage struct {
+	ID        string       `gorm:"primaryKey;type:varchar(36)"` // DiscordメッセージID
+	ChannelID string       `gorm:"index;type:varchar(36);not null"`
+	UserID    string       `gorm:"index;type:varchar(36);not null"`
+	RealName  string       `gorm:"type:varchar(255)"`
+	StudentID string       `gorm:"type:varchar(255)"`
+	Hobbies   string       `gorm:"type:text"`
+	WhatToDo  string       `gorm:"type:text"`
+	Comment   string       `gorm:"type:text"`
+	Extra     string       `gorm:"type:text"` // JSONオブジェクトとして保存
+	PostedAt  time.Time    `gorm:"index;type:datetime"`
+	EditedAt  sql.NullTime `gorm:"type:datetime"`
+	DeletedAt sql.NullTime `gorm:"index;type:datetime"`
+	CreatedAt time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt time.Time    `gorm:"autoUpdateTime"`
+}
+
+func (IntroMessage) TableName() string {
+	return "intro_messages"
+}
+
+func (m *IntroMessage) ToDomain() *domain.IntroMessage {
+	var editedAt *time.Time
+	if m.EditedAt.Valid {
+		editedAt = &m.EditedAt.Time
+	}
+
+	var deletedAt *time.Time
+	if m.DeletedAt.Valid {
+		deletedAt = &m.DeletedAt.Time
+	}
+
+	return &domain.IntroMessage{
+		ID:        m.ID,
+		ChannelID: m.ChannelID,
+		UserID:    m.UserID,
+		RealName:  m.RealName,
+		StudentID: m.StudentID,
+		Hobbies:   m.Hobbies,
+		WhatToDo:  m.WhatToDo,
+		Comment:   m.Comment,
+		Extra:     decodeExtra(m.Extra),
+		PostedAt:  m.PostedAt,
+		EditedAt:  editedAt,
+		DeletedAt: deletedAt,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func FromDomainIntroMessage(m *domain.IntroMessage) *IntroMessage {
+	var editedAt sql.NullTime
+	if m.EditedAt != nil {
+		editedAt = sql.NullTime{Time: *m.EditedAt, Valid: true}
+	}
+
+	var deletedAt sql.NullTime
+	if m.DeletedAt != nil {
+		deletedAt = sql.NullTime{Time: *m.DeletedAt, Valid: true}
+	}
+
+	return &IntroMessage{
+		ID:        m.ID,
+		ChannelID: m.ChannelID,
+		UserID:    m.UserID,
+		RealName:  m.RealName,
+		StudentID: m.StudentID,
+		Hobbies:   m.Hobbies,
+		WhatToDo:  m.WhatToDo,
+		Comment:   m.Comment,
+		Extra:     encodeExtra(m.Extra),
+		PostedAt:  m.PostedAt,
+		EditedAt:  editedAt,
+		DeletedAt: deletedAt,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+// Role GORM model
+type Role struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)"` // DiscordロールID
+	Name      string    `gorm:"type:varchar(255);not null"`
+	Color     int       `gorm:"not null;default:0"`
+	Position  int       `gorm:"index;not null;default:0"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+func (r *Role) ToDomain() *domain.Role {
+	return &domain.Role{
+		ID:        r.ID,
+		Name:      r.Name,
+		Color:     r.Color,
+		Position:  r.Position,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+func FromDomainRole(r *domain.Role) *Role {
+	return &Role{
+		ID:        r.ID,
+		Name:      r.Name,
+		Color:     r.Color,
+		Position:  r.Position,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// SyncCursor GORM model
+type SyncCursor struct {
+	ChannelID      string       `gorm:"primaryKey;type:varchar(36)"`
+	LastMessageID  string       `gorm:"type:varchar(36)"`
+	LastFullSyncAt sql.NullTime `gorm:"type:datetime"`
+	UpdatedAt      time.Time    `gorm:"autoUpdateTime"`
+}
+
+func (SyncCursor) TableName() string {
+	return "sync_cursors"
+}
+
+func (c *SyncCursor) ToDomain() *domain.SyncCursor {
+	var lastFullSyncAt *time.Time
+	if c.LastFullSyncAt.Valid {
+		lastFullSyncAt = &c.LastFullSyncAt.Time
+	}
+
+	return &domain.SyncCursor{
+		ChannelID:      c.ChannelID,
+		LastMessageID:  c.LastMessageID,
+		LastFullSyncAt: lastFullSyncAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+}
+
+func FromDomainSyncCursor(c *domain.SyncCursor) *SyncCursor {
+	var lastFullSyncAt sql.NullTime
+	if c.LastFullSyncAt != nil {
+		lastFullSyncAt = sql.NullTime{Time: *c.LastFullSyncAt, Valid: true}
+	}
+
+	return &SyncCursor{
+		ChannelID:      c.ChannelID,
+		LastMessageID:  c.LastMessageID,
+		LastFullSyncAt: lastFullSyncAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+}
+
+// ChangeRecord GORM model
+type ChangeRecord struct {
+	ID          string    `gorm:"primaryKey;type:varchar(36)"`
+	UserID      string    `gorm:"uniqueIndex:idx_change_history_version;index:idx_change_history_user_created;type:varchar(36);not null"`
+	Subject     string    `gorm:"uniqueIndex:idx_change_history_version;type:varchar(16);not null"`
+	Version     int       `gorm:"uniqueIndex:idx_change_history_version;not null"`
+	ActorType   string    `gorm:"type:varchar(16);not null"`
+	ActorUserID string    `gorm:"type:varchar(36)"`
+	Changes     string    `gorm:"type:text"` // 変更された項目の一覧（JSON配列）
+	CreatedAt   time.Time `gorm:"index:idx_change_history_user_created;index"`
+}
+
+func (ChangeRecord) TableName() string {
+	return "change_history"
+}
+
+func (c *ChangeRecord) ToDomain() *domain.ChangeRecord {
+	var changes []domain.FieldChange
+	_ = json.Unmarshal([]byte(c.Changes), &changes)
+
+	return &domain.ChangeRecord{
+		ID:          c.ID,
+		UserID:      c.UserID,
+		Subject:     domain.ChangeSubject(c.Subject),
+		Version:     c.Version,
+		ActorType:   domain.ChangeActorType(c.ActorType),
+		ActorUserID: c.ActorUserID,
+		Changes:     changes,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+func FromDomainChangeRecord(c *domain.ChangeRecord) *ChangeRecord {
+	changes, _ := json.Marshal(c.Changes)
+
+	return &ChangeRecord{
+		ID:          c.ID,
+		UserID:      c.UserID,
+		Subject:     string(c.Subject),
+		Version:     c.Version,
+		ActorType:   string(c.ActorType),
+		ActorUserID: c.ActorUserID,
+		Changes:     string(changes),
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+// ProfileSearchTerm GORM model
+// 主キーの先頭を語にしているため、語での検索は主キーのインデックスを使用します
+type ProfileSearchTerm struct {
+	Term   string `gorm:"primaryKey;type:varchar(32)"`
+	UserID string `gorm:"primaryKey;index;type:varchar(36)"`
+	Field  string `gorm:"primaryKey;type:varchar(64)"`
+	Count  int    `gorm:"not null"`
+}
+
+func (ProfileSearchTerm) TableName() string {
+	return "profile_search_terms"
+}
+
+func (t *ProfileSearchTerm) ToDomain() *domain.SearchPosting {
+	return &domain.SearchPosting{
+		UserID: t.UserID,
+		Field:  t.Field,
+		Term:   t.Term,
+		Count:  t.Count,
+	}
+}
+
+func FromDomainSearchPosting(p *domain.SearchPosting) *ProfileSearchTerm {
+	return &ProfileSearchTerm{
+		Term:   p.Term,
+		UserID: p.UserID,
+		Field:  p.Field,
+		Count:  p.Count,
+	}
+}
+
+// AuditLog GORM model
+type AuditLog struct {
+	ID          string    `gorm:"primaryKey;type:varchar(36)"`
+	Action      string    `gorm:"index:idx_audit_logs_action_created;type:varchar(64);not null"`
+	ActorUserID string    `gorm:"index;type:varchar(36)"`
+	Via         string    `gorm:"type:varchar(16);not null"`
+	RemoteAddr  string    `gorm:"type:varchar(64)"`
+	Details     string    `gorm:"type:text"` // 操作の内容（JSONオブジェクト）
+	CreatedAt   time.Time `gorm:"index:idx_audit_logs_action_created;index"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+func (a *AuditLog) ToDomain() *domain.AuditEntry {
+	var details map[string]string
+	_ = json.Unmarshal([]byte(a.Details), &details)
+
+	return &domain.AuditEntry{
+		ID:          a.ID,
+		Action:      a.Action,
+		ActorUserID: a.ActorUserID,
+		Via:         a.Via,
+		RemoteAddr:  a.RemoteAddr,
+		Details:     details,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func FromDomainAuditEntry(e *domain.AuditEntry) *AuditLog {
+	details, _ := json.Marshal(e.Details)
+
+	return &AuditLog{
+		ID:          e.ID,
+		Action:      e.Action,
+		ActorUserID: e.ActorUserID,
+		Via:         e.Via,
+		RemoteAddr:  e.RemoteAddr,
+		Details:     string(details),
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+// WebhookEndpoint GORM model
+type WebhookEndpoint struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)"`
+	ClientID  string    `gorm:"index;type:varchar(255);not null"`
+	URL       string    `gorm:"type:varchar(2048);not null"`
+	Secret    string    `gorm:"type:varchar(255);not null"`
+	Events    string    `gorm:"type:text"` // JSON配列として保存
+	Active    bool      `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+func (e *WebhookEndpoint) ToDomain() *domain.WebhookEndpoint {
+	var events []domain.EventType
+	_ = json.Unmarshal([]byte(e.Events), &events)
+
+	return &domain.WebhookEndpoint{
+		ID:        e.ID,
+		ClientID:  e.ClientID,
+		URL:       e.URL,
+		Secret:    e.Secret,
+		Events:    events,
+		Active:    e.Active,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func FromDomainWebhookEndpoint(e *domain.WebhookEndpoint) *WebhookEndpoint {
+	events, _ := json.Marshal(e.Events)
+
+	return &WebhookEndpoint{
+		ID:        e.ID,
+		ClientID:  e.ClientID,
+		URL:       e.URL,
+		Secret:    e.Secret,
+		Events:    string(events),
+		Active:    e.Active,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+// WebhookDelivery GORM model
+type WebhookDelivery struct {
+	ID             string       `gorm:"primaryKey;type:varchar(36)"`
+	EndpointID     string       `gorm:"index:idx_webhook_deliveries_endpoint_created,priority:1;type:varchar(36);not null"`
+	EventID        string       `gorm:"index;type:varchar(36);not null"`
+	EventType      string       `gorm:"type:varchar(64);not null"`
+	Payload        string       `gorm:"type:text"`
+	Status         string       `gorm:"index:idx_webhook_deliveries_due,priority:1;type:varchar(16);not null"`
+	Attempts       int          `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time    `gorm:"index:idx_webhook_deliveries_due,priority:2;not null"`
+	ClaimID        string       `gorm:"index;type:varchar(36)"` // 配信処理中のワーカーが設定する識別子
+	LastStatusCode int          `gorm:"not null;default:0"`
+	LastError      string       `gorm:"type:text"`
+	ReplayOf       string       `gorm:"type:varchar(36)"`
+	CreatedAt      time.Time    `gorm:"index:idx_webhook_deliveries_endpoint_created,priority:2;not null"`
+	CompletedAt    sql.NullTime `gorm:"index"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *WebhookDelivery) ToDomain() *domain.WebhookDelivery {
+	var completedAt *time.Time
+	if d.CompletedAt.Valid {
+		completedAt = &d.CompletedAt.Time
+	}
+
+	return &domain.WebhookDelivery{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      domain.EventType(d.EventType),
+		Payload:        d.Payload,
+		Status:         domain.WebhookDeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		ReplayOf:       d.ReplayOf,
+		CreatedAt:      d.CreatedAt,
+		CompletedAt:    completedAt,
+	}
+}
+
+func FromDomainWebhookDelivery(d *domain.WebhookDelivery) *WebhookDelivery {
+	var completedAt sql.NullTime
+	if d.CompletedAt != nil {
+		completedAt = sql.NullTime{Time: *d.CompletedAt, Valid: true}
+	}
+
+	return &WebhookDelivery{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      string(d.EventType),
+		Payload:        d.Payload,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		ReplayOf:       d.ReplayOf,
+		CreatedAt:      d.CreatedAt,
+		CompletedAt:    completedAt,
+	}
+}
+
+// SAMLServiceProvider GORM model
+type SAMLServiceProvider struct {
+	ID            string    `gorm:"primaryKey;type:varchar(36)"`
+	OwnerID       string    `gorm:"type:varchar(36)"`
+	EntityID      string    `gorm:"uniqueIndex;type:varchar(512);not null"`
+	Name          string    `gorm:"type:varchar(255);not null"`
+	ACSURLs       string    `gorm:"column:acs_urls;type:text;not null"` // JSON配列として保存
+	Attributes    string    `gorm:"type:text"`                          // JSONオブジェクトとして保存（空の場合は既定の属性）
+	ProfileAccess string    `gorm:"type:varchar(16)"`                   // 空の場合はmembers
+	CreatedAt     time.Time `gorm:"not null"`
+	UpdatedAt     time.Time `gorm:"not null"`
+}
+
+func (SAMLServiceProvider) TableName() string {
+	return "saml_service_providers"
+}
+
+func (sp *SAMLServiceProvider) ToDomain() (*domain.SAMLServiceProvider, error) {
+	var acsURLs []string
+	if err := json.Unmarshal([]byte(sp.ACSURLs), &acsURLs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal acs_urls: %w", err)
+	}
+	var attributes map[string]domain.SAMLAttributeSource
+	if sp.Attributes != "" {
+		if err := json.Unmarshal([]byte(sp.Attributes), &attributes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attributes: %w", err)
+		}
+	}
+
+	return &domain.SAMLServiceProvider{
+		ID:            sp.ID,
+		OwnerID:       sp.OwnerID,
+		EntityID:      sp.EntityID,
+		Name:          sp.Name,
+		ACSURLs:       acsURLs,
+		Attributes:    attributes,
+		ProfileAccess: domain.FieldVisibility(sp.ProfileAccess),
+		CreatedAt:     sp.CreatedAt,
+		UpdatedAt:     sp.UpdatedAt,
+	}, nil
+}
+
+func FromDomainSAMLServiceProvider(sp *domain.SAMLServiceProvider) (*SAMLServiceProvider, error) {
+	acsURLs, err := json.Marshal(sp.ACSURLs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal acs_urls: %w", err)
+	}
+	var attributes []byte
+	if len(sp.Attributes) > 0 {
+		if attributes, err = json.Marshal(sp.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+		}
+	}
+
+	return &SAMLServiceProvider{
+		ID:            sp.ID,
+		OwnerID:       sp.OwnerID,
+		EntityID:      sp.EntityID,
+		Name:          sp.Name,
+		ACSURLs:       string(acsURLs),
+		Attributes:    string(attributes),
+		ProfileAccess: string(sp.ProfileAccess),
+		CreatedAt:     sp.CreatedAt,
+		UpdatedAt:     sp.UpdatedAt,
+	}, nil
+}
+
+// AppPassword GORM model
+type AppPassword struct {
+	ID             string       `gorm:"primaryKey;type:varchar(36)"`
+	UserID         string       `gorm:"index;type:varchar(36);not null"`
+	Name           string       `gorm:"type:varchar(255);not null"`
+	Scopes         string       `gorm:"type:varchar(255)"` // スペース区切り。空の場合はldapのみ（スコープの導入前に発行したパスワード）
+	HashedPassword string       `gorm:"type:varchar(255);not null"`
+	CreatedAt      time.Time    `gorm:"not null"`
+	ExpiresAt      sql.NullTime `gorm:"type:datetime"`
+	LastUsedAt     sql.NullTime `gorm:"type:datetime"`
+}
+
+func (AppPassword) TableName() string {
+	return "app_passwords"
+}
+
+func (p *AppPassword) ToDomain() *domain.AppPassword {
+	scopes := strings.Fields(p.Scopes)
+	if len(scopes) == 0 {
+		scopes = []string{domain.AppPasswordScopeLDAP}
+	}
+	var expiresAt, lastUsedAt *time.Time
+	if p.ExpiresAt.Valid {
+		expiresAt = &p.ExpiresAt.Time
+	}
+	if p.LastUsedAt.Valid {
+		lastUsedAt = &p.LastUsedAt.Time
+	}
+
+	return &domain.AppPassword{
+		ID:             p.ID,
+		UserID:         p.UserID,
+		Name:           p.Name,
+		Scopes:         scopes,
+		HashedPassword: p.HashedPassword,
+		CreatedAt:      p.CreatedAt,
+		ExpiresAt:      expiresAt,
+		LastUsedAt:     lastUsedAt,
+	}
+}
+
+func FromDomainAppPassword(p *domain.AppPassword) *AppPassword {
+	var expiresAt, lastUsedAt sql.NullTime
+	if p.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *p.ExpiresAt, Valid: true}
+	}
+	if p.LastUsedAt != nil {
+		lastUsedAt = sql.NullTime{Time: *p.LastUsedAt, Valid: true}
+	}
+
+	return &AppPassword{
+		ID:             p.ID,
+		UserID:         p.UserID,
+		Name:           p.Name,
+		Scopes:         strings.Join(p.Scopes, " "),
+		HashedPassword: p.HashedPassword,
+		CreatedAt:      p.CreatedAt,
+		ExpiresAt:      expiresAt,
+		LastUsedAt:     lastUsedAt,
 	}
 }
@@ -49,10 +49,10 @@ func (r *profileRepository) GetByID(ctx context.Context, id string) (*domain.Pro
 	return p.ToDomain(), nil
 }
 
-// GetByUserID はユーザーIDでプロフィールを取得します
+// GetByUserID はユーザーIDでプロフィールを取得します（墓標化されたものは除く）
 func (r *profileRepository) GetByUserID(ctx context.Context, userID string) (*domain.Profile, error) {
 	var p Profile
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&p).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND deleted_at IS NULL", userID).Order("updated_at DESC").First(&p).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("%w: user_id=%s", domain.ErrProfileNotFound, userID)
 		}
@@ -75,14 +75,14 @@ func (r *profileRepository) GetByMessageID(ctx context.Context, messageID string
 	return p.ToDomain(), nil
 }
 
-// GetByUserIDs はユーザーIDのリストでプロフィールを一括取得します
+// GetByUserIDs はユーザーIDのリストでプロフィールを一括取得します（墓標化されたものは除く）
 func (r *profileRepository) GetByUserIDs(ctx context.Context, userIDs []string) ([]*domain.Profile, error) {
 	if len(userIDs) == 0 {
 		return []*domain.Profile{}, nil
 	}
 
 	var profiles []Profile
-	if err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&profiles).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("user_id IN ? AND deleted_at IS NULL", userIDs).Find(&profiles).Error; err != nil {
 		return nil, fmt.Errorf("failed to get profiles by user_ids: %w", err)
 	}
 
@@ -94,10 +94,10 @@ func (r *profileRepository) GetByUserIDs(ctx context.Context, userIDs []string)
 	return domainProfiles, nil
 }
 
-// GetAll はすべてのプロフィールを取得します
+// GetAll はすべてのプロフィールを取得します（墓標化されたものは除く）
 func (r *profileRepository) GetAll(ctx context.Context) ([]*domain.Profile, error) {
 	var profiles []Profile
-	if err := r.db.WithContext(ctx).Find(&profiles).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("deleted_at IS NULL").Find(&profiles).Error; err != nil {
 		return nil, fmt.Errorf("failed to get all profiles: %w", err)
 	}
 
@@ -109,6 +109,22 @@ func (r *profileRepository) GetAll(ctx context.Context) ([]*domain.Profile, erro
 	return domainProfiles, nil
 }
 
+// ListByUserID はユーザーのプロフィールを墓標化されたものも含めてすべて取得します
+// 重複したプロフィールの整理に使用します
+func (r *profileRepository) ListByUserID(ctx context.Context, userID string) ([]*domain.Profile, error) {
+	var profiles []Profile
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list profiles by user_id: %w", err)
+	}
+
+	domainProfiles := make([]*domain.Profile, len(profiles))
+	for i, p := range profiles {
+		domainProfiles[i] = p.ToDomain()
+	}
+
+	return domainProfiles, nil
+}
+
 // Update は既存のプロフィールを更新します
 func (r *profileRepository) Update(ctx context.Context, profile *domain.Profile) error {
 	if err := profile.Validate(); err != nil {
@@ -119,12 +135,23 @@ func (r *profileRepository) Update(ctx context.Context, profile *domain.Profile)
 	p.UpdatedAt = time.Now()
 
 	result := r.db.WithContext(ctx).Model(&Profile{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
-		"real_name":  p.RealName,
-		"student_id": p.StudentID,
-		"hobbies":    p.Hobbies,
-		"what_to_do": p.WhatToDo,
-		"comment":    p.Comment,
-		"updated_at": p.UpdatedAt,
+		"discord_message_id":    p.DiscordMessageID,
+		"real_name":             p.RealName,
+		"student_id":            p.StudentID,
+		"enrollment_year":       p.EnrollmentYear,
+		"faculty":               p.Faculty,
+		"hobbies":               p.Hobbies,
+		"what_to_do":            p.WhatToDo,
+		"comment":               p.Comment,
+		"extra":                 p.Extra,
+		"visibility":            p.Visibility,
+		"sources":               p.Sources,
+		"student_id_visibility": p.StudentIDVisibility,
+		"real_name_visibility":  p.RealNameVisibility,
+		"hobbies_visibility":    p.HobbiesVisibility,
+		"edited_at":             p.EditedAt,
+		"deleted_at":            p.DeletedAt,
+		"updated_at":            p.UpdatedAt,
 	})
 
 	if result.Error != nil {
@@ -161,6 +188,24 @@ func (r *profileRepository) Upsert(ctx context.Context, profile *domain.Profile)
 	return nil
 }
 
+// Tombstone はプロフィールを墓標化（論理削除）します
+// 墓標化されたプロフィールは取得系のメソッドから除外されます
+func (r *profileRepository) Tombstone(ctx context.Context, id string) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&Profile{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"deleted_at": now,
+		"updated_at": now,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to tombstone profile: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("profile not found: %s", id)
+	}
+
+	return nil
+}
+
 // Delete はプロフィールをデータベースから削除します
 func (r *profileRepository) Delete(ctx context.Context, id string) error {
 	result := r.db.WithContext(ctx).Delete(&Profile{}, "id = ?", id)
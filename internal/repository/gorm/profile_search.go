@@ -0,0 +1,79 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+// searchBatchSize は索引を一括挿入する際の1回あたりの件数です
+const searchBatchSize = 500
+
+type profileSearchRepository struct {
+	db *gorm.DB
+}
+
+// NewProfileSearchRepository は新しいGORM全文検索索引リポジトリを作成します
+func NewProfileSearchRepository(db *gorm.DB) repository.ProfileSearchRepository {
+	return &profileSearchRepository{db: db}
+}
+
+// Replace はユーザーの索引を削除してからpostingsを挿入します
+func (r *profileSearchRepository) Replace(ctx context.Context, userID string, postings []*domain.SearchPosting) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&ProfileSearchTerm{}).Error; err != nil {
+			return fmt.Errorf("failed to delete search terms: %w", err)
+		}
+		if len(postings) == 0 {
+			return nil
+		}
+
+		terms := make([]*ProfileSearchTerm, len(postings))
+		for i, p := range postings {
+			terms[i] = FromDomainSearchPosting(p)
+		}
+		if err := tx.CreateInBatches(terms, searchBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to create search terms: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteByUserID はユーザーの索引を削除します
+func (r *profileSearchRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&ProfileSearchTerm{}).Error; err != nil {
+		return fmt.Errorf("failed to delete search terms: %w", err)
+	}
+	return nil
+}
+
+// FindByTerms はいずれかの語を含む索引を取得します
+func (r *profileSearchRepository) FindByTerms(ctx context.Context, terms []string) ([]*domain.SearchPosting, error) {
+	if len(terms) == 0 {
+		return []*domain.SearchPosting{}, nil
+	}
+
+	var rows []ProfileSearchTerm
+	if err := r.db.WithContext(ctx).Where("term IN ?", terms).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to find search terms: %w", err)
+	}
+
+	postings := make([]*domain.SearchPosting, len(rows))
+	for i, row := range rows {
+		postings[i] = row.ToDomain()
+	}
+	return postings, nil
+}
+
+// CountUsers は索引に登録されているユーザー数を返します
+func (r *profileSearchRepository) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&ProfileSearchTerm{}).Distinct("user_id").Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count indexed users: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,81 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupProfileSearchTestDB は全文検索索引テスト用のインメモリGORMデータベースをセットアップします
+func setupProfileSearchTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&ProfileSearchTerm{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestProfileSearchRepository はユーザーごとの索引の置き換え・語での検索・削除をテストします
+func TestProfileSearchRepository(t *testing.T) {
+	db := setupProfileSearchTestDB(t)
+	repo := NewProfileSearchRepository(db)
+	ctx := context.Background()
+
+	posting := func(userID, field, term string, count int) *domain.SearchPosting {
+		return &domain.SearchPosting{UserID: userID, Field: field, Term: term, Count: count}
+	}
+
+	if err := repo.Replace(ctx, "user-1", []*domain.SearchPosting{
+		posting("user-1", domain.ProfileFieldHobbies, "ゲー", 2),
+		posting("user-1", domain.ProfileFieldWhatToDo, "ゲー", 1),
+		posting("user-1", domain.ProfileFieldHobbies, "読書", 1),
+	}); err != nil {
+		t.Fatalf("Failed to replace: %v", err)
+	}
+	if err := repo.Replace(ctx, "user-2", []*domain.SearchPosting{
+		posting("user-2", domain.ProfileFieldHobbies, "ゲー", 1),
+	}); err != nil {
+		t.Fatalf("Failed to replace: %v", err)
+	}
+
+	found, err := repo.FindByTerms(ctx, []string{"ゲー"})
+	if err != nil {
+		t.Fatalf("Failed to find: %v", err)
+	}
+	if len(found) != 3 {
+		t.Errorf("Expected 3 postings, got %d", len(found))
+	}
+	if count, _ := repo.CountUsers(ctx); count != 2 {
+		t.Errorf("Expected 2 indexed users, got %d", count)
+	}
+
+	// 置き換えると以前の語は残らない
+	if err := repo.Replace(ctx, "user-1", []*domain.SearchPosting{
+		posting("user-1", domain.ProfileFieldHobbies, "映画", 1),
+	}); err != nil {
+		t.Fatalf("Failed to replace: %v", err)
+	}
+	found, _ = repo.FindByTerms(ctx, []string{"ゲー", "読書"})
+	if len(found) != 1 || found[0].UserID != "user-2" {
+		t.Errorf("Expected only user-2 posting, got %+v", found)
+	}
+
+	if err := repo.DeleteByUserID(ctx, "user-2"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if count, _ := repo.CountUsers(ctx); count != 1 {
+		t.Errorf("Expected 1 indexed user, got %d", count)
+	}
+}
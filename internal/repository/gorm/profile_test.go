@@ -67,3 +67,89 @@ func TestProfileRepository_GetByUserID(t *testing.T) {
 		}
 	})
 }
+
+func TestProfileRepository_Tombstone(t *testing.T) {
+	db := setupTestDB(t)
+	repo := gormRepo.NewProfileRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New().String()
+	profile := &domain.Profile{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		DiscordMessageID: "987654321",
+		RealName:         "Test User",
+	}
+	if err := repo.Create(ctx, profile); err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+
+	if err := repo.Tombstone(ctx, profile.ID); err != nil {
+		t.Fatalf("Failed to tombstone profile: %v", err)
+	}
+
+	// 墓標化されたプロフィールは通常の取得から除外される
+	if _, err := repo.GetByUserID(ctx, userID); !errors.Is(err, domain.ErrProfileNotFound) {
+		t.Errorf("Expected ErrProfileNotFound for tombstoned profile, got %v", err)
+	}
+
+	profiles, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get all profiles: %v", err)
+	}
+	for _, p := range profiles {
+		if p.ID == profile.ID {
+			t.Error("Expected tombstoned profile to be excluded from GetAll")
+		}
+	}
+
+	// ListByUserIDでは墓標も取得できる
+	listed, err := repo.ListByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to list profiles: %v", err)
+	}
+	if len(listed) != 1 || !listed[0].IsDeleted() {
+		t.Errorf("Expected one tombstoned profile, got %v", listed)
+	}
+}
+
+func TestProfileRepository_Sources(t *testing.T) {
+	db := setupTestDB(t)
+	repo := gormRepo.NewProfileRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New().String()
+	profile := &domain.Profile{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		DiscordMessageID: domain.ManualProfileMessageID(userID),
+		Hobbies:          "プログラミング",
+		Sources: map[string]domain.FieldOrigin{
+			domain.ProfileFieldHobbies: {Source: domain.FieldSourceManual, IntroValue: "読書"},
+		},
+	}
+	if err := repo.Create(ctx, profile); err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+
+	result, err := repo.GetByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if origin := result.Sources[domain.ProfileFieldHobbies]; origin.Source != domain.FieldSourceManual || origin.IntroValue != "読書" {
+		t.Errorf("Expected sources to round-trip, got %+v", result.Sources)
+	}
+
+	// 手動編集をやめた項目は更新で取り除かれる
+	result.Sources = nil
+	if err := repo.Update(ctx, result); err != nil {
+		t.Fatalf("Failed to update profile: %v", err)
+	}
+	result, err = repo.GetByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if len(result.Sources) != 0 {
+		t.Errorf("Expected sources to be cleared, got %+v", result.Sources)
+	}
+}
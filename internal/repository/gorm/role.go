@@ -0,0 +1,91 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository は新しいGORMロールリポジトリを作成します
+func NewRoleRepository(db *gorm.DB) repository.RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// Upsert はロールを挿入または更新します
+func (r *roleRepository) Upsert(ctx context.Context, role *domain.Role) error {
+	if err := role.Validate(); err != nil {
+		return fmt.Errorf("invalid role: %w", err)
+	}
+
+	m := FromDomainRole(role)
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "color", "position", "updated_at"}),
+	}).Create(m).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert role: %w", err)
+	}
+
+	return nil
+}
+
+// GetAll はすべてのロールを上位順（position降順）で取得します
+func (r *roleRepository) GetAll(ctx context.Context) ([]*domain.Role, error) {
+	var roles []Role
+	if err := r.db.WithContext(ctx).Order("position DESC").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to get all roles: %w", err)
+	}
+
+	domainRoles := make([]*domain.Role, len(roles))
+	for i, role := range roles {
+		domainRoles[i] = role.ToDomain()
+	}
+
+	return domainRoles, nil
+}
+
+// GetByIDs はロールIDのリストでロールを上位順に一括取得します
+func (r *roleRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.Role, error) {
+	if len(ids) == 0 {
+		return []*domain.Role{}, nil
+	}
+
+	var roles []Role
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("position DESC").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to get roles by ids: %w", err)
+	}
+
+	domainRoles := make([]*domain.Role, len(roles))
+	for i, role := range roles {
+		domainRoles[i] = role.ToDomain()
+	}
+
+	return domainRoles, nil
+}
+
+// DeleteExcept は指定されたID以外のロールを削除します（Discord側で削除されたロールの掃除用）
+func (r *roleRepository) DeleteExcept(ctx context.Context, ids []string) error {
+	query := r.db.WithContext(ctx)
+	if len(ids) > 0 {
+		query = query.Where("id NOT IN ?", ids)
+	} else {
+		query = query.Where("1 = 1")
+	}
+
+	if err := query.Delete(&Role{}).Error; err != nil {
+		return fmt.Errorf("failed to delete stale roles: %w", err)
+	}
+
+	return nil
+}
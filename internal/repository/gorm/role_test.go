@@ -0,0 +1,121 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupRoleTestDB はロールテスト用のインメモリGORMデータベースをセットアップします
+func setupRoleTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&Role{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestRoleRepository_Upsert はロールの挿入と更新をテストします
+func TestRoleRepository_Upsert(t *testing.T) {
+	db := setupRoleTestDB(t)
+	repo := NewRoleRepository(db)
+	ctx := context.Background()
+
+	role := &domain.Role{ID: "100", Name: "部員", Color: 0x00ff00, Position: 1}
+	if err := repo.Upsert(ctx, role); err != nil {
+		t.Fatalf("Failed to insert role: %v", err)
+	}
+
+	// 名前と順序を変更して再度Upsert
+	role.Name = "正部員"
+	role.Position = 3
+	if err := repo.Upsert(ctx, role); err != nil {
+		t.Fatalf("Failed to update role: %v", err)
+	}
+
+	roles, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get roles: %v", err)
+	}
+
+	if len(roles) != 1 {
+		t.Fatalf("Expected 1 role, got %d", len(roles))
+	}
+	if roles[0].Name != "正部員" || roles[0].Position != 3 {
+		t.Errorf("Role was not updated: %+v", roles[0])
+	}
+}
+
+// TestRoleRepository_GetByIDs はID指定取得が上位順で返ることをテストします
+func TestRoleRepository_GetByIDs(t *testing.T) {
+	db := setupRoleTestDB(t)
+	repo := NewRoleRepository(db)
+	ctx := context.Background()
+
+	for _, role := range []*domain.Role{
+		{ID: "1", Name: "部員", Position: 1},
+		{ID: "2", Name: "幹部", Position: 5},
+		{ID: "3", Name: "OB", Position: 2},
+	} {
+		if err := repo.Upsert(ctx, role); err != nil {
+			t.Fatalf("Failed to upsert role: %v", err)
+		}
+	}
+
+	roles, err := repo.GetByIDs(ctx, []string{"1", "2", "unknown"})
+	if err != nil {
+		t.Fatalf("Failed to get roles: %v", err)
+	}
+
+	if len(roles) != 2 {
+		t.Fatalf("Expected 2 roles, got %d", len(roles))
+	}
+	if roles[0].ID != "2" || roles[1].ID != "1" {
+		t.Errorf("Expected roles ordered by position desc, got %s, %s", roles[0].ID, roles[1].ID)
+	}
+
+	empty, err := repo.GetByIDs(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected empty result for nil ids, got %v (err: %v)", empty, err)
+	}
+}
+
+// TestRoleRepository_DeleteExcept は指定外のロールが削除されることをテストします
+func TestRoleRepository_DeleteExcept(t *testing.T) {
+	db := setupRoleTestDB(t)
+	repo := NewRoleRepository(db)
+	ctx := context.Background()
+
+	for _, role := range []*domain.Role{
+		{ID: "1", Name: "部員"},
+		{ID: "2", Name: "幹部"},
+	} {
+		if err := repo.Upsert(ctx, role); err != nil {
+			t.Fatalf("Failed to upsert role: %v", err)
+		}
+	}
+
+	if err := repo.DeleteExcept(ctx, []string{"2"}); err != nil {
+		t.Fatalf("Failed to delete roles: %v", err)
+	}
+
+	roles, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get roles: %v", err)
+	}
+	if len(roles) != 1 || roles[0].ID != "2" {
+		t.Errorf("Expected only role 2 to remain, got %+v", roles)
+	}
+}
@@ -0,0 +1,108 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type samlServiceProviderRepository struct {
+	db *gorm.DB
+}
+
+// NewSAMLServiceProviderRepository は新しいGORM SAMLサービスリポジトリを作成します
+func NewSAMLServiceProviderRepository(db *gorm.DB) repository.SAMLServiceProviderRepository {
+	return &samlServiceProviderRepository{db: db}
+}
+
+// Create は新しいサービスをデータベースに挿入します
+func (r *samlServiceProviderRepository) Create(ctx context.Context, sp *domain.SAMLServiceProvider) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+
+	model, err := FromDomainSAMLServiceProvider(sp)
+	if err != nil {
+		return fmt.Errorf("failed to map saml service provider: %w", err)
+	}
+
+	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
+		return fmt.Errorf("failed to create saml service provider: %w", err)
+	}
+	return nil
+}
+
+// GetByEntityID はエンティティIDでサービスを取得します
+func (r *samlServiceProviderRepository) GetByEntityID(ctx context.Context, entityID string) (*domain.SAMLServiceProvider, error) {
+	var model SAMLServiceProvider
+	if err := r.db.WithContext(ctx).Where("entity_id = ?", entityID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", domain.ErrSAMLServiceProviderNotFound, entityID)
+		}
+		return nil, fmt.Errorf("failed to get saml service provider: %w", err)
+	}
+	return model.ToDomain()
+}
+
+// GetAll はすべてのサービスを登録順に取得します
+func (r *samlServiceProviderRepository) GetAll(ctx context.Context) ([]*domain.SAMLServiceProvider, error) {
+	var models []SAMLServiceProvider
+	if err := r.db.WithContext(ctx).Order("created_at").Order("id").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to get saml service providers: %w", err)
+	}
+
+	sps := make([]*domain.SAMLServiceProvider, len(models))
+	for i, model := range models {
+		sp, err := model.ToDomain()
+		if err != nil {
+			return nil, fmt.Errorf("failed to map saml service provider (id: %s): %w", model.ID, err)
+		}
+		sps[i] = sp
+	}
+	return sps, nil
+}
+
+// Update は既存のサービスを更新します（エンティティIDは変更できません）
+func (r *samlServiceProviderRepository) Update(ctx context.Context, sp *domain.SAMLServiceProvider) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+
+	model, err := FromDomainSAMLServiceProvider(sp)
+	if err != nil {
+		return fmt.Errorf("failed to map saml service provider: %w", err)
+	}
+
+	result := r.db.WithContext(ctx).Model(&SAMLServiceProvider{}).Where("id = ?", model.ID).Updates(map[string]interface{}{
+		"name":           model.Name,
+		"acs_urls":       model.ACSURLs,
+		"attributes":     model.Attributes,
+		"profile_access": model.ProfileAccess,
+		"updated_at":     time.Now(),
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update saml service provider: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrSAMLServiceProviderNotFound, sp.EntityID)
+	}
+	return nil
+}
+
+// Delete はサービスをデータベースから削除します
+func (r *samlServiceProviderRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Delete(&SAMLServiceProvider{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete saml service provider: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: id=%s", domain.ErrSAMLServiceProviderNotFound, id)
+	}
+	return nil
+}
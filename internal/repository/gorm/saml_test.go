@@ -0,0 +1,85 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupSAMLTestDB はSAMLのサービスのテスト用のインメモリGORMデータベースをセットアップします
+func setupSAMLTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&SAMLServiceProvider{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestSAMLServiceProviderRepository はサービスの登録・取得・更新・削除をテストします
+func TestSAMLServiceProviderRepository(t *testing.T) {
+	db := setupSAMLTestDB(t)
+	repo := NewSAMLServiceProviderRepository(db)
+	ctx := context.Background()
+
+	sp := &domain.SAMLServiceProvider{
+		ID:        "sp-1",
+		OwnerID:   "user-1",
+		EntityID:  "https://wiki.example.com/saml/metadata",
+		Name:      "Wiki",
+		ACSURLs:   []string{"https://wiki.example.com/saml/acs"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := repo.Create(ctx, sp); err != nil {
+		t.Fatalf("Failed to create service provider: %v", err)
+	}
+
+	retrieved, err := repo.GetByEntityID(ctx, sp.EntityID)
+	if err != nil {
+		t.Fatalf("Failed to get service provider: %v", err)
+	}
+	if retrieved.Name != "Wiki" || len(retrieved.ACSURLs) != 1 || retrieved.Attributes != nil {
+		t.Errorf("Unexpected service provider: %+v", retrieved)
+	}
+
+	// 属性の対応を設定する
+	retrieved.Attributes = map[string]domain.SAMLAttributeSource{"urn:oid:0.9.2342.19200300.100.1.1": domain.SAMLAttributeStudentID}
+	retrieved.ProfileAccess = domain.VisibilityOfficers
+	if err := repo.Update(ctx, retrieved); err != nil {
+		t.Fatalf("Failed to update service provider: %v", err)
+	}
+	all, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list service providers: %v", err)
+	}
+	if len(all) != 1 || all[0].Attributes["urn:oid:0.9.2342.19200300.100.1.1"] != domain.SAMLAttributeStudentID || all[0].ProfileAccess != domain.VisibilityOfficers {
+		t.Errorf("Unexpected service providers after update: %+v", all)
+	}
+
+	// 不正な属性の対応は保存しない
+	retrieved.Attributes = map[string]domain.SAMLAttributeSource{"mail": "email"}
+	if err := repo.Update(ctx, retrieved); !errors.Is(err, domain.ErrInvalidSAMLServiceProvider) {
+		t.Errorf("Expected ErrInvalidSAMLServiceProvider, got %v", err)
+	}
+
+	if err := repo.Delete(ctx, sp.ID); err != nil {
+		t.Fatalf("Failed to delete service provider: %v", err)
+	}
+	if _, err := repo.GetByEntityID(ctx, sp.EntityID); !errors.Is(err, domain.ErrSAMLServiceProviderNotFound) {
+		t.Errorf("Expected ErrSAMLServiceProviderNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type syncCursorRepository struct {
+	db *gorm.DB
+}
+
+// NewSyncCursorRepository は新しいGORM同期カーソルリポジトリを作成します
+func NewSyncCursorRepository(db *gorm.DB) repository.SyncCursorRepository {
+	return &syncCursorRepository{db: db}
+}
+
+// GetByChannelID はチャンネルIDで同期カーソルを取得します
+func (r *syncCursorRepository) GetByChannelID(ctx context.Context, channelID string) (*domain.SyncCursor, error) {
+	var c SyncCursor
+	if err := r.db.WithContext(ctx).Where("channel_id = ?", channelID).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: channel_id=%s", domain.ErrSyncCursorNotFound, channelID)
+		}
+		return nil, fmt.Errorf("failed to get sync cursor: %w", err)
+	}
+
+	return c.ToDomain(), nil
+}
+
+// Upsert は同期カーソルを挿入または更新します
+func (r *syncCursorRepository) Upsert(ctx context.Context, cursor *domain.SyncCursor) error {
+	if err := cursor.Validate(); err != nil {
+		return fmt.Errorf("invalid sync cursor: %w", err)
+	}
+
+	c := FromDomainSyncCursor(cursor)
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel_id"}},
+		UpdateAll: true,
+	}).Create(c).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert sync cursor: %w", err)
+	}
+
+	return nil
+}
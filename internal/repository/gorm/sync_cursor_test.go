@@ -0,0 +1,72 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupSyncCursorTestDB は同期カーソルテスト用のインメモリGORMデータベースをセットアップします
+func setupSyncCursorTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&SyncCursor{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+
+	return db
+}
+
+// TestSyncCursorRepository_GetByChannelID_NotFound は未作成のカーソル取得をテストします
+func TestSyncCursorRepository_GetByChannelID_NotFound(t *testing.T) {
+	db := setupSyncCursorTestDB(t)
+	repo := NewSyncCursorRepository(db)
+
+	_, err := repo.GetByChannelID(context.Background(), "channel-1")
+	if !errors.Is(err, domain.ErrSyncCursorNotFound) {
+		t.Errorf("Expected ErrSyncCursorNotFound, got %v", err)
+	}
+}
+
+// TestSyncCursorRepository_Upsert はカーソルの作成と更新をテストします
+func TestSyncCursorRepository_Upsert(t *testing.T) {
+	db := setupSyncCursorTestDB(t)
+	repo := NewSyncCursorRepository(db)
+	ctx := context.Background()
+
+	cursor := &domain.SyncCursor{ChannelID: "channel-1", LastMessageID: "100"}
+	if err := repo.Upsert(ctx, cursor); err != nil {
+		t.Fatalf("Failed to create cursor: %v", err)
+	}
+
+	fullSyncAt := time.Now()
+	cursor.LastMessageID = "200"
+	cursor.LastFullSyncAt = &fullSyncAt
+	if err := repo.Upsert(ctx, cursor); err != nil {
+		t.Fatalf("Failed to update cursor: %v", err)
+	}
+
+	retrieved, err := repo.GetByChannelID(ctx, "channel-1")
+	if err != nil {
+		t.Fatalf("Failed to get cursor: %v", err)
+	}
+
+	if retrieved.LastMessageID != "200" {
+		t.Errorf("Expected last_message_id 200, got %s", retrieved.LastMessageID)
+	}
+	if retrieved.LastFullSyncAt == nil {
+		t.Error("Expected last_full_sync_at to be set")
+	}
+}
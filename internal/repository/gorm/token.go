@@ -75,6 +75,23 @@ func (r *tokenRepository) Revoke(ctx context.Context, token string) error {
 	return nil
 }
 
+// ListRevokedJWTAccessTokens は取り消したJWT形式のアクセストークンのうち、nowの時点で期限切れでないものを取得します
+func (r *tokenRepository) ListRevokedJWTAccessTokens(ctx context.Context, now time.Time) ([]*domain.Token, error) {
+	var tokens []Token
+	if err := r.db.WithContext(ctx).
+		Where("token_type = ? AND revoked = ? AND expires_at > ? AND token LIKE ?", string(domain.TokenTypeAccess), true, now, domain.JWTAccessTokenPrefix+"%").
+		Order("expires_at").
+		Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to list revoked tokens: %w", err)
+	}
+
+	domainTokens := make([]*domain.Token, len(tokens))
+	for i, t := range tokens {
+		domainTokens[i] = t.ToDomain()
+	}
+	return domainTokens, nil
+}
+
 // DeleteExpired は期限切れのトークンを削除します
 func (r *tokenRepository) DeleteExpired(ctx context.Context) error {
 	now := time.Now()
@@ -296,3 +296,38 @@ func TestTokenRepository_UniqueConstraint(t *testing.T) {
 		t.Error("Expected error when creating token with duplicate token value, got nil")
 	}
 }
+
+// TestTokenRepository_ListRevokedJWTAccessTokens は取り消したJWT形式のアクセストークンの取得をテストします
+func TestTokenRepository_ListRevokedJWTAccessTokens(t *testing.T) {
+	db := setupTokenTestDB(t)
+	repo := NewTokenRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	tokens := []*domain.Token{
+		{ID: "jti-revoked", Token: domain.JWTAccessTokenKey("jti-revoked"), TokenType: domain.TokenTypeAccess, ExpiresAt: now.Add(time.Hour)},
+		{ID: "jti-active", Token: domain.JWTAccessTokenKey("jti-active"), TokenType: domain.TokenTypeAccess, ExpiresAt: now.Add(time.Hour)},
+		{ID: "jti-expired", Token: domain.JWTAccessTokenKey("jti-expired"), TokenType: domain.TokenTypeAccess, ExpiresAt: now.Add(-time.Minute)},
+		{ID: "opaque", Token: "opaque-access-token", TokenType: domain.TokenTypeAccess, ExpiresAt: now.Add(time.Hour)},
+		{ID: "refresh", Token: "refresh-token", TokenType: domain.TokenTypeRefresh, ExpiresAt: now.Add(time.Hour)},
+	}
+	for _, token := range tokens {
+		token.UserID, token.ClientID, token.CreatedAt = "user-10", "client-10", now
+		if err := repo.Create(ctx, token); err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		if token.ID != "jti-active" {
+			if err := repo.Revoke(ctx, token.Token); err != nil {
+				t.Fatalf("Failed to revoke token: %v", err)
+			}
+		}
+	}
+
+	revoked, err := repo.ListRevokedJWTAccessTokens(ctx, now)
+	if err != nil {
+		t.Fatalf("Failed to list revoked tokens: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0].JTI() != "jti-revoked" {
+		t.Errorf("Expected only jti-revoked, got %+v", revoked)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -27,11 +28,12 @@ func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 	}
 
 	u := FromDomainUser(user)
-	if err := r.db.WithContext(ctx).Create(u).Error; err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
-	}
-
-	return nil
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		return replaceUserRoles(tx, u.ID, user.GuildRoles)
+	})
 }
 
 // GetByID はIDでユーザーを取得します
@@ -73,55 +75,101 @@ func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
 	// Updates(struct)の挙動ではゼロ値は更新されない。
 	// 今回はすべてのフィールドを上書きして問題ないか確認が必要。
 	// sqlite実装では username, avatar_url, updated_at, last_login_at のみを更新している。
+	// Guild Member情報（ニックネーム・ロール・参加日時・脱退日時）はロスター同期でも更新されるため対象に含める。
+
+	// ロールでの絞り込みに使用する対応表も同じトランザクションで更新する
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+			"username":       u.Username,
+			"display_name":   u.DisplayName,
+			"avatar_url":     u.AvatarURL,
+			"guild_nickname": u.GuildNickname,
+			"guild_roles":    u.GuildRoles,
+			"joined_at":      u.JoinedAt,
+			"left_at":        u.LeftAt,
+			"updated_at":     u.UpdatedAt,
+			"last_login_at":  u.LastLoginAt,
+		})
+
+		if result.Error != nil {
+			return fmt.Errorf("failed to update user: %w", result.Error)
+		}
 
-	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
-		"username":      u.Username,
-		"display_name":  u.DisplayName,
-		"avatar_url":    u.AvatarURL,
-		"updated_at":    u.UpdatedAt,
-		"last_login_at": u.LastLoginAt,
-	})
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to update user: %w", result.Error)
-	}
-
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found: %s", user.ID)
-	}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("user not found: %s", user.ID)
+		}
 
-	return nil
+		return replaceUserRoles(tx, u.ID, user.GuildRoles)
+	})
 }
 
 // Delete はユーザーをデータベースから削除します
 func (r *userRepository) Delete(ctx context.Context, id string) error {
-	result := r.db.WithContext(ctx).Delete(&User{}, "id = ?", id)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete user: %w", result.Error)
-	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&User{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete user: %w", result.Error)
+		}
 
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found: %s", id)
-	}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("user not found: %s", id)
+		}
 
-	return nil
+		return replaceUserRoles(tx, id, nil)
+	})
 }
 
-// GetAll は全てのユーザーを取得します
+// GetAll はサーバーに在籍している全てのユーザーを最終ログイン日時の新しい順に取得します（脱退済みのユーザーは除く）
+// OFFSETは件数が多いと遅くなり、取得の途中でログインしたユーザーがいると結果がずれるため、続けて取得する場合はFindMembersのカーソルを使用してください
 func (r *userRepository) GetAll(ctx context.Context, limit, offset int) ([]*domain.User, error) {
-	var users []User
-	query := r.db.WithContext(ctx).Order("last_login_at DESC")
+	users, _, err := r.FindMembers(ctx, domain.MemberFilter{}, domain.DefaultMemberPage(limit, offset))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all users: %w", err)
+	}
+	return users, nil
+}
+
+// FindMembers は在籍中のユーザーのうち絞り込み条件に一致するものを、指定された並び順・範囲で取得します
+// 取得範囲の続きがある場合は、次の範囲を取得するためのカーソルを返します
+func (r *userRepository) FindMembers(ctx context.Context, filter domain.MemberFilter, page domain.MemberPage) ([]*domain.User, *domain.MemberCursor, error) {
+	if err := page.Validate(); err != nil {
+		return nil, nil, err
+	}
+	column, ok := memberSortColumns[page.Sort]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unknown sort %q", domain.ErrInvalidMemberPage, page.Sort)
+	}
 
-	if limit > 0 {
-		query = query.Limit(limit)
+	direction := "ASC"
+	if page.Desc {
+		direction = "DESC"
+	}
+
+	query := r.memberQuery(ctx, filter).Order(column + " " + direction).Order("users.id " + direction)
+	if page.Cursor != nil {
+		condition, args, err := keysetCondition(column, page)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.Where(condition, args...)
+	} else if page.Offset > 0 {
+		query = query.Offset(page.Offset)
 	}
 
-	if offset > 0 {
-		query = query.Offset(offset)
+	// 続きがあるかを判定するため1件多く取得する
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit + 1)
 	}
 
+	var users []User
 	if err := query.Find(&users).Error; err != nil {
-		return nil, fmt.Errorf("failed to get all users: %w", err)
+		return nil, nil, fmt.Errorf("failed to find members: %w", err)
+	}
+
+	var next *domain.MemberCursor
+	if page.Limit > 0 && len(users) > page.Limit {
+		users = users[:page.Limit]
+		next = memberCursor(page, &users[len(users)-1])
 	}
 
 	domainUsers := make([]*domain.User, len(users))
@@ -129,5 +177,69 @@ func (r *userRepository) GetAll(ctx context.Context, limit, offset int) ([]*doma
 		domainUsers[i] = u.ToDomain()
 	}
 
+	return domainUsers, next, nil
+}
+
+// CountMembers は在籍中のユーザーのうち絞り込み条件に一致するものの件数を返します
+func (r *userRepository) CountMembers(ctx context.Context, filter domain.MemberFilter) (int64, error) {
+	var count int64
+	if err := r.memberQuery(ctx, filter).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return count, nil
+}
+
+// MarkLeftExcept は指定したDiscord ID以外の在籍中ユーザーを脱退済みにし、脱退済みにしたユーザーを返します
+// ロスター同期で、サーバーのメンバー一覧に存在しないユーザーを検出するために使用します
+func (r *userRepository) MarkLeftExcept(ctx context.Context, discordIDs []string) ([]*domain.User, error) {
+	query := r.db.WithContext(ctx).Where("left_at IS NULL")
+	if len(discordIDs) > 0 {
+		query = query.Where("discord_id NOT IN ?", discordIDs)
+	}
+
+	var users []User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to find departed users: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&User{}).
+		Where("id IN ? AND left_at IS NULL", ids).
+		Updates(map[string]interface{}{
+			"left_at":    now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to mark users as left: %w", result.Error)
+	}
+
+	domainUsers := make([]*domain.User, len(users))
+	for i, u := range users {
+		user := u.ToDomain()
+		user.LeftAt = &now
+		user.UpdatedAt = now
+		domainUsers[i] = user
+	}
 	return domainUsers, nil
 }
+
+// visibilityValues は公開範囲の一覧を検索条件用の文字列に変換します
+// 公開範囲の導入前に保存されたプロフィールは空文字列（members扱い）のため、membersに含めます
+func visibilityValues(levels []domain.FieldVisibility) []string {
+	values := []string{}
+	for _, v := range levels {
+		values = append(values, string(v))
+		if v == domain.VisibilityMembers {
+			values = append(values, "")
+		}
+	}
+	return values
+}
@@ -2,6 +2,9 @@ package gorm
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
@@ -22,7 +25,7 @@ func setupUserTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// AutoMigrateでテーブルを作成
-	if err := db.AutoMigrate(&User{}); err != nil {
+	if err := db.AutoMigrate(&User{}, &UserRole{}); err != nil {
 		t.Fatalf("Failed to migrate test schema: %v", err)
 	}
 
@@ -229,3 +232,401 @@ func TestUserRepository_UniqueConstraint(t *testing.T) {
 		t.Error("Expected error when creating user with duplicate discord_id, got nil")
 	}
 }
+
+// TestUserRepository_MarkLeftExcept はロスターにいないユーザーが脱退済みになることをテストします
+func TestUserRepository_MarkLeftExcept(t *testing.T) {
+	db := setupUserTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	for _, id := range []string{"1", "2", "3"} {
+		user := &domain.User{
+			ID:        "user-" + id,
+			DiscordID: "discord-" + id,
+			Username:  "user" + id,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	marked, err := repo.MarkLeftExcept(ctx, []string{"discord-1", "discord-2"})
+	if err != nil {
+		t.Fatalf("Failed to mark users as left: %v", err)
+	}
+	if len(marked) != 1 || marked[0].ID != "user-3" || marked[0].IsGuildMember() {
+		t.Errorf("Expected user-3 marked as left, got %v", marked)
+	}
+
+	left, err := repo.GetByID(ctx, "user-3")
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if left.IsGuildMember() {
+		t.Error("Expected user-3 to be marked as left")
+	}
+
+	// 脱退済みのユーザーはGetAllに含まれない
+	users, err := repo.GetAll(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to get all users: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected 2 current members, got %d", len(users))
+	}
+}
+
+// TestUserRepository_FindMembers は入学年度・学部コードでの絞り込みをテストします
+func TestUserRepository_FindMembers(t *testing.T) {
+	db := setupUserTestDB(t)
+	if err := db.AutoMigrate(&Profile{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+	repo := NewUserRepository(db)
+	profileRepo := NewProfileRepository(db)
+	ctx := context.Background()
+
+	academics := []struct {
+		enrollmentYear int
+		faculty        string
+	}{
+		{2023, "X"},
+		{2024, "X"},
+		{2024, "Y"},
+	}
+	for i, a := range academics {
+		id := strconv.Itoa(i + 1)
+		user := &domain.User{
+			ID:        "user-" + id,
+			DiscordID: "discord-" + id,
+			Username:  "user" + id,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		profile := &domain.Profile{
+			ID:               "profile-" + id,
+			UserID:           user.ID,
+			DiscordMessageID: "message-" + id,
+			EnrollmentYear:   a.enrollmentYear,
+			Faculty:          a.faculty,
+		}
+		if err := profileRepo.Create(ctx, profile); err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+	}
+
+	// プロフィールのないユーザーは条件指定時には含まれない
+	if err := repo.Create(ctx, &domain.User{ID: "user-4", DiscordID: "discord-4", Username: "user4"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   domain.MemberFilter
+		expected int
+	}{
+		{name: "条件なし", filter: domain.MemberFilter{}, expected: 4},
+		{name: "入学年度", filter: domain.MemberFilter{EnrollmentYear: 2024}, expected: 2},
+		{name: "学部コード", filter: domain.MemberFilter{Faculty: "X"}, expected: 2},
+		{name: "入学年度と学部コード", filter: domain.MemberFilter{EnrollmentYear: 2024, Faculty: "Y"}, expected: 1},
+		{name: "該当なし", filter: domain.MemberFilter{EnrollmentYear: 2020}, expected: 0},
+		{name: "ユーザー名", filter: domain.MemberFilter{Usernames: []string{"user1", "user4", "user"}}, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, _, err := repo.FindMembers(ctx, tt.filter, domain.DefaultMemberPage(0, 0))
+			if err != nil {
+				t.Fatalf("Failed to find members: %v", err)
+			}
+			if len(users) != tt.expected {
+				t.Errorf("Expected %d members, got %d", tt.expected, len(users))
+			}
+		})
+	}
+
+	// 墓標化したプロフィールは条件に一致しない
+	if err := profileRepo.Tombstone(ctx, "profile-3"); err != nil {
+		t.Fatalf("Failed to tombstone profile: %v", err)
+	}
+	users, _, err := repo.FindMembers(ctx, domain.MemberFilter{EnrollmentYear: 2024}, domain.DefaultMemberPage(0, 0))
+	if err != nil {
+		t.Fatalf("Failed to find members: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != "user-2" {
+		t.Errorf("Expected only user-2, got %+v", users)
+	}
+}
+
+// TestUserRepository_FindMembers_Visibility は学籍番号の公開範囲に応じて絞り込みの対象が変わることをテストします
+func TestUserRepository_FindMembers_Visibility(t *testing.T) {
+	db := setupUserTestDB(t)
+	if err := db.AutoMigrate(&Profile{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+	repo := NewUserRepository(db)
+	profileRepo := NewProfileRepository(db)
+	ctx := context.Background()
+
+	visibilities := []domain.FieldVisibility{"", domain.VisibilityOfficers, domain.VisibilityHidden}
+	for i, v := range visibilities {
+		id := strconv.Itoa(i + 1)
+		if err := repo.Create(ctx, &domain.User{ID: "user-" + id, DiscordID: "discord-" + id, Username: "user" + id}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		profile := &domain.Profile{
+			ID:               "profile-" + id,
+			UserID:           "user-" + id,
+			DiscordMessageID: "message-" + id,
+			EnrollmentYear:   2024,
+		}
+		if v != "" {
+			profile.Visibility = map[string]domain.FieldVisibility{domain.ProfileFieldStudentID: v}
+		}
+		if err := profileRepo.Create(ctx, profile); err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		viewer   *domain.ProfileViewer
+		expected int
+	}{
+		{name: "メンバー", viewer: &domain.ProfileViewer{UserID: "other", Member: true}, expected: 1},
+		{name: "幹部", viewer: &domain.ProfileViewer{UserID: "other", Member: true, Officer: true}, expected: 2},
+		{name: "非公開にした本人", viewer: &domain.ProfileViewer{UserID: "user-3", Member: true}, expected: 2},
+		{name: "閲覧者を指定しない", viewer: nil, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, _, err := repo.FindMembers(ctx, domain.MemberFilter{EnrollmentYear: 2024, Viewer: tt.viewer}, domain.DefaultMemberPage(0, 0))
+			if err != nil {
+				t.Fatalf("Failed to find members: %v", err)
+			}
+			if len(users) != tt.expected {
+				t.Errorf("Expected %d members, got %d", tt.expected, len(users))
+			}
+		})
+	}
+}
+
+// TestUserRepository_FindMembers_Search は名前・ロール・参加日時・趣味・プロフィールの有無での絞り込みをテストします
+func TestUserRepository_FindMembers_Search(t *testing.T) {
+	db := setupUserTestDB(t)
+	if err := db.AutoMigrate(&Profile{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+	repo := NewUserRepository(db)
+	profileRepo := NewProfileRepository(db)
+	ctx := context.Background()
+
+	date := func(s string) *time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return &d
+	}
+	nickname := "ぎょじ"
+	users := []*domain.User{
+		{ID: "user-1", DiscordID: "discord-1", Username: "taro", GuildRoles: []string{"role-member", "role-officer"}, JoinedAt: date("2023-04-10")},
+		{ID: "user-2", DiscordID: "discord-2", Username: "hanako", GuildNickname: &nickname, GuildRoles: []string{"role-member"}, JoinedAt: date("2024-04-10")},
+		{ID: "user-3", DiscordID: "discord-3", Username: "jiro_100%", GuildRoles: []string{"role-member"}, JoinedAt: date("2025-04-10")},
+		{ID: "user-4", DiscordID: "discord-4", Username: "nobody"},
+	}
+	for _, u := range users {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	profiles := []*domain.Profile{
+		{ID: "profile-1", UserID: "user-1", DiscordMessageID: "message-1", RealName: "じょぎ太郎", Hobbies: "ゲーム制作、競プロ"},
+		{ID: "profile-2", UserID: "user-2", DiscordMessageID: "message-2", RealName: "じょぎ花子", Hobbies: "ゲーム",
+			Visibility: map[string]domain.FieldVisibility{domain.ProfileFieldRealName: domain.VisibilityOfficers, domain.ProfileFieldHobbies: domain.VisibilityHidden}},
+		{ID: "profile-3", UserID: "user-3", DiscordMessageID: "message-3", RealName: "次郎", Hobbies: "読書"},
+	}
+	for _, p := range profiles {
+		if err := profileRepo.Create(ctx, p); err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+	}
+	if err := profileRepo.Tombstone(ctx, "profile-3"); err != nil {
+		t.Fatalf("Failed to tombstone profile: %v", err)
+	}
+
+	// ロールの変更は対応表にも反映される
+	users[2].GuildRoles = []string{"role-guest"}
+	if err := repo.Update(ctx, users[2]); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+
+	member := &domain.ProfileViewer{UserID: "user-4", Member: true}
+	officer := &domain.ProfileViewer{UserID: "user-4", Member: true, Officer: true}
+	hasProfile, noProfile := true, false
+
+	tests := []struct {
+		name   string
+		filter domain.MemberFilter
+		want   []string
+	}{
+		{name: "ユーザー名", filter: domain.MemberFilter{Query: "TARO"}, want: []string{"user-1"}},
+		{name: "ニックネーム", filter: domain.MemberFilter{Query: "ぎょじ"}, want: []string{"user-2"}},
+		{name: "LIKEの特殊文字", filter: domain.MemberFilter{Query: "0%"}, want: []string{"user-3"}},
+		{name: "氏名・閲覧者なし", filter: domain.MemberFilter{Query: "じょぎ"}, want: []string{"user-1", "user-2"}},
+		{name: "氏名・メンバー", filter: domain.MemberFilter{Query: "じょぎ", Viewer: member}, want: []string{"user-1"}},
+		{name: "氏名・幹部", filter: domain.MemberFilter{Query: "じょぎ", Viewer: officer}, want: []string{"user-1", "user-2"}},
+		{name: "墓標化したプロフィールの氏名", filter: domain.MemberFilter{Query: "次郎"}, want: nil},
+		{name: "ロール", filter: domain.MemberFilter{RoleIDs: []string{"role-member"}}, want: []string{"user-1", "user-2"}},
+		{name: "いずれかのロール", filter: domain.MemberFilter{RoleIDs: []string{"role-officer", "role-guest"}}, want: []string{"user-1", "user-3"}},
+		{name: "参加日時の範囲", filter: domain.MemberFilter{JoinedAfter: date("2024-01-01"), JoinedBefore: date("2025-04-10")}, want: []string{"user-2"}},
+		{name: "趣味", filter: domain.MemberFilter{HobbiesKeywords: []string{"ゲーム"}}, want: []string{"user-1", "user-2"}},
+		{name: "趣味・すべてのキーワード", filter: domain.MemberFilter{HobbiesKeywords: []string{"ゲーム", "競プロ"}}, want: []string{"user-1"}},
+		{name: "趣味・非公開", filter: domain.MemberFilter{HobbiesKeywords: []string{"ゲーム"}, Viewer: officer}, want: []string{"user-1"}},
+		{name: "プロフィールあり", filter: domain.MemberFilter{HasProfile: &hasProfile}, want: []string{"user-1", "user-2"}},
+		{name: "プロフィールなし", filter: domain.MemberFilter{HasProfile: &noProfile}, want: []string{"user-3", "user-4"}},
+		{name: "複数の条件", filter: domain.MemberFilter{RoleIDs: []string{"role-member"}, HobbiesKeywords: []string{"競プロ"}}, want: []string{"user-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := domain.MemberPage{Sort: domain.MemberSortUsername}
+			found, _, err := repo.FindMembers(ctx, tt.filter, page)
+			if err != nil {
+				t.Fatalf("Failed to find members: %v", err)
+			}
+			var got []string
+			for _, u := range found {
+				got = append(got, u.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+
+			// 件数は同じ条件で取得した件数と一致する
+			count, err := repo.CountMembers(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to count members: %v", err)
+			}
+			if count != int64(len(tt.want)) {
+				t.Errorf("Expected count %d, got %d", len(tt.want), count)
+			}
+		})
+	}
+}
+
+// TestUserRepository_FindMembers_Cursor はカーソルによるページングで全員を重複なく取得できることをテストします
+func TestUserRepository_FindMembers_Cursor(t *testing.T) {
+	db := setupUserTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	base := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		id := strconv.Itoa(i)
+		user := &domain.User{ID: "user-" + id, DiscordID: "discord-" + id, Username: "user" + id}
+		// user-1とuser-2は同じ日時、user-5は日時なし（NULL）
+		if i <= 4 {
+			at := base.Add(time.Duration(max(i, 2)) * time.Hour)
+			user.JoinedAt = &at
+			user.LastLoginAt = &at
+		}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		page domain.MemberPage
+		want []string
+	}{
+		{name: "最終ログイン・新しい順", page: domain.MemberPage{Sort: domain.MemberSortLastLogin, Desc: true}, want: []string{"user-4", "user-3", "user-2", "user-1", "user-5"}},
+		{name: "参加日時・古い順", page: domain.MemberPage{Sort: domain.MemberSortJoinedAt}, want: []string{"user-5", "user-1", "user-2", "user-3", "user-4"}},
+		{name: "ユーザー名・降順", page: domain.MemberPage{Sort: domain.MemberSortUsername, Desc: true}, want: []string{"user-5", "user-4", "user-3", "user-2", "user-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := tt.page
+			page.Limit = 2
+			var got []string
+			for range 5 {
+				found, next, err := repo.FindMembers(ctx, domain.MemberFilter{}, page)
+				if err != nil {
+					t.Fatalf("Failed to find members: %v", err)
+				}
+				for _, u := range found {
+					got = append(got, u.ID)
+				}
+				if next == nil {
+					break
+				}
+				// カーソルは文字列に変換しても同じ位置を表す
+				cursor, err := domain.DecodeMemberCursor(next.Encode())
+				if err != nil {
+					t.Fatalf("Failed to decode cursor: %v", err)
+				}
+				page.Cursor = cursor
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// 並び順と異なるカーソルは拒否する
+	page := domain.MemberPage{Sort: domain.MemberSortUsername, Limit: 2, Cursor: &domain.MemberCursor{Sort: domain.MemberSortJoinedAt, ID: "user-1"}}
+	if _, _, err := repo.FindMembers(ctx, domain.MemberFilter{}, page); !errors.Is(err, domain.ErrInvalidMemberPage) {
+		t.Errorf("Expected ErrInvalidMemberPage, got %v", err)
+	}
+}
+
+// TestBackfillMemberSearch は既存のユーザー・プロフィールから検索用の対応表とカラムが作成されることをテストします
+func TestBackfillMemberSearch(t *testing.T) {
+	db := setupUserTestDB(t)
+	if err := db.AutoMigrate(&Profile{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	// 対応表・公開範囲のカラムの導入前に保存されたデータを再現する
+	if err := db.Create(FromDomainUser(&domain.User{ID: "user-1", DiscordID: "discord-1", Username: "taro", GuildRoles: []string{"role-member"}})).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	profile := FromDomainProfile(&domain.Profile{
+		ID: "profile-1", UserID: "user-1", DiscordMessageID: "message-1", RealName: "じょぎ太郎",
+		Visibility: map[string]domain.FieldVisibility{domain.ProfileFieldRealName: domain.VisibilityHidden},
+	})
+	profile.RealNameVisibility = ""
+	if err := db.Create(profile).Error; err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+
+	if err := backfillMemberSearch(ctx, db); err != nil {
+		t.Fatalf("Failed to backfill: %v", err)
+	}
+
+	found, _, err := repo.FindMembers(ctx, domain.MemberFilter{RoleIDs: []string{"role-member"}}, domain.DefaultMemberPage(0, 0))
+	if err != nil {
+		t.Fatalf("Failed to find members: %v", err)
+	}
+	if len(found) != 1 {
+		t.Errorf("Expected 1 member with role, got %d", len(found))
+	}
+
+	viewer := &domain.ProfileViewer{UserID: "other", Member: true, Officer: true}
+	found, _, err = repo.FindMembers(ctx, domain.MemberFilter{Query: "じょぎ", Viewer: viewer}, domain.DefaultMemberPage(0, 0))
+	if err != nil {
+		t.Fatalf("Failed to find members: %v", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("Expected hidden real name not to match, got %d members", len(found))
+	}
+}
@@ -0,0 +1,209 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+type webhookEndpointRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookEndpointRepository は新しいGORM Webhook送信先リポジトリを作成します
+func NewWebhookEndpointRepository(db *gorm.DB) repository.WebhookEndpointRepository {
+	return &webhookEndpointRepository{db: db}
+}
+
+// Create は送信先を追加します
+func (r *webhookEndpointRepository) Create(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	if err := r.db.WithContext(ctx).Create(FromDomainWebhookEndpoint(endpoint)).Error; err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// GetByID はIDで送信先を取得します
+func (r *webhookEndpointRepository) GetByID(ctx context.Context, id string) (*domain.WebhookEndpoint, error) {
+	var e WebhookEndpoint
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&e).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: endpoint=%s", domain.ErrWebhookNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return e.ToDomain(), nil
+}
+
+// ListByClientID はクライアントの送信先を登録順に取得します
+func (r *webhookEndpointRepository) ListByClientID(ctx context.Context, clientID string) ([]*domain.WebhookEndpoint, error) {
+	var endpoints []WebhookEndpoint
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).Order("created_at").Order("id").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	return toDomainWebhookEndpoints(endpoints), nil
+}
+
+// ListActive は有効な送信先をすべて取得します
+func (r *webhookEndpointRepository) ListActive(ctx context.Context) ([]*domain.WebhookEndpoint, error) {
+	var endpoints []WebhookEndpoint
+	if err := r.db.WithContext(ctx).Where("active = ?", true).Order("created_at").Order("id").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to list active webhook endpoints: %w", err)
+	}
+	return toDomainWebhookEndpoints(endpoints), nil
+}
+
+// Update は送信先のURL・シークレット・購読する出来事・有効状態を更新します
+func (r *webhookEndpointRepository) Update(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	e := FromDomainWebhookEndpoint(endpoint)
+	result := r.db.WithContext(ctx).Model(&WebhookEndpoint{}).Where("id = ?", e.ID).Updates(map[string]interface{}{
+		"url":        e.URL,
+		"secret":     e.Secret,
+		"events":     e.Events,
+		"active":     e.Active,
+		"updated_at": e.UpdatedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: endpoint=%s", domain.ErrWebhookNotFound, e.ID)
+	}
+	return nil
+}
+
+// Delete は送信先とその配信の記録を削除します
+func (r *webhookEndpointRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", id).Delete(&WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		result := tx.Delete(&WebhookEndpoint{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete webhook endpoint: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: endpoint=%s", domain.ErrWebhookNotFound, id)
+		}
+		return nil
+	})
+}
+
+// toDomainWebhookEndpoints はGORMモデルの送信先をドメインモデルに変換します
+func toDomainWebhookEndpoints(endpoints []WebhookEndpoint) []*domain.WebhookEndpoint {
+	result := make([]*domain.WebhookEndpoint, len(endpoints))
+	for i := range endpoints {
+		result[i] = endpoints[i].ToDomain()
+	}
+	return result
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookDeliveryRepository は新しいGORM Webhook配信リポジトリを作成します
+func NewWebhookDeliveryRepository(db *gorm.DB) repository.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+// Create は配信を追加します
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	if err := r.db.WithContext(ctx).Create(FromDomainWebhookDelivery(delivery)).Error; err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetByID はIDで配信を取得します
+func (r *webhookDeliveryRepository) GetByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	var d WebhookDelivery
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: delivery=%s", domain.ErrWebhookNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return d.ToDomain(), nil
+}
+
+// ListByEndpointID は送信先の配信を新しい順に取得します
+func (r *webhookDeliveryRepository) ListByEndpointID(ctx context.Context, endpointID string, limit int) ([]*domain.WebhookDelivery, error) {
+	query := r.db.WithContext(ctx).Where("endpoint_id = ?", endpointID).Order("created_at DESC").Order("id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var deliveries []WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return toDomainWebhookDeliveries(deliveries), nil
+}
+
+// ClaimDue は送信日時を過ぎた配信待ちを最大limit件取得し、lease後まで他のワーカーから取得されないようにします
+// 候補の次の送信日時を条件付きで先送りし、更新できたものだけを返します（処理中に停止した場合はlease後に再試行されます）
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	var ids []string
+	if err := r.db.WithContext(ctx).Model(&WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", string(domain.WebhookDeliveryPending), now).
+		Order("next_attempt_at").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to find due webhook deliveries: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	claimID := uuid.New().String()
+	if err := r.db.WithContext(ctx).Model(&WebhookDelivery{}).
+		Where("id IN ? AND status = ? AND next_attempt_at <= ?", ids, string(domain.WebhookDeliveryPending), now).
+		Updates(map[string]interface{}{
+			"claim_id":        claimID,
+			"next_attempt_at": now.Add(lease),
+		}).Error; err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	var deliveries []WebhookDelivery
+	if err := r.db.WithContext(ctx).Where("claim_id = ?", claimID).Order("next_attempt_at").Order("created_at").Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get claimed webhook deliveries: %w", err)
+	}
+	return toDomainWebhookDeliveries(deliveries), nil
+}
+
+// Update は配信の状態・試行回数・次の送信日時・結果を更新します
+func (r *webhookDeliveryRepository) Update(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	d := FromDomainWebhookDelivery(delivery)
+	result := r.db.WithContext(ctx).Model(&WebhookDelivery{}).Where("id = ?", d.ID).Updates(map[string]interface{}{
+		"status":           d.Status,
+		"attempts":         d.Attempts,
+		"next_attempt_at":  d.NextAttemptAt,
+		"claim_id":         "",
+		"last_status_code": d.LastStatusCode,
+		"last_error":       d.LastError,
+		"completed_at":     d.CompletedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: delivery=%s", domain.ErrWebhookNotFound, d.ID)
+	}
+	return nil
+}
+
+// toDomainWebhookDeliveries はGORMモデルの配信をドメインモデルに変換します
+func toDomainWebhookDeliveries(deliveries []WebhookDelivery) []*domain.WebhookDelivery {
+	result := make([]*domain.WebhookDelivery, len(deliveries))
+	for i := range deliveries {
+		result[i] = deliveries[i].ToDomain()
+	}
+	return result
+}
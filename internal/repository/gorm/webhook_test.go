@@ -0,0 +1,170 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// setupWebhookTestDB はWebhookのテーブルを作成したインメモリDBを返します
+func setupWebhookTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&WebhookEndpoint{}, &WebhookDelivery{}); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+	return db
+}
+
+// TestWebhookEndpointRepository は送信先の追加・取得・更新・削除をテストします
+func TestWebhookEndpointRepository(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	repo := NewWebhookEndpointRepository(db)
+	deliveryRepo := NewWebhookDeliveryRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	endpoints := []*domain.WebhookEndpoint{
+		{ID: "ep-1", ClientID: "client-1", URL: "https://example.com/hook", Secret: "s1", Events: []domain.EventType{domain.EventUserCreated, domain.EventMemberLeft}, Active: true, CreatedAt: now, UpdatedAt: now},
+		{ID: "ep-2", ClientID: "client-1", URL: "https://example.com/other", Secret: "s2", Events: []domain.EventType{domain.EventTokenRevoked}, Active: false, CreatedAt: now.Add(time.Second), UpdatedAt: now},
+		{ID: "ep-3", ClientID: "client-2", URL: "https://example.org/hook", Secret: "s3", Events: []domain.EventType{domain.EventProfileUpdated}, Active: true, CreatedAt: now.Add(2 * time.Second), UpdatedAt: now},
+	}
+	for _, endpoint := range endpoints {
+		if err := repo.Create(ctx, endpoint); err != nil {
+			t.Fatalf("Failed to create endpoint: %v", err)
+		}
+	}
+
+	got, err := repo.GetByID(ctx, "ep-1")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint: %v", err)
+	}
+	if got.URL != "https://example.com/hook" || !slices.Equal(got.Events, endpoints[0].Events) || !got.Active {
+		t.Errorf("Unexpected endpoint: %+v", got)
+	}
+	if _, err := repo.GetByID(ctx, "missing"); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+	}
+
+	list, err := repo.ListByClientID(ctx, "client-1")
+	if err != nil {
+		t.Fatalf("Failed to list endpoints: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "ep-1" || list[1].ID != "ep-2" {
+		t.Errorf("Expected [ep-1 ep-2], got %d endpoints", len(list))
+	}
+
+	active, err := repo.ListActive(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list active endpoints: %v", err)
+	}
+	if len(active) != 2 || active[0].ID != "ep-1" || active[1].ID != "ep-3" {
+		t.Errorf("Expected active [ep-1 ep-3], got %d endpoints", len(active))
+	}
+
+	got.Secret = "rotated"
+	got.Active = false
+	got.Events = []domain.EventType{domain.EventUserUpdated}
+	got.UpdatedAt = now.Add(time.Minute)
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("Failed to update endpoint: %v", err)
+	}
+	updated, _ := repo.GetByID(ctx, "ep-1")
+	if updated.Secret != "rotated" || updated.Active || !slices.Equal(updated.Events, []domain.EventType{domain.EventUserUpdated}) {
+		t.Errorf("Unexpected updated endpoint: %+v", updated)
+	}
+
+	// 削除すると配信の記録も削除される
+	delivery := &domain.WebhookDelivery{ID: "d-1", EndpointID: "ep-1", EventID: "ev-1", EventType: domain.EventUserCreated, Status: domain.WebhookDeliveryPending, NextAttemptAt: now, CreatedAt: now}
+	if err := deliveryRepo.Create(ctx, delivery); err != nil {
+		t.Fatalf("Failed to create delivery: %v", err)
+	}
+	if err := repo.Delete(ctx, "ep-1"); err != nil {
+		t.Fatalf("Failed to delete endpoint: %v", err)
+	}
+	if _, err := deliveryRepo.GetByID(ctx, "d-1"); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("Expected delivery to be deleted, got %v", err)
+	}
+	if err := repo.Delete(ctx, "ep-1"); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+	}
+}
+
+// TestWebhookDeliveryRepository_ClaimDue は送信日時を過ぎた配信待ちのみが一度だけ取得されることをテストします
+func TestWebhookDeliveryRepository_ClaimDue(t *testing.T) {
+	db := setupWebhookTestDB(t)
+	repo := NewWebhookDeliveryRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	completed := now.Add(-time.Minute)
+	deliveries := []*domain.WebhookDelivery{
+		{ID: "due-1", EndpointID: "ep-1", EventID: "ev-1", EventType: domain.EventUserCreated, Payload: `{"id":"ev-1"}`, Status: domain.WebhookDeliveryPending, NextAttemptAt: now.Add(-2 * time.Minute), CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "due-2", EndpointID: "ep-1", EventID: "ev-2", EventType: domain.EventUserUpdated, Status: domain.WebhookDeliveryPending, NextAttemptAt: now.Add(-time.Minute), CreatedAt: now.Add(-time.Minute)},
+		{ID: "later", EndpointID: "ep-1", EventID: "ev-3", EventType: domain.EventUserUpdated, Status: domain.WebhookDeliveryPending, NextAttemptAt: now.Add(time.Hour), CreatedAt: now},
+		{ID: "done", EndpointID: "ep-1", EventID: "ev-4", EventType: domain.EventUserUpdated, Status: domain.WebhookDeliverySucceeded, Attempts: 1, NextAttemptAt: now.Add(-time.Hour), CreatedAt: now.Add(-time.Hour), CompletedAt: &completed},
+	}
+	for _, delivery := range deliveries {
+		if err := repo.Create(ctx, delivery); err != nil {
+			t.Fatalf("Failed to create delivery: %v", err)
+		}
+	}
+
+	claimed, err := repo.ClaimDue(ctx, now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("Failed to claim deliveries: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != "due-1" || claimed[1].ID != "due-2" {
+		t.Fatalf("Expected [due-1 due-2], got %d deliveries", len(claimed))
+	}
+	if claimed[0].Payload != `{"id":"ev-1"}` {
+		t.Errorf("Expected payload to be loaded, got %q", claimed[0].Payload)
+	}
+
+	// 取得済みの配信はleaseの間は再び取得されない
+	again, err := repo.ClaimDue(ctx, now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("Failed to claim deliveries: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("Expected no deliveries while leased, got %d", len(again))
+	}
+
+	// 結果を記録すると次の送信日時で再び取得される
+	retry := claimed[1]
+	retry.Attempts = 1
+	retry.LastStatusCode = 500
+	retry.LastError = "server error"
+	retry.NextAttemptAt = now.Add(30 * time.Second)
+	if err := repo.Update(ctx, retry); err != nil {
+		t.Fatalf("Failed to update delivery: %v", err)
+	}
+	later, err := repo.ClaimDue(ctx, now.Add(45*time.Second), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("Failed to claim deliveries: %v", err)
+	}
+	if len(later) != 1 || later[0].ID != "due-2" || later[0].Attempts != 1 || later[0].LastStatusCode != 500 {
+		t.Errorf("Expected due-2 to be retried, got %+v", later)
+	}
+
+	list, err := repo.ListByEndpointID(ctx, "ep-1", 3)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if len(list) != 3 || list[0].ID != "later" {
+		t.Errorf("Expected 3 deliveries starting with later, got %d", len(list))
+	}
+}
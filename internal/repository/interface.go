@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
 )
@@ -14,6 +15,9 @@ type UserRepository interface {
 	Update(ctx context.Context, user *domain.User) error
 	Delete(ctx context.Context, id string) error
 	GetAll(ctx context.Context, limit, offset int) ([]*domain.User, error)
+	FindMembers(ctx context.Context, filter domain.MemberFilter, page domain.MemberPage) ([]*domain.User, *domain.MemberCursor, error)
+	CountMembers(ctx context.Context, filter domain.MemberFilter) (int64, error)
+	MarkLeftExcept(ctx context.Context, discordIDs []string) ([]*domain.User, error)
 }
 
 // SessionRepository はセッションデータアクセスのインターフェースを定義します
@@ -38,6 +42,28 @@ type ClientRepository interface {
 	ValidateRedirectURI(ctx context.Context, clientID, redirectURI string) (bool, error)
 }
 
+// ClientSecretRepository はクライアントのClient Secretデータアクセスのインターフェースを定義します
+type ClientSecretRepository interface {
+	Create(ctx context.Context, secret *domain.ClientSecret) error
+	// ListByClientID はクライアントのClient Secretを新しい順に取得します（期限切れのものを含みます）
+	ListByClientID(ctx context.Context, clientID string) ([]*domain.ClientSecret, error)
+	// UpdateExpiresAt は有効期限を変更します
+	UpdateExpiresAt(ctx context.Context, id string, expiresAt time.Time) error
+	// UpdateLastUsed は最終使用日時を記録します
+	UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error
+	// Delete はクライアントのClient Secretを削除します（見つからない場合はErrClientSecretNotFound）
+	Delete(ctx context.Context, clientID, id string) error
+	// DeleteByClientID はクライアントのClient Secretをすべて削除します
+	DeleteByClientID(ctx context.Context, clientID string) error
+}
+
+// ClientAssertionRepository はprivate_key_jwtのclient_assertionのjtiの記録のインターフェースを定義します
+type ClientAssertionRepository interface {
+	// Record はjtiを有効期限まで記録します（有効期限内に同じjtiが記録済みの場合はErrClientAssertionReplayed）
+	// 同じクライアントの期限切れの記録は削除します
+	Record(ctx context.Context, clientID, jti string, expiresAt time.Time) error
+}
+
 // AuthCodeRepository は認可コードデータアクセスのインターフェースを定義します
 type AuthCodeRepository interface {
 	Create(ctx context.Context, authCode *domain.AuthCode) error
@@ -46,12 +72,39 @@ type AuthCodeRepository interface {
 	DeleteExpired(ctx context.Context) error
 }
 
+// DeviceCodeRepository はデバイス認可（RFC 8628）のデバイスコードデータアクセスのインターフェースを定義します
+type DeviceCodeRepository interface {
+	Create(ctx context.Context, deviceCode *domain.DeviceCode) error
+	// GetByDeviceCode はデバイスコードで取得します（見つからない場合はErrDeviceCodeNotFound）
+	GetByDeviceCode(ctx context.Context, deviceCode string) (*domain.DeviceCode, error)
+	// GetByUserCode は正規化済みのユーザーコードで期限内のものを取得します（見つからない場合はErrDeviceCodeNotFound）
+	GetByUserCode(ctx context.Context, userCode string) (*domain.DeviceCode, error)
+	// UpdatePolling は最後にポーリングされた日時とポーリングの間隔を記録します
+	UpdatePolling(ctx context.Context, id string, polledAt time.Time, interval time.Duration) error
+	// UpdateStatus は状態がfromの場合のみtoに変更します（userIDが空でなければ承認・拒否したユーザーも記録します）
+	// 状態がfromでない場合はErrDeviceCodeNotFoundを返します
+	UpdateStatus(ctx context.Context, id string, from, to domain.DeviceCodeStatus, userID string) error
+	DeleteExpired(ctx context.Context) error
+}
+
+// InitialAccessTokenRepository はクライアントの動的登録に使用する初期アクセストークンのデータアクセスのインターフェースを定義します
+type InitialAccessTokenRepository interface {
+	Create(ctx context.Context, token *domain.InitialAccessToken) error
+	// GetByTokenHash はトークンのハッシュで取得します（見つからない場合はErrInvalidRegistrationToken）
+	GetByTokenHash(ctx context.Context, tokenHash string) (*domain.InitialAccessToken, error)
+	// MarkUsed は未使用の場合のみ使用済みにします（使用済みの場合はErrInvalidRegistrationToken）
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) error
+	DeleteExpired(ctx context.Context) error
+}
+
 // TokenRepository はトークンデータアクセスのインターフェースを定義します
 type TokenRepository interface {
 	Create(ctx context.Context, token *domain.Token) error
 	GetByToken(ctx context.Context, token string) (*domain.Token, error)
 	GetByUserID(ctx context.Context, userID string) ([]*domain.Token, error)
 	Revoke(ctx context.Context, token string) error
+	// ListRevokedJWTAccessTokens は取り消したJWT形式のアクセストークンのうち、nowの時点で期限切れでないものを取得します
+	ListRevokedJWTAccessTokens(ctx context.Context, now time.Time) ([]*domain.Token, error)
 	DeleteExpired(ctx context.Context) error
 }
 
@@ -63,7 +116,110 @@ type ProfileRepository interface {
 	GetByMessageID(ctx context.Context, messageID string) (*domain.Profile, error)
 	GetByUserIDs(ctx context.Context, userIDs []string) ([]*domain.Profile, error)
 	GetAll(ctx context.Context) ([]*domain.Profile, error)
+	ListByUserID(ctx context.Context, userID string) ([]*domain.Profile, error)
 	Update(ctx context.Context, profile *domain.Profile) error
 	Upsert(ctx context.Context, profile *domain.Profile) error
+	Tombstone(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// IntroMessageRepository は自己紹介メッセージデータアクセスのインターフェースを定義します
+type IntroMessageRepository interface {
+	Upsert(ctx context.Context, message *domain.IntroMessage) error
+	GetByID(ctx context.Context, id string) (*domain.IntroMessage, error)
+	GetActiveByUserID(ctx context.Context, userID string) ([]*domain.IntroMessage, error)
+	GetActiveByChannelID(ctx context.Context, channelID string) ([]*domain.IntroMessage, error)
+	MarkDeleted(ctx context.Context, id string) error
+}
+
+// RoleRepository はロールデータアクセスのインターフェースを定義します
+type RoleRepository interface {
+	Upsert(ctx context.Context, role *domain.Role) error
+	GetAll(ctx context.Context) ([]*domain.Role, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*domain.Role, error)
+	DeleteExcept(ctx context.Context, ids []string) error
+}
+
+// SyncCursorRepository は同期カーソルデータアクセスのインターフェースを定義します
+type SyncCursorRepository interface {
+	GetByChannelID(ctx context.Context, channelID string) (*domain.SyncCursor, error)
+	Upsert(ctx context.Context, cursor *domain.SyncCursor) error
+}
+
+// ChangeHistoryRepository はプロフィール・ユーザー情報の変更履歴データアクセスのインターフェースを定義します
+type ChangeHistoryRepository interface {
+	// Create は変更履歴を追加します（Versionはユーザーと対象ごとに採番されます）
+	Create(ctx context.Context, record *domain.ChangeRecord) error
+	// List は変更履歴を新しい順に取得します（userIDが空の場合は全ユーザー）
+	List(ctx context.Context, userID string, limit, offset int) ([]*domain.ChangeRecord, error)
+	// ListSince は(since, afterID)より後に記録された変更履歴を古い順に取得します（記録日時・IDの順）
+	ListSince(ctx context.Context, since time.Time, afterID string, limit int) ([]*domain.ChangeRecord, error)
+}
+
+// ProfileSearchRepository はプロフィールの全文検索の索引データアクセスのインターフェースを定義します
+type ProfileSearchRepository interface {
+	// Replace はユーザーの索引をpostingsで置き換えます
+	Replace(ctx context.Context, userID string, postings []*domain.SearchPosting) error
+	DeleteByUserID(ctx context.Context, userID string) error
+	// FindByTerms はいずれかの語を含む索引を取得します
+	FindByTerms(ctx context.Context, terms []string) ([]*domain.SearchPosting, error)
+	// CountUsers は索引に登録されているユーザー数を返します
+	CountUsers(ctx context.Context) (int64, error)
+}
+
+// AuditLogRepository は監査ログデータアクセスのインターフェースを定義します
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *domain.AuditEntry) error
+	// List は監査ログを新しい順に取得します（actionが空の場合は全ての操作）
+	List(ctx context.Context, action string, limit, offset int) ([]*domain.AuditEntry, error)
+}
+
+// WebhookEndpointRepository はWebhookの送信先データアクセスのインターフェースを定義します
+type WebhookEndpointRepository interface {
+	Create(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	GetByID(ctx context.Context, id string) (*domain.WebhookEndpoint, error)
+	// ListByClientID はクライアントの送信先を登録順に取得します
+	ListByClientID(ctx context.Context, clientID string) ([]*domain.WebhookEndpoint, error)
+	// ListActive は有効な送信先をすべて取得します
+	ListActive(ctx context.Context) ([]*domain.WebhookEndpoint, error)
+	Update(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	// Delete は送信先とその配信の記録を削除します
+	Delete(ctx context.Context, id string) error
+}
+
+// WebhookDeliveryRepository はWebhookの配信データアクセスのインターフェースを定義します
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetByID(ctx context.Context, id string) (*domain.WebhookDelivery, error)
+	// ListByEndpointID は送信先の配信を新しい順に取得します
+	ListByEndpointID(ctx context.Context, endpointID string, limit int) ([]*domain.WebhookDelivery, error)
+	// ClaimDue は送信日時を過ぎた配信待ちを最大limit件取得し、lease後まで他のワーカーから取得されないようにします
+	// 複数のプロセスが同時に呼び出しても、同じ配信は1つのプロセスにのみ返されます
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error)
+	// Update は配信の状態・試行回数・次の送信日時・結果を更新します
+	Update(ctx context.Context, delivery *domain.WebhookDelivery) error
+}
+
+// SAMLServiceProviderRepository はSAMLのサービス（SP）データアクセスのインターフェースを定義します
+type SAMLServiceProviderRepository interface {
+	Create(ctx context.Context, sp *domain.SAMLServiceProvider) error
+	// GetByEntityID はエンティティIDでサービスを取得します（見つからない場合はErrSAMLServiceProviderNotFound）
+	GetByEntityID(ctx context.Context, entityID string) (*domain.SAMLServiceProvider, error)
+	// GetAll はすべてのサービスを登録順に取得します
+	GetAll(ctx context.Context) ([]*domain.SAMLServiceProvider, error)
+	Update(ctx context.Context, sp *domain.SAMLServiceProvider) error
 	Delete(ctx context.Context, id string) error
 }
+
+// AppPasswordRepository はアプリパスワードデータアクセスのインターフェースを定義します
+type AppPasswordRepository interface {
+	Create(ctx context.Context, password *domain.AppPassword) error
+	// GetByID はIDでアプリパスワードを取得します（見つからない場合はErrAppPasswordNotFound）
+	GetByID(ctx context.Context, id string) (*domain.AppPassword, error)
+	// ListByUserID はユーザーのアプリパスワードを新しい順に取得します
+	ListByUserID(ctx context.Context, userID string) ([]*domain.AppPassword, error)
+	// UpdateLastUsed は最終使用日時を記録します
+	UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error
+	// Delete はユーザーのアプリパスワードを削除します（見つからない場合はErrAppPasswordNotFound）
+	Delete(ctx context.Context, userID, id string) error
+}
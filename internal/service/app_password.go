@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+)
+
+// appPasswordAlphabet はアプリパスワードに使用する文字です（読み間違えやすい0・1・l・oを除く）
+const appPasswordAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+const (
+	// appPasswordMaxFailures は認証を一時的に停止するまでに許容する、ユーザーごとの連続した失敗の回数です
+	appPasswordMaxFailures = 10
+	// appPasswordLockout は失敗が続いたユーザーのアプリパスワードの認証を停止する時間です
+	appPasswordLockout = 15 * time.Minute
+	// appPasswordVerifiedTTL は検証に成功したアプリパスワードのbcryptの検証を省略する時間です
+	// フォワード認証・認証プロキシではリクエストのたびにBasic認証のパスワードを検証するため、結果を再利用します
+	appPasswordVerifiedTTL = 5 * time.Minute
+)
+
+// AppPasswordService はアプリパスワードの発行・取り消し・認証を提供します
+type AppPasswordService struct {
+	passwordRepo repository.AppPasswordRepository
+	userRepo     repository.UserRepository
+
+	mu       sync.Mutex
+	failures map[string]*appPasswordFailures // ユーザーIDをキーとする
+	verified map[string]time.Time            // 検証に成功したパスワードのSHA-256をキーとし、再利用できる期限を値とする
+}
+
+// appPasswordFailures はユーザーのアプリパスワードの認証に連続して失敗した記録です
+type appPasswordFailures struct {
+	count        int
+	lastFailedAt time.Time
+	lockedUntil  time.Time
+}
+
+// NewAppPasswordService は新しいAppPasswordServiceを作成します
+func NewAppPasswordService(passwordRepo repository.AppPasswordRepository, userRepo repository.UserRepository) *AppPasswordService {
+	return &AppPasswordService{
+		passwordRepo: passwordRepo,
+		userRepo:     userRepo,
+		failures:     make(map[string]*appPasswordFailures),
+		verified:     make(map[string]time.Time),
+	}
+}
+
+// Create はアプリパスワードを発行し、平文のパスワードを返します（平文は保存しないため再表示できません）
+// expiresInが0の場合は無期限です
+func (s *AppPasswordService) Create(ctx context.Context, userID, name string, scopes []string, expiresIn time.Duration) (*domain.AppPassword, string, error) {
+	existing, err := s.passwordRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list app passwords: %w", err)
+	}
+	if len(existing) >= domain.MaxAppPasswordsPerUser {
+		return nil, "", fmt.Errorf("%w: up to %d app passwords can be created", domain.ErrInvalidAppPassword, domain.MaxAppPasswordsPerUser)
+	}
+
+	secret, err := generateAppPassword()
+	if err != nil {
+		return nil, "", err
+	}
+	hashed, err := auth.HashClientSecret(secret)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash app password: %w", err)
+	}
+
+	now := time.Now()
+	password := &domain.AppPassword{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		Name:           strings.TrimSpace(name),
+		Scopes:         scopes,
+		HashedPassword: hashed,
+		CreatedAt:      now,
+	}
+	if expiresIn != 0 {
+		expiresAt := now.Add(expiresIn)
+		password.ExpiresAt = &expiresAt
+	}
+	if err := password.Validate(); err != nil {
+		return nil, "", err
+	}
+	if err := s.passwordRepo.Create(ctx, password); err != nil {
+		return nil, "", fmt.Errorf("failed to create app password: %w", err)
+	}
+	return password, domain.FormatAppPassword(password.ID, secret), nil
+}
+
+// List はユーザーのアプリパスワードを新しい順に返します
+func (s *AppPasswordService) List(ctx context.Context, userID string) ([]*domain.AppPassword, error) {
+	passwords, err := s.passwordRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list app passwords: %w", err)
+	}
+	return passwords, nil
+}
+
+// Revoke はユーザーのアプリパスワードを取り消します
+func (s *AppPasswordService) Revoke(ctx context.Context, userID, id string) error {
+	if err := s.passwordRepo.Delete(ctx, userID, id); err != nil {
+		return fmt.Errorf("failed to revoke app password: %w", err)
+	}
+	return nil
+}
+
+// Authenticate はユーザー名とアプリパスワードで在籍中のメンバーを認証します
+// scopeを持ち、有効期限内のアプリパスワードのみ使用できます
+// パスワードのIDで取得した1件だけをbcryptで検証し、失敗が続いたユーザーは一定時間認証を停止します
+// ユーザーが存在しない・退出した・パスワードが一致しない場合はErrUnauthenticatedを返します
+func (s *AppPasswordService) Authenticate(ctx context.Context, username, password, scope string) (*domain.User, error) {
+	errInvalid := fmt.Errorf("%w: invalid username or app password", domain.ErrUnauthenticated)
+	id, secret, ok := domain.ParseAppPassword(password)
+	if !ok {
+		return nil, errInvalid
+	}
+
+	// IDは推測できないため、ユーザーの有無によらずIDが一致しない場合はbcryptの検証を行わない
+	user, err := s.findMember(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.passwordRepo.GetByID(ctx, id)
+	if errors.Is(err, domain.ErrAppPasswordNotFound) {
+		return nil, errInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app password: %w", err)
+	}
+	now := time.Now()
+	if user == nil || p.UserID != user.ID || !p.HasScope(scope) || p.IsExpired(now) {
+		return nil, errInvalid
+	}
+
+	key := appPasswordKey(password)
+	if s.isVerified(key, now) {
+		return user, nil
+	}
+	if s.isLockedOut(user.ID, now) {
+		return nil, fmt.Errorf("%w: too many failed attempts for user %s", domain.ErrUnauthenticated, user.ID)
+	}
+	if auth.ValidateClientSecret(secret, p.HashedPassword) != nil {
+		s.recordFailure(user.ID, now)
+		return nil, errInvalid
+	}
+	s.recordSuccess(user.ID, key, now)
+
+	if err := s.passwordRepo.UpdateLastUsed(ctx, p.ID, now); err != nil {
+		log.Printf("Failed to record app password usage: %v", err)
+	}
+	return user, nil
+}
+
+// AuthenticateLDAP はLDAPのBindでユーザー名とldapスコープのアプリパスワードを検証します（LDAPAuthenticatorを実装します）
+func (s *AppPasswordService) AuthenticateLDAP(ctx context.Context, username, password string) (*domain.User, error) {
+	return s.Authenticate(ctx, username, password, domain.AppPasswordScopeLDAP)
+}
+
+// isVerified はパスワードが最近の検証に成功しており、bcryptの検証を省略できるかどうかを返します
+func (s *AppPasswordService) isVerified(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.verified[key]
+	return ok && now.Before(expiresAt)
+}
+
+// isLockedOut はユーザーのアプリパスワードの認証が停止されているかどうかを返します
+func (s *AppPasswordService) isLockedOut(userID string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.failures[userID]
+	return ok && now.Before(f.lockedUntil)
+}
+
+// recordFailure は認証の失敗を記録し、連続した失敗が上限に達したユーザーの認証を停止します
+func (s *AppPasswordService) recordFailure(userID string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, f := range s.failures {
+		if now.Sub(f.lastFailedAt) >= appPasswordLockout && !now.Before(f.lockedUntil) {
+			delete(s.failures, id)
+		}
+	}
+
+	f, ok := s.failures[userID]
+	if !ok {
+		f = &appPasswordFailures{}
+		s.failures[userID] = f
+	}
+	f.count++
+	f.lastFailedAt = now
+	if f.count >= appPasswordMaxFailures {
+		log.Printf("Warning: App password authentication for user %s is locked for %s after %d failed attempts", userID, appPasswordLockout, f.count)
+		f.count = 0
+		f.lockedUntil = now.Add(appPasswordLockout)
+	}
+}
+
+// recordSuccess はユーザーの失敗の記録を消去し、検証に成功したパスワードを一定時間再利用できるようにします
+func (s *AppPasswordService) recordSuccess(userID, key string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, userID)
+	for k, expiresAt := range s.verified {
+		if !now.Before(expiresAt) {
+			delete(s.verified, k)
+		}
+	}
+	s.verified[key] = now.Add(appPasswordVerifiedTTL)
+}
+
+// appPasswordKey は検証に成功したパスワードを記録するためのキー（平文のSHA-256）を返します
+func appPasswordKey(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// findMember はユーザー名が一致する在籍中のメンバーを返します（見つからない場合はnil）
+func (s *AppPasswordService) findMember(ctx context.Context, username string) (*domain.User, error) {
+	if username == "" {
+		return nil, nil
+	}
+	users, _, err := s.userRepo.FindMembers(ctx, domain.MemberFilter{Usernames: usernameCandidates(username)}, domain.MemberPage{Sort: domain.MemberSortUsername})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Username, username) && user.IsGuildMember() {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// generateAppPassword は「xxxx-xxxx-xxxx-xxxx-xxxx」形式（約100ビット）のアプリパスワードの秘密の部分を生成します
+func generateAppPassword() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate app password: %w", err)
+	}
+	var password strings.Builder
+	for i, c := range b {
+		if i > 0 && i%4 == 0 {
+			password.WriteByte('-')
+		}
+		password.WriteByte(appPasswordAlphabet[int(c)%len(appPasswordAlphabet)])
+	}
+	return password.String(), nil
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+func TestAppPasswordService(t *testing.T) {
+	ctx := context.Background()
+
+	userRepo := newMockUserRepository()
+	passwordRepo := newMockAppPasswordRepository()
+	leftAt := time.Now()
+	member := &domain.User{ID: "user-1", DiscordID: "d-1", Username: "taro"}
+	left := &domain.User{ID: "user-2", DiscordID: "d-2", Username: "jiro", LeftAt: &leftAt}
+	userRepo.Create(ctx, member)
+	userRepo.Create(ctx, left)
+
+	service := NewAppPasswordService(passwordRepo, userRepo)
+
+	password, plain, err := service.Create(ctx, member.ID, "  Gitea  ", []string{domain.AppPasswordScopeLDAP, domain.AppPasswordScopeProfile}, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if password.Name != "Gitea" {
+		t.Errorf("Expected trimmed name, got %q", password.Name)
+	}
+	if !regexp.MustCompile(`^` + regexp.QuoteMeta(password.ID) + `\.[a-z2-9]{4}(-[a-z2-9]{4}){4}$`).MatchString(plain) {
+		t.Errorf("Unexpected app password format: %q", plain)
+	}
+	if strings.Contains(password.HashedPassword, plain) {
+		t.Error("Expected the app password to be stored hashed")
+	}
+
+	t.Run("正しいパスワード", func(t *testing.T) {
+		user, err := service.Authenticate(ctx, "Taro", plain, domain.AppPasswordScopeLDAP)
+		if err != nil {
+			t.Fatalf("Authenticate failed: %v", err)
+		}
+		if user.ID != member.ID {
+			t.Errorf("Expected %s, got %s", member.ID, user.ID)
+		}
+		if passwordRepo.passwords[password.ID].LastUsedAt == nil {
+			t.Error("Expected last used time to be recorded")
+		}
+	})
+
+	t.Run("誤ったパスワード", func(t *testing.T) {
+		for _, tt := range []struct{ username, password string }{
+			{"taro", "wrong"},
+			{"taro", ""},
+			{"taro", password.ID + ".wrong"},
+			{"taro", "unknown." + strings.SplitN(plain, ".", 2)[1]},
+			{"hanako", plain},
+			{"", plain},
+		} {
+			if _, err := service.Authenticate(ctx, tt.username, tt.password, domain.AppPasswordScopeLDAP); !errors.Is(err, domain.ErrUnauthenticated) {
+				t.Errorf("Authenticate(%q, %q): expected ErrUnauthenticated, got %v", tt.username, tt.password, err)
+			}
+		}
+	})
+
+	t.Run("スコープ", func(t *testing.T) {
+		if _, err := service.Authenticate(ctx, "taro", plain, domain.AppPasswordScopeProfile); err != nil {
+			t.Errorf("Expected profile scope to be accepted, got %v", err)
+		}
+		for _, scope := range []string{domain.AppPasswordScopeMembers, domain.AppPasswordScopeProxy} {
+			if _, err := service.Authenticate(ctx, "taro", plain, scope); !errors.Is(err, domain.ErrUnauthenticated) {
+				t.Errorf("Expected ErrUnauthenticated for scope %s, got %v", scope, err)
+			}
+		}
+
+		// LDAPのBindにはldapスコープが必要
+		if _, err := service.AuthenticateLDAP(ctx, "taro", plain); err != nil {
+			t.Errorf("Expected ldap scope to be accepted for LDAP binds, got %v", err)
+		}
+		proxyOnly, proxyPlain, err := service.Create(ctx, member.ID, "git", []string{domain.AppPasswordScopeProxy}, 0)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, err := service.AuthenticateLDAP(ctx, "taro", proxyPlain); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated for an LDAP bind without ldap scope, got %v", err)
+		}
+		if err := service.Revoke(ctx, member.ID, proxyOnly.ID); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+	})
+
+	t.Run("有効期限", func(t *testing.T) {
+		expiring, expiringPlain, err := service.Create(ctx, member.ID, "CI", []string{domain.AppPasswordScopeProxy}, time.Hour)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if expiring.ExpiresAt == nil || expiring.ExpiresAt.Sub(expiring.CreatedAt) != time.Hour {
+			t.Fatalf("Unexpected expiry: %v", expiring.ExpiresAt)
+		}
+		if _, err := service.Authenticate(ctx, "taro", expiringPlain, domain.AppPasswordScopeProxy); err != nil {
+			t.Errorf("Expected unexpired password to be accepted, got %v", err)
+		}
+		past := time.Now().Add(-time.Minute)
+		passwordRepo.passwords[expiring.ID].ExpiresAt = &past
+		if _, err := service.Authenticate(ctx, "taro", expiringPlain, domain.AppPasswordScopeProxy); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected expired password to be rejected, got %v", err)
+		}
+		if err := service.Revoke(ctx, member.ID, expiring.ID); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+	})
+
+	t.Run("退出したメンバー", func(t *testing.T) {
+		_, leftPlain, err := service.Create(ctx, left.ID, "Wiki", []string{domain.AppPasswordScopeLDAP}, 0)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, err := service.Authenticate(ctx, left.Username, leftPlain, domain.AppPasswordScopeLDAP); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated for a member who left, got %v", err)
+		}
+	})
+
+	t.Run("検証結果の再利用", func(t *testing.T) {
+		_, cachedPlain, err := service.Create(ctx, member.ID, "Proxy", []string{domain.AppPasswordScopeProxy}, 0)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		id, _, _ := domain.ParseAppPassword(cachedPlain)
+		if _, err := service.Authenticate(ctx, "taro", cachedPlain, domain.AppPasswordScopeProxy); err != nil {
+			t.Fatalf("Authenticate failed: %v", err)
+		}
+
+		// 検証に成功したパスワードはbcryptの検証を省略する（ハッシュを差し替えても受け付ける）
+		hashed := passwordRepo.passwords[id].HashedPassword
+		passwordRepo.passwords[id].HashedPassword = "invalid"
+		if _, err := service.Authenticate(ctx, "taro", cachedPlain, domain.AppPasswordScopeProxy); err != nil {
+			t.Errorf("Expected the verified password to be reused, got %v", err)
+		}
+		passwordRepo.passwords[id].HashedPassword = hashed
+
+		// 期限を過ぎた記録は使用しない
+		service.verified[appPasswordKey(cachedPlain)] = time.Now().Add(-time.Second)
+		passwordRepo.passwords[id].HashedPassword = "invalid"
+		if _, err := service.Authenticate(ctx, "taro", cachedPlain, domain.AppPasswordScopeProxy); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected the expired verification to be ignored, got %v", err)
+		}
+		passwordRepo.passwords[id].HashedPassword = hashed
+
+		// 取り消し・スコープはリポジトリの値で毎回確認する
+		if _, err := service.Authenticate(ctx, "taro", cachedPlain, domain.AppPasswordScopeLDAP); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated for another scope, got %v", err)
+		}
+		if err := service.Revoke(ctx, member.ID, id); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+		if _, err := service.Authenticate(ctx, "taro", cachedPlain, domain.AppPasswordScopeProxy); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected revoked password to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("連続した失敗", func(t *testing.T) {
+		_, lockedPlain, err := service.Create(ctx, member.ID, "Locked", []string{domain.AppPasswordScopeLDAP}, 0)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		id, _, _ := domain.ParseAppPassword(lockedPlain)
+		for i := 0; i < appPasswordMaxFailures; i++ {
+			if _, err := service.Authenticate(ctx, "taro", id+".wrong", domain.AppPasswordScopeLDAP); !errors.Is(err, domain.ErrUnauthenticated) {
+				t.Fatalf("Expected ErrUnauthenticated, got %v", err)
+			}
+		}
+		// 認証を停止している間は正しいパスワードも受け付けない
+		if _, err := service.Authenticate(ctx, "taro", lockedPlain, domain.AppPasswordScopeLDAP); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated while locked out, got %v", err)
+		}
+		if passwordRepo.passwords[id].LastUsedAt != nil {
+			t.Error("Expected the password not to be used while locked out")
+		}
+
+		service.failures[member.ID].lockedUntil = time.Now().Add(-time.Second)
+		if _, err := service.Authenticate(ctx, "taro", lockedPlain, domain.AppPasswordScopeLDAP); err != nil {
+			t.Errorf("Expected the password to be accepted after the lockout, got %v", err)
+		}
+		if _, ok := service.failures[member.ID]; ok {
+			t.Error("Expected the failures to be cleared after a success")
+		}
+		if err := service.Revoke(ctx, member.ID, id); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+	})
+
+	t.Run("取り消し", func(t *testing.T) {
+		if err := service.Revoke(ctx, left.ID, password.ID); !errors.Is(err, domain.ErrAppPasswordNotFound) {
+			t.Errorf("Expected ErrAppPasswordNotFound for another user's password, got %v", err)
+		}
+		if err := service.Revoke(ctx, member.ID, password.ID); err != nil {
+			t.Fatalf("Revoke failed: %v", err)
+		}
+		if _, err := service.Authenticate(ctx, member.Username, plain, domain.AppPasswordScopeLDAP); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Errorf("Expected revoked password to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("名前・スコープと上限", func(t *testing.T) {
+		ldapOnly := []string{domain.AppPasswordScopeLDAP}
+		if _, _, err := service.Create(ctx, member.ID, " ", ldapOnly, 0); !errors.Is(err, domain.ErrInvalidAppPassword) {
+			t.Errorf("Expected ErrInvalidAppPassword for empty name, got %v", err)
+		}
+		if _, _, err := service.Create(ctx, member.ID, "app", nil, 0); !errors.Is(err, domain.ErrInvalidAppPassword) {
+			t.Errorf("Expected ErrInvalidAppPassword without scopes, got %v", err)
+		}
+		if _, _, err := service.Create(ctx, member.ID, "app", []string{"admin"}, 0); !errors.Is(err, domain.ErrInvalidAppPassword) {
+			t.Errorf("Expected ErrInvalidAppPassword for unknown scope, got %v", err)
+		}
+		for i := 0; i < domain.MaxAppPasswordsPerUser; i++ {
+			if _, _, err := service.Create(ctx, member.ID, "app", ldapOnly, 0); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+		}
+		if _, _, err := service.Create(ctx, member.ID, "app", ldapOnly, 0); !errors.Is(err, domain.ErrInvalidAppPassword) {
+			t.Errorf("Expected ErrInvalidAppPassword over the limit, got %v", err)
+		}
+	})
+}
+
+// モックAppPasswordRepository
+type mockAppPasswordRepository struct {
+	passwords map[string]*domain.AppPassword
+}
+
+func newMockAppPasswordRepository() *mockAppPasswordRepository {
+	return &mockAppPasswordRepository{passwords: make(map[string]*domain.AppPassword)}
+}
+
+func (m *mockAppPasswordRepository) Create(ctx context.Context, password *domain.AppPassword) error {
+	if err := password.Validate(); err != nil {
+		return err
+	}
+	m.passwords[password.ID] = password
+	return nil
+}
+
+func (m *mockAppPasswordRepository) GetByID(ctx context.Context, id string) (*domain.AppPassword, error) {
+	p, ok := m.passwords[id]
+	if !ok {
+		return nil, domain.ErrAppPasswordNotFound
+	}
+	return p, nil
+}
+
+func (m *mockAppPasswordRepository) ListByUserID(ctx context.Context, userID string) ([]*domain.AppPassword, error) {
+	var passwords []*domain.AppPassword
+	for _, p := range m.passwords {
+		if p.UserID == userID {
+			passwords = append(passwords, p)
+		}
+	}
+	return passwords, nil
+}
+
+func (m *mockAppPasswordRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	if p, ok := m.passwords[id]; ok {
+		p.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+func (m *mockAppPasswordRepository) Delete(ctx context.Context, userID, id string) error {
+	p, ok := m.passwords[id]
+	if !ok || p.UserID != userID {
+		return domain.ErrAppPasswordNotFound
+	}
+	delete(m.passwords, id)
+	return nil
+}
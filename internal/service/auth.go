@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,11 +18,14 @@ import (
 
 // AuthService は認証サービスを表します
 type AuthService struct {
-	discordClient *discord.Client
-	userRepo      repository.UserRepository
-	sessionRepo   repository.SessionRepository
-	profileRepo   repository.ProfileRepository
-	guildID       string
+	discordClient  *discord.Client
+	userRepo       repository.UserRepository
+	sessionRepo    repository.SessionRepository
+	profileRepo    repository.ProfileRepository
+	roleRepo       repository.RoleRepository
+	historyRepo    repository.ChangeHistoryRepository
+	guildID        string
+	officerRoleIDs []string // 幹部として扱うDiscordロールID
 }
 
 // NewAuthService は新しい認証サービスを作成します
@@ -30,14 +34,20 @@ func NewAuthService(
 	userRepo repository.UserRepository,
 	sessionRepo repository.SessionRepository,
 	profileRepo repository.ProfileRepository,
+	roleRepo repository.RoleRepository,
+	historyRepo repository.ChangeHistoryRepository,
 	guildID string,
+	officerRoleIDs []string,
 ) *AuthService {
 	return &AuthService{
-		discordClient: discordClient,
-		userRepo:      userRepo,
-		sessionRepo:   sessionRepo,
-		profileRepo:   profileRepo,
-		guildID:       guildID,
+		discordClient:  discordClient,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		profileRepo:    profileRepo,
+		roleRepo:       roleRepo,
+		historyRepo:    historyRepo,
+		guildID:        guildID,
+		officerRoleIDs: officerRoleIDs,
 	}
 }
 
@@ -110,60 +120,37 @@ func (s *AuthService) upsertUser(ctx context.Context, discordUser *discord.User,
 
 	now := time.Now()
 
-	// GuildMember情報から追加フィールドを取得
-	var guildNickname *string
-	if guildMember.Nick != nil && *guildMember.Nick != "" {
-		guildNickname = guildMember.Nick
-	}
-
-	var joinedAt *time.Time
-	if guildMember.JoinedAt != "" {
-		if parsedTime, err := time.Parse(time.RFC3339, guildMember.JoinedAt); err == nil {
-			joinedAt = &parsedTime
-		}
-	}
-
-	guildRoles := guildMember.Roles
-	if guildRoles == nil || len(guildRoles) == 0 {
-		guildRoles = []string{}
-	}
-
 	if existingUser != nil {
 		// 既存ユーザーを更新
-		existingUser.Username = discordUser.Username
-		existingUser.DisplayName = discordUser.GetDisplayName()
-		existingUser.AvatarURL = discordUser.GetAvatarURL()
-		existingUser.GuildNickname = guildNickname
-		existingUser.GuildRoles = guildRoles
-		existingUser.JoinedAt = joinedAt
+		before := existingUser.Clone()
+		applyDiscordUser(existingUser, discordUser)
+		applyGuildMember(existingUser, guildMember)
 		existingUser.LastLoginAt = &now
 		existingUser.UpdatedAt = now
 
 		if err := s.userRepo.Update(ctx, existingUser); err != nil {
 			return nil, fmt.Errorf("failed to update user: %w", err)
 		}
+		recordUserChange(ctx, s.historyRepo, before, existingUser, domain.SyncActor())
 
 		return existingUser, nil
 	}
 
 	// 新規ユーザーを作成
 	user := &domain.User{
-		ID:            uuid.New().String(),
-		DiscordID:     discordUser.ID,
-		Username:      discordUser.Username,
-		DisplayName:   discordUser.GetDisplayName(),
-		AvatarURL:     discordUser.GetAvatarURL(),
-		GuildNickname: guildNickname,
-		GuildRoles:    guildRoles,
-		JoinedAt:      joinedAt,
-		CreatedAt:     now,
-		UpdatedAt:     now,
-		LastLoginAt:   &now,
+		ID:          uuid.New().String(),
+		DiscordID:   discordUser.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		LastLoginAt: &now,
 	}
+	applyDiscordUser(user, discordUser)
+	applyGuildMember(user, guildMember)
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
+	recordUserChange(ctx, s.historyRepo, nil, user, domain.SyncActor())
 
 	return user, nil
 }
@@ -233,6 +220,7 @@ func (s *AuthService) Logout(ctx context.Context, sessionToken string) error {
 type MemberWithProfile struct {
 	User    *domain.User
 	Profile *domain.Profile
+	Roles   []*domain.Role // GuildRolesを名前解決したロール（上位順、未同期のロールは含まない）
 }
 
 // GetAllMembers は全てのメンバーを取得します（Deprecated: GetMembersWithProfilesを使用してください）
@@ -246,16 +234,25 @@ func (s *AuthService) GetAllMembers(ctx context.Context, limit, offset int) ([]*
 	return members, nil
 }
 
-// GetMembersWithProfiles は指定された範囲のメンバーとそのプロフィール情報を取得します
-func (s *AuthService) GetMembersWithProfiles(ctx context.Context, limit, offset int) ([]*MemberWithProfile, error) {
+// GetMembersWithProfiles は絞り込み条件に一致するメンバーのうち指定された範囲とそのプロフィール情報を取得します
+// filter.RoleIDsにはロールIDのほかロール名（大文字・小文字を区別しない）も指定できます
+// 続きがある場合は次の範囲を取得するためのカーソルを返します
+func (s *AuthService) GetMembersWithProfiles(ctx context.Context, filter domain.MemberFilter, page domain.MemberPage) ([]*MemberWithProfile, *domain.MemberCursor, error) {
+	// ロール名の解決とメンバーのロールの表示のために全ロールを一括取得
+	roles, err := s.roleRepo.GetAll(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	filter.RoleIDs = resolveRoleIDs(roles, filter.RoleIDs)
+
 	// ユーザーを取得
-	users, err := s.userRepo.GetAll(ctx, limit, offset)
+	users, next, err := s.userRepo.FindMembers(ctx, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get users: %w", err)
+		return nil, nil, fmt.Errorf("failed to get users: %w", err)
 	}
 
 	if len(users) == 0 {
-		return []*MemberWithProfile{}, nil
+		return []*MemberWithProfile{}, nil, nil
 	}
 
 	// ユーザーIDのリストを作成
@@ -267,7 +264,7 @@ func (s *AuthService) GetMembersWithProfiles(ctx context.Context, limit, offset
 	// 該当するプロフィールを一括取得
 	profiles, err := s.profileRepo.GetByUserIDs(ctx, userIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get profiles: %w", err)
+		return nil, nil, fmt.Errorf("failed to get profiles: %w", err)
 	}
 
 	// プロフィールをマップ化（UserID -> Profile）
@@ -282,10 +279,51 @@ func (s *AuthService) GetMembersWithProfiles(ctx context.Context, limit, offset
 		result = append(result, &MemberWithProfile{
 			User:    user,
 			Profile: profileMap[user.ID], // マップから取得（存在しなければnil）
+			Roles:   filterRoles(roles, user.GuildRoles),
 		})
 	}
 
-	return result, nil
+	return result, next, nil
+}
+
+// CountMembers は絞り込み条件に一致するメンバーの件数を返します
+// filter.RoleIDsはGetMembersWithProfilesと同様にロール名も指定できます
+func (s *AuthService) CountMembers(ctx context.Context, filter domain.MemberFilter) (int64, error) {
+	if len(filter.RoleIDs) > 0 {
+		roles, err := s.roleRepo.GetAll(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get roles: %w", err)
+		}
+		filter.RoleIDs = resolveRoleIDs(roles, filter.RoleIDs)
+	}
+
+	count, err := s.userRepo.CountMembers(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return count, nil
+}
+
+// resolveRoleIDs はロールIDまたはロール名の一覧をロールIDの一覧に変換します
+// どのロールにも一致しない値は、ロールIDとしてそのまま残します（該当するメンバーはいません）
+func resolveRoleIDs(roles []*domain.Role, values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		resolved := false
+		for _, role := range roles {
+			if role.ID == value || strings.EqualFold(role.Name, value) {
+				ids = append(ids, role.ID)
+				resolved = true
+			}
+		}
+		if !resolved {
+			ids = append(ids, value)
+		}
+	}
+	return ids
 }
 
 // GetUserWithProfile は指定されたユーザーとそのプロフィール情報を取得します
@@ -307,8 +345,84 @@ func (s *AuthService) GetUserWithProfile(ctx context.Context, userID string) (*M
 		profile = nil
 	}
 
+	// ロール名を解決
+	roles, err := s.roleRepo.GetByIDs(ctx, user.GuildRoles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
 	return &MemberWithProfile{
 		User:    user,
 		Profile: profile,
+		Roles:   roles,
 	}, nil
 }
+
+// ProfileViewer はユーザーをプロフィールの閲覧者として扱うための情報を返します
+func (s *AuthService) ProfileViewer(user *domain.User) *domain.ProfileViewer {
+	return domain.NewProfileViewer(user, s.officerRoleIDs)
+}
+
+// GetProfileViewer は指定されたユーザーをプロフィールの閲覧者として取得します
+func (s *AuthService) GetProfileViewer(ctx context.Context, userID string) (*domain.ProfileViewer, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return s.ProfileViewer(user), nil
+}
+
+// UpdateProfileVisibility はユーザー自身のプロフィールの項目ごとの公開範囲を更新します
+// visibilityに含まれない項目の設定は変更しません。membersを指定した項目は設定を削除します
+func (s *AuthService) UpdateProfileVisibility(ctx context.Context, userID string, visibility map[string]domain.FieldVisibility) (*domain.Profile, error) {
+	profile, err := s.profileRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	if err := profile.ValidateVisibility(visibility); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidVisibility, err)
+	}
+
+	before := profile.Clone()
+	for key, v := range visibility {
+		if v == "" || v == domain.VisibilityMembers {
+			delete(profile.Visibility, key)
+			continue
+		}
+		if profile.Visibility == nil {
+			profile.Visibility = make(map[string]domain.FieldVisibility)
+		}
+		profile.Visibility[key] = v
+	}
+
+	profile.UpdatedAt = time.Now()
+	if err := s.profileRepo.Update(ctx, profile); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	recordProfileChange(ctx, s.historyRepo, before, profile, domain.UserActor(userID, userID))
+
+	return profile, nil
+}
+
+// filterRoles は上位順に並んだロール一覧からroleIDsに含まれるものだけを返します
+func filterRoles(roles []*domain.Role, roleIDs []string) []*domain.Role {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+
+	idSet := make(map[string]struct{}, len(roleIDs))
+	for _, id := range roleIDs {
+		idSet[id] = struct{}{}
+	}
+
+	result := make([]*domain.Role, 0, len(roleIDs))
+	for _, role := range roles {
+		if _, ok := idSet[role.ID]; ok {
+			result = append(result, role)
+		}
+	}
+
+	return result
+}
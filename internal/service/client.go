@@ -13,13 +13,15 @@ import (
 
 // ClientService はクライアントアプリケーションの管理機能を提供します
 type ClientService struct {
-	clientRepo repository.ClientRepository
+	clientRepo       repository.ClientRepository
+	clientSecretRepo repository.ClientSecretRepository
 }
 
 // NewClientService は新しいClientServiceを作成します
-func NewClientService(clientRepo repository.ClientRepository) *ClientService {
+func NewClientService(clientRepo repository.ClientRepository, clientSecretRepo repository.ClientSecretRepository) *ClientService {
 	return &ClientService{
-		clientRepo: clientRepo,
+		clientRepo:       clientRepo,
+		clientSecretRepo: clientSecretRepo,
 	}
 }
 
@@ -73,7 +75,46 @@ func (s *ClientService) RegisterClient(ctx context.Context, ownerID, clientID, p
 	return client, nil
 }
 
+// RegisterPublicClient はClient Secretを持たない公開クライアント（配布するCLIツールなど）を登録します
+// 公開クライアントはclient_idのみで識別し、デバイス認可グラントにのみ使用できます
+func (s *ClientService) RegisterPublicClient(ctx context.Context, ownerID, clientID, name string) (*domain.ClientApp, error) {
+	if ownerID == "" {
+		return nil, fmt.Errorf("owner_id is required")
+	}
+	if clientID == "" {
+		return nil, fmt.Errorf("client_id is required")
+	}
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	// クライアントIDの重複チェック
+	if _, err := s.clientRepo.GetByClientID(ctx, clientID); err == nil {
+		return nil, fmt.Errorf("client_id already exists: %s", clientID)
+	}
+
+	now := time.Now()
+	client := &domain.ClientApp{
+		ID:                      uuid.New().String(),
+		OwnerID:                 ownerID,
+		ClientID:                clientID,
+		Name:                    name,
+		RedirectURIs:            []string{},
+		GrantTypes:              []string{domain.GrantTypeDeviceCode},
+		TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodNone,
+		CreatedAt:               now,
+		UpdatedAt:               now,
+	}
+
+	if err := s.clientRepo.Create(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to create client app: %w", err)
+	}
+
+	return client, nil
+}
+
 // UpdateClient は既存のクライアントアプリケーションを更新します
+// シークレットを指定した場合は、既存のClient Secretを直ちに無効にして置き換えます（猶予期間を設ける場合はRotateClientSecretを使用します）
 func (s *ClientService) UpdateClient(ctx context.Context, clientID, plainSecret, name string, redirectURIs []string) (*domain.ClientApp, error) {
 	// クライアントの取得
 	client, err := s.clientRepo.GetByClientID(ctx, clientID)
@@ -95,11 +136,54 @@ func (s *ClientService) UpdateClient(ctx context.Context, clientID, plainSecret,
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash client secret: %w", err)
 		}
-		client.ClientSecret = hashedSecret
+		if _, err := s.replaceClientSecret(ctx, client, hashedSecret, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	client.UpdatedAt = time.Now()
+
+	if err := s.clientRepo.Update(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to update client app: %w", err)
+	}
+
+	return client, nil
+}
+
+// SetProfileAccess はクライアントに返すプロフィール項目の公開範囲の上限を変更します
+func (s *ClientService) SetProfileAccess(ctx context.Context, clientID string, access domain.FieldVisibility) (*domain.ClientApp, error) {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("client not found: %w", err)
+	}
+
+	client.ProfileAccess = access
+	if err := client.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid client app: %w", err)
 	}
 
 	client.UpdatedAt = time.Now()
+	if err := s.clientRepo.Update(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to update client app: %w", err)
+	}
 
+	return client, nil
+}
+
+// SetAccessTokenFormat はクライアントに発行するアクセストークンの形式（opaqueまたはjwt）を変更します
+// 変更前に発行したアクセストークンは、有効期限まで元の形式のまま使用できます
+func (s *ClientService) SetAccessTokenFormat(ctx context.Context, clientID, format string) (*domain.ClientApp, error) {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("client not found: %w", err)
+	}
+
+	client.AccessTokenFormat = format
+	if err := client.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid client app: %w", err)
+	}
+
+	client.UpdatedAt = time.Now()
 	if err := s.clientRepo.Update(ctx, client); err != nil {
 		return nil, fmt.Errorf("failed to update client app: %w", err)
 	}
@@ -125,8 +209,15 @@ func (s *ClientService) GetClientByID(ctx context.Context, id string) (*domain.C
 	return client, nil
 }
 
-// DeleteClient はクライアントアプリケーションを削除します
+// DeleteClient はクライアントアプリケーションとClient Secretを削除します
 func (s *ClientService) DeleteClient(ctx context.Context, id string) error {
+	client, err := s.clientRepo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if err := s.clientSecretRepo.DeleteByClientID(ctx, client.ClientID); err != nil {
+		return fmt.Errorf("failed to delete client secrets: %w", err)
+	}
 	if err := s.clientRepo.Delete(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete client: %w", err)
 	}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwks"
+)
+
+const (
+	// jwksCacheTTL はjwks_uriから取得した公開鍵をキャッシュする期間です
+	jwksCacheTTL = 10 * time.Minute
+	// jwksRefreshInterval は署名を検証できなかった場合に公開鍵を取得し直す最小の間隔です（鍵のローテーションに対応します）
+	jwksRefreshInterval = time.Minute
+	// jwksFetchTimeout はjwks_uriから公開鍵を取得する際のタイムアウトです
+	jwksFetchTimeout = 5 * time.Second
+	// clientAssertionLeeway はclient_assertionの時刻の検証で許容するずれです
+	clientAssertionLeeway = 30 * time.Second
+)
+
+// clientAssertionMethods はclient_assertionの署名に使用できるアルゴリズムです（共通鍵・署名なしは使用できません）
+var clientAssertionMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// ClientAssertionVerifier はprivate_key_jwt（RFC 7523）のclient_assertionを検証します
+type ClientAssertionVerifier struct {
+	assertionRepo repository.ClientAssertionRepository
+	audiences     []string
+	httpClient    *http.Client
+
+	mu        sync.Mutex
+	jwksCache map[string]*cachedJWKS // jwks_uriをキーとする
+}
+
+// cachedJWKS はjwks_uriから取得した公開鍵です
+type cachedJWKS struct {
+	set       *jwks.Set
+	fetchedAt time.Time
+}
+
+// NewClientAssertionVerifier は新しいClientAssertionVerifierを作成します
+// issuerはこのサーバーの公開URLです。client_assertionのaudにはissuerまたはトークンエンドポイントのURLを指定します
+// jwks_uriはメンバーが登録できるため、プライベートIPアドレス・ループバックアドレスからは取得せず、リダイレクトも追跡しません（SSRF対策）
+func NewClientAssertionVerifier(assertionRepo repository.ClientAssertionRepository, issuer string) *ClientAssertionVerifier {
+	return &ClientAssertionVerifier{
+		assertionRepo: assertionRepo,
+		audiences:     []string{issuer, issuer + "/oauth/token"},
+		httpClient:    newOutboundHTTPClient(jwksFetchTimeout, false),
+		jwksCache:     make(map[string]*cachedJWKS),
+	}
+}
+
+// validateClientKeys は登録するクライアントの公開鍵を確認します
+// JWKSを直接指定した場合は署名の検証に使用できる鍵を含むかを確認します（jwks_uriは認証時に取得します）
+func validateClientKeys(metadata *domain.ClientMetadata) error {
+	if len(metadata.JWKS) == 0 {
+		return nil
+	}
+	if _, err := jwks.Parse(metadata.JWKS); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidClientMetadata, err)
+	}
+	return nil
+}
+
+// Verify はclient_assertionの署名とiss・sub・aud・exp・jtiを検証し、jtiを記録します
+// 検証に失敗した場合とjtiが使用済みの場合はdomain.ErrInvalidClientを返します
+func (v *ClientAssertionVerifier) Verify(ctx context.Context, client *domain.ClientApp, assertion string) error {
+	if !client.UsesPrivateKeyJWT() {
+		return fmt.Errorf("%w: the client is not registered for private_key_jwt", domain.ErrInvalidClient)
+	}
+
+	claims, err := v.parse(ctx, client, assertion, false)
+	if err != nil && client.JWKSURI != "" && (errors.Is(err, jwt.ErrTokenUnverifiable) || errors.Is(err, jwt.ErrTokenSignatureInvalid)) {
+		// 鍵がローテーションされた可能性があるため、公開鍵を取得し直して検証する
+		claims, err = v.parse(ctx, client, assertion, true)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: invalid client_assertion: %v", domain.ErrInvalidClient, err)
+	}
+
+	if claims.ID == "" || len(claims.ID) > domain.MaxClientAssertionJTILength {
+		return fmt.Errorf("%w: client_assertion must include a jti", domain.ErrInvalidClient)
+	}
+	expiresAt := claims.ExpiresAt.Time
+	if expiresAt.After(time.Now().Add(domain.MaxClientAssertionLifetime + clientAssertionLeeway)) {
+		return fmt.Errorf("%w: client_assertion must expire within %v", domain.ErrInvalidClient, domain.MaxClientAssertionLifetime)
+	}
+
+	// 許容するずれの間も再利用できないよう、有効期限の後まで記録する
+	if err := v.assertionRepo.Record(ctx, client.ClientID, claims.ID, expiresAt.Add(clientAssertionLeeway)); err != nil {
+		if errors.Is(err, domain.ErrClientAssertionReplayed) {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidClient, err)
+		}
+		return fmt.Errorf("failed to record client assertion: %w", err)
+	}
+	return nil
+}
+
+// parse はクライアントの公開鍵でclient_assertionの署名とクレームを検証します
+func (v *ClientAssertionVerifier) parse(ctx context.Context, client *domain.ClientApp, assertion string, refresh bool) (*jwt.RegisteredClaims, error) {
+	set, err := v.keySet(ctx, client, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(assertion, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		var keys jwt.VerificationKeySet
+		for _, key := range set.Lookup(kid) {
+			if key.Algorithm == "" || key.Algorithm == token.Method.Alg() {
+				keys.Keys = append(keys.Keys, key.PublicKey)
+			}
+		}
+		if len(keys.Keys) == 0 {
+			return nil, fmt.Errorf("no key matches kid %q and alg %q", kid, token.Method.Alg())
+		}
+		return keys, nil
+	},
+		jwt.WithValidMethods(clientAssertionMethods),
+		jwt.WithIssuer(client.ClientID),
+		jwt.WithSubject(client.ClientID),
+		jwt.WithAudience(v.audiences...),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clientAssertionLeeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// keySet はクライアントの公開鍵を返します
+// jwks_uriの公開鍵はキャッシュし、refreshがtrueの場合は最小の間隔を空けて取得し直します
+func (v *ClientAssertionVerifier) keySet(ctx context.Context, client *domain.ClientApp, refresh bool) (*jwks.Set, error) {
+	if client.JWKS != "" {
+		return jwks.Parse([]byte(client.JWKS))
+	}
+
+	now := time.Now()
+	v.mu.Lock()
+	cached := v.jwksCache[client.JWKSURI]
+	v.mu.Unlock()
+	if cached != nil {
+		age := now.Sub(cached.fetchedAt)
+		if age < jwksCacheTTL && (!refresh || age < jwksRefreshInterval) {
+			return cached.set, nil
+		}
+	}
+
+	set, err := jwks.Fetch(ctx, v.httpClient, client.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	v.jwksCache[client.JWKSURI] = &cachedJWKS{set: set, fetchedAt: now}
+	v.mu.Unlock()
+	return set, nil
+}
+
+// clientAssertionSubject は検証前のclient_assertionからクライアントID（sub）を取得します
+// client_idを省略したリクエストで、どのクライアントの公開鍵で検証するかを決めるために使用します
+func clientAssertionSubject(assertion string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, claims); err != nil {
+		return "", fmt.Errorf("%w: invalid client_assertion: %v", domain.ErrInvalidClient, err)
+	}
+	if claims.Subject == "" {
+		return "", fmt.Errorf("%w: client_assertion must include a sub", domain.ErrInvalidClient)
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwks"
+)
+
+// モックClientAssertionRepository
+type mockClientAssertionRepository struct {
+	jtis map[string]time.Time // client_id + " " + jti をキーとする
+}
+
+func newMockClientAssertionRepository() *mockClientAssertionRepository {
+	return &mockClientAssertionRepository{jtis: make(map[string]time.Time)}
+}
+
+func (m *mockClientAssertionRepository) Record(ctx context.Context, clientID, jti string, expiresAt time.Time) error {
+	key := clientID + " " + jti
+	if exp, ok := m.jtis[key]; ok && exp.After(time.Now()) {
+		return domain.ErrClientAssertionReplayed
+	}
+	m.jtis[key] = expiresAt
+	return nil
+}
+
+// newTestSigningKey はテスト用のEC鍵と、その公開鍵のJWKSを生成します
+func newTestSigningKey(t *testing.T, kid string) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	set := &jwks.Set{Keys: []jwks.Key{{KeyID: kid, Algorithm: "ES256", PublicKey: &key.PublicKey}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	return key, data
+}
+
+// signClientAssertion はクライアントのclient_assertionを署名します
+func signClientAssertion(t *testing.T, key *ecdsa.PrivateKey, kid string, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign client assertion: %v", err)
+	}
+	return signed
+}
+
+func TestOAuth2Service_ClientAssertion(t *testing.T) {
+	ctx := context.Background()
+	const issuer = "https://auth.example.com"
+	clientRepo := newMockClientRepository()
+	verifier := NewClientAssertionVerifier(newMockClientAssertionRepository(), issuer)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), newMockAuthCodeRepository(), newMockDeviceCodeRepository(), newMockTokenRepository(), newMockOAuth2UserRepository(), nil, verifier, nil)
+
+	key, jwksJSON := newTestSigningKey(t, "key-1")
+	clientRepo.clients["svc"] = &domain.ClientApp{
+		ID:                      "id-svc",
+		ClientID:                "svc",
+		GrantTypes:              []string{domain.GrantTypeDeviceCode},
+		TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodPrivateKeyJWT,
+		JWKS:                    string(jwksJSON),
+	}
+
+	// claims は有効なclient_assertionのクレームを返します
+	claims := func(jti string) jwt.RegisteredClaims {
+		now := time.Now()
+		return jwt.RegisteredClaims{
+			Issuer:    "svc",
+			Subject:   "svc",
+			Audience:  jwt.ClaimStrings{issuer + "/oauth/token"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		}
+	}
+	authorize := func(clientID, clientSecret, assertion string) error {
+		_, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{
+			ClientID:        clientID,
+			ClientSecret:    clientSecret,
+			ClientAssertion: assertion,
+		}, issuer+"/device")
+		return err
+	}
+
+	t.Run("有効なclient_assertion", func(t *testing.T) {
+		// client_idは省略でき、subからクライアントを特定する
+		if err := authorize("", "", signClientAssertion(t, key, "key-1", claims("jti-ok"))); err != nil {
+			t.Fatalf("Expected the assertion to be accepted, got %v", err)
+		}
+		// audにissuerを指定してもよい
+		c := claims("jti-issuer")
+		c.Audience = jwt.ClaimStrings{issuer}
+		if err := authorize("svc", "", signClientAssertion(t, key, "key-1", c)); err != nil {
+			t.Fatalf("Expected the issuer audience to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("jtiの再利用", func(t *testing.T) {
+		assertion := signClientAssertion(t, key, "key-1", claims("jti-replay"))
+		if err := authorize("svc", "", assertion); err != nil {
+			t.Fatalf("Expected the first use to be accepted, got %v", err)
+		}
+		if err := authorize("svc", "", assertion); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient for a replayed assertion, got %v", err)
+		}
+	})
+
+	t.Run("無効なclient_assertion", func(t *testing.T) {
+		otherKey, _ := newTestSigningKey(t, "key-1")
+		tests := []struct {
+			name     string
+			clientID string
+			modify   func(c *jwt.RegisteredClaims)
+			key      *ecdsa.PrivateKey
+		}{
+			{name: "別の鍵で署名", key: otherKey},
+			{name: "issが異なる", modify: func(c *jwt.RegisteredClaims) { c.Issuer = "other" }},
+			{name: "audが異なる", modify: func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"https://other.example.com/oauth/token"} }},
+			{name: "期限切れ", modify: func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }},
+			{name: "有効期限が長すぎる", modify: func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour)) }},
+			{name: "expがない", modify: func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil }},
+			{name: "jtiがない", modify: func(c *jwt.RegisteredClaims) { c.ID = "" }},
+			{name: "client_idとsubが異なる", clientID: "other"},
+		}
+		for i, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := claims("jti-invalid-" + string(rune('a'+i)))
+				if tt.modify != nil {
+					tt.modify(&c)
+				}
+				signingKey := key
+				if tt.key != nil {
+					signingKey = tt.key
+				}
+				if err := authorize(tt.clientID, "", signClientAssertion(t, signingKey, "key-1", c)); !errors.Is(err, domain.ErrInvalidClient) {
+					t.Errorf("Expected ErrInvalidClient, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("Client Secretでは認証できない", func(t *testing.T) {
+		if err := authorize("svc", "secret", ""); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient, got %v", err)
+		}
+	})
+
+	t.Run("jwks_uriの鍵のローテーション", func(t *testing.T) {
+		currentJWKS := jwksJSON
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(currentJWKS)
+		}))
+		defer server.Close()
+		verifier.httpClient = server.Client()
+
+		clientRepo.clients["remote"] = &domain.ClientApp{
+			ID:                      "id-remote",
+			ClientID:                "remote",
+			GrantTypes:              []string{domain.GrantTypeDeviceCode},
+			TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodPrivateKeyJWT,
+			JWKSURI:                 server.URL + "/jwks.json",
+		}
+		remoteClaims := func(jti string) jwt.RegisteredClaims {
+			c := claims(jti)
+			c.Issuer, c.Subject = "remote", "remote"
+			return c
+		}
+		if err := authorize("remote", "", signClientAssertion(t, key, "key-1", remoteClaims("remote-1"))); err != nil {
+			t.Fatalf("Expected the assertion to be accepted, got %v", err)
+		}
+
+		// 新しい鍵に切り替えた直後は、最小の間隔を空けるまで取得し直さない
+		newKey, newJWKS := newTestSigningKey(t, "key-2")
+		currentJWKS = newJWKS
+		if err := authorize("remote", "", signClientAssertion(t, newKey, "key-2", remoteClaims("remote-2"))); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Fatalf("Expected ErrInvalidClient before the refresh interval, got %v", err)
+		}
+
+		verifier.jwksCache[server.URL+"/jwks.json"].fetchedAt = time.Now().Add(-jwksRefreshInterval)
+		if err := authorize("remote", "", signClientAssertion(t, newKey, "key-2", remoteClaims("remote-3"))); err != nil {
+			t.Errorf("Expected the rotated key to be accepted, got %v", err)
+		}
+	})
+}
+
+// TestClientAssertionVerifier_RejectsPrivateJWKSURI はjwks_uriがループバックアドレスの場合に公開鍵を取得しないことを確認します（SSRF対策）
+func TestClientAssertionVerifier_RejectsPrivateJWKSURI(t *testing.T) {
+	ctx := context.Background()
+	const issuer = "https://auth.example.com"
+
+	// TLSの検証で失敗しないよう、HTTPのサーバーで接続そのものを拒否することを確認する
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	clientRepo := newMockClientRepository()
+	clientRepo.clients["internal"] = &domain.ClientApp{
+		ID:                      "id-internal",
+		ClientID:                "internal",
+		GrantTypes:              []string{domain.GrantTypeDeviceCode},
+		TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodPrivateKeyJWT,
+		JWKSURI:                 server.URL + "/jwks.json",
+	}
+	verifier := NewClientAssertionVerifier(newMockClientAssertionRepository(), issuer)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), newMockAuthCodeRepository(), newMockDeviceCodeRepository(), newMockTokenRepository(), newMockOAuth2UserRepository(), nil, verifier, nil)
+
+	key, _ := newTestSigningKey(t, "key-1")
+	now := time.Now()
+	assertion := signClientAssertion(t, key, "key-1", jwt.RegisteredClaims{
+		Issuer:    "internal",
+		Subject:   "internal",
+		Audience:  jwt.ClaimStrings{issuer},
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ID:        "jti-internal",
+	})
+	_, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "internal", ClientAssertion: assertion}, issuer+"/device")
+	if !errors.Is(err, domain.ErrInvalidClient) {
+		t.Errorf("Expected ErrInvalidClient, got %v", err)
+	}
+	if requested {
+		t.Error("Expected the loopback jwks_uri not to be fetched")
+	}
+}
+
+func TestClientRegistrationService_PrivateKeyJWT(t *testing.T) {
+	ctx := context.Background()
+	clientRepo := newMockClientRepository()
+	service := NewClientRegistrationService(clientRepo, newMockClientSecretRepository(), newMockInitialAccessTokenRepository())
+	_, jwksJSON := newTestSigningKey(t, "key-1")
+
+	register := func(metadata *domain.ClientMetadata) (*ClientRegistration, error) {
+		token, _, err := service.IssueInitialAccessToken(ctx, "owner-1")
+		if err != nil {
+			t.Fatalf("IssueInitialAccessToken failed: %v", err)
+		}
+		return service.Register(ctx, token, metadata)
+	}
+
+	registration, err := register(&domain.ClientMetadata{
+		ClientName:              "出席管理バッチ",
+		GrantTypes:              []string{domain.GrantTypeDeviceCode},
+		TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodPrivateKeyJWT,
+		JWKS:                    jwksJSON,
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if registration.ClientSecret != "" || registration.Client.ClientSecret != "" || registration.Client.JWKS == "" {
+		t.Fatalf("Expected a client without a secret, got %+v", registration.Client)
+	}
+
+	// 秘密鍵を含むJWKSは登録できない
+	if _, err := register(&domain.ClientMetadata{
+		ClientName:              "秘密鍵",
+		GrantTypes:              []string{domain.GrantTypeDeviceCode},
+		TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodPrivateKeyJWT,
+		JWKS:                    json.RawMessage(`{"keys":[{"kty":"EC","crv":"P-256","x":"a","y":"b","d":"c"}]}`),
+	}); !errors.Is(err, domain.ErrInvalidClientMetadata) {
+		t.Errorf("Expected ErrInvalidClientMetadata for a private key, got %v", err)
+	}
+
+	// Client Secretがないため、Client Secretの認証方式には変更できない
+	_, err = service.UpdateClient(ctx, registration.Client.ClientID, registration.RegistrationAccessToken, "", &domain.ClientMetadata{
+		ClientName: "出席管理バッチ",
+		GrantTypes: []string{domain.GrantTypeDeviceCode},
+	})
+	if !errors.Is(err, domain.ErrInvalidClientMetadata) {
+		t.Errorf("Expected ErrInvalidClientMetadata when switching to client_secret_post, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+)
+
+// ClientRegistrationService はクライアントの動的登録（RFC 7591）と登録内容の管理（RFC 7592）の機能を提供します
+type ClientRegistrationService struct {
+	clientRepo             repository.ClientRepository
+	clientSecretRepo       repository.ClientSecretRepository
+	initialAccessTokenRepo repository.InitialAccessTokenRepository
+}
+
+// NewClientRegistrationService は新しいClientRegistrationServiceを作成します
+func NewClientRegistrationService(clientRepo repository.ClientRepository, clientSecretRepo repository.ClientSecretRepository, initialAccessTokenRepo repository.InitialAccessTokenRepository) *ClientRegistrationService {
+	return &ClientRegistrationService{
+		clientRepo:             clientRepo,
+		clientSecretRepo:       clientSecretRepo,
+		initialAccessTokenRepo: initialAccessTokenRepo,
+	}
+}
+
+// ClientRegistration は動的登録したクライアントと、登録時にのみ返す平文の認証情報を表します
+type ClientRegistration struct {
+	Client                  *domain.ClientApp
+	ClientSecret            string // private_key_jwtのクライアントの場合は空
+	RegistrationAccessToken string
+}
+
+// IssueInitialAccessToken はログイン中のメンバーにクライアントを1つ登録するための初期アクセストークンを発行します
+// 平文のトークンは戻り値でのみ返します
+func (s *ClientRegistrationService) IssueInitialAccessToken(ctx context.Context, userID string) (string, *domain.InitialAccessToken, error) {
+	plain, err := generateSecureToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate initial access token: %w", err)
+	}
+
+	now := time.Now()
+	token := &domain.InitialAccessToken{
+		ID:        uuid.New().String(),
+		TokenHash: hashInitialAccessToken(plain),
+		UserID:    userID,
+		ExpiresAt: now.Add(domain.InitialAccessTokenExpiration),
+		CreatedAt: now,
+	}
+	if err := s.initialAccessTokenRepo.Create(ctx, token); err != nil {
+		return "", nil, fmt.Errorf("failed to store initial access token: %w", err)
+	}
+	return plain, token, nil
+}
+
+// Register は初期アクセストークンで認証し、メタデータの内容でクライアントを登録します
+// 登録したクライアントの所有者は初期アクセストークンを発行したメンバーです
+// トークンが無効な場合はdomain.ErrInvalidRegistrationToken、メタデータが無効な場合はdomain.ErrInvalidClientMetadataまたはdomain.ErrInvalidRedirectURIを返します
+func (s *ClientRegistrationService) Register(ctx context.Context, initialAccessToken string, metadata *domain.ClientMetadata) (*ClientRegistration, error) {
+	// 1. 初期アクセストークンの検証
+	token, err := s.initialAccessTokenRepo.GetByTokenHash(ctx, hashInitialAccessToken(initialAccessToken))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !token.IsUsable(now) {
+		return nil, fmt.Errorf("%w: initial access token is expired or already used", domain.ErrInvalidRegistrationToken)
+	}
+
+	// 2. メタデータの検証
+	metadata.ApplyDefaults()
+	if err := metadata.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateClientKeys(metadata); err != nil {
+		return nil, err
+	}
+
+	// 3. 認証情報を生成（private_key_jwt・公開クライアントにはClient Secretを発行しない）
+	var clientSecret, hashedSecret string
+	if metadata.UsesClientSecret() {
+		clientSecret, err = generateSecureToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate client secret: %w", err)
+		}
+		hashedSecret, err = auth.HashClientSecret(clientSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash client secret: %w", err)
+		}
+	}
+	registrationAccessToken, err := generateSecureToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate registration access token: %w", err)
+	}
+	hashedRegistrationAccessToken, err := auth.HashClientSecret(registrationAccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash registration access token: %w", err)
+	}
+
+	// 4. 同じトークンで二重に登録しないよう、先に使用済みにマーク
+	if err := s.initialAccessTokenRepo.MarkUsed(ctx, token.ID, now); err != nil {
+		return nil, err
+	}
+
+	// 5. クライアントを登録
+	client := &domain.ClientApp{
+		ID:                      uuid.New().String(),
+		OwnerID:                 token.UserID,
+		ClientID:                uuid.New().String(),
+		ClientSecret:            hashedSecret,
+		RegistrationAccessToken: hashedRegistrationAccessToken,
+		CreatedAt:               now,
+		UpdatedAt:               now,
+	}
+	client.ApplyMetadata(metadata)
+	if err := s.clientRepo.Create(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to create client app: %w", err)
+	}
+
+	return &ClientRegistration{
+		Client:                  client,
+		ClientSecret:            clientSecret,
+		RegistrationAccessToken: registrationAccessToken,
+	}, nil
+}
+
+// GetClient は登録アクセストークンで認証し、動的登録したクライアントを取得します
+// クライアントが存在しない場合も、存在を知られないようdomain.ErrInvalidRegistrationTokenを返します
+func (s *ClientRegistrationService) GetClient(ctx context.Context, clientID, registrationAccessToken string) (*domain.ClientApp, error) {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidRegistrationToken, err)
+	}
+	if client.RegistrationAccessToken == "" {
+		return nil, fmt.Errorf("%w: client was not registered dynamically", domain.ErrInvalidRegistrationToken)
+	}
+	if err := auth.ValidateClientSecret(registrationAccessToken, client.RegistrationAccessToken); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidRegistrationToken, err)
+	}
+	return client, nil
+}
+
+// UpdateClient は登録アクセストークンで認証し、クライアントのメタデータを置き換えます（RFC 7592 2.2）
+// 省略した項目は既定値に戻ります。Client IDとClient Secretは変更しません
+// private_key_jwtで登録したクライアントは、Client Secretの認証方式には変更できません
+// clientSecretを指定した場合は、有効なClient Secretのいずれかと一致する必要があります
+func (s *ClientRegistrationService) UpdateClient(ctx context.Context, clientID, registrationAccessToken, clientSecret string, metadata *domain.ClientMetadata) (*domain.ClientApp, error) {
+	client, err := s.GetClient(ctx, clientID, registrationAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if clientSecret != "" {
+		if err := verifyClientSecret(ctx, s.clientSecretRepo, client, clientSecret); err != nil {
+			if errors.Is(err, domain.ErrInvalidClient) {
+				return nil, fmt.Errorf("%w: client_secret does not match", domain.ErrInvalidClientMetadata)
+			}
+			return nil, err
+		}
+	}
+
+	metadata.ApplyDefaults()
+	if err := metadata.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateClientKeys(metadata); err != nil {
+		return nil, err
+	}
+	// private_key_jwt・公開クライアントとして登録したクライアントにはClient Secretがないため、Client Secretの認証方式には変更できない
+	if metadata.UsesClientSecret() && client.ClientSecret == "" {
+		return nil, fmt.Errorf("%w: the client has no client_secret; token_endpoint_auth_method must be private_key_jwt or none", domain.ErrInvalidClientMetadata)
+	}
+
+	client.ApplyMetadata(metadata)
+	client.UpdatedAt = time.Now()
+	if err := s.clientRepo.Update(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to update client app: %w", err)
+	}
+	return client, nil
+}
+
+// DeleteClient は登録アクセストークンで認証し、クライアントとClient Secretを削除します（RFC 7592 2.3）
+func (s *ClientRegistrationService) DeleteClient(ctx context.Context, clientID, registrationAccessToken string) error {
+	client, err := s.GetClient(ctx, clientID, registrationAccessToken)
+	if err != nil {
+		return err
+	}
+	if err := s.clientSecretRepo.DeleteByClientID(ctx, client.ClientID); err != nil {
+		return fmt.Errorf("failed to delete client secrets: %w", err)
+	}
+	if err := s.clientRepo.Delete(ctx, client.ID); err != nil {
+		return fmt.Errorf("failed to delete client app: %w", err)
+	}
+	return nil
+}
+
+// hashInitialAccessToken は初期アクセストークンを保存・検索用のSHA-256（16進数）に変換します
+func hashInitialAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+)
+
+// モックInitialAccessTokenRepository
+type mockInitialAccessTokenRepository struct {
+	tokens map[string]*domain.InitialAccessToken // IDをキーとする
+}
+
+func newMockInitialAccessTokenRepository() *mockInitialAccessTokenRepository {
+	return &mockInitialAccessTokenRepository{tokens: make(map[string]*domain.InitialAccessToken)}
+}
+
+func (m *mockInitialAccessTokenRepository) Create(ctx context.Context, token *domain.InitialAccessToken) error {
+	m.tokens[token.ID] = token
+	return nil
+}
+
+func (m *mockInitialAccessTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*domain.InitialAccessToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return nil, domain.ErrInvalidRegistrationToken
+}
+
+func (m *mockInitialAccessTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	t, ok := m.tokens[id]
+	if !ok || t.UsedAt != nil {
+		return domain.ErrInvalidRegistrationToken
+	}
+	t.UsedAt = &usedAt
+	return nil
+}
+
+func (m *mockInitialAccessTokenRepository) DeleteExpired(ctx context.Context) error {
+	return nil
+}
+
+func TestClientRegistrationService(t *testing.T) {
+	ctx := context.Background()
+	clientRepo := newMockClientRepository()
+	tokenRepo := newMockInitialAccessTokenRepository()
+	service := NewClientRegistrationService(clientRepo, newMockClientSecretRepository(), tokenRepo)
+
+	// register は初期アクセストークンを発行してクライアントを登録します
+	register := func(t *testing.T, metadata *domain.ClientMetadata) (*ClientRegistration, error) {
+		t.Helper()
+		token, issued, err := service.IssueInitialAccessToken(ctx, "owner-1")
+		if err != nil {
+			t.Fatalf("IssueInitialAccessToken failed: %v", err)
+		}
+		if issued.TokenHash == token || !issued.IsUsable(time.Now()) {
+			t.Fatalf("Expected a hashed usable token, got %+v", issued)
+		}
+		return service.Register(ctx, token, metadata)
+	}
+
+	t.Run("登録と管理", func(t *testing.T) {
+		registration, err := register(t, &domain.ClientMetadata{
+			ClientName:   "出席管理",
+			RedirectURIs: []string{"https://attendance.example.com/callback", "http://localhost:3000/callback"},
+			LogoURI:      "https://attendance.example.com/logo.png",
+		})
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		client := registration.Client
+		if client.OwnerID != "owner-1" || client.ClientID == "" || registration.ClientSecret == "" || registration.RegistrationAccessToken == "" {
+			t.Fatalf("Unexpected registration: %+v", registration)
+		}
+		if err := auth.ValidateClientSecret(registration.ClientSecret, client.ClientSecret); err != nil {
+			t.Errorf("Expected client secret to be hashed: %v", err)
+		}
+		// 既定値は認可コードグラントとprofileスコープ
+		metadata := client.Metadata()
+		if metadata.Scope != domain.ClientScopeProfile || len(metadata.GrantTypes) != 1 || metadata.GrantTypes[0] != domain.GrantTypeAuthorizationCode || metadata.ResponseTypes[0] != "code" {
+			t.Errorf("Unexpected defaults: %+v", metadata)
+		}
+
+		if _, err := service.GetClient(ctx, client.ClientID, "wrong"); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected ErrInvalidRegistrationToken for a wrong token, got %v", err)
+		}
+		got, err := service.GetClient(ctx, client.ClientID, registration.RegistrationAccessToken)
+		if err != nil || got.Name != "出席管理" {
+			t.Fatalf("GetClient failed: %v", err)
+		}
+
+		updated, err := service.UpdateClient(ctx, client.ClientID, registration.RegistrationAccessToken, registration.ClientSecret, &domain.ClientMetadata{
+			ClientName:              "出席管理v2",
+			RedirectURIs:            []string{"https://attendance.example.com/callback"},
+			GrantTypes:              []string{domain.GrantTypeAuthorizationCode, domain.GrantTypeDeviceCode},
+			Scope:                   "profile members",
+			TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodClientSecretBasic,
+		})
+		if err != nil {
+			t.Fatalf("UpdateClient failed: %v", err)
+		}
+		if updated.Name != "出席管理v2" || updated.LogoURI != "" || !updated.AllowsGrantType(domain.GrantTypeDeviceCode) || !updated.AllowsScope(domain.ClientScopeMembers) || updated.Metadata().TokenEndpointAuthMethod != domain.TokenEndpointAuthMethodClientSecretBasic {
+			t.Errorf("Expected metadata to be replaced, got %+v", updated)
+		}
+		if _, err := service.UpdateClient(ctx, client.ClientID, registration.RegistrationAccessToken, "wrong", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"https://example.com/cb"}}); !errors.Is(err, domain.ErrInvalidClientMetadata) {
+			t.Errorf("Expected ErrInvalidClientMetadata for a mismatched client_secret, got %v", err)
+		}
+
+		if err := service.DeleteClient(ctx, client.ClientID, registration.RegistrationAccessToken); err != nil {
+			t.Fatalf("DeleteClient failed: %v", err)
+		}
+		if _, err := service.GetClient(ctx, client.ClientID, registration.RegistrationAccessToken); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected ErrInvalidRegistrationToken for a deleted client, got %v", err)
+		}
+	})
+
+	t.Run("初期アクセストークン", func(t *testing.T) {
+		token, _, err := service.IssueInitialAccessToken(ctx, "owner-1")
+		if err != nil {
+			t.Fatalf("IssueInitialAccessToken failed: %v", err)
+		}
+		metadata := func() *domain.ClientMetadata {
+			return &domain.ClientMetadata{ClientName: "CLI", GrantTypes: []string{domain.GrantTypeDeviceCode}}
+		}
+		if _, err := service.Register(ctx, "unknown", metadata()); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected ErrInvalidRegistrationToken for an unknown token, got %v", err)
+		}
+		registration, err := service.Register(ctx, token, metadata())
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		if len(registration.Client.RedirectURIs) != 0 || registration.Client.AllowsGrantType(domain.GrantTypeAuthorizationCode) {
+			t.Errorf("Expected a device-only client, got %+v", registration.Client)
+		}
+		if _, err := service.Register(ctx, token, metadata()); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected ErrInvalidRegistrationToken for a used token, got %v", err)
+		}
+
+		expired, issued, _ := service.IssueInitialAccessToken(ctx, "owner-1")
+		issued.ExpiresAt = time.Now().Add(-time.Second)
+		if _, err := service.Register(ctx, expired, metadata()); !errors.Is(err, domain.ErrInvalidRegistrationToken) {
+			t.Errorf("Expected ErrInvalidRegistrationToken for an expired token, got %v", err)
+		}
+	})
+
+	t.Run("公開クライアント", func(t *testing.T) {
+		registration, err := register(t, &domain.ClientMetadata{
+			ClientName:              "出席CLI",
+			GrantTypes:              []string{domain.GrantTypeDeviceCode},
+			TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodNone,
+		})
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		client := registration.Client
+		if registration.ClientSecret != "" || client.ClientSecret != "" || !client.IsPublic() {
+			t.Errorf("Expected a public client without a client secret, got %+v", registration)
+		}
+		if client.Metadata().TokenEndpointAuthMethod != domain.TokenEndpointAuthMethodNone {
+			t.Errorf("Expected token_endpoint_auth_method none, got %q", client.Metadata().TokenEndpointAuthMethod)
+		}
+
+		// Client Secretがないため、Client Secretの認証方式には変更できない
+		if _, err := service.UpdateClient(ctx, client.ClientID, registration.RegistrationAccessToken, "", &domain.ClientMetadata{
+			ClientName: "出席CLI",
+			GrantTypes: []string{domain.GrantTypeDeviceCode},
+		}); !errors.Is(err, domain.ErrInvalidClientMetadata) {
+			t.Errorf("Expected ErrInvalidClientMetadata, got %v", err)
+		}
+	})
+
+	t.Run("メタデータの検証", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			metadata *domain.ClientMetadata
+			want     error
+		}{
+			{"名前なし", &domain.ClientMetadata{RedirectURIs: []string{"https://example.com/cb"}}, domain.ErrInvalidClientMetadata},
+			{"リダイレクトURIなし", &domain.ClientMetadata{ClientName: "x"}, domain.ErrInvalidRedirectURI},
+			{"HTTP", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"http://example.com/cb"}}, domain.ErrInvalidRedirectURI},
+			{"localhostに似たホスト", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"http://localhost.example.com/cb"}}, domain.ErrInvalidRedirectURI},
+			{"未対応のグラント", &domain.ClientMetadata{ClientName: "x", GrantTypes: []string{"client_credentials"}}, domain.ErrInvalidClientMetadata},
+			{"未対応のレスポンス", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"https://example.com/cb"}, ResponseTypes: []string{"token"}}, domain.ErrInvalidClientMetadata},
+			{"不明なスコープ", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"https://example.com/cb"}, Scope: "profile ldap"}, domain.ErrInvalidClientMetadata},
+			{"HTTPのロゴ", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"https://example.com/cb"}, LogoURI: "http://example.com/logo.png"}, domain.ErrInvalidClientMetadata},
+			{"未対応の認証方式", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"https://example.com/cb"}, TokenEndpointAuthMethod: "tls_client_auth"}, domain.ErrInvalidClientMetadata},
+			{"認可コードの公開クライアント", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"https://example.com/cb"}, TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodNone}, domain.ErrInvalidClientMetadata},
+			{"認可コードとデバイスの公開クライアント", &domain.ClientMetadata{ClientName: "x", RedirectURIs: []string{"https://example.com/cb"}, GrantTypes: []string{domain.GrantTypeAuthorizationCode, domain.GrantTypeDeviceCode}, TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodNone}, domain.ErrInvalidClientMetadata},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				before := len(clientRepo.clients)
+				if _, err := register(t, tt.metadata); !errors.Is(err, tt.want) {
+					t.Errorf("Expected %v, got %v", tt.want, err)
+				}
+				if len(clientRepo.clients) != before {
+					t.Error("Expected no client to be registered")
+				}
+			})
+		}
+	})
+}
+
+func TestOAuth2Service_ClientGrantTypes(t *testing.T) {
+	ctx := context.Background()
+	clientRepo := newMockClientRepository()
+	userRepo := newMockOAuth2UserRepository()
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), newMockAuthCodeRepository(), newMockDeviceCodeRepository(), newMockTokenRepository(), userRepo, nil, nil, nil)
+
+	hashed, err := auth.HashClientSecret("secret")
+	if err != nil {
+		t.Fatalf("Failed to hash secret: %v", err)
+	}
+	clientRepo.clients["cli"] = &domain.ClientApp{ID: "id-cli", ClientID: "cli", ClientSecret: hashed, GrantTypes: []string{domain.GrantTypeDeviceCode}}
+	clientRepo.clients["web"] = &domain.ClientApp{ID: "id-web", ClientID: "web", ClientSecret: hashed, RedirectURIs: []string{"https://example.com/cb"}, GrantTypes: []string{domain.GrantTypeAuthorizationCode}}
+	userRepo.users["user-1"] = &domain.User{ID: "user-1"}
+
+	if _, err := service.Authorize(ctx, &AuthorizeRequest{ClientID: "cli", RedirectURI: "https://example.com/cb", ResponseType: "code", UserID: "user-1"}); !errors.Is(err, domain.ErrUnauthorizedClient) {
+		t.Errorf("Expected ErrUnauthorizedClient for authorize, got %v", err)
+	}
+	if _, err := service.ExchangeToken(ctx, &TokenRequest{GrantType: "authorization_code", Code: "code", ClientID: "cli", ClientSecret: "secret"}); !errors.Is(err, domain.ErrUnauthorizedClient) {
+		t.Errorf("Expected ErrUnauthorizedClient for token, got %v", err)
+	}
+	if _, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "web", ClientSecret: "secret"}, "https://auth.example.com/device"); !errors.Is(err, domain.ErrUnauthorizedClient) {
+		t.Errorf("Expected ErrUnauthorizedClient for device authorization, got %v", err)
+	}
+	if _, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "cli", ClientSecret: "secret"}, "https://auth.example.com/device"); err != nil {
+		t.Errorf("Expected device authorization for a device client, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+)
+
+// listClientSecrets はクライアントのClient Secretを新しい順に取得します
+// client_secretsに1件も登録されていないクライアント（手動で登録したものなど）は、
+// client_apps.client_secretを最初のシークレットとして登録します
+func listClientSecrets(ctx context.Context, secretRepo repository.ClientSecretRepository, client *domain.ClientApp) ([]*domain.ClientSecret, error) {
+	secrets, err := secretRepo.ListByClientID(ctx, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) > 0 || client.ClientSecret == "" {
+		return secrets, nil
+	}
+
+	// IDをクライアントの内部IDに固定し、同時に移行しても二重に登録されないようにする
+	secret := &domain.ClientSecret{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		HashedSecret: client.ClientSecret,
+		CreatedAt:    client.CreatedAt,
+	}
+	if err := secretRepo.Create(ctx, secret); err != nil {
+		secrets, listErr := secretRepo.ListByClientID(ctx, client.ClientID)
+		if listErr != nil || len(secrets) == 0 {
+			return nil, fmt.Errorf("failed to migrate client secret: %w", err)
+		}
+		return secrets, nil
+	}
+	return []*domain.ClientSecret{secret}, nil
+}
+
+// verifyClientSecret は平文のシークレットがクライアントの有効なClient Secretのいずれかと一致するかを確認し、
+// 一致したシークレットの最終使用日時を記録します
+// 一致しない場合はdomain.ErrInvalidClientを返します
+func verifyClientSecret(ctx context.Context, secretRepo repository.ClientSecretRepository, client *domain.ClientApp, plainSecret string) error {
+	secrets, err := listClientSecrets(ctx, secretRepo, client)
+	if err != nil {
+		return fmt.Errorf("failed to get client secrets: %w", err)
+	}
+
+	now := time.Now()
+	for _, secret := range secrets {
+		if secret.IsExpired(now) {
+			continue
+		}
+		if err := auth.ValidateClientSecret(plainSecret, secret.HashedSecret); err != nil {
+			continue
+		}
+		if err := secretRepo.UpdateLastUsed(ctx, secret.ID, now); err != nil {
+			log.Printf("Failed to record client secret usage: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: client_secret does not match", domain.ErrInvalidClient)
+}
+
+// RotateClientSecret はクライアントに新しいClient Secretを発行します
+// 既存のシークレットはgracePeriodの間は引き続き使用でき、その後無効になります（0の場合は直ちに無効になります）
+// 平文のシークレットは戻り値でのみ返します
+func (s *ClientService) RotateClientSecret(ctx context.Context, clientID string, gracePeriod time.Duration) (string, *domain.ClientSecret, error) {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return "", nil, fmt.Errorf("client not found: %w", err)
+	}
+
+	plainSecret, err := generateSecureToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate client secret: %w", err)
+	}
+	hashedSecret, err := auth.HashClientSecret(plainSecret)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to hash client secret: %w", err)
+	}
+
+	secret, err := s.replaceClientSecret(ctx, client, hashedSecret, gracePeriod)
+	if err != nil {
+		return "", nil, err
+	}
+	client.UpdatedAt = time.Now()
+	if err := s.clientRepo.Update(ctx, client); err != nil {
+		return "", nil, fmt.Errorf("failed to update client app: %w", err)
+	}
+	return plainSecret, secret, nil
+}
+
+// ListClientSecrets はクライアントのClient Secretを新しい順に取得します（期限切れのものを含みます）
+func (s *ClientService) ListClientSecrets(ctx context.Context, clientID string) ([]*domain.ClientSecret, error) {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("client not found: %w", err)
+	}
+	secrets, err := listClientSecrets(ctx, s.clientSecretRepo, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// RevokeClientSecret はクライアントのClient Secretを直ちに無効にします
+// 有効なシークレットが1つだけの場合は、クライアントが使用できなくなるためdomain.ErrInvalidClientSecretを返します
+func (s *ClientService) RevokeClientSecret(ctx context.Context, clientID, secretID string) error {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return fmt.Errorf("client not found: %w", err)
+	}
+	secrets, err := listClientSecrets(ctx, s.clientSecretRepo, client)
+	if err != nil {
+		return fmt.Errorf("failed to list client secrets: %w", err)
+	}
+
+	now := time.Now()
+	var target, newest *domain.ClientSecret
+	for _, secret := range secrets {
+		if secret.ID == secretID {
+			target = secret
+			continue
+		}
+		if newest == nil && !secret.IsExpired(now) {
+			newest = secret
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%w: id=%s", domain.ErrClientSecretNotFound, secretID)
+	}
+	if !target.IsExpired(now) && newest == nil {
+		return fmt.Errorf("%w: cannot revoke the only active client secret", domain.ErrInvalidClientSecret)
+	}
+
+	if err := s.clientSecretRepo.Delete(ctx, clientID, secretID); err != nil {
+		return fmt.Errorf("failed to revoke client secret: %w", err)
+	}
+
+	// client_apps.client_secretは常に最新の有効なシークレットを指すようにする
+	if newest != nil && client.ClientSecret == target.HashedSecret {
+		client.ClientSecret = newest.HashedSecret
+		client.UpdatedAt = now
+		if err := s.clientRepo.Update(ctx, client); err != nil {
+			return fmt.Errorf("failed to update client app: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaceClientSecret はハッシュ化したシークレットをクライアントの新しいClient Secretとして登録し、
+// 既存の有効なシークレットの有効期限をgracePeriod後までに短縮します。期限切れのシークレットは削除します
+// client.ClientSecretも新しいシークレットに置き換えるため、呼び出し側でクライアントを保存する必要があります
+// private_key_jwtで認証するクライアントと公開クライアントはClient Secretを使用しないためdomain.ErrInvalidClientSecretを返します
+func (s *ClientService) replaceClientSecret(ctx context.Context, client *domain.ClientApp, hashedSecret string, gracePeriod time.Duration) (*domain.ClientSecret, error) {
+	switch {
+	case client.UsesPrivateKeyJWT():
+		return nil, fmt.Errorf("%w: the client authenticates with private_key_jwt", domain.ErrInvalidClientSecret)
+	case client.IsPublic():
+		return nil, fmt.Errorf("%w: public clients do not use a client secret", domain.ErrInvalidClientSecret)
+	}
+
+	secrets, err := listClientSecrets(ctx, s.clientSecretRepo, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client secrets: %w", err)
+	}
+
+	now := time.Now()
+	graceEnd := now.Add(gracePeriod)
+	var expired, remaining []*domain.ClientSecret
+	for _, secret := range secrets {
+		if secret.IsExpired(now) || gracePeriod <= 0 {
+			expired = append(expired, secret)
+		} else {
+			remaining = append(remaining, secret)
+		}
+	}
+	if len(remaining)+1 > domain.MaxActiveClientSecrets {
+		return nil, fmt.Errorf("%w: up to %d client secrets can be active at the same time", domain.ErrInvalidClientSecret, domain.MaxActiveClientSecrets)
+	}
+
+	// 失敗してもクライアントが使用できなくならないよう、新しいシークレットを先に登録する
+	secret := &domain.ClientSecret{
+		ID:           uuid.New().String(),
+		ClientID:     client.ClientID,
+		HashedSecret: hashedSecret,
+		CreatedAt:    now,
+	}
+	if err := s.clientSecretRepo.Create(ctx, secret); err != nil {
+		return nil, fmt.Errorf("failed to create client secret: %w", err)
+	}
+
+	for _, old := range remaining {
+		if old.ExpiresAt != nil && old.ExpiresAt.Before(graceEnd) {
+			continue
+		}
+		if err := s.clientSecretRepo.UpdateExpiresAt(ctx, old.ID, graceEnd); err != nil {
+			return nil, fmt.Errorf("failed to expire client secret: %w", err)
+		}
+	}
+	for _, old := range expired {
+		if err := s.clientSecretRepo.Delete(ctx, client.ClientID, old.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete client secret: %w", err)
+		}
+	}
+
+	client.ClientSecret = hashedSecret
+	return secret, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+)
+
+// モックClientSecretRepository
+type mockClientSecretRepository struct {
+	secrets map[string]*domain.ClientSecret // IDをキーとする
+}
+
+func newMockClientSecretRepository() *mockClientSecretRepository {
+	return &mockClientSecretRepository{secrets: make(map[string]*domain.ClientSecret)}
+}
+
+func (m *mockClientSecretRepository) Create(ctx context.Context, secret *domain.ClientSecret) error {
+	if _, ok := m.secrets[secret.ID]; ok {
+		return errors.New("duplicate id")
+	}
+	m.secrets[secret.ID] = secret
+	return nil
+}
+
+func (m *mockClientSecretRepository) ListByClientID(ctx context.Context, clientID string) ([]*domain.ClientSecret, error) {
+	var secrets []*domain.ClientSecret
+	for _, s := range m.secrets {
+		if s.ClientID == clientID {
+			secrets = append(secrets, s)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].CreatedAt.After(secrets[j].CreatedAt) })
+	return secrets, nil
+}
+
+func (m *mockClientSecretRepository) UpdateExpiresAt(ctx context.Context, id string, expiresAt time.Time) error {
+	if s, ok := m.secrets[id]; ok {
+		s.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+func (m *mockClientSecretRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	if s, ok := m.secrets[id]; ok {
+		s.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+func (m *mockClientSecretRepository) Delete(ctx context.Context, clientID, id string) error {
+	s, ok := m.secrets[id]
+	if !ok || s.ClientID != clientID {
+		return domain.ErrClientSecretNotFound
+	}
+	delete(m.secrets, id)
+	return nil
+}
+
+func (m *mockClientSecretRepository) DeleteByClientID(ctx context.Context, clientID string) error {
+	for id, s := range m.secrets {
+		if s.ClientID == clientID {
+			delete(m.secrets, id)
+		}
+	}
+	return nil
+}
+
+func TestClientService_RotateClientSecret(t *testing.T) {
+	ctx := context.Background()
+	clientRepo := newMockClientRepository()
+	secretRepo := newMockClientSecretRepository()
+	clientService := NewClientService(clientRepo, secretRepo)
+	oauth2Service := NewOAuth2Service(clientRepo, secretRepo, newMockAuthCodeRepository(), newMockDeviceCodeRepository(), newMockTokenRepository(), newMockOAuth2UserRepository(), nil, nil, nil)
+
+	hashed, err := auth.HashClientSecret("old-secret")
+	if err != nil {
+		t.Fatalf("Failed to hash secret: %v", err)
+	}
+	// client_secretsに登録されていない既存のクライアント
+	clientRepo.clients["app"] = &domain.ClientApp{ID: "id-app", ClientID: "app", ClientSecret: hashed, CreatedAt: time.Now().Add(-time.Hour)}
+
+	// authenticate はトークンエンドポイントと同じ方法でクライアントを認証します
+	authenticate := func(secret string) error {
+		_, err := oauth2Service.authenticateClient(ctx, "app", secret, "")
+		return err
+	}
+
+	if err := authenticate("old-secret"); err != nil {
+		t.Fatalf("Expected the existing secret to be migrated, got %v", err)
+	}
+	secrets, _ := clientService.ListClientSecrets(ctx, "app")
+	if len(secrets) != 1 || secrets[0].ID != "id-app" || secrets[0].LastUsedAt == nil {
+		t.Fatalf("Expected one migrated secret with usage, got %+v", secrets)
+	}
+
+	t.Run("猶予期間中は両方使用できる", func(t *testing.T) {
+		plain, secret, err := clientService.RotateClientSecret(ctx, "app", 24*time.Hour)
+		if err != nil {
+			t.Fatalf("RotateClientSecret failed: %v", err)
+		}
+		if secret.ExpiresAt != nil || clientRepo.clients["app"].ClientSecret != secret.HashedSecret {
+			t.Errorf("Expected the new secret to be current, got %+v", secret)
+		}
+		if err := authenticate(plain); err != nil {
+			t.Errorf("Expected the new secret to work, got %v", err)
+		}
+		if err := authenticate("old-secret"); err != nil {
+			t.Errorf("Expected the old secret to work during the grace period, got %v", err)
+		}
+		old := secretRepo.secrets["id-app"]
+		if old.ExpiresAt == nil || old.ExpiresAt.Before(time.Now().Add(23*time.Hour)) {
+			t.Errorf("Expected the old secret to expire after the grace period, got %+v", old)
+		}
+
+		// 猶予期間が過ぎると古いシークレットは使用できない
+		expired := time.Now().Add(-time.Second)
+		old.ExpiresAt = &expired
+		if err := authenticate("old-secret"); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient for an expired secret, got %v", err)
+		}
+	})
+
+	t.Run("取り消し", func(t *testing.T) {
+		plain, secret, err := clientService.RotateClientSecret(ctx, "app", 0)
+		if err != nil {
+			t.Fatalf("RotateClientSecret failed: %v", err)
+		}
+		// 猶予期間なしの場合は既存のシークレットを直ちに削除する
+		if len(secretRepo.secrets) != 1 {
+			t.Errorf("Expected only the new secret to remain, got %d", len(secretRepo.secrets))
+		}
+		if err := clientService.RevokeClientSecret(ctx, "app", secret.ID); !errors.Is(err, domain.ErrInvalidClientSecret) {
+			t.Errorf("Expected ErrInvalidClientSecret for the only active secret, got %v", err)
+		}
+		if err := clientService.RevokeClientSecret(ctx, "app", "unknown"); !errors.Is(err, domain.ErrClientSecretNotFound) {
+			t.Errorf("Expected ErrClientSecretNotFound, got %v", err)
+		}
+
+		_, next, err := clientService.RotateClientSecret(ctx, "app", time.Hour)
+		if err != nil {
+			t.Fatalf("RotateClientSecret failed: %v", err)
+		}
+		if err := clientService.RevokeClientSecret(ctx, "app", secret.ID); err != nil {
+			t.Fatalf("RevokeClientSecret failed: %v", err)
+		}
+		if err := authenticate(plain); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient for a revoked secret, got %v", err)
+		}
+		if clientRepo.clients["app"].ClientSecret != next.HashedSecret {
+			t.Error("Expected the client to keep the newest secret")
+		}
+	})
+
+	t.Run("同時に有効なシークレットの上限", func(t *testing.T) {
+		for i := len(secretRepo.secrets); i < domain.MaxActiveClientSecrets; i++ {
+			if _, _, err := clientService.RotateClientSecret(ctx, "app", time.Hour); err != nil {
+				t.Fatalf("RotateClientSecret failed: %v", err)
+			}
+		}
+		if _, _, err := clientService.RotateClientSecret(ctx, "app", time.Hour); !errors.Is(err, domain.ErrInvalidClientSecret) {
+			t.Errorf("Expected ErrInvalidClientSecret over the limit, got %v", err)
+		}
+	})
+
+	t.Run("公開クライアント", func(t *testing.T) {
+		clientRepo.clients["public-cli"] = &domain.ClientApp{
+			ID:                      "id-public-cli",
+			ClientID:                "public-cli",
+			GrantTypes:              []string{domain.GrantTypeDeviceCode},
+			TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodNone,
+		}
+		if _, _, err := clientService.RotateClientSecret(ctx, "public-cli", time.Hour); !errors.Is(err, domain.ErrInvalidClientSecret) {
+			t.Errorf("Expected ErrInvalidClientSecret for a public client, got %v", err)
+		}
+	})
+
+	t.Run("削除", func(t *testing.T) {
+		if err := clientService.DeleteClient(ctx, "id-app"); err != nil {
+			t.Fatalf("DeleteClient failed: %v", err)
+		}
+		if len(secretRepo.secrets) != 0 {
+			t.Errorf("Expected client secrets to be deleted, got %d", len(secretRepo.secrets))
+		}
+	})
+}
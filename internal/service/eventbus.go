@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+const (
+	// EventBusBufferSize は再接続時に再送するために保持する最近の出来事の件数です
+	EventBusBufferSize = 1000
+	// eventBusMaxSubscribers は同時に購読できる数の上限です
+	eventBusMaxSubscribers = 500
+	// eventSubscriptionBuffer は購読ごとに送信待ちにできる出来事の件数です（超えた購読は切断します）
+	eventSubscriptionBuffer = 64
+	// changeFeedPollInterval は他のプロセスで記録された変更履歴を確認する間隔です
+	changeFeedPollInterval = 2 * time.Second
+	// changeFeedOverlap は変更履歴を確認し直す期間です（プロセス間の時刻のずれとトランザクションの遅れを吸収します）
+	changeFeedOverlap = 30 * time.Second
+	// changeFeedBatchSize は1回に取得する変更履歴の件数です
+	changeFeedBatchSize = 200
+)
+
+// ErrTooManySubscribers は購読の数が上限に達していることを表します
+var ErrTooManySubscribers = errors.New("too many event subscribers")
+
+// EventBus はメンバーの変更の出来事をダッシュボードなどの購読者に配信するプロセス内のpub/subです
+// 最近の出来事を保持し、再接続した購読者に最後に受け取った出来事より後のものを再送します
+// クライアントアプリに限定された出来事（トークンの取り消しなど）は配信しません
+type EventBus struct {
+	historyRepo repository.ChangeHistoryRepository
+
+	mu          sync.Mutex
+	buffer      []*domain.Event
+	seen        map[string]time.Time // 最近配信した出来事のIDと発生日時（同じ出来事を重複して配信しないため）
+	subscribers map[*EventSubscription]struct{}
+	closed      bool
+}
+
+// EventSubscription はEventBusの購読です
+type EventSubscription struct {
+	bus    *EventBus
+	events chan *domain.Event
+}
+
+// Events は購読した出来事を受け取るチャネルを返します
+// 購読者の受信が遅れた場合やEventBusが閉じられた場合はチャネルが閉じられます
+func (s *EventSubscription) Events() <-chan *domain.Event {
+	return s.events
+}
+
+// Close は購読を終了します
+func (s *EventSubscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.removeLocked(s)
+}
+
+// NewEventBus は新しいEventBusを作成します
+// historyRepoは同期ツールなど別のプロセスで記録された変更履歴を確認するために使用します（通知を行うデコレーターではなく元のリポジトリを渡します）
+func NewEventBus(historyRepo repository.ChangeHistoryRepository) *EventBus {
+	return &EventBus{
+		historyRepo: historyRepo,
+		seen:        make(map[string]time.Time),
+		subscribers: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// Publish は出来事を保持し、すべての購読者に配信します（配信済みのIDの出来事は無視します）
+func (b *EventBus) Publish(ctx context.Context, event *domain.Event) {
+	if event.ClientID != "" {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	if _, ok := b.seen[event.ID]; ok {
+		return
+	}
+	b.seen[event.ID] = event.OccurredAt
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > EventBusBufferSize {
+		b.buffer = slices.Delete(b.buffer, 0, len(b.buffer)-EventBusBufferSize)
+	}
+
+	for subscription := range b.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			// 受信が遅れている購読者は切断する（再接続時にLast-Event-IDから再送される）
+			b.removeLocked(subscription)
+		}
+	}
+}
+
+// Subscribe は出来事を購読します
+// lastEventIDが空でない場合は、保持している出来事のうちlastEventIDより後のものを返します
+// lastEventIDの出来事を保持していない場合（古すぎる場合やサーバーの再起動後）はfoundがfalseになり、購読者は状態を取得し直す必要があります
+func (b *EventBus) Subscribe(lastEventID string) (subscription *EventSubscription, missed []*domain.Event, found bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.subscribers) >= eventBusMaxSubscribers {
+		return nil, nil, false, ErrTooManySubscribers
+	}
+
+	subscription = &EventSubscription{bus: b, events: make(chan *domain.Event, eventSubscriptionBuffer)}
+	if b.closed {
+		close(subscription.events)
+		return subscription, nil, true, nil
+	}
+	b.subscribers[subscription] = struct{}{}
+
+	if lastEventID == "" {
+		return subscription, nil, true, nil
+	}
+	index := slices.IndexFunc(b.buffer, func(event *domain.Event) bool { return event.ID == lastEventID })
+	if index < 0 {
+		return subscription, nil, false, nil
+	}
+	return subscription, slices.Clone(b.buffer[index+1:]), true, nil
+}
+
+// Close はすべての購読を終了し、以降の出来事を配信しないようにします（サーバーのシャットダウン時に呼び出します）
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for subscription := range b.subscribers {
+		b.removeLocked(subscription)
+	}
+}
+
+// removeLocked は購読を削除してチャネルを閉じます（b.muを取得した状態で呼び出します）
+func (b *EventBus) removeLocked(subscription *EventSubscription) {
+	if _, ok := b.subscribers[subscription]; !ok {
+		return
+	}
+	delete(b.subscribers, subscription)
+	close(subscription.events)
+}
+
+// Start は別のプロセスで記録された変更履歴を定期的に確認し、出来事を配信します
+// 同じプロセスで記録された変更はPublishで配信済みのため、IDで重複を除きます
+func (b *EventBus) Start(ctx context.Context) {
+	ticker := time.NewTicker(changeFeedPollInterval)
+	defer ticker.Stop()
+
+	latest := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			next, err := b.followChanges(ctx, latest)
+			if err != nil {
+				log.Printf("Failed to follow change history: %v", err)
+				continue
+			}
+			latest = next
+		}
+	}
+}
+
+// followChanges はlatestから一定期間遡って記録された変更履歴の出来事を配信し、確認した最新の記録日時を返します
+func (b *EventBus) followChanges(ctx context.Context, latest time.Time) (time.Time, error) {
+	since, afterID := latest.Add(-changeFeedOverlap), ""
+	for {
+		records, err := b.historyRepo.ListSince(ctx, since, afterID, changeFeedBatchSize)
+		if err != nil {
+			return latest, err
+		}
+		for _, record := range records {
+			for _, event := range eventsFromChange(record) {
+				b.Publish(ctx, event)
+			}
+			if record.CreatedAt.After(latest) {
+				latest = record.CreatedAt
+			}
+		}
+		if len(records) < changeFeedBatchSize {
+			break
+		}
+		last := records[len(records)-1]
+		since, afterID = last.CreatedAt, last.ID
+	}
+
+	// 次回の確認の対象にならない出来事は重複の確認も不要になる
+	b.pruneSeen(latest.Add(-changeFeedOverlap))
+	return latest, nil
+}
+
+// pruneSeen はbeforeより前に発生した出来事のIDを重複の確認の対象から外します
+func (b *EventBus) pruneSeen(before time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, occurredAt := range b.seen {
+		if occurredAt.Before(before) {
+			delete(b.seen, id)
+		}
+	}
+}
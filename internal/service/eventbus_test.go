@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// newTestEvent はテスト用の出来事を作成します
+func newTestEvent(id string) *domain.Event {
+	return &domain.Event{ID: id, Type: domain.EventUserUpdated, UserID: "user-1", OccurredAt: time.Now()}
+}
+
+// receiveEventIDs は購読から届いている出来事のIDを返します
+func receiveEventIDs(subscription *EventSubscription) []string {
+	var ids []string
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+// eventIDs は出来事のIDの一覧を返します
+func eventIDs(events []*domain.Event) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func TestEventBus_PublishAndResume(t *testing.T) {
+	bus := NewEventBus(newMockChangeHistoryRepository())
+	ctx := context.Background()
+
+	subscription, missed, found, err := bus.Subscribe("")
+	if err != nil || !found || len(missed) != 0 {
+		t.Fatalf("Unexpected subscribe result: %v %v %v", missed, found, err)
+	}
+	defer subscription.Close()
+
+	bus.Publish(ctx, newTestEvent("1"))
+	bus.Publish(ctx, newTestEvent("2"))
+	// 同じIDの出来事は配信しない
+	bus.Publish(ctx, newTestEvent("1"))
+	// クライアントアプリに限定された出来事は配信しない
+	revoked := newTestEvent("3")
+	revoked.ClientID = "client-1"
+	bus.Publish(ctx, revoked)
+	bus.Publish(ctx, newTestEvent("4"))
+
+	if got := receiveEventIDs(subscription); !slices.Equal(got, []string{"1", "2", "4"}) {
+		t.Errorf("Expected events [1 2 4], got %v", got)
+	}
+
+	// Last-Event-IDより後の出来事を再送する
+	resumed, missed, found, err := bus.Subscribe("2")
+	if err != nil || !found {
+		t.Fatalf("Expected to resume from buffered event: %v %v", found, err)
+	}
+	defer resumed.Close()
+	if got := eventIDs(missed); !slices.Equal(got, []string{"4"}) {
+		t.Errorf("Expected missed events [4], got %v", got)
+	}
+
+	// 保持していない出来事からは再送できない
+	unknown, missed, found, err := bus.Subscribe("unknown")
+	if err != nil || found || len(missed) != 0 {
+		t.Errorf("Expected unknown Last-Event-ID to require reset, got %v %v %v", missed, found, err)
+	}
+	unknown.Close()
+}
+
+func TestEventBus_BufferIsBounded(t *testing.T) {
+	bus := NewEventBus(newMockChangeHistoryRepository())
+	ctx := context.Background()
+
+	for i := 0; i < EventBusBufferSize+10; i++ {
+		bus.Publish(ctx, newTestEvent(fmt.Sprintf("event-%d", i)))
+	}
+
+	if _, _, found, _ := bus.Subscribe("event-5"); found {
+		t.Error("Expected evicted event not to be found")
+	}
+	_, missed, found, _ := bus.Subscribe("event-10")
+	if !found || len(missed) != EventBusBufferSize-1 {
+		t.Errorf("Expected %d missed events, got %d (found=%v)", EventBusBufferSize-1, len(missed), found)
+	}
+}
+
+func TestEventBus_DisconnectsSlowSubscriber(t *testing.T) {
+	bus := NewEventBus(newMockChangeHistoryRepository())
+	ctx := context.Background()
+
+	slow, _, _, _ := bus.Subscribe("")
+	for i := 0; i <= eventSubscriptionBuffer; i++ {
+		bus.Publish(ctx, newTestEvent(fmt.Sprintf("event-%d", i)))
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != eventSubscriptionBuffer {
+		t.Errorf("Expected %d events before disconnect, got %d", eventSubscriptionBuffer, received)
+	}
+	// 切断された購読を閉じても問題ない
+	slow.Close()
+
+	bus.Close()
+	closed, _, _, err := bus.Subscribe("")
+	if err != nil {
+		t.Fatalf("Failed to subscribe closed bus: %v", err)
+	}
+	if _, ok := <-closed.Events(); ok {
+		t.Error("Expected subscription of closed bus to be closed")
+	}
+}
+
+func TestEventBus_TooManySubscribers(t *testing.T) {
+	bus := NewEventBus(newMockChangeHistoryRepository())
+	for i := 0; i < eventBusMaxSubscribers; i++ {
+		if _, _, _, err := bus.Subscribe(""); err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+	}
+	if _, _, _, err := bus.Subscribe(""); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("Expected ErrTooManySubscribers, got %v", err)
+	}
+}
+
+func TestEventBus_FollowChanges(t *testing.T) {
+	historyRepo := newMockChangeHistoryRepository()
+	bus := NewEventBus(historyRepo)
+	ctx := context.Background()
+
+	subscription, _, _, _ := bus.Subscribe("")
+	defer subscription.Close()
+
+	// 同じプロセスで記録された変更は記録時に配信される
+	publishingRepo := NewEventHistoryRepository(historyRepo, bus)
+	now := time.Now()
+	local := &domain.ChangeRecord{ID: "local", UserID: "user-1", Subject: domain.ChangeSubjectProfile, CreatedAt: now.Add(-2 * time.Second),
+		Changes: []domain.FieldChange{{Field: "real_name", New: "じょぎ太郎"}}}
+	if err := publishingRepo.Create(ctx, local); err != nil {
+		t.Fatalf("Failed to create change record: %v", err)
+	}
+
+	// 別のプロセス（sync-profiles）で記録された変更
+	for i := 0; i < changeFeedBatchSize+1; i++ {
+		record := &domain.ChangeRecord{ID: fmt.Sprintf("remote-%03d", i), UserID: fmt.Sprintf("user-%d", i+2), Subject: domain.ChangeSubjectUser,
+			CreatedAt: now.Add(-time.Second), Changes: []domain.FieldChange{{Field: "username", New: "taro"}}}
+		if err := historyRepo.Create(ctx, record); err != nil {
+			t.Fatalf("Failed to create change record: %v", err)
+		}
+	}
+	// 確認し直す期間より前の変更は配信しない
+	if err := historyRepo.Create(ctx, &domain.ChangeRecord{ID: "old", UserID: "user-old", Subject: domain.ChangeSubjectUser,
+		CreatedAt: now.Add(-time.Hour), Changes: []domain.FieldChange{{Field: "username", New: "old"}}}); err != nil {
+		t.Fatalf("Failed to create change record: %v", err)
+	}
+
+	latest, err := bus.followChanges(ctx, now.Add(-3*time.Second))
+	if err != nil {
+		t.Fatalf("Failed to follow changes: %v", err)
+	}
+	if !latest.Equal(now.Add(-time.Second)) {
+		t.Errorf("Expected latest to advance to the newest record, got %v", latest)
+	}
+
+	events := make([]*domain.Event, 0, changeFeedBatchSize+2)
+	for len(events) < changeFeedBatchSize+2 {
+		select {
+		case event := <-subscription.Events():
+			events = append(events, event)
+		default:
+			t.Fatalf("Expected %d events, got %d", changeFeedBatchSize+2, len(events))
+		}
+	}
+	if events[0].Type != domain.EventProfileUpdated || events[1].Type != domain.EventUserCreated || events[1].UserID != "user-2" {
+		t.Errorf("Unexpected events: %+v, %+v", events[0], events[1])
+	}
+	// 出来事のIDは変更履歴から導出されるため、記録時に配信した変更は重複して配信しない
+	if again := eventsFromChange(local); again[0].ID != events[0].ID {
+		t.Errorf("Expected event ID to be derived from change record, got %s and %s", again[0].ID, events[0].ID)
+	}
+
+	if _, err := bus.followChanges(ctx, latest); err != nil {
+		t.Fatalf("Failed to follow changes: %v", err)
+	}
+	if got := receiveEventIDs(subscription); len(got) != 0 {
+		t.Errorf("Expected no duplicate events, got %d", len(got))
+	}
+}
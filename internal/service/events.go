@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+// EventPublisher はシステム内の出来事を通知先に配信します
+// 通知に失敗しても元の処理は取り消さないため、エラーは返さずに実装側でログに出力します
+type EventPublisher interface {
+	Publish(ctx context.Context, event *domain.Event)
+}
+
+// multiPublisher は出来事を複数の通知先に順に配信します
+type multiPublisher []EventPublisher
+
+// NewMultiPublisher は出来事をpublishersのすべてに配信するEventPublisherを作成します
+func NewMultiPublisher(publishers ...EventPublisher) EventPublisher {
+	return multiPublisher(publishers)
+}
+
+// Publish は出来事をすべての通知先に配信します
+func (p multiPublisher) Publish(ctx context.Context, event *domain.Event) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, event)
+	}
+}
+
+// changeEventNamespace は変更履歴から出来事のIDを導出するための名前空間です
+var changeEventNamespace = uuid.MustParse("6b1d2c4e-3f0a-4c8e-9a57-2e4f1b7d9c30")
+
+// eventsFromChange は変更履歴から通知する出来事を、IDと日時を設定して返します
+// IDは変更履歴のIDと出来事の種類から導出するため、同じ変更履歴から何度作成しても同じIDになります
+func eventsFromChange(record *domain.ChangeRecord) []*domain.Event {
+	events := domain.EventsFromChange(record)
+	for _, event := range events {
+		event.ID = uuid.NewSHA1(changeEventNamespace, []byte(record.ID+"/"+string(event.Type))).String()
+		event.OccurredAt = record.CreatedAt
+	}
+	return events
+}
+
+// eventHistoryRepository は変更履歴を記録したときに、変更内容に応じた出来事を通知する変更履歴リポジトリです
+type eventHistoryRepository struct {
+	repository.ChangeHistoryRepository
+	publisher EventPublisher
+}
+
+// NewEventHistoryRepository は変更履歴の記録に合わせて出来事を通知する変更履歴リポジトリを作成します
+// ユーザー情報・プロフィールの変更はすべて変更履歴に記録されるため、記録の時点で通知します
+func NewEventHistoryRepository(repo repository.ChangeHistoryRepository, publisher EventPublisher) repository.ChangeHistoryRepository {
+	return &eventHistoryRepository{ChangeHistoryRepository: repo, publisher: publisher}
+}
+
+// Create は変更履歴を追加し、変更内容に応じた出来事を通知します
+func (r *eventHistoryRepository) Create(ctx context.Context, record *domain.ChangeRecord) error {
+	if err := r.ChangeHistoryRepository.Create(ctx, record); err != nil {
+		return err
+	}
+	for _, event := range eventsFromChange(record) {
+		r.publisher.Publish(ctx, event)
+	}
+	return nil
+}
+
+// eventPayload は出来事を通知する本文（JSON）を表します
+type eventPayload struct {
+	ID        string                 `json:"id"`
+	Type      domain.EventType       `json:"type"`
+	CreatedAt string                 `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// newEventPayload は出来事を通知する本文を作成します（対象のユーザーIDはdata.user_idに含めます）
+func newEventPayload(event *domain.Event) *eventPayload {
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for key, value := range event.Data {
+		data[key] = value
+	}
+	if event.UserID != "" {
+		data["user_id"] = event.UserID
+	}
+	return &eventPayload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.OccurredAt.UTC().Format(time.RFC3339),
+		Data:      data,
+	}
+}
+
+// MarshalEvent は出来事をWebhookの本文と同じ形式のJSONにします（イベントストリームで使用します）
+func MarshalEvent(event *domain.Event) ([]byte, error) {
+	return json.Marshal(newEventPayload(event))
+}
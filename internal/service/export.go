@@ -0,0 +1,304 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/studentid"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/xlsx"
+)
+
+// exportBatchSize はエクスポートで1回に取得するメンバーの件数です
+const exportBatchSize = 200
+
+// ExportService はメンバー一覧のエクスポートを提供します
+type ExportService struct {
+	userRepo      repository.UserRepository
+	profileRepo   repository.ProfileRepository
+	roleRepo      repository.RoleRepository
+	auditRepo     repository.AuditLogRepository
+	exportRoleIDs []string // エクスポートを許可するDiscordロールID（空の場合は幹部）
+}
+
+// NewExportService は新しいExportServiceを作成します
+func NewExportService(
+	userRepo repository.UserRepository,
+	profileRepo repository.ProfileRepository,
+	roleRepo repository.RoleRepository,
+	auditRepo repository.AuditLogRepository,
+	exportRoleIDs []string,
+) *ExportService {
+	return &ExportService{
+		userRepo:      userRepo,
+		profileRepo:   profileRepo,
+		roleRepo:      roleRepo,
+		auditRepo:     auditRepo,
+		exportRoleIDs: exportRoleIDs,
+	}
+}
+
+// MemberExport はメンバー一覧のエクスポートの指定です
+type MemberExport struct {
+	Format domain.ExportFormat
+	Fields []string // domain.ParseExportFieldsで検証した項目のキー
+	Filter domain.MemberFilter
+	Query  string // 監査ログに記録する絞り込み条件（クエリ文字列の形式）
+}
+
+// CanExport はユーザーがメンバー一覧をエクスポートできるかどうかを返します
+// エクスポートを許可するロールが設定されていない場合は幹部のみ許可します
+func (s *ExportService) CanExport(user *domain.User, viewer *domain.ProfileViewer) bool {
+	if user == nil || !user.IsGuildMember() {
+		return false
+	}
+	if len(s.exportRoleIDs) == 0 {
+		return viewer != nil && viewer.Officer
+	}
+	for _, roleID := range user.GuildRoles {
+		if slices.Contains(s.exportRoleIDs, roleID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportMembers は絞り込み条件に一致する在籍中のメンバーを指定された形式でwに書き出し、書き出した件数を返します
+// 書き出しの前に監査ログを記録し、記録できない場合は書き出しません
+// プロフィールの項目はviewerの公開範囲に従い、閲覧できない値は空欄になります
+func (s *ExportService) ExportMembers(ctx context.Context, actor domain.AuditActor, user *domain.User, viewer *domain.ProfileViewer, export *MemberExport, w io.Writer) (int, error) {
+	if !s.CanExport(user, viewer) {
+		return 0, domain.ErrExportForbidden
+	}
+
+	roles, err := s.roleRepo.GetAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get roles: %w", err)
+	}
+	filter := export.Filter
+	filter.RoleIDs = resolveRoleIDs(roles, filter.RoleIDs)
+	filter.Viewer = viewer
+
+	entry := &domain.AuditEntry{
+		ID:          uuid.New().String(),
+		Action:      domain.AuditActionMemberExport,
+		ActorUserID: actor.UserID,
+		Via:         actor.Via,
+		RemoteAddr:  actor.RemoteAddr,
+		Details: map[string]string{
+			"format": string(export.Format),
+			"fields": strings.Join(export.Fields, ","),
+			"query":  export.Query,
+		},
+		CreatedAt: time.Now(),
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		return 0, fmt.Errorf("failed to record audit log: %w", err)
+	}
+
+	rows := newExportRowWriter(export.Format, w)
+	if err := rows.WriteRow(export.Fields); err != nil {
+		return 0, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	// ユーザー名の順にカーソルで取得し、取得した範囲ごとに書き出す
+	now := time.Now()
+	count := 0
+	page := domain.MemberPage{Sort: domain.MemberSortUsername, Limit: exportBatchSize}
+	for {
+		users, next, err := s.userRepo.FindMembers(ctx, filter, page)
+		if err != nil {
+			return count, fmt.Errorf("failed to get members: %w", err)
+		}
+
+		userIDs := make([]string, len(users))
+		for i, u := range users {
+			userIDs[i] = u.ID
+		}
+		profiles, err := s.profileRepo.GetByUserIDs(ctx, userIDs)
+		if err != nil {
+			return count, fmt.Errorf("failed to get profiles: %w", err)
+		}
+		profileMap := make(map[string]*domain.Profile, len(profiles))
+		for _, profile := range profiles {
+			profileMap[profile.UserID] = profile
+		}
+
+		for _, u := range users {
+			values := exportValues(u, profileMap[u.ID], filterRoles(roles, u.GuildRoles), viewer, export.Fields, now)
+			if err := rows.WriteRow(values); err != nil {
+				return count, fmt.Errorf("failed to write member: %w", err)
+			}
+			count++
+		}
+
+		if next == nil {
+			break
+		}
+		page.Cursor = next
+	}
+
+	if err := rows.Close(); err != nil {
+		return count, fmt.Errorf("failed to finish export: %w", err)
+	}
+	log.Printf("Exported %d members as %s (audit log: %s)", count, export.Format, entry.ID)
+	return count, nil
+}
+
+// exportValues はメンバーの指定された項目の値を返します
+// プロフィールは閲覧者が閲覧できない項目を取り除いてから参照します
+func exportValues(user *domain.User, profile *domain.Profile, roles []*domain.Role, viewer *domain.ProfileViewer, fields []string, now time.Time) []string {
+	if profile != nil {
+		profile = profile.RedactFor(viewer)
+	} else {
+		profile = &domain.Profile{}
+	}
+
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		switch field {
+		case domain.ExportFieldID:
+			values[i] = user.ID
+		case domain.ExportFieldDiscordID:
+			values[i] = user.DiscordID
+		case domain.ExportFieldUsername:
+			values[i] = user.Username
+		case domain.ExportFieldDisplayName:
+			values[i] = user.DisplayName
+		case domain.ExportFieldGuildNickname:
+			if user.GuildNickname != nil {
+				values[i] = *user.GuildNickname
+			}
+		case domain.ExportFieldRoles:
+			names := make([]string, len(roles))
+			for j, role := range roles {
+				names[j] = role.Name
+			}
+			values[i] = strings.Join(names, ",")
+		case domain.ExportFieldJoinedAt:
+			values[i] = exportTime(user.JoinedAt)
+		case domain.ExportFieldLastLoginAt:
+			values[i] = exportTime(user.LastLoginAt)
+		case domain.ExportFieldEnrollmentYear:
+			if profile.EnrollmentYear != 0 {
+				values[i] = strconv.Itoa(profile.EnrollmentYear)
+			}
+		case domain.ExportFieldFaculty:
+			values[i] = profile.Faculty
+		case domain.ExportFieldGrade:
+			if profile.EnrollmentYear != 0 {
+				values[i] = strconv.Itoa(studentid.Grade(profile.EnrollmentYear, now))
+			}
+		default:
+			values[i] = profile.FieldValue(field)
+		}
+	}
+	return values
+}
+
+// exportTime は日時をRFC3339形式の文字列で返します（nilは空文字列）
+func exportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// exportRowWriter はエクスポートの1行（先頭は項目のキー）を形式に応じて書き出します
+type exportRowWriter interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+// newExportRowWriter は形式に応じたexportRowWriterを作成します
+func newExportRowWriter(format domain.ExportFormat, w io.Writer) exportRowWriter {
+	switch format {
+	case domain.ExportFormatJSONL:
+		return &jsonlRowWriter{w: bufio.NewWriter(w)}
+	case domain.ExportFormatXLSX:
+		return xlsx.NewWriter(w, "members")
+	default:
+		return &csvRowWriter{w: csv.NewWriter(w), out: w}
+	}
+}
+
+// csvRowWriter はCSVで書き出します
+// Excelで文字化けしないよう先頭にBOMを付け、数式として解釈される値は先頭に「'」を付けます
+type csvRowWriter struct {
+	w       *csv.Writer
+	out     io.Writer
+	started bool
+}
+
+func (c *csvRowWriter) WriteRow(values []string) error {
+	if !c.started {
+		c.started = true
+		if _, err := io.WriteString(c.out, "\ufeff"); err != nil {
+			return err
+		}
+	}
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeCSVFormula(value)
+	}
+	return c.w.Write(escaped)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeCSVFormula は表計算ソフトで数式として解釈される値の先頭に「'」を付けます
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// jsonlRowWriter はJSON Linesで書き出します（1行目の項目のキーを各オブジェクトのキーに使用します）
+type jsonlRowWriter struct {
+	w    *bufio.Writer
+	keys []string
+}
+
+func (j *jsonlRowWriter) WriteRow(values []string) error {
+	if j.keys == nil {
+		j.keys = values
+		return nil
+	}
+
+	// 項目の順序を保つため、オブジェクトを1項目ずつ組み立てる
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range j.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(values[i])
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteString("}\n")
+	_, err := j.w.Write(b.Bytes())
+	return err
+}
+
+func (j *jsonlRowWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// TestExportService_ExportMembers はロールによる制限、公開範囲に従った値、監査ログの記録を確認します
+func TestExportService_ExportMembers(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newMockUserRepository()
+	profileRepo := newMockProfileRepository()
+	roleRepo := newMockRoleRepository()
+	auditRepo := newMockAuditLogRepository()
+	exportService := NewExportService(userRepo, profileRepo, roleRepo, auditRepo, nil)
+
+	_ = roleRepo.Upsert(ctx, &domain.Role{ID: "role-officer", Name: "幹部", Position: 2})
+	_ = roleRepo.Upsert(ctx, &domain.Role{ID: "role-member", Name: "部員", Position: 1})
+	joined := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	officerUser := &domain.User{ID: "user-1", DiscordID: "discord-1", Username: "taro", GuildRoles: []string{"role-member", "role-officer"}, JoinedAt: &joined}
+	memberUser := &domain.User{ID: "user-2", DiscordID: "discord-2", Username: "=hanako", GuildRoles: []string{"role-member"}}
+	for _, u := range []*domain.User{officerUser, memberUser} {
+		_ = userRepo.Create(ctx, u)
+	}
+	_ = profileRepo.Create(ctx, &domain.Profile{ID: "profile-2", UserID: "user-2", RealName: "じょぎ花子", StudentID: "24X0001", EnrollmentYear: 2024, Faculty: "X",
+		Visibility: map[string]domain.FieldVisibility{domain.ProfileFieldStudentID: domain.VisibilityHidden}})
+
+	officer := domain.NewProfileViewer(officerUser, []string{"role-officer"})
+	member := domain.NewProfileViewer(memberUser, []string{"role-officer"})
+	actor := domain.AuditActor{UserID: officerUser.ID, Via: domain.AuditViaWeb, RemoteAddr: "192.0.2.1"}
+	export := &MemberExport{
+		Format: domain.ExportFormatCSV,
+		Fields: []string{domain.ExportFieldUsername, domain.ExportFieldRoles, domain.ProfileFieldRealName, domain.ProfileFieldStudentID, domain.ExportFieldFaculty},
+		Query:  "role=部員",
+	}
+
+	t.Run("幹部以外は拒否", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := exportService.ExportMembers(ctx, actor, memberUser, member, export, &buf); !errors.Is(err, domain.ErrExportForbidden) {
+			t.Fatalf("Expected ErrExportForbidden, got %v", err)
+		}
+		if buf.Len() != 0 || len(auditRepo.entries) != 0 {
+			t.Errorf("Expected nothing to be written or audited")
+		}
+	})
+
+	t.Run("監査ログを記録できない場合は書き出さない", func(t *testing.T) {
+		auditRepo.createError = errors.New("db down")
+		defer func() { auditRepo.createError = nil }()
+		var buf bytes.Buffer
+		if _, err := exportService.ExportMembers(ctx, actor, officerUser, officer, export, &buf); err == nil {
+			t.Fatal("Expected error")
+		}
+		if buf.Len() != 0 {
+			t.Errorf("Expected nothing to be written, got %q", buf.String())
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := exportService.ExportMembers(ctx, actor, officerUser, officer, export, &buf)
+		if err != nil {
+			t.Fatalf("ExportMembers failed: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("Expected 2 members, got %d", count)
+		}
+
+		content := buf.String()
+		if !strings.HasPrefix(content, "\ufeff") {
+			t.Error("Expected BOM")
+		}
+		records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\ufeff"))).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to parse CSV: %v", err)
+		}
+		rows := make(map[string][]string)
+		for _, record := range records[1:] {
+			rows[record[0]] = record
+		}
+		if got := strings.Join(records[0], ","); got != "username,roles,real_name,student_id,faculty" {
+			t.Errorf("Unexpected header: %s", got)
+		}
+		// ロールの並び順はロール一覧の順序（リポジトリでは上位順）に従う
+		if got := rows["taro"]; got == nil || (got[1] != "幹部,部員" && got[1] != "部員,幹部") {
+			t.Errorf("Expected role names, got %v", got)
+		}
+		// 数式として解釈される値はエスケープし、非公開の学籍番号と学部は空欄にする
+		if got := rows["'=hanako"]; got == nil || got[2] != "じょぎ花子" || got[3] != "" || got[4] != "" {
+			t.Errorf("Unexpected row for hanako: %v", got)
+		}
+
+		if len(auditRepo.entries) != 1 {
+			t.Fatalf("Expected 1 audit entry, got %d", len(auditRepo.entries))
+		}
+		entry := auditRepo.entries[0]
+		if entry.Action != domain.AuditActionMemberExport || entry.ActorUserID != "user-1" || entry.RemoteAddr != "192.0.2.1" ||
+			entry.Details["format"] != "csv" || entry.Details["query"] != "role=部員" || entry.Details["fields"] != "username,roles,real_name,student_id,faculty" {
+			t.Errorf("Unexpected audit entry: %+v", entry)
+		}
+	})
+
+	t.Run("JSON Lines", func(t *testing.T) {
+		jsonl := *export
+		jsonl.Format = domain.ExportFormatJSONL
+		var buf bytes.Buffer
+		if _, err := exportService.ExportMembers(ctx, actor, officerUser, officer, &jsonl, &buf); err != nil {
+			t.Fatalf("ExportMembers failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 lines, got %d", len(lines))
+		}
+		for _, line := range lines {
+			var row map[string]string
+			if err := json.Unmarshal([]byte(line), &row); err != nil {
+				t.Fatalf("Failed to parse line %q: %v", line, err)
+			}
+			// JSON Linesでは値をそのまま出力する
+			if row["username"] == "=hanako" && row["real_name"] != "じょぎ花子" {
+				t.Errorf("Unexpected row: %v", row)
+			}
+			if !strings.HasPrefix(line, `{"username":`) {
+				t.Errorf("Expected fields in requested order, got %s", line)
+			}
+		}
+	})
+}
+
+// TestExportService_CanExport はエクスポートを許可するロールの設定による判定を確認します
+func TestExportService_CanExport(t *testing.T) {
+	exportService := NewExportService(nil, nil, nil, nil, []string{"role-secretary"})
+	secretary := &domain.User{ID: "user-1", GuildRoles: []string{"role-secretary"}}
+	officer := &domain.User{ID: "user-2", GuildRoles: []string{"role-officer"}}
+	left := time.Now()
+	former := &domain.User{ID: "user-3", GuildRoles: []string{"role-secretary"}, LeftAt: &left}
+
+	if !exportService.CanExport(secretary, domain.NewProfileViewer(secretary, []string{"role-officer"})) {
+		t.Error("Expected secretary to be allowed")
+	}
+	// 許可するロールを設定した場合は幹部でも許可しない
+	if exportService.CanExport(officer, domain.NewProfileViewer(officer, []string{"role-officer"})) {
+		t.Error("Expected officer without the export role to be denied")
+	}
+	if exportService.CanExport(former, domain.NewProfileViewer(former, []string{"role-officer"})) {
+		t.Error("Expected former member to be denied")
+	}
+}
+
+// モックAuditLogRepository
+type mockAuditLogRepository struct {
+	entries     []*domain.AuditEntry
+	createError error
+}
+
+func newMockAuditLogRepository() *mockAuditLogRepository {
+	return &mockAuditLogRepository{}
+}
+
+func (m *mockAuditLogRepository) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	if m.createError != nil {
+		return m.createError
+	}
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *mockAuditLogRepository) List(ctx context.Context, action string, limit, offset int) ([]*domain.AuditEntry, error) {
+	var entries []*domain.AuditEntry
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if action == "" || m.entries[i].Action == action {
+			entries = append(entries, m.entries[i])
+		}
+	}
+	return entries, nil
+}
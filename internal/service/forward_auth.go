@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwt"
+)
+
+// ForwardAuthService はリバースプロキシ（nginx auth_request・Traefik・Caddyなど）からの認証の問い合わせに応答します
+// ログイン機能のない内部ツールを、じょぎのDiscordログインとロールで保護するために使用します
+type ForwardAuthService struct {
+	authService        *AuthService
+	appPasswordService *AppPasswordService
+	userRepo           repository.UserRepository
+	roleRepo           repository.RoleRepository
+	jwtSecret          string
+	policies           domain.AccessPolicies
+}
+
+// ForwardAuthRequest はリバースプロキシからの認証の問い合わせです
+type ForwardAuthRequest struct {
+	SessionToken  string // session_token Cookieの値
+	BearerToken   string // AuthorizationヘッダーのBearerトークン（/tokenで発行したJWT）
+	BasicUsername string // AuthorizationヘッダーのBasic認証のユーザー名
+	BasicPassword string // AuthorizationヘッダーのBasic認証のパスワード（proxyスコープのアプリパスワード）
+	Host          string // 元のリクエストのホスト
+	Path          string // 元のリクエストのパス
+}
+
+// HasCredentials はAuthorizationヘッダーの認証情報（BearerトークンまたはBasic認証）があるかどうかを返します
+func (r *ForwardAuthRequest) HasCredentials() bool {
+	return r.BearerToken != "" || r.BasicUsername != ""
+}
+
+// ForwardAuthIdentity はアクセスを許可したユーザーと、上流に渡すロールです
+type ForwardAuthIdentity struct {
+	User          *domain.User
+	RoleNames     []string // ユーザーのロール名（上位のロールから順に並べます）
+	ByCredentials bool     // セッションではなくAuthorizationヘッダー（Bearerトークン・アプリパスワード）で認証したか
+}
+
+// NewForwardAuthService は新しいForwardAuthServiceを作成します
+func NewForwardAuthService(
+	authService *AuthService,
+	appPasswordService *AppPasswordService,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	jwtSecret string,
+	policies domain.AccessPolicies,
+) *ForwardAuthService {
+	return &ForwardAuthService{
+		authService:        authService,
+		appPasswordService: appPasswordService,
+		userRepo:           userRepo,
+		roleRepo:           roleRepo,
+		jwtSecret:          jwtSecret,
+		policies:           policies,
+	}
+}
+
+// Policies は保護するホスト・パスのアクセス条件を返します
+func (s *ForwardAuthService) Policies() domain.AccessPolicies {
+	return s.policies
+}
+
+// Authorize はセッション・Bearerトークン・アプリパスワードでユーザーを認証し、ホスト・パスのアクセス条件を満たすか確認します
+// 認証できない場合はdomain.ErrUnauthenticated、アクセス条件がない・満たさない場合はdomain.ErrAccessDeniedを返します
+func (s *ForwardAuthService) Authorize(ctx context.Context, req *ForwardAuthRequest) (*ForwardAuthIdentity, error) {
+	user, byCredentials, err := s.authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := s.policies.Match(req.Host, req.Path)
+	if policy == nil {
+		return nil, fmt.Errorf("%w: no policy for %s%s", domain.ErrAccessDenied, req.Host, req.Path)
+	}
+	return s.authorizeUser(ctx, user, byCredentials, policy, req)
+}
+
+// AuthorizePolicy はセッション・Bearerトークン・アプリパスワードでユーザーを認証し、指定されたアクセス条件を満たすか確認します
+// 認証プロキシのルートなど、呼び出し側で適用する条件を決める場合に使用します
+func (s *ForwardAuthService) AuthorizePolicy(ctx context.Context, req *ForwardAuthRequest, policy *domain.AccessPolicy) (*ForwardAuthIdentity, error) {
+	user, byCredentials, err := s.authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.authorizeUser(ctx, user, byCredentials, policy, req)
+}
+
+// authorizeUser はユーザーがアクセス条件を満たすか確認し、上流に渡すロール名を返します
+func (s *ForwardAuthService) authorizeUser(ctx context.Context, user *domain.User, byCredentials bool, policy *domain.AccessPolicy, req *ForwardAuthRequest) (*ForwardAuthIdentity, error) {
+	roles, err := s.roleRepo.GetByIDs(ctx, user.GuildRoles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	if !policy.Allows(user, roles) {
+		return nil, fmt.Errorf("%w: user %s is not allowed for %s%s", domain.ErrAccessDenied, user.ID, req.Host, req.Path)
+	}
+
+	return &ForwardAuthIdentity{User: user, RoleNames: roleNames(roles), ByCredentials: byCredentials}, nil
+}
+
+// authenticate はセッション（優先）・Bearerトークン・アプリパスワードからユーザーを取得し、Authorizationヘッダーで認証したかどうかを返します
+func (s *ForwardAuthService) authenticate(ctx context.Context, req *ForwardAuthRequest) (*domain.User, bool, error) {
+	if req.SessionToken != "" {
+		if user, err := s.authService.GetUserBySessionToken(ctx, req.SessionToken); err == nil {
+			return user, false, nil
+		}
+	}
+	if req.BearerToken != "" {
+		claims, err := jwt.ValidateToken(req.BearerToken, s.jwtSecret)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %v", domain.ErrUnauthenticated, err)
+		}
+		user, err := s.userRepo.GetByID(ctx, claims.UserID)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, false, fmt.Errorf("%w: %v", domain.ErrUnauthenticated, err)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get user: %w", err)
+		}
+		return user, true, nil
+	}
+	if req.BasicUsername != "" {
+		user, err := s.appPasswordService.Authenticate(ctx, req.BasicUsername, req.BasicPassword, domain.AppPasswordScopeProxy)
+		if err != nil {
+			return nil, false, err
+		}
+		return user, true, nil
+	}
+	return nil, false, domain.ErrUnauthenticated
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwt"
+)
+
+func TestForwardAuthService_Authorize(t *testing.T) {
+	const jwtSecret = "test-secret-key-at-least-32-characters"
+	ctx := context.Background()
+
+	userRepo := newMockUserRepository()
+	roleRepo := newMockRoleRepository()
+	sessionRepo := newMockSessionRepository()
+	roleRepo.roles["role-member"] = &domain.Role{ID: "role-member", Name: "部員", Position: 1}
+	roleRepo.roles["role-officer"] = &domain.Role{ID: "role-officer", Name: "幹部", Position: 5}
+
+	leftAt := time.Now()
+	member := &domain.User{ID: "user-1", DiscordID: "d-1", Username: "taro", GuildRoles: []string{"role-member"}}
+	officer := &domain.User{ID: "user-2", DiscordID: "d-2", Username: "hanako", GuildRoles: []string{"role-member", "role-officer"}}
+	left := &domain.User{ID: "user-3", DiscordID: "d-3", Username: "jiro", GuildRoles: []string{"role-member"}, LeftAt: &leftAt}
+	for _, user := range []*domain.User{member, officer, left} {
+		userRepo.Create(ctx, user)
+	}
+	sessionRepo.Create(ctx, &domain.Session{ID: "s-1", UserID: member.ID, Token: "member-session", ExpiresAt: time.Now().Add(time.Hour)})
+	sessionRepo.Create(ctx, &domain.Session{ID: "s-2", UserID: officer.ID, Token: "officer-session", ExpiresAt: time.Now().Add(time.Hour)})
+	sessionRepo.Create(ctx, &domain.Session{ID: "s-3", UserID: left.ID, Token: "left-session", ExpiresAt: time.Now().Add(time.Hour)})
+	sessionRepo.Create(ctx, &domain.Session{ID: "s-4", UserID: member.ID, Token: "expired-session", ExpiresAt: time.Now().Add(-time.Hour)})
+
+	policies, err := domain.ParseAccessPolicies([]byte(`[
+		{"host": "wiki.example.com"},
+		{"host": "wiki.example.com", "path_prefix": "/admin", "roles": ["幹部"]},
+		{"host": "*.tools.example.com", "roles": ["role-officer"]}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to parse policies: %v", err)
+	}
+
+	authService := NewAuthService(nil, userRepo, sessionRepo, newMockProfileRepository(), roleRepo, newMockChangeHistoryRepository(), "guild", nil)
+	appPasswordService := NewAppPasswordService(newMockAppPasswordRepository(), userRepo)
+	service := NewForwardAuthService(authService, appPasswordService, userRepo, roleRepo, jwtSecret, policies)
+
+	_, proxyPassword, err := appPasswordService.Create(ctx, member.ID, "git", []string{domain.AppPasswordScopeProxy}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create app password: %v", err)
+	}
+	_, ldapPassword, err := appPasswordService.Create(ctx, member.ID, "Gitea", []string{domain.AppPasswordScopeLDAP}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create app password: %v", err)
+	}
+
+	memberJWT, err := jwt.GenerateToken(member.ID, member.DiscordID, member.Username, jwtSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		req     *ForwardAuthRequest
+		wantErr error
+		wantID  string
+	}{
+		{name: "member session", req: &ForwardAuthRequest{SessionToken: "member-session", Host: "wiki.example.com", Path: "/page"}, wantID: member.ID},
+		{name: "host with port", req: &ForwardAuthRequest{SessionToken: "member-session", Host: "Wiki.Example.com:443", Path: "/"}, wantID: member.ID},
+		{name: "bearer token", req: &ForwardAuthRequest{BearerToken: memberJWT, Host: "wiki.example.com", Path: "/"}, wantID: member.ID},
+		{name: "longer path prefix requires role", req: &ForwardAuthRequest{SessionToken: "member-session", Host: "wiki.example.com", Path: "/admin/users"}, wantErr: domain.ErrAccessDenied},
+		{name: "role by name", req: &ForwardAuthRequest{SessionToken: "officer-session", Host: "wiki.example.com", Path: "/admin"}, wantID: officer.ID},
+		{name: "path prefix matches by segment", req: &ForwardAuthRequest{SessionToken: "member-session", Host: "wiki.example.com", Path: "/administrator"}, wantID: member.ID},
+		{name: "wildcard host", req: &ForwardAuthRequest{SessionToken: "officer-session", Host: "jupyter.tools.example.com", Path: "/"}, wantID: officer.ID},
+		{name: "wildcard host denies member", req: &ForwardAuthRequest{SessionToken: "member-session", Host: "jupyter.tools.example.com", Path: "/"}, wantErr: domain.ErrAccessDenied},
+		{name: "wildcard does not match apex", req: &ForwardAuthRequest{SessionToken: "officer-session", Host: "tools.example.com", Path: "/"}, wantErr: domain.ErrAccessDenied},
+		{name: "unknown host", req: &ForwardAuthRequest{SessionToken: "officer-session", Host: "evil.example.net", Path: "/"}, wantErr: domain.ErrAccessDenied},
+		{name: "left member", req: &ForwardAuthRequest{SessionToken: "left-session", Host: "wiki.example.com", Path: "/"}, wantErr: domain.ErrAccessDenied},
+		{name: "expired session", req: &ForwardAuthRequest{SessionToken: "expired-session", Host: "wiki.example.com", Path: "/"}, wantErr: domain.ErrUnauthenticated},
+		{name: "invalid bearer token", req: &ForwardAuthRequest{BearerToken: "invalid", Host: "wiki.example.com", Path: "/"}, wantErr: domain.ErrUnauthenticated},
+		{name: "app password", req: &ForwardAuthRequest{BasicUsername: "taro", BasicPassword: proxyPassword, Host: "wiki.example.com", Path: "/"}, wantID: member.ID},
+		{name: "app password without proxy scope", req: &ForwardAuthRequest{BasicUsername: "taro", BasicPassword: ldapPassword, Host: "wiki.example.com", Path: "/"}, wantErr: domain.ErrUnauthenticated},
+		{name: "app password of another user", req: &ForwardAuthRequest{BasicUsername: "hanako", BasicPassword: proxyPassword, Host: "wiki.example.com", Path: "/"}, wantErr: domain.ErrUnauthenticated},
+		{name: "no credentials", req: &ForwardAuthRequest{Host: "wiki.example.com", Path: "/"}, wantErr: domain.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := service.Authorize(ctx, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to authorize: %v", err)
+			}
+			if identity.User.ID != tt.wantID {
+				t.Errorf("Expected user %s, got %s", tt.wantID, identity.User.ID)
+			}
+		})
+	}
+
+	identity, err := service.Authorize(ctx, &ForwardAuthRequest{SessionToken: "officer-session", Host: "wiki.example.com", Path: "/"})
+	if err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	if !slices.Equal(identity.RoleNames, []string{"幹部", "部員"}) {
+		t.Errorf("Expected role names ordered by position, got %v", identity.RoleNames)
+	}
+}
+
+func TestParseAccessPolicies(t *testing.T) {
+	for _, data := range []string{
+		`{"host": "wiki.example.com"}`,
+		`[{"host": ""}]`,
+		`[{"host": "*"}]`,
+		`[{"host": "wiki.example.com:8080"}]`,
+		`[{"host": "wiki.example.com", "path_prefix": "admin"}]`,
+		`[null]`,
+	} {
+		if _, err := domain.ParseAccessPolicies([]byte(data)); !errors.Is(err, domain.ErrInvalidAccessPolicy) {
+			t.Errorf("Expected ErrInvalidAccessPolicy for %s, got %v", data, err)
+		}
+	}
+}
+
+// モックSessionRepository
+type mockSessionRepository struct {
+	sessions map[string]*domain.Session // トークンをキーとする
+}
+
+func newMockSessionRepository() *mockSessionRepository {
+	return &mockSessionRepository{sessions: make(map[string]*domain.Session)}
+}
+
+func (m *mockSessionRepository) Create(ctx context.Context, session *domain.Session) error {
+	m.sessions[session.Token] = session
+	return nil
+}
+
+func (m *mockSessionRepository) GetByID(ctx context.Context, id string) (*domain.Session, error) {
+	for _, session := range m.sessions {
+		if session.ID == id {
+			return session, nil
+		}
+	}
+	return nil, domain.ErrSessionNotFound
+}
+
+func (m *mockSessionRepository) GetByToken(ctx context.Context, token string) (*domain.Session, error) {
+	session, ok := m.sessions[token]
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (m *mockSessionRepository) GetByUserID(ctx context.Context, userID string) ([]*domain.Session, error) {
+	var sessions []*domain.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockSessionRepository) Delete(ctx context.Context, id string) error {
+	for token, session := range m.sessions {
+		if session.ID == id {
+			delete(m.sessions, token)
+		}
+	}
+	return nil
+}
+
+func (m *mockSessionRepository) DeleteByToken(ctx context.Context, token string) error {
+	delete(m.sessions, token)
+	return nil
+}
+
+func (m *mockSessionRepository) DeleteExpired(ctx context.Context) error {
+	for token, session := range m.sessions {
+		if session.IsExpired() {
+			delete(m.sessions, token)
+		}
+	}
+	return nil
+}
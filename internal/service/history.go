@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+)
+
+// HistoryService はプロフィール・ユーザー情報の変更履歴の参照を提供します
+type HistoryService struct {
+	historyRepo repository.ChangeHistoryRepository
+	profileRepo repository.ProfileRepository
+}
+
+// NewHistoryService は新しいHistoryServiceを作成します
+func NewHistoryService(historyRepo repository.ChangeHistoryRepository, profileRepo repository.ProfileRepository) *HistoryService {
+	return &HistoryService{
+		historyRepo: historyRepo,
+		profileRepo: profileRepo,
+	}
+}
+
+// ListChanges は変更履歴を新しい順に取得します（userIDが空の場合は全ユーザー）
+// プロフィールの変更は、各ユーザーの現在の公開範囲に従ってviewerが閲覧できない値を取り除きます
+func (s *HistoryService) ListChanges(ctx context.Context, viewer *domain.ProfileViewer, userID string, limit, offset int) ([]*domain.ChangeRecord, error) {
+	records, err := s.historyRepo.List(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list change history: %w", err)
+	}
+
+	var userIDs []string
+	for _, record := range records {
+		if record.Subject == domain.ChangeSubjectProfile && !slices.Contains(userIDs, record.UserID) {
+			userIDs = append(userIDs, record.UserID)
+		}
+	}
+	profiles, err := s.profileRepo.GetByUserIDs(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profiles: %w", err)
+	}
+	profileMap := make(map[string]*domain.Profile, len(profiles))
+	for _, profile := range profiles {
+		profileMap[profile.UserID] = profile
+	}
+
+	redacted := make([]*domain.ChangeRecord, len(records))
+	for i, record := range records {
+		redacted[i] = record.RedactFor(viewer, profileMap[record.UserID])
+	}
+	return redacted, nil
+}
+
+// recordChange は変更内容を変更履歴に記録します（変更がなければ何もしません）
+// 履歴の記録に失敗しても元の更新は取り消さず、警告をログに出力します
+func recordChange(ctx context.Context, historyRepo repository.ChangeHistoryRepository, userID string, subject domain.ChangeSubject, actor domain.ChangeActor, changes []domain.FieldChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	record := &domain.ChangeRecord{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Subject:     subject,
+		ActorType:   actor.Type,
+		ActorUserID: actor.UserID,
+		Changes:     changes,
+		CreatedAt:   time.Now(),
+	}
+	if err := historyRepo.Create(ctx, record); err != nil {
+		log.Printf("Warning: Failed to record %s change history for user %s: %v", subject, userID, err)
+	}
+}
+
+// recordProfileChange はプロフィールの変更前後の差分を変更履歴に記録します（beforeがnilの場合は新規作成）
+func recordProfileChange(ctx context.Context, historyRepo repository.ChangeHistoryRepository, before, after *domain.Profile, actor domain.ChangeActor) {
+	recordChange(ctx, historyRepo, after.UserID, domain.ChangeSubjectProfile, actor, domain.DiffProfiles(before, after))
+}
+
+// recordUserChange はユーザー情報の変更前後の差分を変更履歴に記録します（beforeがnilの場合は新規作成）
+func recordUserChange(ctx context.Context, historyRepo repository.ChangeHistoryRepository, before, after *domain.User, actor domain.ChangeActor) {
+	recordChange(ctx, historyRepo, after.ID, domain.ChangeSubjectUser, actor, domain.DiffUsers(before, after))
+}
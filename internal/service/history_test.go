@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+func TestHistoryService_ListChanges(t *testing.T) {
+	ctx := context.Background()
+	profileRepo := newMockProfileRepository()
+	historyRepo := newMockChangeHistoryRepository()
+	service := NewHistoryService(historyRepo, profileRepo)
+
+	// 本名は幹部のみ、趣味はメンバー全員に公開する
+	profileRepo.Create(ctx, &domain.Profile{
+		ID:     "profile-1",
+		UserID: "user-1",
+		Visibility: map[string]domain.FieldVisibility{
+			domain.ProfileFieldRealName: domain.VisibilityOfficers,
+		},
+	})
+	recordChange(ctx, historyRepo, "user-1", domain.ChangeSubjectProfile, domain.ChangeActor{Type: domain.ChangeActorSync}, []domain.FieldChange{
+		{Field: domain.ProfileFieldRealName, Old: "じょぎ太郎", New: "じょぎ花子"},
+		{Field: domain.ProfileFieldHobbies, Old: "", New: "読書"},
+	})
+	recordChange(ctx, historyRepo, "user-1", domain.ChangeSubjectUser, domain.ChangeActor{Type: domain.ChangeActorSync}, []domain.FieldChange{
+		{Field: "username", Old: "taro", New: "hanako"},
+	})
+
+	tests := []struct {
+		name         string
+		viewer       *domain.ProfileViewer
+		wantRedacted bool
+	}{
+		{"member", &domain.ProfileViewer{UserID: "user-2", Member: true}, true},
+		{"officer", &domain.ProfileViewer{UserID: "officer-1", Officer: true, Member: true}, false},
+		{"self", &domain.ProfileViewer{UserID: "user-1", Member: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := service.ListChanges(ctx, tt.viewer, "user-1", 0, 0)
+			if err != nil {
+				t.Fatalf("ListChanges failed: %v", err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("Expected 2 records, got %d", len(records))
+			}
+			for _, record := range records {
+				for _, change := range record.Changes {
+					switch change.Field {
+					case domain.ProfileFieldRealName:
+						if change.Redacted != tt.wantRedacted {
+							t.Errorf("Expected redacted=%v for real_name, got %+v", tt.wantRedacted, change)
+						}
+						if change.Redacted && (change.Old != "" || change.New != "") {
+							t.Errorf("Expected the redacted values to be removed, got %+v", change)
+						}
+					default:
+						// メンバーに公開している項目とユーザー情報の変更は伏せない
+						if change.Redacted {
+							t.Errorf("Expected %s to be visible, got %+v", change.Field, change)
+						}
+					}
+				}
+			}
+		})
+	}
+
+	// 伏せた値は保存されている履歴には影響しない
+	stored, _ := historyRepo.List(ctx, "user-1", 0, 0)
+	for _, record := range stored {
+		for _, change := range record.Changes {
+			if change.Redacted {
+				t.Errorf("Expected the stored record to be unchanged, got %+v", change)
+			}
+		}
+	}
+}
+
+// モックChangeHistoryRepository
+type mockChangeHistoryRepository struct {
+	records []*domain.ChangeRecord
+}
+
+func newMockChangeHistoryRepository() *mockChangeHistoryRepository {
+	return &mockChangeHistoryRepository{}
+}
+
+func (m *mockChangeHistoryRepository) Create(ctx context.Context, record *domain.ChangeRecord) error {
+	version := 0
+	for _, r := range m.records {
+		if r.UserID == record.UserID && r.Subject == record.Subject {
+			version = max(version, r.Version)
+		}
+	}
+	record.Version = version + 1
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *mockChangeHistoryRepository) List(ctx context.Context, userID string, limit, offset int) ([]*domain.ChangeRecord, error) {
+	var records []*domain.ChangeRecord
+	for i := len(m.records) - 1; i >= 0; i-- {
+		if userID == "" || m.records[i].UserID == userID {
+			records = append(records, m.records[i])
+		}
+	}
+	return records, nil
+}
+
+func (m *mockChangeHistoryRepository) ListSince(ctx context.Context, since time.Time, afterID string, limit int) ([]*domain.ChangeRecord, error) {
+	var records []*domain.ChangeRecord
+	for _, r := range m.records {
+		if r.CreatedAt.After(since) || (r.CreatedAt.Equal(since) && r.ID > afterID) {
+			records = append(records, r)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+		return records[i].ID < records[j].ID
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// newOutboundHTTPClient はユーザーが登録したURL（Webhookの送信先・jwks_uriなど）に接続するためのHTTPクライアントを作成します
+// allowPrivateNetworksがfalseの場合、プライベートIPアドレス・ループバックアドレスへの接続を拒否します（SSRF対策）
+// 環境変数のプロキシは使用せず、リダイレクト先は検証していないため追跡しません（3xxはそのまま返します）
+func newOutboundHTTPClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = rejectPrivateAddress
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// rejectPrivateAddress はプライベートIPアドレス・ループバックアドレスなどへの接続を拒否します
+// 名前解決後のアドレスで判定するため、DNSで内部のアドレスを返すホスト名も拒否します
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address: %s", address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("connection to private address %s is not allowed", ip)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwks"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwt"
+)
+
+// JWTAccessTokenIssuer はaccess_token_formatがjwtのクライアントに発行するアクセストークン（RFC 9068）に署名し、検証します
+type JWTAccessTokenIssuer struct {
+	signer   *jwt.Signer
+	issuer   string
+	roleRepo repository.RoleRepository
+}
+
+// NewJWTAccessTokenIssuer は新しいJWTAccessTokenIssuerを作成します
+// issuerはこのサーバーの公開URLで、アクセストークンのissに設定します
+func NewJWTAccessTokenIssuer(signer *jwt.Signer, issuer string, roleRepo repository.RoleRepository) *JWTAccessTokenIssuer {
+	return &JWTAccessTokenIssuer{
+		signer:   signer,
+		issuer:   issuer,
+		roleRepo: roleRepo,
+	}
+}
+
+// PublicKeys はリソースサーバーがアクセストークンの署名を検証するための公開鍵を返します
+func (i *JWTAccessTokenIssuer) PublicKeys() *jwks.Set {
+	return i.signer.PublicKeys()
+}
+
+// issue はユーザーとクライアントのアクセストークンに署名します
+// audにはclient_id、scopeにはクライアントに登録したスコープ、rolesにはユーザーのロール名（上位のロールから順）を設定します
+// スコープを登録していないクライアントには、AllowsScopeと同じく登録できるすべてのスコープを設定します
+func (i *JWTAccessTokenIssuer) issue(ctx context.Context, user *domain.User, client *domain.ClientApp, jti string, issuedAt, expiresAt time.Time) (string, error) {
+	roles, err := i.roleRepo.GetByIDs(ctx, user.GuildRoles)
+	if err != nil {
+		return "", fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	return i.signer.SignAccessToken(&jwt.AccessToken{
+		ID:        jti,
+		Issuer:    i.issuer,
+		Subject:   user.ID,
+		Audience:  []string{client.ClientID},
+		ClientID:  client.ClientID,
+		Scope:     strings.Join(client.EffectiveScopes(), " "),
+		Roles:     roleNames(roles),
+		IssuedAt:  issuedAt,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// parse はこのサーバーが署名したアクセストークンを検証します（allowExpiredがtrueの場合は期限切れも受け付けます）
+func (i *JWTAccessTokenIssuer) parse(token string, allowExpired bool) (*jwt.AccessToken, error) {
+	return i.signer.ParseAccessToken(token, i.issuer, allowExpired)
+}
+
+// roleNames はロールを上位のロールから順に並べ、ロール名を返します
+func roleNames(roles []*domain.Role) []string {
+	sorted := append([]*domain.Role(nil), roles...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position > sorted[j].Position })
+	names := make([]string, len(sorted))
+	for i, role := range sorted {
+		names[i] = role.Name
+	}
+	return names
+}
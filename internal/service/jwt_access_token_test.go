@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwt"
+)
+
+func TestOAuth2Service_JWTAccessToken(t *testing.T) {
+	ctx := context.Background()
+	const issuer = "https://auth.example.com"
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := jwt.NewSigner(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	roleRepo := newMockRoleRepository()
+	roleRepo.roles["r1"] = &domain.Role{ID: "r1", Name: "部員", Position: 1}
+	roleRepo.roles["r2"] = &domain.Role{ID: "r2", Name: "幹部", Position: 5}
+	userRepo := newMockOAuth2UserRepository()
+	userRepo.users["user-1"] = &domain.User{ID: "user-1", DiscordID: "123", GuildRoles: []string{"r1", "r2"}}
+
+	hashed, err := auth.HashClientSecret("secret")
+	if err != nil {
+		t.Fatalf("Failed to hash secret: %v", err)
+	}
+	clientRepo := newMockClientRepository()
+	clientRepo.clients["client-a"] = &domain.ClientApp{
+		ID:                "id-a",
+		ClientID:          "client-a",
+		ClientSecret:      hashed,
+		RedirectURIs:      []string{"https://app.example.com/callback"},
+		Scopes:            []string{domain.ClientScopeProfile},
+		AccessTokenFormat: domain.AccessTokenFormatJWT,
+	}
+
+	authCodeRepo := newMockAuthCodeRepository()
+	tokenRepo := newMockTokenRepository()
+	jwtAccessTokens := NewJWTAccessTokenIssuer(signer, issuer, roleRepo)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, jwtAccessTokens)
+
+	// exchange は認可コードを発行し、アクセストークンに交換します
+	exchange := func(service *OAuth2Service, code string) (*TokenResponse, error) {
+		authCodeRepo.authCodes[code] = &domain.AuthCode{
+			Code:        code,
+			ClientID:    "client-a",
+			UserID:      "user-1",
+			RedirectURI: "https://app.example.com/callback",
+			ExpiresAt:   time.Now().Add(time.Minute),
+		}
+		return service.ExchangeToken(ctx, &TokenRequest{
+			GrantType:    domain.GrantTypeAuthorizationCode,
+			Code:         code,
+			ClientID:     "client-a",
+			ClientSecret: "secret",
+			RedirectURI:  "https://app.example.com/callback",
+		})
+	}
+
+	tokenResp, err := exchange(service, "code-1")
+	if err != nil {
+		t.Fatalf("ExchangeToken failed: %v", err)
+	}
+	claims, err := signer.ParseAccessToken(tokenResp.AccessToken, issuer, false)
+	if err != nil {
+		t.Fatalf("Expected a signed access token, got %v", err)
+	}
+	if claims.Subject != "user-1" || claims.ClientID != "client-a" || claims.Scope != domain.ClientScopeProfile || claims.ID == "" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "client-a" {
+		t.Errorf("Expected aud to be the client_id, got %v", claims.Audience)
+	}
+	if len(claims.Roles) != 2 || claims.Roles[0] != "幹部" || claims.Roles[1] != "部員" {
+		t.Errorf("Expected roles ordered by position, got %v", claims.Roles)
+	}
+
+	// JWTでユーザー情報を取得できる（保存用のキーでは取得できない）
+	if user, err := service.GetUserByAccessToken(ctx, tokenResp.AccessToken); err != nil || user.ID != "user-1" {
+		t.Fatalf("Expected user-1, got %v, %v", user, err)
+	}
+	if _, err := service.GetUserByAccessToken(ctx, domain.JWTAccessTokenKey(claims.ID)); err == nil {
+		t.Error("Expected the storage key to be rejected")
+	}
+
+	// 取り消したJWTは使用できず、取り消しの一覧にjtiが含まれる
+	if err := service.RevokeToken(ctx, &RevokeRequest{Token: tokenResp.AccessToken, ClientID: "client-a", ClientSecret: "secret"}); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+	if _, err := service.GetUserByAccessToken(ctx, tokenResp.AccessToken); err == nil {
+		t.Error("Expected the revoked access token to be rejected")
+	}
+	revoked, err := service.ListRevokedAccessTokens(ctx)
+	if err != nil {
+		t.Fatalf("ListRevokedAccessTokens failed: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0].JTI() != claims.ID {
+		t.Errorf("Expected jti %s to be listed, got %v", claims.ID, revoked)
+	}
+
+	// スコープを登録していないクライアントのトークンには、登録できるすべてのスコープを設定する
+	clientRepo.clients["client-a"].Scopes = nil
+	tokenResp, err = exchange(service, "code-unscoped")
+	if err != nil {
+		t.Fatalf("ExchangeToken failed: %v", err)
+	}
+	claims, err = signer.ParseAccessToken(tokenResp.AccessToken, issuer, false)
+	if err != nil {
+		t.Fatalf("Expected a signed access token, got %v", err)
+	}
+	if claims.Scope != strings.Join(domain.ClientScopes, " ") {
+		t.Errorf("Expected all scopes for a client without registered scopes, got %q", claims.Scope)
+	}
+
+	// 署名鍵を設定していないサーバーではJWT形式のクライアントにトークンを発行できない
+	unconfigured := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
+	if _, err := exchange(unconfigured, "code-2"); err == nil {
+		t.Error("Expected an error without a signing key")
+	}
+	if unconfigured.AccessTokenKeys() != nil {
+		t.Error("Expected no access token keys without a signing key")
+	}
+}
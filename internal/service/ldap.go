@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/ldap"
+)
+
+// LDAPService はメンバーとロールを読み取り専用のLDAPディレクトリとして提供します（ldap.Backendを実装します）
+//
+// ディレクトリの構成（baseDNが dc=jyogi の場合）:
+//
+//	dc=jyogi
+//	├── ou=people  uid=<ユーザー名>（inetOrgPerson、在籍中のメンバーのみ）
+//	└── ou=groups  cn=<ロール名>（groupOfNames）
+//
+// Bindはメンバーの識別名とパスワードで行い、検索はBindしたメンバーのみ行えます
+type LDAPService struct {
+	userRepo      repository.UserRepository
+	roleRepo      repository.RoleRepository
+	authenticator LDAPAuthenticator
+	baseDN        ldap.DN
+	peopleDN      ldap.DN
+	groupsDN      ldap.DN
+}
+
+// LDAPAuthenticator はLDAPのBindでメンバーのパスワードを検証します
+// メンバーはDiscordのパスワードを持たないため、LDAP用に発行したパスワードで認証します
+type LDAPAuthenticator interface {
+	// AuthenticateLDAP はユーザー名とパスワードで在籍中のメンバーを認証します
+	// 認証できない場合はErrUnauthenticatedを返します
+	AuthenticateLDAP(ctx context.Context, username, password string) (*domain.User, error)
+}
+
+// NewLDAPService は新しいLDAPServiceを作成します
+// authenticatorがnilの場合、Bindはすべて拒否します
+func NewLDAPService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, authenticator LDAPAuthenticator, baseDN ldap.DN) *LDAPService {
+	return &LDAPService{
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		authenticator: authenticator,
+		baseDN:        baseDN,
+		peopleDN:      baseDN.Child("ou", "people"),
+		groupsDN:      baseDN.Child("ou", "groups"),
+	}
+}
+
+// BaseDN はディレクトリの最上位の識別名を返します
+func (s *LDAPService) BaseDN() ldap.DN {
+	return s.baseDN
+}
+
+// UserDN はメンバーの識別名を返します
+func (s *LDAPService) UserDN(user *domain.User) ldap.DN {
+	return s.peopleDN.Child("uid", user.Username)
+}
+
+// Bind はメンバーの識別名（uid=<ユーザー名>,ou=people,...）とパスワードを検証します
+func (s *LDAPService) Bind(ctx context.Context, dn ldap.DN, password string) error {
+	username, ok := s.usernameOf(dn)
+	if !ok || s.authenticator == nil {
+		return ldap.ErrInvalidCredentials
+	}
+	if _, err := s.authenticator.AuthenticateLDAP(ctx, username, password); err != nil {
+		if errors.Is(err, domain.ErrUnauthenticated) {
+			return ldap.ErrInvalidCredentials
+		}
+		return err
+	}
+	return nil
+}
+
+// Search は検索の起点の配下のエントリーを返します
+// メンバーはフィルターのuid・cn・displayName・memberOfの条件で、グループはcnの条件でリポジトリから絞り込み、
+// それ以外の条件はサーバーで判定します
+func (s *LDAPService) Search(ctx context.Context, boundDN ldap.DN, req *ldap.SearchRequest) ([]*ldap.Entry, error) {
+	if len(boundDN) == 0 {
+		return nil, ldap.ErrInsufficientAccess
+	}
+
+	entries := []*ldap.Entry{
+		s.organizationEntry(),
+		ldap.NewEntry(s.peopleDN).Add("objectClass", "top", "organizationalUnit").Add("ou", "people"),
+		ldap.NewEntry(s.groupsDN).Add("objectClass", "top", "organizationalUnit").Add("ou", "groups"),
+	}
+	searchPeople := s.overlaps(req.BaseDN, s.peopleDN)
+	searchGroups := s.overlaps(req.BaseDN, s.groupsDN)
+	if !searchPeople && !searchGroups {
+		return entries, nil
+	}
+
+	roles, err := s.roleRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	groups := newLDAPGroups(roles)
+
+	if searchPeople {
+		filter, ok := s.memberFilter(req, groups)
+		if ok {
+			users, _, err := s.userRepo.FindMembers(ctx, filter, domain.MemberPage{Sort: domain.MemberSortUsername})
+			if err != nil {
+				return nil, fmt.Errorf("failed to find members: %w", err)
+			}
+			for _, user := range users {
+				if user.IsGuildMember() {
+					entries = append(entries, s.userEntry(user, groups))
+				}
+			}
+		}
+	}
+
+	if searchGroups {
+		// グループのメンバーの一覧のため、対象のグループのロールを持つ在籍中のメンバーを取得する
+		names, filter := s.groupFilter(req, groups)
+		if len(names) == 0 {
+			return entries, nil
+		}
+		users, _, err := s.userRepo.FindMembers(ctx, filter, domain.MemberPage{Sort: domain.MemberSortUsername})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find members: %w", err)
+		}
+		members := make(map[string][]string)
+		for _, user := range users {
+			if !user.IsGuildMember() {
+				continue
+			}
+			for _, name := range groups.namesOf(user.GuildRoles) {
+				members[name] = append(members[name], s.UserDN(user).String())
+			}
+		}
+		for _, name := range names {
+			entries = append(entries, ldap.NewEntry(s.groupsDN.Child("cn", name)).
+				Add("objectClass", "top", "groupOfNames").
+				Add("cn", name).
+				Add("member", members[name]...))
+		}
+	}
+	return entries, nil
+}
+
+// memberFilter は検索の起点とフィルターからメンバーの絞り込み条件を作成します
+// 該当するメンバーがいないことが明らかな場合はokがfalseです
+func (s *LDAPService) memberFilter(req *ldap.SearchRequest, groups *ldapGroups) (filter domain.MemberFilter, ok bool) {
+	if username, isUser := s.usernameOf(req.BaseDN); isUser {
+		filter.Usernames = usernameCandidates(username)
+	} else if usernames, found := req.Filter.EqualityValues("uid"); found {
+		filter.Usernames = usernameCandidates(usernames...)
+	}
+	// cn・displayNameはユーザー名・表示名・サーバーニックネームのいずれかのため、キーワードの部分一致で絞り込む
+	if keyword, found := req.Filter.SubstringValue("uid", "sn", "cn", "displayName"); found {
+		filter.Query = keyword
+	}
+
+	if groupDNs, found := req.Filter.EqualityValues("memberOf"); found {
+		for _, value := range groupDNs {
+			dn, err := ldap.ParseDN(value)
+			if err != nil || len(dn) != len(s.groupsDN)+1 || !dn.HasSuffix(s.groupsDN) {
+				continue
+			}
+			filter.RoleIDs = append(filter.RoleIDs, groups.roleIDs(dn[0].Value)...)
+		}
+		if len(filter.RoleIDs) == 0 {
+			return filter, false
+		}
+	}
+	return filter, true
+}
+
+// groupFilter は検索の起点とフィルターのcnの条件から対象のグループ名と、そのメンバーの絞り込み条件を返します
+// 対象のグループを特定できない場合はすべてのグループを対象にします
+func (s *LDAPService) groupFilter(req *ldap.SearchRequest, groups *ldapGroups) ([]string, domain.MemberFilter) {
+	var candidates []string
+	if len(req.BaseDN) == len(s.groupsDN)+1 && req.BaseDN.HasSuffix(s.groupsDN) && strings.EqualFold(req.BaseDN[0].Attribute, "cn") {
+		candidates = []string{req.BaseDN[0].Value}
+	} else if values, found := req.Filter.EqualityValues("cn"); found {
+		candidates = values
+	} else {
+		return groups.names, domain.MemberFilter{}
+	}
+
+	var names []string
+	var filter domain.MemberFilter
+	for _, name := range groups.names {
+		if slices.ContainsFunc(candidates, func(candidate string) bool { return strings.EqualFold(candidate, name) }) {
+			names = append(names, name)
+			filter.RoleIDs = append(filter.RoleIDs, groups.roleIDs(name)...)
+		}
+	}
+	return names, filter
+}
+
+// userEntry はメンバーのエントリーを作成します
+func (s *LDAPService) userEntry(user *domain.User, groups *ldapGroups) *ldap.Entry {
+	var memberOf []string
+	for _, name := range groups.namesOf(user.GuildRoles) {
+		memberOf = append(memberOf, s.groupsDN.Child("cn", name).String())
+	}
+	return ldap.NewEntry(s.UserDN(user)).
+		Add("objectClass", "top", "person", "organizationalPerson", "inetOrgPerson").
+		Add("uid", user.Username).
+		Add("cn", user.PreferredName()).
+		Add("sn", user.Username).
+		Add("displayName", user.PreferredName()).
+		Add("entryUUID", user.ID).
+		Add("memberOf", memberOf...)
+}
+
+// organizationEntry はディレクトリの最上位のエントリーを作成します
+func (s *LDAPService) organizationEntry() *ldap.Entry {
+	entry := ldap.NewEntry(s.baseDN).Add("objectClass", "top", "organization")
+	if len(s.baseDN) > 0 {
+		entry.Add(s.baseDN[0].Attribute, s.baseDN[0].Value)
+	}
+	if len(s.baseDN) > 0 && !strings.EqualFold(s.baseDN[0].Attribute, "o") {
+		entry.Add("o", s.baseDN[0].Value)
+	}
+	return entry
+}
+
+// usernameOf はメンバーの識別名からユーザー名を返します
+func (s *LDAPService) usernameOf(dn ldap.DN) (string, bool) {
+	if len(dn) != len(s.peopleDN)+1 || !dn.HasSuffix(s.peopleDN) || !strings.EqualFold(dn[0].Attribute, "uid") {
+		return "", false
+	}
+	return dn[0].Value, true
+}
+
+// usernameCandidates はユーザー名の検索に使う候補を返します
+// Discordのユーザー名は小文字のため、大文字を含む入力は小文字でも検索します
+func usernameCandidates(usernames ...string) []string {
+	var candidates []string
+	for _, username := range usernames {
+		candidates = append(candidates, username)
+		if lower := strings.ToLower(username); lower != username {
+			candidates = append(candidates, lower)
+		}
+	}
+	return candidates
+}
+
+// overlaps は検索の起点の範囲がsubtreeと重なるかどうかを確認します
+func (s *LDAPService) overlaps(base, subtree ldap.DN) bool {
+	return subtree.HasSuffix(base) || base.HasSuffix(subtree)
+}
+
+// ldapGroups はロールをロール名ごとのグループにまとめたものです（同じ名前のロールは1つのグループにします）
+type ldapGroups struct {
+	names     []string            // グループ名（上位のロールから順）
+	nameOf    map[string]string   // ロールID→グループ名
+	roleIDsOf map[string][]string // 小文字のグループ名→ロールID
+}
+
+// newLDAPGroups は上位順に並んだロールからグループを作成します
+func newLDAPGroups(roles []*domain.Role) *ldapGroups {
+	g := &ldapGroups{nameOf: make(map[string]string), roleIDsOf: make(map[string][]string)}
+	for _, role := range roles {
+		key := strings.ToLower(role.Name)
+		ids, exists := g.roleIDsOf[key]
+		if !exists {
+			g.names = append(g.names, role.Name)
+		}
+		g.roleIDsOf[key] = append(ids, role.ID)
+		if exists {
+			g.nameOf[role.ID] = g.nameOf[ids[0]]
+		} else {
+			g.nameOf[role.ID] = role.Name
+		}
+	}
+	return g
+}
+
+// namesOf はロールIDの一覧に対応するグループ名を上位順に重複なく返します
+func (g *ldapGroups) namesOf(roleIDs []string) []string {
+	has := make(map[string]bool)
+	for _, id := range roleIDs {
+		if name, ok := g.nameOf[id]; ok {
+			has[name] = true
+		}
+	}
+	var names []string
+	for _, name := range g.names {
+		if has[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// roleIDs はグループ名に対応するロールIDを返します
+func (g *ldapGroups) roleIDs(name string) []string {
+	return g.roleIDsOf[strings.ToLower(name)]
+}
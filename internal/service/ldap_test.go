@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/ldap"
+)
+
+func TestLDAPService(t *testing.T) {
+	ctx := context.Background()
+
+	userRepo := newMockUserRepository()
+	roleRepo := newMockRoleRepository()
+	roleRepo.roles["role-member"] = &domain.Role{ID: "role-member", Name: "部員", Position: 1}
+	roleRepo.roles["role-officer"] = &domain.Role{ID: "role-officer", Name: "幹部", Position: 5}
+
+	nickname := "たろう"
+	leftAt := time.Now()
+	member := &domain.User{ID: "user-1", DiscordID: "d-1", Username: "taro", GuildNickname: &nickname, GuildRoles: []string{"role-member"}}
+	officer := &domain.User{ID: "user-2", DiscordID: "d-2", Username: "hanako", GuildRoles: []string{"role-member", "role-officer"}}
+	left := &domain.User{ID: "user-3", DiscordID: "d-3", Username: "jiro", GuildRoles: []string{"role-member"}, LeftAt: &leftAt}
+	for _, user := range []*domain.User{member, officer, left} {
+		userRepo.Create(ctx, user)
+	}
+
+	plain := "taro-password"
+	authenticator := &stubLDAPAuthenticator{users: map[string]*domain.User{plain: member}}
+
+	baseDN := ldap.MustParseDN("dc=jyogi")
+	service := NewLDAPService(userRepo, roleRepo, authenticator, baseDN)
+	memberDN := ldap.MustParseDN("uid=taro,ou=people,dc=jyogi")
+
+	t.Run("Bind", func(t *testing.T) {
+		if err := service.Bind(ctx, memberDN, plain); err != nil {
+			t.Errorf("Bind failed: %v", err)
+		}
+		// パスワードを検証できない場合はすべて拒否する
+		if err := NewLDAPService(userRepo, roleRepo, nil, baseDN).Bind(ctx, memberDN, plain); !errors.Is(err, ldap.ErrInvalidCredentials) {
+			t.Errorf("Expected ErrInvalidCredentials without an authenticator, got %v", err)
+		}
+		for _, tt := range []struct {
+			dn       string
+			password string
+		}{
+			{"uid=taro,ou=people,dc=jyogi", "wrong"},
+			{"uid=hanako,ou=people,dc=jyogi", plain},
+			{"cn=taro,ou=people,dc=jyogi", plain},
+			{"uid=taro,ou=groups,dc=jyogi", plain},
+		} {
+			if err := service.Bind(ctx, ldap.MustParseDN(tt.dn), tt.password); !errors.Is(err, ldap.ErrInvalidCredentials) {
+				t.Errorf("Bind(%s): expected ErrInvalidCredentials, got %v", tt.dn, err)
+			}
+		}
+	})
+
+	everything := &ldap.Filter{Type: ldap.FilterPresent, Attribute: "objectClass"}
+
+	t.Run("匿名での検索", func(t *testing.T) {
+		req := &ldap.SearchRequest{BaseDN: baseDN, Scope: ldap.ScopeWholeSubtree, Filter: everything}
+		if _, err := service.Search(ctx, nil, req); !errors.Is(err, ldap.ErrInsufficientAccess) {
+			t.Errorf("Expected ErrInsufficientAccess, got %v", err)
+		}
+	})
+
+	t.Run("ディレクトリ全体", func(t *testing.T) {
+		req := &ldap.SearchRequest{BaseDN: baseDN, Scope: ldap.ScopeWholeSubtree, Filter: everything}
+		entries, err := service.Search(ctx, memberDN, req)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		byDN := make(map[string]*ldap.Entry)
+		for _, e := range entries {
+			byDN[e.DN.String()] = e
+		}
+		for _, dn := range []string{"dc=jyogi", "ou=people,dc=jyogi", "ou=groups,dc=jyogi", "uid=taro,ou=people,dc=jyogi", "uid=hanako,ou=people,dc=jyogi", "cn=部員,ou=groups,dc=jyogi", "cn=幹部,ou=groups,dc=jyogi"} {
+			if byDN[dn] == nil {
+				t.Errorf("Expected entry %s", dn)
+			}
+		}
+		if byDN["uid=jiro,ou=people,dc=jyogi"] != nil {
+			t.Error("Expected members who left to be excluded")
+		}
+
+		taro := byDN["uid=taro,ou=people,dc=jyogi"]
+		if got := taro.Get("cn"); !slices.Equal(got, []string{"たろう"}) {
+			t.Errorf("Expected cn to be the nickname, got %v", got)
+		}
+		if got := taro.Get("memberOf"); !slices.Equal(got, []string{"cn=部員,ou=groups,dc=jyogi"}) {
+			t.Errorf("Unexpected memberOf: %v", got)
+		}
+		officers := byDN["cn=幹部,ou=groups,dc=jyogi"]
+		if got := officers.Get("member"); !slices.Equal(got, []string{"uid=hanako,ou=people,dc=jyogi"}) {
+			t.Errorf("Unexpected group members: %v", got)
+		}
+		members := byDN["cn=部員,ou=groups,dc=jyogi"].Get("member")
+		slices.Sort(members)
+		if !slices.Equal(members, []string{"uid=hanako,ou=people,dc=jyogi", "uid=taro,ou=people,dc=jyogi"}) {
+			t.Errorf("Unexpected group members: %v", members)
+		}
+	})
+
+	t.Run("グループの配下のみ", func(t *testing.T) {
+		req := &ldap.SearchRequest{BaseDN: ldap.MustParseDN("ou=groups,dc=jyogi"), Scope: ldap.ScopeSingleLevel, Filter: everything}
+		entries, err := service.Search(ctx, memberDN, req)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		for _, e := range entries {
+			if e.DN.HasSuffix(ldap.MustParseDN("ou=people,dc=jyogi")) && len(e.DN) > 3 {
+				t.Errorf("Did not expect user entries, got %s", e.DN)
+			}
+		}
+	})
+
+	t.Run("グループ名での絞り込み", func(t *testing.T) {
+		recorder := &recordingUserRepository{mockUserRepository: userRepo}
+		service := NewLDAPService(recorder, roleRepo, authenticator, baseDN)
+		for _, req := range []*ldap.SearchRequest{
+			{BaseDN: ldap.MustParseDN("cn=幹部,ou=groups,dc=jyogi"), Scope: ldap.ScopeBaseObject, Filter: everything},
+			{BaseDN: ldap.MustParseDN("ou=groups,dc=jyogi"), Scope: ldap.ScopeSingleLevel, Filter: &ldap.Filter{Type: ldap.FilterEqual, Attribute: "cn", Value: "幹部"}},
+		} {
+			recorder.filters = nil
+			entries, err := service.Search(ctx, memberDN, req)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(recorder.filters) != 1 || !slices.Equal(recorder.filters[0].RoleIDs, []string{"role-officer"}) {
+				t.Errorf("Expected members to be narrowed to the group's roles, got %+v", recorder.filters)
+			}
+			for _, e := range entries {
+				if e.DN.String() == "cn=部員,ou=groups,dc=jyogi" {
+					t.Errorf("Did not expect other groups, got %s", e.DN)
+				}
+			}
+		}
+
+		// 存在しないグループはメンバーを取得しない
+		recorder.filters = nil
+		req := &ldap.SearchRequest{BaseDN: ldap.MustParseDN("cn=unknown,ou=groups,dc=jyogi"), Scope: ldap.ScopeBaseObject, Filter: everything}
+		if _, err := service.Search(ctx, memberDN, req); err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(recorder.filters) != 0 {
+			t.Errorf("Expected no member lookup, got %+v", recorder.filters)
+		}
+	})
+
+	t.Run("存在しないグループでの絞り込み", func(t *testing.T) {
+		req := &ldap.SearchRequest{
+			BaseDN: ldap.MustParseDN("ou=people,dc=jyogi"),
+			Scope:  ldap.ScopeSingleLevel,
+			Filter: &ldap.Filter{Type: ldap.FilterEqual, Attribute: "memberOf", Value: "cn=unknown,ou=groups,dc=jyogi"},
+		}
+		entries, err := service.Search(ctx, memberDN, req)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		for _, e := range entries {
+			if len(e.Get("uid")) > 0 {
+				t.Errorf("Expected no user entries, got %s", e.DN)
+			}
+		}
+	})
+}
+
+func TestLDAPService_MemberFilter(t *testing.T) {
+	service := NewLDAPService(nil, nil, nil, ldap.MustParseDN("dc=jyogi"))
+	groups := newLDAPGroups([]*domain.Role{
+		{ID: "role-officer", Name: "幹部"},
+		{ID: "role-member", Name: "Member"},
+		{ID: "role-member-2", Name: "member"},
+	})
+
+	tests := []struct {
+		name      string
+		baseDN    string
+		filter    *ldap.Filter
+		usernames []string
+		query     string
+		roleIDs   []string
+		ok        bool
+	}{
+		{
+			name:      "uidの一致",
+			baseDN:    "ou=people,dc=jyogi",
+			filter:    &ldap.Filter{Type: ldap.FilterAnd, Children: []*ldap.Filter{{Type: ldap.FilterEqual, Attribute: "objectClass", Value: "person"}, {Type: ldap.FilterEqual, Attribute: "uid", Value: "Taro"}}},
+			usernames: []string{"Taro", "taro"},
+			query:     "Taro",
+			ok:        true,
+		},
+		{
+			name:      "検索の起点がメンバー",
+			baseDN:    "uid=hanako,ou=people,dc=jyogi",
+			filter:    &ldap.Filter{Type: ldap.FilterPresent, Attribute: "objectClass"},
+			usernames: []string{"hanako"},
+			ok:        true,
+		},
+		{
+			name:   "表示名の部分一致",
+			baseDN: "ou=people,dc=jyogi",
+			filter: &ldap.Filter{Type: ldap.FilterAnd, Children: []*ldap.Filter{{Type: ldap.FilterEqual, Attribute: "objectClass", Value: "person"}, {Type: ldap.FilterSubstrings, Attribute: "displayName", Initial: "た", Final: "ろう"}}},
+			query:  "ろう",
+			ok:     true,
+		},
+		{
+			name:   "cnの一致",
+			baseDN: "dc=jyogi",
+			filter: &ldap.Filter{Type: ldap.FilterEqual, Attribute: "cn", Value: "たろう"},
+			query:  "たろう",
+			ok:     true,
+		},
+		{
+			name:    "同じ名前のロールはまとめる",
+			baseDN:  "dc=jyogi",
+			filter:  &ldap.Filter{Type: ldap.FilterEqual, Attribute: "memberOf", Value: "cn=MEMBER,ou=groups,dc=jyogi"},
+			roleIDs: []string{"role-member", "role-member-2"},
+			ok:      true,
+		},
+		{
+			name:   "絞り込めない条件",
+			baseDN: "dc=jyogi",
+			filter: &ldap.Filter{Type: ldap.FilterOr, Children: []*ldap.Filter{{Type: ldap.FilterEqual, Attribute: "uid", Value: "taro"}, {Type: ldap.FilterEqual, Attribute: "mail", Value: "taro@example.com"}}},
+			ok:     true,
+		},
+		{
+			name:   "存在しないグループ",
+			baseDN: "dc=jyogi",
+			filter: &ldap.Filter{Type: ldap.FilterEqual, Attribute: "memberOf", Value: "cn=幹部,ou=other,dc=jyogi"},
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, ok := service.memberFilter(&ldap.SearchRequest{BaseDN: ldap.MustParseDN(tt.baseDN), Filter: tt.filter}, groups)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if !slices.Equal(filter.Usernames, tt.usernames) {
+				t.Errorf("Expected usernames %v, got %v", tt.usernames, filter.Usernames)
+			}
+			if filter.Query != tt.query {
+				t.Errorf("Expected query %q, got %q", tt.query, filter.Query)
+			}
+			if !slices.Equal(filter.RoleIDs, tt.roleIDs) {
+				t.Errorf("Expected role IDs %v, got %v", tt.roleIDs, filter.RoleIDs)
+			}
+		})
+	}
+}
+
+// recordingUserRepository はメンバーの絞り込み条件を記録するUserRepositoryです
+type recordingUserRepository struct {
+	*mockUserRepository
+	filters []domain.MemberFilter
+}
+
+func (r *recordingUserRepository) FindMembers(ctx context.Context, filter domain.MemberFilter, page domain.MemberPage) ([]*domain.User, *domain.MemberCursor, error) {
+	r.filters = append(r.filters, filter)
+	return r.mockUserRepository.FindMembers(ctx, filter, page)
+}
+
+// stubLDAPAuthenticator は固定のパスワードでメンバーを認証するLDAPAuthenticatorです
+type stubLDAPAuthenticator struct {
+	users map[string]*domain.User // パスワード→メンバー
+}
+
+func (a *stubLDAPAuthenticator) AuthenticateLDAP(ctx context.Context, username, password string) (*domain.User, error) {
+	user, ok := a.users[password]
+	if !ok || user.Username != username {
+		return nil, domain.ErrUnauthenticated
+	}
+	return user, nil
+}
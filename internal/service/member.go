@@ -0,0 +1,87 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/discord"
+)
+
+// applyDiscordUser はDiscordユーザー情報をドメインユーザーに反映します
+// いずれかのフィールドが変更された場合はtrueを返します
+func applyDiscordUser(user *domain.User, discordUser *discord.User) bool {
+	changed := false
+
+	if user.Username != discordUser.Username {
+		user.Username = discordUser.Username
+		changed = true
+	}
+	if displayName := discordUser.GetDisplayName(); user.DisplayName != displayName {
+		user.DisplayName = displayName
+		changed = true
+	}
+	if avatarURL := discordUser.GetAvatarURL(); user.AvatarURL != avatarURL {
+		user.AvatarURL = avatarURL
+		changed = true
+	}
+
+	return changed
+}
+
+// applyGuildMember はGuild Member情報（ニックネーム・ロール・参加日時）をドメインユーザーに反映します
+// いずれかのフィールドが変更された場合はtrueを返します
+func applyGuildMember(user *domain.User, member *discord.GuildMember) bool {
+	changed := false
+
+	var guildNickname *string
+	if member.Nick != nil && *member.Nick != "" {
+		guildNickname = member.Nick
+	}
+	if !equalStringPtr(user.GuildNickname, guildNickname) {
+		user.GuildNickname = guildNickname
+		changed = true
+	}
+
+	var joinedAt *time.Time
+	if member.JoinedAt != "" {
+		if parsedTime, err := time.Parse(time.RFC3339, member.JoinedAt); err == nil {
+			joinedAt = &parsedTime
+		}
+	}
+	if !equalTimePtr(user.JoinedAt, joinedAt) {
+		user.JoinedAt = joinedAt
+		changed = true
+	}
+
+	// メンバー情報が取得できた＝在籍中なので、脱退扱いを解除
+	if user.LeftAt != nil {
+		user.LeftAt = nil
+		changed = true
+	}
+
+	guildRoles := member.Roles
+	if len(guildRoles) == 0 {
+		guildRoles = []string{}
+	}
+	if user.GuildRoles == nil || !slices.Equal(user.GuildRoles, guildRoles) {
+		user.GuildRoles = guildRoles
+		changed = true
+	}
+
+	return changed
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
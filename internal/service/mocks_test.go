@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// モックRoleRepository
+type mockRoleRepository struct {
+	roles map[string]*domain.Role
+}
+
+func newMockRoleRepository() *mockRoleRepository {
+	return &mockRoleRepository{
+		roles: make(map[string]*domain.Role),
+	}
+}
+
+func (m *mockRoleRepository) Upsert(ctx context.Context, role *domain.Role) error {
+	m.roles[role.ID] = role
+	return nil
+}
+
+func (m *mockRoleRepository) GetAll(ctx context.Context) ([]*domain.Role, error) {
+	var roles []*domain.Role
+	for _, r := range m.roles {
+		roles = append(roles, r)
+	}
+	return roles, nil
+}
+
+func (m *mockRoleRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.Role, error) {
+	var roles []*domain.Role
+	for _, id := range ids {
+		if r, ok := m.roles[id]; ok {
+			roles = append(roles, r)
+		}
+	}
+	return roles, nil
+}
+
+func (m *mockRoleRepository) DeleteExcept(ctx context.Context, ids []string) error {
+	keep := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+	for id := range m.roles {
+		if !keep[id] {
+			delete(m.roles, id)
+		}
+	}
+	return nil
+}
+
+// モックSyncCursorRepository
+type mockSyncCursorRepository struct {
+	cursors map[string]*domain.SyncCursor
+}
+
+func newMockSyncCursorRepository() *mockSyncCursorRepository {
+	return &mockSyncCursorRepository{
+		cursors: make(map[string]*domain.SyncCursor),
+	}
+}
+
+func (m *mockSyncCursorRepository) GetByChannelID(ctx context.Context, channelID string) (*domain.SyncCursor, error) {
+	cursor, ok := m.cursors[channelID]
+	if !ok {
+		return nil, domain.ErrSyncCursorNotFound
+	}
+	return cursor, nil
+}
+
+func (m *mockSyncCursorRepository) Upsert(ctx context.Context, cursor *domain.SyncCursor) error {
+	m.cursors[cursor.ChannelID] = cursor
+	return nil
+}
+
+// モックIntroMessageRepository
+type mockIntroMessageRepository struct {
+	messages     map[string]*domain.IntroMessage
+	upsertErrors map[string]error // メッセージIDごとに1回だけ返すエラー
+}
+
+func newMockIntroMessageRepository() *mockIntroMessageRepository {
+	return &mockIntroMessageRepository{
+		messages: make(map[string]*domain.IntroMessage),
+	}
+}
+
+func (m *mockIntroMessageRepository) Upsert(ctx context.Context, message *domain.IntroMessage) error {
+	if err, ok := m.upsertErrors[message.ID]; ok {
+		delete(m.upsertErrors, message.ID)
+		return err
+	}
+	m.messages[message.ID] = message
+	return nil
+}
+
+func (m *mockIntroMessageRepository) GetByID(ctx context.Context, id string) (*domain.IntroMessage, error) {
+	message, ok := m.messages[id]
+	if !ok {
+		return nil, domain.ErrIntroMessageNotFound
+	}
+	return message, nil
+}
+
+func (m *mockIntroMessageRepository) GetActiveByUserID(ctx context.Context, userID string) ([]*domain.IntroMessage, error) {
+	var messages []*domain.IntroMessage
+	for _, msg := range m.messages {
+		if msg.UserID == userID && !msg.IsDeleted() {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].PostedAt.Before(messages[j].PostedAt)
+	})
+	return messages, nil
+}
+
+func (m *mockIntroMessageRepository) GetActiveByChannelID(ctx context.Context, channelID string) ([]*domain.IntroMessage, error) {
+	var messages []*domain.IntroMessage
+	for _, msg := range m.messages {
+		if msg.ChannelID == channelID && !msg.IsDeleted() {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func (m *mockIntroMessageRepository) MarkDeleted(ctx context.Context, id string) error {
+	if msg, ok := m.messages[id]; ok {
+		now := time.Now()
+		msg.DeletedAt = &now
+	}
+	return nil
+}
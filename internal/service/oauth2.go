@@ -5,13 +5,15 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
-	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwks"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwt"
 )
 
 const (
@@ -21,28 +23,52 @@ const (
 	AccessTokenExpiration = 1 * time.Hour
 	// RefreshTokenExpiration はリフレッシュトークンの有効期限（7日）
 	RefreshTokenExpiration = 7 * 24 * time.Hour
+	// DeviceCodeExpiration はデバイスコード・ユーザーコードの有効期限（10分）
+	DeviceCodeExpiration = 10 * time.Minute
+	// DeviceCodePollInterval はデバイスがトークンエンドポイントをポーリングする最小間隔（5秒）
+	DeviceCodePollInterval = 5 * time.Second
+	// DeviceCodeSlowDownStep はslow_downを返すたびにポーリングの間隔を延長する時間（5秒、RFC 8628 3.5）
+	DeviceCodeSlowDownStep = 5 * time.Second
 )
 
+// GrantTypeDeviceCode はデバイス認可グラント（RFC 8628）のgrant_typeです
+const GrantTypeDeviceCode = domain.GrantTypeDeviceCode
+
 // OAuth2Service はOAuth2認可サーバーの機能を提供します
 type OAuth2Service struct {
-	clientRepo   repository.ClientRepository
-	authCodeRepo repository.AuthCodeRepository
-	tokenRepo    repository.TokenRepository
-	userRepo     repository.UserRepository
+	clientRepo       repository.ClientRepository
+	clientSecretRepo repository.ClientSecretRepository
+	authCodeRepo     repository.AuthCodeRepository
+	deviceCodeRepo   repository.DeviceCodeRepository
+	tokenRepo        repository.TokenRepository
+	userRepo         repository.UserRepository
+	events           EventPublisher           // トークンの取り消しを通知します（nilの場合は通知しません）
+	clientAssertions *ClientAssertionVerifier // private_key_jwtのclient_assertionを検証します（nilの場合はprivate_key_jwtを使用できません）
+	jwtAccessTokens  *JWTAccessTokenIssuer    // JWT形式のアクセストークンに署名します（nilの場合はJWT形式を使用できません）
 }
 
 // NewOAuth2Service は新しいOAuth2サービスを作成します
 func NewOAuth2Service(
 	clientRepo repository.ClientRepository,
+	clientSecretRepo repository.ClientSecretRepository,
 	authCodeRepo repository.AuthCodeRepository,
+	deviceCodeRepo repository.DeviceCodeRepository,
 	tokenRepo repository.TokenRepository,
 	userRepo repository.UserRepository,
+	events EventPublisher,
+	clientAssertions *ClientAssertionVerifier,
+	jwtAccessTokens *JWTAccessTokenIssuer,
 ) *OAuth2Service {
 	return &OAuth2Service{
-		clientRepo:   clientRepo,
-		authCodeRepo: authCodeRepo,
-		tokenRepo:    tokenRepo,
-		userRepo:     userRepo,
+		clientRepo:       clientRepo,
+		clientSecretRepo: clientSecretRepo,
+		authCodeRepo:     authCodeRepo,
+		deviceCodeRepo:   deviceCodeRepo,
+		tokenRepo:        tokenRepo,
+		userRepo:         userRepo,
+		events:           events,
+		clientAssertions: clientAssertions,
+		jwtAccessTokens:  jwtAccessTokens,
 	}
 }
 
@@ -74,6 +100,9 @@ func (s *OAuth2Service) Authorize(ctx context.Context, req *AuthorizeRequest) (*
 	if err != nil {
 		return nil, fmt.Errorf("invalid client_id: %w", err)
 	}
+	if !client.AllowsGrantType(domain.GrantTypeAuthorizationCode) {
+		return nil, fmt.Errorf("%w: the client is not registered for the authorization_code grant", domain.ErrUnauthorizedClient)
+	}
 
 	// 3. redirect_uri の検証
 	valid, err := s.clientRepo.ValidateRedirectURI(ctx, req.ClientID, req.RedirectURI)
@@ -121,11 +150,13 @@ func (s *OAuth2Service) Authorize(ctx context.Context, req *AuthorizeRequest) (*
 
 // TokenRequest はトークンリクエストのパラメータを表します
 type TokenRequest struct {
-	GrantType    string
-	Code         string
-	ClientID     string
-	ClientSecret string
-	RedirectURI  string
+	GrantType       string
+	Code            string
+	DeviceCode      string // grant_typeがGrantTypeDeviceCodeの場合のデバイスコード
+	ClientID        string
+	ClientSecret    string
+	ClientAssertion string // private_key_jwtのクライアントが署名したJWT（client_assertion）
+	RedirectURI     string
 }
 
 // TokenResponse はトークンレスポンスを表します
@@ -136,22 +167,24 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-// ExchangeToken は認可コードをアクセストークンに交換します
+// ExchangeToken は認可コード（またはユーザーが承認したデバイスコード）をアクセストークンに交換します
 func (s *OAuth2Service) ExchangeToken(ctx context.Context, req *TokenRequest) (*TokenResponse, error) {
 	// 1. grant_type の検証
-	if req.GrantType != "authorization_code" {
+	switch req.GrantType {
+	case domain.GrantTypeAuthorizationCode:
+	case GrantTypeDeviceCode:
+		return s.exchangeDeviceCode(ctx, req)
+	default:
 		return nil, fmt.Errorf("unsupported grant_type: %s", req.GrantType)
 	}
 
-	// 2. クライアント認証
-	client, err := s.clientRepo.GetByClientID(ctx, req.ClientID)
+	// 2. クライアント認証（公開クライアントはデバイス認可グラントのみを登録している）
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret, req.ClientAssertion)
 	if err != nil {
-		return nil, fmt.Errorf("invalid client_id: %w", err)
+		return nil, err
 	}
-
-	// クライアントシークレットの検証
-	if err := auth.ValidateClientSecret(req.ClientSecret, client.ClientSecret); err != nil {
-		return nil, fmt.Errorf("invalid client_secret: %w", err)
+	if !client.AllowsGrantType(domain.GrantTypeAuthorizationCode) {
+		return nil, fmt.Errorf("%w: the client is not registered for the authorization_code grant", domain.ErrUnauthorizedClient)
 	}
 
 	// 3. 認可コードの取得と検証
@@ -180,40 +213,46 @@ func (s *OAuth2Service) ExchangeToken(ctx context.Context, req *TokenRequest) (*
 		return nil, fmt.Errorf("redirect_uri mismatch")
 	}
 
-	// 4. アクセストークンとリフレッシュトークンを生成（副作用なし、先に実行）
-	accessToken, err := generateSecureToken()
+	// 4. アクセストークンとリフレッシュトークンを発行
+	tokenResp, err := s.issueTokens(ctx, authCode.UserID, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, err
 	}
 
-	refreshToken, err := generateSecureToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	// 5. 最後に認可コードを使用済みにマーク
+	if err := s.authCodeRepo.MarkAsUsed(ctx, req.Code); err != nil {
+		return nil, fmt.Errorf("failed to mark auth code as used: %w", err)
 	}
 
+	return tokenResp, nil
+}
+
+// issueTokens はユーザーにクライアント向けのアクセストークンとリフレッシュトークンを発行します
+func (s *OAuth2Service) issueTokens(ctx context.Context, userID string, client *domain.ClientApp) (*TokenResponse, error) {
 	now := time.Now()
 
-	// 5. アクセストークンを保存
-	accessTokenObj := &domain.Token{
-		ID:        uuid.New().String(),
-		Token:     accessToken,
-		TokenType: domain.TokenTypeAccess,
-		UserID:    authCode.UserID,
-		ClientID:  client.ClientID,
-		ExpiresAt: now.Add(AccessTokenExpiration),
-		CreatedAt: now,
-		Revoked:   false,
+	// 1. アクセストークンとリフレッシュトークンを生成（副作用なし、先に実行）
+	accessToken, accessTokenObj, err := s.newAccessToken(ctx, userID, client, now)
+	if err != nil {
+		return nil, err
 	}
+
+	refreshToken, err := generateSecureToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	// 2. アクセストークンを保存
 	if err := s.tokenRepo.Create(ctx, accessTokenObj); err != nil {
 		return nil, fmt.Errorf("failed to store access token: %w", err)
 	}
 
-	// 6. リフレッシュトークンを保存
+	// 3. リフレッシュトークンを保存
 	refreshTokenObj := &domain.Token{
 		ID:        uuid.New().String(),
 		Token:     refreshToken,
 		TokenType: domain.TokenTypeRefresh,
-		UserID:    authCode.UserID,
+		UserID:    userID,
 		ClientID:  client.ClientID,
 		ExpiresAt: now.Add(RefreshTokenExpiration),
 		CreatedAt: now,
@@ -223,11 +262,6 @@ func (s *OAuth2Service) ExchangeToken(ctx context.Context, req *TokenRequest) (*
 		return nil, fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
-	// 7. 最後に認可コードを使用済みにマーク
-	if err := s.authCodeRepo.MarkAsUsed(ctx, req.Code); err != nil {
-		return nil, fmt.Errorf("failed to mark auth code as used: %w", err)
-	}
-
 	return &TokenResponse{
 		AccessToken:  accessToken,
 		TokenType:    "Bearer",
@@ -236,31 +270,292 @@ func (s *OAuth2Service) ExchangeToken(ctx context.Context, req *TokenRequest) (*
 	}, nil
 }
 
+// newAccessToken はクライアントのaccess_token_formatに応じたアクセストークンと、保存するトークンを生成します
+// JWT形式の場合はjtiをトークンのIDとし、JWTの代わりにdomain.JWTAccessTokenKey(jti)を保存します
+func (s *OAuth2Service) newAccessToken(ctx context.Context, userID string, client *domain.ClientApp, now time.Time) (string, *domain.Token, error) {
+	token := &domain.Token{
+		ID:        uuid.New().String(),
+		TokenType: domain.TokenTypeAccess,
+		UserID:    userID,
+		ClientID:  client.ClientID,
+		ExpiresAt: now.Add(AccessTokenExpiration),
+		CreatedAt: now,
+		Revoked:   false,
+	}
+
+	if client.AccessTokenFormatOrDefault() != domain.AccessTokenFormatJWT {
+		accessToken, err := generateSecureToken()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate access token: %w", err)
+		}
+		token.Token = accessToken
+		return accessToken, token, nil
+	}
+
+	if s.jwtAccessTokens == nil {
+		return "", nil, fmt.Errorf("JWT access tokens are not configured on this server")
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	accessToken, err := s.jwtAccessTokens.issue(ctx, user, client, token.ID, now, token.ExpiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	token.Token = domain.JWTAccessTokenKey(token.ID)
+	return accessToken, token, nil
+}
+
+// RevokeRequest はトークンの取り消しリクエストのパラメータを表します
+type RevokeRequest struct {
+	Token           string
+	TokenTypeHint   string // access_token または refresh_token（検索の順序のヒントのため、指定がなくても取り消せます）
+	ClientID        string
+	ClientSecret    string
+	ClientAssertion string // private_key_jwtのクライアントが署名したJWT（client_assertion）
+}
+
+// RevokeToken はクライアントに発行したトークンを取り消します（RFC 7009）
+// 存在しないトークン・他のクライアントのトークン・取り消し済みのトークンはエラーにせず何もしません
+// リフレッシュトークンを取り消した場合は、同じユーザーに同じクライアントが発行したアクセストークンも取り消します
+func (s *OAuth2Service) RevokeToken(ctx context.Context, req *RevokeRequest) error {
+	// 1. クライアント認証
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret, req.ClientAssertion)
+	if err != nil {
+		return err
+	}
+
+	// 2. トークンを取得（見つからない場合も成功として扱う）
+	token, err := s.findToken(ctx, req.Token, true)
+	if err != nil || token.ClientID != client.ClientID || token.Revoked {
+		return nil
+	}
+
+	// 3. トークンを取り消す
+	revoked := []*domain.Token{token}
+	if token.TokenType == domain.TokenTypeRefresh {
+		tokens, err := s.tokenRepo.GetByUserID(ctx, token.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get tokens: %w", err)
+		}
+		for _, t := range tokens {
+			if t.ClientID == client.ClientID && t.TokenType == domain.TokenTypeAccess && t.IsValid() {
+				revoked = append(revoked, t)
+			}
+		}
+	}
+	for _, t := range revoked {
+		if err := s.tokenRepo.Revoke(ctx, t.Token); err != nil {
+			return fmt.Errorf("failed to revoke token: %w", err)
+		}
+	}
+
+	// 4. 取り消したトークンの発行先のクライアントに通知する
+	if s.events != nil {
+		for _, t := range revoked {
+			s.events.Publish(ctx, &domain.Event{
+				Type:     domain.EventTokenRevoked,
+				UserID:   t.UserID,
+				ClientID: client.ClientID,
+				Data: map[string]interface{}{
+					"client_id":  client.ClientID,
+					"token_id":   t.ID,
+					"token_type": t.TokenType,
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// authenticateClient はトークンエンドポイントでクライアントを認証します（失敗した場合はdomain.ErrInvalidClient）
+// client_assertionを指定した場合は署名したJWT（private_key_jwt）で、それ以外はClient IDと有効なClient Secretのいずれかで認証します
+// 公開クライアント（token_endpoint_auth_methodがnone）はClient IDのみで識別します
+// client_idを省略した場合はclient_assertionのsubをClient IDとして扱います
+func (s *OAuth2Service) authenticateClient(ctx context.Context, clientID, clientSecret, clientAssertion string) (*domain.ClientApp, error) {
+	if clientAssertion != "" {
+		if s.clientAssertions == nil {
+			return nil, fmt.Errorf("%w: client_assertion is not supported", domain.ErrInvalidClient)
+		}
+		subject, err := clientAssertionSubject(clientAssertion)
+		if err != nil {
+			return nil, err
+		}
+		if clientID != "" && clientID != subject {
+			return nil, fmt.Errorf("%w: client_id does not match the client_assertion", domain.ErrInvalidClient)
+		}
+		clientID = subject
+	}
+
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidClient, err)
+	}
+
+	switch {
+	case clientAssertion != "":
+		if err := s.clientAssertions.Verify(ctx, client, clientAssertion); err != nil {
+			return nil, err
+		}
+	case client.UsesPrivateKeyJWT():
+		return nil, fmt.Errorf("%w: the client must authenticate with client_assertion", domain.ErrInvalidClient)
+	case client.IsPublic():
+		// 公開クライアントはClient Secretを持たないため、送信された場合は設定の誤りとして拒否する
+		if clientSecret != "" {
+			return nil, fmt.Errorf("%w: public clients must not send client_secret", domain.ErrInvalidClient)
+		}
+	default:
+		if err := verifyClientSecret(ctx, s.clientSecretRepo, client, clientSecret); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// IntrospectRequest はトークンのイントロスペクションリクエストのパラメータを表します
+type IntrospectRequest struct {
+	Token           string
+	TokenTypeHint   string // access_token または refresh_token（検索の順序のヒントのため、無視します）
+	ClientID        string
+	ClientSecret    string
+	ClientAssertion string // private_key_jwtのクライアントが署名したJWT（client_assertion）
+}
+
+// IntrospectionResponse はトークンのイントロスペクションの結果を表します（RFC 7662 2.2）
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+}
+
+// IntrospectToken はクライアントに発行したアクセストークンが有効か確認します（RFC 7662）
+// 存在しないトークン・他のクライアントのトークン・リフレッシュトークン・期限切れや取り消し済みのトークンは、
+// エラーにせず active: false を返します（トークンの有無を推測させない）
+// 公開クライアントはトークンを保護できないため、イントロスペクションを使用できません
+func (s *OAuth2Service) IntrospectToken(ctx context.Context, req *IntrospectRequest) (*IntrospectionResponse, error) {
+	// 1. クライアント認証
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret, req.ClientAssertion)
+	if err != nil {
+		return nil, err
+	}
+	if client.IsPublic() {
+		return nil, fmt.Errorf("%w: public clients cannot introspect tokens", domain.ErrInvalidClient)
+	}
+
+	// 2. トークンを検証（このクライアントに発行した有効なアクセストークンのみ）
+	inactive := &IntrospectionResponse{Active: false}
+	user, token, err := s.authenticateAccessToken(ctx, req.Token)
+	if err != nil || token.ClientID != client.ClientID {
+		return inactive, nil
+	}
+
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(client.EffectiveScopes(), " "),
+		ClientID:  client.ClientID,
+		Username:  user.Username,
+		TokenType: "Bearer",
+		ExpiresAt: token.ExpiresAt.Unix(),
+		IssuedAt:  token.CreatedAt.Unix(),
+		Subject:   user.ID,
+		Audience:  client.ClientID,
+		JTI:       token.JTI(),
+	}, nil
+}
+
 // GetUserByAccessToken はアクセストークンからユーザー情報を取得します
 func (s *OAuth2Service) GetUserByAccessToken(ctx context.Context, accessToken string) (*domain.User, error) {
+	user, _, err := s.authenticateAccessToken(ctx, accessToken)
+	return user, err
+}
+
+// GetUserAndClientByAccessToken はアクセストークンからユーザー情報と発行先のクライアントを取得します
+func (s *OAuth2Service) GetUserAndClientByAccessToken(ctx context.Context, accessToken string) (*domain.User, *domain.ClientApp, error) {
+	user, token, err := s.authenticateAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := s.clientRepo.GetByClientID(ctx, token.ClientID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client: %w", err)
+	}
+
+	return user, client, nil
+}
+
+// authenticateAccessToken はアクセストークンを検証し、ユーザー情報とトークンを返します
+func (s *OAuth2Service) authenticateAccessToken(ctx context.Context, accessToken string) (*domain.User, *domain.Token, error) {
 	// 1. トークンを取得
-	token, err := s.tokenRepo.GetByToken(ctx, accessToken)
+	token, err := s.findToken(ctx, accessToken, false)
 	if err != nil {
-		return nil, fmt.Errorf("invalid access token: %w", err)
+		return nil, nil, fmt.Errorf("invalid access token: %w", err)
 	}
 
 	// 2. トークンの種類を確認（アクセストークンか？）
 	if token.TokenType != domain.TokenTypeAccess {
-		return nil, fmt.Errorf("token is not an access token")
+		return nil, nil, fmt.Errorf("token is not an access token")
 	}
 
 	// 3. トークンの有効性を確認（期限切れ & 取り消し済みチェック）
 	if !token.IsValid() {
-		return nil, fmt.Errorf("token is expired or revoked")
+		return nil, nil, fmt.Errorf("token is expired or revoked")
 	}
 
 	// 4. ユーザー情報を取得
 	user, err := s.userRepo.GetByID(ctx, token.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return user, nil
+	return user, token, nil
+}
+
+// findToken はクライアントから受け取ったトークンを取得します
+// JWT形式のアクセストークンは署名を検証してからjtiで取得します（allowExpiredがtrueの場合は期限切れのJWTも受け付けます）
+func (s *OAuth2Service) findToken(ctx context.Context, rawToken string, allowExpired bool) (*domain.Token, error) {
+	// 保存用のキーをそのままトークンとして使用させない
+	if strings.HasPrefix(rawToken, domain.JWTAccessTokenPrefix) {
+		return nil, fmt.Errorf("token not found")
+	}
+	if !jwt.IsJWT(rawToken) {
+		return s.tokenRepo.GetByToken(ctx, rawToken)
+	}
+
+	if s.jwtAccessTokens == nil {
+		return nil, fmt.Errorf("token not found")
+	}
+	claims, err := s.jwtAccessTokens.parse(rawToken, allowExpired)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenRepo.GetByToken(ctx, domain.JWTAccessTokenKey(claims.ID))
+}
+
+// AccessTokenKeys はJWT形式のアクセストークンの署名を検証するための公開鍵を返します（JWT形式を使用できない場合はnil）
+func (s *OAuth2Service) AccessTokenKeys() *jwks.Set {
+	if s.jwtAccessTokens == nil {
+		return nil
+	}
+	return s.jwtAccessTokens.PublicKeys()
+}
+
+// ListRevokedAccessTokens は取り消したJWT形式のアクセストークンのうち、期限切れでないものを返します
+// リソースサーバーはこの一覧（jti）をキャッシュして、署名を検証したアクセストークンが取り消されていないか確認します
+func (s *OAuth2Service) ListRevokedAccessTokens(ctx context.Context) ([]*domain.Token, error) {
+	tokens, err := s.tokenRepo.ListRevokedJWTAccessTokens(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revoked access tokens: %w", err)
+	}
+	return tokens, nil
 }
 
 // generateSecureToken は暗号学的に安全なランダムトークンを生成します
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// DeviceAuthorizationRequest はデバイス認可リクエスト（RFC 8628 3.1）のパラメータを表します
+type DeviceAuthorizationRequest struct {
+	ClientID        string
+	ClientSecret    string
+	ClientAssertion string // private_key_jwtのクライアントが署名したJWT（client_assertion）
+}
+
+// DeviceAuthorizationResponse はデバイス認可レスポンス（RFC 8628 3.2）を表します
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// RequestDeviceAuthorization はブラウザを開けないデバイス（CLIツール・部室の端末など）にデバイスコードとユーザーコードを発行します
+// verificationURIはユーザーがユーザーコードを入力するページのURLです
+func (s *OAuth2Service) RequestDeviceAuthorization(ctx context.Context, req *DeviceAuthorizationRequest, verificationURI string) (*DeviceAuthorizationResponse, error) {
+	// 1. クライアント認証
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret, req.ClientAssertion)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrantType(GrantTypeDeviceCode) {
+		return nil, fmt.Errorf("%w: the client is not registered for the device_code grant", domain.ErrUnauthorizedClient)
+	}
+
+	// 2. デバイスコードとユーザーコードを生成
+	deviceCode, err := generateSecureToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate user code: %w", err)
+	}
+
+	// 3. デバイスコードを保存
+	now := time.Now()
+	d := &domain.DeviceCode{
+		ID:         uuid.New().String(),
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ClientID:   client.ClientID,
+		Status:     domain.DeviceCodeStatusPending,
+		Interval:   DeviceCodePollInterval,
+		ExpiresAt:  now.Add(DeviceCodeExpiration),
+		CreatedAt:  now,
+	}
+	if err := s.deviceCodeRepo.Create(ctx, d); err != nil {
+		return nil, fmt.Errorf("failed to store device code: %w", err)
+	}
+
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                d.FormattedUserCode(),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(d.FormattedUserCode()),
+		ExpiresIn:               int(DeviceCodeExpiration.Seconds()),
+		Interval:                int(DeviceCodePollInterval.Seconds()),
+	}, nil
+}
+
+// GetPendingDeviceAuthorization はユーザーが入力したユーザーコードから、承認待ちのデバイス認可と要求したクライアントを取得します
+// 見つからない・期限切れ・承認または拒否済みの場合はdomain.ErrDeviceCodeNotFoundを返します
+func (s *OAuth2Service) GetPendingDeviceAuthorization(ctx context.Context, userCode string) (*domain.DeviceCode, *domain.ClientApp, error) {
+	normalized := domain.NormalizeUserCode(userCode)
+	if len(normalized) != domain.UserCodeLength {
+		return nil, nil, domain.ErrDeviceCodeNotFound
+	}
+
+	d, err := s.deviceCodeRepo.GetByUserCode(ctx, normalized)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.Status != domain.DeviceCodeStatusPending || d.IsExpired() {
+		return nil, nil, domain.ErrDeviceCodeNotFound
+	}
+
+	client, err := s.clientRepo.GetByClientID(ctx, d.ClientID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	return d, client, nil
+}
+
+// ApproveDeviceAuthorization はログイン中のユーザーとしてデバイス認可を承認します
+// 次のポーリングでデバイスにユーザーのトークンを発行します
+func (s *OAuth2Service) ApproveDeviceAuthorization(ctx context.Context, userCode, userID string) error {
+	return s.decideDeviceAuthorization(ctx, userCode, userID, domain.DeviceCodeStatusApproved)
+}
+
+// DenyDeviceAuthorization はログイン中のユーザーとしてデバイス認可を拒否します
+// 次のポーリングでデバイスにaccess_deniedを返します
+func (s *OAuth2Service) DenyDeviceAuthorization(ctx context.Context, userCode, userID string) error {
+	return s.decideDeviceAuthorization(ctx, userCode, userID, domain.DeviceCodeStatusDenied)
+}
+
+// decideDeviceAuthorization は承認待ちのデバイス認可を承認または拒否します
+func (s *OAuth2Service) decideDeviceAuthorization(ctx context.Context, userCode, userID string, status domain.DeviceCodeStatus) error {
+	d, _, err := s.GetPendingDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		return err
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return fmt.Errorf("invalid user_id: %w", err)
+	}
+
+	// 同じユーザーコードが同時に承認・拒否された場合は先に処理したものを優先する
+	if err := s.deviceCodeRepo.UpdateStatus(ctx, d.ID, domain.DeviceCodeStatusPending, status, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// exchangeDeviceCode はデバイスのポーリング（RFC 8628 3.4）を処理し、承認済みの場合はトークンを発行します
+// 承認待ちの場合はdomain.ErrAuthorizationPending、間隔を守らない場合はdomain.ErrSlowDown、
+// 拒否された場合はdomain.ErrAccessDenied、期限切れの場合はdomain.ErrDeviceCodeExpiredを返します
+func (s *OAuth2Service) exchangeDeviceCode(ctx context.Context, req *TokenRequest) (*TokenResponse, error) {
+	// 1. クライアント認証
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret, req.ClientAssertion)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrantType(GrantTypeDeviceCode) {
+		return nil, fmt.Errorf("%w: the client is not registered for the device_code grant", domain.ErrUnauthorizedClient)
+	}
+
+	// 2. デバイスコードの取得と検証
+	d, err := s.deviceCodeRepo.GetByDeviceCode(ctx, req.DeviceCode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device code: %w", err)
+	}
+	if d.ClientID != client.ClientID {
+		return nil, fmt.Errorf("client_id mismatch")
+	}
+	if d.IsExpired() {
+		return nil, domain.ErrDeviceCodeExpired
+	}
+
+	// 3. ポーリングの間隔を確認（短すぎる場合は間隔を延長してslow_downを返す）
+	now := time.Now()
+	interval := d.Interval
+	tooFast := d.LastPolledAt != nil && now.Sub(*d.LastPolledAt) < d.Interval
+	if tooFast {
+		interval += DeviceCodeSlowDownStep
+	}
+	if err := s.deviceCodeRepo.UpdatePolling(ctx, d.ID, now, interval); err != nil {
+		return nil, err
+	}
+	if tooFast {
+		return nil, domain.ErrSlowDown
+	}
+
+	// 4. 状態に応じて応答
+	switch d.Status {
+	case domain.DeviceCodeStatusPending:
+		return nil, domain.ErrAuthorizationPending
+	case domain.DeviceCodeStatusDenied:
+		return nil, fmt.Errorf("%w: the user denied the device authorization", domain.ErrAccessDenied)
+	case domain.DeviceCodeStatusApproved:
+	default:
+		return nil, fmt.Errorf("device code already used")
+	}
+
+	// 5. 同じデバイスコードで二重に発行しないよう、先に使用済みにマーク
+	if err := s.deviceCodeRepo.UpdateStatus(ctx, d.ID, domain.DeviceCodeStatusApproved, domain.DeviceCodeStatusUsed, ""); err != nil {
+		if errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			return nil, fmt.Errorf("device code already used")
+		}
+		return nil, err
+	}
+
+	// 6. アクセストークンとリフレッシュトークンを発行
+	return s.issueTokens(ctx, d.UserID, client)
+}
+
+// generateUserCode はユーザーが入力しやすいユーザーコード（区切りなしの8文字）を生成します
+func generateUserCode() (string, error) {
+	charsetLen := big.NewInt(int64(len(domain.UserCodeCharset)))
+	code := make([]byte, domain.UserCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, charsetLen)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random user code: %w", err)
+		}
+		code[i] = domain.UserCodeCharset[n.Int64()]
+	}
+	return string(code), nil
+}
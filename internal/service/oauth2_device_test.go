@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
+)
+
+func TestOAuth2Service_DeviceAuthorization(t *testing.T) {
+	ctx := context.Background()
+
+	tokenRepo := newMockTokenRepository()
+	userRepo := newMockOAuth2UserRepository()
+	clientRepo := newMockClientRepository()
+	deviceCodeRepo := newMockDeviceCodeRepository()
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), newMockAuthCodeRepository(), deviceCodeRepo, tokenRepo, userRepo, nil, nil, nil)
+
+	hashed, err := auth.HashClientSecret("secret")
+	if err != nil {
+		t.Fatalf("Failed to hash secret: %v", err)
+	}
+	clientRepo.clients["cli"] = &domain.ClientApp{ID: "id-cli", ClientID: "cli", ClientSecret: hashed, Name: "出席CLI"}
+	clientRepo.clients["kiosk"] = &domain.ClientApp{ID: "id-kiosk", ClientID: "kiosk", ClientSecret: hashed}
+	userRepo.users["user-1"] = &domain.User{ID: "user-1", DiscordID: "d-1", Username: "taro"}
+
+	// start はデバイス認可を要求し、ポーリングの間隔を経過したことにするための関数を返します
+	start := func(t *testing.T) (*DeviceAuthorizationResponse, func()) {
+		t.Helper()
+		resp, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "cli", ClientSecret: "secret"}, "https://auth.example.com/device")
+		if err != nil {
+			t.Fatalf("RequestDeviceAuthorization failed: %v", err)
+		}
+		d, _ := deviceCodeRepo.GetByDeviceCode(ctx, resp.DeviceCode)
+		return resp, func() {
+			if d.LastPolledAt != nil {
+				past := d.LastPolledAt.Add(-d.Interval)
+				d.LastPolledAt = &past
+			}
+		}
+	}
+	poll := func(deviceCode string) (*TokenResponse, error) {
+		return service.ExchangeToken(ctx, &TokenRequest{GrantType: GrantTypeDeviceCode, DeviceCode: deviceCode, ClientID: "cli", ClientSecret: "secret"})
+	}
+
+	t.Run("承認", func(t *testing.T) {
+		resp, wait := start(t)
+		if !regexp.MustCompile(`^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$`).MatchString(resp.UserCode) {
+			t.Errorf("Unexpected user code format: %q", resp.UserCode)
+		}
+		if resp.VerificationURIComplete != "https://auth.example.com/device?user_code="+resp.UserCode || resp.Interval != 5 || resp.ExpiresIn != 600 {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+
+		if _, err := poll(resp.DeviceCode); !errors.Is(err, domain.ErrAuthorizationPending) {
+			t.Fatalf("Expected ErrAuthorizationPending, got %v", err)
+		}
+
+		// 入力されたユーザーコードは大文字小文字・区切りを問わない
+		d, client, err := service.GetPendingDeviceAuthorization(ctx, " "+domain.NormalizeUserCode(resp.UserCode)[:4]+" "+strings.ToLower(resp.UserCode[5:]))
+		if err != nil {
+			t.Fatalf("GetPendingDeviceAuthorization failed: %v", err)
+		}
+		if client.Name != "出席CLI" || d.FormattedUserCode() != resp.UserCode {
+			t.Errorf("Unexpected device authorization: %+v, %+v", d, client)
+		}
+
+		if err := service.ApproveDeviceAuthorization(ctx, resp.UserCode, "user-1"); err != nil {
+			t.Fatalf("ApproveDeviceAuthorization failed: %v", err)
+		}
+		if _, _, err := service.GetPendingDeviceAuthorization(ctx, resp.UserCode); !errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			t.Errorf("Expected approved user code to be no longer pending, got %v", err)
+		}
+
+		wait()
+		tokenResp, err := poll(resp.DeviceCode)
+		if err != nil {
+			t.Fatalf("Expected tokens after approval, got %v", err)
+		}
+		token := tokenRepo.tokens[tokenResp.AccessToken]
+		if token == nil || token.UserID != "user-1" || token.ClientID != "cli" || tokenResp.RefreshToken == "" {
+			t.Errorf("Unexpected token: %+v", token)
+		}
+
+		wait()
+		if _, err := poll(resp.DeviceCode); err == nil {
+			t.Error("Expected error for a used device code")
+		}
+	})
+
+	t.Run("slow_down", func(t *testing.T) {
+		resp, wait := start(t)
+		if _, err := poll(resp.DeviceCode); !errors.Is(err, domain.ErrAuthorizationPending) {
+			t.Fatalf("Expected ErrAuthorizationPending, got %v", err)
+		}
+		if _, err := poll(resp.DeviceCode); !errors.Is(err, domain.ErrSlowDown) {
+			t.Fatalf("Expected ErrSlowDown, got %v", err)
+		}
+		d, _ := deviceCodeRepo.GetByDeviceCode(ctx, resp.DeviceCode)
+		if d.Interval != 10*time.Second {
+			t.Errorf("Expected interval to be extended to 10s, got %v", d.Interval)
+		}
+		wait()
+		if _, err := poll(resp.DeviceCode); !errors.Is(err, domain.ErrAuthorizationPending) {
+			t.Errorf("Expected ErrAuthorizationPending after waiting, got %v", err)
+		}
+	})
+
+	t.Run("拒否", func(t *testing.T) {
+		resp, _ := start(t)
+		if err := service.DenyDeviceAuthorization(ctx, resp.UserCode, "user-1"); err != nil {
+			t.Fatalf("DenyDeviceAuthorization failed: %v", err)
+		}
+		if err := service.ApproveDeviceAuthorization(ctx, resp.UserCode, "user-1"); !errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			t.Errorf("Expected ErrDeviceCodeNotFound for a denied user code, got %v", err)
+		}
+		if _, err := poll(resp.DeviceCode); !errors.Is(err, domain.ErrAccessDenied) {
+			t.Errorf("Expected ErrAccessDenied, got %v", err)
+		}
+	})
+
+	t.Run("期限切れ", func(t *testing.T) {
+		resp, _ := start(t)
+		d, _ := deviceCodeRepo.GetByDeviceCode(ctx, resp.DeviceCode)
+		d.ExpiresAt = time.Now().Add(-time.Second)
+		if _, err := poll(resp.DeviceCode); !errors.Is(err, domain.ErrDeviceCodeExpired) {
+			t.Errorf("Expected ErrDeviceCodeExpired, got %v", err)
+		}
+		if err := service.ApproveDeviceAuthorization(ctx, resp.UserCode, "user-1"); !errors.Is(err, domain.ErrDeviceCodeNotFound) {
+			t.Errorf("Expected ErrDeviceCodeNotFound for an expired user code, got %v", err)
+		}
+	})
+
+	t.Run("公開クライアント", func(t *testing.T) {
+		clientRepo.clients["public-cli"] = &domain.ClientApp{
+			ID:                      "id-public-cli",
+			ClientID:                "public-cli",
+			Name:                    "配布CLI",
+			GrantTypes:              []string{domain.GrantTypeDeviceCode},
+			TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodNone,
+		}
+		// client_idのみで要求・ポーリングできる
+		resp, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "public-cli"}, "https://auth.example.com/device")
+		if err != nil {
+			t.Fatalf("RequestDeviceAuthorization failed: %v", err)
+		}
+		if err := service.ApproveDeviceAuthorization(ctx, resp.UserCode, "user-1"); err != nil {
+			t.Fatalf("ApproveDeviceAuthorization failed: %v", err)
+		}
+		tokenResp, err := service.ExchangeToken(ctx, &TokenRequest{GrantType: GrantTypeDeviceCode, DeviceCode: resp.DeviceCode, ClientID: "public-cli"})
+		if err != nil {
+			t.Fatalf("ExchangeToken failed: %v", err)
+		}
+		if tokenResp.AccessToken == "" {
+			t.Error("Expected an access token")
+		}
+
+		// Client Secretを送信した場合と、デバイス認可グラント以外は拒否する
+		if _, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "public-cli", ClientSecret: "secret"}, "https://auth.example.com/device"); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient with a client_secret, got %v", err)
+		}
+		if _, err := service.ExchangeToken(ctx, &TokenRequest{GrantType: domain.GrantTypeAuthorizationCode, Code: "code", ClientID: "public-cli"}); !errors.Is(err, domain.ErrUnauthorizedClient) {
+			t.Errorf("Expected ErrUnauthorizedClient for the authorization_code grant, got %v", err)
+		}
+
+		// 機密クライアントはClient Secretを省略できない
+		if _, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "cli"}, "https://auth.example.com/device"); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient without a client_secret, got %v", err)
+		}
+	})
+
+	t.Run("クライアント", func(t *testing.T) {
+		if _, err := service.RequestDeviceAuthorization(ctx, &DeviceAuthorizationRequest{ClientID: "cli", ClientSecret: "wrong"}, "https://auth.example.com/device"); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient, got %v", err)
+		}
+		resp, _ := start(t)
+		if _, err := service.ExchangeToken(ctx, &TokenRequest{GrantType: GrantTypeDeviceCode, DeviceCode: resp.DeviceCode, ClientID: "kiosk", ClientSecret: "secret"}); err == nil || errors.Is(err, domain.ErrAuthorizationPending) {
+			t.Errorf("Expected another client's device code to be rejected, got %v", err)
+		}
+		if _, err := poll("unknown"); err == nil {
+			t.Error("Expected error for unknown device code")
+		}
+	})
+}
+
+// モックDeviceCodeRepository
+type mockDeviceCodeRepository struct {
+	deviceCodes map[string]*domain.DeviceCode
+}
+
+func newMockDeviceCodeRepository() *mockDeviceCodeRepository {
+	return &mockDeviceCodeRepository{
+		deviceCodes: make(map[string]*domain.DeviceCode),
+	}
+}
+
+func (m *mockDeviceCodeRepository) Create(ctx context.Context, deviceCode *domain.DeviceCode) error {
+	if err := deviceCode.Validate(); err != nil {
+		return err
+	}
+	m.deviceCodes[deviceCode.ID] = deviceCode
+	return nil
+}
+
+func (m *mockDeviceCodeRepository) GetByDeviceCode(ctx context.Context, deviceCode string) (*domain.DeviceCode, error) {
+	for _, d := range m.deviceCodes {
+		if d.DeviceCode == deviceCode {
+			return d, nil
+		}
+	}
+	return nil, domain.ErrDeviceCodeNotFound
+}
+
+func (m *mockDeviceCodeRepository) GetByUserCode(ctx context.Context, userCode string) (*domain.DeviceCode, error) {
+	for _, d := range m.deviceCodes {
+		if d.UserCode == userCode && !d.IsExpired() {
+			return d, nil
+		}
+	}
+	return nil, domain.ErrDeviceCodeNotFound
+}
+
+func (m *mockDeviceCodeRepository) UpdatePolling(ctx context.Context, id string, polledAt time.Time, interval time.Duration) error {
+	if d, ok := m.deviceCodes[id]; ok {
+		d.LastPolledAt = &polledAt
+		d.Interval = interval
+	}
+	return nil
+}
+
+func (m *mockDeviceCodeRepository) UpdateStatus(ctx context.Context, id string, from, to domain.DeviceCodeStatus, userID string) error {
+	d, ok := m.deviceCodes[id]
+	if !ok || d.Status != from {
+		return domain.ErrDeviceCodeNotFound
+	}
+	d.Status = to
+	if userID != "" {
+		d.UserID = userID
+	}
+	return nil
+}
+
+func (m *mockDeviceCodeRepository) DeleteExpired(ctx context.Context) error {
+	return nil
+}
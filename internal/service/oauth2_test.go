@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/auth"
 )
 
 // モックTokenRepository
@@ -63,6 +64,16 @@ func (m *mockTokenRepository) Revoke(ctx context.Context, token string) error {
 	return nil
 }
 
+func (m *mockTokenRepository) ListRevokedJWTAccessTokens(ctx context.Context, now time.Time) ([]*domain.Token, error) {
+	var tokens []*domain.Token
+	for _, t := range m.tokens {
+		if t.TokenType == domain.TokenTypeAccess && t.Revoked && t.ExpiresAt.After(now) && t.JTI() != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
 func (m *mockTokenRepository) DeleteExpired(ctx context.Context) error {
 	return nil
 }
@@ -232,6 +243,21 @@ func (m *mockOAuth2UserRepository) GetAll(ctx context.Context, limit, offset int
 	return users, nil
 }
 
+func (m *mockOAuth2UserRepository) FindMembers(ctx context.Context, filter domain.MemberFilter, page domain.MemberPage) ([]*domain.User, *domain.MemberCursor, error) {
+	// プロフィールとの結合はモックでは再現しないため、条件は無視する
+	users, err := m.GetAll(ctx, page.Limit, page.Offset)
+	return users, nil, err
+}
+
+func (m *mockOAuth2UserRepository) CountMembers(ctx context.Context, filter domain.MemberFilter) (int64, error) {
+	users, err := m.GetAll(ctx, 0, 0)
+	return int64(len(users)), err
+}
+
+func (m *mockOAuth2UserRepository) MarkLeftExcept(ctx context.Context, discordIDs []string) ([]*domain.User, error) {
+	return nil, nil
+}
+
 // TestOAuth2Service_GetUserByAccessToken_Success tests that a valid access token returns the expected user
 func TestOAuth2Service_GetUserByAccessToken_Success(t *testing.T) {
 	tokenRepo := newMockTokenRepository()
@@ -239,7 +265,7 @@ func TestOAuth2Service_GetUserByAccessToken_Success(t *testing.T) {
 	clientRepo := newMockClientRepository()
 	authCodeRepo := newMockAuthCodeRepository()
 
-	service := NewOAuth2Service(clientRepo, authCodeRepo, tokenRepo, userRepo)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
 
 	// テストデータを準備
 	userID := uuid.New().String()
@@ -296,7 +322,7 @@ func TestOAuth2Service_GetUserByAccessToken_TokenNotFound(t *testing.T) {
 	clientRepo := newMockClientRepository()
 	authCodeRepo := newMockAuthCodeRepository()
 
-	service := NewOAuth2Service(clientRepo, authCodeRepo, tokenRepo, userRepo)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
 
 	ctx := context.Background()
 	_, err := service.GetUserByAccessToken(ctx, "non-existent-token")
@@ -313,7 +339,7 @@ func TestOAuth2Service_GetUserByAccessToken_WrongTokenType(t *testing.T) {
 	clientRepo := newMockClientRepository()
 	authCodeRepo := newMockAuthCodeRepository()
 
-	service := NewOAuth2Service(clientRepo, authCodeRepo, tokenRepo, userRepo)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
 
 	userID := uuid.New().String()
 	tokenString := "refresh-token"
@@ -352,7 +378,7 @@ func TestOAuth2Service_GetUserByAccessToken_ExpiredToken(t *testing.T) {
 	clientRepo := newMockClientRepository()
 	authCodeRepo := newMockAuthCodeRepository()
 
-	service := NewOAuth2Service(clientRepo, authCodeRepo, tokenRepo, userRepo)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
 
 	userID := uuid.New().String()
 	tokenString := "expired-token"
@@ -391,7 +417,7 @@ func TestOAuth2Service_GetUserByAccessToken_RevokedToken(t *testing.T) {
 	clientRepo := newMockClientRepository()
 	authCodeRepo := newMockAuthCodeRepository()
 
-	service := NewOAuth2Service(clientRepo, authCodeRepo, tokenRepo, userRepo)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
 
 	userID := uuid.New().String()
 	tokenString := "revoked-token"
@@ -430,7 +456,7 @@ func TestOAuth2Service_GetUserByAccessToken_UserNotFound(t *testing.T) {
 	clientRepo := newMockClientRepository()
 	authCodeRepo := newMockAuthCodeRepository()
 
-	service := NewOAuth2Service(clientRepo, authCodeRepo, tokenRepo, userRepo)
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
 
 	userID := uuid.New().String()
 	tokenString := "valid-token-but-user-not-found"
@@ -461,3 +487,149 @@ func TestOAuth2Service_GetUserByAccessToken_UserNotFound(t *testing.T) {
 		t.Errorf("Error message mismatch: got %v", err.Error())
 	}
 }
+
+// TestOAuth2Service_RevokeToken tests that revoking a refresh token also revokes the client's access tokens and publishes events
+func TestOAuth2Service_RevokeToken(t *testing.T) {
+	tokenRepo := newMockTokenRepository()
+	userRepo := newMockOAuth2UserRepository()
+	clientRepo := newMockClientRepository()
+	authCodeRepo := newMockAuthCodeRepository()
+	publisher := &recordingPublisher{}
+
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), authCodeRepo, newMockDeviceCodeRepository(), tokenRepo, userRepo, publisher, nil, nil)
+
+	hashed, err := auth.HashClientSecret("secret")
+	if err != nil {
+		t.Fatalf("Failed to hash secret: %v", err)
+	}
+	clientRepo.clients["client-a"] = &domain.ClientApp{ID: "id-a", ClientID: "client-a", ClientSecret: hashed}
+
+	expiresAt := time.Now().Add(time.Hour)
+	tokenRepo.tokens["refresh"] = &domain.Token{ID: "t1", Token: "refresh", TokenType: domain.TokenTypeRefresh, UserID: "user-1", ClientID: "client-a", ExpiresAt: expiresAt}
+	tokenRepo.tokens["access"] = &domain.Token{ID: "t2", Token: "access", TokenType: domain.TokenTypeAccess, UserID: "user-1", ClientID: "client-a", ExpiresAt: expiresAt}
+	tokenRepo.tokens["other-client"] = &domain.Token{ID: "t3", Token: "other-client", TokenType: domain.TokenTypeAccess, UserID: "user-1", ClientID: "client-b", ExpiresAt: expiresAt}
+
+	ctx := context.Background()
+
+	// クライアント認証に失敗した場合はエラー
+	err = service.RevokeToken(ctx, &RevokeRequest{Token: "refresh", ClientID: "client-a", ClientSecret: "wrong"})
+	if !errors.Is(err, domain.ErrInvalidClient) {
+		t.Fatalf("Expected ErrInvalidClient, got %v", err)
+	}
+
+	// 存在しないトークン・他のクライアントのトークンは何もせず成功
+	for _, token := range []string{"unknown", "other-client"} {
+		if err := service.RevokeToken(ctx, &RevokeRequest{Token: token, ClientID: "client-a", ClientSecret: "secret"}); err != nil {
+			t.Errorf("Expected no error for %s, got %v", token, err)
+		}
+	}
+	if tokenRepo.tokens["other-client"].Revoked {
+		t.Error("Expected other client's token to remain valid")
+	}
+
+	if err := service.RevokeToken(ctx, &RevokeRequest{Token: "refresh", ClientID: "client-a", ClientSecret: "secret"}); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+	if !tokenRepo.tokens["refresh"].Revoked || !tokenRepo.tokens["access"].Revoked {
+		t.Error("Expected refresh and access tokens to be revoked")
+	}
+	if len(publisher.events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(publisher.events))
+	}
+	for _, event := range publisher.events {
+		if event.Type != domain.EventTokenRevoked || event.ClientID != "client-a" || event.UserID != "user-1" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	}
+
+	// 取り消し済みのトークンは再度通知しない
+	if err := service.RevokeToken(ctx, &RevokeRequest{Token: "refresh", ClientID: "client-a", ClientSecret: "secret"}); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+	if len(publisher.events) != 2 {
+		t.Errorf("Expected no new events, got %d", len(publisher.events))
+	}
+}
+
+// TestOAuth2Service_IntrospectToken tests that only valid access tokens issued to the authenticated client are reported as active
+func TestOAuth2Service_IntrospectToken(t *testing.T) {
+	tokenRepo := newMockTokenRepository()
+	userRepo := newMockOAuth2UserRepository()
+	clientRepo := newMockClientRepository()
+
+	service := NewOAuth2Service(clientRepo, newMockClientSecretRepository(), newMockAuthCodeRepository(), newMockDeviceCodeRepository(), tokenRepo, userRepo, nil, nil, nil)
+
+	hashed, err := auth.HashClientSecret("secret")
+	if err != nil {
+		t.Fatalf("Failed to hash secret: %v", err)
+	}
+	clientRepo.clients["client-a"] = &domain.ClientApp{ID: "id-a", ClientID: "client-a", ClientSecret: hashed, Scopes: []string{domain.ClientScopeProfile}}
+	clientRepo.clients["client-b"] = &domain.ClientApp{ID: "id-b", ClientID: "client-b", ClientSecret: hashed}
+	clientRepo.clients["public-cli"] = &domain.ClientApp{
+		ID:                      "id-p",
+		ClientID:                "public-cli",
+		GrantTypes:              []string{domain.GrantTypeDeviceCode},
+		TokenEndpointAuthMethod: domain.TokenEndpointAuthMethodNone,
+	}
+	userRepo.users["user-1"] = &domain.User{ID: "user-1", Username: "alice"}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	tokenRepo.tokens["access"] = &domain.Token{ID: "t1", Token: "access", TokenType: domain.TokenTypeAccess, UserID: "user-1", ClientID: "client-a", ExpiresAt: expiresAt, CreatedAt: now}
+	tokenRepo.tokens["refresh"] = &domain.Token{ID: "t2", Token: "refresh", TokenType: domain.TokenTypeRefresh, UserID: "user-1", ClientID: "client-a", ExpiresAt: expiresAt}
+	tokenRepo.tokens["expired"] = &domain.Token{ID: "t3", Token: "expired", TokenType: domain.TokenTypeAccess, UserID: "user-1", ClientID: "client-a", ExpiresAt: now.Add(-time.Minute)}
+	tokenRepo.tokens["revoked"] = &domain.Token{ID: "t4", Token: "revoked", TokenType: domain.TokenTypeAccess, UserID: "user-1", ClientID: "client-a", ExpiresAt: expiresAt, Revoked: true}
+	tokenRepo.tokens["other-client"] = &domain.Token{ID: "t5", Token: "other-client", TokenType: domain.TokenTypeAccess, UserID: "user-1", ClientID: "client-b", ExpiresAt: expiresAt}
+
+	ctx := context.Background()
+
+	// クライアント認証に失敗した場合・公開クライアントの場合はエラー
+	for _, req := range []*IntrospectRequest{
+		{Token: "access", ClientID: "client-a", ClientSecret: "wrong"},
+		{Token: "access", ClientID: "public-cli"},
+	} {
+		if _, err := service.IntrospectToken(ctx, req); !errors.Is(err, domain.ErrInvalidClient) {
+			t.Errorf("Expected ErrInvalidClient for %s, got %v", req.ClientID, err)
+		}
+	}
+
+	resp, err := service.IntrospectToken(ctx, &IntrospectRequest{Token: "access", ClientID: "client-a", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatalf("IntrospectToken failed: %v", err)
+	}
+	want := IntrospectionResponse{
+		Active:    true,
+		Scope:     "profile",
+		ClientID:  "client-a",
+		Username:  "alice",
+		TokenType: "Bearer",
+		ExpiresAt: expiresAt.Unix(),
+		IssuedAt:  now.Unix(),
+		Subject:   "user-1",
+		Audience:  "client-a",
+	}
+	if *resp != want {
+		t.Errorf("Unexpected response: got %+v, want %+v", *resp, want)
+	}
+
+	// スコープを登録していないクライアントは、登録できるすべてのスコープを返す
+	resp, err = service.IntrospectToken(ctx, &IntrospectRequest{Token: "other-client", ClientID: "client-b", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatalf("IntrospectToken failed: %v", err)
+	}
+	if resp.Scope != "profile members" {
+		t.Errorf("Expected all scopes for client without scopes, got %q", resp.Scope)
+	}
+
+	// 存在しない・リフレッシュトークン・期限切れ・取り消し済み・他のクライアントのトークンは無効として返す
+	for _, token := range []string{"unknown", "refresh", "expired", "revoked", "other-client"} {
+		resp, err := service.IntrospectToken(ctx, &IntrospectRequest{Token: token, ClientID: "client-a", ClientSecret: "secret"})
+		if err != nil {
+			t.Errorf("Expected no error for %s, got %v", token, err)
+			continue
+		}
+		if *resp != (IntrospectionResponse{}) {
+			t.Errorf("Expected inactive response for %s, got %+v", token, *resp)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,172 +14,888 @@ import (
 	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
 	"github.com/jyogi-web/jyogi-discord-auth/pkg/discord"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/studentid"
 )
 
+// DefaultFullSyncInterval は全件照合（フルスキャン）を行う既定の間隔です
+const DefaultFullSyncInterval = 24 * time.Hour
+
 // SyncStats はプロフィール同期の統計情報を保持します
 type SyncStats struct {
 	SuccessCount  int
 	SkipCount     int
 	ErrorCount    int
+	DeletedCount  int
 	TotalMessages int
+	FullSync      bool // 全件照合で実行されたかどうか
+}
+
+// RosterSyncStats はロスター（サーバーメンバー一覧）同期の統計情報を保持します
+type RosterSyncStats struct {
+	RoleCount    int
+	MemberCount  int
+	CreatedCount int
+	UpdatedCount int
+	LeftCount    int
+	ErrorCount   int
 }
 
 // ProfileService はプロフィール同期サービスを提供します
 type ProfileService struct {
-	profileRepo   repository.ProfileRepository
-	userRepo      repository.UserRepository
-	botToken      string
-	channelID     string
-	lastSyncStats SyncStats
-	mu            sync.RWMutex
+	profileRepo      repository.ProfileRepository
+	userRepo         repository.UserRepository
+	roleRepo         repository.RoleRepository
+	cursorRepo       repository.SyncCursorRepository
+	introRepo        repository.IntroMessageRepository
+	historyRepo      repository.ChangeHistoryRepository
+	searchRepo       repository.ProfileSearchRepository
+	botToken         string
+	guildID          string
+	channelID        string
+	fullSyncInterval time.Duration
+	mergeStrategy    domain.ProfileMergeStrategy
+	template         *discord.ProfileTemplate
+	studentIDs       *studentid.Parser
+	lastSyncStats    SyncStats
+	lastRosterStats  RosterSyncStats
+	mu               sync.RWMutex
+	syncMu           sync.Mutex // ポーリングとGatewayイベントの同期処理を直列化します
 }
 
 // NewProfileService は新しいProfileServiceを作成します
+// fullSyncIntervalが0以下の場合はDefaultFullSyncInterval、mergeStrategyが空の場合はnewest、
+// templateがnilの場合は標準の自己紹介テンプレート、studentIDsがnilの場合は標準の学籍番号形式を使用します
 func NewProfileService(
 	profileRepo repository.ProfileRepository,
 	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	cursorRepo repository.SyncCursorRepository,
+	introRepo repository.IntroMessageRepository,
+	historyRepo repository.ChangeHistoryRepository,
+	searchRepo repository.ProfileSearchRepository,
 	botToken string,
+	guildID string,
 	channelID string,
+	fullSyncInterval time.Duration,
+	mergeStrategy domain.ProfileMergeStrategy,
+	template *discord.ProfileTemplate,
+	studentIDs *studentid.Parser,
 ) *ProfileService {
+	if fullSyncInterval <= 0 {
+		fullSyncInterval = DefaultFullSyncInterval
+	}
+	if mergeStrategy == "" {
+		mergeStrategy = domain.ProfileMergeNewest
+	}
+	if template == nil {
+		template = discord.DefaultProfileTemplate()
+	}
+	if studentIDs == nil {
+		studentIDs = studentid.DefaultParser()
+	}
+
 	return &ProfileService{
-		profileRepo: profileRepo,
-		userRepo:    userRepo,
-		botToken:    botToken,
-		channelID:   channelID,
+		profileRepo:      profileRepo,
+		userRepo:         userRepo,
+		roleRepo:         roleRepo,
+		cursorRepo:       cursorRepo,
+		introRepo:        introRepo,
+		historyRepo:      historyRepo,
+		searchRepo:       searchRepo,
+		botToken:         botToken,
+		guildID:          guildID,
+		channelID:        channelID,
+		fullSyncInterval: fullSyncInterval,
+		mergeStrategy:    mergeStrategy,
+		template:         template,
+		studentIDs:       studentIDs,
+	}
+}
+
+// SyncAll はロスター同期（ギルドIDが設定されている場合）とプロフィール同期を順に実行します
+// ロスター同期に失敗してもプロフィール同期は実行します
+func (s *ProfileService) SyncAll(ctx context.Context) error {
+	var rosterErr error
+	if s.guildID != "" {
+		if err := s.SyncRoster(ctx); err != nil {
+			log.Printf("Error in roster sync: %v", err)
+			rosterErr = err
+		}
+	}
+
+	if err := s.SyncProfiles(ctx); err != nil {
+		return err
+	}
+
+	if rosterErr != nil {
+		return fmt.Errorf("roster sync failed: %w", rosterErr)
+	}
+	return nil
+}
+
+// SyncRoster はBotトークンでサーバーのロールと全メンバーを同期します
+// ログインや自己紹介の有無に関わらず、すべてのメンバーがusersテーブルに登録されます
+func (s *ProfileService) SyncRoster(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	log.Println("Starting roster synchronization...")
+
+	// 1. ロールを同期
+	roles, err := discord.GetGuildRoles(ctx, s.botToken, s.guildID)
+	if err != nil {
+		return fmt.Errorf("failed to get guild roles: %w", err)
+	}
+
+	stats := RosterSyncStats{}
+	roleIDs := make([]string, 0, len(roles))
+	for _, r := range roles {
+		// @everyoneロール（IDがギルドIDと同じ）はメンバーのロール一覧に含まれないため除外
+		if r.ID == s.guildID {
+			continue
+		}
+
+		role := &domain.Role{
+			ID:        r.ID,
+			Name:      r.Name,
+			Color:     r.Color,
+			Position:  r.Position,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := s.roleRepo.Upsert(ctx, role); err != nil {
+			return fmt.Errorf("failed to upsert role %s: %w", r.ID, err)
+		}
+		roleIDs = append(roleIDs, r.ID)
+	}
+	stats.RoleCount = len(roleIDs)
+
+	// Discord側で削除されたロールを掃除
+	if err := s.roleRepo.DeleteExcept(ctx, roleIDs); err != nil {
+		return fmt.Errorf("failed to delete stale roles: %w", err)
 	}
+
+	// 2. メンバーを同期
+	members, err := discord.GetAllGuildMembers(ctx, s.botToken, s.guildID)
+	if err != nil {
+		return fmt.Errorf("failed to get guild members: %w", err)
+	}
+
+	discordIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		if member.User == nil || member.User.Bot {
+			continue
+		}
+		stats.MemberCount++
+		discordIDs = append(discordIDs, member.User.ID)
+
+		created, updated, err := s.upsertGuildMember(ctx, member)
+		if err != nil {
+			log.Printf("Error syncing guild member %s: %v", member.User.ID, err)
+			stats.ErrorCount++
+			continue
+		}
+		if created {
+			stats.CreatedCount++
+		} else if updated {
+			stats.UpdatedCount++
+		}
+	}
+
+	// メンバー一覧にいないユーザーを脱退済みにする
+	// （一覧が空の場合は取得失敗の可能性があるため行わない）
+	if len(discordIDs) > 0 {
+		left, err := s.userRepo.MarkLeftExcept(ctx, discordIDs)
+		if err != nil {
+			return fmt.Errorf("failed to mark departed members: %w", err)
+		}
+		for _, user := range left {
+			recordChange(ctx, s.historyRepo, user.ID, domain.ChangeSubjectUser, domain.SyncActor(), []domain.FieldChange{
+				{Field: "left_at", New: user.LeftAt.UTC().Format(time.RFC3339)},
+			})
+		}
+		stats.LeftCount = len(left)
+	}
+
+	log.Printf("Roster synchronization completed: %d roles, %d members (%d created, %d updated, %d left, %d errors)",
+		stats.RoleCount, stats.MemberCount, stats.CreatedCount, stats.UpdatedCount, stats.LeftCount, stats.ErrorCount)
+
+	s.mu.Lock()
+	s.lastRosterStats = stats
+	s.mu.Unlock()
+
+	return nil
+}
+
+// upsertGuildMember はギルドメンバーをユーザーとして作成または更新します
+func (s *ProfileService) upsertGuildMember(ctx context.Context, member *discord.GuildMember) (created, updated bool, err error) {
+	user, err := s.userRepo.GetByDiscordID(ctx, member.User.ID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			return false, false, fmt.Errorf("failed to get user by discord_id: %w", err)
+		}
+		user = nil
+	}
+
+	now := time.Now()
+
+	if user == nil {
+		user = &domain.User{
+			ID:        uuid.New().String(),
+			DiscordID: member.User.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		applyDiscordUser(user, member.User)
+		applyGuildMember(user, member)
+
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return false, false, fmt.Errorf("failed to create user: %w", err)
+		}
+		recordUserChange(ctx, s.historyRepo, nil, user, domain.SyncActor())
+		return true, false, nil
+	}
+
+	before := user.Clone()
+	userChanged := applyDiscordUser(user, member.User)
+	memberChanged := applyGuildMember(user, member)
+	if !userChanged && !memberChanged {
+		return false, false, nil
+	}
+
+	user.UpdatedAt = now
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return false, false, fmt.Errorf("failed to update user: %w", err)
+	}
+	recordUserChange(ctx, s.historyRepo, before, user, domain.SyncActor())
+
+	return false, true, nil
 }
 
 // SyncProfiles はDiscord自己紹介チャンネルからプロフィールを同期します
+// 前回同期したメッセージ以降のみを取得する差分同期を行い、
+// カーソルがない場合や前回の全件照合からfullSyncInterval以上経過した場合は全件照合を行います
 func (s *ProfileService) SyncProfiles(ctx context.Context) error {
-	log.Println("Starting profile synchronization...")
+	cursor, err := s.cursorRepo.GetByChannelID(ctx, s.channelID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrSyncCursorNotFound) {
+			return fmt.Errorf("failed to get sync cursor: %w", err)
+		}
+		cursor = &domain.SyncCursor{ChannelID: s.channelID}
+	}
+
+	if cursor.NeedsFullSync(s.fullSyncInterval) {
+		return s.syncProfiles(ctx, cursor, true)
+	}
+	return s.syncProfiles(ctx, cursor, false)
+}
+
+// SyncProfilesFull はカーソルに関わらずチャンネル全体を再取得して全件照合を行います
+// 編集されたメッセージの反映と、削除されたメッセージに対応するプロフィールの墓標化を行います
+func (s *ProfileService) SyncProfilesFull(ctx context.Context) error {
+	cursor, err := s.cursorRepo.GetByChannelID(ctx, s.channelID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrSyncCursorNotFound) {
+			return fmt.Errorf("failed to get sync cursor: %w", err)
+		}
+		cursor = &domain.SyncCursor{ChannelID: s.channelID}
+	}
+
+	return s.syncProfiles(ctx, cursor, true)
+}
 
-	// チャンネルのすべてのメッセージを取得（ページネーション対応）
-	messages, err := discord.GetAllChannelMessages(ctx, s.botToken, s.channelID, 0)
+// syncProfiles はメッセージを取得してプロフィールに反映し、カーソルを進めます
+func (s *ProfileService) syncProfiles(ctx context.Context, cursor *domain.SyncCursor, full bool) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	var messages []*discord.Message
+	var err error
+	if full {
+		log.Println("Starting full profile synchronization...")
+		// チャンネルのすべてのメッセージを取得（ページネーション対応）
+		messages, err = discord.GetAllChannelMessages(ctx, s.botToken, s.channelID, 0)
+	} else {
+		log.Printf("Starting incremental profile synchronization (after message %s)...", cursor.LastMessageID)
+		messages, err = discord.GetChannelMessagesAfter(ctx, s.botToken, s.channelID, cursor.LastMessageID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get channel messages: %w", err)
 	}
 
 	log.Printf("Retrieved %d messages from channel", len(messages))
+	return s.applyMessages(ctx, cursor, messages, full)
+}
 
-	successCount := 0
-	skipCount := 0
-	errorCount := 0
+// applyMessages は取得したメッセージをプロフィールに反映し、カーソルを進めます
+// 反映に失敗したメッセージがある場合、次回の差分同期で再試行するため、カーソルは失敗したメッセージの直前までしか進めません
+func (s *ProfileService) applyMessages(ctx context.Context, cursor *domain.SyncCursor, messages []*discord.Message, full bool) error {
+	stats := SyncStats{
+		TotalMessages: len(messages),
+		FullSync:      full,
+	}
 
+	var firstFailedID string
 	for _, msg := range messages {
-		// メッセージからプロフィールをパース
-		profileData := discord.ParseProfile(msg.Content)
+		synced, err := s.syncMessage(ctx, msg)
+		if err != nil {
+			log.Printf("Error syncing profile for message %s: %v", msg.ID, err)
+			stats.ErrorCount++
+			if firstFailedID == "" || discord.CompareSnowflakes(msg.ID, firstFailedID) < 0 {
+				firstFailedID = msg.ID
+			}
+			continue
+		}
+		if synced {
+			stats.SuccessCount++
+		} else {
+			stats.SkipCount++
+		}
+	}
 
-		// 有効なプロフィールでない場合はスキップ
-		if !profileData.IsValidProfile() {
-			log.Printf("Skipping message %s: not a valid profile", msg.ID)
-			skipCount++
+	lastMessageID := cursor.LastMessageID
+	for _, msg := range messages {
+		if firstFailedID != "" && discord.CompareSnowflakes(msg.ID, firstFailedID) >= 0 {
 			continue
 		}
+		if discord.CompareSnowflakes(msg.ID, lastMessageID) > 0 {
+			lastMessageID = msg.ID
+		}
+	}
 
-		// Discord IDでユーザーを検索または作成
-		user, err := s.userRepo.GetByDiscordID(ctx, msg.Author.ID)
+	// 全件照合の場合、チャンネルから消えたメッセージを墓標化
+	if full {
+		deleted, err := s.tombstoneRemovedMessages(ctx, messages)
 		if err != nil {
-			// ユーザーが見つからない場合は新規作成
-			if errors.Is(err, domain.ErrUserNotFound) {
-				user = nil
-			} else {
-				log.Printf("Error getting user by discord_id %s: %v", msg.Author.ID, err)
-				errorCount++
-				continue
-			}
+			log.Printf("Error reconciling deleted profiles: %v", err)
+			stats.ErrorCount++
 		}
+		stats.DeletedCount = deleted
+	}
 
-		// ユーザーが存在しない場合は作成
-		if user == nil {
-			user = &domain.User{
-				ID:          uuid.New().String(),
-				DiscordID:   msg.Author.ID,
-				Username:    msg.Author.Username,
-				DisplayName: msg.Author.GetDisplayName(),
-				AvatarURL:   msg.Author.GetAvatarURL(),
-				GuildRoles:  []string{},
-				CreatedAt:   time.Now(),
-				UpdatedAt:   time.Now(),
-			}
+	// カーソルを更新
+	now := time.Now()
+	cursor.LastMessageID = lastMessageID
+	cursor.UpdatedAt = now
+	if full {
+		cursor.LastFullSyncAt = &now
+	}
+	if cursor.LastMessageID != "" {
+		if err := s.cursorRepo.Upsert(ctx, cursor); err != nil {
+			return fmt.Errorf("failed to update sync cursor: %w", err)
+		}
+	}
 
-			if err := s.userRepo.Create(ctx, user); err != nil {
-				log.Printf("Error creating user for discord_id %s: %v", msg.Author.ID, err)
-				errorCount++
-				continue
-			}
+	log.Printf("Profile synchronization completed: %d success, %d skipped, %d deleted, %d errors",
+		stats.SuccessCount, stats.SkipCount, stats.DeletedCount, stats.ErrorCount)
 
-			log.Printf("Created new user: %s (discord_id: %s)", user.Username, user.DiscordID)
+	// 統計情報を保存
+	s.mu.Lock()
+	s.lastSyncStats = stats
+	s.mu.Unlock()
+
+	return nil
+}
+
+// syncMessage は1件のメッセージをパースして自己紹介メッセージとして保存し、投稿者のプロフィールを再構築します
+// 有効なプロフィールでない場合はfalseを返します
+func (s *ProfileService) syncMessage(ctx context.Context, msg *discord.Message) (bool, error) {
+	// メッセージからプロフィールをパース
+	profileData := s.template.Parse(msg.Content)
+	if len(profileData.InvalidFields) > 0 {
+		log.Printf("Message %s: discarded invalid fields %v", msg.ID, profileData.InvalidFields)
+	}
+
+	// 有効なプロフィールでない場合はスキップ
+	// （以前は有効だったメッセージが編集で無効になった場合は削除扱い）
+	if !profileData.IsValidProfile() {
+		log.Printf("Skipping message %s: not a valid profile", msg.ID)
+		if _, err := s.deleteMessage(ctx, msg.ID); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	// Discord IDでユーザーを検索または作成
+	user, err := s.userRepo.GetByDiscordID(ctx, msg.Author.ID)
+	if err != nil {
+		// ユーザーが見つからない場合は新規作成
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			return false, fmt.Errorf("failed to get user by discord_id %s: %w", msg.Author.ID, err)
+		}
+		user = nil
+	}
+
+	// ユーザーが存在しない場合は作成
+	if user == nil {
+		user = &domain.User{
+			ID:          uuid.New().String(),
+			DiscordID:   msg.Author.ID,
+			Username:    msg.Author.Username,
+			DisplayName: msg.Author.GetDisplayName(),
+			AvatarURL:   msg.Author.GetAvatarURL(),
+			GuildRoles:  []string{},
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return false, fmt.Errorf("failed to create user for discord_id %s: %w", msg.Author.ID, err)
+		}
+		recordUserChange(ctx, s.historyRepo, nil, user, domain.SyncActor())
+
+		log.Printf("Created new user: %s (discord_id: %s)", user.Username, user.DiscordID)
+	} else if before := user.Clone(); applyDiscordUser(user, &msg.Author) {
+		// 既存ユーザーの場合、Discord情報を更新
+		// Username, DisplayName, AvatarURLはDiscordの最新情報に同期
+		user.UpdatedAt = time.Now()
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			// ユーザー更新エラーはログに記録するが、プロフィール同期は続行
+			log.Printf("Warning: Failed to update user info for discord_id %s: %v (proceeding with profile sync)", msg.Author.ID, err)
 		} else {
-			// 既存ユーザーの場合、Discord情報を更新
-			// Username, DisplayName, AvatarURLはDiscordの最新情報に同期
-			newUsername := msg.Author.Username
-			newDisplayName := msg.Author.GetDisplayName()
-			newAvatarURL := msg.Author.GetAvatarURL()
-			updated := false
-
-			if user.Username != newUsername {
-				user.Username = newUsername
-				updated = true
+			recordUserChange(ctx, s.historyRepo, before, user, domain.SyncActor())
+			log.Printf("Updated user info for %s (discord_id: %s)", user.Username, user.DiscordID)
+		}
+	}
+
+	now := time.Now()
+	intro := &domain.IntroMessage{
+		ID:        msg.ID,
+		ChannelID: s.channelID,
+		UserID:    user.ID,
+		RealName:  profileData.RealName,
+		StudentID: profileData.StudentID,
+		Hobbies:   profileData.Hobbies,
+		WhatToDo:  profileData.WhatToDo,
+		Comment:   profileData.Comment,
+		Extra:     profileData.Extra,
+		PostedAt:  msg.PostedAt(),
+		EditedAt:  msg.EditedAt(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if intro.PostedAt.IsZero() {
+		intro.PostedAt = now
+	}
+
+	// 既に同じ内容で保存済みならプロフィールの再構築は不要
+	existing, err := s.introRepo.GetByID(ctx, msg.ID)
+	if err != nil && !errors.Is(err, domain.ErrIntroMessageNotFound) {
+		return false, fmt.Errorf("failed to get intro message: %w", err)
+	}
+	if existing != nil && !existing.IsDeleted() && existing.UserID == intro.UserID && existing.SameContent(intro) {
+		return true, nil
+	}
+
+	if err := s.introRepo.Upsert(ctx, intro); err != nil {
+		return false, fmt.Errorf("failed to upsert intro message: %w", err)
+	}
+
+	if err := s.rebuildProfile(ctx, user.ID); err != nil {
+		return false, err
+	}
+
+	log.Printf("Synced profile for user %s (message: %s)", user.Username, msg.ID)
+	return true, nil
+}
+
+// deleteMessage は自己紹介メッセージを墓標化し、投稿者のプロフィールを再構築します
+// 対象のメッセージが保存されていない場合はfalseを返します
+func (s *ProfileService) deleteMessage(ctx context.Context, messageID string) (bool, error) {
+	intro, err := s.introRepo.GetByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrIntroMessageNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get intro message: %w", err)
+	}
+	if intro.IsDeleted() {
+		return false, nil
+	}
+
+	if err := s.introRepo.MarkDeleted(ctx, messageID); err != nil {
+		return false, fmt.Errorf("failed to mark intro message as deleted: %w", err)
+	}
+
+	if err := s.rebuildProfile(ctx, intro.UserID); err != nil {
+		return false, err
+	}
+
+	log.Printf("Tombstoned intro message %s", messageID)
+	return true, nil
+}
+
+// rebuildProfile はユーザーの有効な自己紹介メッセージから正規のプロフィールを作り直します
+// 有効なメッセージが1件もなければプロフィールを墓標化し、重複したプロフィールは削除します
+func (s *ProfileService) rebuildProfile(ctx context.Context, userID string) error {
+	intros, err := s.introRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get intro messages: %w", err)
+	}
+
+	profile, err := s.canonicalProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// 自己紹介から組み立てた値（手動編集された項目を反映する前）
+	introProfile := &domain.Profile{UserID: userID}
+	if len(intros) > 0 {
+		mergeIntroMessages(introProfile, intros, s.mergeStrategy)
+	}
+
+	if profile == nil {
+		if len(intros) == 0 {
+			return nil
+		}
+		now := time.Now()
+		profile = &domain.Profile{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		profile.ApplyIntro(introProfile)
+		s.applyStudentID(profile)
+		if err := s.profileRepo.Create(ctx, profile); err != nil {
+			return fmt.Errorf("failed to create profile: %w", err)
+		}
+		recordProfileChange(ctx, s.historyRepo, nil, profile, domain.SyncActor())
+		indexProfile(ctx, s.searchRepo, profile)
+		return nil
+	}
+
+	before := profile.Clone()
+	if err := s.saveProfile(ctx, profile, introProfile); err != nil {
+		return err
+	}
+	recordProfileChange(ctx, s.historyRepo, before, profile, domain.SyncActor())
+	indexProfile(ctx, s.searchRepo, profile)
+	return nil
+}
+
+// canonicalProfile はユーザーのプロフィールを1件に絞って返します（墓標化されたものを含む。なければnil）
+// 有効なプロフィールを優先して1件を残し、残りの重複は削除します
+func (s *ProfileService) canonicalProfile(ctx context.Context, userID string) (*domain.Profile, error) {
+	existing, err := s.profileRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	var profile *domain.Profile
+	for _, p := range existing {
+		if profile == nil || (profile.IsDeleted() && !p.IsDeleted()) {
+			profile = p
+		}
+	}
+	for _, p := range existing {
+		if p == profile {
+			continue
+		}
+		if err := s.profileRepo.Delete(ctx, p.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete duplicate profile %s: %w", p.ID, err)
+		}
+	}
+
+	return profile, nil
+}
+
+// saveProfile は自己紹介の値と手動編集の値をまとめてプロフィールを保存します
+// 値が1つも残らない場合はプロフィールを墓標化します
+func (s *ProfileService) saveProfile(ctx context.Context, profile, introProfile *domain.Profile) error {
+	profile.ApplyIntro(introProfile)
+	s.applyStudentID(profile)
+
+	if !introProfile.HasIntro() && !profile.HasContent() {
+		if !profile.IsDeleted() {
+			if err := s.profileRepo.Tombstone(ctx, profile.ID); err != nil {
+				return fmt.Errorf("failed to tombstone profile: %w", err)
 			}
-			if user.DisplayName != newDisplayName {
-				user.DisplayName = newDisplayName
-				updated = true
+			now := time.Now()
+			profile.DeletedAt = &now
+		}
+		return nil
+	}
+
+	profile.DeletedAt = nil
+	profile.UpdatedAt = time.Now()
+	if err := s.profileRepo.Update(ctx, profile); err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return nil
+}
+
+// mergeIntroMessages は投稿日時の昇順に並んだ自己紹介メッセージをプロフィールにまとめます
+// newestでは最新メッセージの内容をそのまま、mergeではフィールドごとに値のある最新の内容を採用します
+func mergeIntroMessages(profile *domain.Profile, intros []*domain.IntroMessage, strategy domain.ProfileMergeStrategy) {
+	newest := intros[len(intros)-1]
+	profile.DiscordMessageID = newest.ID
+	profile.EditedAt = newest.EditedAt
+	profile.RealName = newest.RealName
+	profile.StudentID = newest.StudentID
+	profile.Hobbies = newest.Hobbies
+	profile.WhatToDo = newest.WhatToDo
+	profile.Comment = newest.Comment
+	profile.Extra = maps.Clone(newest.Extra)
+
+	if strategy != domain.ProfileMergeFields {
+		return
+	}
+
+	for i := len(intros) - 2; i >= 0; i-- {
+		m := intros[i]
+		if profile.RealName == "" {
+			profile.RealName = m.RealName
+		}
+		if profile.StudentID == "" {
+			profile.StudentID = m.StudentID
+		}
+		if profile.Hobbies == "" {
+			profile.Hobbies = m.Hobbies
+		}
+		if profile.WhatToDo == "" {
+			profile.WhatToDo = m.WhatToDo
+		}
+		if profile.Comment == "" {
+			profile.Comment = m.Comment
+		}
+		for key, value := range m.Extra {
+			if _, ok := profile.Extra[key]; ok {
+				continue
 			}
-			if user.AvatarURL != newAvatarURL {
-				user.AvatarURL = newAvatarURL
-				updated = true
+			if profile.Extra == nil {
+				profile.Extra = make(map[string]string)
 			}
+			profile.Extra[key] = value
+		}
+	}
+}
 
-			if updated {
-				user.UpdatedAt = time.Now()
-				if err := s.userRepo.Update(ctx, user); err != nil {
-					// ユーザー更新エラーはログに記録するが、プロフィール同期は続行
-					log.Printf("Warning: Failed to update user info for discord_id %s: %v (proceeding with profile sync)", msg.Author.ID, err)
-					errorCount++
-					// continue は削除: プロフィール同期は続行する
-				} else {
-					log.Printf("Updated user info for %s (discord_id: %s)", user.Username, user.DiscordID)
-				}
-			}
+// applyStudentID は学籍番号を正規化し、入学年度と学部コードをプロフィールに反映します
+// いずれかのフィールドが変更された場合はtrueを返します
+func (s *ProfileService) applyStudentID(profile *domain.Profile) bool {
+	info := s.studentIDs.Parse(profile.StudentID)
+	if profile.StudentID == info.Normalized && profile.EnrollmentYear == info.EnrollmentYear && profile.Faculty == info.Faculty {
+		return false
+	}
+
+	profile.StudentID = info.Normalized
+	profile.EnrollmentYear = info.EnrollmentYear
+	profile.Faculty = info.Faculty
+	return true
+}
+
+// RebuildSearchIndex は保存済みの全プロフィールから全文検索の索引を作り直し、登録したプロフィールの数を返します
+// 墓標化されたプロフィールの索引が残っていても、検索時にプロフィールを取得できないため結果には含まれません
+func (s *ProfileService) RebuildSearchIndex(ctx context.Context) (int, error) {
+	profiles, err := s.profileRepo.GetAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get profiles: %w", err)
+	}
+
+	for i, profile := range profiles {
+		if err := s.searchRepo.Replace(ctx, profile.UserID, profilePostings(profile)); err != nil {
+			return i, fmt.Errorf("failed to index profile %s: %w", profile.ID, err)
+		}
+	}
+	return len(profiles), nil
+}
+
+// EnsureSearchIndex は全文検索の索引が空の場合（導入直後など）に索引を作成し、登録したプロフィールの数を返します
+func (s *ProfileService) EnsureSearchIndex(ctx context.Context) (int, error) {
+	count, err := s.searchRepo.CountUsers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count indexed users: %w", err)
+	}
+	if count > 0 {
+		return 0, nil
+	}
+	return s.RebuildSearchIndex(ctx)
+}
+
+// RefreshStudentIDs は保存済みの全プロフィールの学籍番号を現在の形式定義で解析し直します
+// 形式定義を変更した場合や、入学年度・学部コードの導入前に同期されたプロフィールに使用します
+func (s *ProfileService) RefreshStudentIDs(ctx context.Context) (int, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	profiles, err := s.profileRepo.GetAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get profiles: %w", err)
+	}
+
+	updated := 0
+	for _, profile := range profiles {
+		before := profile.Clone()
+		if !s.applyStudentID(profile) {
+			continue
 		}
+		profile.UpdatedAt = time.Now()
+		if err := s.profileRepo.Update(ctx, profile); err != nil {
+			return updated, fmt.Errorf("failed to update profile %s: %w", profile.ID, err)
+		}
+		recordProfileChange(ctx, s.historyRepo, before, profile, domain.SyncActor())
+		updated++
+	}
+
+	return updated, nil
+}
+
+// tombstoneRemovedMessages はチャンネルに存在しなくなった自己紹介メッセージを墓標化します
+// 自己紹介メッセージが記録される前に作成されたプロフィールも、元メッセージがなければ墓標化します
+func (s *ProfileService) tombstoneRemovedMessages(ctx context.Context, messages []*discord.Message) (int, error) {
+	// チャンネルが空で返ってきた場合は取得失敗の可能性があるため、全削除は行わない
+	if len(messages) == 0 {
+		return 0, nil
+	}
 
-		// プロフィールを作成または更新
-		profile := &domain.Profile{
-			ID:               uuid.New().String(),
-			UserID:           user.ID,
-			DiscordMessageID: msg.ID,
-			RealName:         profileData.RealName,
-			StudentID:        profileData.StudentID,
-			Hobbies:          profileData.Hobbies,
-			WhatToDo:         profileData.WhatToDo,
-			Comment:          profileData.Comment,
-			CreatedAt:        time.Now(),
-			UpdatedAt:        time.Now(),
+	messageIDs := make(map[string]struct{}, len(messages))
+	for _, msg := range messages {
+		messageIDs[msg.ID] = struct{}{}
+	}
+
+	intros, err := s.introRepo.GetActiveByChannelID(ctx, s.channelID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get intro messages: %w", err)
+	}
+
+	deleted := 0
+	activeUsers := make(map[string]struct{}, len(intros))
+	for _, m := range intros {
+		if _, ok := messageIDs[m.ID]; ok {
+			activeUsers[m.UserID] = struct{}{}
+			continue
 		}
+		if _, err := s.deleteMessage(ctx, m.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
 
-		if err := s.profileRepo.Upsert(ctx, profile); err != nil {
-			log.Printf("Error upserting profile for message %s: %v", msg.ID, err)
-			errorCount++
+	profiles, err := s.profileRepo.GetAll(ctx)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to get profiles: %w", err)
+	}
+
+	for _, p := range profiles {
+		if _, ok := activeUsers[p.UserID]; ok {
+			continue
+		}
+		if _, ok := messageIDs[p.DiscordMessageID]; ok {
 			continue
 		}
+		// 自己紹介の投稿を持たない手動編集のみのプロフィールは対象外
+		if !p.HasIntro() {
+			continue
+		}
+		// 手動編集の項目を持つプロフィールは、自己紹介の値だけを取り除いて残す
+		if len(p.Sources) > 0 {
+			if err := s.rebuildProfile(ctx, p.UserID); err != nil {
+				return deleted, err
+			}
+			continue
+		}
+		before := p.Clone()
+		if err := s.profileRepo.Tombstone(ctx, p.ID); err != nil {
+			return deleted, fmt.Errorf("failed to tombstone profile %s: %w", p.ID, err)
+		}
+		now := time.Now()
+		p.DeletedAt = &now
+		recordProfileChange(ctx, s.historyRepo, before, p, domain.SyncActor())
+		indexProfile(ctx, s.searchRepo, p)
+		log.Printf("Tombstoned profile %s (message %s no longer exists)", p.ID, p.DiscordMessageID)
+		deleted++
+	}
+
+	return deleted, nil
+}
 
-		log.Printf("Synced profile for user %s (message: %s)", user.Username, msg.ID)
-		successCount++
+// UpdateProfileManually はWebからの編集内容をプロフィールに反映します
+// 編集した項目は手動編集として記録し、以降の同期では自己紹介の値が変わらない限り上書きしません
+// 自己紹介の投稿がないユーザーは手動編集の項目だけでプロフィールを作成します
+// actorUserIDは編集したユーザーのIDで、本人以外の場合は幹部による変更として履歴に記録します
+func (s *ProfileService) UpdateProfileManually(ctx context.Context, userID string, edit domain.ProfileEdit, actorUserID string) (*domain.Profile, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	intros, err := s.introRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get intro messages: %w", err)
+	}
+	introProfile := &domain.Profile{UserID: userID}
+	if len(intros) > 0 {
+		mergeIntroMessages(introProfile, intros, s.mergeStrategy)
 	}
 
-	log.Printf("Profile synchronization completed: %d success, %d skipped, %d errors", successCount, skipCount, errorCount)
+	profile, err := s.canonicalProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	actor := domain.UserActor(actorUserID, userID)
+	var before *domain.Profile
+	create := profile == nil
+	if !create {
+		before = profile.Clone()
+	} else {
+		now := time.Now()
+		profile = &domain.Profile{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
 
-	// 統計情報を保存
-	s.mu.Lock()
-	s.lastSyncStats = SyncStats{
-		SuccessCount:  successCount,
-		SkipCount:     skipCount,
-		ErrorCount:    errorCount,
-		TotalMessages: len(messages),
+	for key := range edit.Fields {
+		if !profile.IsEditableField(key) {
+			return nil, fmt.Errorf("%w: %s", domain.ErrInvalidProfileField, key)
+		}
 	}
-	s.mu.Unlock()
 
-	return nil
+	if profile.Sources == nil {
+		profile.Sources = make(map[string]domain.FieldOrigin)
+	}
+	for _, key := range edit.Reset {
+		delete(profile.Sources, key)
+	}
+	for key, value := range edit.Fields {
+		value = strings.TrimSpace(value)
+		profile.Sources[key] = domain.FieldOrigin{
+			Source:     domain.FieldSourceManual,
+			IntroValue: introProfile.FieldValue(key),
+		}
+		profile.SetFieldValue(key, value)
+	}
+
+	if create {
+		profile.ApplyIntro(introProfile)
+		s.applyStudentID(profile)
+		if !profile.HasContent() {
+			return nil, domain.ErrProfileNotFound
+		}
+		if err := s.profileRepo.Create(ctx, profile); err != nil {
+			return nil, fmt.Errorf("failed to create profile: %w", err)
+		}
+		recordProfileChange(ctx, s.historyRepo, nil, profile, actor)
+		indexProfile(ctx, s.searchRepo, profile)
+		return profile, nil
+	}
+
+	if err := s.saveProfile(ctx, profile, introProfile); err != nil {
+		return nil, err
+	}
+	recordProfileChange(ctx, s.historyRepo, before, profile, actor)
+	indexProfile(ctx, s.searchRepo, profile)
+	if profile.IsDeleted() {
+		return nil, domain.ErrProfileNotFound
+	}
+	return profile, nil
+}
+
+// FieldLabels は自己紹介テンプレートで定義された項目のキーと見出しを定義順に返します
+func (s *ProfileService) FieldLabels() []discord.FieldDefinition {
+	return s.template.Fields
 }
 
 // GetProfileByUserID はユーザーIDでプロフィールを取得します
@@ -196,3 +914,10 @@ func (s *ProfileService) GetLastSyncStats() SyncStats {
 	defer s.mu.RUnlock()
 	return s.lastSyncStats
 }
+
+// GetLastRosterStats は最後のロスター同期の統計情報を取得します
+func (s *ProfileService) GetLastRosterStats() RosterSyncStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastRosterStats
+}
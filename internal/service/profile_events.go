@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/discord"
+)
+
+// GatewayIntents はGatewayモードで必要なIntentを返します
+// ロスター同期（ギルドIDの設定）がある場合はServer Members Intentも要求します
+func (s *ProfileService) GatewayIntents() int {
+	intents := discord.IntentGuildMessages | discord.IntentMessageContent
+	if s.guildID != "" {
+		intents |= discord.IntentGuilds | discord.IntentGuildMembers
+	}
+	return intents
+}
+
+// GatewayHandlers はDiscord Gatewayのイベントをプロフィールとロスターに反映するハンドラーを返します
+func (s *ProfileService) GatewayHandlers() *discord.GatewayHandlers {
+	handlers := &discord.GatewayHandlers{
+		MessageCreate: func(ctx context.Context, msg *discord.Message) {
+			if err := s.HandleMessage(ctx, msg); err != nil {
+				log.Printf("Error handling MESSAGE_CREATE %s: %v", msg.ID, err)
+			}
+		},
+		MessageUpdate: func(ctx context.Context, msg *discord.Message) {
+			if err := s.HandleMessage(ctx, msg); err != nil {
+				log.Printf("Error handling MESSAGE_UPDATE %s: %v", msg.ID, err)
+			}
+		},
+		MessageDelete: func(ctx context.Context, event *discord.MessageDeleteEvent) {
+			if err := s.HandleMessageDelete(ctx, event.ChannelID, event.ID); err != nil {
+				log.Printf("Error handling MESSAGE_DELETE %s: %v", event.ID, err)
+			}
+		},
+	}
+
+	if s.guildID != "" {
+		memberUpsert := func(ctx context.Context, event *discord.GuildMemberEvent) {
+			if err := s.HandleGuildMemberUpsert(ctx, event.GuildID, &event.GuildMember); err != nil {
+				log.Printf("Error handling guild member event: %v", err)
+			}
+		}
+		handlers.GuildMemberAdd = memberUpsert
+		handlers.GuildMemberUpdate = memberUpsert
+		handlers.GuildMemberRemove = func(ctx context.Context, event *discord.GuildMemberRemoveEvent) {
+			if err := s.HandleGuildMemberRemove(ctx, event.GuildID, &event.User); err != nil {
+				log.Printf("Error handling GUILD_MEMBER_REMOVE %s: %v", event.User.ID, err)
+			}
+		}
+	}
+
+	return handlers
+}
+
+// HandleMessage は自己紹介チャンネルへの投稿・編集をプロフィールに反映します
+// 同期カーソルはポーリングでの取りこぼし検出に使うため、ここでは進めません
+func (s *ProfileService) HandleMessage(ctx context.Context, msg *discord.Message) error {
+	if msg.ChannelID != s.channelID || msg.Author.Bot {
+		return nil
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if _, err := s.syncMessage(ctx, msg); err != nil {
+		return err
+	}
+	return nil
+}
+
+// HandleMessageDelete は自己紹介チャンネルのメッセージ削除をプロフィールに反映します
+func (s *ProfileService) HandleMessageDelete(ctx context.Context, channelID, messageID string) error {
+	if channelID != s.channelID {
+		return nil
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if _, err := s.deleteMessage(ctx, messageID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// HandleGuildMemberUpsert はメンバーの参加・更新をユーザーに反映します
+func (s *ProfileService) HandleGuildMemberUpsert(ctx context.Context, guildID string, member *discord.GuildMember) error {
+	if guildID != s.guildID || member.User == nil || member.User.Bot {
+		return nil
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if _, _, err := s.upsertGuildMember(ctx, member); err != nil {
+		return err
+	}
+	return nil
+}
+
+// HandleGuildMemberRemove はメンバーの脱退（キック・BANを含む）をユーザーに反映します
+func (s *ProfileService) HandleGuildMemberRemove(ctx context.Context, guildID string, discordUser *discord.User) error {
+	if guildID != s.guildID {
+		return nil
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	user, err := s.userRepo.GetByDiscordID(ctx, discordUser.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user by discord_id: %w", err)
+	}
+	if user == nil || !user.IsGuildMember() {
+		return nil
+	}
+
+	before := user.Clone()
+	now := time.Now()
+	user.LeftAt = &now
+	user.UpdatedAt = now
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to mark user as left: %w", err)
+	}
+	recordUserChange(ctx, s.historyRepo, before, user, domain.SyncActor())
+
+	log.Printf("Marked user %s as left (discord_id: %s)", user.Username, user.DiscordID)
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/discord"
 )
 
 // モックProfileRepository
@@ -44,7 +45,7 @@ func (m *mockProfileRepository) GetByID(ctx context.Context, id string) (*domain
 
 func (m *mockProfileRepository) GetByUserID(ctx context.Context, userID string) (*domain.Profile, error) {
 	profile, ok := m.profilesByUser[userID]
-	if !ok {
+	if !ok || profile.IsDeleted() {
 		return nil, domain.ErrProfileNotFound
 	}
 	return profile, nil
@@ -62,7 +63,9 @@ func (m *mockProfileRepository) GetByMessageID(ctx context.Context, messageID st
 func (m *mockProfileRepository) GetAll(ctx context.Context) ([]*domain.Profile, error) {
 	var profiles []*domain.Profile
 	for _, p := range m.profiles {
-		profiles = append(profiles, p)
+		if !p.IsDeleted() {
+			profiles = append(profiles, p)
+		}
 	}
 	return profiles, nil
 }
@@ -82,6 +85,26 @@ func (m *mockProfileRepository) Upsert(ctx context.Context, profile *domain.Prof
 	return nil
 }
 
+func (m *mockProfileRepository) ListByUserID(ctx context.Context, userID string) ([]*domain.Profile, error) {
+	var profiles []*domain.Profile
+	for _, p := range m.profiles {
+		if p.UserID == userID {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles, nil
+}
+
+func (m *mockProfileRepository) Tombstone(ctx context.Context, id string) error {
+	profile, ok := m.profiles[id]
+	if !ok {
+		return errors.New("profile not found")
+	}
+	now := time.Now()
+	profile.DeletedAt = &now
+	return nil
+}
+
 func (m *mockProfileRepository) Delete(ctx context.Context, id string) error {
 	delete(m.profiles, id)
 	return nil
@@ -163,11 +186,38 @@ func (m *mockUserRepository) GetAll(ctx context.Context, limit, offset int) ([]*
 	return users, nil
 }
 
+func (m *mockUserRepository) FindMembers(ctx context.Context, filter domain.MemberFilter, page domain.MemberPage) ([]*domain.User, *domain.MemberCursor, error) {
+	// プロフィールとの結合はモックでは再現しないため、条件は無視する
+	users, err := m.GetAll(ctx, page.Limit, page.Offset)
+	return users, nil, err
+}
+
+func (m *mockUserRepository) CountMembers(ctx context.Context, filter domain.MemberFilter) (int64, error) {
+	users, err := m.GetAll(ctx, 0, 0)
+	return int64(len(users)), err
+}
+
+func (m *mockUserRepository) MarkLeftExcept(ctx context.Context, discordIDs []string) ([]*domain.User, error) {
+	keep := make(map[string]bool, len(discordIDs))
+	for _, id := range discordIDs {
+		keep[id] = true
+	}
+	var marked []*domain.User
+	for _, u := range m.users {
+		if !keep[u.DiscordID] && u.LeftAt == nil {
+			now := time.Now()
+			u.LeftAt = &now
+			marked = append(marked, u)
+		}
+	}
+	return marked, nil
+}
+
 func TestProfileService_GetProfileByUserID(t *testing.T) {
 	profileRepo := newMockProfileRepository()
 	userRepo := newMockUserRepository()
 
-	service := NewProfileService(profileRepo, userRepo, "test-token", "test-channel")
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
 
 	userID := uuid.New().String()
 	expectedProfile := &domain.Profile{
@@ -205,7 +255,7 @@ func TestProfileService_GetProfileByUserID_NotFound(t *testing.T) {
 	profileRepo := newMockProfileRepository()
 	userRepo := newMockUserRepository()
 
-	service := NewProfileService(profileRepo, userRepo, "test-token", "test-channel")
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
 
 	ctx := context.Background()
 	profile, err := service.GetProfileByUserID(ctx, "non-existent-user-id")
@@ -227,7 +277,7 @@ func TestProfileService_GetAllProfiles(t *testing.T) {
 	profileRepo := newMockProfileRepository()
 	userRepo := newMockUserRepository()
 
-	service := NewProfileService(profileRepo, userRepo, "test-token", "test-channel")
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
 
 	// 複数のプロフィールを追加
 	for i := 0; i < 3; i++ {
@@ -258,7 +308,7 @@ func TestProfileService_GetLastSyncStats(t *testing.T) {
 	profileRepo := newMockProfileRepository()
 	userRepo := newMockUserRepository()
 
-	service := NewProfileService(profileRepo, userRepo, "test-token", "test-channel")
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
 
 	// 初期状態のstatsを確認
 	stats := service.GetLastSyncStats()
@@ -285,7 +335,7 @@ func TestProfileService_SyncStats_ThreadSafe(t *testing.T) {
 	profileRepo := newMockProfileRepository()
 	userRepo := newMockUserRepository()
 
-	service := NewProfileService(profileRepo, userRepo, "test-token", "test-channel")
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
 
 	// 複数のゴルーチンから同時にstatsにアクセス
 	done := make(chan bool, 10)
@@ -305,3 +355,579 @@ func TestProfileService_SyncStats_ThreadSafe(t *testing.T) {
 	// レースコンディションがなければテスト成功
 	// go test -race で実行してレースコンディションを検出
 }
+
+func TestProfileService_UpsertGuildMember(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	nick := "じょぎ太郎"
+	member := &discord.GuildMember{
+		User:     &discord.User{ID: "discord-1", Username: "jyogi_taro"},
+		Nick:     &nick,
+		Roles:    []string{"role-1", "role-2"},
+		JoinedAt: "2024-04-01T12:00:00+09:00",
+	}
+
+	// 未登録ユーザーは作成される
+	created, updated, err := service.upsertGuildMember(ctx, member)
+	if err != nil {
+		t.Fatalf("upsertGuildMember failed: %v", err)
+	}
+	if !created || updated {
+		t.Errorf("Expected created=true updated=false, got created=%v updated=%v", created, updated)
+	}
+
+	user := userRepo.usersByDiscordID["discord-1"]
+	if user == nil {
+		t.Fatal("Expected user to be created")
+	}
+	if user.GuildNickname == nil || *user.GuildNickname != nick {
+		t.Errorf("GuildNickname mismatch: got %v", user.GuildNickname)
+	}
+	if len(user.GuildRoles) != 2 {
+		t.Errorf("Expected 2 guild roles, got %v", user.GuildRoles)
+	}
+	if user.JoinedAt == nil {
+		t.Error("Expected JoinedAt to be set")
+	}
+
+	// 変更がなければ更新しない
+	created, updated, err = service.upsertGuildMember(ctx, member)
+	if err != nil {
+		t.Fatalf("upsertGuildMember failed: %v", err)
+	}
+	if created || updated {
+		t.Errorf("Expected no changes, got created=%v updated=%v", created, updated)
+	}
+
+	// ロールが変われば更新される
+	member.Roles = []string{"role-1"}
+	_, updated, err = service.upsertGuildMember(ctx, member)
+	if err != nil {
+		t.Fatalf("upsertGuildMember failed: %v", err)
+	}
+	if !updated {
+		t.Error("Expected user to be updated after role change")
+	}
+	if len(userRepo.usersByDiscordID["discord-1"].GuildRoles) != 1 {
+		t.Errorf("Expected 1 guild role, got %v", userRepo.usersByDiscordID["discord-1"].GuildRoles)
+	}
+}
+
+// newIntroTestMessage は自己紹介メッセージのテストデータを作成します
+func newIntroTestMessage(id, authorID, content string, postedAt time.Time) *discord.Message {
+	return &discord.Message{
+		ID:        id,
+		Author:    discord.User{ID: authorID, Username: "user-" + authorID},
+		Content:   content,
+		Timestamp: postedAt.Format(time.RFC3339),
+	}
+}
+
+func TestProfileService_ApplyMessages_CursorStopsBeforeFailure(t *testing.T) {
+	introRepo := newMockIntroMessageRepository()
+	cursorRepo := newMockSyncCursorRepository()
+	service := NewProfileService(newMockProfileRepository(), newMockUserRepository(), newMockRoleRepository(), cursorRepo, introRepo, newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	messages := []*discord.Message{
+		newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎", base),
+		newIntroTestMessage("200", "discord-2", "本名: じょぎ花子", base.Add(time.Minute)),
+		newIntroTestMessage("300", "discord-3", "本名: じょぎ次郎", base.Add(2*time.Minute)),
+	}
+
+	// 200の保存が1回だけ失敗した場合、カーソルは100までしか進めない
+	introRepo.upsertErrors = map[string]error{"200": errors.New("database is locked")}
+	cursor := &domain.SyncCursor{ChannelID: "test-channel"}
+	if err := service.applyMessages(ctx, cursor, messages, false); err != nil {
+		t.Fatalf("applyMessages failed: %v", err)
+	}
+	if got := cursorRepo.cursors["test-channel"].LastMessageID; got != "100" {
+		t.Fatalf("Expected cursor to stop at 100, got %s", got)
+	}
+	if stats := service.GetLastSyncStats(); stats.ErrorCount != 1 || stats.SuccessCount != 2 {
+		t.Errorf("Expected 2 successes and 1 error, got %+v", stats)
+	}
+
+	// 次回の差分同期で失敗したメッセージを再試行し、カーソルを最後まで進める
+	if err := service.applyMessages(ctx, cursorRepo.cursors["test-channel"], messages[1:], false); err != nil {
+		t.Fatalf("applyMessages failed: %v", err)
+	}
+	if got := cursorRepo.cursors["test-channel"].LastMessageID; got != "300" {
+		t.Errorf("Expected cursor to advance to 300, got %s", got)
+	}
+	if _, ok := introRepo.messages["200"]; !ok {
+		t.Error("Expected message 200 to be synced on retry")
+	}
+}
+
+func TestProfileService_SyncMessage_DuplicatesNewestWins(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎\n趣味: 読書", base)); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("200", "discord-1", "本名: じょぎ太郎\nひとこと: よろしく", base.Add(time.Minute))); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+
+	user := userRepo.usersByDiscordID["discord-1"]
+	profiles, _ := profileRepo.ListByUserID(ctx, user.ID)
+	if len(profiles) != 1 {
+		t.Fatalf("Expected 1 canonical profile, got %d", len(profiles))
+	}
+
+	profile := profiles[0]
+	if profile.DiscordMessageID != "200" {
+		t.Errorf("Expected newest message 200 to be canonical, got %s", profile.DiscordMessageID)
+	}
+	if profile.Hobbies != "" {
+		t.Errorf("Expected hobbies from older message to be dropped, got %s", profile.Hobbies)
+	}
+	if profile.Comment != "よろしく" {
+		t.Errorf("Expected comment from newest message, got %s", profile.Comment)
+	}
+}
+
+func TestProfileService_SyncMessage_FieldMerge(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeFields, nil, nil)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	service.syncMessage(ctx, newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎\n趣味: 読書", base))
+	service.syncMessage(ctx, newIntroTestMessage("200", "discord-1", "本名: じょぎ次郎\nひとこと: よろしく", base.Add(time.Minute)))
+
+	user := userRepo.usersByDiscordID["discord-1"]
+	profile, err := profileRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID failed: %v", err)
+	}
+
+	if profile.RealName != "じょぎ次郎" {
+		t.Errorf("Expected newest real name, got %s", profile.RealName)
+	}
+	if profile.Hobbies != "読書" {
+		t.Errorf("Expected hobbies merged from older message, got %s", profile.Hobbies)
+	}
+	if profile.Comment != "よろしく" {
+		t.Errorf("Expected comment from newest message, got %s", profile.Comment)
+	}
+}
+
+func TestProfileService_SyncMessage_Edited(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	msg := newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎", time.Now().Add(-time.Hour))
+	service.syncMessage(ctx, msg)
+
+	editedAt := time.Now().Format(time.RFC3339)
+	msg.Content = "本名: じょぎ花子"
+	msg.EditedTimestamp = &editedAt
+	if _, err := service.syncMessage(ctx, msg); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+
+	user := userRepo.usersByDiscordID["discord-1"]
+	profile, _ := profileRepo.GetByUserID(ctx, user.ID)
+	if profile.RealName != "じょぎ花子" {
+		t.Errorf("Expected edited real name, got %s", profile.RealName)
+	}
+	if profile.EditedAt == nil {
+		t.Error("Expected EditedAt to be recorded")
+	}
+}
+
+func TestProfileService_TombstoneRemovedMessages(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	kept := newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎", base)
+	removed := newIntroTestMessage("200", "discord-2", "本名: じょぎ次郎", base)
+	service.syncMessage(ctx, kept)
+	service.syncMessage(ctx, removed)
+
+	// 自己紹介メッセージ導入前に作成されたプロフィール
+	legacy := &domain.Profile{ID: "legacy", UserID: "user-legacy", DiscordMessageID: "50"}
+	profileRepo.profiles[legacy.ID] = legacy
+
+	deleted, err := service.tombstoneRemovedMessages(ctx, []*discord.Message{kept})
+	if err != nil {
+		t.Fatalf("tombstoneRemovedMessages failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 tombstoned, got %d", deleted)
+	}
+
+	keptUser := userRepo.usersByDiscordID["discord-1"]
+	if p, _ := profileRepo.ListByUserID(ctx, keptUser.ID); len(p) != 1 || p[0].IsDeleted() {
+		t.Error("Expected profile for existing message to be kept")
+	}
+	removedUser := userRepo.usersByDiscordID["discord-2"]
+	if p, _ := profileRepo.ListByUserID(ctx, removedUser.ID); len(p) != 1 || !p[0].IsDeleted() {
+		t.Error("Expected profile for deleted message to be tombstoned")
+	}
+	if !legacy.IsDeleted() {
+		t.Error("Expected legacy profile to be tombstoned")
+	}
+
+	// 空のメッセージ一覧では何も墓標化しない
+	deleted, err = service.tombstoneRemovedMessages(ctx, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("Expected 0 tombstoned for empty channel, got %d", deleted)
+	}
+}
+
+func TestSyncCursor_NeedsFullSync(t *testing.T) {
+	recent := time.Now().Add(-1 * time.Hour)
+	old := time.Now().Add(-48 * time.Hour)
+
+	tests := []struct {
+		name   string
+		cursor domain.SyncCursor
+		want   bool
+	}{
+		{"no cursor", domain.SyncCursor{ChannelID: "c"}, true},
+		{"never fully synced", domain.SyncCursor{ChannelID: "c", LastMessageID: "100"}, true},
+		{"recently fully synced", domain.SyncCursor{ChannelID: "c", LastMessageID: "100", LastFullSyncAt: &recent}, false},
+		{"full sync is stale", domain.SyncCursor{ChannelID: "c", LastMessageID: "100", LastFullSyncAt: &old}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cursor.NeedsFullSync(DefaultFullSyncInterval); got != tt.want {
+				t.Errorf("NeedsFullSync() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileService_HandleMessage_IgnoresOtherChannels(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	msg := newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎", time.Now())
+	msg.ChannelID = "other-channel"
+	if err := service.HandleMessage(ctx, msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	if len(profileRepo.profiles) != 0 {
+		t.Errorf("Expected no profiles for other channel, got %d", len(profileRepo.profiles))
+	}
+
+	msg.ChannelID = "test-channel"
+	if err := service.HandleMessage(ctx, msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	if len(profileRepo.profiles) != 1 {
+		t.Errorf("Expected 1 profile, got %d", len(profileRepo.profiles))
+	}
+
+	if err := service.HandleMessageDelete(ctx, "test-channel", "100"); err != nil {
+		t.Fatalf("HandleMessageDelete failed: %v", err)
+	}
+	user := userRepo.usersByDiscordID["discord-1"]
+	if _, err := profileRepo.GetByUserID(ctx, user.ID); !errors.Is(err, domain.ErrProfileNotFound) {
+		t.Errorf("Expected profile to be tombstoned after delete, got %v", err)
+	}
+}
+
+func TestProfileService_HandleGuildMemberRemove(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	member := &discord.GuildMember{User: &discord.User{ID: "discord-1", Username: "jyogi_taro"}}
+	if err := service.HandleGuildMemberUpsert(ctx, "test-guild", member); err != nil {
+		t.Fatalf("HandleGuildMemberUpsert failed: %v", err)
+	}
+
+	if err := service.HandleGuildMemberRemove(ctx, "test-guild", member.User); err != nil {
+		t.Fatalf("HandleGuildMemberRemove failed: %v", err)
+	}
+	if userRepo.usersByDiscordID["discord-1"].IsGuildMember() {
+		t.Error("Expected user to be marked as left")
+	}
+
+	// 再参加すると在籍中に戻る
+	if err := service.HandleGuildMemberUpsert(ctx, "test-guild", member); err != nil {
+		t.Fatalf("HandleGuildMemberUpsert failed: %v", err)
+	}
+	if !userRepo.usersByDiscordID["discord-1"].IsGuildMember() {
+		t.Error("Expected user to be a member again after rejoining")
+	}
+}
+
+func TestMergeIntroMessages_Extra(t *testing.T) {
+	intros := []*domain.IntroMessage{
+		{ID: "100", Extra: map[string]string{"hometown": "福岡", "language": "Python"}},
+		{ID: "200", Extra: map[string]string{"language": "Go"}},
+	}
+
+	newest := &domain.Profile{}
+	mergeIntroMessages(newest, intros, domain.ProfileMergeNewest)
+	if len(newest.Extra) != 1 || newest.Extra["language"] != "Go" {
+		t.Errorf("newest: Extra = %v, want only language=Go", newest.Extra)
+	}
+
+	merged := &domain.Profile{}
+	mergeIntroMessages(merged, intros, domain.ProfileMergeFields)
+	if merged.Extra["language"] != "Go" || merged.Extra["hometown"] != "福岡" {
+		t.Errorf("merge: Extra = %v, want language=Go and hometown=福岡", merged.Extra)
+	}
+
+	// 元のメッセージのExtraは変更されない
+	if len(intros[1].Extra) != 1 {
+		t.Errorf("source Extra was modified: %v", intros[1].Extra)
+	}
+}
+
+func TestProfileService_SyncMessage_StudentID(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎\n学籍番号: ２３ｘ０１２３", time.Now())); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+
+	user := userRepo.usersByDiscordID["discord-1"]
+	profile, err := profileRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID failed: %v", err)
+	}
+	if profile.StudentID != "23X0123" {
+		t.Errorf("Expected normalized student ID 23X0123, got %s", profile.StudentID)
+	}
+	if profile.EnrollmentYear != 2023 || profile.Faculty != "X" {
+		t.Errorf("Expected enrollment year 2023 and faculty X, got %d and %s", profile.EnrollmentYear, profile.Faculty)
+	}
+
+	// 導入前に保存されたプロフィールも解析し直される
+	legacy := &domain.Profile{ID: "legacy", UserID: "user-legacy", DiscordMessageID: "200", StudentID: "２４ｙ９９９９"}
+	profileRepo.profiles[legacy.ID] = legacy
+	profileRepo.profilesByUser[legacy.UserID] = legacy
+
+	updated, err := service.RefreshStudentIDs(ctx)
+	if err != nil {
+		t.Fatalf("RefreshStudentIDs failed: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("Expected 1 refreshed profile, got %d", updated)
+	}
+	if legacy.StudentID != "24Y9999" || legacy.EnrollmentYear != 2024 || legacy.Faculty != "Y" {
+		t.Errorf("Unexpected refreshed profile: %+v", legacy)
+	}
+}
+
+func TestProfileService_UpdateProfileManually(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎\n趣味: 読書\nひとこと: よろしく", base)); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+	user := userRepo.usersByDiscordID["discord-1"]
+
+	profile, err := service.UpdateProfileManually(ctx, user.ID, domain.ProfileEdit{
+		Fields: map[string]string{"hobbies": "プログラミング", "favorite_language": "Go"},
+	}, user.ID)
+	if err != nil {
+		t.Fatalf("UpdateProfileManually failed: %v", err)
+	}
+	if profile.Hobbies != "プログラミング" || profile.Extra["favorite_language"] != "Go" {
+		t.Errorf("Expected manual values to be applied, got %+v", profile)
+	}
+	if profile.SourceOf("hobbies") != domain.FieldSourceManual || profile.SourceOf("real_name") != domain.FieldSourceDiscordIntro {
+		t.Errorf("Unexpected sources: %+v", profile.Sources)
+	}
+
+	// 自己紹介の内容が変わらなければ手動編集の値は残る
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("101", "discord-1", "本名: じょぎ太郎\n趣味: 読書\nひとこと: よろしくお願いします", base.Add(time.Minute))); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+	profile, _ = profileRepo.GetByUserID(ctx, user.ID)
+	if profile.Hobbies != "プログラミング" || profile.Extra["favorite_language"] != "Go" {
+		t.Errorf("Expected manual values to survive sync, got %+v", profile)
+	}
+	if profile.Comment != "よろしくお願いします" {
+		t.Errorf("Expected intro value for unedited field, got %s", profile.Comment)
+	}
+
+	// 自己紹介でその項目が書き換えられた場合は自己紹介の値で上書きする
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("102", "discord-1", "本名: じょぎ太郎\n趣味: 映画鑑賞", base.Add(2*time.Minute))); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+	profile, _ = profileRepo.GetByUserID(ctx, user.ID)
+	if profile.Hobbies != "映画鑑賞" || profile.SourceOf("hobbies") != domain.FieldSourceDiscordIntro {
+		t.Errorf("Expected changed intro to override manual value, got %s (%s)", profile.Hobbies, profile.SourceOf("hobbies"))
+	}
+	if profile.Extra["favorite_language"] != "Go" {
+		t.Errorf("Expected manual extra field to be kept, got %+v", profile.Extra)
+	}
+
+	// 手動編集をやめると自己紹介の値に戻る
+	profile, err = service.UpdateProfileManually(ctx, user.ID, domain.ProfileEdit{Reset: []string{"favorite_language"}}, user.ID)
+	if err != nil {
+		t.Fatalf("UpdateProfileManually failed: %v", err)
+	}
+	if _, ok := profile.Extra["favorite_language"]; ok {
+		t.Errorf("Expected reset field to be removed, got %+v", profile.Extra)
+	}
+
+	// 不正な項目名は拒否する
+	if _, err := service.UpdateProfileManually(ctx, user.ID, domain.ProfileEdit{Fields: map[string]string{"Bad Key": "x"}}, user.ID); !errors.Is(err, domain.ErrInvalidProfileField) {
+		t.Errorf("Expected ErrInvalidProfileField, got %v", err)
+	}
+}
+
+func TestProfileService_UpdateProfileManually_WithoutIntro(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	introRepo := newMockIntroMessageRepository()
+	service := NewProfileService(profileRepo, newMockUserRepository(), newMockRoleRepository(), newMockSyncCursorRepository(), introRepo, newMockChangeHistoryRepository(), newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	profile, err := service.UpdateProfileManually(ctx, "user-1", domain.ProfileEdit{
+		Fields: map[string]string{"real_name": "じょぎ花子", "student_id": "２３ｘ０１２３"},
+	}, "user-1")
+	if err != nil {
+		t.Fatalf("UpdateProfileManually failed: %v", err)
+	}
+	if profile.HasIntro() || profile.DiscordMessageID != domain.ManualProfileMessageID("user-1") {
+		t.Errorf("Expected manual-only profile, got message ID %s", profile.DiscordMessageID)
+	}
+	if profile.StudentID != "23X0123" || profile.EnrollmentYear != 2023 {
+		t.Errorf("Expected normalized student ID, got %s (%d)", profile.StudentID, profile.EnrollmentYear)
+	}
+
+	// 自己紹介チャンネルの一覧にないメッセージの削除処理でも手動のみのプロフィールは残る
+	introRepo.Upsert(ctx, &domain.IntroMessage{ID: "100", ChannelID: "test-channel", UserID: "user-2", RealName: "じょぎ次郎"})
+	if _, err := service.tombstoneRemovedMessages(ctx, []*discord.Message{{ID: "100"}}); err != nil {
+		t.Fatalf("tombstoneRemovedMessages failed: %v", err)
+	}
+	if p, err := profileRepo.GetByUserID(ctx, "user-1"); err != nil || p.RealName != "じょぎ花子" {
+		t.Errorf("Expected manual-only profile to be kept, got %+v (%v)", p, err)
+	}
+
+	// すべての項目を空にするとプロフィールは非表示になる
+	if _, err := service.UpdateProfileManually(ctx, "user-1", domain.ProfileEdit{
+		Fields: map[string]string{"real_name": "", "student_id": ""},
+	}, "user-1"); !errors.Is(err, domain.ErrProfileNotFound) {
+		t.Errorf("Expected ErrProfileNotFound for empty profile, got %v", err)
+	}
+	if _, err := profileRepo.GetByUserID(ctx, "user-1"); !errors.Is(err, domain.ErrProfileNotFound) {
+		t.Errorf("Expected empty profile to be tombstoned, got %v", err)
+	}
+}
+
+func TestProfileService_ChangeHistory(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	historyRepo := newMockChangeHistoryRepository()
+	service := NewProfileService(profileRepo, userRepo, newMockRoleRepository(), newMockSyncCursorRepository(), newMockIntroMessageRepository(), historyRepo, newMockProfileSearchRepository(), "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎\n学籍番号: 23X0123", base)); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+	user := userRepo.usersByDiscordID["discord-1"]
+
+	// 同じ内容の再同期では履歴は増えない
+	if _, err := service.syncMessage(ctx, newIntroTestMessage("100", "discord-1", "本名: じょぎ太郎\n学籍番号: 23X0123", base)); err != nil {
+		t.Fatalf("syncMessage failed: %v", err)
+	}
+
+	if _, err := service.UpdateProfileManually(ctx, user.ID, domain.ProfileEdit{Fields: map[string]string{"real_name": "じょぎ花子"}}, user.ID); err != nil {
+		t.Fatalf("UpdateProfileManually failed: %v", err)
+	}
+	if _, err := service.UpdateProfileManually(ctx, user.ID, domain.ProfileEdit{Fields: map[string]string{"hobbies": "読書"}}, "officer-1"); err != nil {
+		t.Fatalf("UpdateProfileManually failed: %v", err)
+	}
+
+	profileHistory, _ := historyRepo.List(ctx, user.ID, 0, 0)
+	var records []*domain.ChangeRecord
+	for _, record := range profileHistory {
+		if record.Subject == domain.ChangeSubjectProfile {
+			records = append(records, record)
+		}
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 profile change records, got %d", len(records))
+	}
+
+	// 新しい順: 幹部による編集、本人による編集、同期による作成
+	admin, manual, created := records[0], records[1], records[2]
+	if admin.ActorType != domain.ChangeActorAdmin || admin.ActorUserID != "officer-1" || admin.Version != 3 {
+		t.Errorf("Unexpected admin record: %+v", admin)
+	}
+	if manual.ActorType != domain.ChangeActorManual || len(manual.Changes) != 1 ||
+		manual.Changes[0] != (domain.FieldChange{Field: "real_name", Old: "じょぎ太郎", New: "じょぎ花子"}) {
+		t.Errorf("Unexpected manual record: %+v", manual)
+	}
+	if created.ActorType != domain.ChangeActorSync || created.Version != 1 {
+		t.Errorf("Unexpected sync record: %+v", created)
+	}
+
+	// ユーザーの作成も記録される
+	userRecords := 0
+	for _, record := range profileHistory {
+		if record.Subject == domain.ChangeSubjectUser {
+			userRecords++
+		}
+	}
+	if userRecords != 1 {
+		t.Errorf("Expected 1 user change record, got %d", userRecords)
+	}
+
+	// 学籍番号を非公開にすると、幹部には値を伏せて返す
+	profile, _ := profileRepo.GetByUserID(ctx, user.ID)
+	profile.Visibility = map[string]domain.FieldVisibility{domain.ProfileFieldStudentID: domain.VisibilityHidden}
+	historyService := NewHistoryService(historyRepo, profileRepo)
+	officer := &domain.ProfileViewer{UserID: "officer-1", Officer: true, Member: true}
+	redacted, err := historyService.ListChanges(ctx, officer, user.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("ListChanges failed: %v", err)
+	}
+	for _, change := range redacted[len(redacted)-1].Changes {
+		if change.Field == domain.ProfileFieldStudentID && (!change.Redacted || change.New != "") {
+			t.Errorf("Expected hidden student_id to be redacted for officer, got %+v", change)
+		}
+		if change.Field == domain.ProfileFieldRealName && change.Redacted {
+			t.Errorf("Expected real_name to be visible for officer, got %+v", change)
+		}
+	}
+
+	self := &domain.ProfileViewer{UserID: user.ID, Member: true}
+	own, _ := historyService.ListChanges(ctx, self, user.ID, 0, 0)
+	for _, change := range own[len(own)-1].Changes {
+		if change.Redacted {
+			t.Errorf("Expected no redaction for the owner, got %+v", change)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/saml"
+)
+
+// SAMLService はSAML 2.0のIdentity Provider（IdP）の機能を提供します
+// ログインにはこのサーバーのセッションを使用し、登録されたサービス（SP）にのみ署名付きのアサーションを送ります
+type SAMLService struct {
+	spRepo      repository.SAMLServiceProviderRepository
+	authService *AuthService
+	keyPair     *saml.KeyPair // アサーションの署名に使用する鍵（SPの管理のみ行う場合はnil）
+	entityID    string        // IdPのエンティティID（メタデータのURL）
+	ssoURL      string        // 認証要求を受け付けるURL
+}
+
+// NewSAMLService は新しいSAMLServiceを作成します
+// baseURLはこのサーバーの公開URL（スキームとホスト）です
+func NewSAMLService(spRepo repository.SAMLServiceProviderRepository, authService *AuthService, keyPair *saml.KeyPair, baseURL string) *SAMLService {
+	return &SAMLService{
+		spRepo:      spRepo,
+		authService: authService,
+		keyPair:     keyPair,
+		entityID:    baseURL + "/saml/metadata",
+		ssoURL:      baseURL + "/saml/sso",
+	}
+}
+
+// SAMLLoginRequest は検証済みのSPからの認証要求です
+type SAMLLoginRequest struct {
+	ServiceProvider *domain.SAMLServiceProvider
+	RequestID       string
+	ACSURL          string // アサーションの送信先
+	IsPassive       bool   // ログイン画面を表示せずに応答することを求めているか
+	ForceAuthn      bool   // ログイン済みのセッションを使わずに認証し直すことを求めているか
+	Raw             []byte // 認証要求のXML
+}
+
+// SAMLLoginResponse はSPのACS URLにHTTP-POSTで送るレスポンスです
+type SAMLLoginResponse struct {
+	ACSURL       string
+	SAMLResponse string // Base64エンコードしたレスポンスのXML
+}
+
+// EntityID はIdPのエンティティIDを返します
+func (s *SAMLService) EntityID() string {
+	return s.entityID
+}
+
+// Metadata はIdPのメタデータを返します
+func (s *SAMLService) Metadata() []byte {
+	return saml.IdentityProviderMetadata(s.entityID, s.ssoURL, s.keyPair.Certificate)
+}
+
+// RegisterServiceProvider は新しいSPを登録します
+func (s *SAMLService) RegisterServiceProvider(ctx context.Context, sp *domain.SAMLServiceProvider) (*domain.SAMLServiceProvider, error) {
+	if err := sp.Validate(); err != nil {
+		return nil, err
+	}
+
+	// エンティティIDの重複チェック
+	if _, err := s.spRepo.GetByEntityID(ctx, sp.EntityID); err == nil {
+		return nil, fmt.Errorf("%w: entity_id already exists: %s", domain.ErrInvalidSAMLServiceProvider, sp.EntityID)
+	} else if !errors.Is(err, domain.ErrSAMLServiceProviderNotFound) {
+		return nil, fmt.Errorf("failed to check saml service provider: %w", err)
+	}
+
+	now := time.Now()
+	sp.ID = uuid.New().String()
+	sp.CreatedAt = now
+	sp.UpdatedAt = now
+	if err := s.spRepo.Create(ctx, sp); err != nil {
+		return nil, fmt.Errorf("failed to create saml service provider: %w", err)
+	}
+	return sp, nil
+}
+
+// UpdateServiceProvider は登録済みのSPの名前・送信先・属性の対応・公開範囲の上限を更新します
+func (s *SAMLService) UpdateServiceProvider(ctx context.Context, sp *domain.SAMLServiceProvider) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+	sp.UpdatedAt = time.Now()
+	if err := s.spRepo.Update(ctx, sp); err != nil {
+		return fmt.Errorf("failed to update saml service provider: %w", err)
+	}
+	return nil
+}
+
+// GetServiceProvider はエンティティIDでSPを取得します
+func (s *SAMLService) GetServiceProvider(ctx context.Context, entityID string) (*domain.SAMLServiceProvider, error) {
+	sp, err := s.spRepo.GetByEntityID(ctx, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saml service provider: %w", err)
+	}
+	return sp, nil
+}
+
+// GetAllServiceProviders は登録済みのSPをすべて取得します
+func (s *SAMLService) GetAllServiceProviders(ctx context.Context) ([]*domain.SAMLServiceProvider, error) {
+	sps, err := s.spRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saml service providers: %w", err)
+	}
+	return sps, nil
+}
+
+// DeleteServiceProvider はSPの登録を削除します
+func (s *SAMLService) DeleteServiceProvider(ctx context.Context, entityID string) error {
+	sp, err := s.spRepo.GetByEntityID(ctx, entityID)
+	if err != nil {
+		return fmt.Errorf("failed to get saml service provider: %w", err)
+	}
+	if err := s.spRepo.Delete(ctx, sp.ID); err != nil {
+		return fmt.Errorf("failed to delete saml service provider: %w", err)
+	}
+	return nil
+}
+
+// ParseRequest はSPからの認証要求をデコードし、登録済みのSPからの要求であることを確認します
+// bindingにはsaml.BindingHTTPRedirectまたはsaml.BindingHTTPPostを指定します
+// 認証要求の署名は検証しないため、アサーションの送信先は登録済みのURLに限定します
+func (s *SAMLService) ParseRequest(ctx context.Context, binding, encoded string) (*SAMLLoginRequest, error) {
+	var (
+		req *saml.AuthnRequest
+		raw []byte
+		err error
+	)
+	switch binding {
+	case saml.BindingHTTPRedirect:
+		req, raw, err = saml.DecodeRedirectRequest(encoded)
+	case saml.BindingHTTPPost:
+		req, raw, err = saml.DecodePostRequest(encoded)
+	default:
+		return nil, fmt.Errorf("%w: unsupported binding %s", domain.ErrInvalidSAMLRequest, binding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSAMLRequest, err)
+	}
+
+	sp, err := s.spRepo.GetByEntityID(ctx, req.Issuer)
+	if err != nil {
+		if errors.Is(err, domain.ErrSAMLServiceProviderNotFound) {
+			return nil, fmt.Errorf("%w: unknown service provider %s", domain.ErrInvalidSAMLRequest, req.Issuer)
+		}
+		return nil, fmt.Errorf("failed to get saml service provider: %w", err)
+	}
+
+	if req.ProtocolBinding != "" && req.ProtocolBinding != saml.BindingHTTPPost {
+		return nil, fmt.Errorf("%w: unsupported protocol binding %s", domain.ErrInvalidSAMLRequest, req.ProtocolBinding)
+	}
+	if req.Destination != "" && req.Destination != s.ssoURL {
+		return nil, fmt.Errorf("%w: unexpected destination %s", domain.ErrInvalidSAMLRequest, req.Destination)
+	}
+	acsURL := sp.ACSURLFor(req.AssertionConsumerServiceURL)
+	if acsURL == "" {
+		return nil, fmt.Errorf("%w: assertion consumer service %s is not registered for %s", domain.ErrInvalidSAMLRequest, req.AssertionConsumerServiceURL, sp.EntityID)
+	}
+
+	return &SAMLLoginRequest{
+		ServiceProvider: sp,
+		RequestID:       req.ID,
+		ACSURL:          acsURL,
+		IsPassive:       req.IsPassive,
+		ForceAuthn:      req.ForceAuthn,
+		Raw:             raw,
+	}, nil
+}
+
+// IssueResponse はログイン中のユーザーの署名付きアサーションを含むレスポンスを作成します
+// サーバーから退出したメンバーにはErrAccessDeniedを返します
+func (s *SAMLService) IssueResponse(ctx context.Context, req *SAMLLoginRequest, user *domain.User) (*SAMLLoginResponse, error) {
+	if !user.IsGuildMember() {
+		return nil, fmt.Errorf("%w: user %s has left the guild", domain.ErrAccessDenied, user.ID)
+	}
+
+	member, err := s.authService.GetUserWithProfile(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	now := time.Now()
+	authnInstant := now
+	if member.User.LastLoginAt != nil {
+		authnInstant = *member.User.LastLoginAt
+	}
+	response := &saml.Response{
+		Issuer:       s.entityID,
+		Audience:     req.ServiceProvider.EntityID,
+		Destination:  req.ACSURL,
+		InResponseTo: req.RequestID,
+		NameID:       member.User.ID,
+		AuthnInstant: authnInstant,
+		IssueInstant: now,
+		Attributes:   s.attributes(req.ServiceProvider, member),
+	}
+	signed, err := response.Sign(s.keyPair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign saml response: %w", err)
+	}
+
+	return &SAMLLoginResponse{
+		ACSURL:       req.ACSURL,
+		SAMLResponse: base64.StdEncoding.EncodeToString(signed),
+	}, nil
+}
+
+// IssueNoPassiveResponse はログインしていないためIsPassiveの認証要求に応えられないことを示すレスポンスを作成します
+func (s *SAMLService) IssueNoPassiveResponse(req *SAMLLoginRequest) (*SAMLLoginResponse, error) {
+	return s.issueStatusResponse(req, saml.StatusNoPassive)
+}
+
+// IssueForceAuthnDeniedResponse はForceAuthnの認証要求を拒否するレスポンスを作成します
+// Discordのログインを再度求める手段がないため、既存のセッションでアサーションを発行せずに拒否します
+func (s *SAMLService) IssueForceAuthnDeniedResponse(req *SAMLLoginRequest) (*SAMLLoginResponse, error) {
+	return s.issueStatusResponse(req, saml.StatusRequestDenied)
+}
+
+// issueStatusResponse はステータスがResponderと指定した第2レベルのステータスコードのレスポンスを作成します
+func (s *SAMLService) issueStatusResponse(req *SAMLLoginRequest, subStatus string) (*SAMLLoginResponse, error) {
+	response, err := saml.NewStatusResponse(s.entityID, req.ACSURL, req.RequestID, saml.StatusResponder, subStatus, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saml response: %w", err)
+	}
+	return &SAMLLoginResponse{
+		ACSURL:       req.ACSURL,
+		SAMLResponse: base64.StdEncoding.EncodeToString(response),
+	}, nil
+}
+
+// attributes はSPの属性の対応に従ってアサーションの属性を作成します（属性名の順）
+// 学籍番号はユーザー本人がSP経由で閲覧する場合の公開範囲に従い、閲覧できない場合は送りません
+func (s *SAMLService) attributes(sp *domain.SAMLServiceProvider, member *MemberWithProfile) []saml.Attribute {
+	mapping := sp.AttributesOrDefault()
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	user := member.User
+	attributes := make([]saml.Attribute, 0, len(names))
+	for _, name := range names {
+		var values []string
+		switch mapping[name] {
+		case domain.SAMLAttributeUserID:
+			values = []string{user.ID}
+		case domain.SAMLAttributeDiscordID:
+			values = []string{user.DiscordID}
+		case domain.SAMLAttributeUsername:
+			values = []string{user.Username}
+		case domain.SAMLAttributeDisplayName:
+			values = []string{user.PreferredName()}
+		case domain.SAMLAttributeRoles:
+			for _, role := range member.Roles {
+				values = append(values, role.Name)
+			}
+		case domain.SAMLAttributeStudentID:
+			if member.Profile != nil {
+				viewer := s.authService.ProfileViewer(user).ViaServiceProvider(sp)
+				if studentID := member.Profile.RedactFor(viewer).StudentID; studentID != "" {
+					values = []string{studentID}
+				}
+			}
+		}
+		attributes = append(attributes, saml.Attribute{Name: name, Values: values})
+	}
+	return attributes
+}
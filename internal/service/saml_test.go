@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/saml"
+)
+
+// newTestSAMLKeyPair はテスト用の署名鍵と自己署名証明書を作成します
+func newTestSAMLKeyPair(t *testing.T) *saml.KeyPair {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jyogi-auth"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyPair, err := saml.ParseKeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to parse key pair: %v", err)
+	}
+	return keyPair
+}
+
+// encodeTestAuthnRequest はHTTP-Redirectバインディングの認証要求を作成します
+func encodeTestAuthnRequest(t *testing.T, issuer, acsURL, destination string) string {
+	t.Helper()
+	raw := `<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_req1" Version="2.0" IssueInstant="2026-01-01T00:00:00Z"`
+	if acsURL != "" {
+		raw += ` AssertionConsumerServiceURL="` + acsURL + `"`
+	}
+	if destination != "" {
+		raw += ` Destination="` + destination + `"`
+	}
+	raw += `><saml:Issuer>` + issuer + `</saml:Issuer></samlp:AuthnRequest>`
+	encoded, err := saml.EncodeRedirectRequest([]byte(raw))
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	return encoded
+}
+
+// testSAMLResponse はテストで読み取るレスポンスの項目です
+type testSAMLResponse struct {
+	Destination  string `xml:"Destination,attr"`
+	InResponseTo string `xml:"InResponseTo,attr"`
+	Status       struct {
+		Code struct {
+			Value string `xml:"Value,attr"`
+			Sub   struct {
+				Value string `xml:"Value,attr"`
+			} `xml:"StatusCode"`
+		} `xml:"StatusCode"`
+	} `xml:"Status"`
+	Assertion struct {
+		NameID     string `xml:"Subject>NameID"`
+		Audience   string `xml:"Conditions>AudienceRestriction>Audience"`
+		Attributes []struct {
+			Name   string   `xml:"Name,attr"`
+			Values []string `xml:"AttributeValue"`
+		} `xml:"AttributeStatement>Attribute"`
+	} `xml:"Assertion"`
+}
+
+// decodeTestSAMLResponse はBase64エンコードされたレスポンスを読み取ります
+func decodeTestSAMLResponse(t *testing.T, encoded string) (*testSAMLResponse, map[string][]string) {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	var response testSAMLResponse
+	if err := xml.Unmarshal(raw, &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	attributes := make(map[string][]string)
+	for _, attr := range response.Assertion.Attributes {
+		attributes[attr.Name] = attr.Values
+	}
+	return &response, attributes
+}
+
+func TestSAMLService(t *testing.T) {
+	ctx := context.Background()
+
+	userRepo := newMockUserRepository()
+	roleRepo := newMockRoleRepository()
+	profileRepo := newMockProfileRepository()
+	spRepo := newMockSAMLServiceProviderRepository()
+	roleRepo.roles["role-officer"] = &domain.Role{ID: "role-officer", Name: "幹部", Position: 5}
+	roleRepo.roles["role-member"] = &domain.Role{ID: "role-member", Name: "部員", Position: 1}
+
+	nickname := "たろう"
+	leftAt := time.Now()
+	member := &domain.User{ID: "user-1", DiscordID: "d-1", Username: "taro", DisplayName: "Taro", GuildNickname: &nickname, GuildRoles: []string{"role-officer", "role-member"}}
+	left := &domain.User{ID: "user-2", DiscordID: "d-2", Username: "jiro", LeftAt: &leftAt}
+	userRepo.Create(ctx, member)
+	userRepo.Create(ctx, left)
+	profileRepo.Create(ctx, &domain.Profile{ID: "p-1", UserID: member.ID, StudentID: "24A1001", Visibility: map[string]domain.FieldVisibility{domain.ProfileFieldStudentID: domain.VisibilityOfficers}})
+
+	authService := NewAuthService(nil, userRepo, newMockSessionRepository(), profileRepo, roleRepo, newMockChangeHistoryRepository(), "guild", []string{"role-officer"})
+	service := NewSAMLService(spRepo, authService, newTestSAMLKeyPair(t), "https://auth.example.com")
+
+	sp, err := service.RegisterServiceProvider(ctx, &domain.SAMLServiceProvider{
+		OwnerID:  member.ID,
+		EntityID: "https://sp.example.com/metadata",
+		Name:     "Shared Service",
+		ACSURLs:  []string{"https://sp.example.com/acs", "https://sp.example.com/acs2"},
+		Attributes: map[string]domain.SAMLAttributeSource{
+			"uid":         domain.SAMLAttributeUsername,
+			"displayName": domain.SAMLAttributeDisplayName,
+			"roles":       domain.SAMLAttributeRoles,
+			"studentID":   domain.SAMLAttributeStudentID,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register service provider: %v", err)
+	}
+	if _, err := service.RegisterServiceProvider(ctx, &domain.SAMLServiceProvider{EntityID: sp.EntityID, Name: "Duplicate", ACSURLs: sp.ACSURLs}); !errors.Is(err, domain.ErrInvalidSAMLServiceProvider) {
+		t.Errorf("Expected duplicate entity ID to be rejected, got %v", err)
+	}
+
+	t.Run("parse request", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			issuer      string
+			acsURL      string
+			destination string
+			wantACS     string
+		}{
+			{name: "default acs", issuer: sp.EntityID, wantACS: "https://sp.example.com/acs"},
+			{name: "registered acs", issuer: sp.EntityID, acsURL: "https://sp.example.com/acs2", destination: "https://auth.example.com/saml/sso", wantACS: "https://sp.example.com/acs2"},
+			{name: "unregistered acs", issuer: sp.EntityID, acsURL: "https://evil.example.net/acs"},
+			{name: "unknown issuer", issuer: "https://unknown.example.com"},
+			{name: "wrong destination", issuer: sp.EntityID, destination: "https://other-idp.example.com/sso"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req, err := service.ParseRequest(ctx, saml.BindingHTTPRedirect, encodeTestAuthnRequest(t, tt.issuer, tt.acsURL, tt.destination))
+				if tt.wantACS == "" {
+					if !errors.Is(err, domain.ErrInvalidSAMLRequest) {
+						t.Fatalf("Expected ErrInvalidSAMLRequest, got %v", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Failed to parse request: %v", err)
+				}
+				if req.ACSURL != tt.wantACS || req.RequestID != "_req1" {
+					t.Errorf("Unexpected request: %+v", req)
+				}
+			})
+		}
+	})
+
+	req, err := service.ParseRequest(ctx, saml.BindingHTTPRedirect, encodeTestAuthnRequest(t, sp.EntityID, "", ""))
+	if err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+
+	t.Run("issue response", func(t *testing.T) {
+		resp, err := service.IssueResponse(ctx, req, member)
+		if err != nil {
+			t.Fatalf("Failed to issue response: %v", err)
+		}
+		response, attributes := decodeTestSAMLResponse(t, resp.SAMLResponse)
+		if resp.ACSURL != "https://sp.example.com/acs" || response.Destination != resp.ACSURL || response.InResponseTo != "_req1" {
+			t.Errorf("Unexpected response: %+v", response)
+		}
+		if response.Status.Code.Value != saml.StatusSuccess || response.Assertion.NameID != member.ID || response.Assertion.Audience != sp.EntityID {
+			t.Errorf("Unexpected assertion: %+v", response)
+		}
+		if got := attributes["uid"]; len(got) != 1 || got[0] != "taro" {
+			t.Errorf("Unexpected uid: %v", got)
+		}
+		if got := attributes["displayName"]; len(got) != 1 || got[0] != "たろう" {
+			t.Errorf("Unexpected displayName: %v", got)
+		}
+		if got := attributes["roles"]; len(got) != 2 || got[0] != "幹部" || got[1] != "部員" {
+			t.Errorf("Unexpected roles: %v", got)
+		}
+		// 幹部限定の学籍番号は、公開範囲の上限がmembersのサービスには送らない
+		if got, ok := attributes["studentID"]; ok {
+			t.Errorf("Expected studentID to be withheld, got %v", got)
+		}
+	})
+
+	t.Run("student id with officers access", func(t *testing.T) {
+		sp.ProfileAccess = domain.VisibilityOfficers
+		if err := service.UpdateServiceProvider(ctx, sp); err != nil {
+			t.Fatalf("Failed to update service provider: %v", err)
+		}
+		req, err := service.ParseRequest(ctx, saml.BindingHTTPRedirect, encodeTestAuthnRequest(t, sp.EntityID, "", ""))
+		if err != nil {
+			t.Fatalf("Failed to parse request: %v", err)
+		}
+		resp, err := service.IssueResponse(ctx, req, member)
+		if err != nil {
+			t.Fatalf("Failed to issue response: %v", err)
+		}
+		if _, attributes := decodeTestSAMLResponse(t, resp.SAMLResponse); len(attributes["studentID"]) != 1 || attributes["studentID"][0] != "24A1001" {
+			t.Errorf("Expected studentID, got %v", attributes["studentID"])
+		}
+	})
+
+	t.Run("left member", func(t *testing.T) {
+		if _, err := service.IssueResponse(ctx, req, left); !errors.Is(err, domain.ErrAccessDenied) {
+			t.Errorf("Expected ErrAccessDenied, got %v", err)
+		}
+	})
+
+	t.Run("no passive", func(t *testing.T) {
+		resp, err := service.IssueNoPassiveResponse(req)
+		if err != nil {
+			t.Fatalf("Failed to issue response: %v", err)
+		}
+		response, _ := decodeTestSAMLResponse(t, resp.SAMLResponse)
+		if response.Status.Code.Value != saml.StatusResponder || response.Status.Code.Sub.Value != saml.StatusNoPassive {
+			t.Errorf("Unexpected status: %+v", response.Status)
+		}
+	})
+
+	t.Run("force authn", func(t *testing.T) {
+		resp, err := service.IssueForceAuthnDeniedResponse(req)
+		if err != nil {
+			t.Fatalf("Failed to issue response: %v", err)
+		}
+		response, attributes := decodeTestSAMLResponse(t, resp.SAMLResponse)
+		if response.Status.Code.Value != saml.StatusResponder || response.Status.Code.Sub.Value != saml.StatusRequestDenied {
+			t.Errorf("Unexpected status: %+v", response.Status)
+		}
+		if len(attributes) != 0 {
+			t.Errorf("Expected no assertion, got %v", attributes)
+		}
+	})
+}
+
+// モックSAMLServiceProviderRepository
+type mockSAMLServiceProviderRepository struct {
+	sps map[string]*domain.SAMLServiceProvider // エンティティIDをキーとする
+}
+
+func newMockSAMLServiceProviderRepository() *mockSAMLServiceProviderRepository {
+	return &mockSAMLServiceProviderRepository{sps: make(map[string]*domain.SAMLServiceProvider)}
+}
+
+func (m *mockSAMLServiceProviderRepository) Create(ctx context.Context, sp *domain.SAMLServiceProvider) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+	m.sps[sp.EntityID] = sp
+	return nil
+}
+
+func (m *mockSAMLServiceProviderRepository) GetByEntityID(ctx context.Context, entityID string) (*domain.SAMLServiceProvider, error) {
+	sp, ok := m.sps[entityID]
+	if !ok {
+		return nil, domain.ErrSAMLServiceProviderNotFound
+	}
+	clone := *sp
+	return &clone, nil
+}
+
+func (m *mockSAMLServiceProviderRepository) GetAll(ctx context.Context) ([]*domain.SAMLServiceProvider, error) {
+	var sps []*domain.SAMLServiceProvider
+	for _, sp := range m.sps {
+		sps = append(sps, sp)
+	}
+	return sps, nil
+}
+
+func (m *mockSAMLServiceProviderRepository) Update(ctx context.Context, sp *domain.SAMLServiceProvider) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+	if _, ok := m.sps[sp.EntityID]; !ok {
+		return domain.ErrSAMLServiceProviderNotFound
+	}
+	m.sps[sp.EntityID] = sp
+	return nil
+}
+
+func (m *mockSAMLServiceProviderRepository) Delete(ctx context.Context, id string) error {
+	for entityID, sp := range m.sps {
+		if sp.ID == id {
+			delete(m.sps, entityID)
+			return nil
+		}
+	}
+	return domain.ErrSAMLServiceProviderNotFound
+}
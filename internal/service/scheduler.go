@@ -27,7 +27,7 @@ func (s *Scheduler) Start(ctx context.Context) {
 	log.Printf("Starting profile sync scheduler (interval: %v)", s.interval)
 
 	// 起動時に即座に1回実行
-	if err := s.profileService.SyncProfiles(ctx); err != nil {
+	if err := s.profileService.SyncAll(ctx); err != nil {
 		log.Printf("Error in initial profile sync: %v", err)
 	}
 
@@ -38,7 +38,7 @@ func (s *Scheduler) Start(ctx context.Context) {
 		select {
 		case <-ticker.C:
 			log.Println("Running scheduled profile sync...")
-			if err := s.profileService.SyncProfiles(ctx); err != nil {
+			if err := s.profileService.SyncAll(ctx); err != nil {
 				log.Printf("Error in scheduled profile sync: %v", err)
 			}
 		case <-s.stopChan:
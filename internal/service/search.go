@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/ngram"
+)
+
+// searchFieldWeights は全文検索のスコアに掛ける項目ごとの重みです
+var searchFieldWeights = map[string]float64{
+	domain.ProfileFieldHobbies:  1.0,
+	domain.ProfileFieldWhatToDo: 1.0,
+	domain.ProfileFieldComment:  0.5,
+}
+
+// ErrEmptySearchQuery は検索語に文字・数字が含まれない場合のエラーです
+var ErrEmptySearchQuery = errors.New("search query has no searchable characters")
+
+// SearchService はプロフィールの全文検索のサービスです
+type SearchService struct {
+	searchRepo  repository.ProfileSearchRepository
+	userRepo    repository.UserRepository
+	profileRepo repository.ProfileRepository
+	roleRepo    repository.RoleRepository
+}
+
+// NewSearchService は新しいSearchServiceを作成します
+func NewSearchService(
+	searchRepo repository.ProfileSearchRepository,
+	userRepo repository.UserRepository,
+	profileRepo repository.ProfileRepository,
+	roleRepo repository.RoleRepository,
+) *SearchService {
+	return &SearchService{
+		searchRepo:  searchRepo,
+		userRepo:    userRepo,
+		profileRepo: profileRepo,
+		roleRepo:    roleRepo,
+	}
+}
+
+// SearchResult は全文検索の結果の1件です
+type SearchResult struct {
+	*MemberWithProfile
+	Score  float64
+	Fields []string // 検索語に一致した項目のキー
+}
+
+// SearchProfiles は趣味・やりたいこと・ひとことの全文検索を行い、スコアの高い順に最大limit件を返します
+// 検索語を空白・記号で区切った各語がいずれかの項目に含まれるプロフィールが対象です
+// 閲覧者が閲覧できない項目は検索の対象にならず、脱退済みのメンバーは結果に含めません
+func (s *SearchService) SearchProfiles(ctx context.Context, viewer *domain.ProfileViewer, query string, limit int) ([]*SearchResult, error) {
+	terms := ngram.QueryTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+
+	// 索引で候補を絞り込む（n-gramをすべて含むユーザー）
+	postings, err := s.searchRepo.FindByTerms(ctx, terms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+	totalUsers, err := s.searchRepo.CountUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count indexed users: %w", err)
+	}
+
+	byUser := make(map[string][]*domain.SearchPosting)
+	documentFrequency := make(map[string]map[string]bool)
+	for _, p := range postings {
+		byUser[p.UserID] = append(byUser[p.UserID], p)
+		if documentFrequency[p.Term] == nil {
+			documentFrequency[p.Term] = make(map[string]bool)
+		}
+		documentFrequency[p.Term][p.UserID] = true
+	}
+
+	var candidates []string
+	for userID, userPostings := range byUser {
+		if containsAllTerms(userPostings, terms) {
+			candidates = append(candidates, userID)
+		}
+	}
+	if len(candidates) == 0 {
+		return []*SearchResult{}, nil
+	}
+
+	profiles, err := s.profileRepo.GetByUserIDs(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profiles: %w", err)
+	}
+
+	// 閲覧できる項目の本文で検索語を確認し、TF-IDFでスコアを付ける
+	words := ngram.Words(query)
+	var results []*SearchResult
+	seen := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		if seen[profile.UserID] {
+			continue
+		}
+		seen[profile.UserID] = true
+		visible := visibleSearchFields(viewer, profile)
+		fields := matchedFields(profile, visible, words)
+		if fields == nil {
+			continue
+		}
+
+		score := 0.0
+		for _, p := range byUser[profile.UserID] {
+			if !slices.Contains(visible, p.Field) || !slices.Contains(terms, p.Term) {
+				continue
+			}
+			idf := math.Log(1 + float64(totalUsers)/float64(len(documentFrequency[p.Term])))
+			score += searchFieldWeights[p.Field] * (1 + math.Log(float64(p.Count))) * idf
+		}
+		results = append(results, &SearchResult{
+			MemberWithProfile: &MemberWithProfile{Profile: profile},
+			Score:             score,
+			Fields:            fields,
+		})
+	}
+
+	slices.SortStableFunc(results, func(a, b *SearchResult) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Profile.UserID, b.Profile.UserID)
+	})
+
+	roles, err := s.roleRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	// スコアの高い順にユーザーを取得し、脱退済みのメンバーを除く
+	hits := make([]*SearchResult, 0, min(len(results), max(limit, 0)))
+	for _, result := range results {
+		if limit > 0 && len(hits) >= limit {
+			break
+		}
+		user, err := s.userRepo.GetByID(ctx, result.Profile.UserID)
+		if err != nil {
+			if errors.Is(err, domain.ErrUserNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if !user.IsGuildMember() {
+			continue
+		}
+		result.User = user
+		result.Roles = filterRoles(roles, user.GuildRoles)
+		hits = append(hits, result)
+	}
+
+	return hits, nil
+}
+
+// containsAllTerms は索引にすべての語が含まれるかどうかを確認します
+func containsAllTerms(postings []*domain.SearchPosting, terms []string) bool {
+	for _, term := range terms {
+		if !slices.ContainsFunc(postings, func(p *domain.SearchPosting) bool { return p.Term == term }) {
+			return false
+		}
+	}
+	return true
+}
+
+// visibleSearchFields は全文検索の対象の項目のうち閲覧者が閲覧できるものを返します（閲覧者がnilの場合はすべて）
+func visibleSearchFields(viewer *domain.ProfileViewer, profile *domain.Profile) []string {
+	var fields []string
+	for _, field := range domain.ProfileSearchFields {
+		if viewer == nil || viewer.CanSee(profile.UserID, profile.VisibilityOf(field)) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// matchedFields は各語がいずれかの項目に含まれる場合に、語を含む項目のキーを返します（一致しない語があればnil）
+func matchedFields(profile *domain.Profile, fields []string, words []string) []string {
+	texts := make(map[string]string, len(fields))
+	for _, field := range fields {
+		texts[field] = ngram.Normalize(profile.FieldValue(field))
+	}
+
+	var matched []string
+	for _, word := range words {
+		found := false
+		for _, field := range fields {
+			if strings.Contains(texts[field], word) {
+				found = true
+				if !slices.Contains(matched, field) {
+					matched = append(matched, field)
+				}
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return matched
+}
+
+// profilePostings はプロフィールの全文検索の対象の項目を索引に登録する語に分割します
+func profilePostings(profile *domain.Profile) []*domain.SearchPosting {
+	var postings []*domain.SearchPosting
+	for _, field := range domain.ProfileSearchFields {
+		for term, count := range ngram.Tokenize(profile.FieldValue(field)) {
+			postings = append(postings, &domain.SearchPosting{
+				UserID: profile.UserID,
+				Field:  field,
+				Term:   term,
+				Count:  count,
+			})
+		}
+	}
+	return postings
+}
+
+// indexProfile はプロフィールの全文検索の索引を更新します（墓標化されたプロフィールは索引から削除します）
+// 索引の更新に失敗しても元の更新は取り消さず、警告をログに出力します（RebuildSearchIndexで復旧できます）
+func indexProfile(ctx context.Context, searchRepo repository.ProfileSearchRepository, profile *domain.Profile) {
+	var err error
+	if profile.IsDeleted() {
+		err = searchRepo.DeleteByUserID(ctx, profile.UserID)
+	} else {
+		err = searchRepo.Replace(ctx, profile.UserID, profilePostings(profile))
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to update search index for user %s: %v", profile.UserID, err)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+)
+
+// TestSearchService_SearchProfiles は同期・手動編集で更新された索引から、閲覧者に応じた検索結果がスコア順に返ることを確認します
+func TestSearchService_SearchProfiles(t *testing.T) {
+	profileRepo := newMockProfileRepository()
+	userRepo := newMockUserRepository()
+	roleRepo := newMockRoleRepository()
+	searchRepo := newMockProfileSearchRepository()
+	profileService := NewProfileService(profileRepo, userRepo, roleRepo, newMockSyncCursorRepository(), newMockIntroMessageRepository(), newMockChangeHistoryRepository(), searchRepo, "test-token", "test-guild", "test-channel", 0, domain.ProfileMergeNewest, nil, nil)
+	searchService := NewSearchService(searchRepo, userRepo, profileRepo, roleRepo)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	intros := []struct {
+		messageID string
+		authorID  string
+		content   string
+	}{
+		{"100", "discord-1", "趣味: ゲーム制作、ゲーム実況\nじょぎでやりたいこと: ゲーム制作"},
+		{"101", "discord-2", "趣味: げーむ\nじょぎでやりたいこと: 競プロ"},
+		{"102", "discord-3", "趣味: 読書"},
+		{"103", "discord-4", "趣味: ゲーム制作"},
+	}
+	for i, intro := range intros {
+		if _, err := profileService.syncMessage(ctx, newIntroTestMessage(intro.messageID, intro.authorID, intro.content, base.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatalf("syncMessage failed: %v", err)
+		}
+	}
+	user := func(discordID string) *domain.User { return userRepo.usersByDiscordID[discordID] }
+
+	// 手動編集した値も索引に反映される
+	if _, err := profileService.UpdateProfileManually(ctx, user("discord-3").ID, domain.ProfileEdit{
+		Fields: map[string]string{"comment": "最近は競プロも"},
+	}, user("discord-3").ID); err != nil {
+		t.Fatalf("UpdateProfileManually failed: %v", err)
+	}
+
+	// 趣味を幹部のみに公開したメンバー
+	hidden := profileRepo.profilesByUser[user("discord-4").ID]
+	hidden.Visibility = map[string]domain.FieldVisibility{domain.ProfileFieldHobbies: domain.VisibilityOfficers}
+
+	member := &domain.ProfileViewer{UserID: "viewer", Member: true}
+	officer := &domain.ProfileViewer{UserID: "viewer", Member: true, Officer: true}
+
+	tests := []struct {
+		name   string
+		viewer *domain.ProfileViewer
+		query  string
+		want   []string
+	}{
+		{name: "閲覧できない項目は検索の対象外", viewer: member, query: "ゲーム制作", want: []string{"discord-1"}},
+		{name: "幹部向けの項目・出現回数が多いほど上位", viewer: officer, query: "ゲーム制作", want: []string{"discord-1", "discord-4"}},
+		{name: "ひらがな・カタカナの表記揺れ", viewer: member, query: "ゲーム", want: []string{"discord-1", "discord-2"}},
+		{name: "手動編集した項目", viewer: member, query: "競プロ", want: []string{"discord-2", "discord-3"}},
+		{name: "すべての語を含む", viewer: member, query: "ゲーム 競プロ", want: []string{"discord-2"}},
+		{name: "一致なし", viewer: member, query: "料理", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := searchService.SearchProfiles(ctx, tt.viewer, tt.query, 10)
+			if err != nil {
+				t.Fatalf("SearchProfiles failed: %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.User.DiscordID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+
+	// 脱退したメンバーは結果に含めない
+	leftAt := time.Now()
+	user("discord-2").LeftAt = &leftAt
+	results, err := searchService.SearchProfiles(ctx, member, "競プロ", 10)
+	if err != nil {
+		t.Fatalf("SearchProfiles failed: %v", err)
+	}
+	if len(results) != 1 || results[0].User.DiscordID != "discord-3" {
+		t.Errorf("Expected only discord-3, got %d results", len(results))
+	}
+	if len(results) == 1 && (len(results[0].Fields) != 1 || results[0].Fields[0] != domain.ProfileFieldComment) {
+		t.Errorf("Expected matched field comment, got %v", results[0].Fields)
+	}
+
+	if _, err := searchService.SearchProfiles(ctx, member, "!?", 10); err != ErrEmptySearchQuery {
+		t.Errorf("Expected ErrEmptySearchQuery, got %v", err)
+	}
+}
+
+// モックProfileSearchRepository
+type mockProfileSearchRepository struct {
+	postings map[string][]*domain.SearchPosting
+}
+
+func newMockProfileSearchRepository() *mockProfileSearchRepository {
+	return &mockProfileSearchRepository{postings: make(map[string][]*domain.SearchPosting)}
+}
+
+func (m *mockProfileSearchRepository) Replace(ctx context.Context, userID string, postings []*domain.SearchPosting) error {
+	m.postings[userID] = postings
+	return nil
+}
+
+func (m *mockProfileSearchRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	delete(m.postings, userID)
+	return nil
+}
+
+func (m *mockProfileSearchRepository) FindByTerms(ctx context.Context, terms []string) ([]*domain.SearchPosting, error) {
+	var found []*domain.SearchPosting
+	for _, postings := range m.postings {
+		for _, p := range postings {
+			if slices.Contains(terms, p.Term) {
+				found = append(found, p)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (m *mockProfileSearchRepository) CountUsers(ctx context.Context) (int64, error) {
+	return int64(len(m.postings)), nil
+}
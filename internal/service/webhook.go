@@ -0,0 +1,449 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/internal/repository"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/webhook"
+)
+
+const (
+	// WebhookMaxAttempts はWebhookの配信を試みる最大回数です
+	WebhookMaxAttempts = 8
+	// webhookInitialRetryDelay は1回目の失敗後に再試行するまでの間隔です（失敗するたびに2倍にします）
+	webhookInitialRetryDelay = 30 * time.Second
+	// webhookMaxRetryDelay は再試行の間隔の上限です
+	webhookMaxRetryDelay = 1 * time.Hour
+	// webhookPollInterval は配信待ちを確認する間隔です
+	webhookPollInterval = 10 * time.Second
+	// webhookClaimLease は取得した配信を他のワーカーから取得されないようにする時間です
+	webhookClaimLease = 2 * time.Minute
+	// webhookBatchSize は1回に取得する配信の件数です
+	webhookBatchSize = 20
+	// webhookRequestTimeout は1回の送信のタイムアウトです
+	webhookRequestTimeout = 10 * time.Second
+	// webhookMaxErrorLength は配信の記録に保存するエラーメッセージの最大長です
+	webhookMaxErrorLength = 500
+)
+
+// WebhookService はクライアントアプリへのWebhookの送信先の管理と配信を提供します
+// 配信は永続化してから非同期に送信するため、同期ツールなど別のプロセスで発生した出来事もサーバーが配信します
+type WebhookService struct {
+	endpointRepo repository.WebhookEndpointRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	httpClient   *http.Client
+	wake         chan struct{}
+}
+
+// NewWebhookService は新しいWebhookServiceを作成します
+// allowPrivateNetworksがfalseの場合、プライベートIPアドレス・ループバックアドレスへの送信を拒否します（SSRF対策）
+func NewWebhookService(
+	endpointRepo repository.WebhookEndpointRepository,
+	deliveryRepo repository.WebhookDeliveryRepository,
+	allowPrivateNetworks bool,
+) *WebhookService {
+	return &WebhookService{
+		endpointRepo: endpointRepo,
+		deliveryRepo: deliveryRepo,
+		// リダイレクトは追跡しない（3xxは失敗として扱う）
+		httpClient: newOutboundHTTPClient(webhookRequestTimeout, allowPrivateNetworks),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Publish は出来事を購読している送信先ごとに配信を記録します
+// 記録した配信は Start で開始したワーカー（別のプロセスの場合はそのワーカー）が送信します
+func (s *WebhookService) Publish(ctx context.Context, event *domain.Event) {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	endpoints, err := s.endpointRepo.ListActive(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list webhook endpoints for %s: %v", event.Type, err)
+		return
+	}
+
+	var payload []byte
+	queued := 0
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribes(event.Type) || (event.ClientID != "" && endpoint.ClientID != event.ClientID) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(newEventPayload(event)); err != nil {
+				log.Printf("Warning: Failed to encode webhook payload for %s: %v", event.Type, err)
+				return
+			}
+		}
+
+		now := time.Now()
+		delivery := &domain.WebhookDelivery{
+			ID:            uuid.New().String(),
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        domain.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if err := s.deliveryRepo.Create(ctx, delivery); err != nil {
+			log.Printf("Warning: Failed to queue webhook delivery of %s to endpoint %s: %v", event.Type, endpoint.ID, err)
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		s.notify()
+	}
+}
+
+// Start はバックグラウンドで配信待ちのWebhookを送信します
+// ctxがキャンセルされるとゴルーチンは終了します（グレースフルシャットダウン対応）
+func (s *WebhookService) Start(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	log.Printf("Webhook delivery worker started (poll interval: %v)", webhookPollInterval)
+
+	for {
+		// 1回で取得しきれなかった場合は続けて送信する
+		for {
+			processed, err := s.ProcessDue(ctx)
+			if err != nil {
+				log.Printf("Webhook delivery failed: %v", err)
+			}
+			if processed < webhookBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-ctx.Done():
+			log.Println("Webhook delivery worker stopped")
+			return
+		}
+	}
+}
+
+// ProcessDue は送信日時を過ぎた配信待ちを取得して送信し、取得した件数を返します
+func (s *WebhookService) ProcessDue(ctx context.Context) (int, error) {
+	deliveries, err := s.deliveryRepo.ClaimDue(ctx, time.Now(), webhookClaimLease, webhookBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *domain.WebhookDelivery) {
+			defer wg.Done()
+			if err := s.deliver(ctx, delivery); err != nil {
+				log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver は配信を1回送信し、結果を記録します
+// 失敗した場合は再試行の上限まで、間隔を2倍ずつ空けて再試行します
+func (s *WebhookService) deliver(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	endpoint, err := s.endpointRepo.GetByID(ctx, delivery.EndpointID)
+	if err != nil && !errors.Is(err, domain.ErrWebhookNotFound) {
+		return fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	now := time.Now()
+	if endpoint == nil || !endpoint.Active {
+		delivery.Status = domain.WebhookDeliveryFailed
+		delivery.LastError = "endpoint is disabled"
+		delivery.CompletedAt = &now
+		return s.deliveryRepo.Update(ctx, delivery)
+	}
+
+	statusCode, sendErr := s.send(ctx, endpoint, delivery)
+	now = time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = domain.WebhookDeliverySucceeded
+		delivery.CompletedAt = &now
+	case delivery.Attempts >= WebhookMaxAttempts:
+		delivery.Status = domain.WebhookDeliveryFailed
+		delivery.LastError = truncateError(sendErr.Error())
+		delivery.CompletedAt = &now
+		log.Printf("Webhook delivery %s of %s to %s failed after %d attempts: %v", delivery.ID, delivery.EventType, endpoint.URL, delivery.Attempts, sendErr)
+	default:
+		delivery.LastError = truncateError(sendErr.Error())
+		delivery.NextAttemptAt = now.Add(webhookRetryDelay(delivery.Attempts))
+	}
+
+	return s.deliveryRepo.Update(ctx, delivery)
+}
+
+// send は配信の本文に署名して送信先にPOSTし、送信先が返したHTTPステータスを返します
+// 2xx以外のステータスはエラーとして扱います
+func (s *WebhookService) send(ctx context.Context, endpoint *domain.WebhookEndpoint, delivery *domain.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "jyogi-discord-auth-webhook/1.0")
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(endpoint.Secret, time.Now(), body))
+	req.Header.Set(webhook.EventHeader, string(delivery.EventType))
+	req.Header.Set(webhook.DeliveryHeader, delivery.ID)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// notify はワーカーに配信待ちが追加されたことを通知します（同じプロセスのワーカーのみ）
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ListEndpoints はクライアントのWebhookの送信先を取得します
+func (s *WebhookService) ListEndpoints(ctx context.Context, clientID string) ([]*domain.WebhookEndpoint, error) {
+	endpoints, err := s.endpointRepo.ListByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// CreateEndpoint はクライアントにWebhookの送信先を追加します
+// 署名用のシークレットは自動生成し、作成した送信先に含めて返します
+func (s *WebhookService) CreateEndpoint(ctx context.Context, clientID, url string, events []domain.EventType) (*domain.WebhookEndpoint, error) {
+	existing, err := s.endpointRepo.ListByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	if len(existing) >= domain.MaxWebhookEndpointsPerClient {
+		return nil, fmt.Errorf("%w: up to %d endpoints can be registered", domain.ErrInvalidWebhook, domain.MaxWebhookEndpointsPerClient)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	endpoint := &domain.WebhookEndpoint{
+		ID:        uuid.New().String(),
+		ClientID:  clientID,
+		URL:       url,
+		Secret:    secret,
+		Events:    uniqueEventTypes(events),
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := endpoint.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.endpointRepo.Create(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// UpdateEndpoint はWebhookの送信先のURL・購読する出来事・有効状態を更新します
+func (s *WebhookService) UpdateEndpoint(ctx context.Context, clientID, endpointID, url string, events []domain.EventType, active bool) (*domain.WebhookEndpoint, error) {
+	endpoint, err := s.getEndpoint(ctx, clientID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.URL = url
+	endpoint.Events = uniqueEventTypes(events)
+	endpoint.Active = active
+	endpoint.UpdatedAt = time.Now()
+	if err := endpoint.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.endpointRepo.Update(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// RotateSecret はWebhookの送信先の署名用のシークレットを再生成します
+// 再試行待ちの配信も、次の送信から新しいシークレットで署名します
+func (s *WebhookService) RotateSecret(ctx context.Context, clientID, endpointID string) (*domain.WebhookEndpoint, error) {
+	endpoint, err := s.getEndpoint(ctx, clientID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Secret = secret
+	endpoint.UpdatedAt = time.Now()
+	if err := s.endpointRepo.Update(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint はWebhookの送信先とその配信の記録を削除します
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, clientID, endpointID string) error {
+	if _, err := s.getEndpoint(ctx, clientID, endpointID); err != nil {
+		return err
+	}
+	if err := s.endpointRepo.Delete(ctx, endpointID); err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// DeleteClientEndpoints はクライアントのWebhookの送信先をすべて削除します（クライアントの削除時に使用）
+func (s *WebhookService) DeleteClientEndpoints(ctx context.Context, clientID string) error {
+	endpoints, err := s.endpointRepo.ListByClientID(ctx, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	for _, endpoint := range endpoints {
+		if err := s.endpointRepo.Delete(ctx, endpoint.ID); err != nil {
+			return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListDeliveries はWebhookの送信先の配信を新しい順に取得します
+func (s *WebhookService) ListDeliveries(ctx context.Context, clientID, endpointID string, limit int) ([]*domain.WebhookDelivery, error) {
+	if _, err := s.getEndpoint(ctx, clientID, endpointID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.deliveryRepo.ListByEndpointID(ctx, endpointID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Replay は配信と同じ本文の配信を新しく記録し、送信し直します
+// 受信側で重複を判定できるよう、本文の出来事のID（id）は元の配信と同じです
+func (s *WebhookService) Replay(ctx context.Context, clientID, deliveryID string) (*domain.WebhookDelivery, error) {
+	original, err := s.deliveryRepo.GetByID(ctx, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if _, err := s.getEndpoint(ctx, clientID, original.EndpointID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &domain.WebhookDelivery{
+		ID:            uuid.New().String(),
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        domain.WebhookDeliveryPending,
+		NextAttemptAt: now,
+		ReplayOf:      original.ID,
+		CreatedAt:     now,
+	}
+	if err := s.deliveryRepo.Create(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to queue webhook replay: %w", err)
+	}
+	s.notify()
+	return delivery, nil
+}
+
+// getEndpoint はクライアントのWebhookの送信先を取得します（他のクライアントの送信先は見つからないものとして扱います）
+func (s *WebhookService) getEndpoint(ctx context.Context, clientID, endpointID string) (*domain.WebhookEndpoint, error) {
+	endpoint, err := s.endpointRepo.GetByID(ctx, endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	if endpoint.ClientID != clientID {
+		return nil, fmt.Errorf("%w: endpoint=%s", domain.ErrWebhookNotFound, endpointID)
+	}
+	return endpoint, nil
+}
+
+// webhookRetryDelay は失敗した回数に応じた再試行までの間隔を返します
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookInitialRetryDelay
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxRetryDelay)
+}
+
+// generateWebhookSecret は署名用のシークレットを生成します
+func generateWebhookSecret() (string, error) {
+	token, err := generateSecureToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + token, nil
+}
+
+// uniqueEventTypes は出来事の種類を重複なく定義順に並べ替えて返します
+func uniqueEventTypes(events []domain.EventType) []domain.EventType {
+	var result []domain.EventType
+	for _, eventType := range domain.EventTypes {
+		if slices.Contains(events, eventType) {
+			result = append(result, eventType)
+		}
+	}
+	for _, eventType := range events {
+		if !slices.Contains(result, eventType) {
+			// 未知の種類は検証でエラーにするため残す
+			result = append(result, eventType)
+		}
+	}
+	return result
+}
+
+// truncateError はエラーメッセージを配信の記録に保存できる長さに切り詰めます
+func truncateError(message string) string {
+	if len(message) <= webhookMaxErrorLength {
+		return message
+	}
+	return strings.ToValidUTF8(message[:webhookMaxErrorLength], "")
+}
@@ -0,0 +1,460 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jyogi-web/jyogi-discord-auth/internal/domain"
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/webhook"
+)
+
+// newTestWebhookService はテスト用の送信先を登録したWebhookServiceを作成します
+func newTestWebhookService(t *testing.T, url string, allowPrivateNetworks bool) (*WebhookService, *mockWebhookEndpointRepository, *mockWebhookDeliveryRepository) {
+	t.Helper()
+	endpointRepo := newMockWebhookEndpointRepository()
+	deliveryRepo := newMockWebhookDeliveryRepository()
+	now := time.Now()
+	endpointRepo.endpoints = []*domain.WebhookEndpoint{
+		{ID: "ep-a", ClientID: "client-a", URL: url, Secret: "secret-a", Events: []domain.EventType{domain.EventUserCreated, domain.EventTokenRevoked}, Active: true, CreatedAt: now, UpdatedAt: now},
+		{ID: "ep-b", ClientID: "client-b", URL: url, Secret: "secret-b", Events: []domain.EventType{domain.EventTokenRevoked}, Active: true, CreatedAt: now, UpdatedAt: now},
+		{ID: "ep-off", ClientID: "client-b", URL: url, Secret: "secret-off", Events: []domain.EventType{domain.EventUserCreated}, Active: false, CreatedAt: now, UpdatedAt: now},
+	}
+	return NewWebhookService(endpointRepo, deliveryRepo, allowPrivateNetworks), endpointRepo, deliveryRepo
+}
+
+// TestWebhookService_PublishAndDeliver は購読している送信先にのみ署名付きで配信されることをテストします
+func TestWebhookService_PublishAndDeliver(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service, _, deliveryRepo := newTestWebhookService(t, server.URL, true)
+	ctx := context.Background()
+
+	service.Publish(ctx, &domain.Event{Type: domain.EventUserCreated, UserID: "user-1", Data: map[string]interface{}{"changed_fields": []string{"username"}}})
+	// 発行先のクライアントを限定した出来事は、そのクライアントの送信先にのみ配信する
+	service.Publish(ctx, &domain.Event{Type: domain.EventTokenRevoked, UserID: "user-1", ClientID: "client-b"})
+
+	if len(deliveryRepo.deliveries) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(deliveryRepo.deliveries))
+	}
+	if deliveryRepo.deliveries[0].EndpointID != "ep-a" || deliveryRepo.deliveries[1].EndpointID != "ep-b" {
+		t.Errorf("Unexpected endpoints: %s, %s", deliveryRepo.deliveries[0].EndpointID, deliveryRepo.deliveries[1].EndpointID)
+	}
+
+	processed, err := service.ProcessDue(ctx)
+	if err != nil {
+		t.Fatalf("ProcessDue failed: %v", err)
+	}
+	if processed != 2 {
+		t.Errorf("Expected 2 processed deliveries, got %d", processed)
+	}
+	close(requests)
+
+	secrets := map[string]string{"user.created": "secret-a", "token.revoked": "secret-b"}
+	count := 0
+	for req := range requests {
+		count++
+		eventType := req.header.Get(webhook.EventHeader)
+		if err := webhook.Verify(secrets[eventType], req.header.Get(webhook.SignatureHeader), req.body, time.Now(), webhook.DefaultTolerance); err != nil {
+			t.Errorf("Invalid signature for %s: %v", eventType, err)
+		}
+		if req.header.Get(webhook.DeliveryHeader) == "" {
+			t.Error("Expected delivery header")
+		}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatalf("Invalid payload: %v", err)
+		}
+		data, _ := payload["data"].(map[string]interface{})
+		if payload["type"] != eventType || payload["id"] == "" || data["user_id"] != "user-1" {
+			t.Errorf("Unexpected payload: %s", req.body)
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 requests, got %d", count)
+	}
+
+	for _, delivery := range deliveryRepo.deliveries {
+		if delivery.Status != domain.WebhookDeliverySucceeded || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusNoContent || delivery.CompletedAt == nil {
+			t.Errorf("Expected succeeded delivery, got %+v", delivery)
+		}
+	}
+}
+
+// TestWebhookService_Retry は失敗した配信が間隔を空けて再試行され、上限に達すると失敗になることをテストします
+func TestWebhookService_Retry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	service, _, deliveryRepo := newTestWebhookService(t, server.URL, true)
+	ctx := context.Background()
+
+	service.Publish(ctx, &domain.Event{Type: domain.EventUserCreated, UserID: "user-1"})
+	before := time.Now()
+	if _, err := service.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue failed: %v", err)
+	}
+
+	delivery := deliveryRepo.deliveries[0]
+	if delivery.Status != domain.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected pending delivery after first failure, got %+v", delivery)
+	}
+	if delivery.NextAttemptAt.Before(before.Add(webhookInitialRetryDelay)) {
+		t.Errorf("Expected retry after %v, got %v", webhookInitialRetryDelay, delivery.NextAttemptAt.Sub(before))
+	}
+
+	// 再試行の時刻を過ぎるまでは送信しない
+	if processed, _ := service.ProcessDue(ctx); processed != 0 {
+		t.Errorf("Expected no deliveries before retry time, got %d", processed)
+	}
+
+	delivery.Attempts = WebhookMaxAttempts - 1
+	delivery.NextAttemptAt = time.Now().Add(-time.Second)
+	if _, err := service.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue failed: %v", err)
+	}
+	delivery = deliveryRepo.deliveries[0]
+	if delivery.Status != domain.WebhookDeliveryFailed || delivery.Attempts != WebhookMaxAttempts || delivery.CompletedAt == nil {
+		t.Errorf("Expected failed delivery, got %+v", delivery)
+	}
+	if !strings.Contains(delivery.LastError, "503") {
+		t.Errorf("Expected status in last error, got %q", delivery.LastError)
+	}
+}
+
+// TestWebhookService_RejectsPrivateAddress はプライベートアドレスへの送信が拒否されることをテストします
+func TestWebhookService_RejectsPrivateAddress(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	service, _, deliveryRepo := newTestWebhookService(t, server.URL, false)
+	ctx := context.Background()
+
+	service.Publish(ctx, &domain.Event{Type: domain.EventUserCreated, UserID: "user-1"})
+	if _, err := service.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue failed: %v", err)
+	}
+
+	delivery := deliveryRepo.deliveries[0]
+	if called {
+		t.Error("Expected request to private address to be blocked")
+	}
+	if delivery.Status != domain.WebhookDeliveryPending || !strings.Contains(delivery.LastError, "not allowed") {
+		t.Errorf("Expected blocked delivery to be retried, got %+v", delivery)
+	}
+}
+
+// TestWebhookService_Replay は配信が同じ本文で再送され、他のクライアントからは再送できないことをテストします
+func TestWebhookService_Replay(t *testing.T) {
+	service, _, deliveryRepo := newTestWebhookService(t, "https://example.com/hook", true)
+	ctx := context.Background()
+
+	service.Publish(ctx, &domain.Event{Type: domain.EventUserCreated, UserID: "user-1"})
+	original := deliveryRepo.deliveries[0]
+
+	if _, err := service.Replay(ctx, "client-b", original.ID); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound for other client, got %v", err)
+	}
+
+	replay, err := service.Replay(ctx, "client-a", original.ID)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if replay.ID == original.ID || replay.ReplayOf != original.ID || replay.EventID != original.EventID || replay.Payload != original.Payload {
+		t.Errorf("Unexpected replay: %+v", replay)
+	}
+	if replay.Status != domain.WebhookDeliveryPending || replay.Attempts != 0 {
+		t.Errorf("Expected new pending delivery, got %+v", replay)
+	}
+
+	deliveries, err := service.ListDeliveries(ctx, "client-a", "ep-a", 10)
+	if err != nil {
+		t.Fatalf("ListDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != replay.ID {
+		t.Errorf("Expected replay first in deliveries, got %d deliveries", len(deliveries))
+	}
+}
+
+// TestWebhookService_ManageEndpoints は送信先の作成・更新・シークレットの再生成・削除をテストします
+func TestWebhookService_ManageEndpoints(t *testing.T) {
+	service, endpointRepo, _ := newTestWebhookService(t, "https://example.com/hook", true)
+	ctx := context.Background()
+
+	if _, err := service.CreateEndpoint(ctx, "client-c", "http://example.com/hook", []domain.EventType{domain.EventUserCreated}); !errors.Is(err, domain.ErrInvalidWebhook) {
+		t.Errorf("Expected ErrInvalidWebhook for http url, got %v", err)
+	}
+	if _, err := service.CreateEndpoint(ctx, "client-c", "https://example.com/hook", nil); !errors.Is(err, domain.ErrInvalidWebhook) {
+		t.Errorf("Expected ErrInvalidWebhook without events, got %v", err)
+	}
+	if _, err := service.CreateEndpoint(ctx, "client-c", "https://example.com/hook", []domain.EventType{"user.deleted"}); !errors.Is(err, domain.ErrInvalidWebhook) {
+		t.Errorf("Expected ErrInvalidWebhook for unknown event, got %v", err)
+	}
+
+	endpoint, err := service.CreateEndpoint(ctx, "client-c", "https://example.com/hook", []domain.EventType{domain.EventMemberLeft, domain.EventUserCreated, domain.EventMemberLeft})
+	if err != nil {
+		t.Fatalf("CreateEndpoint failed: %v", err)
+	}
+	if !strings.HasPrefix(endpoint.Secret, "whsec_") || !endpoint.Active {
+		t.Errorf("Unexpected endpoint: %+v", endpoint)
+	}
+	if !slices.Equal(endpoint.Events, []domain.EventType{domain.EventUserCreated, domain.EventMemberLeft}) {
+		t.Errorf("Expected events in definition order without duplicates, got %v", endpoint.Events)
+	}
+
+	// 他のクライアントの送信先は変更できない
+	if _, err := service.UpdateEndpoint(ctx, "client-a", endpoint.ID, "https://example.com/other", []domain.EventType{domain.EventUserUpdated}, true); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound for other client, got %v", err)
+	}
+
+	updated, err := service.UpdateEndpoint(ctx, "client-c", endpoint.ID, "https://example.com/other", []domain.EventType{domain.EventUserUpdated}, false)
+	if err != nil {
+		t.Fatalf("UpdateEndpoint failed: %v", err)
+	}
+	if updated.URL != "https://example.com/other" || updated.Active || updated.Secret != endpoint.Secret {
+		t.Errorf("Unexpected updated endpoint: %+v", updated)
+	}
+
+	rotated, err := service.RotateSecret(ctx, "client-c", endpoint.ID)
+	if err != nil {
+		t.Fatalf("RotateSecret failed: %v", err)
+	}
+	if rotated.Secret == endpoint.Secret {
+		t.Error("Expected secret to change")
+	}
+
+	if err := service.DeleteClientEndpoints(ctx, "client-c"); err != nil {
+		t.Fatalf("DeleteClientEndpoints failed: %v", err)
+	}
+	if endpoints, _ := endpointRepo.ListByClientID(ctx, "client-c"); len(endpoints) != 0 {
+		t.Errorf("Expected endpoints to be deleted, got %d", len(endpoints))
+	}
+}
+
+// TestWebhookRetryDelay は再試行の間隔が2倍ずつ増え、上限で止まることをテストします
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// TestEventHistoryRepository は変更履歴の記録に合わせて出来事が通知されることをテストします
+func TestEventHistoryRepository(t *testing.T) {
+	publisher := &recordingPublisher{}
+	historyRepo := NewEventHistoryRepository(newMockChangeHistoryRepository(), publisher)
+	ctx := context.Background()
+
+	leftAt := time.Now()
+	user := &domain.User{ID: "user-1", Username: "taro"}
+	renamed := user.Clone()
+	renamed.Username = "jiro"
+	left := renamed.Clone()
+	left.LeftAt = &leftAt
+	left.GuildRoles = nil
+	rejoined := left.Clone()
+	rejoined.LeftAt = nil
+
+	recordUserChange(ctx, historyRepo, nil, user, domain.SyncActor())
+	recordUserChange(ctx, historyRepo, user, renamed, domain.SyncActor())
+	recordUserChange(ctx, historyRepo, renamed, left, domain.SyncActor())
+	recordUserChange(ctx, historyRepo, left, rejoined, domain.SyncActor())
+	recordProfileChange(ctx, historyRepo, nil, &domain.Profile{UserID: "user-1", RealName: "じょぎ太郎"}, domain.UserActor("user-1", "user-1"))
+	// 変更がない場合は通知しない
+	recordUserChange(ctx, historyRepo, rejoined, rejoined, domain.SyncActor())
+
+	want := []domain.EventType{domain.EventUserCreated, domain.EventUserUpdated, domain.EventMemberLeft, domain.EventUserUpdated, domain.EventProfileUpdated}
+	got := make([]domain.EventType, len(publisher.events))
+	for i, event := range publisher.events {
+		got[i] = event.Type
+		if event.ID == "" || event.UserID != "user-1" || event.OccurredAt.IsZero() {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	if fields := publisher.events[1].Data["changed_fields"]; !slices.Equal(fields.([]string), []string{"username"}) {
+		t.Errorf("Expected changed_fields [username], got %v", fields)
+	}
+	if fields := publisher.events[3].Data["changed_fields"]; !slices.Equal(fields.([]string), []string{"left_at"}) {
+		t.Errorf("Expected rejoin to be reported as left_at update, got %v", fields)
+	}
+}
+
+// モックWebhookEndpointRepository
+type mockWebhookEndpointRepository struct {
+	endpoints []*domain.WebhookEndpoint
+}
+
+func newMockWebhookEndpointRepository() *mockWebhookEndpointRepository {
+	return &mockWebhookEndpointRepository{}
+}
+
+func (m *mockWebhookEndpointRepository) Create(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	m.endpoints = append(m.endpoints, endpoint)
+	return nil
+}
+
+func (m *mockWebhookEndpointRepository) GetByID(ctx context.Context, id string) (*domain.WebhookEndpoint, error) {
+	for _, e := range m.endpoints {
+		if e.ID == id {
+			clone := *e
+			return &clone, nil
+		}
+	}
+	return nil, domain.ErrWebhookNotFound
+}
+
+func (m *mockWebhookEndpointRepository) ListByClientID(ctx context.Context, clientID string) ([]*domain.WebhookEndpoint, error) {
+	var endpoints []*domain.WebhookEndpoint
+	for _, e := range m.endpoints {
+		if e.ClientID == clientID {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
+
+func (m *mockWebhookEndpointRepository) ListActive(ctx context.Context) ([]*domain.WebhookEndpoint, error) {
+	var endpoints []*domain.WebhookEndpoint
+	for _, e := range m.endpoints {
+		if e.Active {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
+
+func (m *mockWebhookEndpointRepository) Update(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	for i, e := range m.endpoints {
+		if e.ID == endpoint.ID {
+			m.endpoints[i] = endpoint
+			return nil
+		}
+	}
+	return domain.ErrWebhookNotFound
+}
+
+func (m *mockWebhookEndpointRepository) Delete(ctx context.Context, id string) error {
+	for i, e := range m.endpoints {
+		if e.ID == id {
+			m.endpoints = slices.Delete(m.endpoints, i, i+1)
+			return nil
+		}
+	}
+	return domain.ErrWebhookNotFound
+}
+
+// モックWebhookDeliveryRepository
+type mockWebhookDeliveryRepository struct {
+	mu         sync.Mutex
+	deliveries []*domain.WebhookDelivery
+}
+
+func newMockWebhookDeliveryRepository() *mockWebhookDeliveryRepository {
+	return &mockWebhookDeliveryRepository{}
+}
+
+func (m *mockWebhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func (m *mockWebhookDeliveryRepository) GetByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			clone := *d
+			return &clone, nil
+		}
+	}
+	return nil, domain.ErrWebhookNotFound
+}
+
+func (m *mockWebhookDeliveryRepository) ListByEndpointID(ctx context.Context, endpointID string, limit int) ([]*domain.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deliveries []*domain.WebhookDelivery
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		if m.deliveries[i].EndpointID == endpointID && (limit <= 0 || len(deliveries) < limit) {
+			deliveries = append(deliveries, m.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *mockWebhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var claimed []*domain.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == domain.WebhookDeliveryPending && !d.NextAttemptAt.After(now) && len(claimed) < limit {
+			d.NextAttemptAt = now.Add(lease)
+			clone := *d
+			claimed = append(claimed, &clone)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *mockWebhookDeliveryRepository) Update(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.deliveries {
+		if d.ID == delivery.ID {
+			clone := *delivery
+			m.deliveries[i] = &clone
+			return nil
+		}
+	}
+	return domain.ErrWebhookNotFound
+}
+
+// recordingPublisher は通知された出来事を記録します
+type recordingPublisher struct {
+	events []*domain.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *domain.Event) {
+	p.events = append(p.events, event)
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 )
@@ -15,6 +18,9 @@ type Client struct {
 	config *oauth2.Config
 }
 
+// apiBaseURL はBot用REST APIのベースURLです（テストで差し替え可能）
+var apiBaseURL = "https://discord.com/api/v10"
+
 // DiscordエンドポイントのURL
 var discordEndpoint = oauth2.Endpoint{
 	AuthURL:  "https://discord.com/api/oauth2/authorize",
@@ -59,6 +65,7 @@ type User struct {
 	Discriminator string  `json:"discriminator"`
 	GlobalName    *string `json:"global_name"` // Display name
 	Avatar        *string `json:"avatar"`      // Avatar hash
+	Bot           bool    `json:"bot,omitempty"`
 }
 
 // GetAvatarURL はアバターのURLを返します
@@ -156,11 +163,30 @@ func (c *Client) IsMemberOfGuild(ctx context.Context, token *oauth2.Token, guild
 
 // Message はDiscordメッセージを表します
 type Message struct {
-	ID        string `json:"id"`
-	ChannelID string `json:"channel_id"`
-	Author    User   `json:"author"`
-	Content   string `json:"content"`
-	Timestamp string `json:"timestamp"`
+	ID              string  `json:"id"`
+	ChannelID       string  `json:"channel_id"`
+	Author          User    `json:"author"`
+	Content         string  `json:"content"`
+	Timestamp       string  `json:"timestamp"`
+	EditedTimestamp *string `json:"edited_timestamp"`
+}
+
+// PostedAt はメッセージの投稿日時を返します（パースできない場合はゼロ値）
+func (m *Message) PostedAt() time.Time {
+	t, _ := time.Parse(time.RFC3339, m.Timestamp)
+	return t
+}
+
+// EditedAt はメッセージの最終編集日時を返します（編集されていない場合はnil）
+func (m *Message) EditedAt() *time.Time {
+	if m.EditedTimestamp == nil || *m.EditedTimestamp == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *m.EditedTimestamp)
+	if err != nil {
+		return nil
+	}
+	return &t
 }
 
 // GetChannelMessages はチャンネルのメッセージを取得します（ページネーション非対応）
@@ -170,7 +196,7 @@ func GetChannelMessages(ctx context.Context, botToken, channelID string, limit i
 		limit = 100 // Discord APIの上限
 	}
 
-	url := fmt.Sprintf("https://discord.com/api/v10/channels/%s/messages?limit=%d", channelID, limit)
+	url := fmt.Sprintf("%s/channels/%s/messages?limit=%d", apiBaseURL, channelID, limit)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -209,7 +235,7 @@ func GetAllChannelMessages(ctx context.Context, botToken, channelID string, maxM
 
 	for {
 		// URLを構築
-		url := fmt.Sprintf("https://discord.com/api/v10/channels/%s/messages?limit=%d", channelID, batchSize)
+		url := fmt.Sprintf("%s/channels/%s/messages?limit=%d", apiBaseURL, channelID, batchSize)
 		if beforeID != "" {
 			url += fmt.Sprintf("&before=%s", beforeID)
 		}
@@ -266,3 +292,53 @@ func GetAllChannelMessages(ctx context.Context, botToken, channelID string, maxM
 
 	return allMessages, nil
 }
+
+// GetChannelMessagesAfter はafterIDより新しいチャンネルのメッセージをすべて取得します（ページネーション対応）
+// 返り値はメッセージID（投稿順）の昇順に並びます
+func GetChannelMessagesAfter(ctx context.Context, botToken, channelID, afterID string) ([]*Message, error) {
+	var allMessages []*Message
+	batchSize := 100 // Discord APIの1回あたりの最大取得数
+
+	for {
+		url := fmt.Sprintf("%s/channels/%s/messages?limit=%d&after=%s", apiBaseURL, channelID, batchSize, afterID)
+
+		var messages []*Message
+		if err := getWithBotToken(ctx, botToken, url, &messages); err != nil {
+			return nil, fmt.Errorf("failed to get channel messages: %w", err)
+		}
+
+		if len(messages) == 0 {
+			break
+		}
+
+		allMessages = append(allMessages, messages...)
+
+		// 100件未満の場合は、これ以上メッセージがないので終了
+		if len(messages) < batchSize {
+			break
+		}
+
+		// 次のページのために最新のメッセージIDを保存
+		// （afterを指定した場合もレスポンスの並び順は保証されないため最大値を探す）
+		for _, msg := range messages {
+			if CompareSnowflakes(msg.ID, afterID) > 0 {
+				afterID = msg.ID
+			}
+		}
+	}
+
+	sort.Slice(allMessages, func(i, j int) bool {
+		return CompareSnowflakes(allMessages[i].ID, allMessages[j].ID) < 0
+	})
+
+	return allMessages, nil
+}
+
+// CompareSnowflakes は2つのスノーフレークIDを数値として比較します
+// a < b なら負、a == b なら0、a > b なら正の値を返します
+func CompareSnowflakes(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
@@ -1,6 +1,11 @@
 package discord
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 )
 
@@ -94,3 +99,86 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+// TestCompareSnowflakes はスノーフレークIDが数値として比較されることを確認します
+func TestCompareSnowflakes(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // 符号のみ比較
+	}{
+		{"100", "100", 0},
+		{"99", "100", -1},
+		{"1000000000000000001", "999999999999999999", 1},
+		{"1234567890123456789", "1234567890123456788", 1},
+	}
+
+	for _, tt := range tests {
+		got := CompareSnowflakes(tt.a, tt.b)
+		if (got < 0 && tt.want >= 0) || (got > 0 && tt.want <= 0) || (got == 0 && tt.want != 0) {
+			t.Errorf("CompareSnowflakes(%s, %s) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestGetChannelMessagesAfter はafter指定で新しいメッセージだけを昇順で取得することを確認します
+func TestGetChannelMessagesAfter(t *testing.T) {
+	const latest = 250
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after, _ := strconv.Atoi(r.URL.Query().Get("after"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		// Discordと同様に新しい順で返す
+		messages := []*Message{}
+		end := after + limit
+		if end > latest {
+			end = latest
+		}
+		for i := end; i > after; i-- {
+			messages = append(messages, &Message{ID: strconv.Itoa(i)})
+		}
+		json.NewEncoder(w).Encode(messages)
+	}))
+	defer server.Close()
+	setAPIBaseURL(t, server.URL)
+
+	messages, err := GetChannelMessagesAfter(context.Background(), "test-token", "channel-1", "120")
+	if err != nil {
+		t.Fatalf("GetChannelMessagesAfter failed: %v", err)
+	}
+
+	if len(messages) != latest-120 {
+		t.Fatalf("messages length = %d, want %d", len(messages), latest-120)
+	}
+	if messages[0].ID != "121" || messages[len(messages)-1].ID != strconv.Itoa(latest) {
+		t.Errorf("messages not sorted ascending: first=%s last=%s", messages[0].ID, messages[len(messages)-1].ID)
+	}
+}
+
+// TestMessage_EditedAt はedited_timestampのデコードとパースを確認します
+func TestMessage_EditedAt(t *testing.T) {
+	var msg Message
+	data := `{"id":"1","timestamp":"2024-04-01T12:00:00.000000+00:00","edited_timestamp":"2024-04-02T08:30:00.123000+00:00"}`
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if msg.PostedAt().IsZero() {
+		t.Error("Expected PostedAt to be parsed")
+	}
+	editedAt := msg.EditedAt()
+	if editedAt == nil {
+		t.Fatal("Expected EditedAt to be set")
+	}
+	if editedAt.Day() != 2 || editedAt.Hour() != 8 {
+		t.Errorf("unexpected EditedAt: %v", editedAt)
+	}
+
+	var unedited Message
+	if err := json.Unmarshal([]byte(`{"id":"2","timestamp":"2024-04-01T12:00:00+00:00","edited_timestamp":null}`), &unedited); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if unedited.EditedAt() != nil {
+		t.Errorf("Expected nil EditedAt, got %v", unedited.EditedAt())
+	}
+}
@@ -0,0 +1,503 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gateway Intents
+// https://discord.com/developers/docs/topics/gateway#gateway-intents
+const (
+	IntentGuilds         = 1 << 0
+	IntentGuildMembers   = 1 << 1  // 特権Intent（Server Members Intent）
+	IntentGuildMessages  = 1 << 9  // サーバー内メッセージのイベント
+	IntentMessageContent = 1 << 15 // 特権Intent（Message Content Intent）
+)
+
+// Gatewayのオペコード
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opResume         = 6
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatAck   = 11
+)
+
+const (
+	gatewayVersionQuery   = "?v=10&encoding=json"
+	defaultGatewayURL     = "wss://gateway.discord.gg"
+	initialReconnectDelay = 1 * time.Second
+	maxReconnectBackoff   = 60 * time.Second
+)
+
+// gatewayPayload はGatewayで送受信されるメッセージです
+type gatewayPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// MessageDeleteEvent はMESSAGE_DELETEイベントのデータです
+type MessageDeleteEvent struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+}
+
+// GuildMemberEvent はGUILD_MEMBER_ADD/GUILD_MEMBER_UPDATEイベントのデータです
+type GuildMemberEvent struct {
+	GuildMember
+	GuildID string `json:"guild_id"`
+}
+
+// GuildMemberRemoveEvent はGUILD_MEMBER_REMOVEイベントのデータです
+type GuildMemberRemoveEvent struct {
+	GuildID string `json:"guild_id"`
+	User    User   `json:"user"`
+}
+
+// GatewayHandlers はGatewayイベントのハンドラーです
+// 必要なイベントのみ設定します（nilのハンドラーは無視されます）
+// ハンドラーは受信順に1つずつ呼び出されます
+type GatewayHandlers struct {
+	MessageCreate     func(ctx context.Context, msg *Message)
+	MessageUpdate     func(ctx context.Context, msg *Message)
+	MessageDelete     func(ctx context.Context, event *MessageDeleteEvent)
+	GuildMemberAdd    func(ctx context.Context, event *GuildMemberEvent)
+	GuildMemberUpdate func(ctx context.Context, event *GuildMemberEvent)
+	GuildMemberRemove func(ctx context.Context, event *GuildMemberRemoveEvent)
+}
+
+// Gateway はDiscord Gateway（WebSocket）の接続を管理します
+// 切断時は再開（Resume）を試み、再開できない場合は新しいセッションを確立します
+type Gateway struct {
+	botToken string
+	intents  int
+	handlers *GatewayHandlers
+
+	// URLはテストで差し替え可能です（空の場合はGET /gateway/botで取得）
+	URL string
+
+	sessionID string
+	resumeURL string
+	sequence  int64
+	seqMu     sync.Mutex
+
+	conn   *websocket.Conn
+	sendMu sync.Mutex
+}
+
+// errReconnect は再接続（可能であればResume）が必要なことを表します
+var errReconnect = errors.New("gateway reconnect requested")
+
+// GatewayCloseError はDiscordからのクローズコードを表します
+type GatewayCloseError struct {
+	Code int
+	Text string
+}
+
+func (e *GatewayCloseError) Error() string {
+	return fmt.Sprintf("gateway closed with code %d: %s", e.Code, e.Text)
+}
+
+// Fatal は再接続しても回復しないクローズコードかどうかを返します
+// （認証失敗、無効なIntent、許可されていないIntentなど）
+func (e *GatewayCloseError) Fatal() bool {
+	switch e.Code {
+	case 4004, 4010, 4011, 4012, 4013, 4014:
+		return true
+	}
+	return false
+}
+
+// resumable はセッションを再開できるクローズコードかどうかを返します
+func (e *GatewayCloseError) resumable() bool {
+	switch e.Code {
+	case 4007, 4009: // 無効なシーケンス番号、セッションタイムアウト
+		return false
+	}
+	return !e.Fatal()
+}
+
+// NewGateway は新しいGateway接続を作成します
+func NewGateway(botToken string, intents int, handlers *GatewayHandlers) *Gateway {
+	return &Gateway{
+		botToken: botToken,
+		intents:  intents,
+		handlers: handlers,
+	}
+}
+
+// Run はctxがキャンセルされるまでGatewayに接続し、イベントを処理し続けます
+// 切断された場合は指数バックオフで再接続します。回復不能なエラーの場合はエラーを返します
+func (g *Gateway) Run(ctx context.Context) error {
+	delay := initialReconnectDelay
+
+	for {
+		connected, err := g.runSession(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var closeErr *GatewayCloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Fatal() {
+				return fmt.Errorf("gateway connection failed: %w", err)
+			}
+			if !closeErr.resumable() {
+				g.resetSession()
+			}
+		}
+
+		// 一度接続に成功していればバックオフをリセット
+		if connected {
+			delay = initialReconnectDelay
+		}
+
+		log.Printf("Gateway disconnected: %v (reconnecting in %v)", err, delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnectBackoff {
+			delay = maxReconnectBackoff
+		}
+	}
+}
+
+// runSession は1回分の接続を確立し、切断されるまでイベントを処理します
+// 返り値のconnectedはHelloを受信できたかどうかを表します
+func (g *Gateway) runSession(ctx context.Context) (connected bool, err error) {
+	url, err := g.gatewayURL(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url+gatewayVersionQuery, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to dial gateway: %w", err)
+	}
+	defer conn.Close()
+
+	g.sendMu.Lock()
+	g.conn = conn
+	g.sendMu.Unlock()
+
+	// ctxがキャンセルされたら接続を閉じて読み込みを終了させる
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-sessionCtx.Done()
+		conn.Close()
+	}()
+
+	// 最初にHelloを受信する
+	hello, err := g.readPayload(conn)
+	if err != nil {
+		return false, err
+	}
+	if hello.Op != opHello {
+		return false, fmt.Errorf("expected hello, got op %d", hello.Op)
+	}
+
+	var helloData struct {
+		HeartbeatInterval int `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(hello.D, &helloData); err != nil {
+		return false, fmt.Errorf("failed to decode hello: %w", err)
+	}
+
+	acks := make(chan struct{}, 1)
+	heartbeatErr := make(chan error, 1)
+	go g.heartbeat(sessionCtx, time.Duration(helloData.HeartbeatInterval)*time.Millisecond, acks, heartbeatErr)
+
+	// セッションがあれば再開、なければ認証
+	if g.sessionID != "" {
+		err = g.sendResume()
+	} else {
+		err = g.sendIdentify()
+	}
+	if err != nil {
+		return true, err
+	}
+
+	for {
+		payload, err := g.readPayload(conn)
+		if err != nil {
+			select {
+			case hbErr := <-heartbeatErr:
+				return true, hbErr
+			default:
+			}
+			return true, err
+		}
+
+		switch payload.Op {
+		case opDispatch:
+			if payload.S != nil {
+				g.setSequence(*payload.S)
+			}
+			g.dispatch(ctx, payload.T, payload.D)
+		case opHeartbeat:
+			if err := g.sendHeartbeat(); err != nil {
+				return true, err
+			}
+		case opHeartbeatAck:
+			select {
+			case acks <- struct{}{}:
+			default:
+			}
+		case opReconnect:
+			return true, errReconnect
+		case opInvalidSession:
+			var resumable bool
+			_ = json.Unmarshal(payload.D, &resumable)
+			if !resumable {
+				g.resetSession()
+			}
+			// Discordの推奨に従い1〜5秒待ってから再接続
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(1000+rand.Intn(4000)) * time.Millisecond):
+			}
+			return true, errReconnect
+		}
+	}
+}
+
+// gatewayURL は接続先のURLを返します（再開時はresume_gateway_urlを優先）
+func (g *Gateway) gatewayURL(ctx context.Context) (string, error) {
+	if g.sessionID != "" && g.resumeURL != "" {
+		return g.resumeURL, nil
+	}
+	if g.URL != "" {
+		return g.URL, nil
+	}
+
+	var resp struct {
+		URL string `json:"url"`
+	}
+	if err := getWithBotToken(ctx, g.botToken, apiBaseURL+"/gateway/bot", &resp); err != nil {
+		return "", fmt.Errorf("failed to get gateway url: %w", err)
+	}
+	if resp.URL == "" {
+		return defaultGatewayURL, nil
+	}
+	return resp.URL, nil
+}
+
+// heartbeat はinterval毎にハートビートを送信します
+// 前回のハートビートにACKが返ってこない場合は接続が死んでいるとみなして切断します
+func (g *Gateway) heartbeat(ctx context.Context, interval time.Duration, acks <-chan struct{}, errc chan<- error) {
+	if interval <= 0 {
+		return
+	}
+
+	// 最初のハートビートはinterval * jitter後に送信
+	timer := time.NewTimer(time.Duration(rand.Float64() * float64(interval)))
+	defer timer.Stop()
+
+	acked := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-acks:
+			acked = true
+		case <-timer.C:
+			if !acked {
+				errc <- errReconnect
+				g.closeConn()
+				return
+			}
+			if err := g.sendHeartbeat(); err != nil {
+				errc <- err
+				g.closeConn()
+				return
+			}
+			acked = false
+			timer.Reset(interval)
+		}
+	}
+}
+
+// dispatch はイベント種別に応じてハンドラーを呼び出します
+func (g *Gateway) dispatch(ctx context.Context, eventType string, data json.RawMessage) {
+	if eventType == "READY" {
+		var ready struct {
+			SessionID        string `json:"session_id"`
+			ResumeGatewayURL string `json:"resume_gateway_url"`
+		}
+		if err := json.Unmarshal(data, &ready); err == nil {
+			g.sessionID = ready.SessionID
+			g.resumeURL = ready.ResumeGatewayURL
+		}
+		log.Println("Gateway session ready")
+		return
+	}
+	if eventType == "RESUMED" {
+		log.Println("Gateway session resumed")
+		return
+	}
+
+	if g.handlers == nil {
+		return
+	}
+
+	switch eventType {
+	case "MESSAGE_CREATE":
+		if g.handlers.MessageCreate != nil {
+			var msg Message
+			if decodeEvent(eventType, data, &msg) {
+				g.handlers.MessageCreate(ctx, &msg)
+			}
+		}
+	case "MESSAGE_UPDATE":
+		if g.handlers.MessageUpdate != nil {
+			var msg Message
+			if decodeEvent(eventType, data, &msg) {
+				g.handlers.MessageUpdate(ctx, &msg)
+			}
+		}
+	case "MESSAGE_DELETE":
+		if g.handlers.MessageDelete != nil {
+			var event MessageDeleteEvent
+			if decodeEvent(eventType, data, &event) {
+				g.handlers.MessageDelete(ctx, &event)
+			}
+		}
+	case "GUILD_MEMBER_ADD":
+		if g.handlers.GuildMemberAdd != nil {
+			var event GuildMemberEvent
+			if decodeEvent(eventType, data, &event) {
+				g.handlers.GuildMemberAdd(ctx, &event)
+			}
+		}
+	case "GUILD_MEMBER_UPDATE":
+		if g.handlers.GuildMemberUpdate != nil {
+			var event GuildMemberEvent
+			if decodeEvent(eventType, data, &event) {
+				g.handlers.GuildMemberUpdate(ctx, &event)
+			}
+		}
+	case "GUILD_MEMBER_REMOVE":
+		if g.handlers.GuildMemberRemove != nil {
+			var event GuildMemberRemoveEvent
+			if decodeEvent(eventType, data, &event) {
+				g.handlers.GuildMemberRemove(ctx, &event)
+			}
+		}
+	}
+}
+
+// decodeEvent はイベントデータをデコードします（失敗した場合はログに記録してfalseを返します）
+func decodeEvent(eventType string, data json.RawMessage, v interface{}) bool {
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Printf("Failed to decode gateway event %s: %v", eventType, err)
+		return false
+	}
+	return true
+}
+
+// readPayload は1件のペイロードを読み込みます
+// クローズフレームを受信した場合はGatewayCloseErrorを返します
+func (g *Gateway) readPayload(conn *websocket.Conn) (*gatewayPayload, error) {
+	var payload gatewayPayload
+	if err := conn.ReadJSON(&payload); err != nil {
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			return nil, &GatewayCloseError{Code: closeErr.Code, Text: closeErr.Text}
+		}
+		return nil, fmt.Errorf("failed to read gateway payload: %w", err)
+	}
+	return &payload, nil
+}
+
+func (g *Gateway) sendIdentify() error {
+	return g.send(opIdentify, map[string]interface{}{
+		"token":   g.botToken,
+		"intents": g.intents,
+		"properties": map[string]string{
+			"os":      runtime.GOOS,
+			"browser": "jyogi-discord-auth",
+			"device":  "jyogi-discord-auth",
+		},
+	})
+}
+
+func (g *Gateway) sendResume() error {
+	return g.send(opResume, map[string]interface{}{
+		"token":      g.botToken,
+		"session_id": g.sessionID,
+		"seq":        g.getSequence(),
+	})
+}
+
+func (g *Gateway) sendHeartbeat() error {
+	seq := g.getSequence()
+	if seq == 0 {
+		return g.send(opHeartbeat, nil)
+	}
+	return g.send(opHeartbeat, seq)
+}
+
+// send はペイロードを送信します（書き込みは同時に1つまで）
+func (g *Gateway) send(op int, data interface{}) error {
+	d, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode gateway payload: %w", err)
+	}
+
+	g.sendMu.Lock()
+	defer g.sendMu.Unlock()
+
+	if g.conn == nil {
+		return errors.New("gateway is not connected")
+	}
+	if err := g.conn.WriteJSON(&gatewayPayload{Op: op, D: d}); err != nil {
+		return fmt.Errorf("failed to send gateway payload: %w", err)
+	}
+	return nil
+}
+
+func (g *Gateway) closeConn() {
+	g.sendMu.Lock()
+	defer g.sendMu.Unlock()
+	if g.conn != nil {
+		g.conn.Close()
+	}
+}
+
+func (g *Gateway) resetSession() {
+	g.sessionID = ""
+	g.resumeURL = ""
+	g.setSequence(0)
+}
+
+func (g *Gateway) setSequence(seq int64) {
+	g.seqMu.Lock()
+	defer g.seqMu.Unlock()
+	g.sequence = seq
+}
+
+func (g *Gateway) getSequence() int64 {
+	g.seqMu.Lock()
+	defer g.seqMu.Unlock()
+	return g.sequence
+}
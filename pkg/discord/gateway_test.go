@@ -0,0 +1,156 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestGateway_IdentifyDispatchAndResume は認証・イベント配送・Reconnect後のResumeを確認します
+func TestGateway_IdentifyDispatchAndResume(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		connections int
+		identify    map[string]interface{}
+		resume      map[string]interface{}
+	)
+
+	upgrader := websocket.Upgrader{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		mu.Lock()
+		connections++
+		n := connections
+		mu.Unlock()
+
+		conn.WriteJSON(map[string]interface{}{"op": opHello, "d": map[string]int{"heartbeat_interval": 45000}})
+
+		var first gatewayPayload
+		if err := conn.ReadJSON(&first); err != nil {
+			return
+		}
+		var data map[string]interface{}
+		json.Unmarshal(first.D, &data)
+
+		wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+		if n == 1 {
+			if first.Op != opIdentify {
+				t.Errorf("expected identify, got op %d", first.Op)
+			}
+			mu.Lock()
+			identify = data
+			mu.Unlock()
+
+			conn.WriteJSON(map[string]interface{}{"op": opDispatch, "s": 1, "t": "READY", "d": map[string]string{"session_id": "session-1", "resume_gateway_url": wsURL}})
+			conn.WriteJSON(map[string]interface{}{"op": opDispatch, "s": 2, "t": "MESSAGE_CREATE", "d": map[string]interface{}{"id": "100", "channel_id": "channel-1", "content": "本名: じょぎ太郎", "author": map[string]string{"id": "discord-1"}}})
+			conn.WriteJSON(map[string]interface{}{"op": opReconnect})
+		} else {
+			if first.Op != opResume {
+				t.Errorf("expected resume, got op %d", first.Op)
+			}
+			mu.Lock()
+			resume = data
+			mu.Unlock()
+
+			conn.WriteJSON(map[string]interface{}{"op": opDispatch, "s": 3, "t": "RESUMED", "d": map[string]string{}})
+			conn.WriteJSON(map[string]interface{}{"op": opDispatch, "s": 4, "t": "MESSAGE_DELETE", "d": map[string]string{"id": "100", "channel_id": "channel-1"}})
+		}
+
+		// クライアントが切断するまで待機
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var created *Message
+	var deleted *MessageDeleteEvent
+	handlers := &GatewayHandlers{
+		MessageCreate: func(ctx context.Context, msg *Message) {
+			created = msg
+		},
+		MessageDelete: func(ctx context.Context, event *MessageDeleteEvent) {
+			deleted = event
+			cancel()
+		},
+	}
+
+	gw := NewGateway("test-token", IntentGuildMessages|IntentMessageContent, handlers)
+	gw.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	if err := gw.Run(ctx); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if created == nil || created.ID != "100" || created.Author.ID != "discord-1" {
+		t.Errorf("unexpected MESSAGE_CREATE: %+v", created)
+	}
+	if deleted == nil || deleted.ID != "100" {
+		t.Errorf("unexpected MESSAGE_DELETE: %+v", deleted)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if identify["token"] != "test-token" {
+		t.Errorf("identify token = %v", identify["token"])
+	}
+	if int(identify["intents"].(float64)) != IntentGuildMessages|IntentMessageContent {
+		t.Errorf("identify intents = %v", identify["intents"])
+	}
+	if resume["session_id"] != "session-1" {
+		t.Errorf("resume session_id = %v", resume["session_id"])
+	}
+	if resume["seq"] != float64(2) {
+		t.Errorf("resume seq = %v, want 2", resume["seq"])
+	}
+}
+
+// TestGateway_FatalClose は回復不能なクローズコードでRunが終了することを確認します
+func TestGateway_FatalClose(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteJSON(map[string]interface{}{"op": opHello, "d": map[string]int{"heartbeat_interval": 45000}})
+		conn.ReadMessage()
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4014, "Disallowed intent(s)"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gw := NewGateway("test-token", IntentGuildMembers, nil)
+	gw.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	err := gw.Run(ctx)
+	if err == nil {
+		t.Fatal("Expected error for disallowed intents, got nil")
+	}
+	if !strings.Contains(err.Error(), "4014") {
+		t.Errorf("Expected close code 4014 in error, got %v", err)
+	}
+}
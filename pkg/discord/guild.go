@@ -0,0 +1,113 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// guildMembersPageSize はGET /guilds/{id}/members の1回あたりの最大取得数です
+const guildMembersPageSize = 1000
+
+// Role はDiscordサーバーのロールを表します
+type Role struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Color    int    `json:"color"`
+	Position int    `json:"position"`
+	Managed  bool   `json:"managed"`
+}
+
+// GetGuildRoles はサーバーのロール一覧を取得します
+// botTokenはBot認証用のトークンです
+func GetGuildRoles(ctx context.Context, botToken, guildID string) ([]*Role, error) {
+	url := fmt.Sprintf("%s/guilds/%s/roles", apiBaseURL, guildID)
+
+	var roles []*Role
+	if err := getWithBotToken(ctx, botToken, url, &roles); err != nil {
+		return nil, fmt.Errorf("failed to get guild roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// GetGuildMembers はサーバーのメンバーを1ページ分取得します
+// afterに指定したユーザーIDより後のメンバーを返します（空の場合は先頭から）
+// Botには Server Members Intent が必要です
+func GetGuildMembers(ctx context.Context, botToken, guildID, after string, limit int) ([]*GuildMember, error) {
+	if limit <= 0 || limit > guildMembersPageSize {
+		limit = guildMembersPageSize // Discord APIの上限
+	}
+
+	url := fmt.Sprintf("%s/guilds/%s/members?limit=%d", apiBaseURL, guildID, limit)
+	if after != "" {
+		url += fmt.Sprintf("&after=%s", after)
+	}
+
+	var members []*GuildMember
+	if err := getWithBotToken(ctx, botToken, url, &members); err != nil {
+		return nil, fmt.Errorf("failed to get guild members: %w", err)
+	}
+
+	return members, nil
+}
+
+// GetAllGuildMembers はサーバーのすべてのメンバーを取得します（ページネーション対応）
+func GetAllGuildMembers(ctx context.Context, botToken, guildID string) ([]*GuildMember, error) {
+	var allMembers []*GuildMember
+	var after string
+
+	for {
+		members, err := GetGuildMembers(ctx, botToken, guildID, after, guildMembersPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		allMembers = append(allMembers, members...)
+
+		// 上限未満の場合は、これ以上メンバーがないので終了
+		if len(members) < guildMembersPageSize {
+			break
+		}
+
+		// 次のページのために最後のユーザーIDを保存
+		last := members[len(members)-1]
+		if last.User == nil {
+			break
+		}
+		after = last.User.ID
+	}
+
+	return allMembers, nil
+}
+
+// getWithBotToken はBotトークンでGETリクエストを送り、レスポンスをvにデコードします
+func getWithBotToken(ctx context.Context, botToken, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bot %s", botToken))
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("discord API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
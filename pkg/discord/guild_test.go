@@ -0,0 +1,94 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// setAPIBaseURL はテスト中だけapiBaseURLを差し替えます
+func setAPIBaseURL(t *testing.T, url string) {
+	original := apiBaseURL
+	apiBaseURL = url
+	t.Cleanup(func() { apiBaseURL = original })
+}
+
+// TestGetAllGuildMembers はafterパラメータでページングされることを確認します
+func TestGetAllGuildMembers(t *testing.T) {
+	const total = guildMembersPageSize + 5
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot test-token" {
+			t.Errorf("unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path != "/guilds/guild-1/members" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		start := 0
+		if after := r.URL.Query().Get("after"); after != "" {
+			n, _ := strconv.Atoi(after)
+			start = n + 1
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		members := []*GuildMember{}
+		for i := start; i < total && len(members) < limit; i++ {
+			members = append(members, &GuildMember{User: &User{ID: strconv.Itoa(i), Username: fmt.Sprintf("user%d", i)}})
+		}
+		json.NewEncoder(w).Encode(members)
+	}))
+	defer server.Close()
+	setAPIBaseURL(t, server.URL)
+
+	members, err := GetAllGuildMembers(context.Background(), "test-token", "guild-1")
+	if err != nil {
+		t.Fatalf("GetAllGuildMembers failed: %v", err)
+	}
+
+	if len(members) != total {
+		t.Fatalf("members length = %d, want %d", len(members), total)
+	}
+	if members[total-1].User.ID != strconv.Itoa(total-1) {
+		t.Errorf("last member ID = %s, want %d", members[total-1].User.ID, total-1)
+	}
+}
+
+// TestGetGuildRoles はロール一覧がデコードされることを確認します
+func TestGetGuildRoles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"1","name":"@everyone","color":0,"position":0},{"id":"2","name":"部長","color":16711680,"position":5}]`))
+	}))
+	defer server.Close()
+	setAPIBaseURL(t, server.URL)
+
+	roles, err := GetGuildRoles(context.Background(), "test-token", "guild-1")
+	if err != nil {
+		t.Fatalf("GetGuildRoles failed: %v", err)
+	}
+
+	if len(roles) != 2 {
+		t.Fatalf("roles length = %d, want 2", len(roles))
+	}
+	if roles[1].Name != "部長" || roles[1].Color != 16711680 || roles[1].Position != 5 {
+		t.Errorf("unexpected role: %+v", roles[1])
+	}
+}
+
+// TestGetGuildRoles_Error はAPIエラー時にエラーを返すことを確認します
+func TestGetGuildRoles_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Missing Access"}`))
+	}))
+	defer server.Close()
+	setAPIBaseURL(t, server.URL)
+
+	if _, err := GetGuildRoles(context.Background(), "test-token", "guild-1"); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+}
@@ -1,10 +1,27 @@
 package discord
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
+// 既知のフィールドキー（ProfileDataの固定フィールドに対応）
+const (
+	FieldRealName  = "real_name"
+	FieldStudentID = "student_id"
+	FieldHobbies   = "hobbies"
+	FieldWhatToDo  = "what_to_do"
+	FieldComment   = "comment"
+)
+
+// maxUnknownLabelLength は未定義のラベルとして扱う最大文字数です
+const maxUnknownLabelLength = 20
+
 // ProfileData は自己紹介から抽出したプロフィールデータを表します
 type ProfileData struct {
 	RealName  string
@@ -12,53 +29,295 @@ type ProfileData struct {
 	Hobbies   string
 	WhatToDo  string
 	Comment   string
+
+	// Extra は固定フィールド以外の項目（テンプレートで定義したカスタム項目や未定義のラベル）です
+	Extra map[string]string
+
+	// InvalidFields はバリデーションに失敗して破棄されたフィールドのキーです
+	InvalidFields []string
+}
+
+// FieldDefinition は自己紹介テンプレートの1項目の定義です
+type FieldDefinition struct {
+	// Key は保存先のキーです（real_name等の既知のキー以外はExtraに保存されます）
+	Key string `json:"key"`
+	// Label は項目の見出しです（例: 本名）
+	Label string `json:"label"`
+	// Aliases はLabelの別表記です（例: 名前, お名前）
+	Aliases []string `json:"aliases,omitempty"`
+	// Multiline がtrueの場合、次の見出しまでの複数行を値として取り込みます
+	Multiline bool `json:"multiline,omitempty"`
+	// Pattern は値が満たすべき正規表現です（空の場合は検証しない）
+	Pattern string `json:"pattern,omitempty"`
+	// MaxLength は値の最大文字数です（0の場合は制限なし）
+	MaxLength int `json:"max_length,omitempty"`
+
+	pattern *regexp.Regexp
 }
 
-// ParseProfile は自己紹介メッセージからプロフィール情報をパースします
-// フォーマット例:
+// ProfileTemplate は自己紹介メッセージの書式定義です
+type ProfileTemplate struct {
+	// Bullets は行頭の記号です（例: ⭕, ○, •）
+	Bullets []string `json:"bullets"`
+	// Fields は項目の定義です
+	Fields []FieldDefinition `json:"fields"`
+	// CaptureUnknown がtrueの場合、記号付きの「見出し: 値」形式の未定義の行もExtraに保存します
+	CaptureUnknown bool `json:"capture_unknown,omitempty"`
+
+	labels []templateLabel
+}
+
+// templateLabel は見出し文字列と対応するフィールド定義です
+type templateLabel struct {
+	text  string
+	field *FieldDefinition
+}
+
+// DefaultProfileTemplate はじょぎの標準の自己紹介テンプレートを返します
 // ⭕本名:じょぎ太郎
 // ⭕学籍番号:20X1234
 // ⭕趣味:アニメ鑑賞
 // ⭕じょぎでやりたいこと:ゲーム作成
 // ⭕ひとこと:よろしくお願いします！
+func DefaultProfileTemplate() *ProfileTemplate {
+	t := &ProfileTemplate{
+		Bullets: []string{"⭕", "○", "◯", "•", "・", "-", "*"},
+		Fields: []FieldDefinition{
+			{Key: FieldRealName, Label: "本名"},
+			{Key: FieldStudentID, Label: "学籍番号"},
+			{Key: FieldHobbies, Label: "趣味", Multiline: true},
+			{Key: FieldWhatToDo, Label: "じょぎでやりたいこと", Multiline: true},
+			{Key: FieldComment, Label: "ひとこと", Multiline: true},
+		},
+	}
+	// 組み込みの定義は必ずコンパイルできる
+	_ = t.compile()
+	return t
+}
+
+// LoadProfileTemplate はJSON形式のフィールド定義ファイルからテンプレートを読み込みます
+func LoadProfileTemplate(path string) (*ProfileTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile template: %w", err)
+	}
+
+	return ParseProfileTemplate(data)
+}
+
+// ParseProfileTemplate はJSON形式のフィールド定義からテンプレートを作成します
+func ParseProfileTemplate(data []byte) (*ProfileTemplate, error) {
+	var t ProfileTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode profile template: %w", err)
+	}
+
+	if err := t.compile(); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// compile は定義を検証し、見出しの照合と値の検証の準備をします
+func (t *ProfileTemplate) compile() error {
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("profile template must define at least one field")
+	}
+
+	t.labels = nil
+	seen := make(map[string]bool)
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.Key == "" || f.Label == "" {
+			return fmt.Errorf("field %d: key and label are required", i)
+		}
+		if seen[f.Key] {
+			return fmt.Errorf("field %s: duplicate key", f.Key)
+		}
+		seen[f.Key] = true
+
+		if f.Pattern != "" {
+			re, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				return fmt.Errorf("field %s: invalid pattern: %w", f.Key, err)
+			}
+			f.pattern = re
+		}
+
+		for _, label := range append([]string{f.Label}, f.Aliases...) {
+			t.labels = append(t.labels, templateLabel{text: label, field: f})
+		}
+	}
+
+	// 「趣味」と「趣味・特技」のように前方一致する見出しがあるため、長いものから照合する
+	sort.SliceStable(t.labels, func(i, j int) bool {
+		return len(t.labels[i].text) > len(t.labels[j].text)
+	})
+
+	return nil
+}
+
+// ParseProfile は標準テンプレートで自己紹介メッセージからプロフィール情報をパースします
 func ParseProfile(content string) *ProfileData {
+	return DefaultProfileTemplate().Parse(content)
+}
+
+// Parse はテンプレートに従って自己紹介メッセージからプロフィール情報をパースします
+// 見出しは行頭（記号・空白の後）にあり、半角または全角のコロンで値と区切られている必要があります
+// 複数行の項目は次の見出しまで、それ以外の項目はその行のみを値とします
+func (t *ProfileTemplate) Parse(content string) *ProfileData {
+	values := make(map[string]string)
+	var order []string
+
+	var currentKey string
+	var currentField *FieldDefinition
+	var currentLines []string
+
+	flush := func() {
+		if currentKey == "" {
+			return
+		}
+		value := strings.TrimSpace(strings.Join(currentLines, "\n"))
+		if _, exists := values[currentKey]; !exists && value != "" {
+			values[currentKey] = value
+			order = append(order, currentKey)
+		}
+		currentKey = ""
+		currentField = nil
+		currentLines = nil
+	}
+
+	for _, rawLine := range strings.Split(content, "\n") {
+		line, hasBullet := t.stripBullet(rawLine)
+
+		if field, value, ok := t.matchLabel(line); ok {
+			flush()
+			currentKey = field.Key
+			currentField = field
+			currentLines = []string{value}
+			continue
+		}
+
+		if t.CaptureUnknown && hasBullet {
+			if label, value, ok := matchUnknownLabel(line); ok {
+				flush()
+				currentKey = label
+				currentLines = []string{value}
+				continue
+			}
+		}
+
+		// 見出しでない行は、複数行の項目であれば値に追加し、そうでなければ項目を終える
+		if currentField != nil && currentField.Multiline {
+			currentLines = append(currentLines, strings.TrimRight(rawLine, " \t\r"))
+			continue
+		}
+		flush()
+	}
+	flush()
+
 	profile := &ProfileData{}
+	for _, key := range order {
+		value := values[key]
+		field := t.field(key)
+		if field != nil && !field.valid(value) {
+			profile.InvalidFields = append(profile.InvalidFields, key)
+			continue
+		}
 
-	// 各フィールドを抽出する正規表現パターン
-	patterns := map[string]*regexp.Regexp{
-		"real_name":  regexp.MustCompile(`(?:⭕|○|◯)?本名\s*[:：]\s*(.+?)(?:\n|$)`),
-		"student_id": regexp.MustCompile(`(?:⭕|○|◯)?学籍番号\s*[:：]\s*(.+?)(?:\n|$)`),
-		"hobbies":    regexp.MustCompile(`(?:⭕|○|◯)?趣味\s*[:：]\s*(.+?)(?:\n|$)`),
-		"what_to_do": regexp.MustCompile(`(?:⭕|○|◯)?じょぎでやりたいこと\s*[:：]\s*(.+?)(?:\n|$)`),
-		"comment":    regexp.MustCompile(`(?:⭕|○|◯)?ひとこと\s*[:：]\s*(.+?)(?:\n|$)`),
+		switch key {
+		case FieldRealName:
+			profile.RealName = value
+		case FieldStudentID:
+			profile.StudentID = value
+		case FieldHobbies:
+			profile.Hobbies = value
+		case FieldWhatToDo:
+			profile.WhatToDo = value
+		case FieldComment:
+			profile.Comment = value
+		default:
+			if profile.Extra == nil {
+				profile.Extra = make(map[string]string)
+			}
+			profile.Extra[key] = value
+		}
 	}
 
-	// 本名を抽出
-	if match := patterns["real_name"].FindStringSubmatch(content); len(match) > 1 {
-		profile.RealName = strings.TrimSpace(match[1])
+	return profile
+}
+
+// stripBullet は行頭の空白と記号を取り除きます
+func (t *ProfileTemplate) stripBullet(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	for _, bullet := range t.Bullets {
+		if bullet != "" && strings.HasPrefix(line, bullet) {
+			return strings.TrimSpace(strings.TrimPrefix(line, bullet)), true
+		}
 	}
+	return line, false
+}
 
-	// 学籍番号を抽出
-	if match := patterns["student_id"].FindStringSubmatch(content); len(match) > 1 {
-		profile.StudentID = strings.TrimSpace(match[1])
+// matchLabel は行が定義済みの見出しで始まっていれば、そのフィールドと値を返します
+func (t *ProfileTemplate) matchLabel(line string) (*FieldDefinition, string, bool) {
+	for _, label := range t.labels {
+		if !strings.HasPrefix(line, label.text) {
+			continue
+		}
+		if value, ok := cutSeparator(line[len(label.text):]); ok {
+			return label.field, value, true
+		}
 	}
+	return nil, "", false
+}
 
-	// 趣味を抽出
-	if match := patterns["hobbies"].FindStringSubmatch(content); len(match) > 1 {
-		profile.Hobbies = strings.TrimSpace(match[1])
+// field はキーに対応するフィールド定義を返します
+func (t *ProfileTemplate) field(key string) *FieldDefinition {
+	for i := range t.Fields {
+		if t.Fields[i].Key == key {
+			return &t.Fields[i]
+		}
 	}
+	return nil
+}
 
-	// じょぎでやりたいことを抽出
-	if match := patterns["what_to_do"].FindStringSubmatch(content); len(match) > 1 {
-		profile.WhatToDo = strings.TrimSpace(match[1])
+// valid は値がフィールドの検証ルールを満たすかどうかを確認します
+func (f *FieldDefinition) valid(value string) bool {
+	if f.MaxLength > 0 && utf8.RuneCountInString(value) > f.MaxLength {
+		return false
+	}
+	if f.pattern != nil && !f.pattern.MatchString(value) {
+		return false
 	}
+	return true
+}
 
-	// ひとことを抽出
-	if match := patterns["comment"].FindStringSubmatch(content); len(match) > 1 {
-		profile.Comment = strings.TrimSpace(match[1])
+// matchUnknownLabel は「見出し: 値」形式の行を見出しと値に分けます
+func matchUnknownLabel(line string) (string, string, bool) {
+	idx := strings.IndexAny(line, ":：")
+	if idx <= 0 {
+		return "", "", false
 	}
 
-	return profile
+	label := strings.TrimSpace(line[:idx])
+	if label == "" || utf8.RuneCountInString(label) > maxUnknownLabelLength || strings.Contains(label, "//") {
+		return "", "", false
+	}
+
+	value, _ := cutSeparator(line[idx:])
+	return label, value, true
+}
+
+// cutSeparator は先頭の空白とコロン（半角・全角）を取り除いた値を返します
+func cutSeparator(rest string) (string, bool) {
+	rest = strings.TrimLeft(rest, " \t　")
+	for _, sep := range []string{":", "："} {
+		if strings.HasPrefix(rest, sep) {
+			return strings.TrimSpace(strings.TrimPrefix(rest, sep)), true
+		}
+	}
+	return "", false
 }
 
 // IsValidProfile はパースされたプロフィールが有効かどうかを確認します
@@ -68,5 +327,6 @@ func (p *ProfileData) IsValidProfile() bool {
 		p.StudentID != "" ||
 		p.Hobbies != "" ||
 		p.WhatToDo != "" ||
-		p.Comment != ""
+		p.Comment != "" ||
+		len(p.Extra) > 0
 }
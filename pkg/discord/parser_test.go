@@ -189,3 +189,88 @@ func TestIsValidProfile(t *testing.T) {
 		})
 	}
 }
+
+func TestParseProfile_Multiline(t *testing.T) {
+	content := `⭕本名:じょぎ太郎
+⭕趣味:
+・カラオケ
+・ゲーム
+⭕ひとこと:よろしくお願いします！
+春からプログラミングを始めました。`
+
+	result := ParseProfile(content)
+
+	if result.RealName != "じょぎ太郎" {
+		t.Errorf("RealName = %v, want じょぎ太郎", result.RealName)
+	}
+	if result.Hobbies != "・カラオケ\n・ゲーム" {
+		t.Errorf("Hobbies = %q, want multi-line value", result.Hobbies)
+	}
+	if result.Comment != "よろしくお願いします！\n春からプログラミングを始めました。" {
+		t.Errorf("Comment = %q, want multi-line value", result.Comment)
+	}
+}
+
+func TestProfileTemplate_CustomFields(t *testing.T) {
+	template, err := ParseProfileTemplate([]byte(`{
+		"bullets": ["▶"],
+		"capture_unknown": true,
+		"fields": [
+			{"key": "real_name", "label": "名前", "aliases": ["本名", "お名前"]},
+			{"key": "student_id", "label": "学籍番号", "pattern": "^[0-9]{2}[A-Z][0-9]{4}$"},
+			{"key": "hometown", "label": "出身", "max_length": 10},
+			{"key": "comment", "label": "ひとこと", "multiline": true}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseProfileTemplate failed: %v", err)
+	}
+
+	content := `▶お名前：じょぎ花子
+▶学籍番号：ひみつ
+▶出身：福岡
+▶好きな言語：Go
+▶ひとこと：よろしく
+お願いします`
+
+	result := template.Parse(content)
+
+	if result.RealName != "じょぎ花子" {
+		t.Errorf("RealName = %v, want じょぎ花子 (alias)", result.RealName)
+	}
+	if result.StudentID != "" {
+		t.Errorf("StudentID = %v, want empty (failed validation)", result.StudentID)
+	}
+	if len(result.InvalidFields) != 1 || result.InvalidFields[0] != "student_id" {
+		t.Errorf("InvalidFields = %v, want [student_id]", result.InvalidFields)
+	}
+	if result.Extra["hometown"] != "福岡" {
+		t.Errorf("Extra[hometown] = %v, want 福岡", result.Extra["hometown"])
+	}
+	if result.Extra["好きな言語"] != "Go" {
+		t.Errorf("Extra[好きな言語] = %v, want Go (unknown label)", result.Extra["好きな言語"])
+	}
+	if result.Comment != "よろしく\nお願いします" {
+		t.Errorf("Comment = %q, want multi-line value", result.Comment)
+	}
+}
+
+func TestParseProfileTemplate_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"フィールドなし", `{"fields": []}`},
+		{"キーの重複", `{"fields": [{"key": "a", "label": "A"}, {"key": "a", "label": "B"}]}`},
+		{"不正な正規表現", `{"fields": [{"key": "a", "label": "A", "pattern": "("}]}`},
+		{"ラベルなし", `{"fields": [{"key": "a"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseProfileTemplate([]byte(tt.json)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
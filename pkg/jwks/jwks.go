@@ -0,0 +1,231 @@
+// Package jwks はJSON Web Key Set（RFC 7517）の署名検証用の公開鍵を読み込み・書き出します
+// RSA（2048ビット以上）とEC（P-256・P-384・P-521）の公開鍵に対応します
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+)
+
+// MaxSize は読み込むJWKS（JSON）の最大サイズです
+const MaxSize = 64 << 10
+
+// minRSABits はRSA鍵の最小の長さです
+const minRSABits = 2048
+
+// ErrNoUsableKey は署名検証に使用できる鍵が1つもない場合のエラーです
+var ErrNoUsableKey = errors.New("jwks: no usable signing key")
+
+// Key は署名検証用の公開鍵です
+type Key struct {
+	KeyID     string           // kid（省略可）
+	Algorithm string           // alg（省略可）
+	PublicKey crypto.PublicKey // *rsa.PublicKey または *ecdsa.PublicKey
+}
+
+// Set は公開鍵の集合です
+type Set struct {
+	Keys []Key
+}
+
+// jsonWebKey はJWKの各項目です（RFC 7517 4・RFC 7518 6）
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"`
+}
+
+// Parse はJWKS（{"keys":[...]}）を読み込みます
+// 署名用でない鍵（useがsig以外）と未対応の種類の鍵は無視します。秘密鍵を含む場合はエラーを返します
+func Parse(data []byte) (*Set, error) {
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("jwks: larger than %d bytes", MaxSize)
+	}
+	var raw struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("jwks: invalid JSON: %w", err)
+	}
+
+	set := &Set{}
+	for i, jwk := range raw.Keys {
+		if jwk.D != "" {
+			return nil, fmt.Errorf("jwks: key %d contains a private key", i)
+		}
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var (
+			publicKey crypto.PublicKey
+			err       error
+		)
+		switch jwk.Kty {
+		case "RSA":
+			publicKey, err = parseRSA(jwk)
+		case "EC":
+			publicKey, err = parseEC(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %d: %w", i, err)
+		}
+		set.Keys = append(set.Keys, Key{KeyID: jwk.Kid, Algorithm: jwk.Alg, PublicKey: publicKey})
+	}
+	if len(set.Keys) == 0 {
+		return nil, ErrNoUsableKey
+	}
+	return set, nil
+}
+
+// Fetch はURLからJWKSを取得して読み込みます
+func Fetch(ctx context.Context, client *http.Client, url string) (*Set, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: invalid URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("jwks: failed to read: %w", err)
+	}
+	return Parse(data)
+}
+
+// Lookup はkidが一致する鍵を返します（kidが空の場合はすべての鍵を返します）
+func (s *Set) Lookup(kid string) []Key {
+	if kid == "" {
+		return s.Keys
+	}
+	var keys []Key
+	for _, key := range s.Keys {
+		if key.KeyID == kid {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// MarshalJSON は公開鍵をJWKS（{"keys":[...]}）として書き出します
+func (s *Set) MarshalJSON() ([]byte, error) {
+	keys := make([]jsonWebKey, 0, len(s.Keys))
+	for _, key := range s.Keys {
+		jwk := jsonWebKey{Kid: key.KeyID, Alg: key.Algorithm, Use: "sig"}
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			point, err := publicKey.Bytes()
+			if err != nil {
+				return nil, fmt.Errorf("jwks: invalid EC key: %w", err)
+			}
+			size := (len(point) - 1) / 2
+			jwk.Kty = "EC"
+			jwk.Crv = publicKey.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(point[1 : 1+size])
+			jwk.Y = base64.RawURLEncoding.EncodeToString(point[1+size:])
+		default:
+			return nil, fmt.Errorf("jwks: unsupported key type %T", key.PublicKey)
+		}
+		keys = append(keys, jwk)
+	}
+	return json.Marshal(map[string]interface{}{"keys": keys})
+}
+
+// parseRSA はRSAの公開鍵（n・e）を読み込みます
+func parseRSA(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid n: %w", err)
+	}
+	e, err := decodeBigInt(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid e: %w", err)
+	}
+	if n.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("unsupported RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+// parseEC はECの公開鍵（crv・x・y）を読み込みます
+func parseEC(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y: %w", err)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, fmt.Errorf("invalid coordinate length for %s", jwk.Crv)
+	}
+
+	// 非圧縮形式（0x04 || x || y）にして曲線上の点か検証する
+	point := append([]byte{4}, x...)
+	point = append(point, y...)
+	publicKey, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EC point: %w", err)
+	}
+	return publicKey, nil
+}
+
+// decodeBigInt はBase64URL（パディングなし）の符号なし整数を読み込みます
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
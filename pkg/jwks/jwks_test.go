@@ -0,0 +1,116 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMarshalAndParse は公開鍵の書き出しと読み込みをテストします
+func TestMarshalAndParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+
+	data, err := (&Set{Keys: []Key{
+		{KeyID: "rsa-1", Algorithm: "RS256", PublicKey: &rsaKey.PublicKey},
+		{KeyID: "ec-1", Algorithm: "ES256", PublicKey: &ecKey.PublicKey},
+	}}).MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	set, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(set.Keys))
+	}
+	if keys := set.Lookup("rsa-1"); len(keys) != 1 || !rsaKey.PublicKey.Equal(keys[0].PublicKey) || keys[0].Algorithm != "RS256" {
+		t.Errorf("Unexpected RSA key: %+v", keys)
+	}
+	if keys := set.Lookup("ec-1"); len(keys) != 1 || !ecKey.PublicKey.Equal(keys[0].PublicKey) {
+		t.Errorf("Unexpected EC key: %+v", keys)
+	}
+	if keys := set.Lookup(""); len(keys) != 2 {
+		t.Errorf("Expected all keys without kid, got %d", len(keys))
+	}
+	if keys := set.Lookup("unknown"); len(keys) != 0 {
+		t.Errorf("Expected no keys for an unknown kid, got %d", len(keys))
+	}
+}
+
+// TestParse_Invalid は使用できないJWKSの読み込みをテストします
+func TestParse_Invalid(t *testing.T) {
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	small, _ := (&Set{Keys: []Key{{PublicKey: &smallKey.PublicKey}}}).MarshalJSON()
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"JSONでない", `keys`},
+		{"鍵なし", `{"keys":[]}`},
+		{"暗号化用の鍵のみ", `{"keys":[{"kty":"EC","use":"enc","crv":"P-256","x":"AA","y":"AA"}]}`},
+		{"未対応の種類のみ", `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`},
+		{"秘密鍵", `{"keys":[{"kty":"EC","crv":"P-256","x":"AA","y":"AA","d":"AA"}]}`},
+		{"曲線上にない点", `{"keys":[{"kty":"EC","crv":"P-256","x":"` + strings.Repeat("A", 43) + `","y":"` + strings.Repeat("A", 43) + `"}]}`},
+		{"短いRSA鍵", string(small)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+
+	if _, err := Parse([]byte(`{"keys":[]}`)); !errors.Is(err, ErrNoUsableKey) {
+		t.Errorf("Expected ErrNoUsableKey, got %v", err)
+	}
+}
+
+// TestFetch はURLからのJWKSの取得をテストします
+func TestFetch(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	data, _ := (&Set{Keys: []Key{{KeyID: "ec-1", PublicKey: &ecKey.PublicKey}}}).MarshalJSON()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jwks.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	set, err := Fetch(context.Background(), server.Client(), server.URL+"/jwks.json")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(set.Lookup("ec-1")) != 1 {
+		t.Errorf("Expected the fetched key, got %+v", set.Keys)
+	}
+	if _, err := Fetch(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Error("Expected error for a missing JWKS")
+	}
+}
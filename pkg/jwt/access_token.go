@@ -0,0 +1,208 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/jyogi-web/jyogi-discord-auth/pkg/jwks"
+)
+
+// AccessTokenType はJWT形式のアクセストークンのtypヘッダーです（RFC 9068 2.1）
+const AccessTokenType = "at+jwt"
+
+// accessTokenLeeway はアクセストークンの時刻の検証で許容するずれです
+const accessTokenLeeway = 30 * time.Second
+
+// AccessToken はJWT形式のアクセストークンの内容です
+type AccessToken struct {
+	ID        string   // jti（取り消しの確認に使用します）
+	Issuer    string   // iss
+	Subject   string   // sub（ユーザーID）
+	Audience  []string // aud（リソースサーバーは自分宛てのトークンかを確認します）
+	ClientID  string   // client_id
+	Scope     string   // scope（スペース区切り）
+	Roles     []string // roles（ロール名）
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// accessTokenClaims はアクセストークンのクレームです
+type accessTokenClaims struct {
+	ClientID string   `json:"client_id"`
+	Scope    string   `json:"scope,omitempty"`
+	Roles    []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// Signer はサーバーの秘密鍵（RSA）でアクセストークンに署名し、検証します
+type Signer struct {
+	privateKey *rsa.PrivateKey
+	keyID      string
+}
+
+// LoadSigner はPEM形式の秘密鍵を読み込みます
+// 値が"-----BEGIN"で始まらない場合はファイルのパスとして扱います
+func LoadSigner(key string) (*Signer, error) {
+	key = strings.TrimSpace(key)
+	keyPEM := []byte(key)
+	if !strings.HasPrefix(key, "-----BEGIN") {
+		data, err := os.ReadFile(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		keyPEM = data
+	}
+	return ParseSigner(keyPEM)
+}
+
+// ParseSigner はPEM形式の秘密鍵（PKCS#1またはPKCS#8のRSA鍵）をパースします
+func ParseSigner(keyPEM []byte) (*Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	if privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return NewSigner(privateKey)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key must be an RSA key")
+	}
+	return NewSigner(privateKey)
+}
+
+// NewSigner はRSA鍵（2048ビット以上）のSignerを作成します
+// 鍵ID（kid）は公開鍵のJWK Thumbprint（RFC 7638）です
+func NewSigner(privateKey *rsa.PrivateKey) (*Signer, error) {
+	if privateKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("private key must be at least 2048 bits")
+	}
+
+	// RFC 7638 3.2: 必須のメンバーを辞書順に並べたJSONのSHA-256
+	thumbprint, err := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute key ID: %w", err)
+	}
+	sum := sha256.Sum256(thumbprint)
+	return &Signer{privateKey: privateKey, keyID: base64.RawURLEncoding.EncodeToString(sum[:])}, nil
+}
+
+// KeyID は署名に使用する鍵のID（kid）を返します
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKeys はリソースサーバーが署名を検証するための公開鍵を返します
+func (s *Signer) PublicKeys() *jwks.Set {
+	return &jwks.Set{Keys: []jwks.Key{{
+		KeyID:     s.keyID,
+		Algorithm: jwt.SigningMethodRS256.Alg(),
+		PublicKey: &s.privateKey.PublicKey,
+	}}}
+}
+
+// SignAccessToken はアクセストークンにRS256で署名します
+func (s *Signer) SignAccessToken(accessToken *AccessToken) (string, error) {
+	claims := &accessTokenClaims{
+		ClientID: accessToken.ClientID,
+		Scope:    accessToken.Scope,
+		Roles:    accessToken.Roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        accessToken.ID,
+			Issuer:    accessToken.Issuer,
+			Subject:   accessToken.Subject,
+			Audience:  accessToken.Audience,
+			IssuedAt:  jwt.NewNumericDate(accessToken.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(accessToken.ExpiresAt),
+		},
+	}
+	if claims.Roles == nil {
+		claims.Roles = []string{}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["typ"] = AccessTokenType
+	token.Header["kid"] = s.keyID
+	signed, err := token.SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return signed, nil
+}
+
+// ParseAccessToken はこのSignerで署名したアクセストークンの署名とissを検証し、内容を返します
+// allowExpiredがtrueの場合は期限切れのトークンも返します（取り消しの処理に使用します）
+func (s *Signer) ParseAccessToken(tokenString, issuer string, allowExpired bool) (*AccessToken, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithLeeway(accessTokenLeeway),
+		jwt.WithExpirationRequired(),
+	}
+	if allowExpired {
+		options = append(options, jwt.WithoutClaimsValidation())
+	}
+
+	claims := &accessTokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != AccessTokenType {
+			return nil, fmt.Errorf("unexpected token type %q", typ)
+		}
+		return &s.privateKey.PublicKey, nil
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse access token: %w", err)
+	}
+	if allowExpired && claims.Issuer != issuer {
+		// クレームの検証を省略した場合もissは確認する
+		return nil, errors.New("failed to parse access token: unexpected issuer")
+	}
+	if !token.Valid || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil, errors.New("failed to parse access token: missing jti or exp")
+	}
+
+	accessToken := &AccessToken{
+		ID:        claims.ID,
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		Audience:  claims.Audience,
+		ClientID:  claims.ClientID,
+		Scope:     claims.Scope,
+		Roles:     claims.Roles,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if claims.IssuedAt != nil {
+		accessToken.IssuedAt = claims.IssuedAt.Time
+	}
+	return accessToken, nil
+}
+
+// IsJWT は文字列がJWS Compact Serialization（ヘッダー.ペイロード.署名）の形式かどうかを返します
+// ランダムな不透明トークンとJWT形式のアクセストークンを区別するために使用します
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
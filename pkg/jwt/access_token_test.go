@@ -0,0 +1,125 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestSigner はテスト用のRSA鍵のSignerを生成します
+func newTestSigner(t *testing.T) *Signer {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := NewSigner(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	return signer
+}
+
+// TestSigner_AccessToken はアクセストークンの署名と検証をテストします
+func TestSigner_AccessToken(t *testing.T) {
+	const issuer = "https://auth.example.com"
+	signer := newTestSigner(t)
+	now := time.Now().Truncate(time.Second)
+
+	accessToken := &AccessToken{
+		ID:        "jti-1",
+		Issuer:    issuer,
+		Subject:   "user-1",
+		Audience:  []string{"client-a"},
+		ClientID:  "client-a",
+		Scope:     "profile members",
+		Roles:     []string{"幹部", "部員"},
+		IssuedAt:  now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	token, err := signer.SignAccessToken(accessToken)
+	if err != nil {
+		t.Fatalf("Failed to sign access token: %v", err)
+	}
+	if !IsJWT(token) {
+		t.Fatalf("Expected a JWT, got %s", token)
+	}
+
+	parsed, err := signer.ParseAccessToken(token, issuer, false)
+	if err != nil {
+		t.Fatalf("Failed to parse access token: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, accessToken) {
+		t.Errorf("Expected %+v, got %+v", accessToken, parsed)
+	}
+
+	// issが異なる場合・別の鍵で署名した場合は検証に失敗する
+	if _, err := signer.ParseAccessToken(token, "https://other.example.com", false); err == nil {
+		t.Error("Expected an error for a different issuer")
+	}
+	if _, err := newTestSigner(t).ParseAccessToken(token, issuer, false); err == nil {
+		t.Error("Expected an error for a different key")
+	}
+
+	// 期限切れのトークンはallowExpiredの場合のみ受け付ける
+	accessToken.ID = "jti-expired"
+	accessToken.ExpiresAt = now.Add(-time.Hour)
+	expired, err := signer.SignAccessToken(accessToken)
+	if err != nil {
+		t.Fatalf("Failed to sign access token: %v", err)
+	}
+	if _, err := signer.ParseAccessToken(expired, issuer, false); err == nil {
+		t.Error("Expected an error for an expired token")
+	}
+	parsed, err = signer.ParseAccessToken(expired, issuer, true)
+	if err != nil {
+		t.Fatalf("Expected the expired token to be accepted, got %v", err)
+	}
+	if parsed.ID != "jti-expired" {
+		t.Errorf("Expected jti-expired, got %s", parsed.ID)
+	}
+	if _, err := signer.ParseAccessToken(expired, "https://other.example.com", true); err == nil {
+		t.Error("Expected an error for a different issuer even if expired tokens are allowed")
+	}
+}
+
+// TestParseSigner はPEM形式の秘密鍵の読み込みをテストします
+func TestParseSigner(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	pkcs1Signer, err := ParseSigner(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}))
+	if err != nil {
+		t.Fatalf("Failed to parse PKCS#1 key: %v", err)
+	}
+	pkcs8Signer, err := ParseSigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	if err != nil {
+		t.Fatalf("Failed to parse PKCS#8 key: %v", err)
+	}
+	// 鍵IDは公開鍵から決まる
+	if pkcs1Signer.KeyID() == "" || pkcs1Signer.KeyID() != pkcs8Signer.KeyID() {
+		t.Errorf("Expected the same key ID, got %q and %q", pkcs1Signer.KeyID(), pkcs8Signer.KeyID())
+	}
+
+	if _, err := ParseSigner([]byte("not a key")); err == nil {
+		t.Error("Expected an error for a non-PEM value")
+	}
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if _, err := NewSigner(smallKey); err == nil {
+		t.Error("Expected an error for a 1024-bit key")
+	}
+}
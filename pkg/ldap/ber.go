@@ -0,0 +1,263 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BERのクラス
+const (
+	classUniversal   byte = 0x00
+	classApplication byte = 0x40
+	classContext     byte = 0x80
+)
+
+// 汎用（UNIVERSAL）クラスのタグ
+const (
+	tagBoolean     = 1
+	tagInteger     = 2
+	tagOctetString = 4
+	tagNull        = 5
+	tagEnumerated  = 10
+	tagSequence    = 16
+	tagSet         = 17
+)
+
+// maxPacketSize は受け付けるLDAPメッセージの最大サイズです
+const maxPacketSize = 1 << 20
+
+// maxPacketDepth は受け付ける要素の入れ子の深さの上限です
+// 入れ子の深さがmaxFilterDepthのフィルターを含む検索リクエストを受け付けられるよう、フィルターの上限より余裕を持たせます
+const maxPacketDepth = 32
+
+// errMalformedPacket はBERの符号化が不正な場合のエラーです
+var errMalformedPacket = errors.New("ldap: malformed packet")
+
+// packet はBERで符号化された1つの要素です
+type packet struct {
+	class       byte
+	constructed bool
+	tag         int
+	value       []byte    // 構造化されていない要素の値
+	children    []*packet // 構造化された要素の子要素
+}
+
+// is は要素のクラスとタグが一致するかどうかを確認します
+func (p *packet) is(class byte, tag int) bool {
+	return p.class == class && p.tag == tag
+}
+
+// readPacket はストリームから1つの要素を読み取ります
+func readPacket(r *bufio.Reader) (*packet, error) {
+	identifier, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxPacketSize {
+		return nil, fmt.Errorf("%w: packet too large (%d bytes)", errMalformedPacket, length)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return newPacket(identifier, content, 0)
+}
+
+// readLength は長さを読み取ります（不定長形式は受け付けません）
+func readLength(r io.ByteReader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+	n := int(first & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, fmt.Errorf("%w: unsupported length form", errMalformedPacket)
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
+
+// newPacket は識別子と内容から要素を作成します（depthは要素の入れ子の深さです）
+func newPacket(identifier byte, content []byte, depth int) (*packet, error) {
+	p := &packet{
+		class:       identifier & 0xc0,
+		constructed: identifier&0x20 != 0,
+		tag:         int(identifier & 0x1f),
+	}
+	if p.tag == 0x1f {
+		return nil, fmt.Errorf("%w: high tag numbers are not supported", errMalformedPacket)
+	}
+	if !p.constructed {
+		p.value = content
+		return p, nil
+	}
+	if depth >= maxPacketDepth {
+		return nil, fmt.Errorf("%w: packet too deep", errMalformedPacket)
+	}
+	children, err := parsePackets(content, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	p.children = children
+	return p, nil
+}
+
+// parsePackets は入れ子の深さがdepthの連続した要素を読み取ります
+func parsePackets(data []byte, depth int) ([]*packet, error) {
+	var packets []*packet
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errMalformedPacket
+		}
+		identifier := data[0]
+		r := &sliceReader{data: data[1:]}
+		length, err := readLength(r)
+		if err != nil {
+			return nil, errMalformedPacket
+		}
+		rest := data[1+r.offset:]
+		if length > len(rest) {
+			return nil, fmt.Errorf("%w: truncated element", errMalformedPacket)
+		}
+		p, err := newPacket(identifier, rest[:length], depth)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, p)
+		data = rest[length:]
+	}
+	return packets, nil
+}
+
+// sliceReader はバイト列から1バイトずつ読み取ります
+type sliceReader struct {
+	data   []byte
+	offset int
+}
+
+// ReadByte は次の1バイトを返します
+func (r *sliceReader) ReadByte() (byte, error) {
+	if r.offset >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.offset]
+	r.offset++
+	return b, nil
+}
+
+// bytes は要素をBERで符号化します
+func (p *packet) bytes() []byte {
+	content := p.value
+	if p.constructed {
+		content = nil
+		for _, child := range p.children {
+			content = append(content, child.bytes()...)
+		}
+	}
+
+	identifier := p.class | byte(p.tag)
+	if p.constructed {
+		identifier |= 0x20
+	}
+	out := []byte{identifier}
+	out = append(out, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+// encodeLength は長さを符号化します
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var digits []byte
+	for n := length; n > 0; n >>= 8 {
+		digits = append([]byte{byte(n)}, digits...)
+	}
+	return append([]byte{0x80 | byte(len(digits))}, digits...)
+}
+
+// constructedPacket は構造化された要素を作成します
+func constructedPacket(class byte, tag int, children ...*packet) *packet {
+	return &packet{class: class, constructed: true, tag: tag, children: children}
+}
+
+// sequencePacket はSEQUENCEを作成します
+func sequencePacket(children ...*packet) *packet {
+	return constructedPacket(classUniversal, tagSequence, children...)
+}
+
+// stringPacket はOCTET STRINGを作成します
+func stringPacket(class byte, tag int, s string) *packet {
+	return &packet{class: class, tag: tag, value: []byte(s)}
+}
+
+// octetString は汎用クラスのOCTET STRINGを作成します
+func octetString(s string) *packet {
+	return stringPacket(classUniversal, tagOctetString, s)
+}
+
+// integerPacket はINTEGER・ENUMERATEDを作成します（2の補数の最短表現）
+func integerPacket(tag int, n int64) *packet {
+	var value []byte
+	for {
+		value = append([]byte{byte(n)}, value...)
+		if (n >= -128 && n < 128) || len(value) == 8 {
+			break
+		}
+		n >>= 8
+	}
+	return &packet{class: classUniversal, tag: tag, value: value}
+}
+
+// booleanPacket はBOOLEANを作成します
+func booleanPacket(b bool) *packet {
+	value := byte(0x00)
+	if b {
+		value = 0xff
+	}
+	return &packet{class: classUniversal, tag: tagBoolean, value: []byte{value}}
+}
+
+// int は要素の値を整数として読み取ります
+func (p *packet) int() (int64, error) {
+	if p.constructed || len(p.value) == 0 || len(p.value) > 8 {
+		return 0, fmt.Errorf("%w: invalid integer", errMalformedPacket)
+	}
+	n := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		n = n<<8 | int64(b)
+	}
+	return n, nil
+}
+
+// bool は要素の値を真偽値として読み取ります
+func (p *packet) bool() (bool, error) {
+	if p.constructed || len(p.value) != 1 {
+		return false, fmt.Errorf("%w: invalid boolean", errMalformedPacket)
+	}
+	return p.value[0] != 0, nil
+}
+
+// string は要素の値を文字列として読み取ります
+func (p *packet) string() (string, error) {
+	if p.constructed {
+		return "", fmt.Errorf("%w: expected primitive string", errMalformedPacket)
+	}
+	return string(p.value), nil
+}
@@ -0,0 +1,164 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// RDN は相対識別名（属性と値の組）です
+type RDN struct {
+	Attribute string
+	Value     string
+}
+
+// DN は識別名です（先頭が末端の要素）
+type DN []RDN
+
+// ParseDN はRFC 4514の文字列表現の識別名を読み取ります（複数の値を持つRDNには対応しません）
+func ParseDN(s string) (DN, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DN{}, nil
+	}
+
+	var dn DN
+	var (
+		attribute string
+		attrStart int // 属性名の開始位置
+		value     strings.Builder
+		inValue   bool
+		trailing  int // 値の末尾のエスケープされていない空白の数
+	)
+	finish := func() error {
+		if !inValue || attribute == "" {
+			return fmt.Errorf("ldap: invalid dn %q", s)
+		}
+		v := value.String()
+		dn = append(dn, RDN{Attribute: attribute, Value: v[:len(v)-trailing]})
+		attribute, inValue, trailing = "", false, 0
+		value.Reset()
+		return nil
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !inValue {
+			if c != '=' {
+				continue
+			}
+			attribute = strings.TrimSpace(s[attrStart:i])
+			inValue = true
+			// 値の先頭の空白は無視する
+			for i+1 < len(s) && s[i+1] == ' ' {
+				i++
+			}
+			continue
+		}
+		switch c {
+		case '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("ldap: invalid escape in dn %q", s)
+			}
+			if decoded, err := hex.DecodeString(s[i+1 : min(i+3, len(s))]); err == nil && len(decoded) == 1 {
+				value.WriteByte(decoded[0])
+				i += 2
+			} else {
+				value.WriteByte(s[i+1])
+				i++
+			}
+			trailing = 0
+		case ',', ';':
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			attrStart = i + 1
+		case '+':
+			return nil, fmt.Errorf("ldap: multi-valued rdn is not supported: %q", s)
+		case ' ':
+			value.WriteByte(c)
+			trailing++
+		default:
+			value.WriteByte(c)
+			trailing = 0
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return dn, nil
+}
+
+// MustParseDN はParseDNと同じですが、不正な識別名の場合はpanicします
+func MustParseDN(s string) DN {
+	dn, err := ParseDN(s)
+	if err != nil {
+		panic(err)
+	}
+	return dn
+}
+
+// String は識別名をRFC 4514の文字列表現で返します
+func (dn DN) String() string {
+	parts := make([]string, len(dn))
+	for i, rdn := range dn {
+		parts[i] = rdn.Attribute + "=" + EscapeDNValue(rdn.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Child は末端に要素を追加した識別名を返します
+func (dn DN) Child(attribute, value string) DN {
+	return append(DN{{Attribute: attribute, Value: value}}, dn...)
+}
+
+// Equal は属性名と値を大文字・小文字を区別せずに比較します
+func (dn DN) Equal(other DN) bool {
+	if len(dn) != len(other) {
+		return false
+	}
+	for i := range dn {
+		if !strings.EqualFold(dn[i].Attribute, other[i].Attribute) || !strings.EqualFold(dn[i].Value, other[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasSuffix は識別名がsuffix自身またはその配下であるかどうかを確認します
+func (dn DN) HasSuffix(suffix DN) bool {
+	return len(dn) >= len(suffix) && dn[len(dn)-len(suffix):].Equal(suffix)
+}
+
+// InScope は識別名が検索の起点と範囲に含まれるかどうかを確認します
+func (dn DN) InScope(base DN, scope Scope) bool {
+	switch scope {
+	case ScopeBaseObject:
+		return dn.Equal(base)
+	case ScopeSingleLevel:
+		return len(dn) == len(base)+1 && dn.HasSuffix(base)
+	default:
+		return dn.HasSuffix(base)
+	}
+}
+
+// EscapeDNValue はRDNの値をRFC 4514に従ってエスケープします
+func EscapeDNValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ',' || c == '+' || c == '"' || c == '\\' || c == '<' || c == '>' || c == ';' || c == '=':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case (c == ' ' && (i == 0 || i == len(s)-1)) || (c == '#' && i == 0):
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,266 @@
+package ldap
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FilterType は検索フィルターの種類です（値はBERのタグ番号）
+type FilterType int
+
+const (
+	FilterAnd FilterType = iota
+	FilterOr
+	FilterNot
+	FilterEqual
+	FilterSubstrings
+	FilterGreaterOrEqual
+	FilterLessOrEqual
+	FilterPresent
+	FilterApprox
+	FilterExtensible
+)
+
+// maxFilterDepth は受け付けるフィルターの入れ子の深さの上限です
+const maxFilterDepth = 16
+
+// Filter は検索フィルターです
+type Filter struct {
+	Type      FilterType
+	Children  []*Filter // and・or・notの条件
+	Attribute string
+	Value     string // equal・greaterOrEqual・lessOrEqual・approx・extensibleの値
+
+	// substringsの値（それぞれ省略可能）
+	Initial string
+	Any     []string
+	Final   string
+}
+
+// parseFilter はBERで符号化されたフィルターを読み取ります
+func parseFilter(p *packet, depth int) (*Filter, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("%w: filter too deep", errMalformedPacket)
+	}
+	if p.class != classContext || p.tag > int(FilterExtensible) {
+		return nil, fmt.Errorf("%w: unknown filter", errMalformedPacket)
+	}
+
+	f := &Filter{Type: FilterType(p.tag)}
+	switch f.Type {
+	case FilterAnd, FilterOr, FilterNot:
+		if !p.constructed || (f.Type == FilterNot && len(p.children) != 1) {
+			return nil, fmt.Errorf("%w: invalid %s filter", errMalformedPacket, f.Type)
+		}
+		for _, child := range p.children {
+			c, err := parseFilter(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			f.Children = append(f.Children, c)
+		}
+	case FilterPresent:
+		f.Attribute = string(p.value)
+	case FilterSubstrings:
+		if len(p.children) != 2 || !p.children[1].constructed {
+			return nil, fmt.Errorf("%w: invalid substrings filter", errMalformedPacket)
+		}
+		f.Attribute = string(p.children[0].value)
+		for _, sub := range p.children[1].children {
+			switch sub.tag {
+			case 0:
+				f.Initial = string(sub.value)
+			case 1:
+				f.Any = append(f.Any, string(sub.value))
+			case 2:
+				f.Final = string(sub.value)
+			}
+		}
+	case FilterExtensible:
+		// MatchingRuleAssertion（matchingRuleとdnAttributesは無視し、属性の一致として扱う）
+		for _, child := range p.children {
+			switch child.tag {
+			case 2:
+				f.Attribute = string(child.value)
+			case 3:
+				f.Value = string(child.value)
+			}
+		}
+	default:
+		if len(p.children) != 2 {
+			return nil, fmt.Errorf("%w: invalid %s filter", errMalformedPacket, f.Type)
+		}
+		f.Attribute = string(p.children[0].value)
+		f.Value = string(p.children[1].value)
+	}
+	return f, nil
+}
+
+// Match はエントリーがフィルターに一致するかどうかを確認します
+// 値は大文字・小文字を区別せずに比較します
+func (f *Filter) Match(e *Entry) bool {
+	switch f.Type {
+	case FilterAnd:
+		for _, c := range f.Children {
+			if !c.Match(e) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, c := range f.Children {
+			if c.Match(e) {
+				return true
+			}
+		}
+		return false
+	case FilterNot:
+		return !f.Children[0].Match(e)
+	case FilterPresent:
+		return len(e.Get(f.Attribute)) > 0
+	}
+
+	for _, value := range e.Get(f.Attribute) {
+		v := strings.ToLower(value)
+		switch f.Type {
+		case FilterEqual, FilterApprox, FilterExtensible:
+			if v == strings.ToLower(f.Value) {
+				return true
+			}
+		case FilterGreaterOrEqual:
+			if v >= strings.ToLower(f.Value) {
+				return true
+			}
+		case FilterLessOrEqual:
+			if v <= strings.ToLower(f.Value) {
+				return true
+			}
+		case FilterSubstrings:
+			if matchSubstrings(v, strings.ToLower(f.Initial), f.Any, strings.ToLower(f.Final)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSubstrings は値が先頭・途中・末尾の部分文字列に順に一致するかどうかを確認します
+func matchSubstrings(value, initial string, any []string, final string) bool {
+	if !strings.HasPrefix(value, initial) {
+		return false
+	}
+	value = value[len(initial):]
+	for _, s := range any {
+		s = strings.ToLower(s)
+		i := strings.Index(value, s)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(s):]
+	}
+	return strings.HasSuffix(value, final)
+}
+
+// EqualityValues はフィルターに一致するエントリーが属性に必ず持つ値の候補を返します
+// リポジトリでの絞り込みに使用し、候補を特定できない場合はokがfalseです
+func (f *Filter) EqualityValues(attribute string) (values []string, ok bool) {
+	switch f.Type {
+	case FilterEqual:
+		if strings.EqualFold(f.Attribute, attribute) {
+			return []string{f.Value}, true
+		}
+	case FilterAnd:
+		for _, c := range f.Children {
+			if values, ok := c.EqualityValues(attribute); ok {
+				return values, true
+			}
+		}
+	case FilterOr:
+		for _, c := range f.Children {
+			v, ok := c.EqualityValues(attribute)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, v...)
+		}
+		return values, len(f.Children) > 0
+	}
+	return nil, false
+}
+
+// SubstringValue はフィルターに一致するエントリーがいずれかの属性の値に必ず含む文字列を返します
+// 一致・部分一致の条件から求め、部分一致は最も長い部分を返します。特定できない場合はokがfalseです
+func (f *Filter) SubstringValue(attributes ...string) (value string, ok bool) {
+	switch f.Type {
+	case FilterEqual, FilterSubstrings:
+		if !slices.ContainsFunc(attributes, func(attribute string) bool { return strings.EqualFold(f.Attribute, attribute) }) {
+			return "", false
+		}
+		if f.Type == FilterEqual {
+			return f.Value, f.Value != ""
+		}
+		for _, s := range append([]string{f.Initial, f.Final}, f.Any...) {
+			if len(s) > len(value) {
+				value = s
+			}
+		}
+		return value, value != ""
+	case FilterAnd:
+		for _, c := range f.Children {
+			if value, ok := c.SubstringValue(attributes...); ok {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// String はフィルターをRFC 4515の文字列表現で返します
+func (f *Filter) String() string {
+	switch f.Type {
+	case FilterAnd, FilterOr, FilterNot:
+		var b strings.Builder
+		b.WriteString("(" + [...]string{"&", "|", "!"}[f.Type])
+		for _, c := range f.Children {
+			b.WriteString(c.String())
+		}
+		return b.String() + ")"
+	case FilterPresent:
+		return "(" + f.Attribute + "=*)"
+	case FilterSubstrings:
+		parts := []string{escapeFilterValue(f.Initial)}
+		for _, s := range f.Any {
+			parts = append(parts, escapeFilterValue(s))
+		}
+		parts = append(parts, escapeFilterValue(f.Final))
+		return "(" + f.Attribute + "=" + strings.Join(parts, "*") + ")"
+	case FilterExtensible:
+		return "(" + f.Attribute + ":=" + escapeFilterValue(f.Value) + ")"
+	}
+	operator := map[FilterType]string{FilterEqual: "=", FilterGreaterOrEqual: ">=", FilterLessOrEqual: "<=", FilterApprox: "~="}[f.Type]
+	return "(" + f.Attribute + operator + escapeFilterValue(f.Value) + ")"
+}
+
+// String はフィルターの種類の名前を返します
+func (t FilterType) String() string {
+	names := [...]string{"and", "or", "not", "equalityMatch", "substrings", "greaterOrEqual", "lessOrEqual", "present", "approxMatch", "extensibleMatch"}
+	if int(t) < len(names) {
+		return names[t]
+	}
+	return fmt.Sprintf("filter(%d)", int(t))
+}
+
+// escapeFilterValue はフィルターの値をRFC 4515に従ってエスケープします
+func escapeFilterValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,359 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// encodeTestFilter はテストのクライアントが送るフィルターを符号化します
+func encodeTestFilter(f *Filter) *packet {
+	tag := int(f.Type)
+	switch f.Type {
+	case FilterAnd, FilterOr, FilterNot:
+		children := make([]*packet, len(f.Children))
+		for i, c := range f.Children {
+			children[i] = encodeTestFilter(c)
+		}
+		return constructedPacket(classContext, tag, children...)
+	case FilterPresent:
+		return stringPacket(classContext, tag, f.Attribute)
+	case FilterSubstrings:
+		var subs []*packet
+		if f.Initial != "" {
+			subs = append(subs, stringPacket(classContext, 0, f.Initial))
+		}
+		for _, s := range f.Any {
+			subs = append(subs, stringPacket(classContext, 1, s))
+		}
+		if f.Final != "" {
+			subs = append(subs, stringPacket(classContext, 2, f.Final))
+		}
+		return constructedPacket(classContext, tag, octetString(f.Attribute), sequencePacket(subs...))
+	default:
+		return constructedPacket(classContext, tag, octetString(f.Attribute), octetString(f.Value))
+	}
+}
+
+// TestBER は整数と長い要素の符号化・復号をテストします
+func TestBER(t *testing.T) {
+	for _, n := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 31} {
+		p := integerPacket(tagInteger, n)
+		decoded, err := readPacket(bufio.NewReader(bytes.NewReader(p.bytes())))
+		if err != nil {
+			t.Fatalf("Failed to decode %d: %v", n, err)
+		}
+		if got, err := decoded.int(); err != nil || got != n {
+			t.Errorf("Expected %d, got %d (%v)", n, got, err)
+		}
+	}
+
+	long := strings.Repeat("x", 70000)
+	p := sequencePacket(octetString(long), booleanPacket(true))
+	decoded, err := readPacket(bufio.NewReader(bytes.NewReader(p.bytes())))
+	if err != nil {
+		t.Fatalf("Failed to decode sequence: %v", err)
+	}
+	if s, _ := decoded.children[0].string(); s != long {
+		t.Errorf("Unexpected string length %d", len(s))
+	}
+	if b, _ := decoded.children[1].bool(); !b {
+		t.Error("Expected true")
+	}
+
+	// 内容が長さより短い要素は拒否する
+	if _, err := parsePackets([]byte{0x04, 0x05, 'a'}, 0); err == nil {
+		t.Error("Expected truncated element to be rejected")
+	}
+
+	// 入れ子が深すぎる要素は拒否する
+	nested := octetString("x")
+	for i := 0; i < maxPacketDepth; i++ {
+		nested = sequencePacket(nested)
+	}
+	if _, err := readPacket(bufio.NewReader(bytes.NewReader(nested.bytes()))); err != nil {
+		t.Errorf("Expected %d nested sequences to be accepted, got %v", maxPacketDepth, err)
+	}
+	if _, err := readPacket(bufio.NewReader(bytes.NewReader(sequencePacket(nested).bytes()))); !errors.Is(err, errMalformedPacket) {
+		t.Errorf("Expected too deeply nested packet to be rejected, got %v", err)
+	}
+}
+
+// TestParseDN は識別名の読み取りとエスケープをテストします
+func TestParseDN(t *testing.T) {
+	tests := []struct {
+		input string
+		want  DN
+	}{
+		{"uid=taro,ou=people,dc=jyogi", DN{{"uid", "taro"}, {"ou", "people"}, {"dc", "jyogi"}}},
+		{" UID = taro , OU=people ", DN{{"UID", "taro"}, {"OU", "people"}}},
+		{`cn=Smith\, John,dc=jyogi`, DN{{"cn", "Smith, John"}, {"dc", "jyogi"}}},
+		{`cn=\e5\b9\b9\e9\83\a8,dc=jyogi`, DN{{"cn", "幹部"}, {"dc", "jyogi"}}},
+		{`cn=trailing\ ,dc=jyogi`, DN{{"cn", "trailing "}, {"dc", "jyogi"}}},
+		{"", DN{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDN(tt.input)
+		if err != nil {
+			t.Errorf("ParseDN(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseDN(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"taro", "uid=a+cn=b,dc=jyogi", "=taro", `uid=taro\`} {
+		if _, err := ParseDN(input); err == nil {
+			t.Errorf("Expected ParseDN(%q) to fail", input)
+		}
+	}
+
+	// エスケープした値は読み取ると元に戻る
+	dn := DN{{"cn", ` #Smith, "J" <j+1>;=\ `}, {"dc", "jyogi"}}
+	parsed, err := ParseDN(dn.String())
+	if err != nil || !slices.Equal(parsed, dn) {
+		t.Errorf("Round trip of %q failed: %v (%v)", dn.String(), parsed, err)
+	}
+
+	base := MustParseDN("dc=jyogi")
+	people := base.Child("ou", "people")
+	taro := people.Child("uid", "taro")
+	if !taro.InScope(base, ScopeWholeSubtree) || taro.InScope(base, ScopeSingleLevel) || !taro.InScope(people, ScopeSingleLevel) {
+		t.Error("Unexpected scope result")
+	}
+	if !taro.InScope(MustParseDN("UID=Taro,OU=People,DC=jyogi"), ScopeBaseObject) {
+		t.Error("Expected DN comparison to be case-insensitive")
+	}
+}
+
+// TestFilterMatch はフィルターの判定をテストします
+func TestFilterMatch(t *testing.T) {
+	entry := NewEntry(MustParseDN("uid=taro,ou=people,dc=jyogi")).
+		Add("objectClass", "top", "inetOrgPerson").
+		Add("uid", "taro").
+		Add("cn", "Taro Yamada").
+		Add("memberOf", "cn=部員,ou=groups,dc=jyogi")
+
+	eq := func(attr, value string) *Filter { return &Filter{Type: FilterEqual, Attribute: attr, Value: value} }
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   bool
+	}{
+		{"equal", eq("uid", "TARO"), true},
+		{"equal other", eq("uid", "jiro"), false},
+		{"attribute option", eq("cn;lang-ja", "taro yamada"), true},
+		{"present", &Filter{Type: FilterPresent, Attribute: "objectclass"}, true},
+		{"missing", &Filter{Type: FilterPresent, Attribute: "mail"}, false},
+		{"substrings", &Filter{Type: FilterSubstrings, Attribute: "cn", Initial: "ta", Any: []string{"o y"}, Final: "ADA"}, true},
+		{"substrings order", &Filter{Type: FilterSubstrings, Attribute: "cn", Any: []string{"yamada", "taro"}}, false},
+		{"and", &Filter{Type: FilterAnd, Children: []*Filter{eq("objectClass", "inetOrgPerson"), eq("uid", "taro")}}, true},
+		{"or", &Filter{Type: FilterOr, Children: []*Filter{eq("uid", "jiro"), eq("memberOf", "cn=部員,ou=groups,dc=jyogi")}}, true},
+		{"not", &Filter{Type: FilterNot, Children: []*Filter{eq("uid", "taro")}}, false},
+		{"greater", &Filter{Type: FilterGreaterOrEqual, Attribute: "uid", Value: "t"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(entry); got != tt.want {
+				t.Errorf("%s: expected %v, got %v", tt.filter, tt.want, got)
+			}
+		})
+	}
+
+	or := &Filter{Type: FilterOr, Children: []*Filter{eq("uid", "taro"), eq("UID", "jiro")}}
+	and := &Filter{Type: FilterAnd, Children: []*Filter{eq("objectClass", "inetOrgPerson"), or}}
+	if values, ok := and.EqualityValues("uid"); !ok || !slices.Equal(values, []string{"taro", "jiro"}) {
+		t.Errorf("Unexpected equality values: %v %v", values, ok)
+	}
+	if _, ok := (&Filter{Type: FilterOr, Children: []*Filter{eq("uid", "taro"), eq("cn", "x")}}).EqualityValues("uid"); ok {
+		t.Error("Expected or with other attributes not to narrow")
+	}
+	substrings := &Filter{Type: FilterAnd, Children: []*Filter{
+		eq("objectClass", "inetOrgPerson"),
+		{Type: FilterSubstrings, Attribute: "displayName", Initial: "ta", Any: []string{"ro y"}, Final: "da"},
+	}}
+	if value, ok := substrings.SubstringValue("cn", "displayName"); !ok || value != "ro y" {
+		t.Errorf("Unexpected substring value: %q %v", value, ok)
+	}
+	if value, ok := eq("CN", "taro").SubstringValue("cn"); !ok || value != "taro" {
+		t.Errorf("Unexpected substring value: %q %v", value, ok)
+	}
+	if _, ok := or.SubstringValue("uid"); ok {
+		t.Error("Expected or not to narrow by substring")
+	}
+	if got := and.String(); got != "(&(objectClass=inetOrgPerson)(|(uid=taro)(UID=jiro)))" {
+		t.Errorf("Unexpected string: %s", got)
+	}
+}
+
+// testBackend はテスト用のディレクトリです
+type testBackend struct {
+	entries []*Entry
+}
+
+func (b *testBackend) Bind(ctx context.Context, dn DN, password string) error {
+	if dn.Equal(MustParseDN("uid=taro,ou=people,dc=jyogi")) && password == "secret" {
+		return nil
+	}
+	return ErrInvalidCredentials
+}
+
+func (b *testBackend) Search(ctx context.Context, boundDN DN, req *SearchRequest) ([]*Entry, error) {
+	if len(boundDN) == 0 {
+		return nil, ErrInsufficientAccess
+	}
+	return b.entries, nil
+}
+
+// testClient はテスト用のLDAPクライアントです
+type testClient struct {
+	t         *testing.T
+	conn      net.Conn
+	reader    *bufio.Reader
+	messageID int64
+}
+
+// send は要求を送信し、応答をSearchResultDoneなどの最終の応答まで読み取ります
+func (c *testClient) send(op *packet) []*packet {
+	c.t.Helper()
+	c.messageID++
+	if _, err := c.conn.Write(sequencePacket(integerPacket(tagInteger, c.messageID), op).bytes()); err != nil {
+		c.t.Fatalf("Failed to send request: %v", err)
+	}
+	var responses []*packet
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msg, err := readPacket(c.reader)
+		if err != nil {
+			c.t.Fatalf("Failed to read response: %v", err)
+		}
+		if id, _ := msg.children[0].int(); id != c.messageID {
+			c.t.Fatalf("Unexpected message ID %d", id)
+		}
+		responses = append(responses, msg.children[1])
+		if msg.children[1].tag != opSearchResultEntry {
+			return responses
+		}
+	}
+}
+
+// resultCode はLDAPResultの結果コードを返します
+func resultCode(p *packet) ResultCode {
+	code, _ := p.children[0].int()
+	return ResultCode(code)
+}
+
+func bindRequest(name, password string) *packet {
+	return constructedPacket(classApplication, opBindRequest, integerPacket(tagInteger, 3), octetString(name), stringPacket(classContext, 0, password))
+}
+
+func searchRequest(base string, scope Scope, filter *Filter, attributes ...string) *packet {
+	attrs := sequencePacket()
+	for _, a := range attributes {
+		attrs.children = append(attrs.children, octetString(a))
+	}
+	return constructedPacket(classApplication, opSearchRequest,
+		octetString(base),
+		integerPacket(tagEnumerated, int64(scope)),
+		integerPacket(tagEnumerated, 0),
+		integerPacket(tagInteger, 0),
+		integerPacket(tagInteger, 0),
+		booleanPacket(false),
+		encodeTestFilter(filter),
+		attrs,
+	)
+}
+
+// TestServer はBind・検索・Who am I?・更新の拒否をテストします
+func TestServer(t *testing.T) {
+	base := MustParseDN("dc=jyogi")
+	people := base.Child("ou", "people")
+	backend := &testBackend{entries: []*Entry{
+		NewEntry(people).Add("objectClass", "organizationalUnit").Add("ou", "people"),
+		NewEntry(people.Child("uid", "taro")).Add("objectClass", "inetOrgPerson").Add("uid", "taro").Add("cn", "Taro"),
+		NewEntry(people.Child("uid", "jiro")).Add("objectClass", "inetOrgPerson").Add("uid", "jiro").Add("cn", "Jiro"),
+	}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := NewServer(base, backend)
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	people1 := &Filter{Type: FilterEqual, Attribute: "objectClass", Value: "inetOrgPerson"}
+
+	// ルートDSEは匿名でも取得できる
+	responses := client.send(searchRequest("", ScopeBaseObject, &Filter{Type: FilterPresent, Attribute: "objectClass"}))
+	if len(responses) != 2 || !strings.Contains(string(responses[0].bytes()), "dc=jyogi") {
+		t.Errorf("Unexpected root DSE: %d responses", len(responses))
+	}
+
+	// 匿名ではディレクトリを検索できない
+	responses = client.send(searchRequest("dc=jyogi", ScopeWholeSubtree, people1))
+	if code := resultCode(responses[len(responses)-1]); code != ResultInsufficientAccessRights {
+		t.Errorf("Expected insufficient access, got %d", code)
+	}
+
+	if code := resultCode(client.send(bindRequest("uid=taro,ou=people,dc=jyogi", "wrong"))[0]); code != ResultInvalidCredentials {
+		t.Errorf("Expected invalid credentials, got %d", code)
+	}
+	if code := resultCode(client.send(bindRequest("uid=taro,ou=people,dc=jyogi", ""))[0]); code != ResultUnwillingToPerform {
+		t.Errorf("Expected unauthenticated bind to be refused, got %d", code)
+	}
+	if code := resultCode(client.send(bindRequest("UID=taro, ou=people, dc=jyogi", "secret"))[0]); code != ResultSuccess {
+		t.Fatalf("Expected bind to succeed, got %d", code)
+	}
+
+	// Who am I?
+	responses = client.send(constructedPacket(classApplication, opExtendedRequest, stringPacket(classContext, 0, oidWhoAmI)))
+	if len(responses[0].children) != 4 || string(responses[0].children[3].value) != "dn:UID=taro,ou=people,dc=jyogi" {
+		t.Errorf("Unexpected whoami response")
+	}
+
+	// フィルターと要求した属性のみを返す
+	responses = client.send(searchRequest("ou=people,dc=jyogi", ScopeSingleLevel, &Filter{Type: FilterAnd, Children: []*Filter{people1, {Type: FilterSubstrings, Attribute: "cn", Initial: "ji"}}}, "uid"))
+	if len(responses) != 2 || resultCode(responses[1]) != ResultSuccess {
+		t.Fatalf("Expected 1 entry, got %d responses", len(responses))
+	}
+	entry := responses[0]
+	if dn, _ := entry.children[0].string(); dn != "uid=jiro,ou=people,dc=jyogi" {
+		t.Errorf("Unexpected DN: %s", dn)
+	}
+	if attrs := entry.children[1].children; len(attrs) != 1 || string(attrs[0].children[0].value) != "uid" {
+		t.Errorf("Expected only uid attribute, got %d attributes", len(attrs))
+	}
+
+	// 検索の起点が範囲外の場合
+	responses = client.send(searchRequest("dc=other", ScopeWholeSubtree, people1))
+	if code := resultCode(responses[0]); code != ResultNoSuchObject {
+		t.Errorf("Expected no such object, got %d", code)
+	}
+
+	// 更新は拒否する
+	responses = client.send(constructedPacket(classApplication, opAddRequest, octetString("uid=saburo,ou=people,dc=jyogi"), sequencePacket()))
+	if responses[0].tag != opAddRequest+1 || resultCode(responses[0]) != ResultUnwillingToPerform {
+		t.Errorf("Expected add to be refused")
+	}
+
+	if err := server.Close(); err != nil {
+		t.Fatalf("Failed to close server: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve returned error: %v", err)
+	}
+}
@@ -0,0 +1,526 @@
+// Package ldap は読み取り専用のLDAPv3サーバーを提供します
+// Simple Bindと検索（Search）・Who am I?拡張操作のみに対応し、エントリーの内容はBackendが返します
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope は検索の範囲です
+type Scope int
+
+const (
+	ScopeBaseObject   Scope = 0 // 起点のエントリーのみ
+	ScopeSingleLevel  Scope = 1 // 起点の直下のエントリー
+	ScopeWholeSubtree Scope = 2 // 起点とその配下のすべてのエントリー
+)
+
+// ResultCode はLDAPの結果コードです
+type ResultCode int
+
+const (
+	ResultSuccess                  ResultCode = 0
+	ResultOperationsError          ResultCode = 1
+	ResultProtocolError            ResultCode = 2
+	ResultSizeLimitExceeded        ResultCode = 4
+	ResultAuthMethodNotSupported   ResultCode = 7
+	ResultNoSuchObject             ResultCode = 32
+	ResultInvalidDNSyntax          ResultCode = 34
+	ResultInvalidCredentials       ResultCode = 49
+	ResultInsufficientAccessRights ResultCode = 50
+	ResultUnwillingToPerform       ResultCode = 53
+)
+
+// プロトコル操作（APPLICATIONクラスのタグ）
+const (
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchResultEntry = 4
+	opSearchResultDone  = 5
+	opModifyRequest     = 6
+	opAddRequest        = 8
+	opDelRequest        = 10
+	opModifyDNRequest   = 12
+	opCompareRequest    = 14
+	opAbandonRequest    = 16
+	opExtendedRequest   = 23
+	opExtendedResponse  = 24
+)
+
+// oidWhoAmI はWho am I?拡張操作（RFC 4532）のOIDです
+const oidWhoAmI = "1.3.6.1.4.1.4203.1.11.3"
+
+const (
+	defaultIdleTimeout   = 5 * time.Minute  // 要求がない接続を切断するまでの時間
+	defaultWriteDeadline = 30 * time.Second // 1つの要求の応答を送信する期限
+)
+
+// Error はクライアントに返す結果コードを伴うエラーです
+type Error struct {
+	Code    ResultCode
+	Message string
+}
+
+// Error はエラーメッセージを返します
+func (e *Error) Error() string {
+	return fmt.Sprintf("ldap: %s (result code %d)", e.Message, e.Code)
+}
+
+// Backendが返すエラー
+var (
+	ErrInvalidCredentials = &Error{Code: ResultInvalidCredentials, Message: "invalid credentials"}
+	ErrInsufficientAccess = &Error{Code: ResultInsufficientAccessRights, Message: "insufficient access rights"}
+	ErrNoSuchObject       = &Error{Code: ResultNoSuchObject, Message: "no such object"}
+)
+
+// Attribute はエントリーの属性です
+type Attribute struct {
+	Name   string
+	Values []string
+}
+
+// Entry はディレクトリのエントリーです
+type Entry struct {
+	DN         DN
+	Attributes []Attribute
+}
+
+// NewEntry は新しいエントリーを作成します
+func NewEntry(dn DN) *Entry {
+	return &Entry{DN: dn}
+}
+
+// Add は属性を追加します（空の値は追加しません）
+func (e *Entry) Add(name string, values ...string) *Entry {
+	var nonEmpty []string
+	for _, v := range values {
+		if v != "" {
+			nonEmpty = append(nonEmpty, v)
+		}
+	}
+	if len(nonEmpty) > 0 {
+		e.Attributes = append(e.Attributes, Attribute{Name: name, Values: nonEmpty})
+	}
+	return e
+}
+
+// Get は属性の値を返します（属性名は大文字・小文字を区別せず、オプションは無視します）
+func (e *Entry) Get(name string) []string {
+	name, _, _ = strings.Cut(name, ";")
+	for _, attr := range e.Attributes {
+		if strings.EqualFold(attr.Name, name) {
+			return attr.Values
+		}
+	}
+	return nil
+}
+
+// SearchRequest は検索の要求です
+type SearchRequest struct {
+	BaseDN     DN
+	Scope      Scope
+	SizeLimit  int
+	TypesOnly  bool
+	Filter     *Filter
+	Attributes []string
+}
+
+// Backend はディレクトリの内容を提供します
+type Backend interface {
+	// Bind は識別名とパスワードを検証します（一致しない場合はErrInvalidCredentials）
+	Bind(ctx context.Context, dn DN, password string) error
+	// Search は検索の起点の配下のエントリーを返します
+	// boundDNはBindした識別名（匿名の場合は空）で、範囲とフィルターの判定はサーバーでも行います
+	Search(ctx context.Context, boundDN DN, req *SearchRequest) ([]*Entry, error)
+}
+
+// Server は読み取り専用のLDAPv3サーバーです
+type Server struct {
+	baseDN      DN
+	backend     Backend
+	idleTimeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// NewServer は新しいサーバーを作成します
+// baseDNはディレクトリの最上位の識別名（namingContexts）です
+func NewServer(baseDN DN, backend Backend) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		baseDN:      baseDN,
+		backend:     backend,
+		idleTimeout: defaultIdleTimeout,
+		ctx:         ctx,
+		cancel:      cancel,
+		listeners:   make(map[net.Listener]struct{}),
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve は接続を受け付けます（Closeされるまで戻りません）
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.ctx.Err() != nil {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// Close は受付を停止し、すべての接続を切断します
+func (s *Server) Close() error {
+	s.cancel()
+	s.mu.Lock()
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// conn は1つの接続の状態です
+type conn struct {
+	server  *Server
+	netConn net.Conn
+	writer  *bufio.Writer
+	boundDN DN // Bindした識別名（匿名の場合は空）
+}
+
+// serveConn は接続からの要求を順に処理します
+func (s *Server) serveConn(netConn net.Conn) {
+	c := &conn{server: s, netConn: netConn, writer: bufio.NewWriter(netConn)}
+	reader := bufio.NewReader(netConn)
+	for {
+		netConn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		msg, err := readPacket(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && s.ctx.Err() == nil {
+				log.Printf("LDAP: failed to read request from %s: %v", netConn.RemoteAddr(), err)
+			}
+			return
+		}
+		if !c.handle(msg) {
+			return
+		}
+	}
+}
+
+// handle は1つの要求を処理します（接続を閉じる場合はfalseを返します）
+func (c *conn) handle(msg *packet) bool {
+	if !msg.is(classUniversal, tagSequence) || len(msg.children) < 2 {
+		log.Printf("LDAP: malformed message from %s", c.netConn.RemoteAddr())
+		return false
+	}
+	messageID, err := msg.children[0].int()
+	if err != nil {
+		return false
+	}
+	op := msg.children[1]
+	if op.class != classApplication {
+		return false
+	}
+	// 検索結果が多い場合は処理の途中でも送信されるため、先に書き込みの期限を設定する
+	c.netConn.SetWriteDeadline(time.Now().Add(defaultWriteDeadline))
+
+	switch op.tag {
+	case opBindRequest:
+		c.handleBind(messageID, op)
+	case opUnbindRequest:
+		return false
+	case opSearchRequest:
+		c.handleSearch(messageID, op)
+	case opModifyRequest, opAddRequest, opDelRequest, opModifyDNRequest, opCompareRequest:
+		c.writeResult(messageID, op.tag+1, ResultUnwillingToPerform, "the directory is read-only")
+	case opAbandonRequest:
+		// 要求は順に処理するため、中断する操作はない
+	case opExtendedRequest:
+		c.handleExtended(messageID, op)
+	default:
+		log.Printf("LDAP: unsupported operation %d from %s", op.tag, c.netConn.RemoteAddr())
+		return false
+	}
+	return c.flush() == nil
+}
+
+// handleBind はBindRequestを処理します
+func (c *conn) handleBind(messageID int64, op *packet) {
+	// 失敗した場合は匿名に戻る
+	c.boundDN = nil
+	if len(op.children) != 3 {
+		c.writeResult(messageID, opBindResponse, ResultProtocolError, "malformed bind request")
+		return
+	}
+	if version, err := op.children[0].int(); err != nil || version != 3 {
+		c.writeResult(messageID, opBindResponse, ResultProtocolError, "only LDAPv3 is supported")
+		return
+	}
+	auth := op.children[2]
+	if !auth.is(classContext, 0) {
+		c.writeResult(messageID, opBindResponse, ResultAuthMethodNotSupported, "only simple bind is supported")
+		return
+	}
+	name, _ := op.children[1].string()
+	password, _ := auth.string()
+
+	if name == "" && password == "" {
+		c.writeResult(messageID, opBindResponse, ResultSuccess, "")
+		return
+	}
+	if password == "" {
+		c.writeResult(messageID, opBindResponse, ResultUnwillingToPerform, "unauthenticated bind is not allowed")
+		return
+	}
+	dn, err := ParseDN(name)
+	if err != nil {
+		c.writeResult(messageID, opBindResponse, ResultInvalidCredentials, "invalid credentials")
+		return
+	}
+	if err := c.server.backend.Bind(c.server.ctx, dn, password); err != nil {
+		code, message := resultFor(err)
+		if code != ResultInvalidCredentials {
+			log.Printf("LDAP: bind failed for %s: %v", dn, err)
+		}
+		c.writeResult(messageID, opBindResponse, code, message)
+		return
+	}
+	c.boundDN = dn
+	c.writeResult(messageID, opBindResponse, ResultSuccess, "")
+}
+
+// handleSearch はSearchRequestを処理します
+func (c *conn) handleSearch(messageID int64, op *packet) {
+	req, err := parseSearchRequest(op)
+	if err != nil {
+		var ldapErr *Error
+		if errors.As(err, &ldapErr) {
+			c.writeResult(messageID, opSearchResultDone, ldapErr.Code, ldapErr.Message)
+			return
+		}
+		c.writeResult(messageID, opSearchResultDone, ResultProtocolError, "malformed search request")
+		return
+	}
+
+	var entries []*Entry
+	switch {
+	case len(req.BaseDN) == 0 && req.Scope == ScopeBaseObject:
+		// ルートDSE（サーバーの情報）は匿名でも取得できる
+		entries = []*Entry{c.server.rootDSE()}
+	case !req.BaseDN.HasSuffix(c.server.baseDN) && !c.server.baseDN.HasSuffix(req.BaseDN):
+		c.writeResult(messageID, opSearchResultDone, ResultNoSuchObject, "no such object")
+		return
+	default:
+		entries, err = c.server.backend.Search(c.server.ctx, c.boundDN, req)
+		if err != nil {
+			code, message := resultFor(err)
+			if code == ResultOperationsError {
+				log.Printf("LDAP: search failed for %s: %v", req.Filter, err)
+			}
+			c.writeResult(messageID, opSearchResultDone, code, message)
+			return
+		}
+	}
+
+	sent := 0
+	for _, entry := range entries {
+		if !entry.DN.InScope(req.BaseDN, req.Scope) || !req.Filter.Match(entry) {
+			continue
+		}
+		if req.SizeLimit > 0 && sent >= req.SizeLimit {
+			c.writeResult(messageID, opSearchResultDone, ResultSizeLimitExceeded, "")
+			return
+		}
+		c.writeMessage(messageID, searchResultEntry(entry, req))
+		sent++
+	}
+	c.writeResult(messageID, opSearchResultDone, ResultSuccess, "")
+}
+
+// handleExtended はExtendedRequestを処理します（Who am I?のみ対応）
+func (c *conn) handleExtended(messageID int64, op *packet) {
+	var name string
+	if len(op.children) > 0 && op.children[0].is(classContext, 0) {
+		name, _ = op.children[0].string()
+	}
+	if name != oidWhoAmI {
+		c.writeResult(messageID, opExtendedResponse, ResultProtocolError, "unsupported extended operation")
+		return
+	}
+
+	authzID := ""
+	if len(c.boundDN) > 0 {
+		authzID = "dn:" + c.boundDN.String()
+	}
+	c.writeMessage(messageID, constructedPacket(classApplication, opExtendedResponse,
+		integerPacket(tagEnumerated, int64(ResultSuccess)),
+		octetString(""),
+		octetString(""),
+		stringPacket(classContext, 11, authzID),
+	))
+}
+
+// rootDSE はルートDSEのエントリーを返します
+func (s *Server) rootDSE() *Entry {
+	return NewEntry(DN{}).
+		Add("objectClass", "top").
+		Add("namingContexts", s.baseDN.String()).
+		Add("supportedLDAPVersion", "3").
+		Add("supportedExtension", oidWhoAmI)
+}
+
+// parseSearchRequest はSearchRequestを読み取ります
+func parseSearchRequest(op *packet) (*SearchRequest, error) {
+	if len(op.children) != 8 {
+		return nil, errMalformedPacket
+	}
+	base, err := op.children[0].string()
+	if err != nil {
+		return nil, err
+	}
+	baseDN, err := ParseDN(base)
+	if err != nil {
+		return nil, &Error{Code: ResultInvalidDNSyntax, Message: "invalid base dn"}
+	}
+	scope, err := op.children[1].int()
+	if err != nil || scope < 0 || scope > 2 {
+		return nil, errMalformedPacket
+	}
+	sizeLimit, err := op.children[3].int()
+	if err != nil {
+		return nil, err
+	}
+	typesOnly, err := op.children[5].bool()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseFilter(op.children[6], 0)
+	if err != nil {
+		return nil, err
+	}
+	var attributes []string
+	for _, attr := range op.children[7].children {
+		attributes = append(attributes, string(attr.value))
+	}
+	return &SearchRequest{
+		BaseDN:     baseDN,
+		Scope:      Scope(scope),
+		SizeLimit:  int(sizeLimit),
+		TypesOnly:  typesOnly,
+		Filter:     filter,
+		Attributes: attributes,
+	}, nil
+}
+
+// searchResultEntry は要求された属性のみを含むSearchResultEntryを作成します
+// 属性の指定がない場合と「*」はすべての属性、「1.1」のみの場合は属性なしです
+func searchResultEntry(entry *Entry, req *SearchRequest) *packet {
+	all := len(req.Attributes) == 0
+	requested := make(map[string]bool)
+	for _, name := range req.Attributes {
+		name, _, _ = strings.Cut(name, ";")
+		if name == "*" {
+			all = true
+		}
+		requested[strings.ToLower(name)] = true
+	}
+
+	attributes := sequencePacket()
+	for _, attr := range entry.Attributes {
+		if !all && !requested[strings.ToLower(attr.Name)] {
+			continue
+		}
+		values := constructedPacket(classUniversal, tagSet)
+		if !req.TypesOnly {
+			for _, v := range attr.Values {
+				values.children = append(values.children, octetString(v))
+			}
+		}
+		attributes.children = append(attributes.children, sequencePacket(octetString(attr.Name), values))
+	}
+	return constructedPacket(classApplication, opSearchResultEntry, octetString(entry.DN.String()), attributes)
+}
+
+// resultFor はBackendのエラーに対応する結果コードを返します
+func resultFor(err error) (ResultCode, string) {
+	var ldapErr *Error
+	if errors.As(err, &ldapErr) {
+		return ldapErr.Code, ldapErr.Message
+	}
+	return ResultOperationsError, "internal error"
+}
+
+// writeResult はLDAPResultを書き込みます
+func (c *conn) writeResult(messageID int64, tag int, code ResultCode, message string) {
+	c.writeMessage(messageID, constructedPacket(classApplication, tag,
+		integerPacket(tagEnumerated, int64(code)),
+		octetString(""),
+		octetString(message),
+	))
+}
+
+// writeMessage は応答のLDAPMessageを書き込みます
+func (c *conn) writeMessage(messageID int64, op *packet) {
+	c.writer.Write(sequencePacket(integerPacket(tagInteger, messageID), op).bytes())
+}
+
+// flush は書き込んだ応答を送信します
+func (c *conn) flush() error {
+	return c.writer.Flush()
+}
@@ -0,0 +1,97 @@
+// Package ngram は日本語を含むテキストを文字n-gramに分割する全文検索用のトークナイザーです
+// 形態素解析の辞書を持たずに、空白で区切られない日本語の部分一致検索を行うために使用します
+package ngram
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 索引に登録するn-gramの長さです
+// 1文字の検索語（「絵」「猫」など）にも一致させるため、bi-gramに加えてuni-gramも登録します
+const (
+	unigram = 1
+	bigram  = 2
+)
+
+// Normalize は検索のためにテキストを正規化します
+// NFKCで全角英数字・半角カタカナなどの表記揺れを統一し、英字を小文字に、ひらがなをカタカナに変換します
+func Normalize(s string) string {
+	s = norm.NFKC.String(s)
+	return strings.Map(func(r rune) rune {
+		// ひらがな（ぁ〜ゖ）をカタカナに変換する
+		if r >= 'ぁ' && r <= 'ゖ' {
+			return r + ('ァ' - 'ぁ')
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// Tokenize はテキストを索引に登録する語（uni-gramとbi-gram）と出現回数に分割します
+func Tokenize(s string) map[string]int {
+	terms := make(map[string]int)
+	for _, run := range runs(Normalize(s)) {
+		for _, n := range []int{unigram, bigram} {
+			for i := 0; i+n <= len(run); i++ {
+				terms[string(run[i:i+n])]++
+			}
+		}
+	}
+	return terms
+}
+
+// QueryTerms は検索語を索引で引く語に分割します（重複は除きます）
+// 2文字以上の連続した文字はbi-gram、1文字だけの場合はuni-gramになります
+func QueryTerms(s string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	for _, run := range runs(Normalize(s)) {
+		if len(run) < bigram {
+			add(string(run))
+			continue
+		}
+		for i := 0; i+bigram <= len(run); i++ {
+			add(string(run[i : i+bigram]))
+		}
+	}
+	return terms
+}
+
+// Words はテキストを正規化し、文字・数字の連続ごとに分割します（空白・記号は区切りとして扱います）
+// 索引で絞り込んだ候補が検索語を実際に含むかどうかの確認に使用します
+func Words(s string) []string {
+	var words []string
+	for _, run := range runs(Normalize(s)) {
+		words = append(words, string(run))
+	}
+	return words
+}
+
+// runs は正規化したテキストを文字・数字の連続に分割します（空白・記号は区切りとして扱います）
+func runs(s string) [][]rune {
+	var result [][]rune
+	var current []rune
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			current = append(current, r)
+			continue
+		}
+		if len(current) > 0 {
+			result = append(result, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package ngram
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "全角英数字", input: "Ｇｏ言語１", expected: "go言語1"},
+		{name: "半角カタカナ", input: "ｹﾞｰﾑ", expected: "ゲーム"},
+		{name: "ひらがなをカタカナに", input: "げーむ", expected: "ゲーム"},
+		{name: "漢字はそのまま", input: "競プロ", expected: "競プロ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	terms := Tokenize("ゲーム制作、ゲーム")
+
+	expected := map[string]int{
+		"ゲ": 2, "ー": 2, "ム": 2, "制": 1, "作": 1,
+		"ゲー": 2, "ーム": 2, "ム制": 1, "制作": 1,
+	}
+	if len(terms) != len(expected) {
+		t.Errorf("Expected %d terms, got %d: %v", len(expected), len(terms), terms)
+	}
+	for term, count := range expected {
+		if terms[term] != count {
+			t.Errorf("terms[%q] = %d, want %d", term, terms[term], count)
+		}
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{name: "bi-gram", input: "競プロ", expected: []string{"競プ", "プロ"}},
+		{name: "1文字はuni-gram", input: "絵", expected: []string{"絵"}},
+		{name: "空白区切り・重複を除く", input: "ゲーム　ゲーム制作", expected: []string{"ゲー", "ーム", "ム制", "制作"}},
+		{name: "英字", input: "Go", expected: []string{"go"}},
+		{name: "記号のみ", input: "!?", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QueryTerms(tt.input); !slices.Equal(got, tt.expected) {
+				t.Errorf("QueryTerms(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	got := Words("ゲーム・制作　Ｇｏ")
+	expected := []string{"ゲーム", "制作", "go"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Words() = %v, want %v", got, expected)
+	}
+}
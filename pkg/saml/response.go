@@ -0,0 +1,186 @@
+package saml
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ステータスコード
+const (
+	StatusSuccess       = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	StatusResponder     = "urn:oasis:names:tc:SAML:2.0:status:Responder"
+	StatusNoPassive     = "urn:oasis:names:tc:SAML:2.0:status:NoPassive"
+	StatusRequestDenied = "urn:oasis:names:tc:SAML:2.0:status:RequestDenied"
+)
+
+// 署名のアルゴリズム
+const (
+	algorithmExcC14N        = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algorithmEnveloped      = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algorithmRSASHA256      = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algorithmSHA256         = "http://www.w3.org/2001/04/xmlenc#sha256"
+	attrNameFormatBasic     = "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"
+	attrNameFormatURI       = "urn:oasis:names:tc:SAML:2.0:attrname-format:uri"
+	confirmationBearer      = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	authnContextUnspecified = "urn:oasis:names:tc:SAML:2.0:ac:classes:unspecified"
+)
+
+// AssertionLifetime はアサーションの有効期間です
+const AssertionLifetime = 5 * time.Minute
+
+// Attribute はアサーションで送る属性です
+type Attribute struct {
+	Name   string
+	Values []string
+}
+
+// Response はSPに返すログイン成功のレスポンスの内容です
+type Response struct {
+	Issuer       string    // IdPのエンティティID
+	Audience     string    // SPのエンティティID
+	Destination  string    // アサーションの送信先（ACS URL）
+	InResponseTo string    // 認証要求のID
+	NameID       string    // ユーザーの識別子（persistent）
+	AuthnInstant time.Time // ユーザーがログインした日時
+	IssueInstant time.Time
+	Attributes   []Attribute
+}
+
+// Sign はアサーションとレスポンスの両方に署名したレスポンスのXMLを返します
+func (r *Response) Sign(keyPair *KeyPair) ([]byte, error) {
+	responseID, err := NewID()
+	if err != nil {
+		return nil, err
+	}
+	assertionID, err := NewID()
+	if err != nil {
+		return nil, err
+	}
+	issueInstant := formatTime(r.IssueInstant)
+	notOnOrAfter := formatTime(r.IssueInstant.Add(AssertionLifetime))
+
+	assertion := newElement("saml:Assertion", "ID", assertionID, "IssueInstant", issueInstant, "Version", "2.0").
+		declare("saml", NamespaceAssertion).add(
+		newElement("saml:Issuer").withText(r.Issuer),
+		newElement("saml:Subject").add(
+			newElement("saml:NameID", "Format", NameIDFormatPersistent, "NameQualifier", r.Issuer, "SPNameQualifier", r.Audience).withText(r.NameID),
+			newElement("saml:SubjectConfirmation", "Method", confirmationBearer).add(
+				newElement("saml:SubjectConfirmationData", "InResponseTo", r.InResponseTo, "NotOnOrAfter", notOnOrAfter, "Recipient", r.Destination),
+			),
+		),
+		newElement("saml:Conditions", "NotBefore", issueInstant, "NotOnOrAfter", notOnOrAfter).add(
+			newElement("saml:AudienceRestriction").add(newElement("saml:Audience").withText(r.Audience)),
+		),
+		newElement("saml:AuthnStatement", "AuthnInstant", formatTime(r.AuthnInstant), "SessionIndex", assertionID).add(
+			newElement("saml:AuthnContext").add(newElement("saml:AuthnContextClassRef").withText(authnContextUnspecified)),
+		),
+	)
+	if statement := attributeStatement(r.Attributes); statement != nil {
+		assertion.add(statement)
+	}
+	if err := sign(assertion, assertionID, keyPair); err != nil {
+		return nil, err
+	}
+
+	response := newResponseElement(responseID, r.Issuer, r.Destination, r.InResponseTo, issueInstant, StatusSuccess, "")
+	response.add(assertion)
+	if err := sign(response, responseID, keyPair); err != nil {
+		return nil, err
+	}
+	return []byte(response.String()), nil
+}
+
+// NewStatusResponse はアサーションを含まないエラーのレスポンスのXMLを返します
+// subStatusには StatusNoPassive などの第2レベルのステータスコードを指定します（空の場合は省略）
+func NewStatusResponse(issuer, destination, inResponseTo, status, subStatus string, issueInstant time.Time) ([]byte, error) {
+	responseID, err := NewID()
+	if err != nil {
+		return nil, err
+	}
+	response := newResponseElement(responseID, issuer, destination, inResponseTo, formatTime(issueInstant), status, subStatus)
+	return []byte(response.String()), nil
+}
+
+// newResponseElement はレスポンスの要素（Issuer・Statusまで）を作成します
+// 名前空間samlの宣言は、レスポンスを正規化してもアサーションの宣言が省略されないよう、子要素ごとに指定します
+func newResponseElement(id, issuer, destination, inResponseTo, issueInstant, status, subStatus string) *element {
+	statusCode := newElement("samlp:StatusCode", "Value", status)
+	if subStatus != "" {
+		statusCode.add(newElement("samlp:StatusCode", "Value", subStatus))
+	}
+	return newElement("samlp:Response",
+		"ID", id,
+		"Version", "2.0",
+		"IssueInstant", issueInstant,
+		"Destination", destination,
+		"InResponseTo", inResponseTo,
+	).declare("samlp", NamespaceProtocol).add(
+		newElement("saml:Issuer").declare("saml", NamespaceAssertion).withText(issuer),
+		newElement("samlp:Status").add(statusCode),
+	)
+}
+
+// attributeStatement は属性の要素を作成します（値のない属性は送りません）
+func attributeStatement(attributes []Attribute) *element {
+	statement := newElement("saml:AttributeStatement")
+	for _, attr := range attributes {
+		if len(attr.Values) == 0 {
+			continue
+		}
+		nameFormat := attrNameFormatBasic
+		if strings.Contains(attr.Name, ":") {
+			nameFormat = attrNameFormatURI
+		}
+		attribute := newElement("saml:Attribute", "Name", attr.Name, "NameFormat", nameFormat)
+		for _, value := range attr.Values {
+			attribute.add(newElement("saml:AttributeValue").withText(value))
+		}
+		statement.add(attribute)
+	}
+	if len(statement.children) == 0 {
+		return nil
+	}
+	return statement
+}
+
+// sign は要素に包含署名（enveloped signature）を追加します
+// 署名は先頭の子要素（Issuer）の直後に挿入します
+func sign(e *element, id string, keyPair *KeyPair) error {
+	digest := sha256.Sum256([]byte(e.String()))
+
+	signedInfo := newElement("ds:SignedInfo").add(
+		newElement("ds:CanonicalizationMethod", "Algorithm", algorithmExcC14N),
+		newElement("ds:SignatureMethod", "Algorithm", algorithmRSASHA256),
+		newElement("ds:Reference", "URI", "#"+id).add(
+			newElement("ds:Transforms").add(
+				newElement("ds:Transform", "Algorithm", algorithmEnveloped),
+				newElement("ds:Transform", "Algorithm", algorithmExcC14N),
+			),
+			newElement("ds:DigestMethod", "Algorithm", algorithmSHA256),
+			newElement("ds:DigestValue").withText(base64.StdEncoding.EncodeToString(digest[:])),
+		),
+	)
+
+	// SignedInfoを単独で正規化すると名前空間dsの宣言が付くため、宣言を付けた形式に署名する
+	signedInfo.declare("ds", NamespaceDSig)
+	hashed := sha256.Sum256([]byte(signedInfo.String()))
+	signedInfo.nsDecls = nil
+	signature, err := rsa.SignPKCS1v15(rand.Reader, keyPair.PrivateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+
+	signatureElement := newElement("ds:Signature").declare("ds", NamespaceDSig).add(
+		signedInfo,
+		newElement("ds:SignatureValue").withText(base64.StdEncoding.EncodeToString(signature)),
+		keyInfo(keyPair.Certificate),
+	)
+	e.children = append(e.children[:1], append([]*element{signatureElement}, e.children[1:]...)...)
+	return nil
+}
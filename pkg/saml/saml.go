@@ -0,0 +1,265 @@
+// Package saml はSAML 2.0のIdentity Provider（IdP）に必要なメタデータ・認証要求・署名付きレスポンスを扱います
+// Web Browser SSO Profileのうち、HTTP-Redirect・HTTP-POSTで認証要求を受け取り、HTTP-POSTでレスポンスを返す用途に限定します
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 名前空間
+const (
+	NamespaceMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	NamespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	NamespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	NamespaceDSig      = "http://www.w3.org/2000/09/xmldsig#"
+)
+
+// バインディング
+const (
+	BindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	BindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+)
+
+// NameIDFormatPersistent はユーザーごとに変わらない識別子のNameIDの形式です
+const NameIDFormatPersistent = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+
+// maxMessageSize は受け付ける認証要求・メタデータの最大サイズです（展開後）
+const maxMessageSize = 256 * 1024
+
+// ErrInvalidMessage は認証要求・メタデータの形式が不正な場合のエラーです
+var ErrInvalidMessage = errors.New("saml: invalid message")
+
+// KeyPair はアサーションの署名に使用するRSA鍵と証明書です
+type KeyPair struct {
+	PrivateKey  *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// LoadKeyPair はPEM形式の秘密鍵と証明書を読み込みます
+// 値が"-----BEGIN"で始まらない場合はファイルのパスとして扱います
+func LoadKeyPair(key, cert string) (*KeyPair, error) {
+	keyPEM, err := readPEM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	certPEM, err := readPEM(cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	return ParseKeyPair(keyPEM, certPEM)
+}
+
+// ParseKeyPair はPEM形式の秘密鍵（PKCS#1またはPKCS#8のRSA鍵）と証明書をパースします
+func ParseKeyPair(keyPEM, certPEM []byte) (*KeyPair, error) {
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	var privateKey *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes); err == nil {
+		privateKey = parsed
+	} else {
+		parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key must be an RSA key")
+		}
+		privateKey = rsaKey
+	}
+	if privateKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("private key must be at least 2048 bits")
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("certificate is not PEM encoded")
+	}
+	certificate, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	if !privateKey.PublicKey.Equal(certificate.PublicKey) {
+		return nil, fmt.Errorf("certificate does not match the private key")
+	}
+
+	return &KeyPair{PrivateKey: privateKey, Certificate: certificate}, nil
+}
+
+// readPEM はPEMの文字列、またはPEMファイルの内容を返します
+func readPEM(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
+
+// AuthnRequest はSPからの認証要求です
+type AuthnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	IsPassive                   bool     `xml:"IsPassive,attr"`
+	ForceAuthn                  bool     `xml:"ForceAuthn,attr"`
+	Issuer                      string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+}
+
+// DecodeRedirectRequest はHTTP-Redirectバインディングの認証要求（DEFLATE圧縮・Base64）をデコードします
+// 認証要求とXMLの本文を返します
+func DecodeRedirectRequest(encoded string) (*AuthnRequest, []byte, error) {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxMessageSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return parseRequest(raw)
+}
+
+// DecodePostRequest はHTTP-POSTバインディングの認証要求（Base64）をデコードします
+// 認証要求とXMLの本文を返します
+func DecodePostRequest(encoded string) (*AuthnRequest, []byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return parseRequest(raw)
+}
+
+// EncodeRedirectRequest は認証要求のXMLをHTTP-Redirectバインディングの形式（DEFLATE圧縮・Base64）にエンコードします
+func EncodeRedirectRequest(raw []byte) (string, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", fmt.Errorf("failed to create deflate writer: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return "", fmt.Errorf("failed to compress request: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress request: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// parseRequest は認証要求のXMLをパースします
+func parseRequest(raw []byte) (*AuthnRequest, []byte, error) {
+	if len(raw) > maxMessageSize {
+		return nil, nil, fmt.Errorf("%w: request is too large", ErrInvalidMessage)
+	}
+	var req AuthnRequest
+	if err := xml.Unmarshal(raw, &req); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if req.ID == "" || req.Version != "2.0" || req.Issuer == "" {
+		return nil, nil, fmt.Errorf("%w: ID, Version 2.0 and Issuer are required", ErrInvalidMessage)
+	}
+	req.Issuer = strings.TrimSpace(req.Issuer)
+	return &req, raw, nil
+}
+
+// ServiceProviderMetadata はSPのメタデータから読み込んだ登録内容です
+type ServiceProviderMetadata struct {
+	EntityID string
+	ACSURLs  []string // HTTP-POSTバインディングのAssertionConsumerService（isDefaultのものが先頭）
+}
+
+// ParseServiceProviderMetadata はSPのメタデータ（EntityDescriptor）からエンティティIDとアサーションの送信先を読み込みます
+func ParseServiceProviderMetadata(data []byte) (*ServiceProviderMetadata, error) {
+	if len(data) > maxMessageSize {
+		return nil, fmt.Errorf("%w: metadata is too large", ErrInvalidMessage)
+	}
+	var descriptor struct {
+		XMLName  xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+		EntityID string   `xml:"entityID,attr"`
+		SP       struct {
+			ACS []struct {
+				Binding   string `xml:"Binding,attr"`
+				Location  string `xml:"Location,attr"`
+				IsDefault bool   `xml:"isDefault,attr"`
+			} `xml:"AssertionConsumerService"`
+		} `xml:"SPSSODescriptor"`
+	}
+	if err := xml.Unmarshal(data, &descriptor); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	metadata := &ServiceProviderMetadata{EntityID: strings.TrimSpace(descriptor.EntityID)}
+	for _, acs := range descriptor.SP.ACS {
+		if acs.Binding != BindingHTTPPost || acs.Location == "" {
+			continue
+		}
+		if acs.IsDefault {
+			metadata.ACSURLs = append([]string{acs.Location}, metadata.ACSURLs...)
+		} else {
+			metadata.ACSURLs = append(metadata.ACSURLs, acs.Location)
+		}
+	}
+	if metadata.EntityID == "" || len(metadata.ACSURLs) == 0 {
+		return nil, fmt.Errorf("%w: entityID and an HTTP-POST AssertionConsumerService are required", ErrInvalidMessage)
+	}
+	return metadata, nil
+}
+
+// IdentityProviderMetadata はIdPのメタデータ（EntityDescriptor）を返します
+// 認証要求はssoURLでHTTP-Redirect・HTTP-POSTのいずれでも受け付けます
+func IdentityProviderMetadata(entityID, ssoURL string, cert *x509.Certificate) []byte {
+	descriptor := newElement("md:EntityDescriptor", "entityID", entityID).declare("md", NamespaceMetadata).add(
+		newElement("md:IDPSSODescriptor",
+			"WantAuthnRequestsSigned", "false",
+			"protocolSupportEnumeration", NamespaceProtocol,
+		).add(
+			newElement("md:KeyDescriptor", "use", "signing").add(keyInfo(cert).declare("ds", NamespaceDSig)),
+			newElement("md:NameIDFormat").withText(NameIDFormatPersistent),
+			newElement("md:SingleSignOnService", "Binding", BindingHTTPRedirect, "Location", ssoURL),
+			newElement("md:SingleSignOnService", "Binding", BindingHTTPPost, "Location", ssoURL),
+		),
+	)
+	return []byte(xml.Header + descriptor.String())
+}
+
+// keyInfo は証明書のKeyInfo要素を返します
+func keyInfo(cert *x509.Certificate) *element {
+	return newElement("ds:KeyInfo").add(
+		newElement("ds:X509Data").add(
+			newElement("ds:X509Certificate").withText(base64.StdEncoding.EncodeToString(cert.Raw)),
+		),
+	)
+}
+
+// NewID はSAMLのメッセージ・アサーションのIDを生成します（XMLのNCNameとして有効な形式）
+func NewID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return "_" + hex.EncodeToString(b), nil
+}
+
+// formatTime は日時をSAMLの形式（UTCのxs:dateTime）にします
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,423 @@
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestKeyPair はテスト用の鍵と自己署名証明書のPEMを作成します
+func newTestKeyPair(t *testing.T) (keyPEM, certPEM []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jyogi-auth"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return keyPEM, certPEM
+}
+
+// TestParseKeyPair は鍵と証明書の組み合わせを検証することを確認します
+func TestParseKeyPair(t *testing.T) {
+	keyPEM, certPEM := newTestKeyPair(t)
+	otherKeyPEM, _ := newTestKeyPair(t)
+
+	if _, err := ParseKeyPair(keyPEM, certPEM); err != nil {
+		t.Fatalf("Failed to parse key pair: %v", err)
+	}
+	if _, err := LoadKeyPair(string(keyPEM), string(certPEM)); err != nil {
+		t.Fatalf("Failed to load key pair from PEM strings: %v", err)
+	}
+	if _, err := ParseKeyPair(otherKeyPEM, certPEM); err == nil {
+		t.Error("Expected error for mismatched certificate")
+	}
+}
+
+// TestRedirectRequest はHTTP-Redirect・HTTP-POSTバインディングの認証要求をデコードできることを確認します
+func TestRedirectRequest(t *testing.T) {
+	raw := []byte(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_req1" Version="2.0" IssueInstant="2026-01-01T00:00:00Z" AssertionConsumerServiceURL="https://sp.example.com/acs" IsPassive="true"><saml:Issuer> https://sp.example.com/metadata </saml:Issuer></samlp:AuthnRequest>`)
+
+	encoded, err := EncodeRedirectRequest(raw)
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	req, decoded, err := DecodeRedirectRequest(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	if !bytes.Equal(decoded, raw) {
+		t.Errorf("Decoded XML differs from original")
+	}
+	if req.ID != "_req1" || req.Issuer != "https://sp.example.com/metadata" || req.AssertionConsumerServiceURL != "https://sp.example.com/acs" || !req.IsPassive {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+
+	if _, _, err := DecodePostRequest(base64.StdEncoding.EncodeToString(raw)); err != nil {
+		t.Errorf("Failed to decode POST request: %v", err)
+	}
+	if _, _, err := DecodePostRequest(base64.StdEncoding.EncodeToString([]byte(`<AuthnRequest/>`))); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected ErrInvalidMessage, got %v", err)
+	}
+}
+
+// TestParseServiceProviderMetadata はSPのメタデータからエンティティIDと送信先を読み込むことを確認します
+func TestParseServiceProviderMetadata(t *testing.T) {
+	metadata, err := ParseServiceProviderMetadata([]byte(`<?xml version="1.0"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://sp.example.com/metadata">
+  <md:SPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Artifact" Location="https://sp.example.com/artifact" index="0"/>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/acs2" index="1"/>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/acs" index="2" isDefault="true"/>
+  </md:SPSSODescriptor>
+</md:EntityDescriptor>`))
+	if err != nil {
+		t.Fatalf("Failed to parse metadata: %v", err)
+	}
+	if metadata.EntityID != "https://sp.example.com/metadata" {
+		t.Errorf("Unexpected entity ID: %s", metadata.EntityID)
+	}
+	if strings.Join(metadata.ACSURLs, ",") != "https://sp.example.com/acs,https://sp.example.com/acs2" {
+		t.Errorf("Unexpected ACS URLs: %v", metadata.ACSURLs)
+	}
+}
+
+// TestResponseSign は署名したレスポンスを正規化して検証できることを確認します
+func TestResponseSign(t *testing.T) {
+	keyPEM, certPEM := newTestKeyPair(t)
+	keyPair, err := ParseKeyPair(keyPEM, certPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse key pair: %v", err)
+	}
+
+	now := time.Now()
+	response := &Response{
+		Issuer:       "https://auth.example.com/saml/metadata",
+		Audience:     "https://sp.example.com/metadata",
+		Destination:  "https://sp.example.com/acs?a=1&b=2",
+		InResponseTo: "_req1",
+		NameID:       "user-1",
+		AuthnInstant: now.Add(-time.Hour),
+		IssueInstant: now,
+		Attributes: []Attribute{
+			{Name: "displayName", Values: []string{`じょぎ <"太郎"> & 'co'` + "\r\n\t"}},
+			{Name: "roles", Values: []string{"幹部", "部員"}},
+			{Name: "urn:oid:2.5.4.42", Values: []string{"x\x00y"}},
+			{Name: "studentID"},
+		},
+	}
+	signed, err := response.Sign(keyPair)
+	if err != nil {
+		t.Fatalf("Failed to sign response: %v", err)
+	}
+
+	root := parseTestXML(t, signed)
+	verifyTestSignature(t, root, &keyPair.PrivateKey.PublicKey)
+	assertion := findTestElement(root, "Assertion")
+	if assertion == nil {
+		t.Fatalf("Assertion not found in %s", signed)
+	}
+	verifyTestSignature(t, assertion, &keyPair.PrivateKey.PublicKey)
+
+	// 属性の値がそのまま読み取れること
+	var parsed struct {
+		Assertion struct {
+			Attributes []struct {
+				Name   string   `xml:"Name,attr"`
+				Values []string `xml:"AttributeValue"`
+			} `xml:"AttributeStatement>Attribute"`
+			Audience string `xml:"Conditions>AudienceRestriction>Audience"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+	}
+	if err := xml.Unmarshal(signed, &parsed); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(parsed.Assertion.Attributes) != 3 {
+		t.Fatalf("Expected 3 attributes, got %+v", parsed.Assertion.Attributes)
+	}
+	if got := parsed.Assertion.Attributes[0].Values[0]; got != `じょぎ <"太郎"> & 'co'`+"\r\n\t" {
+		t.Errorf("Unexpected display name: %q", got)
+	}
+	if got := parsed.Assertion.Attributes[2].Values[0]; got != "xy" {
+		t.Errorf("Expected invalid XML characters to be removed, got %q", got)
+	}
+	if parsed.Assertion.Audience != response.Audience {
+		t.Errorf("Unexpected audience: %s", parsed.Assertion.Audience)
+	}
+
+	// 改ざんしたレスポンスは検証できないこと
+	tampered := bytes.Replace(signed, []byte("user-1"), []byte("user-2"), 1)
+	tamperedRoot := parseTestXML(t, tampered)
+	if err := checkTestSignature(findTestElement(tamperedRoot, "Assertion"), &keyPair.PrivateKey.PublicKey); err == nil {
+		t.Error("Expected tampered assertion to fail verification")
+	}
+}
+
+// TestIdentityProviderMetadata はIdPのメタデータに証明書とSSOのURLが含まれることを確認します
+func TestIdentityProviderMetadata(t *testing.T) {
+	keyPEM, certPEM := newTestKeyPair(t)
+	keyPair, _ := ParseKeyPair(keyPEM, certPEM)
+
+	metadata := IdentityProviderMetadata("https://auth.example.com/saml/metadata", "https://auth.example.com/saml/sso", keyPair.Certificate)
+	var parsed struct {
+		EntityID    string `xml:"entityID,attr"`
+		Certificate string `xml:"IDPSSODescriptor>KeyDescriptor>KeyInfo>X509Data>X509Certificate"`
+		SSO         []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"IDPSSODescriptor>SingleSignOnService"`
+	}
+	if err := xml.Unmarshal(metadata, &parsed); err != nil {
+		t.Fatalf("Failed to parse metadata: %v", err)
+	}
+	if parsed.EntityID != "https://auth.example.com/saml/metadata" || len(parsed.SSO) != 2 {
+		t.Errorf("Unexpected metadata: %+v", parsed)
+	}
+	if parsed.Certificate != base64.StdEncoding.EncodeToString(keyPair.Certificate.Raw) {
+		t.Errorf("Metadata does not contain the signing certificate")
+	}
+}
+
+// testNode はテストで署名を検証するためのXMLの要素です（正規化の実装とは独立にパースします）
+type testNode struct {
+	prefix, local string
+	nsDecls       map[string]string // 接頭辞とURI（この要素で宣言したもの）
+	attrs         []xml.Attr        // 名前空間の宣言以外の属性（Name.Spaceは接頭辞）
+	children      []any             // *testNode または string
+	parent        *testNode
+}
+
+// parseTestXML はXMLを接頭辞を保ったままパースします
+func parseTestXML(t *testing.T, data []byte) *testNode {
+	t.Helper()
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *testNode
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to parse XML: %v", err)
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			node := &testNode{prefix: tok.Name.Space, local: tok.Name.Local, nsDecls: map[string]string{}, parent: current}
+			for _, attr := range tok.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					node.nsDecls[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					node.nsDecls[""] = attr.Value
+				default:
+					node.attrs = append(node.attrs, attr)
+				}
+			}
+			if current != nil {
+				current.children = append(current.children, node)
+			} else {
+				root = node
+			}
+			current = node
+		case xml.EndElement:
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, string(tok))
+			}
+		}
+	}
+	return root
+}
+
+// findTestElement はローカル名が一致する最初の要素を返します
+func findTestElement(n *testNode, local string) *testNode {
+	if n.local == local {
+		return n
+	}
+	for _, child := range n.children {
+		if c, ok := child.(*testNode); ok {
+			if found := findTestElement(c, local); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// lookupNamespace は要素で有効な接頭辞のURIを返します
+func (n *testNode) lookupNamespace(prefix string) string {
+	for e := n; e != nil; e = e.parent {
+		if uri, ok := e.nsDecls[prefix]; ok {
+			return uri
+		}
+	}
+	return ""
+}
+
+// exclusiveC14N は要素を排他的XML正規化します（skipの要素は出力しません）
+func exclusiveC14N(n *testNode, rendered map[string]string, skip *testNode, b *strings.Builder) {
+	qualified := func(prefix, local string) string {
+		if prefix == "" {
+			return local
+		}
+		return prefix + ":" + local
+	}
+
+	// 要素と属性で使用している接頭辞のうち、出力済みの祖先で宣言していないものを宣言する
+	used := []string{n.prefix}
+	for _, attr := range n.attrs {
+		if attr.Name.Space != "" {
+			used = append(used, attr.Name.Space)
+		}
+	}
+	renderedHere := map[string]string{}
+	for k, v := range rendered {
+		renderedHere[k] = v
+	}
+	var decls []string
+	for _, prefix := range used {
+		uri := n.lookupNamespace(prefix)
+		if current, ok := renderedHere[prefix]; ok && current == uri {
+			continue
+		}
+		if prefix == "" && uri == "" {
+			continue
+		}
+		renderedHere[prefix] = uri
+		decls = append(decls, prefix)
+	}
+	sort.Strings(decls)
+
+	escapeAttrValue := strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+	b.WriteString("<" + qualified(n.prefix, n.local))
+	for _, prefix := range decls {
+		if prefix == "" {
+			b.WriteString(` xmlns="` + escapeAttrValue.Replace(renderedHere[prefix]) + `"`)
+		} else {
+			b.WriteString(` xmlns:` + prefix + `="` + escapeAttrValue.Replace(renderedHere[prefix]) + `"`)
+		}
+	}
+	attrs := append([]xml.Attr(nil), n.attrs...)
+	sort.Slice(attrs, func(i, j int) bool {
+		ui, uj := n.lookupNamespace(attrs[i].Name.Space), n.lookupNamespace(attrs[j].Name.Space)
+		if attrs[i].Name.Space == "" {
+			ui = ""
+		}
+		if attrs[j].Name.Space == "" {
+			uj = ""
+		}
+		if ui != uj {
+			return ui < uj
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	for _, attr := range attrs {
+		b.WriteString(" " + qualified(attr.Name.Space, attr.Name.Local) + `="` + escapeAttrValue.Replace(attr.Value) + `"`)
+	}
+	b.WriteString(">")
+
+	escapeTextValue := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	for _, child := range n.children {
+		switch c := child.(type) {
+		case string:
+			b.WriteString(escapeTextValue.Replace(c))
+		case *testNode:
+			if c != skip {
+				exclusiveC14N(c, renderedHere, skip, b)
+			}
+		}
+	}
+	b.WriteString("</" + qualified(n.prefix, n.local) + ">")
+}
+
+// childElement は指定したローカル名の直下の子要素を返します
+func (n *testNode) childElement(local string) *testNode {
+	for _, child := range n.children {
+		if c, ok := child.(*testNode); ok && c.local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// textContent は要素の文字列を返します
+func (n *testNode) textContent() string {
+	var b strings.Builder
+	for _, child := range n.children {
+		if s, ok := child.(string); ok {
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+// checkTestSignature は要素の包含署名を検証します
+func checkTestSignature(signed *testNode, publicKey *rsa.PublicKey) error {
+	signature := signed.childElement("Signature")
+	if signature == nil {
+		return errors.New("signature not found")
+	}
+	signedInfo := signature.childElement("SignedInfo")
+	reference := signedInfo.childElement("Reference")
+
+	var id string
+	for _, attr := range signed.attrs {
+		if attr.Name.Local == "ID" {
+			id = attr.Value
+		}
+	}
+	for _, attr := range reference.attrs {
+		if attr.Name.Local == "URI" && attr.Value != "#"+id {
+			return errors.New("reference URI does not match ID")
+		}
+	}
+
+	var content strings.Builder
+	exclusiveC14N(signed, map[string]string{}, signature, &content)
+	digest := sha256.Sum256([]byte(content.String()))
+	if base64.StdEncoding.EncodeToString(digest[:]) != reference.childElement("DigestValue").textContent() {
+		return errors.New("digest mismatch")
+	}
+
+	var canonicalSignedInfo strings.Builder
+	exclusiveC14N(signedInfo, map[string]string{}, nil, &canonicalSignedInfo)
+	hashed := sha256.Sum256([]byte(canonicalSignedInfo.String()))
+	signatureValue, err := base64.StdEncoding.DecodeString(signature.childElement("SignatureValue").textContent())
+	if err != nil {
+		return err
+	}
+	return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], signatureValue)
+}
+
+// verifyTestSignature は要素の包含署名を検証し、失敗した場合はテストを失敗させます
+func verifyTestSignature(t *testing.T, signed *testNode, publicKey *rsa.PublicKey) {
+	t.Helper()
+	if err := checkTestSignature(signed, publicKey); err != nil {
+		t.Errorf("Failed to verify signature of %s: %v", signed.local, err)
+	}
+}
@@ -0,0 +1,116 @@
+package saml
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// element は排他的XML正規化（Exclusive XML Canonicalization）の形式で出力するXML要素です
+// 署名の対象を正規化済みの形で生成することで、XMLのパース・再正規化を行わずに署名します
+// 名前空間の宣言は、その接頭辞を最初に使用する要素にのみ指定する必要があります
+type element struct {
+	name     string      // 接頭辞付きの要素名
+	nsDecls  [][2]string // 名前空間の宣言（接頭辞とURI）
+	attrs    [][2]string // 接頭辞のない属性（名前と値）
+	text     string
+	children []*element
+}
+
+// newElement は属性を名前と値の組で指定して要素を作成します（値が空の属性は出力しません）
+func newElement(name string, attrs ...string) *element {
+	e := &element{name: name}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			e.attrs = append(e.attrs, [2]string{attrs[i], attrs[i+1]})
+		}
+	}
+	return e
+}
+
+// declare は名前空間の宣言を追加します
+func (e *element) declare(prefix, uri string) *element {
+	e.nsDecls = append(e.nsDecls, [2]string{prefix, uri})
+	return e
+}
+
+// withText は要素の文字列を設定します
+func (e *element) withText(text string) *element {
+	e.text = text
+	return e
+}
+
+// add は子要素を追加します
+func (e *element) add(children ...*element) *element {
+	e.children = append(e.children, children...)
+	return e
+}
+
+// String は要素を正規化した形式で返します
+func (e *element) String() string {
+	var b strings.Builder
+	e.writeTo(&b)
+	return b.String()
+}
+
+// writeTo は要素を正規化した形式で書き出します
+// 名前空間の宣言・属性はそれぞれ名前の順に並べ、空の要素も開始タグと終了タグで出力します
+func (e *element) writeTo(b *strings.Builder) {
+	b.WriteString("<" + e.name)
+
+	nsDecls := append([][2]string(nil), e.nsDecls...)
+	sort.Slice(nsDecls, func(i, j int) bool { return nsDecls[i][0] < nsDecls[j][0] })
+	for _, ns := range nsDecls {
+		b.WriteString(` xmlns:` + ns[0] + `="` + escapeAttr(ns[1]) + `"`)
+	}
+	attrs := append([][2]string(nil), e.attrs...)
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i][0] < attrs[j][0] })
+	for _, attr := range attrs {
+		b.WriteString(" " + attr[0] + `="` + escapeAttr(attr[1]) + `"`)
+	}
+	b.WriteString(">")
+
+	b.WriteString(escapeText(e.text))
+	for _, child := range e.children {
+		child.writeTo(b)
+	}
+	b.WriteString("</" + e.name + ">")
+}
+
+// escapeText は正規化の規則に従って文字列をエスケープします
+func escapeText(s string) string {
+	s = sanitizeXMLString(s)
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;").Replace(s)
+}
+
+// escapeAttr は正規化の規則に従って属性値をエスケープします
+func escapeAttr(s string) string {
+	s = sanitizeXMLString(s)
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;").Replace(s)
+}
+
+// sanitizeXMLString は不正なUTF-8の並びとXMLで使用できない文字を取り除きます
+func sanitizeXMLString(s string) string {
+	valid := func(r rune) bool {
+		return r == '\t' || r == '\n' || r == '\r' ||
+			(r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0x10FFFF)
+	}
+
+	clean := true
+	for _, r := range s {
+		if r == utf8.RuneError || !valid(r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || !valid(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,177 @@
+package studentid
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 正規表現の名前付きグループ
+const (
+	groupYear    = "year"
+	groupFaculty = "faculty"
+)
+
+// academicYearStartMonth は年度の開始月です（4月始まり）
+const academicYearStartMonth = time.April
+
+// DefaultPattern はじょぎの標準の学籍番号形式です（例: 20X1234 = 2020年度入学・学部X・番号1234）
+const DefaultPattern = `^(?P<year>\d{2})(?P<faculty>[A-Z])(?P<number>\d{4})$`
+
+// Pattern は大学ごとの学籍番号の形式定義です
+// Regexpには入学年度を表すyearグループが必須で、学部コードを表すfacultyグループは任意です
+// yearが2桁の場合は2000年代として解釈します
+type Pattern struct {
+	Name   string `json:"name"`
+	Regexp string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// Info は学籍番号を正規化・解析した結果です
+type Info struct {
+	Normalized     string // 正規化した学籍番号（形式に一致しない場合も正規化した値が入ります）
+	Valid          bool   // いずれかの形式に一致したかどうか
+	Pattern        string // 一致した形式の名前
+	EnrollmentYear int    // 入学年度（不明な場合は0）
+	Faculty        string // 学部コード（不明な場合は空文字列）
+}
+
+// Parser は設定された形式で学籍番号を解析します
+type Parser struct {
+	patterns []Pattern
+}
+
+// NewParser は形式定義からParserを作成します
+// 定義は先頭から順に照合されます
+func NewParser(patterns []Pattern) (*Parser, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("at least one student id pattern is required")
+	}
+
+	compiled := make([]Pattern, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: invalid regexp: %w", i, err)
+		}
+		if re.SubexpIndex(groupYear) < 0 {
+			return nil, fmt.Errorf("pattern %d: named group %q is required", i, groupYear)
+		}
+		p.re = re
+		compiled[i] = p
+	}
+
+	return &Parser{patterns: compiled}, nil
+}
+
+// DefaultParser は標準の形式のみを持つParserを返します
+func DefaultParser() *Parser {
+	p, _ := NewParser([]Pattern{{Name: "default", Regexp: DefaultPattern}})
+	return p
+}
+
+// ParsePatterns はJSON形式の形式定義（Patternの配列）からParserを作成します
+func ParsePatterns(data []byte) (*Parser, error) {
+	var patterns []Pattern
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return nil, fmt.Errorf("failed to decode student id patterns: %w", err)
+	}
+
+	return NewParser(patterns)
+}
+
+// Parse は学籍番号を正規化し、形式に一致すれば入学年度と学部コードを取り出します
+func (p *Parser) Parse(raw string) *Info {
+	info := &Info{Normalized: Normalize(raw)}
+	if info.Normalized == "" {
+		return info
+	}
+
+	for _, pattern := range p.patterns {
+		m := pattern.re.FindStringSubmatch(info.Normalized)
+		if m == nil {
+			continue
+		}
+
+		year, err := parseYear(m[pattern.re.SubexpIndex(groupYear)])
+		if err != nil {
+			continue
+		}
+
+		info.Valid = true
+		info.Pattern = pattern.Name
+		info.EnrollmentYear = year
+		if idx := pattern.re.SubexpIndex(groupFaculty); idx >= 0 {
+			info.Faculty = m[idx]
+		}
+		return info
+	}
+
+	return info
+}
+
+// Normalize は全角英数字を半角に、英字を大文字に変換し、空白を取り除きます
+func Normalize(raw string) string {
+	var b strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '！' && r <= '～':
+			// 全角ASCII（U+FF01〜U+FF5E）を半角に変換
+			r -= '！' - '!'
+		case r == 'ー' || r == '－' || r == '‐':
+			r = '-'
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// parseYear は2桁または4桁の入学年度を西暦に変換します
+func parseYear(s string) (int, error) {
+	year, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid year: %w", err)
+	}
+
+	switch len(s) {
+	case 2:
+		return 2000 + year, nil
+	case 4:
+		return year, nil
+	default:
+		return 0, fmt.Errorf("year must be 2 or 4 digits: %s", s)
+	}
+}
+
+// AcademicYear は日時が属する年度（4月始まり）を返します
+func AcademicYear(t time.Time) int {
+	if t.Month() < academicYearStartMonth {
+		return t.Year() - 1
+	}
+	return t.Year()
+}
+
+// Grade は入学年度と現在日時から学年を求めます（入学年度が不明な場合や入学前は0）
+func Grade(enrollmentYear int, now time.Time) int {
+	if enrollmentYear == 0 {
+		return 0
+	}
+	grade := AcademicYear(now) - enrollmentYear + 1
+	if grade < 1 {
+		return 0
+	}
+	return grade
+}
+
+// EnrollmentYearForGrade は現在日時において指定の学年となる入学年度を返します
+func EnrollmentYearForGrade(grade int, now time.Time) int {
+	return AcademicYear(now) - grade + 1
+}
@@ -0,0 +1,124 @@
+package studentid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "半角はそのまま", input: "20X1234", expected: "20X1234"},
+		{name: "全角英数字", input: "２０Ｘ１２３４", expected: "20X1234"},
+		{name: "小文字", input: "20x1234", expected: "20X1234"},
+		{name: "空白を除去", input: " 20X 1234　", expected: "20X1234"},
+		{name: "全角ハイフン", input: "２０－Ｘ－１２３４", expected: "20-X-1234"},
+		{name: "日本語は変換しない", input: "ないです", expected: "ないです"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParser_Parse(t *testing.T) {
+	parser, err := ParsePatterns([]byte(`[
+		{"name": "undergraduate", "pattern": "^(?P<year>\\d{2})(?P<faculty>[A-Z])(?P<number>\\d{4})$"},
+		{"name": "graduate", "pattern": "^M(?P<year>\\d{4})-(?P<number>\\d{3})$"}
+	]`))
+	if err != nil {
+		t.Fatalf("ParsePatterns failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected Info
+	}{
+		{
+			name:     "学部生",
+			input:    "２３ｘ０１２３",
+			expected: Info{Normalized: "23X0123", Valid: true, Pattern: "undergraduate", EnrollmentYear: 2023, Faculty: "X"},
+		},
+		{
+			name:     "4桁の年度・学部なし",
+			input:    "m2024-001",
+			expected: Info{Normalized: "M2024-001", Valid: true, Pattern: "graduate", EnrollmentYear: 2024},
+		},
+		{
+			name:     "形式に一致しない",
+			input:    "ないです",
+			expected: Info{Normalized: "ないです"},
+		},
+		{
+			name:     "空文字列",
+			input:    "",
+			expected: Info{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parser.Parse(tt.input)
+			if *got != tt.expected {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, *got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewParser_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []Pattern
+	}{
+		{name: "定義なし", patterns: nil},
+		{name: "不正な正規表現", patterns: []Pattern{{Name: "broken", Regexp: "^(?P<year>\\d{2}"}}},
+		{name: "yearグループなし", patterns: []Pattern{{Name: "no-year", Regexp: "^\\d{7}$"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewParser(tt.patterns); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestGrade(t *testing.T) {
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+
+	tests := []struct {
+		name           string
+		enrollmentYear int
+		now            time.Time
+		expected       int
+	}{
+		{name: "入学年度の4月", enrollmentYear: 2024, now: time.Date(2024, time.April, 1, 0, 0, 0, 0, jst), expected: 1},
+		{name: "翌年の3月はまだ1年生", enrollmentYear: 2024, now: time.Date(2025, time.March, 31, 0, 0, 0, 0, jst), expected: 1},
+		{name: "翌年の4月で2年生", enrollmentYear: 2024, now: time.Date(2025, time.April, 1, 0, 0, 0, 0, jst), expected: 2},
+		{name: "入学前", enrollmentYear: 2025, now: time.Date(2025, time.March, 1, 0, 0, 0, 0, jst), expected: 0},
+		{name: "入学年度不明", enrollmentYear: 0, now: time.Date(2025, time.April, 1, 0, 0, 0, 0, jst), expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Grade(tt.enrollmentYear, tt.now); got != tt.expected {
+				t.Errorf("Grade(%d, %v) = %d, want %d", tt.enrollmentYear, tt.now, got, tt.expected)
+			}
+		})
+	}
+
+	now := time.Date(2025, time.October, 1, 0, 0, 0, 0, jst)
+	if year := EnrollmentYearForGrade(3, now); Grade(year, now) != 3 {
+		t.Errorf("EnrollmentYearForGrade(3) = %d, which is grade %d", year, Grade(year, now))
+	}
+}
@@ -0,0 +1,94 @@
+// Package webhook はWebhookの署名の作成と検証を提供します
+// 受信側のアプリケーションからも利用できるよう、このリポジトリの他のパッケージには依存しません
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 送信するリクエストのヘッダー
+const (
+	// SignatureHeader は署名のヘッダーです（形式: t=<UNIX秒>,v1=<HMAC-SHA256の16進数>）
+	SignatureHeader = "X-Jyogi-Signature"
+	// EventHeader は出来事の種類のヘッダーです
+	EventHeader = "X-Jyogi-Event"
+	// DeliveryHeader は配信IDのヘッダーです（再送では異なるIDになります）
+	DeliveryHeader = "X-Jyogi-Delivery"
+)
+
+// DefaultTolerance は署名の日時と受信した日時の許容差の推奨値です
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature は署名のヘッダーの形式が不正、または署名が一致しない場合のエラーです
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrSignatureExpired は署名の日時が許容差を超えている場合のエラーです
+	ErrSignatureExpired = errors.New("webhook: signature timestamp out of tolerance")
+)
+
+// Sign は本文の署名のヘッダーの値を返します
+// 署名は「<UNIX秒>.<本文>」のHMAC-SHA256で、日時を含めることで再送攻撃を防ぎます
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, computeSignature(secret, t, body))
+}
+
+// Verify は署名のヘッダーの値を検証します
+// 日時がnowからtolerance以上離れている場合はErrSignatureExpiredを返します（toleranceが0の場合は日時を検証しません）
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		diff := now.Sub(time.Unix(unix, 0))
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	expected := computeSignature(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// computeSignature は「<UNIX秒>.<本文>」のHMAC-SHA256を16進数で返します
+func computeSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSignAndVerify は署名の作成と検証をテストします
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"user.created"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign("secret", now, body)
+
+	if header[:13] != "t=1700000000," {
+		t.Errorf("Unexpected header: %s", header)
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", "secret", header, body, now.Add(time.Minute), nil},
+		{"wrong secret", "other", header, body, now, ErrInvalidSignature},
+		{"modified body", "secret", header, []byte(`{"type":"user.updated"}`), now, ErrInvalidSignature},
+		{"expired", "secret", header, body, now.Add(10 * time.Minute), ErrSignatureExpired},
+		{"future", "secret", header, body, now.Add(-10 * time.Minute), ErrSignatureExpired},
+		{"malformed", "secret", "v1=abc", body, now, ErrInvalidSignature},
+		{"multiple signatures", "secret", "t=1700000000,v1=0000," + header[13:], body, now, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, tt.now, DefaultTolerance)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
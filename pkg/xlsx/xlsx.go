@@ -0,0 +1,186 @@
+// Package xlsx はOffice Open XML形式（.xlsx）のワークシートを1行ずつ書き出します
+// 1シートの文字列のみの表を出力する用途に限定し、行をメモリに保持せずに書き出します
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxRows はワークシートの最大行数です
+const MaxRows = 1048576
+
+// maxCellLength はセルに格納できる最大文字数です
+const maxCellLength = 32767
+
+// ErrTooManyRows はワークシートの最大行数を超えた場合のエラーです
+var ErrTooManyRows = errors.New("xlsx: too many rows")
+
+// staticParts はワークシート以外のパッケージの構成要素です
+var staticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// Writer は1シートのワークブックを書き出します
+type Writer struct {
+	zw        *zip.Writer
+	sheetName string
+	sheet     io.Writer
+	rows      int
+	err       error
+}
+
+// NewWriter はwに書き出すWriterを作成します
+// sheetNameはシート名です（Excelの制限により31文字まで、使用できない文字は「_」に置き換えます）
+func NewWriter(w io.Writer, sheetName string) *Writer {
+	return &Writer{zw: zip.NewWriter(w), sheetName: sanitizeSheetName(sheetName)}
+}
+
+// WriteRow は1行を書き出します（値はすべて文字列のセルになります）
+func (w *Writer) WriteRow(values []string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.sheet == nil {
+		if w.err = w.begin(); w.err != nil {
+			return w.err
+		}
+	}
+	if w.rows >= MaxRows {
+		return ErrTooManyRows
+	}
+	w.rows++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, w.rows)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, cellRef(i, w.rows))
+		_ = xml.EscapeText(&b, []byte(truncate(value, maxCellLength)))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+
+	if _, err := io.WriteString(w.sheet, b.String()); err != nil {
+		w.err = fmt.Errorf("failed to write row: %w", err)
+		return w.err
+	}
+	return nil
+}
+
+// Close はワークシートを閉じ、パッケージの書き出しを完了します（wは閉じません）
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.sheet == nil {
+		if err := w.begin(); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+		return fmt.Errorf("failed to write worksheet: %w", err)
+	}
+	if err := w.zw.Close(); err != nil {
+		return fmt.Errorf("failed to close xlsx package: %w", err)
+	}
+	return nil
+}
+
+// begin はワークシート以外の構成要素を書き出し、ワークシートの書き出しを開始します
+func (w *Writer) begin() error {
+	for _, part := range staticParts {
+		if err := w.writePart(part.name, part.content); err != nil {
+			return err
+		}
+	}
+
+	var workbook strings.Builder
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`)
+	_ = xml.EscapeText(&workbook, []byte(w.sheetName))
+	workbook.WriteString(`" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+	if err := w.writePart("xl/workbook.xml", workbook.String()); err != nil {
+		return err
+	}
+
+	sheet, err := w.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("failed to create worksheet: %w", err)
+	}
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return fmt.Errorf("failed to write worksheet: %w", err)
+	}
+	w.sheet = sheet
+	return nil
+}
+
+// writePart はパッケージに構成要素を1つ書き出します
+func (w *Writer) writePart(name, content string) error {
+	part, err := w.zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if _, err := io.WriteString(part, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// ColumnName は0始まりの列番号を列名（A, B, ..., Z, AA, ...）に変換します
+func ColumnName(index int) string {
+	name := ""
+	for n := index + 1; n > 0; n = (n - 1) / 26 {
+		name = string(rune('A'+(n-1)%26)) + name
+	}
+	return name
+}
+
+// sanitizeSheetName はシート名に使用できない文字を置き換え、31文字に切り詰めます
+func sanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = truncate(name, 31)
+	if name == "" {
+		return "Sheet1"
+	}
+	return name
+}
+
+// truncate は文字列をmaxLength文字に切り詰めます
+func truncate(s string, maxLength int) string {
+	if utf8.RuneCountInString(s) <= maxLength {
+		return s
+	}
+	return string([]rune(s)[:maxLength])
+}
+
+// cellRef はセルの位置を「A1」形式で返します
+func cellRef(column, row int) string {
+	return ColumnName(column) + strconv.Itoa(row)
+}
@@ -0,0 +1,139 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+// TestColumnName は列番号が列名に変換されることをテストします
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := ColumnName(tt.index); got != tt.want {
+			t.Errorf("ColumnName(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+// TestWriter は書き出したワークブックのシートから行を読み出せることをテストします
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "members/2025")
+	rows := [][]string{
+		{"username", "real_name", "comment"},
+		{"taro", "じょぎ太郎", "<よろしく> & \"お願いします\""},
+		{"hanako", "", "=1+1"},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open package: %v", err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		parts[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Expected part %s", name)
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatalf("Failed to parse workbook: %v", err)
+	}
+	if len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != "members_2025" {
+		t.Errorf("Expected sheet name members_2025, got %+v", workbook.Sheets)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R string `xml:"r,attr"`
+				T string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("Failed to parse worksheet: %v", err)
+	}
+	if len(sheet.Rows) != len(rows) {
+		t.Fatalf("Expected %d rows, got %d", len(rows), len(sheet.Rows))
+	}
+	for i, row := range sheet.Rows {
+		got := make([]string, len(rows[i]))
+		for _, cell := range row.Cells {
+			for j := range got {
+				if cell.R == cellRef(j, i+1) {
+					got[j] = cell.T
+				}
+			}
+		}
+		if row.R != i+1 || !slices.Equal(got, rows[i]) {
+			t.Errorf("Row %d: expected %q, got %q (r=%d)", i+1, rows[i], got, row.R)
+		}
+	}
+}
+
+// TestWriter_Empty は行がなくても有効なワークブックを書き出せることをテストします
+func TestWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf, "").Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Errorf("Failed to open package: %v", err)
+	}
+}
+
+// TestWriter_WriteError は書き出し先のエラーが返されることをテストします
+func TestWriter_WriteError(t *testing.T) {
+	// 書き出しはバッファリングされるため、遅くとも閉じる時点でエラーになる
+	w := NewWriter(failingWriter{}, "members")
+	_ = w.WriteRow([]string{"a"})
+	if err := w.Close(); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
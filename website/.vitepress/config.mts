@@ -20,6 +20,11 @@ export default defineConfig({
                     items: [
                         { text: 'クイックスタート (クライアント統合)', link: '/guide/client-integration' },
                         { text: 'Rails: DB直接参照', link: '/guide/rails-direct-db' },
+                        { text: 'Webhook', link: '/guide/webhooks' },
+                        { text: 'フォワード認証', link: '/guide/forward-auth' },
+                        { text: 'SAMLでのログイン', link: '/guide/saml' },
+                        { text: 'LDAPでのログイン', link: '/guide/ldap' },
+                        { text: 'CLI・端末からのログイン', link: '/guide/device-flow' },
                         { text: 'API リファレンス', link: '/reference/api' }
                     ]
                 },
@@ -31,6 +36,7 @@ export default defineConfig({
                         { text: 'アーキテクチャ', link: '/guide/architecture' },
                         { text: 'デプロイ', link: '/guide/deployment' },
                         { text: 'プロフィール同期', link: '/guide/profile-sync' },
+                        { text: 'メンバー名簿のエクスポート', link: '/guide/member-export' },
                         { text: 'テストガイド', link: '/guide/testing' },
                         { text: 'トラブルシューティング', link: '/guide/troubleshooting' },
                         { text: 'データベース設計', link: '/reference/database' }